@@ -0,0 +1,187 @@
+package logparser
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// albFieldNames names the positional fields of an AWS Application Load
+// Balancer access log entry, in the documented order. Entries written
+// before a field was introduced end early, and fields added after these
+// are ignored.
+var albFieldNames = []string{
+	"type",
+	"time",
+	"elb",
+	"client:port",
+	"target:port",
+	"request_processing_time",
+	"target_processing_time",
+	"response_processing_time",
+	"elb_status_code",
+	"target_status_code",
+	"received_bytes",
+	"sent_bytes",
+	"request",
+	"user_agent",
+	"ssl_cipher",
+	"ssl_protocol",
+	"target_group_arn",
+	"trace_id",
+	"domain_name",
+	"chosen_cert_arn",
+	"matched_rule_priority",
+	"request_creation_time",
+	"actions_executed",
+	"redirect_url",
+	"error_reason",
+	"target:port_list",
+	"target_status_code_list",
+	"classification",
+	"classification_reason",
+	"conn_trace_id",
+}
+
+// albMinFields is the number of fields up to and including request, the
+// least an ALB entry must have
+const albMinFields = 13
+
+// albNumeric lists the ALB fields stored as float64
+var albNumeric = map[string]bool{
+	"request_processing_time":  true,
+	"target_processing_time":   true,
+	"response_processing_time": true,
+	"elb_status_code":          true,
+	"target_status_code":       true,
+	"received_bytes":           true,
+	"sent_bytes":               true,
+	"matched_rule_priority":    true,
+}
+
+// parseALBLine parses one ALB access log entry. The request line becomes
+// the message and is also split into method, url, and protocol; the
+// client and target addresses are split into client_ip, client_port,
+// target_ip, and target_port. Processing times (seconds), status codes,
+// byte counts, and the rule priority are float64; a "-", or the -1 ALB
+// writes for an unavailable time, leaves the field out. The level is
+// ERROR for a 5xx elb_status_code, WARN for 4xx, and INFO otherwise.
+func parseALBLine(line string, cfg *config) (*LogEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, ErrEmptyLine
+	}
+
+	values := splitALBFields(line)
+	if len(values) < albMinFields {
+		return nil, fmt.Errorf("ALB entry has %d fields, want at least %d", len(values), albMinFields)
+	}
+
+	start := cfg.timer.start()
+	ts, err := time.Parse(time.RFC3339Nano, values[1])
+	cfg.timer.stop(phaseExtractTS, start, 1)
+
+	if err != nil {
+		return nil, fmt.Errorf("ALB entry time: %w", err)
+	}
+
+	entry := &LogEntry{
+		Timestamp: ts,
+		Message:   values[12],
+		Fields:    cfg.newFields(),
+	}
+
+	for i, value := range values[:min(len(values), len(albFieldNames))] {
+		name := albFieldNames[i]
+
+		switch {
+		case i == 1 || i == 12 || value == "-" || value == "":
+			continue
+		case name == "client:port" || name == "target:port":
+			setALBAddress(entry, strings.TrimSuffix(name, ":port"), value, cfg)
+		case albNumeric[name]:
+			if f, err := strconv.ParseFloat(value, 64); err == nil && f != -1 {
+				cfg.setField(entry, name, f)
+			}
+		default:
+			cfg.setField(entry, strings.ReplaceAll(name, ":", "_"), value)
+		}
+	}
+
+	if method, rest, ok := strings.Cut(values[12], " "); ok {
+		url, protocol, _ := strings.Cut(rest, " ")
+		cfg.setField(entry, "method", method)
+		cfg.setField(entry, "url", url)
+		cfg.setField(entry, "protocol", protocol)
+	}
+
+	entry.Level = statusLevel(values[8])
+
+	cfg.finishEntry(entry)
+
+	return entry, nil
+}
+
+// splitALBFields splits an ALB entry at spaces, keeping double-quoted
+// values, whose quotes are removed, whole
+func splitALBFields(line string) []string {
+	values := make([]string, 0, len(albFieldNames))
+
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+
+			continue
+		}
+
+		if line[i] == '"' {
+			var value string
+
+			value, i = scanLogfmtQuoted(line, i)
+			values = append(values, value)
+
+			continue
+		}
+
+		end := strings.IndexByte(line[i:], ' ')
+		if end < 0 {
+			end = len(line) - i
+		}
+
+		values = append(values, line[i:i+end])
+		i += end
+	}
+
+	return values
+}
+
+// setALBAddress stores an ip:port address as <prefix>_ip and <prefix>_port
+func setALBAddress(entry *LogEntry, prefix, addr string, cfg *config) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		cfg.setField(entry, prefix, addr)
+
+		return
+	}
+
+	cfg.setField(entry, prefix+"_ip", host)
+
+	if n, err := strconv.ParseFloat(port, 64); err == nil {
+		cfg.setField(entry, prefix+"_port", n)
+	}
+}
+
+// statusLevel derives the level from an HTTP status code sent by a load
+// balancer or proxy
+func statusLevel(status string) string {
+	switch {
+	case strings.HasPrefix(status, "5"):
+		return LevelError
+	case strings.HasPrefix(status, "4"):
+		return ParseLevel("warn")
+	default:
+		return LevelInfo
+	}
+}
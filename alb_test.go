@@ -0,0 +1,102 @@
+package logparser
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestALBAccessLog(t *testing.T) {
+	data, err := os.ReadFile("testdata/alb.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := NewWithFormat(FormatALB).ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	wantLevels := []string{"INFO", "INFO", "INFO", "ERROR", "WARN"}
+	if len(entries) != len(wantLevels) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(wantLevels))
+	}
+
+	for i, want := range wantLevels {
+		if entries[i].Level != want {
+			t.Errorf("entry %d: level %s, want %s", i, entries[i].Level, want)
+		}
+	}
+
+	e := entries[1]
+	if want := time.Date(2018, 7, 2, 22, 23, 0, 186641000, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	if e.Message != "GET https://www.example.com:443/ HTTP/1.1" {
+		t.Errorf("Message = %q", e.Message)
+	}
+
+	want := map[string]interface{}{
+		"type":                     "https",
+		"elb":                      "app/my-loadbalancer/50dc6c495c0c9188",
+		"client_ip":                "192.168.131.39",
+		"client_port":              2817.0,
+		"target_ip":                "10.0.0.1",
+		"target_port":              80.0,
+		"request_processing_time":  0.086,
+		"target_processing_time":   0.048,
+		"response_processing_time": 0.037,
+		"elb_status_code":          200.0,
+		"target_status_code":       200.0,
+		"received_bytes":           0.0,
+		"sent_bytes":               57.0,
+		"method":                   "GET",
+		"url":                      "https://www.example.com:443/",
+		"protocol":                 "HTTP/1.1",
+		"user_agent":               "curl/7.46.0",
+		"ssl_protocol":             "TLSv1.2",
+		"trace_id":                 "Root=1-58337281-1d84f3d73c47ec4e58577259",
+		"domain_name":              "www.example.com",
+		"matched_rule_priority":    1.0,
+		"actions_executed":         "authenticate,forward",
+		"target_port_list":         "10.0.0.1:80",
+		"target_status_code_list":  "200",
+		"conn_trace_id":            "TID_1234abcd5678ef90",
+	}
+
+	for k, v := range want {
+		if e.Fields[k] != v {
+			t.Errorf("Fields[%q] = %#v, want %#v", k, e.Fields[k], v)
+		}
+	}
+
+	// A request that never reached a target
+	e = entries[3]
+	for _, k := range []string{"target_ip", "request_processing_time", "target_status_code", "redirect_url"} {
+		if v, ok := e.Fields[k]; ok {
+			t.Errorf("Fields[%q] = %v, want none", k, v)
+		}
+	}
+
+	if e.Fields["user_agent"] != `Mozilla/5.0 (X11; Linux x86_64) "quoted"` {
+		t.Errorf("user_agent = %q", e.Fields["user_agent"])
+	}
+}
+
+func TestALBMalformed(t *testing.T) {
+	for _, line := range []string{
+		"http 2018-07-02T22:23:00.186641Z app/lb 1.2.3.4:1",
+		`http yesterday app/lb 1.2.3.4:1 - 0 0 0 200 200 0 0 "GET / HTTP/1.1"`,
+	} {
+		if _, err := ParseLine(line, FormatALB); err == nil {
+			t.Errorf("ParseLine(%q) succeeded, want error", line)
+		}
+	}
+
+	// Entries from before later fields were added
+	entry, err := ParseLine(`http 2018-07-02T22:23:00Z app/lb 1.2.3.4:1 - 0 0 0 301 - 0 0 "GET / HTTP/1.1"`, FormatALB)
+	if err != nil || entry.Fields["elb_status_code"] != 301.0 || entry.Fields["url"] != "/" {
+		t.Errorf("ParseLine() = %+v, %v", entry, err)
+	}
+}
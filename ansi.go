@@ -0,0 +1,181 @@
+package logparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Control bytes recognized by the ANSI stripper
+const (
+	ansiESC = 0x1b
+	ansiBEL = 0x07
+	ansiCSI = '['
+	ansiOSC = ']'
+)
+
+// stripANSI removes ANSI escape sequences from s, reporting whether any was
+// removed. Carriage returns are left alone: a lone one ends a line before
+// the text parser sees it (see ScanLines).
+func stripANSI(s string) (string, bool) {
+	if strings.IndexByte(s, ansiESC) < 0 {
+		return s, false
+	}
+
+	var b strings.Builder
+
+	b.Grow(len(s))
+
+	found := false
+
+	for i := 0; i < len(s); {
+		ch := s[i]
+
+		switch {
+		case ch == ansiESC && i+1 < len(s) && s[i+1] == ansiCSI:
+			found = true
+			i = skipCSI(s, i+2)
+		case ch == ansiESC && i+1 < len(s) && s[i+1] == ansiOSC:
+			found = true
+			i = skipOSC(s, i+2)
+		case ch == ansiESC:
+			found = true
+			i += 2 // Two-byte escape such as ESC 7 or ESC c
+		default:
+			b.WriteByte(ch)
+
+			i++
+		}
+	}
+
+	return b.String(), found
+}
+
+// skipCSI returns the index after a CSI sequence whose parameters start at i.
+// Parameter and intermediate bytes run until a final byte in 0x40-0x7E.
+func skipCSI(s string, i int) int {
+	for i < len(s) {
+		ch := s[i]
+		i++
+
+		if ch >= 0x40 && ch <= 0x7e {
+			break
+		}
+	}
+
+	return i
+}
+
+// skipOSC returns the index after an OSC sequence whose payload starts at i.
+// The payload ends at BEL or at the string terminator ESC \.
+func skipOSC(s string, i int) int {
+	for i < len(s) {
+		switch {
+		case s[i] == ansiBEL:
+			return i + 1
+		case s[i] == ansiESC && i+1 < len(s) && s[i+1] == '\\':
+			return i + 2
+		}
+
+		i++
+	}
+
+	return i
+}
+
+// WithSanitizeControl makes messages safe to print to a terminal, as
+// SafeMessage does, marking entries whose message changed with
+// Fields["_sanitized"] = true. The message as logged stays available in
+// Fields["_raw"] with WithRawLine.
+func WithSanitizeControl(enable bool) Option {
+	return func(c *config) {
+		c.sanitizeControl = enable
+	}
+}
+
+// sanitizeMessage sanitizes the entry's message when enabled
+func (c *config) sanitizeMessage(entry *LogEntry) {
+	if !c.sanitizeControl {
+		return
+	}
+
+	if msg, changed := sanitizeControl(entry.Message); changed {
+		entry.Message = msg
+		c.setField(entry, "_sanitized", true)
+	}
+}
+
+// SafeMessage returns the message with raw control characters made
+// harmless for terminal output, leaving Message itself untouched. Carriage
+// return overwrites, as written by progress bars, collapse to the final
+// text of each line, and other C0 control characters except tab and
+// newline are shown as escapes such as \x07, so escape sequences print as
+// text instead of acting on the terminal. Newlines are kept because
+// multi-line entries join their lines with them.
+func (e *LogEntry) SafeMessage() string {
+	msg, _ := sanitizeControl(e.Message)
+
+	return msg
+}
+
+// sanitizeControl implements SafeMessage, reporting whether s changed
+func sanitizeControl(s string) (string, bool) {
+	if !hasControl(s) {
+		return s, false
+	}
+
+	lines := strings.Split(s, "\n")
+
+	var b strings.Builder
+
+	b.Grow(len(s))
+
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		if strings.IndexByte(line, '\r') >= 0 {
+			line = lastOverwrite(line)
+		}
+
+		for j := 0; j < len(line); j++ {
+			if ch := line[j]; isEscapedControl(ch) {
+				fmt.Fprintf(&b, `\x%02x`, ch)
+			} else {
+				b.WriteByte(ch)
+			}
+		}
+	}
+
+	return b.String(), true
+}
+
+// hasControl reports whether s holds a control character SafeMessage changes
+func hasControl(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\r' || isEscapedControl(s[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isEscapedControl reports whether SafeMessage shows ch as an escape
+func isEscapedControl(ch byte) bool {
+	return ch < 0x20 && ch != '\t' && ch != '\n' && ch != '\r'
+}
+
+// lastOverwrite returns the text a line shows after its carriage return
+// overwrites: the last non-blank segment between them
+func lastOverwrite(line string) string {
+	segments := strings.Split(line, "\r")
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		if strings.TrimSpace(segments[i]) != "" {
+			return segments[i]
+		}
+	}
+
+	return ""
+}
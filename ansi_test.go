@@ -0,0 +1,166 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripANSICompose(t *testing.T) {
+	entries, err := ParseFile(NewWithFormat(FormatText), "testdata/compose.log")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	want := []string{
+		"web-1     | 2024-01-02 15:04:05 [INFO] Listening on :8080",
+		"db-1      | 2024-01-02 15:04:05.123 UTC [1] LOG:  database system is ready to accept connections",
+		"web-1     | 2024-01-02 15:04:06 [ERROR] failed to connect to db:5432",
+		"worker-1  | WARN retrying job 42",
+		"web-1     | GET /healthz 200",
+		"Step 1/5 : FROM golang:1.22",
+		// A lone carriage return ends a line, so each progress update is
+		// an entry of its own rather than collapsing to the last one
+		"Downloading 10%",
+		"Downloading 55%",
+		"Downloading 100% complete",
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("want %d entries, got %d", len(want), len(entries))
+	}
+
+	for i, msg := range want {
+		if entries[i].Message != msg {
+			t.Errorf("entry %d: want %q, got %q", i, msg, entries[i].Message)
+		}
+
+		if strings.ContainsRune(entries[i].Message, 0x1b) {
+			t.Errorf("entry %d still contains escape sequences", i)
+		}
+	}
+
+	if entries[0].Fields["_ansi"] != true {
+		t.Errorf("want _ansi marker on colored line, got %v", entries[0].Fields)
+	}
+
+	for _, e := range entries[6:] {
+		if _, ok := e.Fields["_ansi"]; ok {
+			t.Errorf("carriage return alone should not set _ansi")
+		}
+	}
+}
+
+func TestStripANSIBeforePatternMatching(t *testing.T) {
+	line := "2024-01-02 15:04:06 [\x1b[31mERROR\x1b[0m] failed to connect"
+
+	entries, err := NewWithFormat(FormatText).ParseString(line)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if entries[0].Level != LevelError || entries[0].Message != "failed to connect" {
+		t.Errorf("want ERROR 'failed to connect', got %s %q", entries[0].Level, entries[0].Message)
+	}
+
+	entries, err = NewWithFormat(FormatText, WithStripANSI(false)).ParseString(line)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if entries[0].Level != LevelInfo || !strings.Contains(entries[0].Message, "\x1b[31m") {
+		t.Errorf("want raw line kept with stripping disabled, got %s %q", entries[0].Level, entries[0].Message)
+	}
+}
+
+func TestStripANSISequences(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "SGR color", input: "\x1b[1;31mred\x1b[0m text", want: "red text"},
+		{name: "cursor movement", input: "a\x1b[2Kb\x1b[1Ac", want: "abc"},
+		{name: "OSC with BEL", input: "\x1b]0;title\x07body", want: "body"},
+		{name: "OSC with ST", input: "\x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\", want: "link"},
+		{name: "unterminated CSI", input: "text\x1b[31", want: "text"},
+		{name: "two byte escape", input: "\x1b7saved\x1b8", want: "saved"},
+		{name: "carriage returns kept", input: "\x1b[32m10%\r50%\x1b[0m", want: "10%\r50%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := stripANSI(tt.input)
+			if got != tt.want || !found {
+				t.Errorf("stripANSI(%q) = %q, %v; want %q, true", tt.input, got, found, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{"plain", "user\tlogged in", "user\tlogged in"},
+		{"osc title", "login as \x1b]0;pwned\x07admin", `login as \x1b]0;pwned\x07admin`},
+		{"csi color", "\x1b[31mred\x1b[0m", `\x1b[31mred\x1b[0m`},
+		{"bell and backspace", "ding\x07 abc\x08\x08x", `ding\x07 abc\x08\x08x`},
+		{"progress bar", "Downloading  10%\rDownloading  50%\rDownloading 100%", "Downloading 100%"},
+		{"trailing carriage return", "done\r", "done"},
+		{"blank overwrite", "working\r   \r", "working"},
+		{"multi-line", "step 1%\rstep 99%\nnext\x00line", "step 99%\nnext\\x00line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := LogEntry{Message: tt.msg}
+			if got := entry.SafeMessage(); got != tt.want {
+				t.Errorf("SafeMessage() = %q, want %q", got, tt.want)
+			}
+
+			if entry.Message != tt.msg {
+				t.Errorf("SafeMessage() modified Message to %q", entry.Message)
+			}
+		})
+	}
+}
+
+func TestWithSanitizeControl(t *testing.T) {
+	lines := []string{
+		`{"level":"info","msg":"hello \u001b]0;owned terminal\u0007world"}`,
+		`{"level":"info","msg":"pulling  3%\r\u001b[Kpulling 47%\r\u001b[Kpulling 100%"}`,
+		`{"level":"info","msg":"clean\tmessage"}`,
+	}
+
+	entries, err := New(WithSanitizeControl(true), WithRawLine(true)).ParseString(strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{`hello \x1b]0;owned terminal\x07world`, `\x1b[Kpulling 100%`, "clean\tmessage"}
+
+	for i, e := range entries {
+		if e.Message != want[i] {
+			t.Errorf("entry %d message = %q, want %q", i, e.Message, want[i])
+		}
+
+		if sanitized := e.Fields["_sanitized"] == true; sanitized != (i < 2) {
+			t.Errorf("entry %d _sanitized = %v", i, e.Fields["_sanitized"])
+		}
+
+		if e.Fields["_raw"] != lines[i] {
+			t.Errorf("entry %d _raw = %q, want the line as read", i, e.Fields["_raw"])
+		}
+	}
+
+	plain, err := New().ParseString(lines[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(plain[0].Message, "\x1b]0;") {
+		t.Errorf("message sanitized without the option: %q", plain[0].Message)
+	}
+}
@@ -0,0 +1,492 @@
+// Package arrowipc writes parsed log entries as Apache Arrow IPC data, for
+// analytics tools that read Arrow or Feather files.
+//
+// It is a module of its own so that only programs importing it depend on
+// the Arrow library. Entries become one record batch per WithBatchSize
+// rows with a "timestamp" column of timestamp[ns, UTC], a dictionary
+// encoded "level" column, a "message" column, and one typed column per
+// selected field:
+//
+//	entries, _ := logparser.New().Parse(r)
+//	err := arrowipc.WriteArrow(f, entries, arrowipc.WithFields("status", "latency_ms"))
+//
+// It needs only LogEntry from the root module, so it builds against any
+// released version of it.
+package arrowipc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	logparser "github.com/yildizm/go-logparser"
+)
+
+// DefaultBatchSize is the number of rows per record batch unless
+// WithBatchSize says otherwise
+const DefaultBatchSize = 64 * 1024
+
+// MaxDefaultFields is the number of field keys written as columns when
+// WithFields is not given
+const MaxDefaultFields = 64
+
+// FieldType is the Arrow type of a field column
+type FieldType int
+
+// Field column types. Infer picks one from the values in the column.
+const (
+	Infer FieldType = iota
+	Int64
+	Float64
+	Bool
+	String
+)
+
+// String returns the Arrow name of the type
+func (t FieldType) String() string {
+	switch t {
+	case Int64:
+		return "int64"
+	case Float64:
+		return "float64"
+	case Bool:
+		return "bool"
+	case String:
+		return "utf8"
+	default:
+		return "infer"
+	}
+}
+
+// Standard column types
+var (
+	timestampType = &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}
+	levelType     = &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+)
+
+// Option configures WriteArrow
+type Option func(*config)
+
+type config struct {
+	fields    []string
+	types     map[string]FieldType
+	batchSize int
+	stream    bool
+}
+
+// WithFields selects the top-level field keys written as columns, in
+// order. By default the MaxDefaultFields most common keys are written,
+// most common first, ties by key.
+func WithFields(keys ...string) Option {
+	return func(c *config) {
+		c.fields = append(c.fields, keys...)
+	}
+}
+
+// WithFieldType sets the type of a field column instead of inferring it.
+// Values that do not convert to the type, such as "abc" in an Int64
+// column or 1.5 in an Int64 column, are written as nulls.
+func WithFieldType(key string, t FieldType) Option {
+	return func(c *config) {
+		if c.types == nil {
+			c.types = make(map[string]FieldType)
+		}
+
+		c.types[key] = t
+	}
+}
+
+// WithBatchSize sets the number of rows per record batch; n of zero or
+// less means DefaultBatchSize
+func WithBatchSize(n int) Option {
+	return func(c *config) {
+		c.batchSize = n
+	}
+}
+
+// WithStream writes the IPC streaming format instead of the file format.
+// The file format, also known as Feather version 2, can be read with
+// random access; the streaming format can be read as it arrives.
+func WithStream(stream bool) Option {
+	return func(c *config) {
+		c.stream = stream
+	}
+}
+
+// WriteArrow writes entries to w as Arrow IPC. Field types are inferred
+// over all entries unless set by WithFieldType: a column is int64 when
+// every value is an integer, float64 when every value is a number, bool
+// when every value is a boolean, and utf8 otherwise. Numeric and boolean
+// strings, as logfmt and text lines produce, count as numbers and
+// booleans. Objects and arrays are written as JSON text. Missing fields,
+// null values, zero timestamps, and empty levels are nulls. A field key
+// named like a standard column gets a "fields." prefix.
+func WriteArrow(w io.Writer, entries []logparser.LogEntry, opts ...Option) error {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.batchSize <= 0 {
+		cfg.batchSize = DefaultBatchSize
+	}
+
+	keys := cfg.fields
+	if keys == nil {
+		keys = commonKeys(entries)
+	}
+
+	schemaFields := []arrow.Field{
+		{Name: "timestamp", Type: timestampType, Nullable: true},
+		{Name: "level", Type: levelType, Nullable: true},
+		{Name: "message", Type: arrow.BinaryTypes.String},
+	}
+
+	fields := make([]fieldColumn, len(keys))
+
+	for i, key := range keys {
+		f := fieldColumn{values: columnValues(entries, key), typ: cfg.types[key]}
+		if f.typ == Infer {
+			f.typ = inferType(f.values)
+		}
+
+		name := key
+		if name == "timestamp" || name == "level" || name == "message" {
+			name = "fields." + key
+		}
+
+		fields[i] = f
+		schemaFields = append(schemaFields, arrow.Field{Name: name, Type: f.typ.arrowType(), Nullable: true})
+	}
+
+	schema := arrow.NewSchema(schemaFields, nil)
+	mem := memory.NewGoAllocator()
+
+	// Every batch shares the dictionary of all levels, as the file
+	// format allows no dictionary replacement between batches
+	levels := levelDictionary(entries, mem)
+	defer levels.Release()
+
+	var iw interface {
+		Write(arrow.Record) error
+		Close() error
+	}
+
+	if cfg.stream {
+		iw = ipc.NewWriter(w, ipc.WithSchema(schema), ipc.WithAllocator(mem))
+	} else {
+		fw, err := ipc.NewFileWriter(w, ipc.WithSchema(schema), ipc.WithAllocator(mem))
+		if err != nil {
+			return fmt.Errorf("arrowipc: %w", err)
+		}
+
+		iw = fw
+	}
+
+	for start := 0; start < len(entries); start += cfg.batchSize {
+		rec := newBatch(schema, mem, levels, entries, fields, start, min(start+cfg.batchSize, len(entries)))
+		err := iw.Write(rec)
+		rec.Release()
+
+		if err != nil {
+			iw.Close()
+
+			return fmt.Errorf("arrowipc: %w", err)
+		}
+	}
+
+	if err := iw.Close(); err != nil {
+		return fmt.Errorf("arrowipc: %w", err)
+	}
+
+	return nil
+}
+
+// fieldColumn is a field column to write: its value in every row, nil
+// where missing, and its type
+type fieldColumn struct {
+	values []interface{}
+	typ    FieldType
+}
+
+// arrowType returns the Arrow data type for t
+func (t FieldType) arrowType() arrow.DataType {
+	switch t {
+	case Int64:
+		return arrow.PrimitiveTypes.Int64
+	case Float64:
+		return arrow.PrimitiveTypes.Float64
+	case Bool:
+		return arrow.FixedWidthTypes.Boolean
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+// commonKeys returns the MaxDefaultFields most common field keys of
+// entries, most common first, ties by key
+func commonKeys(entries []logparser.LogEntry) []string {
+	counts := make(map[string]int)
+
+	for i := range entries {
+		for k := range entries[i].Fields {
+			counts[k]++
+		}
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	return keys[:min(len(keys), MaxDefaultFields)]
+}
+
+// columnValues returns the value of key in every row, nil where missing
+func columnValues(entries []logparser.LogEntry, key string) []interface{} {
+	values := make([]interface{}, len(entries))
+	for i := range entries {
+		values[i] = entries[i].Fields[key]
+	}
+
+	return values
+}
+
+// levelDictionary returns the distinct non-empty levels of entries in the
+// order they first appear
+func levelDictionary(entries []logparser.LogEntry, mem memory.Allocator) arrow.Array {
+	b := array.NewStringBuilder(mem)
+	defer b.Release()
+
+	seen := make(map[string]bool)
+
+	for i := range entries {
+		if l := entries[i].Level; l != "" && !seen[l] {
+			seen[l] = true
+			b.Append(l)
+		}
+	}
+
+	return b.NewArray()
+}
+
+// newBatch builds the record batch of rows start to end
+func newBatch(schema *arrow.Schema, mem memory.Allocator, levels arrow.Array,
+	entries []logparser.LogEntry, fields []fieldColumn, start, end int,
+) arrow.Record {
+	ts := array.NewTimestampBuilder(mem, timestampType)
+	lvl := array.NewDictionaryBuilderWithDict(mem, levelType, levels).(*array.BinaryDictionaryBuilder)
+	msg := array.NewStringBuilder(mem)
+
+	builders := []array.Builder{ts, lvl, msg}
+
+	for i := start; i < end; i++ {
+		e := &entries[i]
+
+		if e.Timestamp.IsZero() {
+			ts.AppendNull()
+		} else {
+			ts.Append(arrow.Timestamp(e.Timestamp.UnixNano()))
+		}
+
+		if l := e.Level; l == "" {
+			lvl.AppendNull()
+		} else {
+			// Cannot fail: the level is in the dictionary
+			_ = lvl.AppendString(l)
+		}
+
+		msg.Append(e.Message)
+	}
+
+	for _, f := range fields {
+		b := array.NewBuilder(mem, f.typ.arrowType())
+		for _, v := range f.values[start:end] {
+			appendValue(b, f.typ, v)
+		}
+
+		builders = append(builders, b)
+	}
+
+	arrays := make([]arrow.Array, len(builders))
+	for i, b := range builders {
+		arrays[i] = b.NewArray()
+		b.Release()
+	}
+
+	rec := array.NewRecord(schema, arrays, int64(end-start))
+
+	for _, a := range arrays {
+		a.Release()
+	}
+
+	return rec
+}
+
+// appendValue appends v to a field column of type t, or a null when v is
+// missing or does not convert
+func appendValue(b array.Builder, t FieldType, v interface{}) {
+	if v == nil {
+		b.AppendNull()
+
+		return
+	}
+
+	switch t {
+	case Int64:
+		if n, ok := toInt64(v); ok {
+			b.(*array.Int64Builder).Append(n)
+
+			return
+		}
+	case Float64:
+		if f, ok := toFloat64(v); ok {
+			b.(*array.Float64Builder).Append(f)
+
+			return
+		}
+	case Bool:
+		if x, ok := toBool(v); ok {
+			b.(*array.BooleanBuilder).Append(x)
+
+			return
+		}
+	default:
+		b.(*array.StringBuilder).Append(toString(v))
+
+		return
+	}
+
+	b.AppendNull()
+}
+
+// inferType returns the narrowest type holding every non-nil value
+func inferType(values []interface{}) FieldType {
+	var bools, ints, floats, others bool
+
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+
+		if _, ok := toBool(v); ok {
+			bools = true
+		} else if _, ok := toInt64(v); ok {
+			ints = true
+		} else if _, ok := toFloat64(v); ok {
+			floats = true
+		} else {
+			others = true
+		}
+	}
+
+	switch {
+	case others || bools && (ints || floats):
+		return String
+	case bools:
+		return Bool
+	case floats:
+		return Float64
+	case ints:
+		return Int64
+	default:
+		return String
+	}
+}
+
+// toInt64 converts an integer value, including whole floats and integer
+// strings
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case float64:
+		if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+			return 0, false
+		}
+
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+
+		return i, err == nil
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// toFloat64 converts a numeric value, including finite numeric strings
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+
+		return f, err == nil && !math.IsInf(f, 0) && !math.IsNaN(f)
+	default:
+		return 0, false
+	}
+}
+
+// toBool converts a boolean or the strings "true" and "false"
+func toBool(v interface{}) (bool, bool) {
+	switch b := v.(type) {
+	case bool:
+		return b, true
+	case string:
+		return b == "true", b == "true" || b == "false"
+	default:
+		return false, false
+	}
+}
+
+// toString formats a value for a utf8 column
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case json.Number:
+		return s.String()
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	case time.Time:
+		return s.Format(time.RFC3339Nano)
+	case map[string]interface{}, []interface{}:
+		if b, err := json.Marshal(s); err == nil {
+			return string(b)
+		}
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,273 @@
+package arrowipc
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	logparser "github.com/yildizm/go-logparser"
+)
+
+const testInput = `{"time":"2024-05-01T10:00:00Z","level":"info","msg":"request","status":200,"latency":12.5,"cached":true,"user":"ann"}
+{"time":"2024-05-01T10:00:01.5Z","level":"error","msg":"request failed","status":503,"latency":30,"user":{"id":7}}
+{"time":"2024-05-01T10:00:02Z","level":"info","msg":"cache warm","status":"204","latency":3,"cached":false}`
+
+// readFile reads the schema and every record of an IPC file, failing the
+// test if the file is not valid
+func readFile(t *testing.T, data []byte) (*arrow.Schema, []arrow.Record) {
+	t.Helper()
+
+	r, err := ipc.NewFileReader(bytes.NewReader(data), ipc.WithAllocator(memory.NewGoAllocator()))
+	if err != nil {
+		t.Fatalf("NewFileReader() error = %v", err)
+	}
+
+	defer r.Close()
+
+	recs := make([]arrow.Record, 0, r.NumRecords())
+
+	for i := 0; i < r.NumRecords(); i++ {
+		rec, err := r.Record(i)
+		if err != nil {
+			t.Fatalf("Record(%d) error = %v", i, err)
+		}
+
+		rec.Retain()
+		recs = append(recs, rec)
+	}
+
+	t.Cleanup(func() {
+		for _, rec := range recs {
+			rec.Release()
+		}
+	})
+
+	return r.Schema(), recs
+}
+
+// column returns the named column of rec
+func column(t *testing.T, rec arrow.Record, name string) arrow.Array {
+	t.Helper()
+
+	idx := rec.Schema().FieldIndices(name)
+	if len(idx) != 1 {
+		t.Fatalf("schema %v has no column %q", rec.Schema(), name)
+	}
+
+	return rec.Column(idx[0])
+}
+
+func TestWriteArrowRoundTrip(t *testing.T) {
+	entries, err := logparser.New().ParseString(testInput)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	entries = append(entries, logparser.LogEntry{Message: "no timestamp or level"})
+
+	var buf bytes.Buffer
+	if err := WriteArrow(&buf, entries, WithFields("status", "latency", "cached", "user", "missing")); err != nil {
+		t.Fatalf("WriteArrow() error = %v", err)
+	}
+
+	schema, recs := readFile(t, buf.Bytes())
+
+	wantTypes := map[string]string{
+		"timestamp": "timestamp[ns, tz=UTC]",
+		"level":     "dictionary<values=utf8, indices=int32, ordered=false>",
+		"message":   "utf8",
+		"status":    "int64",
+		"latency":   "float64",
+		"cached":    "bool",
+		"user":      "utf8",
+		"missing":   "utf8",
+	}
+
+	if schema.NumFields() != len(wantTypes) {
+		t.Fatalf("schema = %v, want %d columns", schema, len(wantTypes))
+	}
+
+	for _, f := range schema.Fields() {
+		if got := f.Type.String(); got != wantTypes[f.Name] {
+			t.Errorf("column %s type = %s, want %s", f.Name, got, wantTypes[f.Name])
+		}
+	}
+
+	if len(recs) != 1 || recs[0].NumRows() != 4 {
+		t.Fatalf("got %d records, want 1 of 4 rows", len(recs))
+	}
+
+	rec := recs[0]
+
+	ts := column(t, rec, "timestamp").(*array.Timestamp)
+	if got := ts.Value(1).ToTime(arrow.Nanosecond); !got.Equal(time.Date(2024, 5, 1, 10, 0, 1, 5e8, time.UTC)) {
+		t.Errorf("timestamp[1] = %v", got)
+	}
+
+	if !ts.IsNull(3) {
+		t.Errorf("timestamp[3] = %v, want null for the zero time", ts.Value(3))
+	}
+
+	if lvl := column(t, rec, "level"); !lvl.IsNull(3) {
+		t.Errorf("level[3] = %v, want null for an empty level", lvl)
+	}
+
+	lvl := column(t, rec, "level").(*array.Dictionary)
+	dict := lvl.Dictionary().(*array.String)
+
+	if dict.Len() != 2 || dict.Value(lvl.GetValueIndex(1)) != "ERROR" || dict.Value(lvl.GetValueIndex(2)) != "INFO" {
+		t.Errorf("level = %v", lvl)
+	}
+
+	if msg := column(t, rec, "message").(*array.String); msg.Value(2) != "cache warm" {
+		t.Errorf("message[2] = %q", msg.Value(2))
+	}
+
+	status := column(t, rec, "status").(*array.Int64)
+	if status.Value(0) != 200 || status.Value(2) != 204 || !status.IsNull(3) {
+		t.Errorf("status = %v", status)
+	}
+
+	if latency := column(t, rec, "latency").(*array.Float64); latency.Value(0) != 12.5 || latency.Value(1) != 30 {
+		t.Errorf("latency = %v", latency)
+	}
+
+	cached := column(t, rec, "cached").(*array.Boolean)
+	if !cached.Value(0) || !cached.IsNull(1) || cached.Value(2) {
+		t.Errorf("cached = %v", cached)
+	}
+
+	if user := column(t, rec, "user").(*array.String); user.Value(0) != "ann" || user.Value(1) != `{"id":7}` {
+		t.Errorf("user = %v", user)
+	}
+
+	if missing := column(t, rec, "missing"); missing.NullN() != 4 {
+		t.Errorf("missing has %d nulls, want 4", missing.NullN())
+	}
+}
+
+func TestWriteArrowFieldTypeOverride(t *testing.T) {
+	entries := []logparser.LogEntry{
+		{Message: "a", Fields: map[string]interface{}{"zip": "02134", "code": 7.0, "ratio": "1"}},
+		{Message: "b", Fields: map[string]interface{}{"zip": "10001", "code": "x", "ratio": 2.5}},
+	}
+
+	var buf bytes.Buffer
+
+	err := WriteArrow(&buf, entries, WithFields("zip", "code", "ratio"),
+		WithFieldType("zip", String), WithFieldType("code", Int64))
+	if err != nil {
+		t.Fatalf("WriteArrow() error = %v", err)
+	}
+
+	_, recs := readFile(t, buf.Bytes())
+	rec := recs[0]
+
+	if zip := column(t, rec, "zip").(*array.String); zip.Value(0) != "02134" {
+		t.Errorf("zip = %v, want leading zero kept", zip)
+	}
+
+	code := column(t, rec, "code").(*array.Int64)
+	if code.Value(0) != 7 || !code.IsNull(1) {
+		t.Errorf("code = %v, want 7 and null", code)
+	}
+
+	if ratio := column(t, rec, "ratio").(*array.Float64); ratio.Value(0) != 1 || ratio.Value(1) != 2.5 {
+		t.Errorf("ratio = %v", ratio)
+	}
+}
+
+func TestWriteArrowBatches(t *testing.T) {
+	levels := []string{"INFO", "WARN", "ERROR"}
+	entries := make([]logparser.LogEntry, 10)
+
+	for i := range entries {
+		entries[i] = logparser.LogEntry{
+			Timestamp: time.Unix(int64(i), 0),
+			Level:     levels[i%len(levels)],
+			Message:   "m",
+			Fields:    map[string]interface{}{"n": float64(i)},
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArrow(&buf, entries, WithBatchSize(4)); err != nil {
+		t.Fatalf("WriteArrow() error = %v", err)
+	}
+
+	_, recs := readFile(t, buf.Bytes())
+
+	if len(recs) != 3 || recs[2].NumRows() != 2 {
+		t.Fatalf("got %d records, want batches of 4, 4, 2", len(recs))
+	}
+
+	last := recs[2]
+	if n := column(t, last, "n").(*array.Int64); n.Value(1) != 9 {
+		t.Errorf("n = %v", n)
+	}
+
+	lvl := column(t, last, "level").(*array.Dictionary)
+	if got := lvl.Dictionary().(*array.String).Value(lvl.GetValueIndex(0)); got != "ERROR" {
+		t.Errorf("level[8] = %q, want ERROR", got)
+	}
+}
+
+func TestWriteArrowStream(t *testing.T) {
+	entries := []logparser.LogEntry{{Level: "INFO", Message: "one"}, {Message: "two"}}
+
+	var buf bytes.Buffer
+	if err := WriteArrow(&buf, entries, WithStream(true)); err != nil {
+		t.Fatalf("WriteArrow() error = %v", err)
+	}
+
+	r, err := ipc.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	defer r.Release()
+
+	if !r.Next() {
+		t.Fatalf("no record: %v", r.Err())
+	}
+
+	rec := r.Record()
+	if rec.NumCols() != 3 || rec.NumRows() != 2 {
+		t.Fatalf("record = %v", rec)
+	}
+
+	if lvl := column(t, rec, "level"); !lvl.IsNull(1) {
+		t.Errorf("level[1] = %v, want null", lvl)
+	}
+}
+
+func TestWriteArrowEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteArrow(&buf, nil); err != nil {
+		t.Fatalf("WriteArrow() error = %v", err)
+	}
+
+	schema, recs := readFile(t, buf.Bytes())
+	if schema.NumFields() != 3 || len(recs) != 0 {
+		t.Errorf("schema = %v with %d records", schema, len(recs))
+	}
+}
+
+func TestWriteArrowStandardKeyClash(t *testing.T) {
+	entries := []logparser.LogEntry{{Message: "m", Fields: map[string]interface{}{"message": "inner"}}}
+
+	var buf bytes.Buffer
+	if err := WriteArrow(&buf, entries); err != nil {
+		t.Fatalf("WriteArrow() error = %v", err)
+	}
+
+	_, recs := readFile(t, buf.Bytes())
+
+	if got := column(t, recs[0], "fields.message").(*array.String).Value(0); got != "inner" {
+		t.Errorf("fields.message = %q", got)
+	}
+}
@@ -0,0 +1,390 @@
+package logparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yildizm/go-logparser/internal/testgen"
+)
+
+// corpusLines is the number of lines in each generated benchmark corpus
+const corpusLines = 100000
+
+// benchmarkCorpora lists the generated corpora and their explicit formats
+func benchmarkCorpora() []struct {
+	name   string
+	format Format
+} {
+	return []struct {
+		name   string
+		format Format
+	}{
+		{testgen.JSON, FormatJSON},
+		{testgen.Logfmt, FormatLogfmt},
+		{testgen.Text, FormatText},
+		{testgen.Mixed, FormatText},
+	}
+}
+
+// reportLineRate reports throughput in lines per second
+func reportLineRate(b *testing.B, lines int) {
+	b.Helper()
+
+	if secs := b.Elapsed().Seconds(); secs > 0 {
+		b.ReportMetric(float64(lines*b.N)/secs, "lines/s")
+	}
+}
+
+func BenchmarkCorpusParse(b *testing.B) {
+	for _, corpus := range benchmarkCorpora() {
+		data := testgen.Generate(corpus.name, corpusLines, testgen.DefaultSeed)
+
+		for _, mode := range []string{"auto", "explicit"} {
+			parser := New()
+			if mode == "explicit" {
+				parser = NewWithFormat(corpus.format)
+			}
+
+			b.Run(corpus.name+"/"+mode, func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+
+				for range b.N {
+					if _, err := parser.Parse(bytes.NewReader(data)); err != nil {
+						b.Fatal(err)
+					}
+				}
+
+				reportLineRate(b, corpusLines)
+			})
+		}
+	}
+}
+
+func BenchmarkCorpusParseString(b *testing.B) {
+	for _, corpus := range benchmarkCorpora() {
+		data := string(testgen.Generate(corpus.name, corpusLines, testgen.DefaultSeed))
+		parser := New()
+
+		b.Run(corpus.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+
+			for range b.N {
+				if _, err := parser.ParseString(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			reportLineRate(b, corpusLines)
+		})
+	}
+}
+
+// BenchmarkParseStringMemory reports the peak heap ParseString needs beyond
+// the input itself, relative to the input size. Lines are cut from the
+// input in place, so the overhead is the parsed entries alone. The CR case
+// ends lines with lone carriage returns, which must take no longer than
+// newlines: finding each line may not scan the rest of the input.
+func BenchmarkParseStringMemory(b *testing.B) {
+	lf := string(testgen.Generate(testgen.Logfmt, 10*corpusLines, testgen.DefaultSeed))
+	parser := NewWithFormat(FormatLogfmt)
+
+	for _, input := range []struct{ name, data string }{
+		{"LF", lf},
+		{"CR", strings.ReplaceAll(lf, "\n", "\r")},
+	} {
+		b.Run(input.name, func(b *testing.B) {
+			data := input.data
+
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			var peak, base uint64
+
+			for range b.N {
+				runtime.GC()
+
+				var stats runtime.MemStats
+
+				runtime.ReadMemStats(&stats)
+				base = stats.HeapInuse
+
+				stop := sampleHeap(&peak)
+				entries, err := parser.ParseString(data)
+
+				stop()
+
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				runtime.KeepAlive(entries)
+			}
+
+			b.ReportMetric(float64(peak)/float64(len(data)), "peak/input")
+			b.ReportMetric(float64(peak-min(base, peak))/float64(len(data)), "extra/input")
+		})
+	}
+}
+
+// columnsLines sets the corpus size of BenchmarkColumnsMemory. The README
+// numbers come from the default, 3M lines, whose []LogEntry result fits in
+// about 2GB; -columns.lines=10000000 runs the 10M-line corpus ParseColumns
+// was designed for, which needs over 6GB for it.
+var columnsLines = flag.Int("columns.lines", 30*corpusLines, "lines in the BenchmarkColumnsMemory corpus")
+
+// BenchmarkColumnsMemory compares the heap retained by Parse and by
+// ParseColumns, and the time a full garbage collection takes while the
+// result is live, which grows with the number of pointers to scan
+func BenchmarkColumnsMemory(b *testing.B) {
+	data := testgen.Generate(testgen.Logfmt, *columnsLines, testgen.DefaultSeed)
+	parser := NewWithFormat(FormatLogfmt)
+
+	parse := map[string]func() (interface{}, error){
+		"entries": func() (interface{}, error) { return parser.Parse(bytes.NewReader(data)) },
+		"columns": func() (interface{}, error) { return ParseColumns(parser, bytes.NewReader(data)) },
+	}
+
+	for _, name := range []string{"entries", "columns"} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			var retained, gcTime, pauses uint64
+
+			for range b.N {
+				var before, after runtime.MemStats
+
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				result, err := parse[name]()
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				start := time.Now()
+
+				runtime.GC()
+
+				gcTime += uint64(time.Since(start))
+
+				runtime.ReadMemStats(&after)
+				runtime.KeepAlive(result)
+
+				retained += after.HeapAlloc - min(before.HeapAlloc, after.HeapAlloc)
+				pauses += after.PauseTotalNs - before.PauseTotalNs
+			}
+
+			n := float64(b.N)
+			b.ReportMetric(float64(retained)/n/float64(*columnsLines), "retained-B/line")
+			b.ReportMetric(float64(gcTime)/n/1e6, "full-gc-ms")
+			b.ReportMetric(float64(pauses)/n/1e3, "gc-pause-us")
+		})
+	}
+}
+
+// recurringKeyLines returns n JSON lines that share 20 field keys
+func recurringKeyLines(n int) []string {
+	lines := make([]string, n)
+
+	for i := range lines {
+		var b strings.Builder
+
+		fmt.Fprintf(&b, `{"time":"2024-01-02T15:04:%02dZ","level":"info","msg":"request %d"`, i%60, i)
+
+		for k := range 17 {
+			fmt.Fprintf(&b, `,"key_%02d":"value %d"`, k, (i+k)%7)
+		}
+
+		b.WriteString("}")
+		lines[i] = b.String()
+	}
+
+	return lines
+}
+
+// BenchmarkRecurringKeys compares decoding JSON lines with encoding/json,
+// as the parser did before keys were interned, against parseJSONLine
+func BenchmarkRecurringKeys(b *testing.B) {
+	lines := recurringKeyLines(1000)
+	cfg := &config{}
+
+	b.Run("encoding_json", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := range b.N {
+			var raw map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[i%len(lines)]), &raw); err != nil {
+				b.Fatal(err)
+			}
+
+			_ = jsonEntry(raw, cfg)
+		}
+	})
+
+	b.Run("interned", func(b *testing.B) {
+		names := make(interner)
+
+		b.ReportAllocs()
+
+		for i := range b.N {
+			if _, err := parseJSONLine(lines[i%len(lines)], cfg, names); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDetectFormat(b *testing.B) {
+	for _, corpus := range benchmarkCorpora() {
+		data := string(testgen.Generate(corpus.name, detectionSampleSize, testgen.DefaultSeed))
+		samples := strings.Split(strings.TrimSpace(data), "\n")
+		d := NewDetector()
+
+		b.Run(corpus.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for range b.N {
+				_ = d.Detect(samples)
+			}
+		})
+	}
+}
+
+// BenchmarkParseSmallPayloads parses a few lines per call, as a service
+// handling one request body per Parse does
+func BenchmarkParseSmallPayloads(b *testing.B) {
+	payload := []byte(`{"time":"2024-05-01T10:00:00Z","level":"info","msg":"request","status":200}` + "\n" +
+		`{"time":"2024-05-01T10:00:01Z","level":"warn","msg":"slow request","status":200}` + "\n")
+	p := NewWithFormat(FormatJSON)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+
+	for range b.N {
+		if _, err := p.Parse(bytes.NewReader(payload)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseHugeLine parses one line of several megabytes, which needs
+// a raised line size limit
+func BenchmarkParseHugeLine(b *testing.B) {
+	payload := []byte(`{"level":"info","msg":"dump","data":"` + strings.Repeat("x", 4<<20) + `"}` + "\n")
+	p := NewWithFormat(FormatJSON, WithMaxLineSize(8<<20))
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+
+	for range b.N {
+		if _, err := p.Parse(bytes.NewReader(payload)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFallbackFormatsClean parses clean JSON with and without fallback
+// formats, which must cost nothing on lines that parse
+func BenchmarkFallbackFormatsClean(b *testing.B) {
+	data := testgen.Generate(testgen.JSON, corpusLines, testgen.DefaultSeed)
+
+	parsers := map[string]Parser{
+		"strict":   NewWithFormat(FormatJSON),
+		"fallback": NewWithFormat(FormatJSON, WithFallbackFormats(FormatLogfmt, FormatText)),
+	}
+
+	for _, name := range []string{"strict", "fallback"} {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+
+			for range b.N {
+				if _, err := parsers[name].Parse(bytes.NewReader(data)); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			reportLineRate(b, corpusLines)
+		})
+	}
+}
+
+// BenchmarkCIDREnrichment looks up addresses in a table of 10k IPv4 and
+// IPv6 prefixes, as WithCIDREnrichment does once per entry
+func BenchmarkCIDREnrichment(b *testing.B) {
+	rng := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // deterministic benchmark data
+	table := make(map[string]interface{}, 10000)
+
+	for len(table) < 9000 {
+		table[fmt.Sprintf("%d.%d.%d.0/%d", rng.IntN(224), rng.IntN(256), rng.IntN(256), 8+rng.IntN(17))] = "v4"
+	}
+
+	for len(table) < 10000 {
+		table[fmt.Sprintf("2001:db8:%x:%x::/%d", rng.IntN(1<<16), rng.IntN(1<<16), 32+rng.IntN(33))] = "v6"
+	}
+
+	cfg := &config{}
+	WithCIDREnrichment("ip", table, "dc")(cfg)
+
+	entries := make([]LogEntry, 1024)
+	for i := range entries {
+		ip := fmt.Sprintf("%d.%d.%d.%d", rng.IntN(224), rng.IntN(256), rng.IntN(256), rng.IntN(256))
+		if i%10 == 0 {
+			ip = fmt.Sprintf("2001:db8:%x:%x::1", rng.IntN(1<<16), rng.IntN(1<<16))
+		}
+
+		entries[i] = LogEntry{Fields: map[string]interface{}{"ip": ip}}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := range b.N {
+		cfg.enrich(&entries[i%len(entries)])
+	}
+}
+
+// TestAllocsPerLine guards against allocation regressions in the line
+// parsers. Budgets sit just above the current counts; lower them when an
+// optimization lands.
+func TestAllocsPerLine(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts differ under the race detector")
+	}
+
+	tests := []struct {
+		format Format
+		line   string
+		budget float64
+	}{
+		{FormatJSON, `{"timestamp":"2024-01-02T15:04:05Z","level":"ERROR","message":"Database connection failed","service":"api"}`, 12},
+		{FormatLogfmt, `time=2024-01-02T15:04:05Z level=error msg="Connection timeout" service=worker duration=1.23`, 11},
+		{FormatText, `2024-01-02 15:04:05 [ERROR] Failed to connect to database`, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			parse := lineParserFor(tt.format, &config{})
+
+			allocs := testing.AllocsPerRun(100, func() {
+				if _, err := parse(tt.line); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			if allocs > tt.budget {
+				t.Errorf("%s line parse allocates %.0f times, budget %.0f", tt.format, allocs, tt.budget)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package logparser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// switchingLog writes blocks of JSON, logfmt, and JSON lines, each message
+// naming its block and position
+func switchingLog(block int) string {
+	var b strings.Builder
+
+	for i := range block {
+		fmt.Fprintf(&b, `{"time":"2024-01-02T15:04:05Z","level":"info","msg":"json-a %d","service":"api"}`+"\n", i)
+	}
+
+	for i := range block {
+		fmt.Fprintf(&b, `time=2024-01-02T15:04:06Z level=warn msg="logfmt %d" service=worker`+"\n", i)
+	}
+
+	for i := range block {
+		fmt.Fprintf(&b, `{"time":"2024-01-02T15:04:07Z","level":"error","msg":"json-b %d","service":"api"}`+"\n", i)
+	}
+
+	return b.String()
+}
+
+func TestBlockDetection(t *testing.T) {
+	for _, block := range []int{25, 4} {
+		t.Run(fmt.Sprintf("blocks of %d", block), func(t *testing.T) {
+			parser := New(WithBlockDetection())
+
+			entries, stats, err := ParseWithStats(parser, strings.NewReader(switchingLog(block)))
+			if err != nil {
+				t.Fatalf("ParseWithStats() error = %v", err)
+			}
+
+			if len(entries) != 3*block {
+				t.Fatalf("got %d entries, want %d", len(entries), 3*block)
+			}
+
+			for i, e := range entries {
+				var want, level, service string
+
+				switch {
+				case i < block:
+					want, level, service = fmt.Sprintf("json-a %d", i), "INFO", "api"
+				case i < 2*block:
+					want, level, service = fmt.Sprintf("logfmt %d", i-block), "WARN", "worker"
+				default:
+					want, level, service = fmt.Sprintf("json-b %d", i-2*block), "ERROR", "api"
+				}
+
+				if e.Message != want || e.Level != level || e.Service != service || len(e.Fields) != 0 {
+					t.Errorf("entry %d mis-parsed: %+v, want %s %q", i, e, level, want)
+				}
+			}
+
+			if stats.Detections > 3 {
+				t.Errorf("%d detection passes, want at most 3", stats.Detections)
+			}
+
+			want := []string{
+				fmt.Sprintf("line %d: json→logfmt", block+1),
+				fmt.Sprintf("line %d: logfmt→json", 2*block+1),
+			}
+
+			if got := fmt.Sprint(stats.FormatSwitches); got != fmt.Sprint(want) {
+				t.Errorf("FormatSwitches = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestBlockDetectionStrayLine(t *testing.T) {
+	log := strings.Repeat(`{"level":"info","msg":"ok"}`+"\n", 12) +
+		"panic: boom\n" +
+		strings.Repeat(`{"level":"info","msg":"ok"}`+"\n", 12)
+
+	entries, stats, err := ParseWithStats(New(WithBlockDetection()), strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	if len(entries) != 25 || entries[12].Message != "panic: boom" {
+		t.Fatalf("got %d entries, stray line %q", len(entries), entries[12].Message)
+	}
+
+	want := "[line 13: json→text line 14: text→json]"
+	if got := fmt.Sprint(stats.FormatSwitches); got != want {
+		t.Errorf("FormatSwitches = %v, want %v", got, want)
+	}
+}
+
+func TestWithoutBlockDetection(t *testing.T) {
+	_, stats, _ := ParseWithStats(New(WithSkipInvalid(true)), strings.NewReader(switchingLog(25)))
+
+	if stats.Detections != 1 || len(stats.FormatSwitches) != 0 {
+		t.Errorf("default mode: %d detections, switches %v", stats.Detections, stats.FormatSwitches)
+	}
+}
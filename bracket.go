@@ -0,0 +1,150 @@
+package logparser
+
+import "strings"
+
+// WithBracketedFields extracts a trailing bracketed or parenthesized
+// key=value section from text messages, as in
+//
+//	device check failed [dev=eth0, speed=1000, duplex="full, auto"]
+//
+// The pairs are stored in Fields, without replacing fields the line's
+// pattern already extracted, and the section is trimmed from Message. The
+// section must follow a space or start the message, and every
+// comma-separated item in it must be a key=value pair, so prose such as
+// "[foo]" or "(see docs)" is left alone. Values may be double-quoted, with
+// logfmt escapes such as \", or single-quoted; quoted values may contain
+// commas and brackets.
+func WithBracketedFields(enable bool) Option {
+	return func(c *config) {
+		c.bracketFields = enable
+	}
+}
+
+// bracketPair is a key and value read from a bracketed section
+type bracketPair struct {
+	key, val string
+}
+
+// extractBracketFields moves a trailing key=value section of the message
+// into the entry's fields
+func (c *config) extractBracketFields(entry *LogEntry) {
+	msg := strings.TrimRight(entry.Message, " \t")
+
+	var open byte
+
+	switch {
+	case strings.HasSuffix(msg, "]"):
+		open = '['
+	case strings.HasSuffix(msg, ")"):
+		open = '('
+	default:
+		return
+	}
+
+	// The last opening bracket that starts a valid section wins, so
+	// brackets inside quoted values are passed over
+	for i := strings.LastIndexByte(msg, open); i >= 0; i = strings.LastIndexByte(msg[:i], open) {
+		if i > 0 && msg[i-1] != ' ' && msg[i-1] != '\t' {
+			continue
+		}
+
+		pairs, ok := parseBracketSection(msg[i+1 : len(msg)-1])
+		if !ok {
+			continue
+		}
+
+		for _, p := range pairs {
+			if _, exists := entry.Fields[p.key]; !exists {
+				c.setField(entry, p.key, p.val)
+			}
+		}
+
+		entry.Message = strings.TrimRight(msg[:i], " \t")
+
+		return
+	}
+}
+
+// parseBracketSection parses comma-separated key=value pairs. It fails
+// unless the whole section is made of them.
+func parseBracketSection(s string) ([]bracketPair, bool) {
+	var pairs []bracketPair
+
+	i := 0
+
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ',' && s[i] > ' ' && !strings.ContainsRune(`"'[]()`, rune(s[i])) {
+			i++
+		}
+
+		if i == start || i == len(s) || s[i] != '=' {
+			return nil, false
+		}
+
+		key := s[start:i]
+		i++ // Skip '='
+
+		var (
+			val string
+			ok  bool
+		)
+
+		if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+			val, i, ok = scanBracketQuoted(s, i)
+			if !ok {
+				return nil, false
+			}
+		} else {
+			start = i
+			for i < len(s) && s[i] != ',' && s[i] != '"' && s[i] != '\'' {
+				i++
+			}
+
+			val = strings.TrimRight(s[start:i], " \t")
+		}
+
+		pairs = append(pairs, bracketPair{key, val})
+
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+
+		switch {
+		case i == len(s):
+			return pairs, true
+		case s[i] != ',':
+			return nil, false
+		}
+
+		i++ // Skip ','
+	}
+}
+
+// scanBracketQuoted reads the quoted value starting at s[i] and returns it
+// with the offset past the closing quote. Double-quoted values are decoded
+// like logfmt ones; single-quoted values are taken as written.
+func scanBracketQuoted(s string, i int) (string, int, bool) {
+	quote := s[i]
+	escaped := false
+
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case s[j] == '\\' && quote == '"':
+			escaped = true
+			j++
+		case s[j] == quote:
+			if quote == '\'' {
+				return s[i+1 : j], j + 1, true
+			}
+
+			return unquoteLogfmt(s[i+1:j], escaped), j + 1, true
+		}
+	}
+
+	return "", 0, false
+}
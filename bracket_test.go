@@ -0,0 +1,74 @@
+package logparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBracketedFieldsFixture(t *testing.T) {
+	entries, err := ParseFile(New(WithBracketedFields(true)), "testdata/appliance.log")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	want := []struct {
+		message string
+		fields  map[string]interface{}
+	}{
+		{"device check failed", map[string]interface{}{"dev": "eth0", "speed": "1000", "duplex": "full, auto"}},
+		{"link up", map[string]interface{}{"dev": "eth1", "speed": "10000"}},
+		{"config rejected", map[string]interface{}{
+			"file": "/etc/app [prod].conf", "reason": `unexpected "]", line 4`, "user": `admin "root"`,
+		}},
+		{"fan [2] speed normal", map[string]interface{}{"fan": "2", "rpm": "3200", "state": "ok"}},
+
+		// Prose and malformed sections stay in the message
+		{"retrying [foo]", nil},
+		{"cache warm (took 5 ms)", nil},
+		{"see [docs, page=4]", nil},
+		{`unterminated [note="open, x=1]`, nil},
+		{"array[idx=3]", nil},
+		{"empty value", map[string]interface{}{"key": "", "other": "1"}},
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for i, w := range want {
+		fields := entries[i].Fields
+		if w.fields == nil {
+			w.fields = map[string]interface{}{}
+		}
+
+		if entries[i].Message != w.message || !reflect.DeepEqual(fields, w.fields) {
+			t.Errorf("entry %d = %q %v, want %q %v", i, entries[i].Message, fields, w.message, w.fields)
+		}
+	}
+}
+
+func TestBracketedFieldsOff(t *testing.T) {
+	line := "2024-01-02 15:04:05 [WARN] device check failed [dev=eth0]"
+
+	entry, err := ParseLine(line, FormatText)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Message != "device check failed [dev=eth0]" || len(entry.Fields) != 0 {
+		t.Errorf("without the option got %q %v", entry.Message, entry.Fields)
+	}
+}
+
+func TestBracketedFieldsKeepPatternFields(t *testing.T) {
+	// The syslog pattern extracts pid; the section does not replace it
+	entries, err := New(WithBracketedFields(true)).ParseString("Jan  2 15:04:05 gw ifmgr[42]: link flap (pid=7, port=3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{"hostname": "gw", "process": "ifmgr", "pid": "42", "port": "3"}
+	if got := entries[0]; got.Message != "link flap" || !reflect.DeepEqual(got.Fields, want) {
+		t.Errorf("got %q %v, want %v", got.Message, got.Fields, want)
+	}
+}
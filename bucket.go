@@ -0,0 +1,236 @@
+package logparser
+
+import (
+	"time"
+)
+
+// BucketUnit selects calendar buckets, whose length varies, instead of a
+// fixed duration
+type BucketUnit int
+
+const (
+	// BucketFixed buckets are Buckets.Size long
+	BucketFixed BucketUnit = iota
+	// BucketDay buckets run from midnight to midnight, so they are 23 or
+	// 25 hours long on daylight saving time changes
+	BucketDay
+	// BucketWeek buckets start on Monday at midnight
+	BucketWeek
+	// BucketMonth buckets start on the first of the month at midnight
+	BucketMonth
+)
+
+// Buckets describes how time is cut into buckets: their size, the wall
+// clock their boundaries are aligned to, and an offset from the aligned
+// boundaries. The zero value is one-minute buckets aligned in UTC, as
+// time.Time.Truncate aligns them.
+type Buckets struct {
+	// Size is the length of BucketFixed buckets (default one minute).
+	// Boundaries fall on multiples of Size since midnight, January 1
+	// 1970, on the Location wall clock.
+	Size time.Duration
+	// Unit selects calendar buckets, for which Size is ignored
+	Unit BucketUnit
+	// Location is the time zone whose wall clock boundaries are computed
+	// on (default UTC)
+	Location *time.Location
+	// Offset moves every boundary later, such as 30*time.Second for
+	// minute buckets starting at :30, or 6*time.Hour for days starting at
+	// 06:00; negative offsets move them earlier
+	Offset time.Duration
+}
+
+// HistogramBucket counts the entries in one bucket
+type HistogramBucket struct {
+	Start  time.Time      `json:"start"`
+	End    time.Time      `json:"end"` // Start of the next bucket
+	Count  int            `json:"count"`
+	Levels map[string]int `json:"levels"`
+}
+
+// Floor returns the start of the bucket holding t, in Location. Fixed
+// buckets are aligned on the wall clock at the offset in effect at their
+// start; when an offset change falls inside a bucket, as when clocks go
+// forward, that bucket stretches to the next boundary on the new wall
+// clock, so buckets never overlap and always cover the timeline.
+func (b Buckets) Floor(t time.Time) time.Time {
+	b = b.withDefaults()
+	t = t.In(b.Location)
+
+	if b.Unit != BucketFixed {
+		anchor := b.calendarAnchor(t)
+
+		start := b.calendarBoundary(anchor)
+		for start.After(t) {
+			anchor = b.addUnit(anchor, -1)
+			start = b.calendarBoundary(anchor)
+		}
+
+		return start
+	}
+
+	for {
+		_, offset := t.Zone()
+		start := t.Add(-b.fixedRemainder(t, offset))
+
+		// A boundary on the wall clock of t is only one if that clock
+		// was already in effect; otherwise look before the change
+		zoneStart, _ := t.ZoneBounds()
+		if zoneStart.IsZero() || !start.Before(zoneStart) {
+			return start
+		}
+
+		t = zoneStart.Add(-1)
+	}
+}
+
+// Next returns the start of the bucket after the one starting at start,
+// which Floor returned
+func (b Buckets) Next(start time.Time) time.Time {
+	b = b.withDefaults()
+	start = start.In(b.Location)
+
+	if b.Unit != BucketFixed {
+		anchor := b.addUnit(b.calendarAnchor(start), 1)
+
+		next := b.calendarBoundary(anchor)
+		for !next.After(start) {
+			anchor = b.addUnit(anchor, 1)
+			next = b.calendarBoundary(anchor)
+		}
+
+		return next
+	}
+
+	next := start.Add(b.Size)
+
+	// Past an offset change, the next boundary is the first one on the
+	// new wall clock
+	if _, zoneEnd := start.ZoneBounds(); !zoneEnd.IsZero() && !next.Before(zoneEnd) {
+		_, offset := zoneEnd.Zone()
+		if rem := b.fixedRemainder(zoneEnd, offset); rem > 0 {
+			return zoneEnd.Add(b.Size - rem)
+		}
+
+		return zoneEnd
+	}
+
+	return next
+}
+
+// Histogram counts entries, in total and by level, per bucket from the
+// bucket of the earliest timestamp to that of the latest, including empty
+// buckets between them. Entries without a timestamp are ignored; with
+// none left the result is empty.
+func Histogram(entries []LogEntry, b Buckets) []HistogramBucket {
+	var first, last time.Time
+
+	for i := range entries {
+		ts := entries[i].Timestamp
+		if ts.IsZero() {
+			continue
+		}
+
+		if first.IsZero() || ts.Before(first) {
+			first = ts
+		}
+
+		if ts.After(last) {
+			last = ts
+		}
+	}
+
+	out := []HistogramBucket{}
+	if first.IsZero() {
+		return out
+	}
+
+	index := make(map[int64]int)
+
+	for start := b.Floor(first); !start.After(last); {
+		next := b.Next(start)
+		index[start.UnixNano()] = len(out)
+		out = append(out, HistogramBucket{Start: start, End: next, Levels: make(map[string]int)})
+		start = next
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		if e.Timestamp.IsZero() {
+			continue
+		}
+
+		h := &out[index[b.Floor(e.Timestamp).UnixNano()]]
+		h.Count++
+		h.Levels[e.Level]++
+	}
+
+	return out
+}
+
+// withDefaults fills in the default size and location
+func (b Buckets) withDefaults() Buckets {
+	if b.Size <= 0 {
+		b.Size = time.Minute
+	}
+
+	if b.Location == nil {
+		b.Location = time.UTC
+	}
+
+	return b
+}
+
+// fixedRemainder returns how far t, read on a wall clock offset seconds
+// east of UTC, is past the last fixed boundary
+func (b Buckets) fixedRemainder(t time.Time, offset int) time.Duration {
+	wall := time.Duration(t.UnixNano()) + time.Duration(offset)*time.Second - b.Offset
+
+	rem := wall % b.Size
+	if rem < 0 {
+		rem += b.Size
+	}
+
+	return rem
+}
+
+// calendarAnchor returns the first day of the calendar unit holding t on
+// the wall clock, less the offset, as a UTC date
+func (b Buckets) calendarAnchor(t time.Time) time.Time {
+	y, m, d := t.Date()
+	hh, mm, ss := t.Clock()
+	wall := time.Date(y, m, d, hh, mm, ss, t.Nanosecond(), time.UTC).Add(-b.Offset)
+
+	y, m, d = wall.Date()
+
+	switch b.Unit {
+	case BucketWeek:
+		d -= (int(wall.Weekday()) + 6) % 7
+	case BucketMonth:
+		d = 1
+	}
+
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// addUnit moves an anchor by n calendar units
+func (b Buckets) addUnit(anchor time.Time, n int) time.Time {
+	switch b.Unit {
+	case BucketWeek:
+		return anchor.AddDate(0, 0, 7*n)
+	case BucketMonth:
+		return anchor.AddDate(0, n, 0)
+	default:
+		return anchor.AddDate(0, 0, n)
+	}
+}
+
+// calendarBoundary returns the bucket start for an anchor: its midnight
+// plus the offset, on the Location wall clock
+func (b Buckets) calendarBoundary(anchor time.Time) time.Time {
+	wall := anchor.Add(b.Offset)
+	y, m, d := wall.Date()
+	hh, mm, ss := wall.Clock()
+
+	return time.Date(y, m, d, hh, mm, ss, wall.Nanosecond(), b.Location)
+}
@@ -0,0 +1,175 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+// loadBerlin returns Europe/Berlin or skips the test
+func loadBerlin(t *testing.T) *time.Location {
+	t.Helper()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	return berlin
+}
+
+// checkBoundaries walks the buckets from the one holding from and
+// compares their starts, in UTC, with want
+func checkBoundaries(t *testing.T, b Buckets, from time.Time, want []string) {
+	t.Helper()
+
+	start := b.Floor(from)
+
+	for i, w := range want {
+		if got := start.UTC().Format(time.RFC3339); got != w {
+			t.Fatalf("boundary %d = %s, want %s", i, got, w)
+		}
+
+		next := b.Next(start)
+
+		// Every instant of a bucket floors to its start
+		for _, ts := range []time.Time{start, start.Add(next.Sub(start) / 2), next.Add(-time.Nanosecond)} {
+			if got := b.Floor(ts); !got.Equal(start) {
+				t.Fatalf("Floor(%v) = %v, want %v", ts.UTC(), got.UTC(), start.UTC())
+			}
+		}
+
+		start = next
+	}
+}
+
+func TestBucketsCalendarDayDST(t *testing.T) {
+	berlin := loadBerlin(t)
+	day := Buckets{Unit: BucketDay, Location: berlin}
+
+	// Clocks went forward on 2024-03-31 and back on 2024-10-27
+	checkBoundaries(t, day, time.Date(2024, 3, 30, 12, 0, 0, 0, berlin), []string{
+		"2024-03-29T23:00:00Z", "2024-03-30T23:00:00Z", "2024-03-31T22:00:00Z", "2024-04-01T22:00:00Z",
+	})
+	checkBoundaries(t, day, time.Date(2024, 10, 26, 12, 0, 0, 0, berlin), []string{
+		"2024-10-25T22:00:00Z", "2024-10-26T22:00:00Z", "2024-10-27T23:00:00Z", "2024-10-28T23:00:00Z",
+	})
+
+	for _, tt := range []struct {
+		date string
+		want time.Duration
+	}{
+		{"2024-03-31", 23 * time.Hour},
+		{"2024-10-27", 25 * time.Hour},
+		{"2024-07-01", 24 * time.Hour},
+	} {
+		noon, _ := time.ParseInLocation("2006-01-02 15:04", tt.date+" 12:00", berlin)
+		start := day.Floor(noon)
+
+		if got := day.Next(start).Sub(start); got != tt.want {
+			t.Errorf("%s lasts %v, want %v", tt.date, got, tt.want)
+		}
+
+		if start.Hour() != 0 || start.Location() != berlin {
+			t.Errorf("%s starts at %v, want local midnight", tt.date, start)
+		}
+	}
+
+	// Days starting at 06:00
+	checkBoundaries(t, Buckets{Unit: BucketDay, Location: berlin, Offset: 6 * time.Hour},
+		time.Date(2024, 3, 31, 3, 0, 0, 0, berlin), []string{
+			"2024-03-30T05:00:00Z", "2024-03-31T04:00:00Z", "2024-04-01T04:00:00Z",
+		})
+}
+
+func TestBucketsFixedDST(t *testing.T) {
+	berlin := loadBerlin(t)
+
+	// Hourly buckets go 01:00, 03:00 CEST on the way forward and see
+	// 02:00 twice on the way back
+	checkBoundaries(t, Buckets{Size: time.Hour, Location: berlin}, time.Date(2024, 3, 31, 0, 30, 0, 0, berlin), []string{
+		"2024-03-30T23:00:00Z", "2024-03-31T00:00:00Z", "2024-03-31T01:00:00Z", "2024-03-31T02:00:00Z",
+	})
+	checkBoundaries(t, Buckets{Size: time.Hour, Location: berlin}, time.Date(2024, 10, 27, 1, 30, 0, 0, berlin), []string{
+		"2024-10-26T23:00:00Z", "2024-10-27T00:00:00Z", "2024-10-27T01:00:00Z", "2024-10-27T02:00:00Z",
+	})
+
+	// Two-hour buckets on even local hours: the one holding the skipped
+	// hour stretches to 04:00 CEST, and the repeated hour gets its own
+	checkBoundaries(t, Buckets{Size: 2 * time.Hour, Location: berlin}, time.Date(2024, 3, 30, 22, 0, 0, 0, berlin), []string{
+		"2024-03-30T21:00:00Z", "2024-03-30T23:00:00Z", "2024-03-31T02:00:00Z", "2024-03-31T04:00:00Z",
+	})
+	checkBoundaries(t, Buckets{Size: 2 * time.Hour, Location: berlin}, time.Date(2024, 10, 27, 0, 30, 0, 0, berlin), []string{
+		"2024-10-26T22:00:00Z", "2024-10-27T00:00:00Z", "2024-10-27T01:00:00Z", "2024-10-27T03:00:00Z",
+	})
+}
+
+func TestBucketsOffsetAndUnits(t *testing.T) {
+	ts := time.Date(2024, 3, 6, 10, 15, 45, 250_000_000, time.UTC) // A Wednesday
+
+	tests := []struct {
+		name string
+		b    Buckets
+		want string
+	}{
+		{"default minute", Buckets{}, "2024-03-06T10:15:00Z"},
+		{"minute from :30", Buckets{Offset: 30 * time.Second}, "2024-03-06T10:15:30Z"},
+		{"sub-second", Buckets{Size: 100 * time.Millisecond}, "2024-03-06T10:15:45.2Z"},
+		{"week", Buckets{Unit: BucketWeek}, "2024-03-04T00:00:00Z"},
+		{"month", Buckets{Unit: BucketMonth}, "2024-03-01T00:00:00Z"},
+		{"month from the 2nd", Buckets{Unit: BucketMonth, Offset: 24 * time.Hour}, "2024-03-02T00:00:00Z"},
+		{"negative offset", Buckets{Unit: BucketDay, Offset: -2 * time.Hour}, "2024-03-05T22:00:00Z"},
+		{"Kathmandu hours", Buckets{Size: time.Hour, Location: time.FixedZone("NPT", 5*3600+45*60)}, "2024-03-06T10:15:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.b.Floor(ts).UTC().Format(time.RFC3339Nano); got != tt.want {
+				t.Errorf("Floor() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if next := (Buckets{Unit: BucketMonth}).Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); next.Month() != 2 || next.Day() != 1 {
+		t.Errorf("Next() = %v, want February 1", next)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	berlin := loadBerlin(t)
+	entries := []LogEntry{
+		{Timestamp: time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC), Level: LevelError}, // 02:30 CET
+		{Timestamp: time.Date(2024, 10, 26, 22, 30, 0, 0, time.UTC), Level: LevelInfo}, // 00:30 CEST
+		{Timestamp: time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), Level: LevelInfo},  // 02:30 CEST
+		{Level: LevelInfo},
+	}
+
+	got := Histogram(entries, Buckets{Size: time.Hour, Location: berlin})
+	if len(got) != 4 {
+		t.Fatalf("got %d buckets, want 4", len(got))
+	}
+
+	for i, want := range []int{1, 0, 1, 1} {
+		if got[i].Count != want {
+			t.Errorf("bucket %d at %v: count %d, want %d", i, got[i].Start, got[i].Count, want)
+		}
+	}
+
+	if got[3].Levels[LevelError] != 1 || !got[3].End.Equal(got[3].Start.Add(time.Hour)) {
+		t.Errorf("last bucket = %+v", got[3])
+	}
+
+	if got := Histogram(nil, Buckets{}); got == nil || len(got) != 0 {
+		t.Errorf("Histogram(nil) = %v", got)
+	}
+
+	// Sparklines follow the same buckets
+	line := RenderSparkline(entries, 0, "", WithBuckets(Buckets{Size: time.Hour, Location: berlin}), WithASCII(true))
+	if line != "# ##" {
+		t.Errorf("RenderSparkline() = %q", line)
+	}
+
+	day := RenderSparkline(entries, 0, "error", WithBuckets(Buckets{Unit: BucketDay, Location: berlin}))
+	if day != "▇" {
+		t.Errorf("daily sparkline = %q", day)
+	}
+}
@@ -0,0 +1,226 @@
+package logparser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Line buffer sizes. Buffers start small and double up to the line size
+// limit, drawing each size from a pool so that repeated calls on small
+// inputs allocate nothing.
+const (
+	minLineBuffer     = 4 * 1024 // First buffer of every scan
+	lineBufferClasses = 9        // Pooled sizes: 4KB, 8KB, ... 1MB
+	maxEmptyReads     = 100      // Reads returning nothing before giving up, as in bufio
+)
+
+// lineBufferPools holds unused line buffers by size class; buffers above
+// the largest class are left to the garbage collector
+var lineBufferPools [lineBufferClasses]sync.Pool
+
+// WithMaxLineSize fails parsing with bufio.ErrTooLong on lines longer than
+// n bytes, not counting the line terminator, instead of BufferSize. The
+// line buffer grows as needed, so a large limit costs nothing until a long
+// line arrives. Zero or less keeps the default.
+func WithMaxLineSize(n int) Option {
+	return func(c *config) {
+		c.maxLineSize = n
+	}
+}
+
+// lineLimit returns the maximum line length
+func (c *config) lineLimit() int {
+	if c.maxLineSize > 0 {
+		return c.maxLineSize
+	}
+
+	return BufferSize
+}
+
+// lineBufferClass returns the pool index of a buffer of size n, or -1 if
+// buffers of that size are not pooled
+func lineBufferClass(n int) int {
+	for i, size := 0, minLineBuffer; i < lineBufferClasses; i, size = i+1, size*2 {
+		if n == size {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// getLineBuffer returns a buffer of n bytes, pooled when n is a size class
+func getLineBuffer(n int) []byte {
+	if i := lineBufferClass(n); i >= 0 {
+		if buf, ok := lineBufferPools[i].Get().(*[]byte); ok {
+			return *buf
+		}
+	}
+
+	return make([]byte, n)
+}
+
+// putLineBuffer returns a buffer to its pool. Buffers of other sizes, such
+// as those above the largest class, are dropped.
+func putLineBuffer(buf []byte) {
+	if i := lineBufferClass(cap(buf)); i >= 0 {
+		buf = buf[:cap(buf)]
+		lineBufferPools[i].Put(&buf)
+	}
+}
+
+// lineScanner reads lines like a bufio.Scanner with ScanLines, from
+// pooled buffers that start at minLineBuffer bytes and grow up to the line
+// size limit. It reports the offset of each line. Call release when done.
+type lineScanner struct {
+	r     io.Reader
+	buf   []byte
+	start int // Start of unread data in buf
+	end   int // End of data in buf
+	max   int // Line size limit
+	eof   bool
+	err   error
+
+	line      []byte
+	lineStart int64 // Offset of line
+	consumed  int64 // Bytes consumed, including line terminators
+}
+
+// newLineScanner returns a scanner for r, whose first byte is at offset
+func newLineScanner(r io.Reader, offset int64, limit int) *lineScanner {
+	return &lineScanner{r: r, buf: getLineBuffer(minLineBuffer), max: limit, consumed: offset}
+}
+
+// Scan advances to the next line, returning false at the end of the input
+// or on an error
+func (s *lineScanner) Scan() bool {
+	for empty := 0; ; {
+		if n, advance, ok := lineEnd(s.buf[s.start:s.end], s.eof, bytes.IndexAny); ok {
+			return s.emit(n, advance)
+		}
+
+		if s.err != nil || s.eof {
+			return false
+		}
+
+		if s.end-s.start > s.max+1 { // Too long even without a CR
+			s.err = bufio.ErrTooLong
+
+			return false
+		}
+
+		if s.fill() > 0 {
+			empty = 0
+		} else if empty++; empty == maxEmptyReads && s.err == nil && !s.eof {
+			s.err = io.ErrNoProgress
+		}
+	}
+}
+
+// emit makes the n bytes at start the current line and consumes advance
+// bytes. It fails with bufio.ErrTooLong when the
+// line exceeds the limit.
+func (s *lineScanner) emit(n, advance int) bool {
+	s.line = s.buf[s.start : s.start+n]
+	if len(s.line) > s.max {
+		s.err = bufio.ErrTooLong
+
+		return false
+	}
+
+	s.lineStart = s.consumed
+	s.consumed += int64(advance)
+	s.start += advance
+
+	return true
+}
+
+// fill reads more input, moving unread data to the front of the buffer or
+// into a buffer twice as large, up to the limit, when it is full. It
+// returns the number of bytes read.
+func (s *lineScanner) fill() int {
+	if s.start > 0 {
+		s.end = copy(s.buf, s.buf[s.start:s.end])
+		s.start = 0
+	}
+
+	if s.end == len(s.buf) {
+		// Room for the longest line and its CR LF
+		buf := getLineBuffer(min(len(s.buf)*2, s.max+2))
+		copy(buf, s.buf[:s.end])
+		putLineBuffer(s.buf)
+		s.buf = buf
+	}
+
+	n, err := s.r.Read(s.buf[s.end:])
+	s.end += n
+
+	switch {
+	case err == io.EOF:
+		s.eof = true
+	case err != nil:
+		s.err = err
+	}
+
+	return n
+}
+
+// Text returns the current line
+func (s *lineScanner) Text() string {
+	return string(s.line)
+}
+
+// Err returns the first error other than io.EOF
+func (s *lineScanner) Err() error {
+	return s.err
+}
+
+// release returns the buffer to its pool; the scanner must not be used
+// afterwards
+func (s *lineScanner) release() {
+	putLineBuffer(s.buf)
+	s.buf, s.line = nil, nil
+}
+
+// ScanLines is a bufio.SplitFunc that splits lines as every parser in this
+// package does: "\n", "\r\n", and a lone "\r", as in old Mac OS exports,
+// each end a line, and are not part of it. U+2028 and U+2029 do not end a
+// line, so JSON strings holding them stay whole. A final line without a
+// terminator is returned too, but no empty line after a final terminator.
+func ScanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	n, advance, ok := lineEnd(data, atEOF, bytes.IndexAny)
+	if !ok {
+		return 0, nil, nil
+	}
+
+	return advance, data[:n], nil
+}
+
+// lineEnd finds the first line of data, by the rules of ScanLines: it is
+// data[:n], and advance bytes take it and its terminator. ok is false when
+// data holds no complete line: when it has no terminator, or ends with a
+// "\r" that may start a "\r\n", unless atEOF. The search stops at the first
+// terminator of either kind, so input split by lone carriage returns is not
+// rescanned to its end for every line.
+func lineEnd[T string | []byte](data T, atEOF bool, indexAny func(T, string) int) (n, advance int, ok bool) {
+	i := indexAny(data, "\r\n")
+
+	switch {
+	case i >= 0 && data[i] == '\n':
+		return i, i + 1, true
+	case i >= 0 && i+1 < len(data):
+		if data[i+1] == '\n' {
+			return i, i + 2, true
+		}
+
+		return i, i + 1, true
+	case i >= 0 && atEOF:
+		return i, i + 1, true
+	case i < 0 && atEOF && len(data) > 0:
+		return len(data), len(data), true
+	default:
+		return 0, 0, false
+	}
+}
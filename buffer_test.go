@@ -0,0 +1,222 @@
+package logparser
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLineScanner(t *testing.T) {
+	long := strings.Repeat("x", 3*minLineBuffer)
+
+	tests := []struct {
+		name    string
+		input   string
+		limit   int
+		lines   []string
+		offsets []int64
+		err     error
+	}{
+		{"lines", "a\nbb\n\nccc", 10, []string{"a", "bb", "", "ccc"}, []int64{0, 2, 5, 6}, nil},
+		{"CRLF", "a\r\nb\r\n", 10, []string{"a", "b"}, []int64{0, 3}, nil},
+		{"CR", "a\rb\r\rc", 10, []string{"a", "b", "", "c"}, []int64{0, 2, 4, 5}, nil},
+		{"CR at end", "a\r", 10, []string{"a"}, []int64{0}, nil},
+		{"mixed", "a\r\n\rb\n\r\nc\r", 10, []string{"a", "", "b", "", "c"}, []int64{0, 3, 4, 6, 8}, nil},
+		{"line separators", "a\u2028b\u2029c\nd", 10, []string{"a\u2028b\u2029c", "d"}, []int64{0, 10}, nil},
+		{"empty", "", 10, nil, nil, nil},
+		{"grows", "a\n" + long + "\nb", len(long), []string{"a", long, "b"}, []int64{0, 2, int64(len(long)) + 3}, nil},
+		{"at limit with CRLF", "abc\r\nd", 3, []string{"abc", "d"}, []int64{0, 5}, nil},
+		{"over limit", "abc\nabcd\n", 3, []string{"abc"}, []int64{0}, bufio.ErrTooLong},
+		{"over limit at end", "abcd", 3, nil, nil, bufio.ErrTooLong},
+		{"long over limit", "a\n" + long, minLineBuffer, []string{"a"}, []int64{0}, bufio.ErrTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte per read exercises every refill path
+			s := newLineScanner(iotest.OneByteReader(strings.NewReader(tt.input)), 0, tt.limit)
+			defer s.release()
+
+			var (
+				lines   []string
+				offsets []int64
+			)
+
+			for s.Scan() {
+				lines = append(lines, s.Text())
+				offsets = append(offsets, s.lineStart)
+			}
+
+			if !errors.Is(s.Err(), tt.err) {
+				t.Errorf("Err() = %v, want %v", s.Err(), tt.err)
+			}
+
+			if strings.Join(lines, "|") != strings.Join(tt.lines, "|") || len(lines) != len(tt.lines) {
+				t.Errorf("lines = %q, want %q", lines, tt.lines)
+			}
+
+			for i := range min(len(offsets), len(tt.offsets)) {
+				if offsets[i] != tt.offsets[i] {
+					t.Errorf("offset of line %d = %d, want %d", i, offsets[i], tt.offsets[i])
+				}
+			}
+
+			if tt.err == nil && s.consumed != int64(len(tt.input)) {
+				t.Errorf("consumed = %d, want %d", s.consumed, len(tt.input))
+			}
+		})
+	}
+}
+
+// lineEndingInputs hold the same three JSON lines with every line ending
+// style, the second line holding a U+2028 in a string
+var lineEndingInputs = map[string]string{
+	"LF":   "{\"msg\":\"a\"}\n{\"msg\":\"b\u2028c\"}\n{\"msg\":\"d\"}\n",
+	"CRLF": "{\"msg\":\"a\"}\r\n{\"msg\":\"b\u2028c\"}\r\n{\"msg\":\"d\"}\r\n",
+	"CR":   "{\"msg\":\"a\"}\r{\"msg\":\"b\u2028c\"}\r{\"msg\":\"d\"}\r",
+	"none": "{\"msg\":\"a\"}\n{\"msg\":\"b\u2028c\"}\r\n{\"msg\":\"d\"}",
+}
+
+func TestLineEndingsConsistent(t *testing.T) {
+	wantMessages := []string{"a", "b\u2028c", "d"}
+
+	for name, input := range lineEndingInputs {
+		t.Run(name, func(t *testing.T) {
+			parser := NewWithFormat(FormatJSON, WithSourceName("in"))
+
+			fromReader, stats, err := ParseWithStats(parser, iotest.OneByteReader(strings.NewReader(input)))
+			if err != nil {
+				t.Fatalf("ParseWithStats() error = %v", err)
+			}
+
+			fromString, err := parser.ParseString(input)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			var fromWriter []LogEntry
+
+			w := NewWriter(parser, func(e LogEntry) { fromWriter = append(fromWriter, e) })
+			for i := range len(input) {
+				if _, err := w.Write([]byte{input[i]}); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			var scanned []string
+
+			scanner := bufio.NewScanner(strings.NewReader(input))
+			scanner.Split(ScanLines)
+
+			for scanner.Scan() {
+				scanned = append(scanned, scanner.Text())
+			}
+
+			if len(scanned) != 3 || stats.LinesSeen != 3 || stats.BlankLines != 0 {
+				t.Errorf("ScanLines gave %q; LinesSeen = %d, BlankLines = %d", scanned, stats.LinesSeen, stats.BlankLines)
+			}
+
+			for path, entries := range map[string][]LogEntry{"Parse": fromReader, "ParseString": fromString, "NewWriter": fromWriter} {
+				if len(entries) != len(wantMessages) {
+					t.Errorf("%s: got %d entries, want %d", path, len(entries), len(wantMessages))
+
+					continue
+				}
+
+				for i, e := range entries {
+					if e.Message != wantMessages[i] || e.Source.Line != i+1 || e.Source.Offset != fromReader[i].Source.Offset {
+						t.Errorf("%s: entry %d = %q at %+v, want %q at line %d, offset %d",
+							path, i, e.Message, *e.Source, wantMessages[i], i+1, fromReader[i].Source.Offset)
+					}
+				}
+			}
+		})
+	}
+}
+
+// emptyReader returns no data and no error
+type emptyReader struct{}
+
+func (emptyReader) Read([]byte) (int, error) { return 0, nil }
+
+func TestLineScannerNoProgress(t *testing.T) {
+	s := newLineScanner(emptyReader{}, 0, 10)
+	defer s.release()
+
+	if s.Scan() || !errors.Is(s.Err(), io.ErrNoProgress) {
+		t.Errorf("Err() = %v, want io.ErrNoProgress", s.Err())
+	}
+}
+
+func TestLineBufferPool(t *testing.T) {
+	tests := []struct {
+		size   int
+		pooled bool
+	}{
+		{minLineBuffer, true},
+		{64 * 1024, true},
+		{BufferSize, true},
+		{BufferSize * 2, false}, // Oversized buffers are dropped
+		{BufferSize + 2, false},
+		{100, false},
+	}
+
+	for _, tt := range tests {
+		if got := lineBufferClass(tt.size) >= 0; got != tt.pooled {
+			t.Errorf("size %d pooled = %v, want %v", tt.size, got, tt.pooled)
+		}
+
+		if buf := getLineBuffer(tt.size); len(buf) != tt.size {
+			t.Errorf("getLineBuffer(%d) returned %d bytes", tt.size, len(buf))
+		}
+	}
+
+	// A short buffer put back returns at its full size
+	buf := getLineBuffer(minLineBuffer)
+	putLineBuffer(buf[:10])
+
+	if got := getLineBuffer(minLineBuffer); len(got) != minLineBuffer {
+		t.Errorf("reused buffer has %d bytes, want %d", len(got), minLineBuffer)
+	}
+}
+
+func TestWithMaxLineSize(t *testing.T) {
+	line := `{"level":"info","msg":"` + strings.Repeat("x", 2*BufferSize) + `"}`
+	input := line + "\n" + `{"level":"warn","msg":"next"}`
+
+	if _, err := NewWithFormat(FormatJSON).Parse(strings.NewReader(input)); !errors.Is(err, bufio.ErrTooLong) {
+		t.Fatalf("default limit error = %v, want bufio.ErrTooLong", err)
+	}
+
+	entries, err := NewWithFormat(FormatJSON, WithMaxLineSize(len(line))).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(entries) != 2 || len(entries[0].Message) != 2*BufferSize || entries[1].Message != "next" {
+		t.Errorf("got %d entries", len(entries))
+	}
+
+	if _, err := NewWithFormat(FormatJSON, WithMaxLineSize(len(line)-1)).Parse(strings.NewReader(input)); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("limit one byte short error = %v, want bufio.ErrTooLong", err)
+	}
+}
+
+func TestWithMaxLineSizeWriter(t *testing.T) {
+	w := NewWriter(NewWithFormat(FormatJSON, WithMaxLineSize(16)), func(LogEntry) {})
+
+	if _, err := w.Write([]byte(`{"msg":"short"}` + "\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if _, err := w.Write([]byte(strings.Repeat("x", 17))); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("long line error = %v, want bufio.ErrTooLong", err)
+	}
+}
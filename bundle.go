@@ -0,0 +1,367 @@
+package logparser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultMaxBundleSize is the number of decompressed bytes ParseBundle
+// reads unless WithMaxBundleSize says otherwise
+const DefaultMaxBundleSize = 1 << 30
+
+// maxBundleDepth is how deep archives may nest in a bundle: members of the
+// bundle, and of archives inside it, are read
+const maxBundleDepth = 2
+
+// Errors returned by ParseBundle
+var (
+	ErrNotBundle      = errors.New("not a tar or zip archive")
+	ErrBundleTooLarge = errors.New("bundle exceeds the decompressed size cap")
+)
+
+// BundleOption configures ParseBundle and ParseBundleReader
+type BundleOption func(*bundleConfig)
+
+// bundleConfig holds bundle settings
+type bundleConfig struct {
+	parser  Parser
+	maxSize int64
+	stats   *BundleStats
+}
+
+// BundleStats describes the members of a bundle read by ParseBundle
+type BundleStats struct {
+	Parsed   []string // Members parsed as logs, in archive order
+	Binary   []string // Members skipped as binary
+	Warnings []string // Members skipped for other reasons, such as archives nested too deep
+	Bytes    int64    // Bytes decompressed, or held in memory for zip streams
+}
+
+// WithBundleParser parses the members with p instead of New(). Each member
+// is parsed on its own, so in auto mode each has its format detected.
+func WithBundleParser(p Parser) BundleOption {
+	return func(c *bundleConfig) {
+		c.parser = p
+	}
+}
+
+// WithMaxBundleSize caps the bytes decompressed from a bundle (default
+// DefaultMaxBundleSize); values below 1 mean the default
+func WithMaxBundleSize(n int64) BundleOption {
+	return func(c *bundleConfig) {
+		c.maxSize = n
+	}
+}
+
+// WithBundleStats fills in stats as the bundle is read
+func WithBundleStats(stats *BundleStats) BundleOption {
+	return func(c *bundleConfig) {
+		c.stats = stats
+	}
+}
+
+// ParseBundle parses the log files in a support bundle: a tar archive,
+// optionally gzip-compressed, or a zip archive. It returns the entries of
+// each member, keyed by its path in the archive, which is also the
+// Source.Name of its entries. Members are told apart by content, not name:
+// gzip-compressed members are decompressed, binary members are skipped,
+// and archives inside the bundle are read as part of it, their members
+// keyed as "inner.tar.gz/app.log"; archives nested deeper are skipped
+// with a warning in BundleStats. Reading stops with ErrBundleTooLarge once
+// more than WithMaxBundleSize bytes have been decompressed, counting a
+// nested archive both as a member and as an archive, which guards against
+// archive bombs. A member that fails to parse stops the read, with the
+// member's path in the error; WithSkipInvalid on the WithBundleParser
+// parser skips bad lines instead.
+func ParseBundle(name string, opts ...BundleOption) (map[string][]LogEntry, error) {
+	file, err := os.Open(name) //nolint:gosec // path is supplied by the caller
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, len(zipMagic))
+	if _, err := io.ReadFull(file, head); err == nil && bytes.Equal(head, zipMagic) {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		b := newBundleReader(opts)
+
+		return b.out, b.readZip("", io.NewSectionReader(file, 0, info.Size()), info.Size(), 1)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return ParseBundleReader(file, opts...)
+}
+
+// ParseBundleReader is ParseBundle for a tar, tar.gz, or zip stream. A zip
+// archive is held in memory, up to the size cap, since its index is at its
+// end.
+func ParseBundleReader(r io.Reader, opts ...BundleOption) (map[string][]LogEntry, error) {
+	b := newBundleReader(opts)
+
+	return b.out, b.readArchive("", r, 1)
+}
+
+// bundleReader walks the members of a bundle
+type bundleReader struct {
+	cfg   bundleConfig
+	stats *BundleStats
+	out   map[string][]LogEntry
+}
+
+// newBundleReader applies opts to the defaults
+func newBundleReader(opts []BundleOption) *bundleReader {
+	cfg := bundleConfig{maxSize: DefaultMaxBundleSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.parser == nil {
+		cfg.parser = New()
+	}
+
+	if cfg.maxSize < 1 {
+		cfg.maxSize = DefaultMaxBundleSize
+	}
+
+	stats := cfg.stats
+	if stats == nil {
+		stats = &BundleStats{}
+	}
+
+	return &bundleReader{cfg: cfg, stats: stats, out: make(map[string][]LogEntry)}
+}
+
+// Magic bytes of the formats found in bundles
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+	tarMagic  = []byte("ustar") // At tarMagicOffset
+)
+
+// tarMagicOffset is the offset of the magic in a tar header
+const tarMagicOffset = 257
+
+// readArchive reads the members of the tar or zip archive in r, possibly
+// gzip-compressed, at the given nesting depth
+func (b *bundleReader) readArchive(name string, r io.Reader, depth int) error {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, _ := br.Peek(sniffSize)
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return bundleError(name, err)
+		}
+
+		return b.readArchive(name, b.counted(gz), depth)
+	case bytes.HasPrefix(head, zipMagic):
+		data, err := io.ReadAll(b.counted(br))
+		if err != nil {
+			return bundleError(name, err)
+		}
+
+		return b.readZip(name, bytes.NewReader(data), int64(len(data)), depth)
+	case isTar(head):
+		return b.readTar(name, tar.NewReader(br), depth)
+	default:
+		return bundleError(name, ErrNotBundle)
+	}
+}
+
+// readTar reads the regular files of a tar archive
+func (b *bundleReader) readTar(name string, tr *tar.Reader, depth int) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return bundleError(name, err)
+		}
+
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		if err := b.readMember(path.Join(name, hdr.Name), tr, hdr.ModTime, depth); err != nil {
+			return err
+		}
+	}
+}
+
+// readZip reads the files of a zip archive
+func (b *bundleReader) readZip(name string, r io.ReaderAt, size int64, depth int) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return bundleError(name, err)
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return bundleError(path.Join(name, f.Name), err)
+		}
+
+		err = b.readMember(path.Join(name, f.Name), b.counted(rc), f.Modified, depth)
+		rc.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readMember parses a member as a log file, or reads it as a nested
+// archive, by its content
+func (b *bundleReader) readMember(name string, r io.Reader, modTime time.Time, depth int) error {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, _ := br.Peek(sniffSize)
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return bundleError(name, err)
+		}
+
+		return b.readMember(name, b.counted(gz), modTime, depth)
+	case bytes.HasPrefix(head, zipMagic) || isTar(head):
+		if depth >= maxBundleDepth {
+			b.stats.Warnings = append(b.stats.Warnings, name+": archive nested too deep, skipped")
+
+			return nil
+		}
+
+		return b.readArchive(name, br, depth+1)
+	case isBinary(head):
+		b.stats.Binary = append(b.stats.Binary, name)
+
+		return nil
+	}
+
+	var (
+		entries []LogEntry
+		err     error
+	)
+
+	if pp, ok := b.cfg.parser.(*parser); ok {
+		entries, _, err = pp.parseReader(br, runInput{name: name, modTime: modTime})
+	} else {
+		entries, err = b.cfg.parser.Parse(br)
+
+		for i := range entries {
+			if entries[i].Source == nil {
+				entries[i].Source = &Source{Name: name}
+			}
+		}
+	}
+
+	if err != nil {
+		return bundleError(name, err)
+	}
+
+	b.out[name] = append(b.out[name], entries...)
+	b.stats.Parsed = append(b.stats.Parsed, name)
+
+	return nil
+}
+
+// counted returns r counting the bytes read from it toward the size cap.
+// Decompressed streams are counted, so an uncompressed tar is not.
+func (b *bundleReader) counted(r io.Reader) io.Reader {
+	return &bundleCounter{r: r, b: b}
+}
+
+// bundleCounter counts the decompressed bytes of a bundle
+type bundleCounter struct {
+	r io.Reader
+	b *bundleReader
+}
+
+func (c *bundleCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+
+	c.b.stats.Bytes += int64(n)
+	if c.b.stats.Bytes > c.b.cfg.maxSize {
+		return n, ErrBundleTooLarge
+	}
+
+	return n, err
+}
+
+// bundleError adds the member name to an error
+func bundleError(name string, err error) error {
+	if name == "" || errors.Is(err, ErrBundleTooLarge) {
+		return err
+	}
+
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// isTar reports whether head starts with a POSIX or GNU tar header
+func isTar(head []byte) bool {
+	return len(head) >= tarMagicOffset+len(tarMagic) && bytes.Equal(head[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic)
+}
+
+// isBinary reports whether the start of a file is binary data rather than
+// text: it has a NUL byte, other than in UTF-16 text, is not UTF-8, or is
+// more than a tenth control characters other than whitespace and escape
+func isBinary(head []byte) bool {
+	if isUTF16(head) {
+		return false
+	}
+
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+
+	// The sniffed bytes may end inside a character
+	valid := false
+
+	for cut := 0; cut < utf8.UTFMax && cut <= len(head); cut++ {
+		if utf8.Valid(head[:len(head)-cut]) {
+			valid = true
+
+			break
+		}
+	}
+
+	if !valid {
+		return true
+	}
+
+	controls := 0
+
+	for _, c := range head {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\v' && c != 0x1b {
+			controls++
+		}
+	}
+
+	return controls*10 > len(head)
+}
@@ -0,0 +1,227 @@
+package logparser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// bundleMember is a file in a test archive
+type bundleMember struct {
+	name string
+	data []byte
+}
+
+// tarGz builds a gzip-compressed tar archive of members
+func tarGz(t *testing.T, members ...bundleMember) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, m := range members {
+		if err := tw.WriteHeader(&tar.Header{Name: m.name, Mode: 0o644, Size: int64(len(m.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write(m.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// zipped builds a zip archive of members
+func zipped(t *testing.T, members ...bundleMember) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for _, m := range members {
+		w, err := zw.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write(m.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// gzipped compresses data
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// supportBundle builds a bundle of JSON, logfmt, and text logs, a binary
+// core dump, a rotated log, and archives nested one and two levels deep
+func supportBundle(t *testing.T) []byte {
+	t.Helper()
+
+	deep := zipped(t, bundleMember{"deep.log", []byte("level=info msg=unreachable\n")})
+	inner := tarGz(t,
+		bundleMember{"db.log", []byte("2024-03-01 10:00:05 ERROR connection pool exhausted\n")},
+		bundleMember{"deep.zip", deep},
+	)
+
+	core := append([]byte("\x7fELF\x02\x01\x01\x00"), bytes.Repeat([]byte{0, 1, 2, 3}, 64)...)
+
+	return tarGz(t,
+		bundleMember{"app/api.json", []byte(`{"time":"2024-03-01T10:00:00Z","level":"info","msg":"started"}` + "\n" +
+			`{"time":"2024-03-01T10:00:01Z","level":"error","msg":"upstream timeout"}` + "\n")},
+		bundleMember{"app/worker.log", []byte("time=2024-03-01T10:00:02Z level=warn msg=\"queue backlog\" depth=120\n")},
+		bundleMember{"system/messages", []byte("2024-03-01 10:00:03 INFO disk check passed\n")},
+		bundleMember{"system/core.dump", core},
+		bundleMember{"app/api.json.1.gz", gzipped(t, []byte(`{"time":"2024-02-29T23:59:59Z","level":"info","msg":"rotated"}`+"\n"))},
+		bundleMember{"nested/inner.tar.gz", inner},
+	)
+}
+
+func TestParseBundleReader(t *testing.T) {
+	var stats BundleStats
+
+	logs, err := ParseBundleReader(bytes.NewReader(supportBundle(t)), WithBundleStats(&stats))
+	if err != nil {
+		t.Fatalf("ParseBundleReader() error = %v", err)
+	}
+
+	want := map[string][]string{
+		"app/api.json":               {"started", "upstream timeout"},
+		"app/worker.log":             {"queue backlog"},
+		"system/messages":            {"disk check passed"},
+		"app/api.json.1.gz":          {"rotated"},
+		"nested/inner.tar.gz/db.log": {"connection pool exhausted"},
+	}
+
+	got := make(map[string][]string)
+
+	for name, entries := range logs {
+		for _, e := range entries {
+			if e.Source == nil || e.Source.Name != name {
+				t.Errorf("%s: source = %+v", name, e.Source)
+			}
+
+			got[name] = append(got[name], e.Message)
+		}
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("members = %v, want %v", got, want)
+	}
+
+	// Text lines may keep their timestamp and level in the message
+	for name, messages := range want {
+		for i, m := range messages {
+			if i >= len(got[name]) || !strings.HasSuffix(got[name][i], m) {
+				t.Errorf("%s: messages = %q, want %q", name, got[name], messages)
+
+				break
+			}
+		}
+	}
+
+	if worker := logs["app/worker.log"]; len(worker) == 1 && worker[0].Level != "WARN" {
+		t.Errorf("logfmt level = %q, want WARN", worker[0].Level)
+	}
+
+	if !reflect.DeepEqual(stats.Binary, []string{"system/core.dump"}) {
+		t.Errorf("binary members = %v", stats.Binary)
+	}
+
+	if len(stats.Warnings) != 1 || !strings.HasPrefix(stats.Warnings[0], "nested/inner.tar.gz/deep.zip:") {
+		t.Errorf("warnings = %v", stats.Warnings)
+	}
+
+	if len(stats.Parsed) != len(want) {
+		t.Errorf("parsed = %v", stats.Parsed)
+	}
+}
+
+func TestParseBundleZipFile(t *testing.T) {
+	bundle := zipped(t,
+		bundleMember{"api.json", []byte(`{"level":"info","msg":"from zip"}` + "\n")},
+		bundleMember{"inner.zip", zipped(t, bundleMember{"worker.log", []byte("level=error msg=\"nested zip\"\n")})},
+	)
+
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := os.WriteFile(path, bundle, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, parse := range map[string]func() (map[string][]LogEntry, error){
+		"file":   func() (map[string][]LogEntry, error) { return ParseBundle(path) },
+		"stream": func() (map[string][]LogEntry, error) { return ParseBundleReader(bytes.NewReader(bundle)) },
+	} {
+		logs, err := parse()
+		if err != nil {
+			t.Fatalf("%s: error = %v", name, err)
+		}
+
+		if len(logs["api.json"]) != 1 || logs["api.json"][0].Message != "from zip" {
+			t.Errorf("%s: api.json = %+v", name, logs["api.json"])
+		}
+
+		if nested := logs["inner.zip/worker.log"]; len(nested) != 1 || nested[0].Level != LevelError {
+			t.Errorf("%s: inner.zip/worker.log = %+v", name, nested)
+		}
+	}
+}
+
+func TestParseBundleSizeCap(t *testing.T) {
+	// A megabyte of log lines compresses to a few kilobytes
+	bomb := tarGz(t, bundleMember{"big.log", bytes.Repeat([]byte("level=info msg=filler\n"), 1<<16)})
+
+	if _, err := ParseBundleReader(bytes.NewReader(bomb), WithMaxBundleSize(64<<10)); !errors.Is(err, ErrBundleTooLarge) {
+		t.Errorf("capped error = %v, want ErrBundleTooLarge", err)
+	}
+
+	if _, err := ParseBundleReader(bytes.NewReader(bomb)); err != nil {
+		t.Errorf("uncapped error = %v", err)
+	}
+}
+
+func TestParseBundleNotArchive(t *testing.T) {
+	if _, err := ParseBundleReader(strings.NewReader("level=info msg=plain\n")); !errors.Is(err, ErrNotBundle) {
+		t.Errorf("error = %v, want ErrNotBundle", err)
+	}
+}
@@ -0,0 +1,260 @@
+package logparser
+
+import (
+	"encoding/json"
+	"hash"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"strings"
+	"time"
+)
+
+// DefaultCardinalityTracked is the number of distinct values
+// CardinalityReport counts exactly per field unless told otherwise
+const DefaultCardinalityTracked = 10000
+
+// Sizing of the per-field HyperLogLog sketch: 2^14 one-byte registers,
+// a standard error of about 0.8%
+const (
+	hllPrecision = 14
+	hllRegisters = 1 << hllPrecision
+)
+
+// Per-field examples kept by CardinalityReport, and their maximum length
+const (
+	cardinalityExamples   = 5
+	cardinalityExampleLen = 128
+)
+
+// FieldCardinality describes the values one field takes across entries
+type FieldCardinality struct {
+	ApproxDistinct uint64         `json:"approx_distinct"` // Distinct values, exact while Exact is true
+	Exact          bool           `json:"exact"`           // ApproxDistinct is an exact count
+	PresenceCount  int            `json:"presence_count"`  // Entries that have the field
+	TypeCounts     map[string]int `json:"type_counts"`     // Values by type: string, number, bool, object, array, null, other
+	LevelCounts    map[string]int `json:"level_counts"`    // Entries that have the field, by level
+	Examples       []string       `json:"examples"`        // First distinct values seen, formatted and shortened
+}
+
+// CardinalityReport counts the distinct values, presence, and value types
+// of every field in entries, to tell high-cardinality fields such as trace
+// ids from low-cardinality ones such as level or region when planning
+// index mappings. Values are counted exactly, by hash, up to maxTracked
+// distinct values per field (DefaultCardinalityTracked when zero or
+// less); past that the field switches to a HyperLogLog estimate, accurate
+// to about 2%, so the memory per field never exceeds maxTracked hashes
+// plus a 16 KiB sketch. Values are compared as LogEntry.Hash compares
+// them, so 2 and "2" are distinct but 2 and 2.0 are not. Keys starting
+// with InternalFieldPrefix are left out. The result marshals to JSON.
+func CardinalityReport(entries []LogEntry, maxTracked int) map[string]FieldCardinality {
+	c := newCardinalityCounter(maxTracked)
+	for i := range entries {
+		c.add(&entries[i])
+	}
+
+	return c.report()
+}
+
+// cardinalityCounter accumulates CardinalityReport one entry at a time
+type cardinalityCounter struct {
+	maxTracked int
+	fields     map[string]*fieldCounter
+	h          hash.Hash64
+}
+
+// fieldCounter accumulates the cardinality of one field
+type fieldCounter struct {
+	result FieldCardinality
+	exact  map[uint64]struct{} // Hashes seen, until there are more than maxTracked
+	sketch []uint8             // HyperLogLog registers, once exact is dropped
+}
+
+func newCardinalityCounter(maxTracked int) *cardinalityCounter {
+	if maxTracked <= 0 {
+		maxTracked = DefaultCardinalityTracked
+	}
+
+	return &cardinalityCounter{maxTracked: maxTracked, fields: make(map[string]*fieldCounter), h: fnv.New64a()}
+}
+
+// add counts the fields of one entry
+func (c *cardinalityCounter) add(e *LogEntry) {
+	for key, val := range e.Fields {
+		if strings.HasPrefix(key, InternalFieldPrefix) {
+			continue
+		}
+
+		f := c.fields[key]
+		if f == nil {
+			f = &fieldCounter{
+				result: FieldCardinality{TypeCounts: make(map[string]int), LevelCounts: make(map[string]int), Examples: []string{}},
+				exact:  make(map[uint64]struct{}),
+			}
+			c.fields[key] = f
+		}
+
+		f.result.PresenceCount++
+		f.result.TypeCounts[valueType(val)]++
+		f.result.LevelCounts[e.Level]++
+
+		c.h.Reset()
+		writeHashValue(c.h, val)
+		f.observe(c.h.Sum64(), val, c.maxTracked)
+	}
+}
+
+// observe counts one value hash
+func (f *fieldCounter) observe(sum uint64, val interface{}, maxTracked int) {
+	if f.sketch != nil {
+		hllAdd(f.sketch, sum)
+
+		return
+	}
+
+	if _, seen := f.exact[sum]; seen {
+		return
+	}
+
+	f.exact[sum] = struct{}{}
+
+	if len(f.result.Examples) < cardinalityExamples {
+		example, _ := truncateValue(formatValue(val), cardinalityExampleLen)
+		f.result.Examples = append(f.result.Examples, example)
+	}
+
+	if len(f.exact) > maxTracked {
+		f.sketch = make([]uint8, hllRegisters)
+		for seen := range f.exact {
+			hllAdd(f.sketch, seen)
+		}
+
+		f.exact = nil
+	}
+}
+
+// report returns the accumulated cardinalities
+func (c *cardinalityCounter) report() map[string]FieldCardinality {
+	out := make(map[string]FieldCardinality, len(c.fields))
+
+	for key, f := range c.fields {
+		r := f.result
+		if f.sketch != nil {
+			r.ApproxDistinct = hllEstimate(f.sketch)
+		} else {
+			r.ApproxDistinct = uint64(len(f.exact))
+			r.Exact = true
+		}
+
+		out[key] = r
+	}
+
+	return out
+}
+
+// valueType names the JSON type of a field value
+func valueType(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64, float32, int, int64, int32, uint64, uint32, uint, json.Number, time.Duration:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return "other"
+	}
+}
+
+// hllAdd records a value hash in the registers. FNV-1a is finished with
+// the SplitMix64 mixer first, so every bit depends on every input bit.
+func hllAdd(registers []uint8, sum uint64) {
+	sum ^= sum >> 30
+	sum *= 0xbf58476d1ce4e5b9
+	sum ^= sum >> 27
+	sum *= 0x94d049bb133111eb
+	sum ^= sum >> 31
+
+	index := sum >> (64 - hllPrecision)
+
+	rank := uint8(bits.LeadingZeros64(sum<<hllPrecision)) + 1
+	if rank > 64-hllPrecision+1 {
+		rank = 64 - hllPrecision + 1
+	}
+
+	if rank > registers[index] {
+		registers[index] = rank
+	}
+}
+
+// hllEstimate returns the cardinality estimate of the registers, using
+// Ertl's improved estimator, which needs no empirical bias correction
+// at small or intermediate cardinalities
+func hllEstimate(registers []uint8) uint64 {
+	const q = 64 - hllPrecision
+
+	var counts [q + 2]float64
+	for _, r := range registers {
+		counts[r]++
+	}
+
+	m := float64(len(registers))
+
+	z := m * hllTau(1-counts[q+1]/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + counts[k])
+	}
+
+	z += m * hllSigma(counts[0]/m)
+	if math.IsInf(z, 1) {
+		return 0
+	}
+
+	return uint64(math.Round(m * m / (2 * math.Ln2) / z))
+}
+
+// hllSigma computes x + sum(x^(2^k) * 2^(k-1)) for the estimator
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+
+	y, z := 1.0, x
+
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+
+		if z == prev {
+			return z
+		}
+	}
+}
+
+// hllTau computes the estimator's correction for saturated registers
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+
+	y, z := 1.0, 1-x
+
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+
+		if z == prev {
+			return z / 3
+		}
+	}
+}
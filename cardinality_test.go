@@ -0,0 +1,112 @@
+package logparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestCardinalityReport(t *testing.T) {
+	entries := []LogEntry{
+		{Level: LevelInfo, Fields: map[string]interface{}{"region": "eu", "status": 200.0, "trace": "a", "_format": "json"}},
+		{Level: LevelInfo, Fields: map[string]interface{}{"region": "us", "status": json.Number("200"), "trace": "b"}},
+		{Level: LevelError, Fields: map[string]interface{}{"region": "eu", "status": "500", "trace": "c", "ctx": map[string]interface{}{"a": 1.0}}},
+	}
+
+	report := CardinalityReport(entries, 0)
+
+	if _, ok := report["_format"]; ok {
+		t.Error("internal field reported")
+	}
+
+	region := report["region"]
+	if region.ApproxDistinct != 2 || !region.Exact || region.PresenceCount != 3 {
+		t.Errorf("region = %+v", region)
+	}
+
+	if len(region.Examples) != 2 || region.Examples[0] != "eu" || region.Examples[1] != "us" {
+		t.Errorf("region examples = %v", region.Examples)
+	}
+
+	// 200 and json.Number("200") are one value, "500" another
+	status := report["status"]
+	if status.ApproxDistinct != 2 || status.TypeCounts["number"] != 2 || status.TypeCounts["string"] != 1 {
+		t.Errorf("status = %+v", status)
+	}
+
+	if ctx := report["ctx"]; ctx.PresenceCount != 1 || ctx.TypeCounts["object"] != 1 || ctx.LevelCounts[LevelError] != 1 {
+		t.Errorf("ctx = %+v", ctx)
+	}
+
+	if trace := report["trace"]; trace.LevelCounts[LevelInfo] != 2 || trace.LevelCounts[LevelError] != 1 {
+		t.Errorf("trace levels = %v", trace.LevelCounts)
+	}
+
+	if _, err := json.Marshal(report); err != nil {
+		t.Errorf("json.Marshal() error = %v", err)
+	}
+}
+
+func TestCardinalityApproximate(t *testing.T) {
+	const n = 1000000
+
+	// One entry reused, so the test needs no memory for a million entries;
+	// CardinalityReport adds its entries the same way
+	c := newCardinalityCounter(1000)
+	e := LogEntry{Level: LevelInfo, Fields: make(map[string]interface{}, 3)}
+
+	for i := range n {
+		e.Fields["trace_id"] = fmt.Sprintf("%016x", uint64(i)*0x9e3779b97f4a7c15)
+		e.Fields["user"] = float64(i % 50000)
+		e.Fields["region"] = []string{"eu-west-1", "us-east-1", "ap-south-1"}[i%3]
+		c.add(&e)
+	}
+
+	report := c.report()
+
+	for key, want := range map[string]float64{"trace_id": n, "user": 50000} {
+		got := report[key]
+		if got.Exact {
+			t.Errorf("%s counted exactly past maxTracked", key)
+		}
+
+		if rel := math.Abs(float64(got.ApproxDistinct)-want) / want; rel > 0.02 {
+			t.Errorf("%s: ApproxDistinct = %d, want %.0f within 2%% (off by %.2f%%)", key, got.ApproxDistinct, want, rel*100)
+		}
+
+		if got.PresenceCount != n || len(got.Examples) != cardinalityExamples {
+			t.Errorf("%s = presence %d, examples %v", key, got.PresenceCount, got.Examples)
+		}
+	}
+
+	// Memory is bounded: past maxTracked only the fixed sketch is kept
+	if f := c.fields["trace_id"]; f.exact != nil || len(f.sketch) != hllRegisters {
+		t.Errorf("trace_id keeps %d hashes and %d registers", len(f.exact), len(f.sketch))
+	}
+
+	if region := report["region"]; region.ApproxDistinct != 3 || !region.Exact {
+		t.Errorf("region = %+v", region)
+	}
+}
+
+func TestHLLEstimateRange(t *testing.T) {
+	registers := make([]uint8, hllRegisters)
+	if got := hllEstimate(registers); got != 0 {
+		t.Errorf("empty estimate = %d", got)
+	}
+
+	// Small and intermediate cardinalities, where the raw HyperLogLog
+	// estimate is biased
+	added := 0
+
+	for _, n := range []int{100, 5000, 40000, 200000} {
+		for ; added < n; added++ {
+			hllAdd(registers, uint64(added))
+		}
+
+		if rel := math.Abs(float64(hllEstimate(registers))-float64(n)) / float64(n); rel > 0.03 {
+			t.Errorf("estimate of %d off by %.2f%%", n, rel*100)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package logparse
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yildizm/go-logparser"
+)
+
+// Outputs are the values accepted by --output
+var Outputs = []string{"json", "logfmt", "text", "csv"}
+
+// levelOrder lists the standard levels from least to most severe
+var levelOrder = []string{"TRACE", "DEBUG", logparser.LevelInfo, "WARN", logparser.LevelError, "FATAL"}
+
+// timeFlagLayouts are the absolute times accepted by --since and --until
+var timeFlagLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// Flags holds the command line settings of logparse. Register adds them to
+// a flag.FlagSet, so other commands can offer the same flags next to their
+// own.
+type Flags struct {
+	Format      logparser.Format // Input format; FormatAuto detects it
+	MinLevel    string           // Least severe level kept, such as "warn"; empty keeps all
+	Since       string           // Earliest timestamp kept, as for ParseTimeFlag
+	Until       string           // Latest timestamp kept, as for ParseTimeFlag
+	Output      string           // One of Outputs
+	Stats       bool             // Report on each input on stderr
+	Follow      bool             // Keep reading the input as it grows
+	SkipInvalid bool             // Skip lines that fail to parse
+	Files       []string         // Inputs; none or "-" reads stdin
+}
+
+// Register defines the flags on fs, storing their values in f
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.TextVar(&f.Format, "format", logparser.FormatAuto,
+		"input `format`: auto, json, logfmt, text, windows_event_xml, alb, haproxy, or squid")
+	fs.StringVar(&f.MinLevel, "min-level", "", "drop entries less severe than `level`")
+	fs.StringVar(&f.Since, "since", "", "drop entries before `time`, such as 2024-01-02T15:04:05Z or 1h for an hour ago")
+	fs.StringVar(&f.Until, "until", "", "drop entries after `time`, as for --since")
+	fs.StringVar(&f.Output, "output", "text", "output `format`: "+strings.Join(Outputs, ", "))
+	fs.BoolVar(&f.Stats, "stats", false, "report on each input on stderr")
+	fs.BoolVar(&f.Follow, "follow", false, "keep reading the input as it grows, like tail -f")
+	fs.BoolVar(&f.SkipInvalid, "skip-invalid", false, "skip lines that fail to parse instead of stopping")
+}
+
+// ParseFlags parses the arguments of logparse, writing usage and errors to
+// stderr. The arguments left after the flags are the input files.
+func ParseFlags(args []string, stderr io.Writer) (Flags, error) {
+	var f Flags
+
+	fs := flag.NewFlagSet("logparse", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: logparse [flags] [file ...]\n\nReads stdin when no file or \"-\" is given.\n\n")
+		fs.PrintDefaults()
+	}
+
+	f.Register(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return f, err
+	}
+
+	f.Files = fs.Args()
+
+	if err := f.Validate(); err != nil {
+		fmt.Fprintln(stderr, err)
+		fs.Usage()
+
+		return f, err
+	}
+
+	return f, nil
+}
+
+// Validate reports settings that cannot be used together or are out of
+// range. Times are checked by FilterFromFlags.
+func (f Flags) Validate() error {
+	switch {
+	case !slices.Contains(Outputs, f.Output):
+		return fmt.Errorf("unknown output %q", f.Output)
+	case f.MinLevel != "" && levelRank(f.MinLevel) < 0:
+		return fmt.Errorf("unknown level %q", f.MinLevel)
+	case f.Follow && len(f.Files) > 1:
+		return errors.New("--follow reads a single input")
+	case f.Follow && f.Format == logparser.FormatWindowsEventXML:
+		return errors.New("--follow cannot read Windows Event XML, which is parsed as a whole")
+	}
+
+	return nil
+}
+
+// OptionsFromFlags returns the parser options for the flags. Level and
+// time filters apply to parsed entries; see FilterFromFlags.
+func OptionsFromFlags(f Flags) []logparser.Option {
+	var opts []logparser.Option
+
+	if f.Format != logparser.FormatAuto {
+		opts = append(opts, logparser.WithFormat(f.Format))
+	}
+
+	if f.SkipInvalid {
+		opts = append(opts, logparser.WithSkipInvalid(true))
+	}
+
+	return opts
+}
+
+// FilterFromFlags returns a function reporting whether an entry passes the
+// --min-level, --since, and --until flags, with relative times counted
+// back from now. Entries whose level is not a standard one fail a level
+// filter.
+func FilterFromFlags(f Flags, now time.Time) (func(*logparser.LogEntry) bool, error) {
+	since, err := ParseTimeFlag(f.Since, now)
+	if err != nil {
+		return nil, fmt.Errorf("--since: %w", err)
+	}
+
+	until, err := ParseTimeFlag(f.Until, now)
+	if err != nil {
+		return nil, fmt.Errorf("--until: %w", err)
+	}
+
+	minRank := -1
+	if f.MinLevel != "" {
+		minRank = levelRank(f.MinLevel)
+	}
+
+	return func(e *logparser.LogEntry) bool {
+		switch {
+		case minRank >= 0 && levelRank(e.Level) < minRank:
+			return false
+		case !since.IsZero() && e.Timestamp.Before(since):
+			return false
+		case !until.IsZero() && e.Timestamp.After(until):
+			return false
+		}
+
+		return true
+	}, nil
+}
+
+// ParseTimeFlag parses a --since or --until value: a Go duration such as
+// "90m", meaning that long before now, or a time as RFC 3339,
+// "2006-01-02T15:04:05", "2006-01-02 15:04:05", or "2006-01-02". Times
+// without a zone offset are read in the location of now. An empty value
+// gives the zero time.
+func ParseTimeFlag(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range timeFlagLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%q is neither a time nor a duration", s)
+}
+
+// StdinIsPipe reports whether r, normally os.Stdin, is a pipe or a file
+// rather than a terminal, so reading it will not wait for typed input.
+// Readers other than an *os.File count as pipes.
+func StdinIsPipe(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return true
+	}
+
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// levelRank returns the position of a level in severity order, reading
+// aliases such as "warning" and "err", or -1 for other levels
+func levelRank(level string) int {
+	normalized := logparser.ParseLevel(level)
+	if normalized == logparser.LevelInfo && !strings.EqualFold(level, logparser.LevelInfo) {
+		// ParseLevel reads unknown levels as INFO
+		normalized = strings.ToUpper(level)
+	}
+
+	return slices.Index(levelOrder, normalized)
+}
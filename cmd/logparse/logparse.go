@@ -0,0 +1,285 @@
+// Package logparse is the logparse command, which parses log files or
+// stdin and writes the entries as JSON, logfmt, aligned text, or CSV:
+//
+//	logparse --min-level warn --since 1h --output json app.log
+//
+// It is an importable package rather than package main so that the
+// command can be tested and embedded: Main runs it with the process's
+// arguments and streams, Command runs it with others, and the pieces
+// connecting flags to the library (Flags, OptionsFromFlags,
+// FilterFromFlags, NewEntryWriter, StdinIsPipe) can be reused by other
+// commands. The logparse subdirectory builds the binary.
+package logparse
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/yildizm/go-logparser"
+)
+
+// Exit codes returned by Main and Command.Run
+const (
+	ExitOK    = 0 // Every input was parsed
+	ExitError = 1 // Reading, parsing, or writing failed
+	ExitUsage = 2 // Invalid flags or no input
+)
+
+// DefaultPollInterval is how often --follow checks a file for new data
+const DefaultPollInterval = 250 * time.Millisecond
+
+// Command is one configuration of the logparse command
+type Command struct {
+	Stdin        io.Reader
+	Stdout       io.Writer
+	Stderr       io.Writer
+	Now          func() time.Time // Clock for relative --since and --until (default time.Now)
+	PollInterval time.Duration    // How often --follow checks for new data (default DefaultPollInterval)
+}
+
+// Main runs logparse with args, not including the program name, on the
+// process's standard streams, and returns the exit code. An interrupt
+// stops --follow, writing the entries held until then.
+func Main(args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := Command{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+
+	return c.Run(ctx, args)
+}
+
+// Run runs logparse with args, not including the program name, and returns
+// the exit code. Inputs are parsed one after another, each with its own
+// format detection, and their entries written in order. With --follow,
+// Run reads until ctx is done, and an entry is written once the next line
+// shows that no continuation line will be folded into it; without
+// --format, nothing is written until enough lines have arrived to detect
+// the format.
+func (c *Command) Run(ctx context.Context, args []string) int {
+	f, err := ParseFlags(args, c.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	} else if err != nil {
+		return ExitUsage
+	}
+
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+
+	keep, err := FilterFromFlags(f, now())
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "logparse: %v\n", err)
+
+		return ExitUsage
+	}
+
+	inputs := f.Files
+	if len(inputs) == 0 {
+		if !StdinIsPipe(c.Stdin) {
+			fmt.Fprintln(c.Stderr, "logparse: no input; give files or pipe logs to stdin")
+
+			return ExitUsage
+		}
+
+		inputs = []string{"-"}
+	}
+
+	out, err := NewEntryWriter(c.Stdout, f.Output)
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "logparse: %v\n", err)
+
+		return ExitUsage
+	}
+
+	var read, written int
+
+	write := func(e logparser.LogEntry) error {
+		read++
+
+		if !keep(&e) {
+			return nil
+		}
+
+		written++
+
+		return out.WriteEntry(e)
+	}
+
+	if f.Follow {
+		err = c.follow(ctx, f, inputs[0], write)
+	} else {
+		for _, name := range inputs {
+			if err = c.parseInput(f, name, write); err != nil {
+				break
+			}
+		}
+	}
+
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	if f.Stats {
+		fmt.Fprintf(c.Stderr, "logparse: %d entries read, %d written\n", read, written)
+	}
+
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "logparse: %v\n", err)
+
+		return ExitError
+	}
+
+	return ExitOK
+}
+
+// parseInput parses a whole input, "-" for stdin, passing its entries to
+// write and, with --stats, its report to stderr
+func (c *Command) parseInput(f Flags, name string, write func(logparser.LogEntry) error) error {
+	r := c.Stdin
+
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		r = file
+	}
+
+	p, err := logparser.NewE(append(OptionsFromFlags(f), logparser.WithSourceName(name))...)
+	if err != nil {
+		return err
+	}
+
+	entries, report, err := logparser.ParseWithReport(p, r)
+
+	if f.Stats {
+		if reportErr := report.WriteReport(c.Stderr, logparser.FormatText); reportErr != nil && err == nil {
+			err = reportErr
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	for _, e := range entries {
+		if err := write(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// follow parses an input as it grows until ctx is done, passing entries
+// to write as they are complete
+func (c *Command) follow(ctx context.Context, f Flags, name string, write func(logparser.LogEntry) error) error {
+	p, err := logparser.NewE(append(OptionsFromFlags(f), logparser.WithSourceName(name))...)
+	if err != nil {
+		return err
+	}
+
+	// The callback runs inside Write and Close, which hold the writer's
+	// lock, so writeErr is safe to read once Close returns
+	var writeErr error
+
+	w := logparser.NewWriter(p, func(e logparser.LogEntry) {
+		if writeErr == nil {
+			writeErr = write(e)
+		}
+	})
+
+	if name == "-" {
+		err = copyUntilDone(ctx, w, c.Stdin)
+	} else {
+		err = c.tail(ctx, w, name)
+	}
+
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = writeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return nil
+}
+
+// tail copies a file to w, then polls it for appended data until ctx is
+// done. A file that shrinks, as when it is truncated on rotation, is read
+// again from the start.
+func (c *Command) tail(ctx context.Context, w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var offset int64
+
+	for {
+		n, err := io.Copy(w, file)
+		offset += n
+
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if info, err := file.Stat(); err == nil && info.Size() < offset {
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+
+			offset = 0
+		}
+	}
+}
+
+// copyUntilDone copies r to w until r ends or ctx is done. The copy runs
+// in its own goroutine, since a read from a pipe cannot be interrupted; it
+// is abandoned when ctx is done first.
+func copyUntilDone(ctx context.Context, w io.Writer, r io.Reader) error {
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := io.Copy(w, r)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
@@ -0,0 +1,15 @@
+// Command logparse parses log files or stdin and writes the entries as
+// JSON, logfmt, aligned text, or CSV; see package logparse for the flags.
+//
+//	go install github.com/yildizm/go-logparser/cmd/logparse/logparse@latest
+package main
+
+import (
+	"os"
+
+	"github.com/yildizm/go-logparser/cmd/logparse"
+)
+
+func main() {
+	os.Exit(logparse.Main(os.Args[1:]))
+}
@@ -0,0 +1,294 @@
+package logparse
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// checkGolden compares got with the named golden file, rewriting it with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != string(want) {
+		t.Errorf("output differs from %s:\n%s\nwant\n%s", path, got, want)
+	}
+}
+
+// run runs the command with args and stdin, returning the exit code and
+// what it wrote
+func run(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+
+	c := Command{
+		Stdin:  strings.NewReader(stdin),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Now:    func() time.Time { return time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC) },
+	}
+
+	code := c.Run(context.Background(), args)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunGolden(t *testing.T) {
+	tests := []struct {
+		golden string
+		args   []string
+	}{
+		{"text.txt", []string{"testdata/app.log", "testdata/api.json"}},
+		{"json.jsonl", []string{"--output", "json", "testdata/app.log"}},
+		{"logfmt.log", []string{"--output", "logfmt", "--format", "json", "testdata/api.json"}},
+		{"csv.csv", []string{"--output", "csv", "testdata/app.log"}},
+		{"min_level.txt", []string{"--min-level", "warning", "testdata/app.log", "testdata/api.json"}},
+		{"since_until.txt", []string{"--since", "25m", "--until", "2024-03-01T09:15:00Z", "testdata/app.log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			code, stdout, stderr := run(t, "", tt.args...)
+			if code != ExitOK {
+				t.Fatalf("exit code %d, stderr:\n%s", code, stderr)
+			}
+
+			checkGolden(t, tt.golden, stdout)
+		})
+	}
+}
+
+func TestRunStdinAndStats(t *testing.T) {
+	input, err := os.ReadFile("testdata/app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := run(t, string(input), "--stats", "--min-level", "error", "--output", "logfmt")
+	if code != ExitOK {
+		t.Fatalf("exit code %d, stderr:\n%s", code, stderr)
+	}
+
+	if want := `time=2024-03-01T09:15:00Z level=ERROR msg="payment failed" order=A-1001 retry=2` + "\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+
+	for _, want := range []string{"parsing -", "Lines:    5 seen, 5 entries", "5 entries read, 1 written"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr lacks %q:\n%s", want, stderr)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{"unknown output", []string{"--output", "yaml", "testdata/app.log"}, ExitUsage, `unknown output "yaml"`},
+		{"unknown format", []string{"--format", "xml", "testdata/app.log"}, ExitUsage, `unknown format "xml"`},
+		{"unknown level", []string{"--min-level", "loud", "testdata/app.log"}, ExitUsage, `unknown level "loud"`},
+		{"bad since", []string{"--since", "yesterday", "testdata/app.log"}, ExitUsage, `--since: "yesterday"`},
+		{"follow two files", []string{"--follow", "testdata/app.log", "testdata/api.json"}, ExitUsage, "single input"},
+		{"missing file", []string{"testdata/missing.log"}, ExitError, "missing.log"},
+		{"invalid line", []string{"--format", "json", "testdata/app.log"}, ExitError, "app.log"},
+		{"help", []string{"--help"}, ExitOK, "usage: logparse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := run(t, "", tt.args...)
+			if code != tt.code || !strings.Contains(stderr, tt.want) {
+				t.Errorf("exit code %d, stderr:\n%s\nwant %d and %q", code, stderr, tt.code, tt.want)
+			}
+		})
+	}
+
+	// Invalid lines can be skipped instead
+	if code, stdout, _ := run(t, "", "--format", "json", "--skip-invalid", "testdata/app.log", "testdata/api.json"); code != ExitOK ||
+		strings.Count(stdout, "\n") != 2 {
+		t.Errorf("--skip-invalid: exit code %d, stdout:\n%s", code, stdout)
+	}
+}
+
+func TestMain(t *testing.T) {
+	// Main writes to the process's stdout, which is swapped for a file
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = out
+
+	defer func() { os.Stdout = stdout }()
+
+	code := Main([]string{"--output", "json", "testdata/app.log"})
+	os.Stdout = stdout
+
+	if code != ExitOK {
+		t.Fatalf("Main() = %d", code)
+	}
+
+	got, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "json.jsonl", string(got))
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestRunFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	// line returns a logfmt line logged s seconds past 09:00
+	line := func(s int, level, msg string) string {
+		return fmt.Sprintf("time=2024-03-01T09:00:%02dZ level=%s msg=%s\n", s, level, msg)
+	}
+
+	if err := os.WriteFile(path, []byte(line(1, "info", "one")+line(2, "error", "two")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr syncBuffer
+
+	c := Command{Stdout: &stdout, Stderr: &stderr, PollInterval: time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+
+	go func() {
+		done <- c.Run(ctx, []string{"--follow", "--format", "logfmt", "--output", "logfmt", "--min-level", "info", path})
+	}()
+
+	// waitFor polls stdout until it holds want
+	waitFor := func(want string) {
+		t.Helper()
+
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if strings.Contains(stdout.String(), want) {
+				return
+			}
+		}
+
+		t.Fatalf("stdout never held %q:\n%s", want, stdout.String())
+	}
+
+	// The first entry is complete once the second line is read
+	waitFor("msg=one")
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteString(line(3, "debug", "three") + line(4, "warn", "four")); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Close()
+	waitFor("msg=two")
+
+	// A truncated file is read again from the start
+	if err := os.WriteFile(path, []byte(line(5, "info", "five")+line(6, "info", "six")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor("msg=five")
+
+	cancel()
+
+	if code := <-done; code != ExitOK {
+		t.Fatalf("Run() = %d, stderr:\n%s", code, stderr.String())
+	}
+
+	// The entries held at exit are written too
+	want := line(1, "INFO", "one") + line(2, "ERROR", "two") + line(4, "WARN", "four") + line(5, "INFO", "five") + line(6, "INFO", "six")
+	if got := stdout.String(); got != want {
+		t.Errorf("stdout =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"2024-03-01T10:00:00Z", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{"2024-03-01 10:00:00", time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{"2024-03-01", time.Date(2024, 2, 29, 23, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimeFlag(tt.in, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseTimeFlag(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseTimeFlag("soon", now); err == nil {
+		t.Error("ParseTimeFlag(soon) succeeded")
+	}
+}
+
+func TestStdinIsPipe(t *testing.T) {
+	if !StdinIsPipe(strings.NewReader("")) {
+		t.Error("StdinIsPipe(strings.Reader) = false")
+	}
+
+	f, err := os.Open("testdata/app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if !StdinIsPipe(f) {
+		t.Error("StdinIsPipe(regular file) = false")
+	}
+}
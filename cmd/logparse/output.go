@@ -0,0 +1,98 @@
+package logparse
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/yildizm/go-logparser"
+)
+
+// EntryWriter writes entries in an output format, one at a time
+type EntryWriter interface {
+	// WriteEntry writes one entry, or holds it until Close
+	WriteEntry(e logparser.LogEntry) error
+	// Close writes any held entries
+	Close() error
+}
+
+// NewEntryWriter returns a writer for one of Outputs. JSON, logfmt, and
+// text entries are written as they arrive. CSV entries are held until
+// Close, since the columns are the timestamp, level, and message followed
+// by every field name seen, sorted.
+func NewEntryWriter(w io.Writer, output string) (EntryWriter, error) {
+	switch output {
+	case "json":
+		return &lineWriter{w: w, format: logparser.FormatJSON}, nil
+	case "logfmt":
+		return &lineWriter{w: w, format: logparser.FormatLogfmt}, nil
+	case "text":
+		return &textWriter{w: w}, nil
+	case "csv":
+		return &csvWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output %q", output)
+	}
+}
+
+// lineWriter writes JSON or logfmt lines
+type lineWriter struct {
+	w      io.Writer
+	format logparser.Format
+}
+
+func (lw *lineWriter) WriteEntry(e logparser.LogEntry) error {
+	return logparser.WriteEntries(lw.w, []logparser.LogEntry{e}, lw.format)
+}
+
+func (lw *lineWriter) Close() error {
+	return nil
+}
+
+// textWriter writes aligned text lines
+type textWriter struct {
+	w io.Writer
+}
+
+func (tw *textWriter) WriteEntry(e logparser.LogEntry) error {
+	return logparser.WriteText(tw.w, []logparser.LogEntry{e}, logparser.Formatter{})
+}
+
+func (tw *textWriter) Close() error {
+	return nil
+}
+
+// csvWriter holds entries and writes them as CSV on Close
+type csvWriter struct {
+	w       io.Writer
+	entries []logparser.LogEntry
+}
+
+func (cw *csvWriter) WriteEntry(e logparser.LogEntry) error {
+	cw.entries = append(cw.entries, e)
+
+	return nil
+}
+
+func (cw *csvWriter) Close() error {
+	seen := make(map[string]bool)
+
+	var fields []string
+
+	for i := range cw.entries {
+		for k := range cw.entries[i].Fields {
+			if !seen[k] {
+				seen[k] = true
+				fields = append(fields, k)
+			}
+		}
+	}
+
+	sort.Strings(fields)
+
+	columns := append([]string{"timestamp", "level", "message"}, fields...)
+	err := logparser.WriteCSV(cw.w, cw.entries, columns)
+	cw.entries = nil
+
+	return err
+}
@@ -0,0 +1,327 @@
+package logparser
+
+import (
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// MaxFieldColumns is the number of field keys EntryColumns stores as
+// columns; other keys are kept in per-row maps
+const MaxFieldColumns = 64
+
+// ZeroTimestamp stands for the zero time.Time in EntryColumns.Timestamps
+const ZeroTimestamp = math.MinInt64
+
+// overflowLevelID marks a row whose level did not fit the level dictionary
+const overflowLevelID = 255
+
+// EntryColumns holds entries as parallel slices rather than one LogEntry
+// with its own Fields map each, so large results cost far less memory and
+// garbage collection work. Row i of every column belongs to entry i.
+// Timestamps are stored as Unix nanoseconds, so they must fall between the
+// years 1678 and 2262, and come back in UTC.
+type EntryColumns struct {
+	Timestamps []int64        // Unix nanoseconds; ZeroTimestamp for the zero time
+	Levels     []uint8        // Index into LevelNames
+	LevelNames []string       // Level dictionary; LevelNames[0] is ""
+	Messages   []string       // Entry messages
+	Fields     []*FieldColumn // Sparse columns for up to MaxFieldColumns keys
+
+	columns       map[string]*FieldColumn
+	levelIndex    map[string]uint8
+	levelOverflow map[int]string                 // Levels of rows beyond 254 distinct levels
+	overflow      map[int]map[string]interface{} // Fields of rows whose keys have no column
+	sourceNames   []string                       // Source columns, filled once any entry has a Source
+	sourceLines   []int32
+	sourceOffsets []int64
+	services      []string // Service and logger columns, filled once any entry has one
+	loggers       []string
+}
+
+// FieldColumn holds the values of one field key for the rows that have it
+type FieldColumn struct {
+	Key    string
+	Rows   []int32 // Rows holding the key, ascending
+	Values []interface{}
+}
+
+// ParseColumns parses logs from a reader with p into columns. Entries are moved
+// into the columns as they are parsed, so their Fields maps never outlive
+// parsing. Keys get columns in the order they are first seen. When a tail
+// limit is set the entries are kept whole until the end. Parsers not made by
+// this package parse with Parse, and their entries are appended afterwards.
+func ParseColumns(p Parser, r io.Reader) (*EntryColumns, error) {
+	pp, ok := p.(*parser)
+	if !ok {
+		entries, err := p.Parse(r)
+
+		cols := NewEntryColumns()
+		for i := range entries {
+			cols.Append(entries[i])
+		}
+
+		return cols, err
+	}
+
+	run := pp.newRun(runInput{name: pp.cfg.sourceName})
+	if pp.cfg.tailLimit <= 0 {
+		run.columns = NewEntryColumns()
+	}
+
+	entries, _, err := run.read(r, 0)
+
+	cols := run.columns
+	if cols == nil {
+		cols = NewEntryColumns()
+	}
+
+	for i := range entries {
+		cols.Append(entries[i])
+	}
+
+	return cols, err
+}
+
+// NewEntryColumns returns empty columns
+func NewEntryColumns() *EntryColumns {
+	return &EntryColumns{
+		LevelNames: []string{""},
+		columns:    make(map[string]*FieldColumn),
+		levelIndex: map[string]uint8{"": 0},
+	}
+}
+
+// ToColumns converts entries to columns, giving the MaxFieldColumns most
+// common field keys their own columns
+func ToColumns(entries []LogEntry) *EntryColumns {
+	counts := make(map[string]int)
+
+	for i := range entries {
+		for k := range entries[i].Fields {
+			counts[k]++
+		}
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	cols := NewEntryColumns()
+	cols.Timestamps = make([]int64, 0, len(entries))
+	cols.Levels = make([]uint8, 0, len(entries))
+	cols.Messages = make([]string, 0, len(entries))
+
+	for _, k := range keys[:min(len(keys), MaxFieldColumns)] {
+		cols.column(k, counts[k])
+	}
+
+	for i := range entries {
+		cols.Append(entries[i])
+	}
+
+	return cols
+}
+
+// Len returns the number of rows
+func (c *EntryColumns) Len() int {
+	return len(c.Messages)
+}
+
+// Append adds an entry as the last row
+func (c *EntryColumns) Append(entry LogEntry) {
+	row := len(c.Messages)
+
+	ts := int64(ZeroTimestamp)
+	if !entry.Timestamp.IsZero() {
+		ts = entry.Timestamp.UnixNano()
+	}
+
+	c.Timestamps = append(c.Timestamps, ts)
+	c.Levels = append(c.Levels, c.levelID(row, entry.Level))
+	c.Messages = append(c.Messages, entry.Message)
+
+	for k, v := range entry.Fields {
+		col := c.columns[k]
+		if col == nil && len(c.Fields) < MaxFieldColumns {
+			col = c.column(k, 0)
+		}
+
+		if col != nil {
+			col.Rows = append(col.Rows, int32(row)) //nolint:gosec // row counts stay far below 2^31
+			col.Values = append(col.Values, v)
+
+			continue
+		}
+
+		if c.overflow == nil {
+			c.overflow = make(map[int]map[string]interface{})
+		}
+
+		if c.overflow[row] == nil {
+			c.overflow[row] = make(map[string]interface{})
+		}
+
+		c.overflow[row][k] = v
+	}
+
+	if entry.Source != nil && c.sourceLines == nil {
+		c.sourceNames = make([]string, row, cap(c.Messages))
+		c.sourceLines = make([]int32, row, cap(c.Messages))
+		c.sourceOffsets = make([]int64, row, cap(c.Messages))
+	}
+
+	if c.sourceLines != nil {
+		var src Source
+		if entry.Source != nil {
+			src = *entry.Source
+		}
+
+		c.sourceNames = append(c.sourceNames, src.Name)
+		c.sourceLines = append(c.sourceLines, int32(src.Line)) //nolint:gosec // line numbers stay far below 2^31
+		c.sourceOffsets = append(c.sourceOffsets, src.Offset)
+	}
+
+	if (entry.Service != "" || entry.Logger != "") && c.services == nil {
+		c.services = make([]string, row, cap(c.Messages))
+		c.loggers = make([]string, row, cap(c.Messages))
+	}
+
+	if c.services != nil {
+		c.services = append(c.services, entry.Service)
+		c.loggers = append(c.loggers, entry.Logger)
+	}
+}
+
+// column adds a field column with room for n rows
+func (c *EntryColumns) column(key string, n int) *FieldColumn {
+	col := &FieldColumn{Key: key, Rows: make([]int32, 0, n), Values: make([]interface{}, 0, n)}
+	c.columns[key] = col
+	c.Fields = append(c.Fields, col)
+
+	return col
+}
+
+// levelID returns the dictionary index for a level, adding it if new
+func (c *EntryColumns) levelID(row int, level string) uint8 {
+	if id, ok := c.levelIndex[level]; ok {
+		return id
+	}
+
+	if len(c.LevelNames) == overflowLevelID {
+		if c.levelOverflow == nil {
+			c.levelOverflow = make(map[int]string)
+		}
+
+		c.levelOverflow[row] = level
+
+		return overflowLevelID
+	}
+
+	id := uint8(len(c.LevelNames))
+	c.levelIndex[level] = id
+	c.LevelNames = append(c.LevelNames, level)
+
+	return id
+}
+
+// Level returns the level of row i
+func (c *EntryColumns) Level(i int) string {
+	if id := c.Levels[i]; id != overflowLevelID {
+		return c.LevelNames[id]
+	}
+
+	return c.levelOverflow[i]
+}
+
+// Time returns the timestamp of row i
+func (c *EntryColumns) Time(i int) time.Time {
+	if c.Timestamps[i] == ZeroTimestamp {
+		return time.Time{}
+	}
+
+	return time.Unix(0, c.Timestamps[i]).UTC()
+}
+
+// Field returns the value of key in row i
+func (c *EntryColumns) Field(i int, key string) (interface{}, bool) {
+	if col := c.columns[key]; col != nil {
+		return col.value(i)
+	}
+
+	v, ok := c.overflow[i][key]
+
+	return v, ok
+}
+
+// Column returns the column for key, or nil if the key has none
+func (c *EntryColumns) Column(key string) *FieldColumn {
+	return c.columns[key]
+}
+
+// value returns the column's value in row i
+func (col *FieldColumn) value(i int) (interface{}, bool) {
+	j := sort.Search(len(col.Rows), func(j int) bool { return int(col.Rows[j]) >= i })
+	if j == len(col.Rows) || int(col.Rows[j]) != i {
+		return nil, false
+	}
+
+	return col.Values[j], true
+}
+
+// Entry rebuilds the entry in row i. Fields is nil when the row has none.
+func (c *EntryColumns) Entry(i int) LogEntry {
+	entry := LogEntry{
+		Timestamp: c.Time(i),
+		Level:     c.Level(i),
+		Message:   c.Messages[i],
+	}
+
+	for _, col := range c.Fields {
+		if v, ok := col.value(i); ok {
+			if entry.Fields == nil {
+				entry.Fields = make(map[string]interface{})
+			}
+
+			entry.Fields[col.Key] = v
+		}
+	}
+
+	for k, v := range c.overflow[i] {
+		if entry.Fields == nil {
+			entry.Fields = make(map[string]interface{})
+		}
+
+		entry.Fields[k] = v
+	}
+
+	if c.services != nil {
+		entry.Service, entry.Logger = c.services[i], c.loggers[i]
+	}
+
+	if c.sourceLines != nil && c.sourceLines[i] != 0 {
+		entry.Source = &Source{Name: c.sourceNames[i], Line: int(c.sourceLines[i]), Offset: c.sourceOffsets[i]}
+	}
+
+	return entry
+}
+
+// Entries rebuilds every entry
+func (c *EntryColumns) Entries() []LogEntry {
+	entries := make([]LogEntry, c.Len())
+	for i := range entries {
+		entries[i] = c.Entry(i)
+	}
+
+	return entries
+}
@@ -0,0 +1,125 @@
+package logparser
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseColumnsMatchesParse(t *testing.T) {
+	for _, name := range []string{"klog.log", "nginx_error.log", "postgres.log", "report.log", "ecs.log"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parser := New(WithSourceName(name), WithSkipInvalid(true))
+
+			want, err := parser.Parse(strings.NewReader(string(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cols, err := ParseColumns(parser, strings.NewReader(string(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cols.Len() != len(want) {
+				t.Fatalf("Len() = %d, want %d", cols.Len(), len(want))
+			}
+
+			for i, entry := range cols.Entries() {
+				if !EntryEqual(entry, want[i]) {
+					t.Errorf("entry %d differs:\n%s", i, DiffEntries(entry, want[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestParseColumnsTailLimit(t *testing.T) {
+	input := "level=info msg=a\nlevel=warn msg=b\nlevel=error msg=c\n"
+
+	cols, err := ParseColumns(New(WithTailLimit(2)), strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cols.Len() != 2 || cols.Messages[0] != "b" || cols.Level(1) != LevelError {
+		t.Errorf("columns = %v %v, want the last two entries", cols.Messages, cols.LevelNames)
+	}
+}
+
+func TestToColumns(t *testing.T) {
+	entries, err := New().ParseString(strings.Join([]string{
+		`{"level":"info","msg":"a","user":"ann","status":200}`,
+		`{"level":"info","msg":"b","status":500}`,
+		`{"level":"error","msg":"c"}`,
+	}, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cols := ToColumns(entries)
+
+	if got := cols.LevelNames; !reflect.DeepEqual(got, []string{"", LevelInfo, LevelError}) {
+		t.Errorf("LevelNames = %v", got)
+	}
+
+	if cols.Fields[0].Key != "status" || cols.Fields[1].Key != "user" {
+		t.Errorf("column order = %s, %s; want status, user", cols.Fields[0].Key, cols.Fields[1].Key)
+	}
+
+	if col := cols.Column("status"); !reflect.DeepEqual(col.Rows, []int32{0, 1}) {
+		t.Errorf("status rows = %v, want [0 1]", col.Rows)
+	}
+
+	if v, ok := cols.Field(1, "user"); ok {
+		t.Errorf("Field(1, user) = %v, want none", v)
+	}
+
+	for i := range entries {
+		if got := cols.Entry(i); !EntryEqual(got, entries[i]) {
+			t.Errorf("Entry(%d) differs:\n%s", i, DiffEntries(got, entries[i]))
+		}
+	}
+
+	if got := cols.Time(0).Location(); got.String() != "UTC" {
+		t.Errorf("Time location = %v, want UTC", got)
+	}
+}
+
+func TestEntryColumnsOverflow(t *testing.T) {
+	cols := NewEntryColumns()
+
+	var entries []LogEntry
+
+	for i := range 300 {
+		fields := make(map[string]interface{})
+		for k := range 3 {
+			fields[fmt.Sprintf("k%d", 3*i+k)] = i
+		}
+
+		entry := LogEntry{Level: fmt.Sprintf("L%d", i), Message: "m", Fields: fields}
+		entries = append(entries, entry)
+		cols.Append(entry)
+	}
+
+	if len(cols.Fields) != MaxFieldColumns {
+		t.Errorf("got %d columns, want %d", len(cols.Fields), MaxFieldColumns)
+	}
+
+	if len(cols.LevelNames) != overflowLevelID {
+		t.Errorf("got %d level names, want %d", len(cols.LevelNames), overflowLevelID)
+	}
+
+	for i := range entries {
+		if got := cols.Entry(i); !EntryEqual(got, entries[i]) {
+			t.Fatalf("Entry(%d) differs:\n%s", i, DiffEntries(got, entries[i]))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+)
+
+const commentedLog = `#Software: Example HTTP Server 1.0
+#Fields: time level msg
+
+time=2024-01-02T03:04:05Z level=info msg=started
+   # indented comment
+
+time=2024-01-02T03:04:06Z level=error msg="disk full"
+`
+
+func TestBlankAndCommentLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		entries   int
+		firstLine int
+		stats     Stats
+	}{
+		{
+			// Blank lines are passed over; the unmarked comments become text entries
+			name:      "default",
+			opts:      []Option{WithSourceName("w3c.log")},
+			entries:   5,
+			firstLine: 1,
+			stats:     Stats{LinesSeen: 5, BlankLines: 2, EntriesEmitted: 5, Detections: 1},
+		},
+		{
+			name:      "comment prefix",
+			opts:      []Option{WithSourceName("w3c.log"), WithCommentPrefix("#")},
+			entries:   2,
+			firstLine: 4,
+			stats:     Stats{LinesSeen: 2, BlankLines: 2, CommentLines: 3, EntriesEmitted: 2, Detections: 1},
+		},
+		{
+			name:      "keep blank lines",
+			opts:      []Option{WithSourceName("w3c.log"), WithCommentPrefix("#"), WithKeepBlankLines(true)},
+			entries:   2,
+			firstLine: 4,
+			stats:     Stats{LinesSeen: 4, BlankLines: 2, CommentLines: 3, EntriesEmitted: 2, Detections: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := New(tt.opts...)
+
+			entries, stats, err := ParseWithStats(parser, strings.NewReader(commentedLog))
+			if err != nil {
+				t.Fatalf("ParseWithStats() error = %v", err)
+			}
+
+			if len(entries) != tt.entries {
+				t.Fatalf("got %d entries, want %d", len(entries), tt.entries)
+			}
+
+			if stats.LinesSeen != tt.stats.LinesSeen || stats.BlankLines != tt.stats.BlankLines ||
+				stats.CommentLines != tt.stats.CommentLines || stats.EntriesEmitted != tt.stats.EntriesEmitted {
+				t.Errorf("Stats = %+v, want %+v", stats, tt.stats)
+			}
+
+			if got := entries[0].Source.Line; got != tt.firstLine {
+				t.Errorf("first entry on line %d, want %d", got, tt.firstLine)
+			}
+
+			// Line numbers stay physical after blank and comment lines
+			if got := entries[len(entries)-1].Source.Line; got != 7 {
+				t.Errorf("last entry on line %d, want 7", got)
+			}
+
+			// ParseString walks the same cursor and agrees on positions
+			fromString, err := parser.ParseString(commentedLog)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			for i := range entries {
+				if *fromString[i].Source != *entries[i].Source {
+					t.Errorf("entry %d: ParseString source %+v, Parse source %+v", i, fromString[i].Source, entries[i].Source)
+				}
+			}
+		})
+	}
+}
+
+func TestCommentLinesSkipDetection(t *testing.T) {
+	log := "# exported by logrotate\n# 2024-01-02\n{\"level\":\"info\",\"msg\":\"a\"}\n{\"level\":\"warn\",\"msg\":\"b\"}\n"
+
+	entries, err := New(WithCommentPrefix("#", "//"), WithMinDetectionConfidence(1)).ParseString(log)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if len(entries) != 2 || entries[1].Level != "WARN" {
+		t.Errorf("got %+v, want the two JSON entries", entries)
+	}
+}
@@ -0,0 +1,183 @@
+package logparser
+
+import (
+	"encoding/json"
+	"time"
+	"unsafe"
+)
+
+// CompactOption configures Compact
+type CompactOption func(*compactConfig)
+
+type compactConfig struct {
+	maxMessage int
+	dropDebug  bool
+}
+
+// WithCompactMaxMessage truncates messages longer than n bytes, as
+// WithMaxFieldSize does, so the original message can be freed
+func WithCompactMaxMessage(n int) CompactOption {
+	return func(c *compactConfig) {
+		c.maxMessage = n
+	}
+}
+
+// WithCompactDropDebug leaves DEBUG and TRACE entries out of the result
+func WithCompactDropDebug(drop bool) CompactOption {
+	return func(c *compactConfig) {
+		c.dropDebug = drop
+	}
+}
+
+// Compact returns copies of entries for long-term retention, keeping only
+// the top-level fields named in keep, each in a map sized to fit. Messages
+// and field values are shared with the input rather than copied, unless a
+// message is truncated. Entries left without fields get a nil Fields map.
+// The input is not modified.
+func Compact(entries []LogEntry, keep []string, opts ...CompactOption) []LogEntry {
+	var cfg compactConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	n := 0
+
+	for i := range entries {
+		if !cfg.drops(&entries[i]) {
+			n++
+		}
+	}
+
+	out := make([]LogEntry, 0, n)
+
+	for i := range entries {
+		entry := entries[i]
+		if cfg.drops(&entry) {
+			continue
+		}
+
+		if cfg.maxMessage > 0 {
+			entry.Message, _ = truncateValue(entry.Message, cfg.maxMessage)
+		}
+
+		entry.Fields = compactFields(entry.Fields, keep)
+		out = append(out, entry)
+	}
+
+	return out
+}
+
+// drops reports whether Compact leaves the entry out
+func (c *compactConfig) drops(entry *LogEntry) bool {
+	return c.dropDebug && (entry.Level == "DEBUG" || entry.Level == "TRACE")
+}
+
+// compactFields returns a new map holding the keep keys of fields, or nil
+// if it would be empty
+func compactFields(fields map[string]interface{}, keep []string) map[string]interface{} {
+	n := 0
+
+	for _, k := range keep {
+		if _, ok := fields[k]; ok {
+			n++
+		}
+	}
+
+	if n == 0 {
+		return nil
+	}
+
+	m := make(map[string]interface{}, n)
+
+	for _, k := range keep {
+		if v, ok := fields[k]; ok {
+			m[k] = v
+		}
+	}
+
+	return m
+}
+
+// Approximate heap costs used by EstimateSize
+const (
+	sizeEntry       = int64(unsafe.Sizeof(LogEntry{}))
+	sizeSource      = int64(unsafe.Sizeof(Source{}))
+	sizeString      = int64(unsafe.Sizeof(""))
+	sizeIface       = int64(unsafe.Sizeof(interface{}(nil)))
+	sizeMapHeader   = 48
+	sizeMapSlots    = 8   // Slots per map group
+	sizeMapLoad     = 7.0 // Average filled slots per group
+	sizeMapGroupTag = 8   // Control bytes per group
+)
+
+// EstimateSize approximates the heap bytes held by entries: the slice, the
+// message and field strings, the Fields maps with their groups of slots,
+// and the values boxed in interfaces, recursively for nested objects and
+// arrays. Strings are counted wherever they appear, even when several
+// entries share one, except level names, which are shared constants.
+// Allocator rounding is ignored, so expect the runtime to report somewhat
+// more.
+func EstimateSize(entries []LogEntry) int64 {
+	size := int64(cap(entries)) * sizeEntry
+
+	for i := range entries {
+		e := &entries[i]
+		size += int64(len(e.Message)) + estimateMap(e.Fields)
+
+		if e.Source != nil {
+			size += sizeSource + int64(len(e.Source.Name))
+		}
+	}
+
+	return size
+}
+
+// estimateMap approximates the heap bytes of a map and its contents
+func estimateMap(m map[string]interface{}) int64 {
+	if m == nil {
+		return 0
+	}
+
+	groups := int64(float64(len(m))/sizeMapLoad) + 1
+	size := sizeMapHeader + groups*(sizeMapGroupTag+sizeMapSlots*(sizeString+sizeIface))
+
+	for k, v := range m {
+		size += int64(len(k)) + estimateValue(v)
+	}
+
+	return size
+}
+
+// estimateValue approximates the heap bytes boxed in an interface value
+func estimateValue(v interface{}) int64 {
+	switch val := v.(type) {
+	case nil, bool:
+		return 0
+	case string:
+		return sizeString + int64(len(val))
+	case json.Number:
+		return sizeString + int64(len(val))
+	case float64, int64, int, time.Duration, uint64:
+		return 8
+	case time.Time:
+		return int64(unsafe.Sizeof(val))
+	case map[string]interface{}:
+		return 8 + estimateMap(val)
+	case []interface{}:
+		size := int64(unsafe.Sizeof(val)) + int64(cap(val))*sizeIface
+		for _, item := range val {
+			size += estimateValue(item)
+		}
+
+		return size
+	case []string:
+		size := int64(unsafe.Sizeof(val)) + int64(cap(val))*sizeString
+		for _, s := range val {
+			size += int64(len(s))
+		}
+
+		return size
+	default:
+		return 16
+	}
+}
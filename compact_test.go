@@ -0,0 +1,97 @@
+package logparser
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	entries, err := New().ParseString(strings.Join([]string{
+		`{"level":"info","msg":"order placed for a customer who waited","order_id":"o-1","user":"ann","trace":{"id":"t1"}}`,
+		`{"level":"debug","msg":"cache miss","key":"k"}`,
+		`{"level":"trace","msg":"enter","fn":"f"}`,
+		`{"level":"error","msg":"short","user":"bob"}`,
+	}, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := Compact(entries, []string{"order_id", "trace", "missing"}, WithCompactMaxMessage(12), WithCompactDropDebug(true))
+
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+
+	if got[0].Message != "order placed…[truncated 26 bytes]" || len(got[0].Fields) != 2 || got[0].Fields["order_id"] != "o-1" {
+		t.Errorf("entry 0 = %+v", got[0])
+	}
+
+	if got[1].Message != "short" || got[1].Fields != nil {
+		t.Errorf("entry 1 = %+v, want nil Fields", got[1])
+	}
+
+	// The input is left alone
+	if entries[0].Fields["user"] != "ann" || !strings.HasPrefix(entries[0].Message, "order placed for") {
+		t.Errorf("input modified: %+v", entries[0])
+	}
+
+	if n := len(Compact(entries, nil)); n != 4 {
+		t.Errorf("Compact without options kept %d entries, want 4", n)
+	}
+}
+
+// estimateEntries returns n entries with distinct strings, so their heap
+// cost is what EstimateSize counts
+func estimateEntries(n int) []LogEntry {
+	entries := make([]LogEntry, n)
+
+	for i := range entries {
+		entries[i] = LogEntry{
+			Level:   LevelInfo,
+			Message: fmt.Sprintf("request %d handled by worker %d after a short wait", i, i%7),
+			Fields: map[string]interface{}{
+				"request_id": fmt.Sprintf("req-%08d", i),
+				"status":     float64(200 + i%5),
+				"user":       fmt.Sprintf("user-%d", i%1000),
+				"ok":         true,
+			},
+		}
+	}
+
+	return entries
+}
+
+func TestEstimateSize(t *testing.T) {
+	const n = 200000
+
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	entries := estimateEntries(n)
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	actual := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	estimate := EstimateSize(entries)
+
+	runtime.KeepAlive(entries)
+
+	if ratio := float64(estimate) / float64(actual); ratio < 0.6 || ratio > 1.25 {
+		t.Errorf("EstimateSize = %d, runtime reports %d (ratio %.2f)", estimate, actual, ratio)
+	}
+
+	if est := EstimateSize(Compact(entries, []string{"status"})); est >= estimate {
+		t.Errorf("estimate with one field kept %d, want under %d", est, estimate)
+	}
+
+	if est := EstimateSize(Compact(entries, nil)); est >= estimate/2 {
+		t.Errorf("estimate without fields %d, want under half of %d", est, estimate)
+	}
+
+	t.Logf("estimate %d, runtime %d bytes", estimate, actual)
+}
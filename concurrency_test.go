@@ -0,0 +1,95 @@
+package logparser
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yildizm/go-logparser/internal/testgen"
+)
+
+func TestSharedParserConcurrentUse(t *testing.T) {
+	inputs := []string{
+		string(testgen.Generate(testgen.JSON, 200, 1)),
+		string(testgen.Generate(testgen.Logfmt, 200, 2)),
+		string(testgen.Generate(testgen.Text, 200, 3)),
+		string(testgen.Generate(testgen.Mixed, 200, 4)),
+	}
+
+	for _, fixture := range []string{"testdata/postgres.log", "testdata/nginx_error.log", "testdata/logcat.log"} {
+		data, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		inputs = append(inputs, string(data))
+	}
+
+	parser := New(
+		WithSkipInvalid(true),
+		WithNestedParsing(2),
+		WithDurationFields("duration"),
+		WithTransform(LowercaseKeys()),
+	)
+
+	// Results from a single goroutine are the reference
+	want := make([][]string, len(inputs))
+
+	for i, input := range inputs {
+		entries, err := parser.ParseString(input)
+		if err != nil {
+			t.Fatalf("ParseString(input %d) error = %v", i, err)
+		}
+
+		want[i] = messages(entries)
+	}
+
+	const goroutines = 32
+
+	var wg sync.WaitGroup
+
+	errs := make(chan string, goroutines)
+
+	for g := range goroutines {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for round := range len(inputs) {
+				i := (g + round) % len(inputs)
+
+				entries, _, err := ParseWithStats(parser, strings.NewReader(inputs[i]))
+				if err != nil {
+					errs <- err.Error()
+
+					return
+				}
+
+				if got := messages(entries); strings.Join(got, "\n") != strings.Join(want[i], "\n") {
+					errs <- "concurrent result differs for input " + inputs[i][:20]
+
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// messages returns the message of each entry
+func messages(entries []LogEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Message
+	}
+
+	return out
+}
@@ -0,0 +1,36 @@
+package logparser
+
+// ConflictPolicy selects how disagreeing keys for the same standard field,
+// such as "time" and "@timestamp" or "level" and "severity", are handled
+type ConflictPolicy int
+
+const (
+	// ConflictIgnore takes the first candidate key in precedence order and
+	// looks no further
+	ConflictIgnore ConflictPolicy = iota
+	// ConflictRecord takes the first candidate key in precedence order and
+	// records the candidates that disagree with it in Fields["_conflicts"]
+	ConflictRecord
+	// ConflictResolve is ConflictRecord, except that the most severe level
+	// and the earliest timestamp win over precedence order
+	ConflictResolve
+)
+
+// WithConflicts sets how JSON and logfmt lines carrying several candidate
+// keys for the timestamp, level, or message are handled. Under
+// ConflictRecord and ConflictResolve, each candidate whose value differs
+// from the extracted one, after parsing timestamps and mapping level
+// aliases, is listed in Fields["_conflicts"] as a map with "field"
+// ("timestamp", "level", or "message"), "key", and "value"; the losing keys
+// also stay in Fields as before. Entries with conflicts are counted in
+// Stats.ConflictedEntries. Defaults to ConflictIgnore.
+func WithConflicts(policy ConflictPolicy) Option {
+	return func(c *config) {
+		c.conflicts = policy
+	}
+}
+
+// stdConflict describes a candidate key that lost to the extracted one
+func stdConflict(field, key string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{"field": field, "key": key, "value": value}
+}
@@ -0,0 +1,132 @@
+package logparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConflicts(t *testing.T) {
+	input := strings.Join([]string{
+		// Two-way timestamp conflict
+		`{"time":"2024-01-02T15:04:05Z","@timestamp":"2024-01-02T15:04:01Z","level":"info","msg":"a"}`,
+		// Three-way level conflict; "warning" and "WARN" agree
+		`{"level":"info","severity":"error","loglevel":"warning","@l":"WARN","msg":"b"}`,
+		// Same value in different spellings is no conflict
+		`{"time":"2024-01-02T15:04:05Z","ts":"2024-01-02T16:04:05+01:00","level":"warn","severity":"WARNING","msg":"c"}`,
+		// Two-way level and two-way message conflict in logfmt
+		`level=debug loglevel=fatal msg=d message=other`,
+		// Three-way timestamp conflict in logfmt
+		`timestamp=2024-01-02T15:04:05Z time=2024-01-02T15:04:09Z ts=2024-01-02T15:04:02Z level=info msg=e`,
+	}, "\n")
+
+	tests := []struct {
+		policy ConflictPolicy
+		levels []string
+		times  []time.Time
+		want   [][]map[string]interface{}
+	}{
+		{
+			policy: ConflictIgnore,
+			levels: []string{LevelInfo, LevelInfo, "WARN", "DEBUG", LevelInfo},
+			times:  []time.Time{time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), {}, {}, {}, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+			want:   make([][]map[string]interface{}, 5),
+		},
+		{
+			policy: ConflictRecord,
+			levels: []string{LevelInfo, LevelInfo, "WARN", "DEBUG", LevelInfo},
+			times:  []time.Time{time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), {}, {}, {}, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+			want: [][]map[string]interface{}{
+				{{"field": "timestamp", "key": "@timestamp", "value": "2024-01-02T15:04:01Z"}},
+				{
+					{"field": "level", "key": "severity", "value": "error"},
+					{"field": "level", "key": "loglevel", "value": "warning"},
+					{"field": "level", "key": "@l", "value": "WARN"},
+				},
+				nil,
+				{
+					{"field": "level", "key": "loglevel", "value": "fatal"},
+					{"field": "message", "key": "message", "value": "other"},
+				},
+				{
+					{"field": "timestamp", "key": "time", "value": "2024-01-02T15:04:09Z"},
+					{"field": "timestamp", "key": "ts", "value": "2024-01-02T15:04:02Z"},
+				},
+			},
+		},
+		{
+			policy: ConflictResolve,
+			levels: []string{LevelInfo, LevelError, "WARN", "FATAL", LevelInfo},
+			times:  []time.Time{time.Date(2024, 1, 2, 15, 4, 1, 0, time.UTC), {}, {}, {}, time.Date(2024, 1, 2, 15, 4, 2, 0, time.UTC)},
+			want: [][]map[string]interface{}{
+				{{"field": "timestamp", "key": "time", "value": "2024-01-02T15:04:05Z"}},
+				{
+					{"field": "level", "key": "level", "value": "info"},
+					{"field": "level", "key": "loglevel", "value": "warning"},
+					{"field": "level", "key": "@l", "value": "WARN"},
+				},
+				nil,
+				{
+					{"field": "level", "key": "level", "value": "debug"},
+					{"field": "message", "key": "message", "value": "other"},
+				},
+				{
+					{"field": "timestamp", "key": "timestamp", "value": "2024-01-02T15:04:05Z"},
+					{"field": "timestamp", "key": "time", "value": "2024-01-02T15:04:09Z"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		entries, stats, err := ParseWithStats(New(WithConflicts(tt.policy), WithBlockDetection()), strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("policy %d: %v", tt.policy, err)
+		}
+
+		conflicted := 0
+
+		for i, e := range entries {
+			if e.Level != tt.levels[i] {
+				t.Errorf("policy %d, entry %d: level %s, want %s", tt.policy, i, e.Level, tt.levels[i])
+			}
+
+			if !tt.times[i].IsZero() && !e.Timestamp.Equal(tt.times[i]) {
+				t.Errorf("policy %d, entry %d: timestamp %v, want %v", tt.policy, i, e.Timestamp, tt.times[i])
+			}
+
+			var got []map[string]interface{}
+			if list, ok := e.Fields["_conflicts"].([]interface{}); ok {
+				conflicted++
+
+				for _, c := range list {
+					got = append(got, c.(map[string]interface{}))
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want[i]) {
+				t.Errorf("policy %d, entry %d: conflicts %v, want %v", tt.policy, i, got, tt.want[i])
+			}
+		}
+
+		if stats.ConflictedEntries != conflicted {
+			t.Errorf("policy %d: ConflictedEntries = %d, want %d", tt.policy, stats.ConflictedEntries, conflicted)
+		}
+	}
+}
+
+func TestConflictLosersStayInFields(t *testing.T) {
+	entry, err := NewLineParser(FormatJSON, WithConflicts(ConflictResolve)).Parse(`{"level":"info","severity":"error","msg":"x"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Level != LevelError || entry.Fields["level"] != "info" {
+		t.Errorf("entry = %+v, want ERROR with level kept in Fields", entry)
+	}
+
+	if _, err := NewE(WithConflicts(ConflictPolicy(9))); err == nil {
+		t.Error("NewE accepted an unknown conflict policy")
+	}
+}
@@ -0,0 +1,165 @@
+package logparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Patterns recognizing the lines of a context block
+var (
+	// An indented "key: value" line, or "key:" opening a nested block.
+	// Keys are single words, so indented prose does not qualify.
+	contextKeyRe = regexp.MustCompile(`^([ \t]+)([A-Za-z_][\w.-]*):(?:[ \t]+(.*))?$`)
+	// The unindented last line of a Python traceback, such as
+	// "ValueError: bad input" or "requests.exceptions.Timeout"
+	contextExceptionRe = regexp.MustCompile(`^(?:[A-Za-z_]\w*(?:\.|::))*[A-Z]\w*(?:Error|Exception|Exit|Interrupt|Timeout|Warning)\b`)
+)
+
+// pythonTracebackHeader starts a Python traceback
+const pythonTracebackHeader = "Traceback (most recent call last):"
+
+// WithContextBlocks folds the indented "key: value" lines some frameworks
+// print after a log line into the preceding entry's Fields, instead of
+// parsing each as an entry of its own. A "key:" line with more deeply
+// indented lines below it becomes a nested object, up to maxDepth levels;
+// deeper keys are joined with dots at the last level. Only single-word
+// keys followed by ": " qualify, so indented prose is not mistaken for
+// context. A "key:" line without nested lines holds an empty object, or
+// nothing at the depth limit. Other indented lines, and an unindented
+// Python traceback, are collected in Fields["stacktrace"], which
+// WithStacktraceParsing parses. The block ends at the first unindented
+// line that starts a new entry. Applies to the text parser; maxDepth of
+// zero or less disables it.
+func WithContextBlocks(maxDepth int) Option {
+	return func(c *config) {
+		c.contextDepth = maxDepth
+	}
+}
+
+// contextKey is a key of a context block that may hold nested keys
+type contextKey struct {
+	indent int
+	name   string
+}
+
+// contextBlock tracks the context block following a text entry
+type contextBlock struct {
+	open  bool         // The last line started an entry that can take a block
+	path  []contextKey // Keys enclosing the next line, outermost first
+	trace []string     // Stack trace lines so far
+}
+
+// fold returns the continuation entry for a line of the open block, or
+// false if the line is not part of it, which closes the block
+func (b *contextBlock) fold(line string, cfg *config) (*LogEntry, bool) {
+	if !b.open {
+		return nil, false
+	}
+
+	indented := line[0] == ' ' || line[0] == '\t'
+
+	if indented && len(b.trace) == 0 {
+		if m := contextKeyRe.FindStringSubmatch(line); m != nil {
+			return b.field(len(m[1]), m[2], m[3], cfg), true
+		}
+	}
+
+	if indented || b.startsTrace(line) {
+		b.trace = append(b.trace, line)
+
+		entry := &LogEntry{Fields: cfg.newFields()}
+		cfg.setField(entry, "stacktrace", strings.Join(b.trace, "\n"))
+
+		return entry, true
+	}
+
+	b.reset()
+
+	return nil, false
+}
+
+// startsTrace reports whether an unindented line belongs to the stack
+// trace of the block
+func (b *contextBlock) startsTrace(line string) bool {
+	if line == pythonTracebackHeader {
+		return true
+	}
+
+	return len(b.trace) > 0 && (strings.HasPrefix(line, "Caused by: ") || contextExceptionRe.MatchString(line))
+}
+
+// field returns a continuation entry holding one context key, nested
+// under the keys that enclose it
+func (b *contextBlock) field(indent int, name, value string, cfg *config) *LogEntry {
+	for len(b.path) > 0 && b.path[len(b.path)-1].indent >= indent {
+		b.path = b.path[:len(b.path)-1]
+	}
+
+	names := make([]string, 0, len(b.path)+1)
+	for _, k := range b.path {
+		names = append(names, k.name)
+	}
+
+	names = append(names, name)
+
+	depth := cfg.contextDepth
+
+	var val interface{}
+
+	switch value = strings.TrimSpace(value); {
+	case value != "":
+		val = contextValue(value)
+	case len(names) < depth:
+		b.path = append(b.path, contextKey{indent: indent, name: name})
+		val = map[string]interface{}{}
+	default:
+		// Nested keys are joined onto this one, which is not kept
+		b.path = append(b.path, contextKey{indent: indent, name: name})
+
+		return &LogEntry{}
+	}
+
+	if len(names) > depth {
+		names = append(names[:depth-1], strings.Join(names[depth-1:], "."))
+	}
+
+	for i := len(names) - 1; i > 0; i-- {
+		val = map[string]interface{}{names[i]: val}
+	}
+
+	entry := &LogEntry{Fields: cfg.newFields()}
+	cfg.setField(entry, names[0], val)
+
+	return entry
+}
+
+// reset closes the block
+func (b *contextBlock) reset() {
+	b.open = false
+	b.path = b.path[:0]
+	b.trace = nil
+}
+
+// contextValue removes the quotes around a quoted value
+func contextValue(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
+
+// mergeFields copies src into dst, merging objects present in both
+func mergeFields(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if sub, ok := v.(map[string]interface{}); ok {
+			if existing, ok := dst[k].(map[string]interface{}); ok {
+				mergeFields(existing, sub)
+
+				continue
+			}
+		}
+
+		dst[k] = v
+	}
+}
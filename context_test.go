@@ -0,0 +1,129 @@
+package logparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContextBlocksRails(t *testing.T) {
+	entries, err := ParseFile(NewWithFormat(FormatText, WithContextBlocks(3)), "testdata/rails_context.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 5 {
+		t.Fatalf("got %d entries, want 5", len(entries))
+	}
+
+	started := entries[0]
+	if started.Level != LevelInfo || started.Message != `Started GET "/users/42" for 10.0.0.5` || started.Fields["pid"] != "4021" {
+		t.Errorf("entry 0 = %+v", started)
+	}
+
+	want := map[string]interface{}{
+		"pid":        "4021",
+		"request_id": "6f1c2a9e-8b7d-4e2f-9c3a-1d5e7f9a0b12",
+		"controller": "UsersController",
+		"action":     "show",
+		"params":     map[string]interface{}{"id": "42", "format": "json"},
+	}
+
+	if !reflect.DeepEqual(started.Fields, want) {
+		t.Errorf("entry 0 fields = %v, want %v", started.Fields, want)
+	}
+
+	if timing := entries[1].Fields["timing"]; !reflect.DeepEqual(timing, map[string]interface{}{"view": "2.1", "db": "1.4"}) {
+		t.Errorf("entry 1 timing = %v", timing)
+	}
+
+	failed := entries[2]
+	if failed.Level != LevelError || failed.Fields["user_id"] != "99" {
+		t.Errorf("entry 2 = %+v", failed)
+	}
+
+	wantTrace := "  app/models/user.rb:17:in `find_visible'\n  app/controllers/users_controller.rb:8:in `show'"
+	if failed.Fields["stacktrace"] != wantTrace {
+		t.Errorf("entry 2 stacktrace = %q, want %q", failed.Fields["stacktrace"], wantTrace)
+	}
+
+	// Indented prose is not context; it is kept as text
+	if _, ok := entries[3].Fields["Notice"]; ok {
+		t.Errorf("prose parsed as a context key: %v", entries[3].Fields)
+	}
+
+	if entries[4].Message != "Shutting down" {
+		t.Errorf("entry 4 message = %q, want the line after the block", entries[4].Message)
+	}
+}
+
+func TestContextBlocksStructlog(t *testing.T) {
+	entries, err := ParseFile(NewWithFormat(FormatText, WithContextBlocks(2), WithStacktraceParsing(false)), "testdata/structlog_context.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(entries))
+	}
+
+	if e := entries[0]; e.Message != "worker started" || e.Fields["queue"] != "default" || e.Fields["concurrency"] != "4" {
+		t.Errorf("entry 0 = %+v", e)
+	}
+
+	retry := entries[1]
+	if retry.Level != "WARN" || retry.Fields["job_id"] != "881" {
+		t.Errorf("entry 1 = %+v", retry)
+	}
+
+	// Depth 2: the customer keys are joined below context
+	wantContext := map[string]interface{}{"task": "send_invoice", "customer.id": "5512", "customer.region": "eu-west-1"}
+	if got := retry.Fields["context"]; !reflect.DeepEqual(got, wantContext) {
+		t.Errorf("entry 1 context = %v, want %v", got, wantContext)
+	}
+
+	failed := entries[2]
+	if failed.Fields["worker"] != "w-3" {
+		t.Errorf("entry 2 worker = %v", failed.Fields["worker"])
+	}
+
+	trace, _ := failed.Fields["stacktrace"].(string)
+	if !strings.HasPrefix(trace, pythonTracebackHeader) || !strings.HasSuffix(trace, "ConnectionError: smtp: connection refused") {
+		t.Errorf("entry 2 stacktrace = %q", trace)
+	}
+
+	frames, _ := failed.Fields["_stack_frames"].([]Frame)
+	if len(frames) != 2 || frames[1].File != "/app/billing.py" || frames[1].Line != 17 {
+		t.Errorf("entry 2 frames = %+v", frames)
+	}
+
+	if entries[3].Message != "worker idle" {
+		t.Errorf("entry 3 message = %q", entries[3].Message)
+	}
+}
+
+func TestContextBlocksDisabled(t *testing.T) {
+	entries, err := ParseFile(NewWithFormat(FormatText), "testdata/rails_context.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the option every indented line is an entry of its own
+	if len(entries) != 20 {
+		t.Errorf("got %d entries, want 20", len(entries))
+	}
+}
+
+func TestContextBlockFlat(t *testing.T) {
+	input := "2024-01-02 15:04:05 [ERROR] failed\n  request:\n    headers:\n      host: example.com\n  status: 500\n"
+
+	entries, err := NewWithFormat(FormatText, WithContextBlocks(1)).ParseString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{"request.headers.host": "example.com", "status": "500"}
+	if len(entries) != 1 || !reflect.DeepEqual(entries[0].Fields, want) {
+		t.Errorf("entries = %+v, want fields %v", entries, want)
+	}
+}
@@ -0,0 +1,338 @@
+package logparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConvertOption configures Convert
+type ConvertOption func(*convertConfig)
+
+// convertConfig holds conversion settings
+type convertConfig struct {
+	passThrough bool
+	keyOrder    bool
+}
+
+// WithPassThrough copies lines that cannot be parsed in the source format
+// to the output unchanged instead of stopping the conversion
+func WithPassThrough(pass bool) ConvertOption {
+	return func(c *convertConfig) {
+		c.passThrough = pass
+	}
+}
+
+// WithKeyOrder writes the keys of logfmt input in the order they were
+// read, as recorded by WithPreserveOrder, instead of the standard fields
+// first and the rest in key order. Converting logfmt to logfmt this way
+// also keeps the timestamp as written under its original key, so
+// well-formed lines come out byte for byte as they went in, apart from
+// quoting, which is added only where needed, and durations, which are
+// written as Go durations.
+func WithKeyOrder(keep bool) ConvertOption {
+	return func(c *convertConfig) {
+		c.keyOrder = keep
+	}
+}
+
+// errNoPairs rejects a logfmt line without a single key=value pair
+var errNoPairs = errors.New("no key=value pairs")
+
+// Convert re-encodes the log lines read from r in format from as JSON or
+// logfmt lines in format to, one line at a time. Key names are kept as
+// written, including the keys the level and message were read from; the
+// timestamp is written under the target's conventional key, "time" for
+// logfmt and "timestamp" for JSON, unless another field already uses it.
+// Parsing the output yields entries semantically equal to parsing the
+// input: logfmt values that are the canonical text of a JSON number,
+// boolean, object, or array become that value in JSON, and JSON values
+// become their text in logfmt. JSON nulls become empty strings.
+//
+// Blank lines are dropped. A line that cannot be parsed stops the
+// conversion with a *LineError unless WithPassThrough is set.
+func Convert(r io.Reader, w io.Writer, from, to Format, opts ...ConvertOption) error {
+	if to != FormatJSON && to != FormatLogfmt {
+		return fmt.Errorf("cannot convert to %s", to)
+	}
+
+	if from == FormatWindowsEventXML {
+		return fmt.Errorf("cannot convert from %s", from)
+	}
+
+	var cfg convertConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	src, skipped := decodeInput(r)
+	scanner := newLineScanner(src, skipped, BufferSize)
+
+	defer scanner.release()
+
+	lp := NewLineParser(from, WithPreserveOriginalKeys(true), WithStripANSI(false), WithPreserveOrder(cfg.keyOrder))
+	bw := bufio.NewWriter(w)
+
+	var (
+		buf    []byte
+		lineNo int
+	)
+
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+
+		entry, err := lp.Parse(line)
+		if err == nil && from == FormatLogfmt && !strings.Contains(line, "=") {
+			err = errNoPairs
+		}
+
+		if err == nil {
+			buf, err = appendConverted(buf[:0], &entry, lp.last, to)
+		}
+
+		switch {
+		case errors.Is(err, ErrEmptyLine):
+			continue
+		case err != nil && cfg.passThrough:
+			buf = append(buf[:0], line...)
+		case err != nil:
+			if len(line) > maxLineErrorText {
+				line = line[:maxLineErrorText]
+			}
+
+			return &LineError{Line: lineNo, Text: line, Err: err}
+		}
+
+		buf = append(buf, '\n')
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// convertPair is a key and value in output order. src is the key the
+// value was read from.
+type convertPair struct {
+	key string
+	val interface{}
+	src string
+}
+
+// appendConverted appends entry, parsed from a line in format from, in
+// format to: the timestamp, level, and message first, then the remaining
+// fields in key order, or all of them in the order recorded by
+// WithPreserveOrder
+func appendConverted(buf []byte, entry *LogEntry, from, to Format) ([]byte, error) {
+	fields := make(map[string]interface{}, len(entry.Fields))
+	for k, v := range entry.Fields {
+		fields[k] = v
+	}
+
+	levelKey, _ := fields["_level_key"].(string)
+	msgKey, _ := fields["_msg_key"].(string)
+	tsKey, _ := fields["_ts_key"].(string)
+	order, _ := fields[keyOrderField].([]string)
+
+	for _, k := range []string{"_level_key", "_msg_key", "_ts_key", "_service_key", "_logger_key", keyOrderField} {
+		delete(fields, k)
+	}
+
+	pairs := make([]convertPair, 0, len(fields)+3)
+
+	// Structured lines record the keys the standard fields came from;
+	// without one the parser filled in a default that is not written.
+	// Text lines record none and keep everything.
+	text := from == FormatText
+
+	if text || tsKey != "" {
+		key := "timestamp"
+		if to == FormatLogfmt {
+			key = "time"
+		}
+
+		if _, taken := fields[key]; taken && tsKey != "" && key != tsKey {
+			key = tsKey
+		}
+
+		var ts interface{} = entry.Timestamp.Format(time.RFC3339Nano)
+
+		// Written as read, to re-emit the line unchanged
+		if order != nil && from == to {
+			key, ts = tsKey, fields[tsKey]
+		}
+
+		delete(fields, tsKey)
+		pairs = append(pairs, convertPair{key, ts, tsKey})
+	}
+
+	// Keys the target does not read the level or message from, as for
+	// text lines, which have none, are replaced by the conventional ones.
+	// A line converted to its own format keeps them, since the parser read
+	// them once already, as with the at= level of Heroku router lines.
+	srcKeys := []string{levelKey, msgKey}
+
+	if text || levelKey != "" && from != to {
+		levelKey = convertStdKey(fields, levelKey, "level", stdLevel, entry.Level, to)
+	}
+
+	if text || msgKey != "" && from != to {
+		msgKey = convertStdKey(fields, msgKey, "msg", stdMessage, entry.Message, to)
+	}
+
+	for i, k := range []string{levelKey, msgKey} {
+		if v, ok := fields[k]; ok {
+			pairs = append(pairs, convertPair{k, v, srcKeys[i]})
+			delete(fields, k)
+		}
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := fields[k]
+		if from == FormatLogfmt && to == FormatJSON {
+			v = typedValue(v)
+		}
+
+		pairs = append(pairs, convertPair{k, v, k})
+	}
+
+	if order != nil {
+		sortByKeyOrder(pairs, order)
+	}
+
+	if to == FormatLogfmt {
+		for i, p := range pairs {
+			if i > 0 {
+				buf = append(buf, ' ')
+			}
+
+			buf = appendLogfmtKey(buf, p.key)
+			buf = append(buf, '=')
+			buf = appendLogfmtValue(buf, formatValue(p.val))
+		}
+
+		return buf, nil
+	}
+
+	buf = append(buf, '{')
+
+	for i, p := range pairs {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+
+		val, err := json.Marshal(p.val)
+		if err != nil {
+			return buf, fmt.Errorf("field %q: %w", p.key, err)
+		}
+
+		buf = appendJSONString(buf, p.key)
+		buf = append(buf, ':')
+		buf = append(buf, val...)
+	}
+
+	return append(buf, '}'), nil
+}
+
+// sortByKeyOrder stably orders pairs by the position of their source key
+// in order; pairs whose key is not listed keep their place after the rest
+func sortByKeyOrder(pairs []convertPair, order []string) {
+	rank := make(map[string]int, len(order))
+	for i, k := range order {
+		rank[k] = i
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		ri, ok := rank[pairs[i].src]
+		if !ok {
+			ri = len(order)
+		}
+
+		rj, ok := rank[pairs[j].src]
+		if !ok {
+			rj = len(order)
+		}
+
+		return ri < rj
+	})
+}
+
+// convertStdKey returns the key to write a standard field under: key if
+// format to reads the field from it, and otherwise fallback, moving the
+// value there. A missing value is taken from the entry.
+func convertStdKey(fields map[string]interface{}, key, fallback string, field stdField, value string, to Format) string {
+	table := logfmtStdKeys
+	if to == FormatJSON {
+		table = jsonStdKeys
+	}
+
+	if slot, ok := table[strings.ToLower(key)]; ok && slot.field == field {
+		return key
+	}
+
+	if v, ok := fields[key]; ok {
+		value, _ = v.(string)
+		delete(fields, key)
+	}
+
+	fields[fallback] = value
+
+	return fallback
+}
+
+// typedValue returns the JSON value a logfmt string stands for when the
+// string is that value's canonical text, so that "42" becomes 42 and "true"
+// becomes true while "042" and "1e3" stay strings. Other values are
+// returned unchanged.
+func typedValue(val interface{}) interface{} {
+	s, ok := val.(string)
+	if !ok || s == "" {
+		return val
+	}
+
+	var typed interface{}
+
+	switch {
+	case s == "true" || s == "false":
+		typed = s == "true"
+	case s[0] == '-' || s[0] >= '0' && s[0] <= '9':
+		d := jsonScanner{s: s}
+
+		f, ok := d.number()
+		if !ok || d.i != len(s) {
+			return val
+		}
+
+		typed = f
+	case s[0] == '{' || s[0] == '[':
+		if json.Unmarshal([]byte(s), &typed) != nil {
+			return val
+		}
+	default:
+		return val
+	}
+
+	if formatValue(typed) != s {
+		return val
+	}
+
+	return typed
+}
@@ -0,0 +1,260 @@
+package logparser
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// convertEqual reports whether two entries carry the same information.
+// Lines without a timestamp get the time they were parsed, which differs.
+func convertEqual(a, b LogEntry, typed bool) bool {
+	parsedNow := time.Since(a.Timestamp) < time.Minute && time.Since(b.Timestamp) < time.Minute
+	if typed {
+		return EntryEqual(a, b, WithIgnoreTimestamp(parsedNow))
+	}
+
+	if !a.Timestamp.Equal(b.Timestamp) && !parsedNow {
+		return false
+	}
+
+	if a.Level != b.Level || a.Message != b.Message || len(a.Fields) != len(b.Fields) {
+		return false
+	}
+
+	// logfmt values are text, so across formats only the text must match
+	for k, v := range a.Fields {
+		if w, ok := b.Fields[k]; !ok || formatValue(v) != formatValue(w) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// convertString runs Convert over input
+func convertString(t *testing.T, input string, from, to Format, opts ...ConvertOption) string {
+	t.Helper()
+
+	var out strings.Builder
+	if err := Convert(strings.NewReader(input), &out, from, to, opts...); err != nil {
+		t.Fatalf("Convert(%s -> %s) error = %v", from, to, err)
+	}
+
+	return out.String()
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	// JSON keeps types only where logfmt text maps back to them: the
+	// string "3" returns as the number 3
+	tests := []struct {
+		name   string
+		format Format
+		other  Format
+		typed  bool
+		input  string
+	}{
+		{
+			name:   "logfmt",
+			format: FormatLogfmt,
+			other:  FormatJSON,
+			typed:  true,
+			input: `ts=2024-01-02T15:04:05.123+02:00 level=warn msg="disk almost full" used=0.93 mount=/var retries=3 ok=true code=042
+time=2024-01-02T15:04:06Z loglevel=error message="quote \" and\nnewline" payload={"a":1} empty=""
+
+level=info msg=no-timestamp tags=[1,2]`,
+		},
+		{
+			name:   "json",
+			format: FormatJSON,
+			other:  FormatLogfmt,
+			input: `{"@timestamp":"2024-01-02T15:04:05.5Z","severity":"ERROR","message":"payment failed","amount":12.5,"ok":false,"user":{"id":7,"tags":["a","b"]}}
+{"time":1704207845,"level":"info","log":"from docker","count":"3","time_ms":15}
+{"msg":"no level or time","nested":{"deep":{"x":[1,{"y":2}]}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := func(s string, format Format) []LogEntry {
+				entries, err := NewWithFormat(format).ParseString(s)
+				if err != nil {
+					t.Fatalf("ParseString(%s) error = %v\n%s", format, err, s)
+				}
+
+				return entries
+			}
+
+			original := parse(tt.input, tt.format)
+			converted := convertString(t, tt.input, tt.format, tt.other)
+			back := convertString(t, converted, tt.other, tt.format)
+
+			across := parse(converted, tt.other)
+			again := parse(back, tt.format)
+
+			if len(across) != len(original) || len(again) != len(original) {
+				t.Fatalf("got %d and %d entries, want %d\n%s\n%s", len(across), len(again), len(original), converted, back)
+			}
+
+			for i := range original {
+				if !convertEqual(original[i], across[i], false) {
+					t.Errorf("entry %d changed in %s:\n got %+v\nwant %+v", i, tt.other, across[i], original[i])
+				}
+
+				if !convertEqual(original[i], again[i], tt.typed) {
+					t.Errorf("entry %d changed after the round trip:\n%s", i, DiffEntries(original[i], again[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestConvertKeys(t *testing.T) {
+	got := convertString(t, `ts=2024-01-02T15:04:05Z LEVEL=warn msg=hi user_id=42 ratio=1e3`+"\n", FormatLogfmt, FormatJSON)
+	want := `{"timestamp":"2024-01-02T15:04:05Z","LEVEL":"warn","msg":"hi","ratio":"1e3","user_id":42}` + "\n"
+
+	if got != want {
+		t.Errorf("logfmt -> JSON\n got %s\nwant %s", got, want)
+	}
+
+	// severity and log are JSON-only names, so logfmt gets level and msg
+	got = convertString(t, `{"ts":"2024-01-02T15:04:05Z","severity":"warn","log":"hi","time":"slow"}`, FormatJSON, FormatLogfmt)
+	want = "ts=2024-01-02T15:04:05Z level=warn msg=hi time=slow\n"
+
+	if got != want {
+		t.Errorf("JSON -> logfmt\n got %s\nwant %s", got, want)
+	}
+
+	got = convertString(t, "2024-01-02 15:04:05 [ERROR] Failed to connect\n", FormatText, FormatLogfmt)
+	if want := "time=2024-01-02T15:04:05Z level=ERROR msg=\"Failed to connect\"\n"; got != want {
+		t.Errorf("text -> logfmt\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestConvertUnparseable(t *testing.T) {
+	input := "{\"msg\":\"a\"}\nnot json\n{\"msg\":\"b\"}\n"
+
+	var out strings.Builder
+
+	err := Convert(strings.NewReader(input), &out, FormatJSON, FormatLogfmt)
+
+	var le *LineError
+	if !errors.As(err, &le) || le.Line != 2 || le.Text != "not json" {
+		t.Fatalf("Convert() error = %v, want a *LineError on line 2", err)
+	}
+
+	got := convertString(t, input, FormatJSON, FormatLogfmt, WithPassThrough(true))
+	if want := "msg=a\nnot json\nmsg=b\n"; got != want {
+		t.Errorf("Convert(WithPassThrough) = %q, want %q", got, want)
+	}
+
+	got = convertString(t, "msg=a\npanic: boom\n", FormatLogfmt, FormatJSON, WithPassThrough(true))
+	if want := "{\"msg\":\"a\"}\npanic: boom\n"; got != want {
+		t.Errorf("logfmt line without pairs = %q, want %q", got, want)
+	}
+
+	if err := Convert(strings.NewReader(""), &out, FormatJSON, FormatText); err == nil {
+		t.Error("Convert() to text succeeded")
+	}
+}
+
+// requoteLogfmt rewrites a logfmt line with the quoting the encoder uses,
+// leaving keys, order, and values as written
+func requoteLogfmt(line string) string {
+	var buf []byte
+
+	scanLogfmt(line, func(key, value string, _ bool) {
+		if buf != nil {
+			buf = append(buf, ' ')
+		}
+
+		buf = append(buf, key...)
+		buf = append(buf, '=')
+		buf = appendLogfmtValue(buf, value)
+	})
+
+	return string(buf)
+}
+
+func TestConvertKeyOrderRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("testdata/logfmt_roundtrip.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := strings.TrimSuffix(string(data), "\n")
+	lines := strings.Split(input, "\n")
+	out := strings.Split(strings.TrimSuffix(convertString(t, input, FormatLogfmt, FormatLogfmt, WithKeyOrder(true)), "\n"), "\n")
+
+	if len(out) != len(lines) {
+		t.Fatalf("got %d lines, want %d", len(out), len(lines))
+	}
+
+	lp := NewLineParser(FormatLogfmt, WithPreserveOrder(true))
+	identical := 0
+
+	for i, line := range lines {
+		if out[i] == requoteLogfmt(line) {
+			identical++
+
+			continue
+		}
+
+		want, err := lp.Parse(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := lp.Parse(out[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !convertEqual(want, got, true) {
+			t.Errorf("line %d changed:\n%s\n%s\n%s", i+1, line, out[i], DiffEntries(want, got))
+		}
+	}
+
+	t.Logf("%d of %d lines byte-identical", identical, len(lines))
+
+	if identical*100 < len(lines)*95 {
+		t.Errorf("%d of %d lines byte-identical, want at least 95%%", identical, len(lines))
+	}
+}
+
+func TestWithPreserveOrder(t *testing.T) {
+	line := `ts=2024-05-06T10:00:00Z caller=main.go:42 b=1 level=info a=2 msg=hi b=3`
+
+	entry, err := NewLineParser(FormatLogfmt, WithPreserveOrder(true)).Parse(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"ts", "caller", "b", "level", "a", "msg"}
+	if got := entry.Fields[keyOrderField]; !reflect.DeepEqual(got, want) {
+		t.Errorf("_key_order = %v, want %v", got, want)
+	}
+
+	buf, err := appendEntryLine(nil, &entry, FormatLogfmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(buf); got != "time=2024-05-06T10:00:00Z level=INFO msg=hi caller=main.go:42 b=3 a=2" {
+		t.Errorf("appendEntryLine() = %s", got)
+	}
+
+	// Without the option, fields are written in key order after the
+	// standard fields
+	if got := convertString(t, line, FormatLogfmt, FormatLogfmt); got != "time=2024-05-06T10:00:00Z level=info msg=hi a=2 b=3 caller=main.go:42\n" {
+		t.Errorf("Convert() = %s", got)
+	}
+
+	if got := convertString(t, line, FormatLogfmt, FormatJSON, WithKeyOrder(true)); got !=
+		`{"timestamp":"2024-05-06T10:00:00Z","caller":"main.go:42","b":3,"level":"info","a":2,"msg":"hi"}`+"\n" {
+		t.Errorf("Convert() to JSON = %s", got)
+	}
+}
@@ -0,0 +1,77 @@
+package logparser
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+)
+
+// CSVOption configures WriteCSV
+type CSVOption func(*csvConfig)
+
+// csvConfig holds WriteCSV settings
+type csvConfig struct {
+	timeLayout string
+}
+
+// WithCSVTimeLayout sets the layout used for the timestamp column.
+// The default is time.RFC3339.
+func WithCSVTimeLayout(layout string) CSVOption {
+	return func(c *csvConfig) {
+		c.timeLayout = layout
+	}
+}
+
+// WriteCSV writes entries as CSV with a header row. Columns may be
+// "timestamp", "level", "message", or any field name; dotted names select
+// values inside nested objects. Missing values render as empty cells.
+func WriteCSV(w io.Writer, entries []LogEntry, columns []string, opts ...CSVOption) error {
+	cfg := csvConfig{timeLayout: time.RFC3339}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	row := make([]string, len(columns))
+
+	for i := range entries {
+		for j, column := range columns {
+			row[j] = csvCell(&entries[i], column, cfg.timeLayout)
+		}
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// csvCell renders a single column for an entry
+func csvCell(entry *LogEntry, column, timeLayout string) string {
+	switch column {
+	case "timestamp":
+		if entry.Timestamp.IsZero() {
+			return ""
+		}
+
+		return entry.Timestamp.Format(timeLayout)
+	case "level":
+		return entry.Level
+	case "message":
+		return entry.Message
+	default:
+		val, ok := entryField(entry, column)
+		if !ok {
+			return ""
+		}
+
+		return formatValue(val)
+	}
+}
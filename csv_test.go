@@ -0,0 +1,64 @@
+package logparser
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// checkGolden compares got with the named golden file, rewriting it with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := "testdata/golden/" + name
+	if *update {
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("output mismatch for %s\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	entries, err := ParseFile(New(), "testdata/export.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	columns := []string{"timestamp", "level", "message", "service", "http.status", "http.method", "retry", "missing"}
+	if err := WriteCSV(&buf, entries, columns); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	checkGolden(t, "export.csv", buf.Bytes())
+}
+
+func TestWriteCSVTimeLayout(t *testing.T) {
+	entries, err := ParseFile(New(), "testdata/export.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := WriteCSV(&buf, entries[:1], []string{"timestamp"}, WithCSVTimeLayout("2006-01-02 15:04")); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	if got, want := buf.String(), "timestamp\n2024-01-02 15:04\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,86 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errContinuation marks a line that adds detail to the previous entry
+// rather than starting a new one
+var errContinuation = errors.New("continuation line")
+
+// errMessageContinuation marks a header-less line whose text continues the
+// previous entry's message, such as the body of a klog multi-line value
+var errMessageContinuation = fmt.Errorf("%w: message", errContinuation)
+
+// postgresContinuations are the message types PostgreSQL logs on their own
+// lines after an error, mapped to the field that holds them
+var postgresContinuations = map[string]string{
+	"DETAIL":    "detail",
+	"HINT":      "hint",
+	"STATEMENT": "statement",
+	"CONTEXT":   "context",
+	"QUERY":     "query",
+	"LOCATION":  "location",
+}
+
+// foldPostgresContinuation turns DETAIL, HINT, STATEMENT, and similar lines
+// into a field to be merged into the preceding entry
+func foldPostgresContinuation(entry *LogEntry, matches []string, cfg *config) error {
+	key, ok := postgresContinuations[matches[6]]
+	if !ok {
+		return nil
+	}
+
+	entry.Fields = cfg.newFields()
+	cfg.setField(entry, key, strings.TrimSpace(matches[7]))
+
+	return errContinuation
+}
+
+// foldContinuation merges the fields of a continuation entry into the most
+// recently stored entry, and with appendMessage adds its message as a new
+// line of the stored message. It reports false if the previous line was not
+// stored, for example because it was sampled out.
+func (r *parseRun) foldContinuation(entry *LogEntry, appendMessage bool) bool {
+	if !r.canFold {
+		return false
+	}
+
+	prev := r.lastStored()
+	if appendMessage {
+		prev.Message += "\n" + entry.Message
+	}
+
+	if len(entry.Fields) > 0 && prev.Fields == nil {
+		prev.Fields = make(map[string]interface{}, len(entry.Fields))
+	}
+
+	mergeFields(prev.Fields, entry.Fields)
+
+	// A stack trace that grew with the line is parsed again
+	if _, ok := entry.Fields["stacktrace"]; ok {
+		r.cfg.expandStacktrace(prev)
+	}
+
+	return true
+}
+
+// lastStored returns the most recently stored entry: one dropped as a
+// duplicate, the one staged by the smart sampler, or the newest in the run
+func (r *parseRun) lastStored() *LogEntry {
+	switch {
+	case r.dropping:
+		return &r.dropped
+	case r.smart != nil:
+		return &r.smart.staged
+	}
+
+	last := len(r.entries) - 1
+	if r.tailNext > 0 {
+		last = r.tailNext - 1
+	}
+
+	return &r.entries[last]
+}
@@ -0,0 +1,151 @@
+package logparser
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPostgresLog(t *testing.T) {
+	data, err := os.ReadFile("testdata/postgres.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := New().ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	wantLevels := []string{"INFO", "ERROR", "WARN", "ERROR", "FATAL", "FATAL"}
+	if len(entries) != len(wantLevels) {
+		t.Fatalf("got %d entries, want %d (continuations should fold)", len(entries), len(wantLevels))
+	}
+
+	for i, want := range wantLevels {
+		if entries[i].Level != want {
+			t.Errorf("entry %d: level %s, want %s", i, entries[i].Level, want)
+		}
+	}
+
+	e := entries[1]
+	if e.Message != `duplicate key value violates unique constraint "orders_pkey"` {
+		t.Errorf("Message = %q", e.Message)
+	}
+
+	want := map[string]string{
+		"pid":       "1240",
+		"user":      "app",
+		"database":  "shop",
+		"sqlstate":  "23505",
+		"detail":    "Key (id)=(42) already exists.",
+		"statement": "INSERT INTO orders (id, total) VALUES (42, 9.99)",
+	}
+
+	for k, v := range want {
+		if e.Fields[k] != v {
+			t.Errorf("Fields[%q] = %v, want %q", k, e.Fields[k], v)
+		}
+	}
+
+	if want := time.Date(2024, 1, 2, 15, 4, 7, 456e6, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	if hint := entries[3].Fields["hint"]; hint != `Perhaps you meant to reference the table "public.orders".` {
+		t.Errorf("hint = %v", hint)
+	}
+
+	if _, ok := entries[2].Fields["sqlstate"]; ok {
+		t.Errorf("prefix without SQLSTATE should not set it: %v", entries[2].Fields)
+	}
+}
+
+func TestPostgresContinuationWithoutParent(t *testing.T) {
+	input := "2024-01-02 15:04:07.456 UTC [1240] DETAIL:  Key (id)=(42) already exists."
+
+	entries, err := NewWithFormat(FormatText).ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Fields["detail"] != "Key (id)=(42) already exists." {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
+
+func TestPostgresZones(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ts   string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"CET from location", "2024-01-02 15:04:05 CET", berlin, time.Date(2024, 1, 2, 14, 4, 5, 0, time.UTC)},
+		{"CEST from location", "2024-07-02 15:04:05 CEST", berlin, time.Date(2024, 7, 2, 13, 4, 5, 0, time.UTC)},
+		{"UTC whatever the location", "2024-01-02 15:04:05 UTC", berlin, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"unknown abbreviation is wall clock", "2024-01-02 15:04:05 EST", berlin, time.Date(2024, 1, 2, 14, 4, 5, 0, time.UTC)},
+		{"CET without a location", "2024-01-02 15:04:05 CET", nil, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"numeric hours", "2024-01-02 15:04:05.250 +03", nil, time.Date(2024, 1, 2, 12, 4, 5, 250e6, time.UTC)},
+		{"numeric hours and minutes", "2024-01-02 15:04:05 -0330", berlin, time.Date(2024, 1, 2, 18, 34, 5, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.loc != nil {
+				opts = append(opts, WithLocation(tt.loc))
+			}
+
+			entries, err := New(opts...).ParseString(tt.ts + " [123] ERROR:  relation does not exist")
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("got %d entries, error = %v", len(entries), err)
+			}
+
+			if e := entries[0]; !e.Timestamp.Equal(tt.want) || e.Level != LevelError || e.Fields["pid"] != "123" {
+				t.Errorf("got %v %s pid=%v, want %v", e.Timestamp, e.Level, e.Fields["pid"], tt.want)
+			}
+		})
+	}
+}
+
+func TestMySQLLog(t *testing.T) {
+	data, err := os.ReadFile("testdata/mysql.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := New().ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	wantLevels := []string{"INFO", "INFO", "WARN", "ERROR", "INFO"}
+	if len(entries) != len(wantLevels) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(wantLevels))
+	}
+
+	for i, want := range wantLevels {
+		if entries[i].Level != want {
+			t.Errorf("entry %d: level %s, want %s", i, entries[i].Level, want)
+		}
+	}
+
+	e := entries[3]
+	if e.Fields["thread"] != "8" || e.Fields["code"] != "MY-012592" || e.Fields["subsystem"] != "InnoDB" {
+		t.Errorf("unexpected fields %v", e.Fields)
+	}
+
+	if e.Message != "Operating system error number 2 in a file operation." {
+		t.Errorf("Message = %q", e.Message)
+	}
+
+	if want := time.Date(2024, 1, 2, 15, 4, 7, 456789e3, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+}
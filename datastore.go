@@ -0,0 +1,126 @@
+package logparser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// redisLevels maps the Redis severity glyphs to levels. Redis has no error
+// glyph: errors are logged at its highest level, '#', alongside warnings.
+var redisLevels = map[string]string{
+	".": "DEBUG", // debug
+	"-": "DEBUG", // verbose
+	"*": LevelInfo,
+	"#": "WARN",
+}
+
+// redisRoles names the Redis role characters
+var redisRoles = map[string]string{
+	"M": "master",
+	"S": "replica",
+	"C": "child",
+	"X": "sentinel",
+}
+
+// redisErrorRe finds the wording Redis uses for failures in '#' lines, such
+// as "Background saving error" or "Failed opening the RDB file"
+var redisErrorRe = regexp.MustCompile(`(?i)\b(?:error|failed|failure|can't|cannot|unable|crashed)\b`)
+
+// parseRedisLine sets the level from the Redis severity glyph, raising
+// warnings that report a failure to ERROR, and spells out the role
+func parseRedisLine(entry *LogEntry, matches []string, cfg *config) error {
+	entry.Level = redisLevels[matches[4]]
+	if entry.Level == "WARN" && redisErrorRe.MatchString(entry.Message) {
+		entry.Level = LevelError
+	}
+
+	if role, ok := redisRoles[matches[2]]; ok {
+		cfg.setField(entry, "role", role)
+	}
+
+	return nil
+}
+
+// MongoDB structured log keys (4.4 and later) mapped onto LogEntry
+const (
+	mongoTimestampKey = "t"
+	mongoSeverityKey  = "s"
+	mongoComponentKey = "c"
+	mongoMessageKey   = "msg"
+)
+
+// isMongoObject reports whether a decoded JSON object is a MongoDB
+// structured log record: "t" holds an extended JSON {"$date": ...} and
+// "s" a severity
+func isMongoObject(raw map[string]interface{}) bool {
+	t, ok := raw[mongoTimestampKey].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	if _, ok := t["$date"]; !ok {
+		return false
+	}
+
+	_, ok = raw[mongoSeverityKey].(string)
+
+	return ok
+}
+
+// extractMongo maps the MongoDB timestamp, severity, component, and message
+// onto entry. The component is kept as the "component" field; id, ctx, and
+// attr stay in Fields as they are.
+func extractMongo(raw map[string]interface{}, entry *LogEntry, cfg *config) {
+	start := cfg.timer.start()
+	t, ok := mongoDate(raw[mongoTimestampKey].(map[string]interface{})["$date"])
+	cfg.timer.stop(phaseExtractTS, start, 1)
+
+	if ok {
+		entry.Timestamp = t
+
+		cfg.consumeKey(raw, "_ts_key", mongoTimestampKey)
+	}
+
+	if level, ok := mongoLevel(raw[mongoSeverityKey].(string)); ok {
+		entry.Level = level
+
+		cfg.consumeKey(raw, "_level_key", mongoSeverityKey)
+	}
+
+	if msg, ok := raw[mongoMessageKey].(string); ok {
+		entry.Message = msg
+
+		cfg.consumeKey(raw, "_msg_key", mongoMessageKey)
+	}
+
+	if c, ok := raw[mongoComponentKey].(string); ok {
+		delete(raw, mongoComponentKey)
+		cfg.setField(entry, "component", c)
+	}
+}
+
+// mongoDate reads the value of an extended JSON $date: an ISO 8601 string
+// in relaxed mode, or {"$numberLong": "<unix millis>"} in canonical mode
+func mongoDate(val interface{}) (time.Time, bool) {
+	if m, ok := val.(map[string]interface{}); ok {
+		val = m["$numberLong"]
+	}
+
+	t, err := parseTimestamp(val)
+
+	return t, err == nil
+}
+
+// mongoLevel maps a MongoDB severity, F, E, W, I, or D1 to D5, to a level
+func mongoLevel(s string) (string, bool) {
+	if len(s) == 2 && s[0] == 'D' && s[1] >= '1' && s[1] <= '5' {
+		return "DEBUG", true
+	}
+
+	if len(s) != 1 || !strings.Contains("FEWI", s) {
+		return "", false
+	}
+
+	return lookupLevel(s)
+}
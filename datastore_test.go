@@ -0,0 +1,143 @@
+package logparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseFixture parses a testdata file with opts, detecting the format
+// unless one is given
+func parseFixture(t *testing.T, name string, opts ...Option) []LogEntry {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := New(opts...).ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	return entries
+}
+
+// checkLevels compares the levels of entries with want
+func checkLevels(t *testing.T, entries []LogEntry, want []string) {
+	t.Helper()
+
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for i := range want {
+		if entries[i].Level != want[i] {
+			t.Errorf("entry %d: level %s, want %s", i, entries[i].Level, want[i])
+		}
+	}
+}
+
+func TestRedisLog(t *testing.T) {
+	entries := parseFixture(t, "redis.log")
+
+	checkLevels(t, entries, []string{
+		"WARN", "INFO", "INFO", "INFO", "ERROR", "ERROR", "WARN", "DEBUG", "DEBUG", "WARN",
+	})
+
+	e := entries[4]
+	if e.Fields["pid"] != "42" || e.Fields["role"] != "child" {
+		t.Errorf("unexpected fields %v", e.Fields)
+	}
+
+	if !strings.HasPrefix(e.Message, "Failed opening the temp RDB file") {
+		t.Errorf("Message = %q", e.Message)
+	}
+
+	if want := time.Date(2024, 1, 2, 15, 9, 5, 310e6, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	for i, want := range map[int]string{1: "master", 7: "replica", 9: "sentinel"} {
+		if got := entries[i].Fields["role"]; got != want {
+			t.Errorf("entry %d: role %v, want %s", i, got, want)
+		}
+	}
+}
+
+func TestMongoDBLog(t *testing.T) {
+	entries := parseFixture(t, "mongodb.log")
+
+	checkLevels(t, entries, []string{"INFO", "INFO", "WARN", "ERROR", "DEBUG", "FATAL"})
+
+	e := entries[3]
+	if e.Message != "SSL peer certificate validation failed" {
+		t.Errorf("Message = %q", e.Message)
+	}
+
+	if want := time.Date(2024, 1, 2, 15, 4, 8, 456e6, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	if e.Fields["component"] != "NETWORK" || e.Fields["ctx"] != "conn12" {
+		t.Errorf("unexpected fields %v", e.Fields)
+	}
+
+	if got, _ := lookupField(e.Fields, "attr.error"); got != "certificate signature failure" {
+		t.Errorf("attr.error = %v", got)
+	}
+
+	for _, key := range []string{"t", "s", "c", "msg"} {
+		if _, ok := e.Fields[key]; ok {
+			t.Errorf("extracted key %s left in Fields", key)
+		}
+	}
+
+	if want := time.UnixMilli(1704207850678).UTC(); !entries[5].Timestamp.Equal(want) {
+		t.Errorf("canonical $date = %v, want %v", entries[5].Timestamp, want)
+	}
+}
+
+func TestMongoDBLookalike(t *testing.T) {
+	// A "t" without $date is an ordinary field
+	line := `{"t":{"x":1},"s":"E","level":"info","msg":"m"}`
+
+	entries, err := NewWithFormat(FormatJSON).ParseString(line)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if e := entries[0]; e.Level != "INFO" || e.Fields["s"] != "E" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+func TestElasticsearchLog(t *testing.T) {
+	entries := parseFixture(t, "elasticsearch.log")
+
+	checkLevels(t, entries, []string{"INFO", "WARN", "ERROR", "DEBUG"})
+
+	e := entries[2]
+	if e.Logger != "o.e.b.Elasticsearch" || e.Fields["node"] != "node-1" {
+		t.Errorf("unexpected fields %v, logger %q", e.Fields, e.Logger)
+	}
+
+	if want := time.Date(2024, 1, 2, 15, 4, 8, 789e6, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	first, trace, _ := strings.Cut(e.Message, "\n")
+	if first != "fatal exception while booting Elasticsearch" || !strings.Contains(trace, "NodeEnvironment.java:299") {
+		t.Errorf("Message = %q, want the stack trace appended", e.Message)
+	}
+
+	if got := entries[1].Logger; got != "o.e.c.r.a.DiskThresholdMonitor" {
+		t.Errorf("unpadded logger = %v", got)
+	}
+
+	if got := entries[3].Message; got != "[logs-2024.01.02][0] search failed" {
+		t.Errorf("Message = %q", got)
+	}
+}
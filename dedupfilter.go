@@ -0,0 +1,268 @@
+package logparser
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// DedupFilter sizing used when NewDedupFilter is given zero or out of
+// range values
+const (
+	DefaultDedupCapacity = 1 << 20 // Entries, for about 1.2MB at the default rate
+	DefaultDedupFPR      = 0.001
+)
+
+// ErrInvalidFilter is returned by DedupFilter.UnmarshalBinary for data not
+// written by MarshalBinary
+var ErrInvalidFilter = errors.New("invalid dedup filter data")
+
+// Binary encoding of a DedupFilter: magic, version, then the sizing and
+// bit words as big-endian integers
+const (
+	dedupMagic      = "LPDF"
+	dedupVersion    = 1
+	dedupHeaderSize = len(dedupMagic) + 1 + 4 + 8 + 8 + 8 + 8 // version, k, bits, count, capacity, fpr
+)
+
+// DedupFilter remembers entry hashes (see LogEntry.Hash) in a bloom
+// filter of fixed size, so deduplication can survive a restart: checkpoint
+// it with MarshalBinary and restore it with UnmarshalBinary. The trade-off
+// is in the other direction from DedupeByHash: MaybeContains never misses
+// a hash that was added, but answers true for a hash that was not with a
+// small probability, the false positive rate, so a filter used with
+// WithDedupFilter drops that fraction of new entries as duplicates. The
+// rate holds up to the capacity the filter was sized for and grows past
+// it; EstimatedFPR reports the rate for the hashes added so far. The zero
+// value is an empty filter sized like NewDedupFilter(0, 0) on first use. A
+// DedupFilter may be used concurrently.
+type DedupFilter struct {
+	once     sync.Once // Sizes the zero value
+	mu       sync.RWMutex
+	words    []uint64
+	bits     uint64 // Filter size in bits, a multiple of 64
+	k        int    // Bits set per hash
+	count    uint64 // Hashes added that were not already present
+	capacity uint64
+	fpr      float64
+}
+
+// NewDedupFilter returns an empty filter sized to hold capacity hashes at
+// a false positive rate of fpr. Zero or less means DefaultDedupCapacity,
+// and a rate outside (0, 1) DefaultDedupFPR. The filter takes about
+// -capacity × ln(fpr) / ln(2)² bits: 1.2 bytes per hash at 1%, 1.8 at 0.1%.
+func NewDedupFilter(capacity int, fpr float64) *DedupFilter {
+	f := &DedupFilter{}
+	f.size(capacity, fpr)
+
+	return f
+}
+
+// size allocates the bits of an empty filter as NewDedupFilter describes.
+// The caller holds the write lock or has not shared f yet.
+func (f *DedupFilter) size(capacity int, fpr float64) {
+	if capacity <= 0 {
+		capacity = DefaultDedupCapacity
+	}
+
+	if fpr <= 0 || fpr >= 1 {
+		fpr = DefaultDedupFPR
+	}
+
+	n := float64(capacity)
+	bits := uint64(math.Ceil(-n*math.Log(fpr)/(math.Ln2*math.Ln2)+63)) / 64 * 64
+	k := max(1, int(math.Round(float64(bits)/n*math.Ln2)))
+
+	f.words, f.bits, f.k, f.capacity, f.fpr = make([]uint64, bits/64), bits, k, uint64(capacity), fpr
+}
+
+// init sizes a zero DedupFilter with the defaults; filters from
+// NewDedupFilter or UnmarshalBinary are left as they are
+func (f *DedupFilter) init() {
+	f.once.Do(func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if f.k == 0 {
+			f.size(0, 0)
+		}
+	})
+}
+
+// Add records a hash
+func (f *DedupFilter) Add(hash uint64) {
+	f.init()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.add(hash)
+}
+
+// MaybeContains reports whether the hash may have been added. False means
+// it certainly was not; true is wrong at the false positive rate.
+func (f *DedupFilter) MaybeContains(hash uint64) bool {
+	f.init()
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	h1, h2 := dedupHashes(hash)
+
+	for i := range f.k {
+		if !f.isSet(h1 + uint64(i)*h2) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Count returns the number of hashes added, less those that were reported
+// present when added
+func (f *DedupFilter) Count() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return int(f.count) //nolint:gosec // bounded by the number of Add calls
+}
+
+// EstimatedFPR returns the false positive rate expected of MaybeContains
+// after the hashes added so far: below the configured rate until the
+// filter reaches its capacity, and above it after
+func (f *DedupFilter) EstimatedFPR() float64 {
+	f.init()
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return math.Pow(1-math.Exp(-float64(f.k)*float64(f.count)/float64(f.bits)), float64(f.k))
+}
+
+// add sets the bits of a hash, reporting whether any was unset, in which
+// case the hash is new. The caller holds the write lock.
+func (f *DedupFilter) add(hash uint64) bool {
+	h1, h2 := dedupHashes(hash)
+	added := false
+
+	for i := range f.k {
+		pos := (h1 + uint64(i)*h2) % f.bits
+		word, bit := pos/64, uint64(1)<<(pos%64)
+
+		if f.words[word]&bit == 0 {
+			f.words[word] |= bit
+			added = true
+		}
+	}
+
+	if added {
+		f.count++
+	}
+
+	return added
+}
+
+// addNew adds a hash, reporting whether it was new
+func (f *DedupFilter) addNew(hash uint64) bool {
+	f.init()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.add(hash)
+}
+
+// isSet reports whether the bit for a probe position is set
+func (f *DedupFilter) isSet(probe uint64) bool {
+	pos := probe % f.bits
+
+	return f.words[pos/64]&(1<<(pos%64)) != 0
+}
+
+// dedupHashes derives the two hashes whose combinations h1 + i×h2 choose
+// the bits of an entry hash
+func dedupHashes(hash uint64) (h1, h2 uint64) {
+	h1 = splitmix64(hash)
+	h2 = splitmix64(h1) | 1
+
+	return h1, h2
+}
+
+// splitmix64 scrambles the bits of x
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+
+	return x ^ x>>31
+}
+
+// MarshalBinary encodes the filter, its sizing, and its count
+func (f *DedupFilter) MarshalBinary() ([]byte, error) {
+	f.init()
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	data := make([]byte, 0, dedupHeaderSize+8*len(f.words))
+	data = append(data, dedupMagic...)
+	data = append(data, dedupVersion)
+	data = binary.BigEndian.AppendUint32(data, uint32(f.k)) //nolint:gosec // k is small
+	data = binary.BigEndian.AppendUint64(data, f.bits)
+	data = binary.BigEndian.AppendUint64(data, f.count)
+	data = binary.BigEndian.AppendUint64(data, f.capacity)
+	data = binary.BigEndian.AppendUint64(data, math.Float64bits(f.fpr))
+
+	for _, w := range f.words {
+		data = binary.BigEndian.AppendUint64(data, w)
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary replaces the filter with one encoded by MarshalBinary,
+// failing with ErrInvalidFilter on other data
+func (f *DedupFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < dedupHeaderSize || string(data[:len(dedupMagic)]) != dedupMagic {
+		return ErrInvalidFilter
+	}
+
+	data = data[len(dedupMagic):]
+	if data[0] != dedupVersion {
+		return fmt.Errorf("%w: version %d", ErrInvalidFilter, data[0])
+	}
+
+	k := int(binary.BigEndian.Uint32(data[1:]))
+	bits := binary.BigEndian.Uint64(data[5:])
+	count := binary.BigEndian.Uint64(data[13:])
+	capacity := binary.BigEndian.Uint64(data[21:])
+	fpr := math.Float64frombits(binary.BigEndian.Uint64(data[29:]))
+	data = data[37:]
+
+	if k < 1 || bits == 0 || bits%64 != 0 || uint64(len(data)) != bits/8 {
+		return fmt.Errorf("%w: %d bits, %d probes, %d bytes of words", ErrInvalidFilter, bits, k, len(data))
+	}
+
+	words := make([]uint64, bits/64)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint64(data[8*i:])
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.words, f.bits, f.k, f.count, f.capacity, f.fpr = words, bits, k, count, capacity, fpr
+
+	return nil
+}
+
+// WithDedupFilter drops entries whose Hash the filter may already contain,
+// and adds the hashes of the others, so entries re-sent after a restart
+// are dropped when the filter was restored from a checkpoint. New entries
+// are dropped at the filter's false positive rate (see DedupFilter). An
+// entry is hashed when its first line is parsed, after WithTransform, so
+// continuation lines folded in later do not change its identity; they are
+// dropped with it. Drops are counted in Stats.DuplicatesDropped. The
+// filter may be shared by parsers.
+func WithDedupFilter(f *DedupFilter) Option {
+	return func(c *config) {
+		c.dedup = f
+	}
+}
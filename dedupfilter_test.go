@@ -0,0 +1,186 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedupFilterFPR(t *testing.T) {
+	const capacity = 20000
+
+	for _, fpr := range []float64{0.01, 0.001} {
+		f := NewDedupFilter(capacity, fpr)
+		rng := rand.New(rand.NewPCG(1, 2))
+
+		for range capacity {
+			f.Add(rng.Uint64())
+		}
+
+		// Hashes never added, so every hit is a false positive
+		const probes = 500000
+
+		hits := 0
+
+		for range probes {
+			if f.MaybeContains(rng.Uint64()) {
+				hits++
+			}
+		}
+
+		observed := float64(hits) / probes
+		if observed < fpr/2 || observed > fpr*1.5 {
+			t.Errorf("target %v: observed false positive rate %v", fpr, observed)
+		}
+
+		if est := f.EstimatedFPR(); est < fpr/2 || est > fpr*1.5 {
+			t.Errorf("target %v: estimated false positive rate %v", fpr, est)
+		}
+	}
+}
+
+func TestDedupFilterNoFalseNegatives(t *testing.T) {
+	f := NewDedupFilter(1000, 0.01)
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	added := make([]uint64, 1000)
+	for i := range added {
+		added[i] = rng.Uint64()
+		f.Add(added[i])
+	}
+
+	for _, h := range added {
+		if !f.MaybeContains(h) {
+			t.Fatalf("added hash %x not contained", h)
+		}
+	}
+
+	if n := f.Count(); n < 990 || n > 1000 {
+		t.Errorf("count = %d, want about 1000", n)
+	}
+}
+
+// shipment returns logfmt lines numbered from up to to
+func shipment(from, to int) string {
+	var b strings.Builder
+
+	for i := from; i < to; i++ {
+		fmt.Fprintf(&b, "time=2024-03-01T10:00:%02dZ level=info msg=\"request %d\"\n", i, i)
+	}
+
+	return b.String()
+}
+
+func TestDedupFilterCheckpoint(t *testing.T) {
+	f := NewDedupFilter(1000, 0.001)
+
+	entries, err := New(WithFormat(FormatLogfmt), WithDedupFilter(f)).ParseString(shipment(0, 30))
+	if err != nil || len(entries) != 30 {
+		t.Fatalf("first shipment: %d entries, error = %v", len(entries), err)
+	}
+
+	checkpoint, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ingester restarts and the last ten lines are shipped again
+	var restored DedupFilter
+	if err := restored.UnmarshalBinary(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Count() != 30 {
+		t.Errorf("restored count = %d, want 30", restored.Count())
+	}
+
+	entries, stats, err := ParseWithStats(New(WithFormat(FormatLogfmt), WithDedupFilter(&restored)), strings.NewReader(shipment(20, 50)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 20 || entries[0].Message != "request 30" || stats.DuplicatesDropped != 10 {
+		t.Errorf("second shipment: %d entries from %q, %d dropped", len(entries), entries[0].Message, stats.DuplicatesDropped)
+	}
+}
+
+func TestDedupFilterContinuation(t *testing.T) {
+	ref := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	lines := []string{
+		`E0102 15:04:08.654321   12345 deployment_controller.go:495] "Error syncing deployment" err=<`,
+		"\tOperation cannot be fulfilled on replicasets.apps",
+		" >",
+		`I0102 15:04:09.000001       1 replica_set.go:577] "Too few replicas"`,
+		`I0102 15:04:10.000001       1 replica_set.go:577] "Replicas created"`,
+	}
+
+	p := New(WithReferenceTime(ref), WithDedupFilter(NewDedupFilter(100, 0.01)))
+
+	if _, err := p.ParseString(strings.Join(lines[:4], "\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The duplicate's continuation lines are dropped with it
+	entries, err := p.ParseString(strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].Message != `"Replicas created"` {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestDedupFilterZeroValue(t *testing.T) {
+	var f DedupFilter
+
+	if f.MaybeContains(1) || f.EstimatedFPR() != 0 {
+		t.Fatalf("zero filter: MaybeContains = true, EstimatedFPR = %v", f.EstimatedFPR())
+	}
+
+	input := "ts=1 msg=a\nts=2 msg=b\nts=1 msg=a\n"
+
+	entries, err := New(WithFormat(FormatLogfmt), WithDedupFilter(&DedupFilter{})).ParseString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0].Message != "a" || entries[1].Message != "b" {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	if f.Add(1); !f.MaybeContains(1) || f.Count() != 1 {
+		t.Errorf("after Add: MaybeContains = %v, Count = %d", f.MaybeContains(1), f.Count())
+	}
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, _ := NewDedupFilter(0, 0).MarshalBinary(); len(data) != len(want) {
+		t.Errorf("MarshalBinary() = %d bytes, want the default %d", len(data), len(want))
+	}
+}
+
+func TestDedupFilterUnmarshalInvalid(t *testing.T) {
+	data, err := NewDedupFilter(100, 0.01).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("XXXX"), data[4:]...),
+		"version":   append(append([]byte("LPDF"), 9), data[5:]...),
+		"truncated": data[:len(data)-8],
+	} {
+		var f DedupFilter
+		if err := f.UnmarshalBinary(bad); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%s: error = %v, want ErrInvalidFilter", name, err)
+		}
+	}
+}
@@ -2,59 +2,128 @@ package logparser
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
 )
 
-// detector handles format detection logic
-type detector struct{}
+// ErrAmbiguousFormat is matched by errors.Is when detection confidence is
+// below the threshold set with WithMinDetectionConfidence
+var ErrAmbiguousFormat = errors.New("ambiguous log format")
 
-// newDetector creates a new format detector
-func newDetector() *detector {
-	return &detector{}
+// AmbiguousFormatError reports the candidate scores of a detection that was
+// not confident enough to pick a format
+type AmbiguousFormatError struct {
+	Confidence float64            // Confidence of the best candidate
+	Threshold  float64            // Minimum confidence that was required
+	Scores     map[Format]float64 // Fraction of samples matching each format
 }
 
-// detectFormat attempts to detect log format from samples
-func (d *detector) detectFormat(samples []string) Format {
-	if len(samples) == 0 {
-		return FormatText // Default to text
+func (e *AmbiguousFormatError) Error() string {
+	formats := make([]Format, 0, len(e.Scores))
+	for f := range e.Scores {
+		formats = append(formats, f)
 	}
 
-	// Take up to 10 samples for detection
-	sampleSize := 10
-	if len(samples) < sampleSize {
-		sampleSize = len(samples)
+	sort.Slice(formats, func(i, j int) bool { return formats[i] < formats[j] })
+
+	parts := make([]string, len(formats))
+	for i, f := range formats {
+		parts[i] = fmt.Sprintf("%s=%.2f", f, e.Scores[f])
 	}
 
-	// Count successful detections for each format
-	scores := make(map[Format]int)
+	return fmt.Sprintf("%s: confidence %.2f below %.2f (%s)",
+		ErrAmbiguousFormat, e.Confidence, e.Threshold, strings.Join(parts, " "))
+}
 
-	for _, sample := range samples[:sampleSize] {
-		if d.isJSON(sample) {
-			scores[FormatJSON]++
-		}
+func (e *AmbiguousFormatError) Unwrap() error {
+	return ErrAmbiguousFormat
+}
 
-		if d.isLogfmt(sample) {
-			scores[FormatLogfmt]++
+// Detector guesses the format of a log from sample lines. Each sample is
+// classified as JSON, logfmt, or text, and the format matching the most
+// samples wins; ties prefer JSON, then logfmt.
+type Detector struct {
+	lenientJSON bool // Set by WithLenientJSON
+}
+
+// NewDetector creates a new format detector
+func NewDetector() *Detector {
+	return &Detector{}
+}
+
+// Detect returns the most likely format for samples
+func (d *Detector) Detect(samples []string) Format {
+	format, _ := d.DetectWithConfidence(samples)
+
+	return format
+}
+
+// DetectWithConfidence returns the most likely format for samples and the
+// fraction of samples that matched it. With no samples it returns FormatText
+// and zero confidence.
+func (d *Detector) DetectWithConfidence(samples []string) (Format, float64) {
+	if len(samples) == 0 {
+		return FormatText, 0
+	}
+
+	scores := d.Scores(samples)
+
+	best := FormatJSON
+	for _, f := range []Format{FormatLogfmt, FormatText} {
+		if scores[f] > scores[best] {
+			best = f
 		}
-		// Text format always matches as fallback
-		scores[FormatText]++
 	}
 
-	// Find format with highest score (prefer JSON > logfmt > text)
-	if scores[FormatJSON] > scores[FormatLogfmt] && scores[FormatJSON] > scores[FormatText]/2 {
-		return FormatJSON
+	return best, scores[best]
+}
+
+// Scores returns the fraction of samples classified as each format
+func (d *Detector) Scores(samples []string) map[Format]float64 {
+	scores := map[Format]float64{FormatJSON: 0, FormatLogfmt: 0, FormatText: 0}
+	if len(samples) == 0 {
+		return scores
 	}
 
-	if scores[FormatLogfmt] > scores[FormatText]/2 {
-		return FormatLogfmt
+	for _, sample := range samples {
+		switch {
+		case d.isJSON(sample):
+			scores[FormatJSON]++
+		case d.isLogfmt(sample):
+			scores[FormatLogfmt]++
+		default:
+			scores[FormatText]++
+		}
 	}
 
-	return FormatText
+	for f := range scores {
+		scores[f] /= float64(len(samples))
+	}
+
+	return scores
 }
 
-// isJSON checks if a line appears to be JSON
-func (d *detector) isJSON(line string) bool {
+// isJSON checks if a line appears to be a JSON object or a Fluentd event array
+func (d *Detector) isJSON(line string) bool {
+	if d.lenientJSON {
+		line = relaxJSON(line)
+	}
+
 	line = strings.TrimSpace(line)
+
+	if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+		var arr []interface{}
+		if json.Unmarshal([]byte(line), &arr) != nil {
+			return false
+		}
+
+		_, ok := fluentEntries(arr, &config{})
+
+		return ok
+	}
+
 	if !strings.HasPrefix(line, "{") || !strings.HasSuffix(line, "}") {
 		return false
 	}
@@ -65,12 +134,60 @@ func (d *detector) isJSON(line string) bool {
 	return json.Unmarshal([]byte(line), &obj) == nil
 }
 
-// isLogfmt checks if a line appears to be logfmt
-func (d *detector) isLogfmt(line string) bool {
-	// Simple heuristic: contains key=value pattern
-	return strings.Contains(line, "=") &&
-		(strings.Contains(line, "level=") ||
-			strings.Contains(line, "msg=") ||
-			strings.Contains(line, "time=") ||
-			strings.Contains(line, "timestamp="))
+// isLogfmt checks if a line appears to be logfmt: every token is a key=value
+// pair and at least one key is a standard logfmt key. Heroku router lines,
+// which have none, count when they start with at=.
+func (d *Detector) isLogfmt(line string) bool {
+	if !strings.Contains(line, "=") {
+		return false
+	}
+
+	pairs := decodeLogfmtFragment(strings.TrimSpace(line))
+	if pairs == nil {
+		return false
+	}
+
+	for _, key := range []string{"level", "msg", "time", "timestamp", "ts"} {
+		if _, ok := pairs[key]; ok {
+			return true
+		}
+	}
+
+	return strings.HasPrefix(strings.TrimSpace(line), "at=") && isHerokuRouter(pairs)
+}
+
+// WithBlockDetection lets auto-detection follow format changes mid-input.
+// When a line does not fit the detected format, the format is detected
+// again from the lines starting there, so a file that alternates between
+// blocks of JSON and logfmt parses each block correctly. Lines are only
+// re-examined at such switch points, keeping the cost close to a single
+// detection. Switches are reported in Stats.FormatSwitches. It has no
+// effect on parsers created with NewWithFormat.
+func WithBlockDetection() Option {
+	return func(c *config) {
+		c.blockDetection = true
+	}
+}
+
+// fits reports whether line plausibly belongs to format. It is cheaper than
+// classifying the line: JSON is only checked by its opening bracket, and
+// logfmt without requiring a standard key.
+func (d *Detector) fits(format Format, line string) bool {
+	switch format {
+	case FormatJSON:
+		return strings.HasPrefix(line, "{") || strings.HasPrefix(line, "[")
+	case FormatLogfmt:
+		return !strings.HasPrefix(line, "{") && isLogfmtPairs(line)
+	default:
+		return !d.isJSON(line) && !d.isLogfmt(line)
+	}
+}
+
+// sampleSize returns the number of lines used for auto-detection
+func (c *config) sampleSize() int {
+	if c.detectionSamples > 0 {
+		return c.detectionSamples
+	}
+
+	return detectionSampleSize
 }
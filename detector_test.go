@@ -0,0 +1,142 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDetectWithConfidence(t *testing.T) {
+	tests := []struct {
+		name       string
+		samples    []string
+		want       Format
+		confidence float64
+	}{
+		{
+			name:       "empty",
+			want:       FormatText,
+			confidence: 0,
+		},
+		{
+			name:       "pure JSON",
+			samples:    []string{`{"level":"info","msg":"a"}`, `{"level":"warn","msg":"b"}`},
+			want:       FormatJSON,
+			confidence: 1,
+		},
+		{
+			name: "prose containing equals",
+			samples: []string{
+				"set x=1 and retry",
+				"x=1",
+				"the ratio a=b holds when level is fine",
+				"2024-01-01 10:00:00 [INFO] started with workers=4",
+			},
+			want:       FormatText,
+			confidence: 1,
+		},
+		{
+			name: "JSON with trailing commentary",
+			samples: []string{
+				`{"level":"info","msg":"a"} // retried`,
+				`{"level":"info","msg":"b"} (truncated)`,
+				`{"level":"info","msg":"c"}`,
+			},
+			want:       FormatText,
+			confidence: 2.0 / 3,
+		},
+		{
+			name:       "mostly logfmt",
+			samples:    []string{"level=info msg=a", "level=warn msg=b", "level=info msg=c", "Starting service v1.2"},
+			want:       FormatLogfmt,
+			confidence: 0.75,
+		},
+		{
+			name:       "tie prefers JSON",
+			samples:    []string{`{"msg":"a"}`, "level=info msg=b"},
+			want:       FormatJSON,
+			confidence: 0.5,
+		},
+	}
+
+	d := NewDetector()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, confidence := d.DetectWithConfidence(tt.samples)
+			if got != tt.want || confidence != tt.confidence {
+				t.Errorf("DetectWithConfidence() = %v, %v, want %v, %v", got, confidence, tt.want, tt.confidence)
+			}
+		})
+	}
+}
+
+// bannerThenLogfmt returns a startup banner of banner lines followed by logfmt lines
+func bannerThenLogfmt(banner, lines int) string {
+	var b strings.Builder
+
+	for i := range banner {
+		fmt.Fprintf(&b, "=== banner line %d ===\n", i)
+	}
+
+	for i := range lines {
+		fmt.Fprintf(&b, "level=info msg=\"request %d\" status=200\n", i)
+	}
+
+	return b.String()
+}
+
+func TestDetectionSampleSize(t *testing.T) {
+	input := bannerThenLogfmt(10, 40)
+
+	entries, err := New().ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if entries[len(entries)-1].Message == "request 39" {
+		t.Fatal("default window should only see the banner")
+	}
+
+	entries, err = New(WithDetectionSampleSize(50)).ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	last := entries[len(entries)-1]
+	if last.Message != "request 39" || last.Fields["status"] != "200" {
+		t.Errorf("expected logfmt parsing after the banner, got %+v", last)
+	}
+}
+
+func TestMinDetectionConfidence(t *testing.T) {
+	input := "level=info msg=a\nplain words\nlevel=info msg=b\nmore words\n"
+
+	_, err := New(WithMinDetectionConfidence(0.8)).ParseString(input)
+	if !errors.Is(err, ErrAmbiguousFormat) {
+		t.Fatalf("ParseString() error = %v, want ErrAmbiguousFormat", err)
+	}
+
+	var ambiguous *AmbiguousFormatError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("error %T is not an *AmbiguousFormatError", err)
+	}
+
+	if ambiguous.Scores[FormatLogfmt] != 0.5 || ambiguous.Scores[FormatText] != 0.5 {
+		t.Errorf("Scores = %v", ambiguous.Scores)
+	}
+
+	if !strings.Contains(err.Error(), "logfmt=0.50") {
+		t.Errorf("error should list candidate scores: %v", err)
+	}
+
+	if _, err := New(WithMinDetectionConfidence(0.5)).ParseString(input); err != nil {
+		t.Errorf("confidence at the threshold should parse, got %v", err)
+	}
+
+	// Explicit formats never consult the detector
+	if _, err := NewWithFormat(FormatText, WithMinDetectionConfidence(1)).ParseString(input); err != nil {
+		t.Errorf("NewWithFormat() error = %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package logparser
+
+import (
+	"math"
+	"sort"
+)
+
+// Diff defaults
+const (
+	defaultDiffThreshold  = 2.0
+	defaultDiffMaxSamples = 3
+)
+
+// DiffOptions configures Diff
+type DiffOptions struct {
+	// Threshold is the relative frequency ratio (in either direction) above
+	// which a template counts as changed. Defaults to 2.
+	Threshold float64
+	// MaxSamples is the number of sample entries kept per template. Defaults to 3.
+	MaxSamples int
+}
+
+// TemplateDiff describes how a message template differs between two runs
+type TemplateDiff struct {
+	Template    string     `json:"template"`
+	BeforeCount int        `json:"before_count"`
+	AfterCount  int        `json:"after_count"`
+	Ratio       float64    `json:"ratio,omitempty"` // After/before relative frequency
+	Samples     []LogEntry `json:"samples"`
+}
+
+// DiffReport lists templates added, removed, or changed between two runs
+type DiffReport struct {
+	Added   []TemplateDiff `json:"added"`
+	Removed []TemplateDiff `json:"removed"`
+	Changed []TemplateDiff `json:"changed"`
+}
+
+// templateCount accumulates entries sharing a message template
+type templateCount struct {
+	count   int
+	samples []LogEntry
+}
+
+// Diff compares the message templates of two parse runs. Frequencies are
+// compared relative to the size of each run, so runs of different lengths
+// can be diffed.
+func Diff(before, after []LogEntry, opts DiffOptions) DiffReport {
+	if opts.Threshold <= 1 {
+		opts.Threshold = defaultDiffThreshold
+	}
+
+	if opts.MaxSamples <= 0 {
+		opts.MaxSamples = defaultDiffMaxSamples
+	}
+
+	beforeCounts := countTemplates(before, opts.MaxSamples)
+	afterCounts := countTemplates(after, opts.MaxSamples)
+
+	report := DiffReport{
+		Added:   []TemplateDiff{},
+		Removed: []TemplateDiff{},
+		Changed: []TemplateDiff{},
+	}
+
+	for tmpl, b := range beforeCounts {
+		a, ok := afterCounts[tmpl]
+		if !ok {
+			report.Removed = append(report.Removed, TemplateDiff{Template: tmpl, BeforeCount: b.count, Samples: b.samples})
+
+			continue
+		}
+
+		ratio := (float64(a.count) / float64(len(after))) / (float64(b.count) / float64(len(before)))
+		if ratio >= opts.Threshold || ratio <= 1/opts.Threshold {
+			report.Changed = append(report.Changed, TemplateDiff{
+				Template:    tmpl,
+				BeforeCount: b.count,
+				AfterCount:  a.count,
+				Ratio:       ratio,
+				Samples:     a.samples,
+			})
+		}
+	}
+
+	for tmpl, a := range afterCounts {
+		if _, ok := beforeCounts[tmpl]; !ok {
+			report.Added = append(report.Added, TemplateDiff{Template: tmpl, AfterCount: a.count, Samples: a.samples})
+		}
+	}
+
+	sortTemplateDiffs(report.Added, func(d TemplateDiff) float64 { return float64(d.AfterCount) })
+	sortTemplateDiffs(report.Removed, func(d TemplateDiff) float64 { return float64(d.BeforeCount) })
+	sortTemplateDiffs(report.Changed, func(d TemplateDiff) float64 { return math.Abs(math.Log(d.Ratio)) })
+
+	return report
+}
+
+// countTemplates groups entries by message template
+func countTemplates(entries []LogEntry, maxSamples int) map[string]*templateCount {
+	counts := make(map[string]*templateCount)
+
+	for i := range entries {
+		tmpl := MessageTemplate(entries[i].Message)
+
+		tc, ok := counts[tmpl]
+		if !ok {
+			tc = &templateCount{}
+			counts[tmpl] = tc
+		}
+
+		tc.count++
+
+		if len(tc.samples) < maxSamples {
+			tc.samples = append(tc.samples, entries[i])
+		}
+	}
+
+	return counts
+}
+
+// sortTemplateDiffs orders diffs by descending weight, then by template
+func sortTemplateDiffs(diffs []TemplateDiff, weight func(TemplateDiff) float64) {
+	sort.Slice(diffs, func(i, j int) bool {
+		wi, wj := weight(diffs[i]), weight(diffs[j])
+		if wi != wj {
+			return wi > wj
+		}
+
+		return diffs[i].Template < diffs[j].Template
+	})
+}
@@ -0,0 +1,67 @@
+package logparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	var before, after []LogEntry
+
+	for i := range 100 {
+		before = append(before, LogEntry{Level: LevelInfo, Message: fmt.Sprintf("request %d served in %dms", i, i%50)})
+		after = append(after, LogEntry{Level: LevelInfo, Message: fmt.Sprintf("request %d served in %dms", i, i%50)})
+	}
+
+	for i := range 10 {
+		before = append(before, LogEntry{Level: LevelInfo, Message: fmt.Sprintf("legacy cache hit for key %d", i)})
+		before = append(before, LogEntry{Level: "WARN", Message: fmt.Sprintf("slow query took %dms", 100+i)})
+	}
+
+	for i := range 40 {
+		after = append(after, LogEntry{Level: "WARN", Message: fmt.Sprintf("slow query took %dms", 100+i)})
+	}
+
+	after = append(after, LogEntry{Level: LevelError, Message: "connection pool exhausted after 30s"})
+
+	report := Diff(before, after, DiffOptions{})
+
+	if len(report.Added) != 1 || report.Added[0].Template != "connection pool exhausted after <duration>" {
+		t.Errorf("unexpected added templates %+v", report.Added)
+	}
+
+	if len(report.Removed) != 1 || report.Removed[0].Template != "legacy cache hit for key <n>" || report.Removed[0].BeforeCount != 10 {
+		t.Errorf("unexpected removed templates %+v", report.Removed)
+	}
+
+	if len(report.Changed) != 1 {
+		t.Fatalf("want 1 changed template, got %+v", report.Changed)
+	}
+
+	changed := report.Changed[0]
+	if changed.Template != "slow query took <duration>" || changed.BeforeCount != 10 || changed.AfterCount != 40 {
+		t.Errorf("unexpected changed template %+v", changed)
+	}
+
+	if len(changed.Samples) != defaultDiffMaxSamples {
+		t.Errorf("want %d samples, got %d", defaultDiffMaxSamples, len(changed.Samples))
+	}
+
+	if _, err := json.Marshal(report); err != nil {
+		t.Errorf("report should marshal to JSON: %v", err)
+	}
+}
+
+func TestDiffEmptyRuns(t *testing.T) {
+	report := Diff(nil, nil, DiffOptions{})
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := string(data), `{"added":[],"removed":[],"changed":[]}`; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
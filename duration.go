@@ -0,0 +1,164 @@
+package logparser
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithDurationFields converts the listed fields to time.Duration values.
+// Go duration strings ("123ms", "2m5s") are parsed directly; bare numbers
+// use the unit implied by the key suffix (_ns, _us, _ms, _s) or set with
+// WithDurationUnit, defaulting to seconds. Values that cannot be parsed
+// are left unchanged and counted in Stats.DurationsUnparsed.
+func WithDurationFields(keys ...string) Option {
+	return func(c *config) {
+		c.durationFields = append(c.durationFields, keys...)
+	}
+}
+
+// WithDurationUnit sets the unit assumed for bare numbers in a duration field
+func WithDurationUnit(key string, unit time.Duration) Option {
+	return func(c *config) {
+		if c.durationUnits == nil {
+			c.durationUnits = make(map[string]time.Duration)
+		}
+
+		c.durationUnits[key] = unit
+	}
+}
+
+// WithDurationsAsMillis stores normalized durations as float64 milliseconds
+// instead of time.Duration
+func WithDurationsAsMillis(millis bool) Option {
+	return func(c *config) {
+		c.durationMillis = millis
+	}
+}
+
+// normalizeDurations converts the configured duration fields in place and
+// returns how many values could not be parsed
+func (c *config) normalizeDurations(entry *LogEntry) int {
+	unparsed := 0
+
+	for _, key := range c.durationFields {
+		val, ok := entry.Fields[key]
+		if !ok {
+			continue
+		}
+
+		d, ok := parseDurationValue(val, c.durationUnit(key))
+		if !ok {
+			unparsed++
+
+			continue
+		}
+
+		if c.durationMillis {
+			entry.Fields[key] = DurationMillis(d)
+		} else {
+			entry.Fields[key] = d
+		}
+	}
+
+	return unparsed
+}
+
+// durationUnit returns the unit for bare numbers in the given field
+func (c *config) durationUnit(key string) time.Duration {
+	if unit, ok := c.durationUnits[key]; ok {
+		return unit
+	}
+
+	lower := strings.ToLower(key)
+
+	for _, suffix := range []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		{"_ns", time.Nanosecond},
+		{"_us", time.Microsecond},
+		{"_micros", time.Microsecond},
+		{"_ms", time.Millisecond},
+		{"_millis", time.Millisecond},
+		{"_s", time.Second},
+		{"_sec", time.Second},
+		{"_seconds", time.Second},
+	} {
+		if strings.HasSuffix(lower, suffix.suffix) {
+			return suffix.unit
+		}
+	}
+
+	return time.Second
+}
+
+// parseDurationValue converts a field value to a duration, using unit for bare numbers
+func parseDurationValue(val interface{}, unit time.Duration) (time.Duration, bool) {
+	switch v := val.(type) {
+	case time.Duration:
+		return v, true
+	case float64:
+		return time.Duration(v * float64(unit)), true
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, false
+		}
+
+		return time.Duration(f * float64(unit)), true
+	case string:
+		s := strings.TrimSpace(v)
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, true
+		}
+
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return time.Duration(f * float64(unit)), true
+		}
+
+		return 0, false
+	default:
+		return 0, false
+	}
+}
+
+// DurationMillis converts a duration to float64 milliseconds
+func DurationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// FieldQuantile returns the nearest-rank quantile q, between 0 and 1, of
+// the numeric field at key across entries, exactly. Durations count in
+// milliseconds, so a field normalized by WithDurationFields gives the same
+// result as a time.Duration or, under WithDurationsAsMillis, as float64
+// milliseconds; duration strings such as "250ms" are read too. Values
+// are converted as StreamStats converts them, and other values are
+// skipped. It reports false when no entry has a usable value or q is out
+// of range.
+func FieldQuantile(entries []LogEntry, key string, q float64) (float64, bool) {
+	if q < 0 || q > 1 {
+		return 0, false
+	}
+
+	var values []float64
+
+	for i := range entries {
+		if val, ok := lookupField(entries[i].Fields, key); ok {
+			if x, ok := streamValue(val); ok {
+				values = append(values, x)
+			}
+		}
+	}
+
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	sort.Float64s(values)
+
+	return values[max(0, int(math.Ceil(q*float64(len(values))))-1)], true
+}
@@ -0,0 +1,95 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurationFields(t *testing.T) {
+	parser := NewWithFormat(FormatLogfmt,
+		WithDurationFields("took", "latency_us", "elapsed", "wait", "bad"),
+		WithDurationUnit("wait", time.Millisecond),
+	)
+
+	entries, stats, err := ParseWithStats(parser, strings.NewReader(
+		`level=info msg=done took=2m5s latency_us=4500 elapsed=1.5 wait=250 bad=soon`))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	want := map[string]time.Duration{
+		"took":       2*time.Minute + 5*time.Second,
+		"latency_us": 4500 * time.Microsecond,
+		"elapsed":    1500 * time.Millisecond,
+		"wait":       250 * time.Millisecond,
+	}
+
+	fields := entries[0].Fields
+	for key, d := range want {
+		if fields[key] != d {
+			t.Errorf("Fields[%q] = %v (%T), want %v", key, fields[key], fields[key], d)
+		}
+	}
+
+	if fields["bad"] != "soon" {
+		t.Errorf("unparseable value should be left alone, got %v", fields["bad"])
+	}
+
+	if stats.DurationsUnparsed != 1 {
+		t.Errorf("DurationsUnparsed = %d, want 1", stats.DurationsUnparsed)
+	}
+}
+
+func TestDurationFieldsAsMillis(t *testing.T) {
+	parser := NewWithFormat(FormatJSON,
+		WithDurationFields("duration_ms", "total"),
+		WithDurationsAsMillis(true),
+	)
+
+	entries, err := parser.ParseString(`{"msg":"ok","duration_ms":12.5,"total":"1.2s"}`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	fields := entries[0].Fields
+	if fields["duration_ms"] != 12.5 || fields["total"] != 1200.0 {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
+
+func TestFieldQuantileDurationRepresentations(t *testing.T) {
+	input := "msg=a took=100ms\nmsg=b took=0.25s\nmsg=c took=1.5\nmsg=d took=2s\nmsg=e took=later\n"
+
+	for _, millis := range []bool{false, true} {
+		p := NewWithFormat(FormatLogfmt, WithDurationFields("took"), WithDurationsAsMillis(millis))
+
+		entries, err := p.ParseString(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for q, want := range map[float64]float64{0: 100, 0.5: 250, 0.75: 1500, 1: 2000} {
+			if got, ok := FieldQuantile(entries, "took", q); !ok || got != want {
+				t.Errorf("millis=%v: quantile %v = %v, %v, want %v", millis, q, got, ok, want)
+			}
+		}
+
+		s := NewStreamStats(StreamStatsOptions{Fields: []string{"took"}})
+		for _, e := range entries {
+			s.Observe(e)
+		}
+
+		if fs := s.Snapshot().Fields["took"]; fs.Count != 4 || fs.Min != 100 || fs.Max != 2000 {
+			t.Errorf("millis=%v: stream stats %+v", millis, fs)
+		}
+	}
+
+	if _, ok := FieldQuantile(nil, "took", 0.5); ok {
+		t.Error("want no quantile without values")
+	}
+
+	if _, ok := FieldQuantile([]LogEntry{{Fields: map[string]interface{}{"took": time.Second}}}, "took", 1.5); ok {
+		t.Error("want no quantile out of range")
+	}
+}
@@ -0,0 +1,244 @@
+package logparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ECSVersion is the Elastic Common Schema version WriteECS documents declare
+const ECSVersion = "8.11.0"
+
+// ECSMapping maps field names to the ECS fields they are stored under, such
+// as "status" to "http.response.status_code". Fields without an entry are
+// kept as labels.
+type ECSMapping map[string]string
+
+// defaultECSMapping holds the aliases NormalizeECS recognizes
+var defaultECSMapping = ECSMapping{
+	"status":        "http.response.status_code",
+	"status_code":   "http.response.status_code",
+	"statusCode":    "http.response.status_code",
+	"http_status":   "http.response.status_code",
+	"method":        "http.request.method",
+	"http_method":   "http.request.method",
+	"path":          "url.path",
+	"url":           "url.original",
+	"svc":           "service.name",
+	"service":       "service.name",
+	"service_name":  "service.name",
+	"err":           "error.message",
+	"error":         "error.message",
+	"error_message": "error.message",
+	"duration":      "event.duration",
+	"duration_ms":   "event.duration",
+	"duration_us":   "event.duration",
+	"duration_ns":   "event.duration",
+	"elapsed":       "event.duration",
+	"elapsed_ms":    "event.duration",
+	"latency":       "event.duration",
+	"latency_ms":    "event.duration",
+	"took":          "event.duration",
+	"took_ms":       "event.duration",
+	"host":          "host.name",
+	"hostname":      "host.name",
+	"pid":           "process.pid",
+	"logger":        "log.logger",
+	"trace_id":      "trace.id",
+	"traceId":       "trace.id",
+	"span_id":       "span.id",
+	"spanId":        "span.id",
+	"user_id":       "user.id",
+	"client_ip":     "client.ip",
+	"_raw":          "event.original",
+}
+
+// ecsReserved lists the ECS fields filled from the entry itself
+var ecsReserved = map[string]bool{
+	"@timestamp":  true,
+	"message":     true,
+	"log.level":   true,
+	"ecs.version": true,
+}
+
+// ecsNumeric lists the ECS fields whose values are numbers
+var ecsNumeric = map[string]bool{
+	"http.response.status_code": true,
+	"process.pid":               true,
+}
+
+// DefaultECSMapping returns a copy of the aliases NormalizeECS recognizes,
+// for callers to extend
+func DefaultECSMapping() ECSMapping {
+	m := make(ECSMapping, len(defaultECSMapping))
+	for k, v := range defaultECSMapping {
+		m[k] = v
+	}
+
+	return m
+}
+
+// NormalizeECS renames the entry's fields to ECS names using the default
+// mapping. See ECSMapping.Normalize.
+func NormalizeECS(entry LogEntry) LogEntry {
+	return defaultECSMapping.Normalize(entry)
+}
+
+// Normalize returns a copy of entry whose Fields are keyed by dotted ECS
+// names. Mapped status codes and process IDs become numbers, and durations
+// become event.duration in nanoseconds, using the unit implied by the key
+// suffix as WithDurationFields does. Fields kept with WithRawLine fill
+// event.original. Every other field, and a mapped field whose value does
+// not fit or whose target is already taken, is stored as a string under
+// labels.<key> with dots in the key replaced by underscores, since ECS
+// labels are flat keywords. Fields are mapped in key order, and mappings
+// onto @timestamp, message, log.level, or ecs.version are ignored.
+func (m ECSMapping) Normalize(entry LogEntry) LogEntry {
+	keys := make([]string, 0, len(entry.Fields))
+	for k := range entry.Fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	fields := make(map[string]interface{}, len(keys))
+	units := &config{}
+
+	for _, k := range keys {
+		val := entry.Fields[k]
+
+		if target, ok := m[k]; ok && !ecsReserved[target] {
+			if _, taken := fields[target]; !taken {
+				if v, ok := ecsValue(target, val, units.durationUnit(k)); ok {
+					fields[target] = v
+
+					continue
+				}
+			}
+		}
+
+		fields["labels."+strings.ReplaceAll(k, ".", "_")] = formatValue(val)
+	}
+
+	entry.Fields = fields
+
+	return entry
+}
+
+// ecsValue converts val to the type the ECS field target holds
+func ecsValue(target string, val interface{}, unit time.Duration) (interface{}, bool) {
+	switch {
+	case target == "event.duration":
+		d, ok := parseDurationValue(val, unit)
+
+		return int64(d), ok
+	case ecsNumeric[target]:
+		n, ok := numericValue(val)
+
+		return int64(n), ok && n == float64(int64(n))
+	default:
+		if _, ok := val.(string); !ok {
+			return formatValue(val), val != nil
+		}
+
+		return val, true
+	}
+}
+
+// WriteECS writes the entries as ECS JSON documents, one per line, with the
+// default mapping. See ECSMapping.Write.
+func WriteECS(w io.Writer, entries []LogEntry) error {
+	return defaultECSMapping.Write(w, entries)
+}
+
+// Write writes the entries as ECS JSON documents, one per line. Each
+// document holds @timestamp, log.level, message, and ecs.version, followed
+// by the normalized fields nested along their dots.
+func (m ECSMapping) Write(w io.Writer, entries []LogEntry) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	for _, entry := range entries {
+		if err := enc.Encode(m.Document(entry)); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Document returns the ECS document for an entry, nested along the dots
+// in its normalized field names
+func (m ECSMapping) Document(entry LogEntry) map[string]interface{} {
+	doc := map[string]interface{}{
+		"@timestamp": entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		"message":    entry.Message,
+	}
+
+	setECSPath(doc, "log.level", entry.Level)
+
+	if entry.Service != "" {
+		setECSPath(doc, "service.name", entry.Service)
+	}
+
+	if entry.Logger != "" {
+		setECSPath(doc, "log.logger", entry.Logger)
+	}
+	setECSPath(doc, "ecs.version", ECSVersion)
+
+	normalized := m.Normalize(entry)
+
+	keys := make([]string, 0, len(normalized.Fields))
+	for k := range normalized.Fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, taken := doc[k]; !taken && !setECSPath(doc, k, normalized.Fields[k]) {
+			// A mapping that nests under a value keeps its dotted name
+			doc[k] = normalized.Fields[k]
+		}
+	}
+
+	return doc
+}
+
+// setECSPath stores val in doc under a dotted path, creating the objects
+// along it. It reports false when the path is taken or runs into a value.
+func setECSPath(doc map[string]interface{}, path string, val interface{}) bool {
+	parts := strings.Split(path, ".")
+	obj := doc
+
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := obj[part]
+		if !ok {
+			child := make(map[string]interface{})
+			obj[part] = child
+			obj = child
+
+			continue
+		}
+
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		obj = child
+	}
+
+	last := parts[len(parts)-1]
+	if _, taken := obj[last]; taken {
+		return false
+	}
+
+	obj[last] = val
+
+	return true
+}
@@ -0,0 +1,72 @@
+package logparser
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteECS(t *testing.T) {
+	entries, err := ParseFile(New(WithBlockDetection(), WithRawLine(true)), "testdata/ecs.log")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteECS(&buf, entries); err != nil {
+		t.Fatalf("WriteECS() error = %v", err)
+	}
+
+	checkGolden(t, "ecs.json", buf.Bytes())
+}
+
+func TestNormalizeECS(t *testing.T) {
+	entry := LogEntry{
+		Timestamp: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		Level:     LevelError,
+		Message:   "boom",
+		Fields: map[string]interface{}{
+			"status":      "503",
+			"status_code": 500.0,
+			"duration_us": 1500.0,
+			"error":       map[string]interface{}{"code": 1},
+			"message":     "shadowed",
+			"a.b":         true,
+		},
+	}
+
+	got := NormalizeECS(entry).Fields
+	want := map[string]interface{}{
+		"http.response.status_code": int64(503),
+		"labels.status_code":        "500",
+		"event.duration":            int64(1500 * time.Microsecond),
+		"error.message":             `{"code":1}`,
+		"labels.message":            "shadowed",
+		"labels.a_b":                "true",
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("NormalizeECS() = %v, want %v", got, want)
+	}
+
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, got[k], v)
+		}
+	}
+
+	if _, ok := entry.Fields["status"]; !ok {
+		t.Error("NormalizeECS modified its input")
+	}
+
+	mapping := DefaultECSMapping()
+	mapping["a.b"] = "custom.flag"
+
+	if got := mapping.Normalize(entry).Fields["custom.flag"]; got != "true" {
+		t.Errorf("extended mapping gave custom.flag = %#v", got)
+	}
+
+	if _, ok := NormalizeECS(entry).Fields["custom.flag"]; ok {
+		t.Error("extending a copy changed the default mapping")
+	}
+}
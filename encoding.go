@@ -0,0 +1,184 @@
+package logparser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Input sniffing constants
+const (
+	sniffSize       = 512  // Bytes inspected to detect the input encoding
+	utf16NullRatio  = 0.9  // Minimum share of zero bytes in the high-byte positions
+	utf16NoiseRatio = 0.05 // Maximum share of zero bytes in the low-byte positions
+)
+
+// decodeInput wraps r so that a leading UTF-8 byte order mark is dropped and
+// UTF-16 input (detected by BOM or by the pattern of zero bytes) is
+// transcoded to UTF-8. It returns the number of bytes skipped at the start.
+func decodeInput(r io.Reader) (io.Reader, int64) {
+	br := bufio.NewReaderSize(r, sniffSize)
+
+	// Sniff only what the first read returns so that no more of the
+	// underlying reader is consumed than a plain scanner would
+	_, _ = br.Peek(1)
+	head, _ := br.Peek(br.Buffered())
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		n, _ := br.Discard(3)
+
+		return br, int64(n)
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		n, _ := br.Discard(2)
+
+		return &utf16Reader{r: br}, int64(n)
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		n, _ := br.Discard(2)
+
+		return &utf16Reader{r: br, bigEndian: true}, int64(n)
+	}
+
+	switch utf16NullPattern(head) {
+	case 1:
+		return &utf16Reader{r: br}, 0
+	case -1:
+		return &utf16Reader{r: br, bigEndian: true}, 0
+	}
+
+	return br, 0
+}
+
+// isUTF16 reports whether head, the start of the input, is UTF-16 as
+// decodeInput detects it: by byte order mark or by the pattern of zero bytes
+func isUTF16(head []byte) bool {
+	return bytes.HasPrefix(head, []byte{0xFF, 0xFE}) || bytes.HasPrefix(head, []byte{0xFE, 0xFF}) || utf16NullPattern(head) != 0
+}
+
+// utf16NullPattern detects BOM-less UTF-16 from mostly-ASCII text, where
+// every other byte is zero. It returns 1 for little endian, -1 for big
+// endian, and 0 when the input does not look like UTF-16.
+func utf16NullPattern(head []byte) int {
+	pairs := len(head) / 2
+	if pairs < 2 {
+		return 0
+	}
+
+	var evenZeros, oddZeros int
+
+	for i := 0; i+1 < len(head); i += 2 {
+		if head[i] == 0 {
+			evenZeros++
+		}
+
+		if head[i+1] == 0 {
+			oddZeros++
+		}
+	}
+
+	switch {
+	case float64(oddZeros) >= utf16NullRatio*float64(pairs) && float64(evenZeros) <= utf16NoiseRatio*float64(pairs):
+		return 1
+	case float64(evenZeros) >= utf16NullRatio*float64(pairs) && float64(oddZeros) <= utf16NoiseRatio*float64(pairs):
+		return -1
+	default:
+		return 0
+	}
+}
+
+// utf16Reader transcodes a UTF-16 byte stream to UTF-8. Unpaired
+// surrogates are replaced with U+FFFD.
+type utf16Reader struct {
+	r         *bufio.Reader
+	bigEndian bool
+	pending   []byte // Encoded UTF-8 not yet returned
+	unit      rune   // Code unit read ahead while pairing surrogates
+	hasUnit   bool
+}
+
+// Read implements io.Reader
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	n := 0
+
+	for n < len(p) {
+		if len(u.pending) > 0 {
+			c := copy(p[n:], u.pending)
+			u.pending = u.pending[c:]
+			n += c
+
+			continue
+		}
+
+		r, err := u.readRune()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+
+			return 0, err
+		}
+
+		if n+utf8.RuneLen(r) > len(p) {
+			u.pending = utf8.AppendRune(u.pending[:0], r)
+
+			continue
+		}
+
+		n += utf8.EncodeRune(p[n:], r)
+	}
+
+	return n, nil
+}
+
+// readRune decodes the next rune, pairing surrogates
+func (u *utf16Reader) readRune() (rune, error) {
+	first, err := u.readUnit()
+	if err != nil {
+		return 0, err
+	}
+
+	if !utf16.IsSurrogate(first) {
+		return first, nil
+	}
+
+	second, err := u.readUnit()
+	if err != nil {
+		return utf8.RuneError, nil //nolint:nilerr // Report the dangling surrogate; the error recurs on the next read
+	}
+
+	if r := utf16.DecodeRune(first, second); r != utf8.RuneError {
+		return r, nil
+	}
+
+	// Not a valid pair: keep the second unit for the next rune
+	u.unit, u.hasUnit = second, true
+
+	return utf8.RuneError, nil
+}
+
+// readUnit reads one 16-bit code unit
+func (u *utf16Reader) readUnit() (rune, error) {
+	if u.hasUnit {
+		u.hasUnit = false
+
+		return u.unit, nil
+	}
+
+	var buf [2]byte
+	if _, err := io.ReadFull(u.r, buf[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, io.EOF // Ignore a trailing odd byte
+		}
+
+		return 0, err
+	}
+
+	if u.bigEndian {
+		return rune(buf[0])<<8 | rune(buf[1]), nil
+	}
+
+	return rune(buf[1])<<8 | rune(buf[0]), nil
+}
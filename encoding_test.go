@@ -0,0 +1,119 @@
+package logparser
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestEncodingFixtures(t *testing.T) {
+	for _, path := range []string{"testdata/bom.log", "testdata/utf16le.log"} {
+		t.Run(path, func(t *testing.T) {
+			fromFile, err := ParseFile(New(), path)
+			if err != nil {
+				t.Fatalf("ParseFile() error = %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fromString, err := New().ParseString(string(data))
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			for _, entries := range [][]LogEntry{fromFile, fromString} {
+				if len(entries) != 2 {
+					t.Fatalf("want 2 entries, got %d", len(entries))
+				}
+
+				if entries[0].Level != LevelError || entries[0].Message != "Déjà vu: connection reset" {
+					t.Errorf("unexpected first entry %+v", entries[0])
+				}
+
+				if entries[1].Message != "recovered 🎉" || entries[1].Service != "api" {
+					t.Errorf("unexpected second entry %+v", entries[1])
+				}
+			}
+		})
+	}
+}
+
+func TestCRLFLogfmt(t *testing.T) {
+	data, err := os.ReadFile("testdata/crlf.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromReader, err := New().Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromString, err := New().ParseString(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entries := range [][]LogEntry{fromReader, fromString} {
+		if entries[0].Fields["mount"] != "/var" || entries[1].Fields["freed"] != "2GB" {
+			t.Errorf("trailing carriage return leaked into fields: %v / %v", entries[0].Fields, entries[1].Fields)
+		}
+	}
+}
+
+func TestBOMInString(t *testing.T) {
+	entries, err := New().ParseString("\uFEFF" + `{"level":"warn","msg":"first"}`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if entries[0].Message != "first" || entries[0].Level != "WARN" {
+		t.Errorf("unexpected entry %+v", entries[0])
+	}
+}
+
+func TestUTF16WithoutBOM(t *testing.T) {
+	text := `{"level":"error","msg":"über failure"}` + "\n" + `{"level":"info","msg":"ok"}` + "\n"
+	units := utf16.Encode([]rune(text))
+
+	le := make([]byte, 0, len(units)*2)
+	be := make([]byte, 0, len(units)*2)
+
+	for _, u := range units {
+		le = append(le, byte(u), byte(u>>8))
+		be = append(be, byte(u>>8), byte(u))
+	}
+
+	for name, data := range map[string][]byte{"little endian": le, "big endian": be} {
+		entries, err := New().Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: Parse() error = %v", name, err)
+		}
+
+		if len(entries) != 2 || entries[0].Message != "über failure" {
+			t.Errorf("%s: unexpected entries %+v", name, entries)
+		}
+	}
+}
+
+func TestInvalidUTF8Replaced(t *testing.T) {
+	input := "level=error msg=\"bad \xff\xfe bytes\" user=b\xc3ob\n"
+
+	entries, err := New().Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := entries[0].Message, "bad � bytes"; got != want {
+		t.Errorf("want message %q, got %q", want, got)
+	}
+
+	if got, want := entries[0].Fields["user"], "b�ob"; got != want {
+		t.Errorf("want user %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,248 @@
+package logparser
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+// enrichment is one lookup table applied by WithEnrichment or
+// WithCIDREnrichment
+type enrichment struct {
+	key   string
+	dest  string
+	table map[string]interface{}
+	cidrs *cidrTable
+	err   error // Invalid CIDR prefix, reported by NewE
+}
+
+// WithEnrichment adds static metadata to entries: when the key field's
+// value, formatted as a string, is in table, the matching value is stored
+// in Fields[destKey], such as the owning team for a "service" field. May
+// be given more than once; tables are applied in order after extraction,
+// so later ones can look up the results of earlier ones. A value missing
+// from the table leaves the entry unchanged. Lookups are counted in
+// Stats.EnrichmentHits and Stats.EnrichmentMisses; entries without the
+// key field are not counted.
+func WithEnrichment(key string, table map[string]interface{}, destKey string) Option {
+	return func(c *config) {
+		c.enrichments = append(c.enrichments, enrichment{key: key, dest: destKey, table: table})
+	}
+}
+
+// WithCIDREnrichment is WithEnrichment for IP address fields, with a table
+// keyed by CIDR prefix ("10.20.0.0/16", "2001:db8::/32") instead of exact
+// value: the value of the longest prefix containing the address is stored
+// in Fields[destKey]. Addresses may carry a port ("10.0.0.1:8080") and
+// IPv4-mapped IPv6 addresses match IPv4 prefixes. The table is compiled
+// into a radix tree when the option is created, so the lookup cost does
+// not grow with the number of prefixes. An invalid prefix makes NewE fail;
+// New ignores the prefix.
+func WithCIDREnrichment(key string, table map[string]interface{}, destKey string) Option {
+	cidrs, err := compileCIDRTable(table)
+
+	return func(c *config) {
+		c.enrichments = append(c.enrichments, enrichment{key: key, dest: destKey, cidrs: cidrs, err: err})
+	}
+}
+
+// enrich applies the lookup tables to entry and returns the number of
+// hits and misses
+func (c *config) enrich(entry *LogEntry) (hits, misses int) {
+	for i := range c.enrichments {
+		en := &c.enrichments[i]
+
+		val, ok := entryField(entry, en.key)
+		if !ok {
+			continue
+		}
+
+		var found interface{}
+
+		if en.cidrs != nil {
+			found, ok = en.cidrs.lookupString(formatValue(val))
+		} else {
+			found, ok = en.table[formatValue(val)]
+		}
+
+		if !ok {
+			misses++
+
+			continue
+		}
+
+		hits++
+
+		c.setField(entry, en.dest, found)
+	}
+
+	return hits, misses
+}
+
+// cidrStride is the number of address bits each radix tree level consumes
+const cidrStride = 4
+
+// cidrTable is a multibit radix tree of CIDR prefixes, one for IPv4 and
+// one for IPv6 addresses, consuming four address bits per level so an
+// IPv4 lookup visits at most eight nodes. Prefixes whose length is not a
+// multiple of four are expanded to the slots they cover. Nodes are held
+// in a slice and linked by index.
+type cidrTable struct {
+	nodes  []cidrNode
+	roots  [2]int32 // IPv4 and IPv6 roots
+	values []interface{}
+}
+
+// cidrNode is a tree node. Per slot it holds the child, the value of the
+// longest prefix ending in the slot, and that prefix's length; indexes
+// are -1 for none.
+type cidrNode struct {
+	children [1 << cidrStride]int32
+	values   [1 << cidrStride]int32
+	lengths  [1 << cidrStride]int16
+}
+
+// compileCIDRTable builds the radix tree of a prefix table. Prefixes are
+// inserted in sorted order so the tree is the same for the same table.
+// Invalid prefixes are skipped, and the first is reported.
+func compileCIDRTable(table map[string]interface{}) (*cidrTable, error) {
+	t := &cidrTable{}
+	t.roots[0] = t.newNode()
+	t.roots[1] = t.newNode()
+
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var firstErr error
+
+	for _, k := range keys {
+		prefix, err := netip.ParsePrefix(k)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%w: CIDR enrichment: %w", ErrInvalidOptions, err)
+			}
+
+			continue
+		}
+
+		t.values = append(t.values, table[k])
+		t.insert(prefix.Masked(), int32(len(t.values)-1))
+	}
+
+	return t, firstErr
+}
+
+// newNode appends an empty node and returns its index
+func (t *cidrTable) newNode() int32 {
+	n := cidrNode{}
+	for i := range n.children {
+		n.children[i], n.values[i], n.lengths[i] = -1, -1, -1
+	}
+
+	t.nodes = append(t.nodes, n)
+
+	return int32(len(t.nodes) - 1)
+}
+
+// insert adds a prefix with the value at index value, which replaces the
+// value of an equal prefix but not that of a longer one
+func (t *cidrTable) insert(prefix netip.Prefix, value int32) {
+	node, bytes, _ := t.root(prefix.Addr())
+	bits := prefix.Bits()
+
+	levels := 0
+	if bits > 0 {
+		levels = (bits - 1) / cidrStride
+	}
+
+	for level := range levels {
+		slot := addrNibble(&bytes, level)
+
+		child := t.nodes[node].children[slot]
+		if child < 0 {
+			child = t.newNode()
+			t.nodes[node].children[slot] = child
+		}
+
+		node = child
+	}
+
+	// The remaining 1 to 4 bits, or none for /0, select the slots covered
+	rest := bits - levels*cidrStride
+	first := addrNibble(&bytes, levels) &^ (1<<(cidrStride-rest) - 1)
+
+	n := &t.nodes[node]
+	for slot := first; slot < first+1<<(cidrStride-rest); slot++ {
+		if int(n.lengths[slot]) <= bits {
+			n.values[slot], n.lengths[slot] = value, int16(bits)
+		}
+	}
+}
+
+// lookupString returns the value of the longest prefix holding the
+// address in s, which may be an address with a port
+func (t *cidrTable) lookupString(s string) (interface{}, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		ap, err := netip.ParseAddrPort(s)
+		if err != nil {
+			return nil, false
+		}
+
+		addr = ap.Addr()
+	}
+
+	return t.lookup(addr)
+}
+
+// lookup returns the value of the longest prefix holding addr
+func (t *cidrTable) lookup(addr netip.Addr) (interface{}, bool) {
+	addr = addr.Unmap().WithZone("")
+	node, bytes, bits := t.root(addr)
+
+	best := int32(-1)
+
+	for level := range bits / cidrStride {
+		n := &t.nodes[node]
+		slot := addrNibble(&bytes, level)
+
+		if v := n.values[slot]; v >= 0 {
+			best = v
+		}
+
+		if node = n.children[slot]; node < 0 {
+			break
+		}
+	}
+
+	if best < 0 {
+		return nil, false
+	}
+
+	return t.values[best], true
+}
+
+// root returns the tree root for the address family of addr, the address
+// bytes, and the number of address bits
+func (t *cidrTable) root(addr netip.Addr) (int32, [16]byte, int) {
+	if addr.Is4() {
+		var b [16]byte
+
+		a := addr.As4()
+		copy(b[:], a[:])
+
+		return t.roots[0], b, 32
+	}
+
+	return t.roots[1], addr.As16(), 128
+}
+
+// addrNibble returns the level'th group of four address bits, counting
+// from the most significant
+func addrNibble(bytes *[16]byte, level int) int {
+	return int(bytes[level/2]>>(4*(1-level%2))) & 0x0f
+}
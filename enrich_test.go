@@ -0,0 +1,105 @@
+package logparser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithEnrichment(t *testing.T) {
+	input := strings.Join([]string{
+		`{"msg":"a","service":"checkout","client":"10.20.3.4:51000"}`,
+		`{"msg":"b","service":"search","client":"10.99.0.1"}`,
+		`{"msg":"c","service":"unknown","client":"192.168.1.1"}`,
+		`{"msg":"d"}`,
+		`{"msg":"e","client":"2001:db8:1::5"}`,
+		`{"msg":"f","client":"::ffff:10.20.0.9"}`,
+	}, "\n")
+
+	p := NewWithFormat(FormatJSON,
+		WithEnrichment("service", map[string]interface{}{"checkout": "payments", "search": "discovery"}, "team"),
+		WithCIDREnrichment("client", map[string]interface{}{
+			"10.0.0.0/8":    "dc1",
+			"10.20.0.0/16":  "dc1-eu",
+			"2001:db8::/32": "dc2",
+		}, "datacenter"),
+		// Later tables see the results of earlier ones
+		WithEnrichment("team", map[string]interface{}{"payments": "#pay-oncall"}, "channel"),
+	)
+
+	entries, stats, err := ParseWithStats(p, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	want := []map[string]interface{}{
+		{"team": "payments", "datacenter": "dc1-eu", "channel": "#pay-oncall"},
+		{"team": "discovery", "datacenter": "dc1"},
+		{},
+		{},
+		{"datacenter": "dc2"},
+		{"datacenter": "dc1-eu"},
+	}
+
+	for i, fields := range want {
+		for _, k := range []string{"team", "datacenter", "channel"} {
+			if got, ok := entries[i].Fields[k]; got != fields[k] || ok != (fields[k] != nil) {
+				t.Errorf("entry %d: Fields[%q] = %v, want %v", i, k, got, fields[k])
+			}
+		}
+	}
+
+	// Hits: 2 teams, 4 datacenters, 1 channel; misses: "unknown", its
+	// address, and the "discovery" channel
+	if stats.EnrichmentHits != 7 || stats.EnrichmentMisses != 3 {
+		t.Errorf("hits, misses = %d, %d, want 7, 3", stats.EnrichmentHits, stats.EnrichmentMisses)
+	}
+}
+
+func TestWithCIDREnrichmentInvalid(t *testing.T) {
+	table := map[string]interface{}{"10.0.0.0/8": "dc1", "not-a-prefix": "x"}
+
+	if _, err := NewE(WithCIDREnrichment("ip", table, "dc")); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("NewE() error = %v, want ErrInvalidOptions", err)
+	}
+
+	entries, err := NewWithFormat(FormatJSON, WithCIDREnrichment("ip", table, "dc")).ParseString(`{"ip":"10.1.1.1","msg":"x"}`)
+	if err != nil || entries[0].Fields["dc"] != "dc1" {
+		t.Errorf("ParseString() = %v, %v; want valid prefixes applied", entries, err)
+	}
+}
+
+func TestCIDRTableLongestPrefix(t *testing.T) {
+	table, err := compileCIDRTable(map[string]interface{}{
+		"0.0.0.0/0":      "default",
+		"192.168.0.0/16": "lan",
+		"192.168.7.0/24": "lab",
+		"192.168.7.7/32": "printer",
+		"::/0":           "v6",
+		"172.16.0.0/12":  "private",
+		"10.0.0.0/10":    "ten-low", // Inserted before the shorter /8
+		"10.0.0.0/8":     "ten",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for addr, want := range map[string]interface{}{
+		"192.168.7.7":   "printer",
+		"192.168.7.8":   "lab",
+		"192.168.8.1":   "lan",
+		"8.8.8.8":       "default",
+		"fe80::1%eth0":  "v6",
+		"[::1]:443":     "v6",
+		"not-an-ip":     nil,
+		"192.168.7.7:1": "printer",
+		"172.31.255.1":  "private",
+		"172.32.0.1":    "default",
+		"10.63.0.1":     "ten-low",
+		"10.64.0.1":     "ten",
+	} {
+		if got, _ := table.lookupString(addr); got != want {
+			t.Errorf("lookup(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package logparser
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+)
+
+// Magnitudes above which an epoch value is read as a finer unit. Seconds
+// stay below 1e11 until the year 5138, so larger values are milliseconds,
+// microseconds, or nanoseconds.
+const (
+	epochMillisThreshold = 1e11
+	epochMicrosThreshold = 1e14
+	epochNanosThreshold  = 1e17
+)
+
+// epochUnit returns the number of fractional second digits carried by an
+// epoch integer part of the given magnitude: 0 for seconds, 3 for
+// milliseconds, 6 for microseconds, 9 for nanoseconds
+func epochUnit(abs float64) int {
+	switch {
+	case abs >= epochNanosThreshold:
+		return 9
+	case abs >= epochMicrosThreshold:
+		return 6
+	case abs >= epochMillisThreshold:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// pow10 holds powers of ten up to 1e9
+var pow10 = [...]int64{1, 10, 100, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9}
+
+// epochFromParts builds a time from the decimal digits of an epoch value.
+// Working on digits rather than a float keeps full nanosecond precision.
+func epochFromParts(neg bool, intDigits, fracDigits string) (time.Time, bool) {
+	if intDigits == "" && fracDigits == "" {
+		return time.Time{}, false
+	}
+
+	var whole int64
+
+	for i := range len(intDigits) {
+		c := intDigits[i]
+		if c < '0' || c > '9' || whole > (math.MaxInt64-int64(c-'0'))/10 {
+			return time.Time{}, false
+		}
+
+		whole = whole*10 + int64(c-'0')
+	}
+
+	unit := epochUnit(float64(whole))
+	scale := pow10[unit]
+
+	// Fraction of one unit, in nanoseconds
+	fracNanos := int64(0)
+	fracWidth := 9 - unit
+
+	for i := range fracWidth {
+		fracNanos *= 10
+
+		if i < len(fracDigits) {
+			c := fracDigits[i]
+			if c < '0' || c > '9' {
+				return time.Time{}, false
+			}
+
+			fracNanos += int64(c - '0')
+		}
+	}
+
+	for i := fracWidth; i < len(fracDigits); i++ {
+		if c := fracDigits[i]; c < '0' || c > '9' {
+			return time.Time{}, false
+		}
+	}
+
+	sec := whole / scale
+	nsec := (whole%scale)*pow10[9-unit] + fracNanos
+
+	if neg {
+		sec, nsec = -sec, -nsec
+	}
+
+	return time.Unix(sec, nsec), true
+}
+
+// parseEpochString parses a decimal epoch value such as "1704207845.123"
+// or "-86400"
+func parseEpochString(s string) (time.Time, bool) {
+	neg := false
+
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intDigits, fracDigits, _ := strings.Cut(s, ".")
+
+	return epochFromParts(neg, intDigits, fracDigits)
+}
+
+// parseEpochFloat converts a float epoch value, keeping sub-second
+// precision down to the resolution of float64
+func parseEpochFloat(v float64) (time.Time, bool) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return time.Time{}, false
+	}
+
+	abs := math.Abs(v)
+	if abs >= math.MaxInt64 {
+		return time.Time{}, false
+	}
+
+	unit := epochUnit(abs)
+	scale := pow10[unit]
+
+	whole := math.Floor(abs)
+	units := int64(whole)
+
+	sec := units / scale
+	nsec := (units%scale)*pow10[9-unit] + int64(math.Round((abs-whole)*float64(pow10[9-unit])))
+
+	if v < 0 {
+		sec, nsec = -sec, -nsec
+	}
+
+	return time.Unix(sec, nsec), true
+}
+
+// parseEpochNumber parses a json.Number epoch value
+func parseEpochNumber(n json.Number) (time.Time, bool) {
+	s := string(n)
+	if strings.ContainsAny(s, "eE") {
+		f, err := n.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+
+		return parseEpochFloat(f)
+	}
+
+	return parseEpochString(s)
+}
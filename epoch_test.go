@@ -0,0 +1,118 @@
+package logparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimestampEpoch(t *testing.T) {
+	tests := []struct {
+		name string
+		val  interface{}
+		want time.Time
+	}{
+		{"float seconds with fraction", 1704207845.5, time.Unix(1704207845, 5e8)},
+		{"millis", float64(1704207845123), time.Unix(1704207845, 123e6)},
+		{"micros", float64(1704207845123456), time.Unix(1704207845, 123456e3)},
+		{"nanos json.Number", json.Number("1704207845123456789"), time.Unix(1704207845, 123456789)},
+		{"json.Number fraction", json.Number("1704207845.123456789"), time.Unix(1704207845, 123456789)},
+		{"json.Number exponent", json.Number("1.7042078455e9"), time.Unix(1704207845, 5e8)},
+		{"int", 1704207845, time.Unix(1704207845, 0)},
+		{"int64 millis", int64(1704207845123), time.Unix(1704207845, 123e6)},
+		{"numeric string", "1704207845.123", time.Unix(1704207845, 123e6)},
+		{"negative seconds", -86400.25, time.Unix(-86400, -25e7)},
+		{"negative string", "-1.5", time.Unix(-1, -5e8)},
+		{"pre-1970 seconds not millis", json.Number("-631152000"), time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTimestamp(tt.val)
+			if err != nil {
+				t.Fatalf("parseTimestamp(%v) error = %v", tt.val, err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("parseTimestamp(%v) = %v, want %v", tt.val, got.UTC(), tt.want.UTC())
+			}
+		})
+	}
+
+	for _, bad := range []interface{}{"12abc", "", "-", json.Number("1e400"), "99999999999999999999"} {
+		if _, err := parseTimestamp(bad); err == nil {
+			t.Errorf("parseTimestamp(%q) should fail", bad)
+		}
+	}
+}
+
+func TestEpochRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	for range 10000 {
+		// Any instant between 1900 and 2200
+		want := time.Unix(rng.Int64N(9467280000)-2208988800, rng.Int64N(1e9))
+
+		// Seconds with a nine-digit fraction round-trip exactly
+		sec := want.Unix()
+		nsec := int64(want.Nanosecond())
+
+		s := fmt.Sprintf("%d.%09d", sec, nsec)
+		if sec < 0 && nsec > 0 {
+			s = fmt.Sprintf("-%d.%09d", -sec-1, 1e9-nsec)
+		}
+
+		got, err := parseTimestamp(json.Number(s))
+		if err != nil {
+			t.Fatalf("parseTimestamp(%s) error = %v", s, err)
+		}
+
+		if d := got.Sub(want); d < -2 || d > 2 {
+			t.Fatalf("round trip of %s lost %v", s, d)
+		}
+
+		// float64 seconds keep precision to the float's resolution
+		f := float64(want.UnixNano()) / 1e9
+
+		got, err = parseTimestamp(f)
+		if err != nil {
+			t.Fatalf("parseTimestamp(%v) error = %v", f, err)
+		}
+
+		if d := got.Sub(want); d < -time.Microsecond || d > time.Microsecond {
+			t.Fatalf("float round trip of %v lost %v", want, d)
+		}
+	}
+}
+
+func TestLogfmtNumericTimestamp(t *testing.T) {
+	entries, err := NewWithFormat(FormatLogfmt).ParseString(`ts=1704207845.123 level=info msg=hi`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if want := time.Unix(1704207845, 123e6); !entries[0].Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", entries[0].Timestamp, want)
+	}
+}
+
+func TestJSONNanosecondTimestamp(t *testing.T) {
+	for _, line := range []string{
+		`{"ts":1704207845123456789,"level":"info","msg":"hi"}`,
+		// Deep nesting sends the line through encoding/json
+		`{"ts":1704207845123456789,"level":"info","msg":"hi","deep":` +
+			strings.Repeat("[", maxJSONScanDepth+1) + strings.Repeat("]", maxJSONScanDepth+1) + `}`,
+	} {
+		entries, err := NewWithFormat(FormatJSON).ParseString(line)
+		if err != nil {
+			t.Fatalf("ParseString(%s) error = %v", line, err)
+		}
+
+		if got := entries[0].Timestamp.UnixNano(); got != 1704207845123456789 {
+			t.Errorf("ParseString(%s) UnixNano = %d, want 1704207845123456789", line, got)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package logparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EqualOption configures EntryEqual and DiffEntries
+type EqualOption func(*equalConfig)
+
+type equalConfig struct {
+	ignoreTime bool
+	ignore     map[string]bool
+	coerce     bool
+}
+
+// WithIgnoreTimestamp leaves the entries' timestamps out of the comparison,
+// for lines without one, which get the time they were parsed
+func WithIgnoreTimestamp(ignore bool) EqualOption {
+	return func(c *equalConfig) {
+		c.ignoreTime = ignore
+	}
+}
+
+// WithIgnoreFields leaves the given fields out of the comparison. Keys are
+// field paths as in CSV columns, so "http.status" names a nested value.
+func WithIgnoreFields(keys ...string) EqualOption {
+	return func(c *equalConfig) {
+		if c.ignore == nil {
+			c.ignore = make(map[string]bool)
+		}
+
+		for _, k := range keys {
+			c.ignore[k] = true
+		}
+	}
+}
+
+// WithCoerceNumbers compares numeric field values by value, so int64(200),
+// 200.0, and json.Number("200") are equal. Numeric strings are still
+// strings.
+func WithCoerceNumbers(coerce bool) EqualOption {
+	return func(c *equalConfig) {
+		c.coerce = coerce
+	}
+}
+
+// EntryEqual reports whether two entries hold the same data. Timestamps are
+// compared as instants, ignoring location and monotonic clock readings, a
+// nil Fields map equals an empty one, and field values are compared deeply,
+// with time.Time values also compared as instants. Sources are compared by
+// value.
+func EntryEqual(a, b LogEntry, opts ...EqualOption) bool {
+	d := newEntryDiff(opts, true)
+	d.entries(a, b)
+
+	return len(d.lines) == 0
+}
+
+// DiffEntries describes how b differs from a, one line per difference in
+// the form "path: a != b", with fields in key order. Values of different
+// types show their type. It returns "" when EntryEqual(a, b, opts...) holds.
+func DiffEntries(a, b LogEntry, opts ...EqualOption) string {
+	d := newEntryDiff(opts, false)
+	d.entries(a, b)
+
+	return strings.Join(d.lines, "\n")
+}
+
+// entryDiff collects the differences between two entries
+type entryDiff struct {
+	cfg   equalConfig
+	first bool // Stop at the first difference
+	lines []string
+}
+
+func newEntryDiff(opts []EqualOption, first bool) *entryDiff {
+	d := &entryDiff{first: first}
+	for _, opt := range opts {
+		opt(&d.cfg)
+	}
+
+	return d
+}
+
+// done reports whether the comparison can stop
+func (d *entryDiff) done() bool {
+	return d.first && len(d.lines) > 0
+}
+
+// add records a difference at path
+func (d *entryDiff) add(path string, a, b interface{}) {
+	d.lines = append(d.lines, fmt.Sprintf("%s: %s != %s", path, diffValue(a, b), diffValue(b, a)))
+}
+
+// entries compares the standard fields, then Fields and Source
+func (d *entryDiff) entries(a, b LogEntry) {
+	if !d.cfg.ignoreTime && !a.Timestamp.Equal(b.Timestamp) {
+		d.add("timestamp", a.Timestamp, b.Timestamp)
+	}
+
+	if a.Level != b.Level {
+		d.add("level", a.Level, b.Level)
+	}
+
+	if a.Message != b.Message {
+		d.add("message", a.Message, b.Message)
+	}
+
+	if a.Service != b.Service {
+		d.add("service", a.Service, b.Service)
+	}
+
+	if a.Logger != b.Logger {
+		d.add("logger", a.Logger, b.Logger)
+	}
+
+	d.maps("fields", "", a.Fields, b.Fields)
+
+	if !d.done() && !reflect.DeepEqual(a.Source, b.Source) {
+		d.add("source", a.Source, b.Source)
+	}
+}
+
+// maps compares two field maps key by key. prefix is the field path of
+// the maps, empty for Fields itself.
+func (d *entryDiff) maps(label, prefix string, a, b map[string]interface{}) {
+	keys := make([]string, 0, len(a)+len(b))
+
+	for k := range a {
+		keys = append(keys, k)
+	}
+
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if d.done() {
+			return
+		}
+
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+
+		if d.cfg.ignore[path] {
+			continue
+		}
+
+		av, aok := a[k]
+		bv, bok := b[k]
+
+		if !aok || !bok {
+			d.add(label+"."+path, missingIf(av, !aok), missingIf(bv, !bok))
+
+			continue
+		}
+
+		d.value(label, path, av, bv)
+	}
+}
+
+// value compares two field values at path
+func (d *entryDiff) value(label, path string, a, b interface{}) {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+
+	if aok && bok {
+		d.maps(label, path, am, bm)
+
+		return
+	}
+
+	as, aok := a.([]interface{})
+	bs, bok := b.([]interface{})
+
+	if aok && bok && len(as) == len(bs) {
+		for i := range as {
+			d.value(label, path+"["+strconv.Itoa(i)+"]", as[i], bs[i])
+		}
+
+		return
+	}
+
+	if !d.equalValues(a, b) {
+		d.add(label+"."+path, a, b)
+	}
+}
+
+// equalValues compares two leaf values
+func (d *entryDiff) equalValues(a, b interface{}) bool {
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+
+		return ok && at.Equal(bt)
+	}
+
+	if d.cfg.coerce {
+		an, aok := equalNumber(a)
+		bn, bok := equalNumber(b)
+
+		if aok && bok {
+			return an == bn
+		}
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// equalNumber returns a numeric value as a float64
+func equalNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// diffMissing stands for an absent field in a diff
+type diffMissing struct{}
+
+// missingIf returns diffMissing when missing, else v
+func missingIf(v interface{}, missing bool) interface{} {
+	if missing {
+		return diffMissing{}
+	}
+
+	return v
+}
+
+// diffValue formats v for a diff, adding its type when other differs in
+// type, so 200 (float64) and "200" (string) can be told apart
+func diffValue(v, other interface{}) string {
+	var s string
+
+	switch val := v.(type) {
+	case diffMissing:
+		return "(missing)"
+	case time.Time:
+		s = val.Format(time.RFC3339Nano)
+	case *Source:
+		if val == nil {
+			return "<nil>"
+		}
+
+		s = fmt.Sprintf("%s:%d@%d", val.Name, val.Line, val.Offset)
+	case string:
+		s = strconv.Quote(val)
+	default:
+		s = fmt.Sprintf("%v", val)
+	}
+
+	if _, missing := other.(diffMissing); !missing && reflect.TypeOf(v) != reflect.TypeOf(other) {
+		s += fmt.Sprintf(" (%T)", v)
+	}
+
+	return s
+}
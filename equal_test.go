@@ -0,0 +1,109 @@
+package logparser
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEntryEqual(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	base := LogEntry{
+		Timestamp: ts,
+		Level:     LevelError,
+		Message:   "payment failed",
+		Fields: map[string]interface{}{
+			"status": 502.0,
+			"http":   map[string]interface{}{"method": "POST", "attempt": int64(2)},
+			"tags":   []interface{}{"pay", 1.0},
+		},
+	}
+
+	same := base
+	same.Timestamp = ts.In(time.FixedZone("CET", 3600)).Add(0)
+	same.Fields = map[string]interface{}{
+		"status": 502.0,
+		"http":   map[string]interface{}{"method": "POST", "attempt": int64(2)},
+		"tags":   []interface{}{"pay", 1.0},
+	}
+
+	if !EntryEqual(base, same) {
+		t.Errorf("EntryEqual() = false for the same instant in another zone:\n%s", DiffEntries(base, same))
+	}
+
+	coerced := same
+	coerced.Timestamp = time.Now()
+	coerced.Fields = map[string]interface{}{
+		"status": json.Number("502"),
+		"http":   map[string]interface{}{"method": "POST", "attempt": 2.0, "retry": true},
+		"tags":   []interface{}{"pay", 1},
+	}
+
+	tests := []struct {
+		name string
+		opts []EqualOption
+		want bool
+	}{
+		{"strict", nil, false},
+		{"timestamps ignored", []EqualOption{WithIgnoreTimestamp(true)}, false},
+		{"numbers coerced", []EqualOption{WithIgnoreTimestamp(true), WithCoerceNumbers(true)}, false},
+		{"all", []EqualOption{WithIgnoreTimestamp(true), WithCoerceNumbers(true), WithIgnoreFields("http.retry")}, true},
+	}
+
+	for _, tt := range tests {
+		if got := EntryEqual(base, coerced, tt.opts...); got != tt.want {
+			t.Errorf("%s: EntryEqual() = %v, want %v\n%s", tt.name, got, tt.want, DiffEntries(base, coerced, tt.opts...))
+		}
+	}
+
+	empty := LogEntry{Timestamp: ts, Level: LevelInfo}
+	withMap := empty
+	withMap.Fields = map[string]interface{}{}
+
+	if !EntryEqual(empty, withMap) {
+		t.Error("nil Fields differs from empty Fields")
+	}
+}
+
+func TestDiffEntries(t *testing.T) {
+	a := LogEntry{
+		Timestamp: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Level:     LevelInfo,
+		Message:   "done",
+		Fields: map[string]interface{}{
+			"status": 200.0,
+			"user":   "ann",
+			"http":   map[string]interface{}{"path": "/a"},
+			"tags":   []interface{}{"x", "y"},
+		},
+	}
+
+	b := LogEntry{
+		Timestamp: a.Timestamp.Add(time.Second),
+		Level:     LevelInfo,
+		Message:   "done",
+		Fields: map[string]interface{}{
+			"status": "200",
+			"http":   map[string]interface{}{"path": "/b"},
+			"tags":   []interface{}{"x", "z"},
+			"extra":  true,
+		},
+		Source: &Source{Name: "app.log", Line: 3},
+	}
+
+	want := `timestamp: 2024-01-02T15:04:05Z != 2024-01-02T15:04:06Z
+fields.extra: (missing) != true
+fields.http.path: "/a" != "/b"
+fields.status: 200 (float64) != "200" (string)
+fields.tags[1]: "y" != "z"
+fields.user: "ann" != (missing)
+source: <nil> != app.log:3@0`
+
+	if got := DiffEntries(a, b); got != want {
+		t.Errorf("DiffEntries() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := DiffEntries(a, a); got != "" {
+		t.Errorf("DiffEntries(a, a) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,94 @@
+package logparser
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// EscalationRule decides whether an entry's level is raised. It returns
+// the new level and true when the rule matches.
+type EscalationRule func(entry LogEntry) (level string, escalate bool)
+
+// WithEscalationRule registers a rule that can raise an entry's level
+// after extraction, for example to ERROR for status_code >= 500. Rules run
+// in registration order and the first match wins; a match never lowers the
+// level. The level before escalation is kept in Fields["_original_level"].
+func WithEscalationRule(rule EscalationRule) Option {
+	return func(c *config) {
+		c.escalations = append(c.escalations, rule)
+	}
+}
+
+// FieldAtLeast returns a rule that escalates to level when the field at
+// key, which may be a dotted path, is a number at least threshold. Numeric
+// strings such as "503" count as numbers.
+func FieldAtLeast(key string, threshold float64, level string) EscalationRule {
+	level = ParseLevel(level)
+
+	return func(entry LogEntry) (string, bool) {
+		val, ok := entryField(&entry, key)
+		if !ok {
+			return "", false
+		}
+
+		n, ok := numericValue(val)
+
+		return level, ok && n >= threshold
+	}
+}
+
+// MessageContains returns a rule that escalates to level when the message
+// contains substr, ignoring case
+func MessageContains(substr, level string) EscalationRule {
+	level = ParseLevel(level)
+	substr = strings.ToLower(substr)
+
+	return func(entry LogEntry) (string, bool) {
+		return level, strings.Contains(strings.ToLower(entry.Message), substr)
+	}
+}
+
+// escalate applies the first matching escalation rule
+func (c *config) escalate(entry *LogEntry) {
+	for _, rule := range c.escalations {
+		level, ok := rule(*entry)
+		if !ok {
+			continue
+		}
+
+		if std, ok := lookupLevel(level); ok {
+			level = std
+		}
+
+		if levelRank(level) > levelRank(entry.Level) {
+			c.setField(entry, "_original_level", entry.Level)
+			entry.Level = level
+		}
+
+		return
+	}
+}
+
+// numericValue returns val as a float64 if it is a number or a string
+// holding one
+func numericValue(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,112 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscalationRules(t *testing.T) {
+	input := strings.Join([]string{
+		`{"level":"info","msg":"GET /","status_code":200}`,
+		`{"level":"info","msg":"GET /pay","status_code":503}`,
+		`level=info msg="GET /pay" status_code=502`,
+		`level=info msg="GET /pay" status_code=" 500 "`,
+		`level=info msg=retrying retry_count=4`,
+		`level=info msg=retrying retry_count=three`,
+		`{"level":"info","msg":"Deadlock detected"}`,
+		`{"level":"fatal","msg":"deadlock","status_code":500}`,
+		`{"level":"debug","msg":"nested","http":{"status":"504"}}`,
+	}, "\n")
+
+	parser := New(
+		WithEscalationRule(FieldAtLeast("status_code", 500, "error")),
+		WithEscalationRule(FieldAtLeast("retry_count", 4, "WARN")),
+		WithEscalationRule(MessageContains("DEADLOCK", LevelError)),
+		WithEscalationRule(FieldAtLeast("http.status", 500, "warning")),
+		WithBlockDetection(),
+	)
+
+	entries, err := parser.ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	want := []struct {
+		level    string
+		original interface{}
+	}{
+		{LevelInfo, nil},
+		{LevelError, LevelInfo},
+		{LevelError, LevelInfo},
+		{LevelError, LevelInfo},
+		{"WARN", LevelInfo},
+		{LevelInfo, nil},
+		{LevelError, LevelInfo},
+		{"FATAL", nil}, // Never lowered
+		{"WARN", "DEBUG"},
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for i, w := range want {
+		if entries[i].Level != w.level || entries[i].Fields["_original_level"] != w.original {
+			t.Errorf("entry %d: level %s (was %v), want %s (was %v)",
+				i, entries[i].Level, entries[i].Fields["_original_level"], w.level, w.original)
+		}
+	}
+}
+
+func TestEscalationFirstMatchWins(t *testing.T) {
+	var calls int
+
+	parser := New(
+		WithEscalationRule(func(e LogEntry) (string, bool) {
+			calls++
+
+			return "warn", e.Fields["slow"] == "true"
+		}),
+		WithEscalationRule(func(LogEntry) (string, bool) {
+			calls++
+
+			return LevelError, true
+		}),
+	)
+
+	entries, err := parser.ParseString("level=info msg=a slow=true\nlevel=info msg=b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entries[0].Level != "WARN" || entries[1].Level != LevelError {
+		t.Errorf("levels = %s, %s; want WARN, ERROR", entries[0].Level, entries[1].Level)
+	}
+
+	if calls != 3 {
+		t.Errorf("rules ran %d times, want 3", calls)
+	}
+}
+
+func TestNumericValue(t *testing.T) {
+	tests := []struct {
+		val  interface{}
+		want float64
+		ok   bool
+	}{
+		{500.0, 500, true},
+		{7, 7, true},
+		{int64(8), 8, true},
+		{"503", 503, true},
+		{" 1.5e2 ", 150, true},
+		{"5xx", 0, false},
+		{true, 0, false},
+		{nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := numericValue(tt.val); got != tt.want || ok != tt.ok {
+			t.Errorf("numericValue(%#v) = %v, %v; want %v, %v", tt.val, got, ok, tt.want, tt.ok)
+		}
+	}
+}
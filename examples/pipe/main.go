@@ -0,0 +1,50 @@
+// Command pipe runs a command and prints its log output as aligned text,
+// with errors in red.
+//
+//	go run ./examples/pipe -- sh -c 'echo "level=error msg=boom"; echo "level=info msg=ok"'
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/yildizm/go-logparser"
+)
+
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: pipe [--] <command> [args...]")
+		os.Exit(2)
+	}
+
+	formatter := logparser.Formatter{Color: true}
+
+	// The callback runs inside Write, so a slow terminal slows the command
+	// down instead of buffering its output
+	w := logparser.NewWriter(logparser.New(logparser.WithSkipInvalid(true)), func(e logparser.LogEntry) {
+		if err := logparser.WriteText(os.Stdout, []logparser.LogEntry{e}, formatter); err != nil {
+			log.Fatal(err)
+		}
+	})
+
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec // the command is the user's
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	runErr := cmd.Run()
+
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	if runErr != nil {
+		log.Fatal(runErr)
+	}
+}
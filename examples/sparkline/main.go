@@ -0,0 +1,37 @@
+// Command sparkline prints per-minute error counts and the level mix of a
+// log file.
+//
+//	go run ./examples/sparkline app.log
+//	go run ./examples/sparkline -ascii app.log
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/yildizm/go-logparser"
+)
+
+func main() {
+	ascii := flag.Bool("ascii", false, "draw with ASCII characters only")
+	bucket := flag.Duration("bucket", time.Minute, "sparkline bucket size")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: sparkline [-ascii] [-bucket d] <logfile>")
+		os.Exit(2)
+	}
+
+	entries, err := logparser.ParseFile(logparser.New(logparser.WithSkipInvalid(true)), flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := []logparser.RenderOption{logparser.WithASCII(*ascii)}
+
+	fmt.Printf("errors |%s|\n", logparser.RenderSparkline(entries, *bucket, logparser.LevelError, opts...))
+	fmt.Printf("levels %s\n", logparser.RenderLevelBar(entries, 40, opts...))
+}
@@ -0,0 +1,26 @@
+// Command summary prints a triage report for a log file.
+//
+//	go run ./examples/summary app.log
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/yildizm/go-logparser"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: summary <logfile>")
+		os.Exit(2)
+	}
+
+	entries, err := logparser.ParseFile(logparser.New(logparser.WithSkipInvalid(true)), os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Print(logparser.Summarize(entries, logparser.SummaryOptions{}))
+}
@@ -0,0 +1,418 @@
+package logparser
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultSortRunSize is the number of entries SortFiles holds in memory
+// unless WithSortRunSize says otherwise
+const DefaultSortRunSize = 100000
+
+// maxMergeFanIn bounds the spill files merged, and so held open, at once
+const maxMergeFanIn = 64
+
+// SortOption configures SortFiles
+type SortOption func(*sortConfig)
+
+// sortConfig holds external sort settings
+type sortConfig struct {
+	parser  Parser
+	runSize int
+	tempDir string
+	format  Format
+}
+
+// WithSortParser parses the input files with p instead of New()
+func WithSortParser(p Parser) SortOption {
+	return func(c *sortConfig) {
+		c.parser = p
+	}
+}
+
+// WithSortRunSize sets the number of entries held in memory before they
+// are sorted and spilled to a temporary file; n of zero or less means
+// DefaultSortRunSize
+func WithSortRunSize(n int) SortOption {
+	return func(c *sortConfig) {
+		c.runSize = n
+	}
+}
+
+// WithSortTempDir creates the spill files in dir instead of the default
+// directory for temporary files
+func WithSortTempDir(dir string) SortOption {
+	return func(c *sortConfig) {
+		c.tempDir = dir
+	}
+}
+
+// WithSortFormat writes the sorted entries as FormatJSON (the default) or
+// FormatLogfmt lines
+func WithSortFormat(format Format) SortOption {
+	return func(c *sortConfig) {
+		c.format = format
+	}
+}
+
+// SortFiles merges the log files at paths into out in timestamp order,
+// using a bounded amount of memory however large the input is. Each file
+// is parsed as a stream; every WithSortRunSize entries are sorted and
+// spilled to a temporary file as JSON, and the spill files are then merged
+// into out, 64 at a time, with a pass over the merged files when there are
+// more. Entries are written one per line as ReplayTo writes them. The sort
+// is stable: entries with equal timestamps keep their order in the input,
+// files in the order given, and entries without a timestamp come first.
+// Field values are written as the JSON encoder writes them. Input must be
+// UTF-8. The spill files are removed before SortFiles returns.
+func SortFiles(paths []string, out io.Writer, opts ...SortOption) error {
+	cfg := sortConfig{format: FormatJSON}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.parser == nil {
+		cfg.parser = New()
+	}
+
+	if cfg.runSize <= 0 {
+		cfg.runSize = DefaultSortRunSize
+	}
+
+	if _, err := appendEntryLine(nil, &LogEntry{}, cfg.format); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp(cfg.tempDir, "logparser-sort-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	s := &externalSort{cfg: &cfg, dir: dir, run: make([]LogEntry, 0, min(cfg.runSize, DefaultSortRunSize))}
+
+	for _, path := range paths {
+		if err := s.readFile(path); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	bw := bufio.NewWriter(out)
+
+	// Input that fits in one run is never spilled
+	if len(s.spills) == 0 {
+		sortRun(s.run)
+
+		for i := range s.run {
+			if err := s.writeEntry(bw, &s.run[i]); err != nil {
+				return err
+			}
+		}
+
+		return bw.Flush()
+	}
+
+	if err := s.spill(); err != nil {
+		return err
+	}
+
+	for len(s.spills) > maxMergeFanIn {
+		if err := s.mergePass(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.merge(s.spills, func(e *LogEntry) error { return s.writeEntry(bw, e) }); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// externalSort holds the state of a SortFiles call
+type externalSort struct {
+	cfg    *sortConfig
+	dir    string
+	run    []LogEntry // Entries not yet spilled
+	spills []string   // Spill files in input order, each sorted
+	next   int        // Number of the next spill file
+	err    error      // First spill error, which stops reading
+	line   []byte
+}
+
+// readFile parses the file at path into runs
+func (s *externalSort) readFile(path string) error {
+	file, err := os.Open(path) //nolint:gosec // path is supplied by the caller
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	add := func(e LogEntry) {
+		if s.err != nil {
+			return
+		}
+
+		s.run = append(s.run, e)
+		if len(s.run) >= s.cfg.runSize {
+			s.err = s.spill()
+		}
+	}
+
+	var w io.WriteCloser
+
+	if p, ok := s.cfg.parser.(*parser); ok {
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		name := p.cfg.sourceName
+		if name == "" {
+			name = path
+		}
+
+		w = p.newWriter(runInput{name: name, size: info.Size(), modTime: info.ModTime()}, add)
+	} else {
+		w = NewWriter(s.cfg.parser, add)
+	}
+
+	_, err = io.Copy(w, &stopReader{r: file, err: &s.err})
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = s.err
+	}
+
+	return err
+}
+
+// stopReader reads from r until *err is set
+type stopReader struct {
+	r   io.Reader
+	err *error
+}
+
+func (r *stopReader) Read(p []byte) (int, error) {
+	if *r.err != nil {
+		return 0, *r.err
+	}
+
+	return r.r.Read(p)
+}
+
+// sortRun sorts entries by timestamp, keeping the order of equal ones
+func sortRun(entries []LogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+}
+
+// spill sorts the entries in memory and writes them to a new spill file
+func (s *externalSort) spill() error {
+	if len(s.run) == 0 {
+		return nil
+	}
+
+	sortRun(s.run)
+
+	err := s.writeSpill(func(enc *json.Encoder) error {
+		for i := range s.run {
+			if err := enc.Encode(&s.run[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	clear(s.run)
+	s.run = s.run[:0]
+
+	return err
+}
+
+// writeSpill creates a spill file, writes it with fill, and appends it to
+// the spill files
+func (s *externalSort) writeSpill(fill func(enc *json.Encoder) error) error {
+	path := filepath.Join(s.dir, fmt.Sprintf("run-%06d.json", s.next))
+	s.next++
+
+	file, err := os.Create(path) //nolint:gosec // path is in a directory created by SortFiles
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(file)
+	err = fill(json.NewEncoder(bw))
+
+	if err == nil {
+		err = bw.Flush()
+	}
+
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.spills = append(s.spills, path)
+
+	return nil
+}
+
+// mergePass merges the spill files in groups of maxMergeFanIn, replacing
+// each group with one larger spill file
+func (s *externalSort) mergePass() error {
+	spills := s.spills
+	s.spills = nil
+
+	for start := 0; start < len(spills); start += maxMergeFanIn {
+		group := spills[start:min(start+maxMergeFanIn, len(spills))]
+
+		err := s.writeSpill(func(enc *json.Encoder) error {
+			return s.merge(group, func(e *LogEntry) error { return enc.Encode(e) })
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, path := range group {
+			os.Remove(path)
+		}
+	}
+
+	return nil
+}
+
+// merge calls emit with the entries of the spill files in timestamp order,
+// taking equal timestamps from earlier files first
+func (s *externalSort) merge(paths []string, emit func(*LogEntry) error) error {
+	h := make(spillHeap, 0, len(paths))
+
+	defer func() {
+		for _, r := range h {
+			r.file.Close()
+		}
+	}()
+
+	for i, path := range paths {
+		file, err := os.Open(path) //nolint:gosec // path is in a directory created by SortFiles
+		if err != nil {
+			return err
+		}
+
+		dec := json.NewDecoder(bufio.NewReader(file))
+		dec.UseNumber()
+
+		r := &spillReader{file: file, dec: dec, index: i}
+
+		ok, err := r.advance()
+		if !ok {
+			file.Close()
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		h = append(h, r)
+	}
+
+	heap.Init(&h)
+
+	for len(h) > 0 {
+		r := h[0]
+		if err := emit(&r.entry); err != nil {
+			return err
+		}
+
+		ok, err := r.advance()
+
+		switch {
+		case err != nil:
+			return err
+		case ok:
+			heap.Fix(&h, 0)
+		default:
+			r.file.Close()
+			heap.Pop(&h)
+		}
+	}
+
+	return nil
+}
+
+// writeEntry writes an entry to the output as a line
+func (s *externalSort) writeEntry(w *bufio.Writer, e *LogEntry) error {
+	var err error
+
+	s.line, err = appendEntryLine(s.line[:0], e, s.cfg.format)
+	if err != nil {
+		return err
+	}
+
+	s.line = append(s.line, '\n')
+	_, err = w.Write(s.line)
+
+	return err
+}
+
+// spillReader reads the entries of a spill file
+type spillReader struct {
+	file  *os.File
+	dec   *json.Decoder
+	index int // Position among the merged files, breaking timestamp ties
+	entry LogEntry
+}
+
+// advance reads the next entry, reporting false at the end of the file
+func (r *spillReader) advance() (bool, error) {
+	r.entry = LogEntry{}
+
+	err := r.dec.Decode(&r.entry)
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// spillHeap orders spill readers by their current entry
+type spillHeap []*spillReader
+
+func (h spillHeap) Len() int { return len(h) }
+
+func (h spillHeap) Less(i, j int) bool {
+	a, b := h[i].entry.Timestamp, h[j].entry.Timestamp
+	if a.Equal(b) {
+		return h[i].index < h[j].index
+	}
+
+	return a.Before(b)
+}
+
+func (h spillHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *spillHeap) Push(x interface{}) { *h = append(*h, x.(*spillReader)) }
+
+func (h *spillHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return r
+}
@@ -0,0 +1,168 @@
+package logparser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeSortInputs writes n files of lines entries each, with timestamps
+// interleaved across files and a tie in every file, and returns their
+// paths and the messages written
+func writeSortInputs(t *testing.T, n, lines int) ([]string, []string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	var (
+		paths    []string
+		messages []string
+	)
+
+	for f := range n {
+		var b strings.Builder
+
+		for i := range lines {
+			// Descending within each file, so every run needs sorting
+			ts := base.Add(time.Duration((lines-i)*n+f) * time.Second)
+			if i == lines-1 {
+				ts = base // Tie across files
+			}
+
+			msg := fmt.Sprintf("file %d line %d", f, i)
+			fmt.Fprintf(&b, `{"time":%q,"level":"info","msg":%q,"seq":%d}`+"\n", ts.Format(time.RFC3339), msg, i)
+			messages = append(messages, msg)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("app-%d.log", f))
+		if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, messages
+}
+
+// checkSorted parses the SortFiles output and checks that it is in
+// timestamp order and holds exactly the messages written
+func checkSorted(t *testing.T, out []byte, messages []string) []LogEntry {
+	t.Helper()
+
+	entries, err := NewWithFormat(FormatJSON).ParseString(string(out))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	got := make([]string, len(entries))
+
+	for i, e := range entries {
+		got[i] = e.Message
+
+		if i > 0 && e.Timestamp.Before(entries[i-1].Timestamp) {
+			t.Fatalf("entry %d at %v before entry %d at %v", i, e.Timestamp, i-1, entries[i-1].Timestamp)
+		}
+	}
+
+	want := append([]string(nil), messages...)
+	sort.Strings(got)
+	sort.Strings(want)
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got %d entries, want %d, with entries lost or duplicated", len(got), len(want))
+	}
+
+	return entries
+}
+
+func TestSortFiles(t *testing.T) {
+	paths, messages := writeSortInputs(t, 3, 10)
+	tmp := t.TempDir()
+
+	var out bytes.Buffer
+	if err := SortFiles(paths, &out, WithSortRunSize(4), WithSortTempDir(tmp)); err != nil {
+		t.Fatalf("SortFiles() error = %v", err)
+	}
+
+	entries := checkSorted(t, out.Bytes(), messages)
+
+	// Ties keep the order of the files
+	for i, want := range []string{"file 0 line 9", "file 1 line 9", "file 2 line 9"} {
+		if entries[i].Message != want {
+			t.Errorf("entry %d = %q, want %q", i, entries[i].Message, want)
+		}
+	}
+
+	if entries[0].Fields["seq"] != float64(9) {
+		t.Errorf("Fields = %v, want seq kept", entries[0].Fields)
+	}
+
+	if left, _ := os.ReadDir(tmp); len(left) != 0 {
+		t.Errorf("spill files left behind: %v", left)
+	}
+}
+
+func TestSortFilesMergePasses(t *testing.T) {
+	// More runs than are merged at once
+	paths, messages := writeSortInputs(t, 4, 2*maxMergeFanIn)
+
+	var out bytes.Buffer
+	if err := SortFiles(paths, &out, WithSortRunSize(3)); err != nil {
+		t.Fatalf("SortFiles() error = %v", err)
+	}
+
+	checkSorted(t, out.Bytes(), messages)
+}
+
+func TestSortFilesInMemory(t *testing.T) {
+	paths, messages := writeSortInputs(t, 2, 5)
+	tmp := t.TempDir()
+
+	var out bytes.Buffer
+	if err := SortFiles(paths, &out, WithSortTempDir(tmp), WithSortFormat(FormatLogfmt)); err != nil {
+		t.Fatalf("SortFiles() error = %v", err)
+	}
+
+	entries, err := NewWithFormat(FormatLogfmt).ParseString(out.String())
+	if err != nil || len(entries) != len(messages) {
+		t.Fatalf("got %d logfmt entries, %v; want %d", len(entries), err, len(messages))
+	}
+
+	if entries[0].Message != "file 0 line 4" {
+		t.Errorf("first entry = %q", entries[0].Message)
+	}
+}
+
+func TestSortFilesErrors(t *testing.T) {
+	paths, _ := writeSortInputs(t, 1, 3)
+
+	var out bytes.Buffer
+
+	err := SortFiles(append(paths, filepath.Join(t.TempDir(), "missing.log")), &out)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("SortFiles() error = %v, want the missing file reported", err)
+	}
+
+	if err := SortFiles(paths, &out, WithSortFormat(FormatText)); err == nil {
+		t.Error("SortFiles() accepted text output")
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.log")
+	if err := os.WriteFile(bad, []byte("{\"msg\":\"a\"}\n{broken\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err = SortFiles([]string{bad}, &out, WithSortParser(NewWithFormat(FormatJSON)))
+	if err == nil || !strings.Contains(err.Error(), bad) {
+		t.Errorf("SortFiles() error = %v, want the parse error with the path", err)
+	}
+}
@@ -0,0 +1,66 @@
+package logparser
+
+// WithFallbackFormats retries a line that fails to parse in the selected or
+// detected format with each of formats in turn, such as the startup banner
+// or panic output in an otherwise JSON log, instead of failing or skipping
+// it. The first format that parses the line wins; its entries get
+// Fields["_format"] naming it and are counted in Stats.FallbackLines. A line
+// no format parses fails as before. Only line formats may be given, not
+// FormatAuto or FormatWindowsEventXML; as text parsing never fails,
+// FormatText ends a chain. Lines that parse are never retried, so clean
+// input costs nothing. Without this option, parsing is strict. LineParser
+// ignores this option.
+func WithFallbackFormats(formats ...Format) Option {
+	return func(c *config) {
+		c.fallbacks = append(c.fallbacks, formats...)
+	}
+}
+
+// validFallbacks reports whether every fallback format is parsed by line
+func validFallbacks(formats []Format) bool {
+	for _, format := range formats {
+		switch format {
+		case FormatJSON, FormatLogfmt, FormatText, FormatALB, FormatHAProxy, FormatSquid:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseFallback retries a line that failed with err in the fallback
+// formats, returning the first result that parses, or entries and err if
+// none does
+func (r *parseRun) parseFallback(line string, entries []*LogEntry, err error) ([]*LogEntry, error) {
+	for _, format := range r.cfg.fallbacks {
+		if format == r.format {
+			continue
+		}
+
+		parse, ok := r.fallback[format]
+		if !ok {
+			if r.fallback == nil {
+				r.fallback = make(map[Format]lineParseFunc, len(r.cfg.fallbacks))
+			}
+
+			parse = lineParserFor(format, r.cfg)
+			r.fallback[format] = parse
+		}
+
+		recovered, ferr := parse(line)
+		if ferr != nil || len(recovered) == 0 {
+			continue
+		}
+
+		r.stats.FallbackLines++
+
+		for _, entry := range recovered {
+			r.cfg.setField(entry, "_format", format.String())
+		}
+
+		return recovered, nil
+	}
+
+	return entries, err
+}
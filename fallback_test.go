@@ -0,0 +1,90 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+)
+
+const fallbackInput = `{"level":"info","msg":"starting"}
+{"level":"info","msg":"listening"}
+=== server v1.2 ===
+{"level":"error","msg":"request failed"}
+panic: runtime error: index out of range
+{"level":"info","msg":"stopped"}
+`
+
+func TestFallbackFormatsDisabledByDefault(t *testing.T) {
+	if _, err := New().ParseString(fallbackInput); err == nil {
+		t.Error("ParseString() succeeded without fallback formats, want the JSON error")
+	}
+}
+
+func TestWithFallbackFormats(t *testing.T) {
+	entries, stats, err := ParseWithStats(New(WithFallbackFormats(FormatText)), strings.NewReader(fallbackInput))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	wantMessages := []string{
+		"starting", "listening", "=== server v1.2 ===", "request failed",
+		"panic: runtime error: index out of range", "stopped",
+	}
+
+	if len(entries) != len(wantMessages) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(wantMessages))
+	}
+
+	for i, want := range wantMessages {
+		if entries[i].Message != want {
+			t.Errorf("entry %d: message %q, want %q", i, entries[i].Message, want)
+		}
+
+		_, fellBack := entries[i].Fields["_format"]
+		if wantFallback := i == 2 || i == 4; fellBack != wantFallback {
+			t.Errorf("entry %d: _format set = %v, want %v", i, fellBack, wantFallback)
+		}
+	}
+
+	if got := entries[2].Fields["_format"]; got != "text" {
+		t.Errorf("_format = %v, want text", got)
+	}
+
+	if stats.FallbackLines != 2 || stats.LinesSkipped != 0 {
+		t.Errorf("FallbackLines = %d, LinesSkipped = %d; want 2 and 0", stats.FallbackLines, stats.LinesSkipped)
+	}
+}
+
+func TestFallbackFormatsOrder(t *testing.T) {
+	input := "{\"level\":\"info\",\"msg\":\"a\"}\nlevel=warn msg=b\n"
+
+	entries, err := NewWithFormat(FormatJSON, WithFallbackFormats(FormatLogfmt, FormatText)).ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if e := entries[1]; e.Level != "WARN" || e.Message != "b" || e.Fields["_format"] != "logfmt" {
+		t.Errorf("entry = %+v, want the logfmt parse", e)
+	}
+
+	// The selected format is not retried, and a chain that never parses
+	// the line fails as before
+	_, err = NewWithFormat(FormatJSON, WithFallbackFormats(FormatJSON, FormatALB)).ParseString(input)
+	if err == nil {
+		t.Error("ParseString() succeeded, want the JSON error")
+	}
+}
+
+func TestFallbackFormatsKeepPartialLines(t *testing.T) {
+	input := "{\"level\":\"info\",\"msg\":\"a\"}\nbanner\n{\"level\":\"info\",\"msg\":\"b\",\"us"
+
+	p := NewWithFormat(FormatJSON, WithFallbackFormats(FormatText), WithPartialLines(PartialDrop))
+	entries, stats, err := ParseWithStats(p, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	if len(entries) != 2 || entries[1].Message != "banner" || stats.PartialLines != 1 {
+		t.Errorf("got %d entries and %d partial lines, want the banner kept and the cut-off line dropped",
+			len(entries), stats.PartialLines)
+	}
+}
@@ -0,0 +1,137 @@
+package logparser
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// HashTokenPrefix starts the tokens that WithFieldHashing puts in place of
+// hashed values
+const HashTokenPrefix = "h:"
+
+// hashTokenBytes is the number of HMAC bytes kept in a token, written as
+// twice as many hex digits
+const hashTokenBytes = 8
+
+// fieldHashing holds the settings of WithFieldHashing and
+// WithMessageHashing
+type fieldHashing struct {
+	keys     []string
+	secret   []byte
+	patterns []*regexp.Regexp
+}
+
+// WithFieldHashing replaces the values of the listed fields with tokens
+// derived from them by HashValue, so entries about the same user or
+// address can still be correlated without the value being kept: the same
+// value gives the same token in every run with the same secret, and
+// without the secret a token cannot be traced back to its value, unlike
+// a plain hash of a guessable ID. Dotted keys select values inside nested
+// objects. Fields are hashed after enrichment, so lookup tables see the
+// original values, and before transforms. May be given more than once;
+// the last secret applies to all keys and WithMessageHashing patterns. An
+// empty secret makes NewE fail.
+func WithFieldHashing(keys []string, secret []byte) Option {
+	return func(c *config) {
+		c.hashing.keys = append(c.hashing.keys, keys...)
+		c.hashing.secret = secret
+	}
+}
+
+// WithMessageHashing replaces matches of the patterns in LogEntry.Message
+// with tokens, as WithFieldHashing does for field values, so an ID written
+// into the message text correlates with the same ID in a hashed field.
+// When a pattern has a capture group, only the first group is replaced, so
+// `user=(\w+)` keeps the "user=". The secret is the one given to
+// WithFieldHashing; without one, NewE fails and New leaves messages as
+// they are.
+func WithMessageHashing(patterns ...*regexp.Regexp) Option {
+	return func(c *config) {
+		c.hashing.patterns = append(c.hashing.patterns, patterns...)
+	}
+}
+
+// HashValue returns the token WithFieldHashing puts in place of a value:
+// HashTokenPrefix followed by the first 8 bytes of the HMAC-SHA256 of the
+// value under secret, in hex, such as "h:aff3e2227d2581ae". Strings are
+// hashed as they are and other values JSON-encoded, so an ID logged as 42
+// and as "42" gives the same token. Use it to find the token of a known
+// value, such as a user ID to look up.
+func HashValue(secret []byte, val interface{}) string {
+	var data []byte
+
+	switch v := val.(type) {
+	case string:
+		data = []byte(v)
+	case json.Number:
+		data = []byte(v)
+	default:
+		data, _ = json.Marshal(v) // Parsed values always encode
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+
+	return HashTokenPrefix + hex.EncodeToString(mac.Sum(nil)[:hashTokenBytes])
+}
+
+// validate reports hashing settings without a secret
+func (h *fieldHashing) validate() error {
+	if (len(h.keys) > 0 || len(h.patterns) > 0) && len(h.secret) == 0 {
+		return fmt.Errorf("%w: field hashing without a secret", ErrInvalidOptions)
+	}
+
+	return nil
+}
+
+// hashFields replaces the values of the hashed fields, and the matches of
+// the message patterns, with their tokens
+func (c *config) hashFields(entry *LogEntry) {
+	h := &c.hashing
+	if len(h.secret) == 0 {
+		return
+	}
+
+	for _, key := range h.keys {
+		replaceField(entry.Fields, key, func(val interface{}) interface{} {
+			return HashValue(h.secret, val)
+		})
+	}
+
+	for _, re := range h.patterns {
+		entry.Message = h.hashMatches(re, entry.Message)
+	}
+}
+
+// hashMatches replaces the matches of re in s, or their first capture
+// groups, with their tokens
+func (h *fieldHashing) hashMatches(re *regexp.Regexp, s string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	out := make([]byte, 0, len(s))
+	last := 0
+
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) > 2 {
+			start, end = m[2], m[3]
+		}
+
+		if start < 0 || start == end {
+			continue // Group did not take part in the match
+		}
+
+		out = append(out, s[last:start]...)
+		out = append(out, HashValue(h.secret, s[start:end])...)
+		last = end
+	}
+
+	return string(append(out, s[last:]...))
+}
@@ -0,0 +1,119 @@
+package logparser
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// hashingLog has the same users logged under different shapes: as string
+// and number IDs, and inside the message
+const hashingLog = `{"level":"info","message":"login user=alice","user":{"id":"u-1"},"ip":"10.0.0.1"}
+{"level":"info","message":"login user=bob","user":{"id":"u-2"},"ip":"10.0.0.2"}
+{"level":"warn","message":"retry user=alice","user":{"id":"u-1"},"ip":"10.0.0.1"}
+{"level":"info","message":"order placed","user":{"id":42},"ip":"10.0.0.3"}
+{"level":"info","message":"order shipped","user":{"id":"42"},"ip":"10.0.0.3"}
+`
+
+func hashedEntries(t *testing.T, secret string) []LogEntry {
+	t.Helper()
+
+	parser, err := NewE(
+		WithFieldHashing([]string{"user.id", "ip"}, []byte(secret)),
+		WithMessageHashing(regexp.MustCompile(`user=(\w+)`)),
+	)
+	if err != nil {
+		t.Fatalf("NewE() error = %v", err)
+	}
+
+	entries, err := parser.ParseString(hashingLog)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	return entries
+}
+
+func TestFieldHashingDeterministic(t *testing.T) {
+	entries := hashedEntries(t, "k1")
+
+	// Tokens are fixed for a secret, across runs and releases
+	const aliceToken = "h:aff3e2227d2581ae"
+	if got := HashValue([]byte("k1"), "alice"); got != aliceToken {
+		t.Errorf("HashValue(alice) = %q, want %q", got, aliceToken)
+	}
+
+	if want := "login user=" + aliceToken; entries[0].Message != want {
+		t.Errorf("message = %q, want %q", entries[0].Message, want)
+	}
+
+	user := entries[0].Fields["user"].(map[string]interface{})
+	if id := user["id"]; id != HashValue([]byte("k1"), "u-1") {
+		t.Errorf("user.id = %v", id)
+	}
+
+	if ip := entries[0].Fields["ip"].(string); !strings.HasPrefix(ip, HashTokenPrefix) || len(ip) != len(aliceToken) {
+		t.Errorf("ip = %q", ip)
+	}
+
+	again := hashedEntries(t, "k1")
+	for i := range entries {
+		if diff := DiffEntries(entries[i], again[i], WithIgnoreTimestamp(true)); diff != "" {
+			t.Errorf("entry %d differs between runs: %s", i, diff)
+		}
+	}
+
+	// Numbers are JSON-encoded, so 42 and "42" correlate
+	if a, b := entries[3].Fields["user"], entries[4].Fields["user"]; a.(map[string]interface{})["id"] != b.(map[string]interface{})["id"] {
+		t.Errorf("user ids 42 and \"42\" hash differently: %v, %v", a, b)
+	}
+}
+
+func TestFieldHashingSecrets(t *testing.T) {
+	one, two := hashedEntries(t, "k1"), hashedEntries(t, "k2")
+
+	for i := range one {
+		if one[i].Fields["ip"] == two[i].Fields["ip"] {
+			t.Errorf("entry %d: ip token %v is the same under both secrets", i, one[i].Fields["ip"])
+		}
+	}
+
+	if HashValue([]byte("k1"), map[string]interface{}{"a": 1.0}) == HashValue([]byte("k2"), map[string]interface{}{"a": 1.0}) {
+		t.Error("object token is the same under both secrets")
+	}
+}
+
+func TestFieldHashingCorrelates(t *testing.T) {
+	entries := hashedEntries(t, "k1")
+
+	// There is no Correlate helper; grouping by the hashed ID with a
+	// Splitter shows the entries of one user still group together
+	split := NewSplitter(SplitByField("user.id"), func(string, LogEntry) error { return nil })
+	for _, e := range entries {
+		if err := split.Add(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counts := split.Counts()
+	if len(counts) != 3 {
+		t.Errorf("got %d users, want 3: %v", len(counts), counts)
+	}
+
+	for id, want := range map[interface{}]int{"u-1": 2, "u-2": 1, 42: 2} {
+		if got := counts[HashValue([]byte("k1"), id)]; got != want {
+			t.Errorf("user %v: %d entries, want %d", id, got, want)
+		}
+	}
+}
+
+func TestFieldHashingWithoutSecret(t *testing.T) {
+	if _, err := NewE(WithFieldHashing([]string{"user"}, nil)); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("NewE() error = %v, want ErrInvalidOptions", err)
+	}
+
+	if _, err := NewE(WithMessageHashing(regexp.MustCompile(`\d+`))); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("NewE() error = %v, want ErrInvalidOptions", err)
+	}
+}
@@ -0,0 +1,134 @@
+package logparser
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lookupField returns the value at a field path. A literal key is tried
+// first; otherwise dotted segments descend into nested objects.
+func lookupField(fields map[string]interface{}, path string) (interface{}, bool) {
+	if val, ok := fields[path]; ok {
+		return val, true
+	}
+
+	current := fields
+
+	for {
+		head, rest, found := strings.Cut(path, ".")
+
+		val, ok := current[head]
+		if !ok {
+			return nil, false
+		}
+
+		if !found {
+			return val, true
+		}
+
+		nested, isMap := val.(map[string]interface{})
+		if !isMap {
+			return nil, false
+		}
+
+		if val, ok := nested[rest]; ok {
+			return val, true
+		}
+
+		current = nested
+		path = rest
+	}
+}
+
+// formatValue renders a field value as text. Durations render as Go
+// duration strings ("1.5s"), objects and arrays are JSON encoded, and nil
+// renders as an empty string.
+func formatValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case time.Duration:
+		return v.String()
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+
+		return string(data)
+	}
+}
+
+// replaceField replaces the value at a field path, as found by
+// lookupField, with the result of fn, and reports whether it was found
+func replaceField(fields map[string]interface{}, path string, fn func(interface{}) interface{}) bool {
+	if val, ok := fields[path]; ok {
+		fields[path] = fn(val)
+
+		return true
+	}
+
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return false
+	}
+
+	nested, isMap := fields[head].(map[string]interface{})
+	if !isMap {
+		return false
+	}
+
+	return replaceField(nested, rest, fn)
+}
+
+// deleteField removes the value at a field path, as found by lookupField,
+// along with the objects the removal leaves empty
+func deleteField(fields map[string]interface{}, path string) {
+	if _, ok := fields[path]; ok {
+		delete(fields, path)
+
+		return
+	}
+
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return
+	}
+
+	nested, isMap := fields[head].(map[string]interface{})
+	if !isMap {
+		return
+	}
+
+	if deleteField(nested, rest); len(nested) == 0 {
+		delete(fields, head)
+	}
+}
+
+// entryField returns the value at a field path of an entry, as lookupField
+// does, falling back to Service and Logger for the paths "service" and
+// "logger", so options naming those fields work once they are extracted
+func entryField(e *LogEntry, path string) (interface{}, bool) {
+	if val, ok := lookupField(e.Fields, path); ok {
+		return val, true
+	}
+
+	switch {
+	case path == "service" && e.Service != "":
+		return e.Service, true
+	case path == "logger" && e.Logger != "":
+		return e.Logger, true
+	}
+
+	return nil, false
+}
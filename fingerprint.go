@@ -0,0 +1,177 @@
+package logparser
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"time"
+)
+
+// FingerprintVersion prefixes every fingerprint. It changes whenever the
+// algorithm does, so stored fingerprints are never silently re-keyed: an
+// alerting system sees new fingerprints with a new prefix instead.
+const FingerprintVersion = "v1"
+
+// FingerprintOptions configures Fingerprint and AlertBatch
+type FingerprintOptions struct {
+	// Fields are top-level field keys, such as "service" or "environment",
+	// whose values also key the fingerprint. Order does not matter, and a
+	// missing field counts as a value of its own.
+	Fields []string
+}
+
+// Alert is a group of error entries sharing a fingerprint, ready to be
+// encoded as JSON and sent to an alerting system
+type Alert struct {
+	Fingerprint string                 `json:"fingerprint"`
+	Level       string                 `json:"level"` // Most severe level in the group
+	Template    string                 `json:"template"`
+	Signature   string                 `json:"signature,omitempty"`
+	Fields      map[string]interface{} `json:"fields,omitempty"` // Values of FingerprintOptions.Fields
+	Count       int                    `json:"count"`
+	FirstSeen   time.Time              `json:"first_seen"`
+	LastSeen    time.Time              `json:"last_seen"`
+	Example     string                 `json:"example"` // Message of the first entry in input order
+}
+
+// Fingerprint returns a short, stable key for deduplicating alerts, such
+// as "v1:9c1f3e0a2b4d6e8f". Version 1 hashes, with 64-bit FNV-1a, the
+// message template (MessageTemplate), the innermost error signature
+// (ExtractErrorSignature of Fields["error"] or Fields["err"] if set, else
+// of the message), and the values of opts.Fields. Timestamps, levels, and
+// all other fields are left out, so repeats of one failure share a
+// fingerprint. The result is the same across runs, processes, and
+// architectures.
+func Fingerprint(entry LogEntry, opts FingerprintOptions) string {
+	return fingerprintOf(MessageTemplate(entry.Message), fingerprintSignature(entry), &entry, fingerprintKeys(opts.Fields))
+}
+
+// AlertBatch groups the ERROR and FATAL entries by Fingerprint with no
+// extra fields. Use FingerprintOptions.AlertBatch to key on fields.
+func AlertBatch(entries []LogEntry) []Alert {
+	return FingerprintOptions{}.AlertBatch(entries)
+}
+
+// AlertBatch groups the ERROR and FATAL entries by Fingerprint, counting
+// each group and recording when it was first and last seen. Alerts are
+// ordered by count, most frequent first, then by fingerprint.
+func (o FingerprintOptions) AlertBatch(entries []LogEntry) []Alert {
+	keys := fingerprintKeys(o.Fields)
+	groups := make(map[string]*Alert)
+
+	for i := range entries {
+		e := &entries[i]
+		if e.Level != LevelError && e.Level != "FATAL" {
+			continue
+		}
+
+		template := MessageTemplate(e.Message)
+		sig := fingerprintSignature(*e)
+		fp := fingerprintOf(template, sig, e, keys)
+
+		alert, ok := groups[fp]
+		if !ok {
+			alert = &Alert{
+				Fingerprint: fp,
+				Level:       e.Level,
+				Template:    template,
+				Signature:   sig,
+				Fields:      alertFields(e, keys),
+				FirstSeen:   e.Timestamp,
+				LastSeen:    e.Timestamp,
+				Example:     e.Message,
+			}
+			groups[fp] = alert
+		}
+
+		alert.Count++
+
+		if levelRank(e.Level) > levelRank(alert.Level) {
+			alert.Level = e.Level
+		}
+
+		if e.Timestamp.Before(alert.FirstSeen) {
+			alert.FirstSeen = e.Timestamp
+		}
+
+		if e.Timestamp.After(alert.LastSeen) {
+			alert.LastSeen = e.Timestamp
+		}
+	}
+
+	result := make([]Alert, 0, len(groups))
+	for _, alert := range groups {
+		result = append(result, *alert)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+
+		return result[i].Fingerprint < result[j].Fingerprint
+	})
+
+	return result
+}
+
+// fingerprintSignature returns the error signature of the entry's error
+// field, or of its message when it has none
+func fingerprintSignature(entry LogEntry) string {
+	for _, key := range []string{"error", "err"} {
+		if s, ok := entry.Fields[key].(string); ok && s != "" {
+			return ExtractErrorSignature(LogEntry{Message: s})
+		}
+	}
+
+	return ExtractErrorSignature(entry)
+}
+
+// fingerprintOf hashes the fingerprint components. The encoding is part
+// of FingerprintVersion: changing it requires a new version.
+func fingerprintOf(template, sig string, entry *LogEntry, keys []string) string {
+	h := fnv.New64a()
+
+	writeHashString(h, template)
+	writeHashString(h, sig)
+	writeHashTag(h, hashObject)
+	writeHashInt(h, int64(len(keys)))
+
+	for _, k := range keys {
+		writeHashString(h, k)
+
+		if v, ok := entryField(entry, k); ok {
+			writeHashValue(h, v)
+		} else {
+			writeHashTag(h, hashOther) // Distinct from a null value
+		}
+	}
+
+	return fmt.Sprintf("%s:%016x", FingerprintVersion, h.Sum64())
+}
+
+// alertFields returns the values of keys present in the entry
+func alertFields(entry *LogEntry, keys []string) map[string]interface{} {
+	var m map[string]interface{}
+
+	for _, k := range keys {
+		if v, ok := entryField(entry, k); ok {
+			if m == nil {
+				m = make(map[string]interface{}, len(keys))
+			}
+
+			m[k] = v
+		}
+	}
+
+	return m
+}
+
+// fingerprintKeys returns a sorted copy of keys without duplicates
+func fingerprintKeys(keys []string) []string {
+	sorted := slices.Clone(keys)
+	slices.Sort(sorted)
+
+	return slices.Compact(sorted)
+}
@@ -0,0 +1,110 @@
+package logparser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFingerprintGolden(t *testing.T) {
+	entries, err := ParseFile(New(), "testdata/alerts.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fixed values: a change here re-keys alerts stored by earlier
+	// versions, so only update them together with FingerprintVersion
+	want := []string{
+		"v1:83fcc815953217f3",
+		"v1:61b0302233350577",
+		"v1:83fcc815953217f3",
+		"v1:5c8c49a3b5f07ab0",
+		"v1:4a8b20adf9d75c08",
+		"v1:83fcc815953217f3",
+		"v1:53d1848c125e18d8",
+		"v1:53d1848c125e18d8",
+		"v1:39bf7b5e34c60c7a",
+		"v1:9be913aa78b4a013",
+	}
+
+	envs := FingerprintOptions{Fields: []string{"service", "env"}}
+
+	for i, e := range entries {
+		if got := Fingerprint(e, envs); got != want[i] {
+			t.Errorf("Fingerprint(entry %d) = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestFingerprintStability(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	base := LogEntry{Timestamp: ts, Level: LevelError, Message: "user 12 not found", Fields: map[string]interface{}{"service": "api"}}
+	opts := FingerprintOptions{Fields: []string{"service"}}
+
+	same := []LogEntry{
+		{Timestamp: ts.Add(time.Hour), Level: "FATAL", Message: "user 99 not found", Fields: map[string]interface{}{"service": "api"}},
+		{Timestamp: ts, Level: LevelError, Message: "user 12 not found", Fields: map[string]interface{}{"service": "api", "request_id": "r1"}},
+	}
+
+	for i, e := range same {
+		if Fingerprint(e, opts) != Fingerprint(base, opts) {
+			t.Errorf("entry %d fingerprints differently from the base entry", i)
+		}
+	}
+
+	different := []LogEntry{
+		{Timestamp: ts, Level: LevelError, Message: "user 12 deleted", Fields: map[string]interface{}{"service": "api"}},
+		{Timestamp: ts, Level: LevelError, Message: "user 12 not found", Fields: map[string]interface{}{"service": "web"}},
+		{Timestamp: ts, Level: LevelError, Message: "user 12 not found"},
+		{Timestamp: ts, Level: LevelError, Message: "user 12 not found", Fields: map[string]interface{}{"service": nil}},
+		{Timestamp: ts, Level: LevelError, Message: "user 12 not found", Fields: map[string]interface{}{"service": "api", "error": "EOF"}},
+	}
+
+	for i, e := range different {
+		if Fingerprint(e, opts) == Fingerprint(base, opts) {
+			t.Errorf("entry %d fingerprints like the base entry", i)
+		}
+	}
+
+	if got := Fingerprint(base, FingerprintOptions{Fields: []string{"service", "service"}}); got != Fingerprint(base, opts) {
+		t.Errorf("duplicate field keys changed the fingerprint to %s", got)
+	}
+
+	if got := Fingerprint(base, opts); !strings.HasPrefix(got, FingerprintVersion+":") || len(got) != len(FingerprintVersion)+17 {
+		t.Errorf("Fingerprint() = %q, want %s: and 16 hex digits", got, FingerprintVersion)
+	}
+}
+
+func TestAlertBatch(t *testing.T) {
+	entries, err := ParseFile(New(), "testdata/alerts.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := json.MarshalIndent(FingerprintOptions{Fields: []string{"env", "service"}}.AlertBatch(entries), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "alerts.json", append(got, '\n'))
+
+	alerts := AlertBatch(entries)
+
+	total := 0
+	for _, a := range alerts {
+		total += a.Count
+	}
+
+	if total != 8 {
+		t.Errorf("alerts cover %d entries, want the 8 ERROR and FATAL ones", total)
+	}
+
+	if len(alerts) == 0 || alerts[0].Count != 4 || alerts[0].Level != "FATAL" {
+		t.Fatalf("first alert = %+v, want the 4 refused payments at FATAL", alerts[0])
+	}
+
+	if !alerts[0].FirstSeen.Equal(entries[0].Timestamp) || !alerts[0].LastSeen.Equal(entries[5].Timestamp) {
+		t.Errorf("first alert seen %v to %v, want %v to %v", alerts[0].FirstSeen, alerts[0].LastSeen, entries[0].Timestamp, entries[5].Timestamp)
+	}
+}
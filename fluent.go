@@ -0,0 +1,109 @@
+package logparser
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// parseFluentLine parses the JSON form of Fluentd/Fluent Bit events:
+//
+//	["tag", time, {record}]                 single event (message mode)
+//	["tag", [[time, {record}], ...]]        batch (forward mode)
+//	[time, {record}]                        untagged event
+//	[[time, {metadata}], {record}]          Fluent Bit v2 event
+//
+// The record is mapped like a JSON line, the event time becomes the
+// Timestamp, and the tag is stored in Fields["tag"].
+func parseFluentLine(line string, cfg *config) ([]*LogEntry, error) {
+	var arr []interface{}
+	if err := json.Unmarshal([]byte(line), &arr); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	entries, ok := fluentEntries(arr, cfg)
+	if !ok {
+		return nil, &ParseError{Type: "fluent", Value: line, Err: "unrecognized Fluentd event array"}
+	}
+
+	return entries, nil
+}
+
+// fluentEntries maps a decoded event array to entries
+func fluentEntries(arr []interface{}, cfg *config) ([]*LogEntry, bool) {
+	tag, tagged := "", false
+	if len(arr) > 0 {
+		tag, tagged = arr[0].(string)
+	}
+
+	if !tagged {
+		entry, ok := fluentEvent(arr, "", cfg)
+		if !ok {
+			return nil, false
+		}
+
+		return []*LogEntry{entry}, true
+	}
+
+	if len(arr) < 2 {
+		return nil, false
+	}
+
+	batch, isBatch := arr[1].([]interface{})
+	if !isBatch {
+		entry, ok := fluentEvent(arr[1:], tag, cfg)
+		if !ok {
+			return nil, false
+		}
+
+		return []*LogEntry{entry}, true
+	}
+
+	entries := make([]*LogEntry, 0, len(batch))
+
+	for _, item := range batch {
+		event, isArray := item.([]interface{})
+		if !isArray {
+			return nil, false
+		}
+
+		entry, ok := fluentEvent(event, tag, cfg)
+		if !ok {
+			return nil, false
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, true
+}
+
+// fluentEvent maps a [time, record] pair to an entry
+func fluentEvent(event []interface{}, tag string, cfg *config) (*LogEntry, bool) {
+	if len(event) < 2 {
+		return nil, false
+	}
+
+	record, ok := event[1].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	eventTime := event[0]
+	if header, isHeader := eventTime.([]interface{}); isHeader && len(header) > 0 {
+		eventTime = header[0] // Fluent Bit v2: [time, metadata]
+	}
+
+	entry := jsonEntry(record, cfg)
+
+	start := cfg.timer.start()
+	if t, err := parseTimestamp(eventTime); err == nil {
+		entry.Timestamp = t
+	}
+	cfg.timer.stop(phaseExtractTS, start, 1)
+
+	if tag != "" {
+		cfg.setField(entry, "tag", tag)
+	}
+
+	return entry, true
+}
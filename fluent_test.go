@@ -0,0 +1,102 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFluentEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		messages []string
+		tag      string
+		ts       time.Time
+	}{
+		{
+			name:     "message mode",
+			input:    `["app.web", 1704207845, {"level":"error","message":"x"}]`,
+			messages: []string{"x"},
+			tag:      "app.web",
+			ts:       time.Unix(1704207845, 0),
+		},
+		{
+			name:     "forward batch",
+			input:    `["app.worker", [[1704207845.5, {"msg":"a"}], [1704207846, {"msg":"b","level":"warn"}]], {"chunk":"p8n9"}]`,
+			messages: []string{"a", "b"},
+			tag:      "app.worker",
+			ts:       time.Unix(1704207845, 5e8),
+		},
+		{
+			name:     "untagged",
+			input:    `[1704207845, {"log":"container started\n","stream":"stdout"}]`,
+			messages: []string{"container started\n"},
+			ts:       time.Unix(1704207845, 0),
+		},
+		{
+			name:     "fluent bit v2",
+			input:    `[[1704207845.25, {}], {"message":"hello"}]`,
+			messages: []string{"hello"},
+			ts:       time.Unix(1704207845, 25e7),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := New().ParseString(tt.input)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			if len(entries) != len(tt.messages) {
+				t.Fatalf("got %d entries, want %d", len(entries), len(tt.messages))
+			}
+
+			for i, msg := range tt.messages {
+				if entries[i].Message != msg {
+					t.Errorf("entry %d: Message = %q, want %q", i, entries[i].Message, msg)
+				}
+
+				if tt.tag != "" && entries[i].Fields["tag"] != tt.tag {
+					t.Errorf("entry %d: tag = %v, want %q", i, entries[i].Fields["tag"], tt.tag)
+				}
+			}
+
+			if !entries[0].Timestamp.Equal(tt.ts) {
+				t.Errorf("Timestamp = %v, want %v", entries[0].Timestamp, tt.ts)
+			}
+		})
+	}
+}
+
+func TestFluentBatchLevelsAndSource(t *testing.T) {
+	input := `["app", [[1704207845, {"msg":"a","level":"error"}], [1704207846, {"msg":"b"}]]]` + "\n" +
+		`["app", 1704207847, {"msg":"c"}]`
+
+	entries, err := NewWithFormat(FormatJSON, WithSourceName("fluent")).ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if len(entries) != 3 || entries[0].Level != LevelError || entries[1].Level != LevelInfo {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+
+	if entries[1].Source.Line != 1 || entries[2].Source.Line != 2 {
+		t.Errorf("batched entries should share their line: %+v %+v", entries[1].Source, entries[2].Source)
+	}
+
+	// Head limits apply within a batch
+	entries, err = NewWithFormat(FormatJSON, WithHeadLimit(1)).ParseString(input)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("head limit: got %d entries, error %v", len(entries), err)
+	}
+}
+
+func TestFluentInvalidArrays(t *testing.T) {
+	for _, input := range []string{`[1, 2, 3]`, `["tag"]`, `["tag", [1]]`, `[`} {
+		if _, err := NewWithFormat(FormatJSON).ParseString(input); err == nil {
+			t.Errorf("ParseString(%s) should fail", input)
+		}
+	}
+}
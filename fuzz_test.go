@@ -0,0 +1,203 @@
+package logparser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fuzzSeeds adds every line of the text fixtures, plus cases aimed at the
+// hand-written scanners, to a fuzz corpus
+func fuzzSeeds(f *testing.F, extra ...string) {
+	f.Helper()
+
+	paths, err := filepath.Glob("testdata/*.log")
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			f.Add(line)
+		}
+	}
+
+	for _, seed := range append([]string{
+		`msg="unterminated`,
+		`msg="a"=`,
+		`msg="a" =`,
+		`="x"`,
+		`==`,
+		`k="\`,
+		`k="\u12`,
+		`k="😀 \ud83d \\\"\\"`,
+		"k=\x00 \x00=\x00 msg=\"\x00\"",
+		strings.Repeat("k", 1<<16) + "=v",
+		`{"a":{"b":{"c":[1,{"d":"é\"\\"}]}},"level":"warn"}`,
+		`{"msg":"\ud800","ts":1e309}`,
+		`{"level":`,
+		`[1,{"log":"x"}]`,
+		"[ERROR] \xff\xfe",
+		"2024-01-02T15:04:05.123Z [INFO] x [k=\"v\\\" a=b]",
+		"\x1b[31mE0102 15:04:05.123456   1 a.go:1] \x1b[0m",
+	}, extra...) {
+		f.Add(seed)
+	}
+}
+
+// checkFuzzEntry fails unless a parsed entry is usable: non-nil, with
+// Fields and one of the standard levels
+func checkFuzzEntry(t *testing.T, line string, entries []*LogEntry, err error) {
+	t.Helper()
+
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		switch {
+		case entry == nil:
+			t.Fatalf("%q: nil entry", line)
+		case entry.Fields == nil:
+			t.Fatalf("%q: nil Fields", line)
+		case levelRank(entry.Level) < 0:
+			t.Fatalf("%q: level %q", line, entry.Level)
+		}
+	}
+}
+
+func FuzzParseLogfmtLine(f *testing.F) {
+	fuzzSeeds(f)
+
+	cfg := &config{}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		entry, err := parseLogfmtLine(line, cfg, make(interner))
+		checkFuzzEntry(t, line, []*LogEntry{entry}, err)
+	})
+}
+
+func FuzzParseJSONLine(f *testing.F) {
+	fuzzSeeds(f)
+
+	parse := lineParserFor(FormatJSON, &config{})
+
+	f.Fuzz(func(t *testing.T, line string) {
+		entries, err := parse(line)
+		checkFuzzEntry(t, line, entries, err)
+	})
+}
+
+func FuzzParseTextLine(f *testing.F) {
+	fuzzSeeds(f)
+
+	cfg := &config{bracketFields: true, inferLevels: true}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		entry, _, err := parseTextLine(line, builtinTextPatterns(), nil, cfg)
+		checkFuzzEntry(t, line, []*LogEntry{entry}, err)
+	})
+}
+
+func FuzzParseTimestamp(f *testing.F) {
+	fuzzSeeds(f,
+		"2024-01-02T15:04:05Z",
+		"2024-01-02T15:04:05.123456789+05:30",
+		"Jan  2 15:04:05",
+		"1704207845",
+		"1704207845123456789",
+		"-99999999999999999999",
+		"1e400",
+	)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, val := range []interface{}{s, json.Number(s)} {
+			if ts, err := parseTimestampIn(val, time.FixedZone("UTC+1", 3600)); err != nil && !ts.IsZero() {
+				t.Fatalf("%q: error %v with time %v", s, err, ts)
+			}
+		}
+	})
+}
+
+func FuzzParseOtherLines(f *testing.F) {
+	fuzzSeeds(f,
+		`<Event><System><EventID>4625</EventID><Level>2</Level></System></Event>`,
+		`<Event><EventData><Data Name="x">&amp;&#0;</Data></EventData>`,
+		`http 2018-07-02T22:23:00Z app/lb 1.2.3.4:1 [::1]:x -1 -1 -1 - - - - "GET"`,
+		`h[1]: 1.2.3.4:5 [06/Feb/2009:12:14:14.655] fe~ be/s 1/-1/+2/3/4 503 +9 - - SC 1/1/1/1/+1 0/0 {a} {b "GET`,
+		`[06/Feb/2009:12:14:14] fe be 0/0/0 1 -- 1 0`,
+		`1286536308.779 -1 ::1 TCP_DENIED/403 0 GET http://x/ - HIER_NONE/- -`,
+	)
+
+	cfg := &config{}
+	parsers := []lineParseFunc{
+		lineParserFor(FormatALB, cfg),
+		lineParserFor(FormatWindowsEventXML, cfg),
+		lineParserFor(FormatHAProxy, cfg),
+		lineParserFor(FormatSquid, cfg),
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		for _, parse := range parsers {
+			entries, err := parse(line)
+			checkFuzzEntry(t, line, entries, err)
+		}
+	})
+}
+
+// FuzzParseFiltered runs whole lines through a parser with an allowlist,
+// which keeps no fields for most of them
+func FuzzParseFiltered(f *testing.F) {
+	fuzzSeeds(f)
+
+	p := New(WithFieldAllowlist("user", "http.status"), WithSkipInvalid(true))
+
+	f.Fuzz(func(t *testing.T, line string) {
+		entries, err := p.ParseString(line)
+
+		ptrs := make([]*LogEntry, len(entries))
+		for i := range entries {
+			ptrs[i] = &entries[i]
+		}
+
+		checkFuzzEntry(t, line, ptrs, err)
+	})
+}
+
+// FuzzRelaxJSON checks that WithLenientJSON never changes valid JSON, and
+// only ever blanks bytes out
+func FuzzRelaxJSON(f *testing.F) {
+	fuzzSeeds(f,
+		`{"url":"http://x//y","a":[1,2,],}`,
+		`{"a":"\"//"} // c`,
+		`{/* c */"a":1 /* unterminated`,
+		`[1,/**/]`,
+		`{"a":"\\",}`,
+		`"//"`,
+	)
+
+	f.Fuzz(func(t *testing.T, line string) {
+		relaxed := relaxJSON(line)
+		if json.Valid([]byte(line)) && relaxed != line {
+			t.Fatalf("valid JSON %q relaxed to %q", line, relaxed)
+		}
+
+		if len(relaxed) != len(line) {
+			t.Fatalf("%q relaxed to %q of another length", line, relaxed)
+		}
+
+		for i := range relaxed {
+			if relaxed[i] != line[i] && relaxed[i] != ' ' {
+				t.Fatalf("%q relaxed to %q: byte %d changed", line, relaxed, i)
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package logparser
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Gap is a period in which a group of entries logged nothing
+type Gap struct {
+	Key      string        `json:"key,omitempty"`   // Field used for grouping, if the group has one
+	Group    string        `json:"group,omitempty"` // Value of the grouping field
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"duration"`
+	Before   LogEntry      `json:"before"` // Last entry before the gap
+	After    LogEntry      `json:"after"`  // First entry after the gap
+}
+
+// String describes the gap, for example
+// "no logs from service=worker for 14m0s between 03:02 and 03:16"
+func (g Gap) String() string {
+	from := ""
+	if g.Group != "" {
+		from = fmt.Sprintf(" from %s=%s", g.Key, g.Group)
+	}
+
+	return fmt.Sprintf("no logs%s for %s between %s and %s",
+		from, g.Duration, g.Start.Format("15:04"), g.End.Format("15:04"))
+}
+
+// DetectGaps finds silences of at least minGap between consecutive entries.
+// Entries are grouped by the first of the groupBy fields they carry
+// (default "service") and each group is checked separately, so a quiet
+// worker is found even while other services keep logging. Entries need not
+// be sorted; zero timestamps are ignored. Gaps are ordered by start time.
+func DetectGaps(entries []LogEntry, minGap time.Duration, groupBy ...string) []Gap {
+	if len(groupBy) == 0 {
+		groupBy = []string{"service"}
+	}
+
+	type groupKey struct{ key, value string }
+
+	groups := make(map[groupKey][]int)
+
+	var order []groupKey
+
+	for i := range entries {
+		if entries[i].Timestamp.IsZero() {
+			continue
+		}
+
+		var gk groupKey
+
+		for _, key := range groupBy {
+			if val, ok := entryField(&entries[i], key); ok {
+				gk = groupKey{key: key, value: formatValue(val)}
+
+				break
+			}
+		}
+
+		if _, seen := groups[gk]; !seen {
+			order = append(order, gk)
+		}
+
+		groups[gk] = append(groups[gk], i)
+	}
+
+	gaps := []Gap{}
+
+	for _, gk := range order {
+		idx := groups[gk]
+
+		sort.SliceStable(idx, func(a, b int) bool {
+			return entries[idx[a]].Timestamp.Before(entries[idx[b]].Timestamp)
+		})
+
+		for n := 1; n < len(idx); n++ {
+			before, after := entries[idx[n-1]], entries[idx[n]]
+
+			if d := after.Timestamp.Sub(before.Timestamp); d >= minGap && d > 0 {
+				gaps = append(gaps, Gap{
+					Key:      gk.key,
+					Group:    gk.value,
+					Start:    before.Timestamp,
+					End:      after.Timestamp,
+					Duration: d,
+					Before:   before,
+					After:    after,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(gaps, func(a, b int) bool {
+		return gaps[a].Start.Before(gaps[b].Start)
+	})
+
+	return gaps
+}
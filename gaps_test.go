@@ -0,0 +1,63 @@
+package logparser
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDetectGaps(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return base.Add(time.Duration(min) * time.Minute) }
+	svc := func(name string) map[string]interface{} { return map[string]interface{}{"service": name} }
+
+	entries := []LogEntry{
+		{Timestamp: at(0), Message: "w1", Fields: svc("worker")},
+		{Timestamp: at(0), Message: "a1", Fields: svc("api")},
+		{Timestamp: at(16), Message: "w3", Fields: svc("worker")}, // out of order
+		{Timestamp: at(2), Message: "w2", Fields: svc("worker")},
+		{Timestamp: at(2), Message: "w2-dup", Fields: svc("worker")}, // duplicate timestamp
+		{Timestamp: at(5), Message: "a2", Fields: svc("api")},
+		{Message: "no timestamp", Fields: svc("worker")},
+		{Timestamp: at(9), Message: "a3", Fields: svc("api")},
+	}
+
+	gaps := DetectGaps(entries, 10*time.Minute)
+	if len(gaps) != 1 {
+		t.Fatalf("got %d gaps, want 1: %v", len(gaps), gaps)
+	}
+
+	g := gaps[0]
+	if g.Key != "service" || g.Group != "worker" || g.Duration != 14*time.Minute {
+		t.Errorf("unexpected gap %+v", g)
+	}
+
+	if g.Before.Message != "w2-dup" || g.After.Message != "w3" {
+		t.Errorf("bracketing entries = %q, %q", g.Before.Message, g.After.Message)
+	}
+
+	// Without per-service grouping the api entries fill the silence
+	if gaps := DetectGaps(entries, 10*time.Minute, "host"); len(gaps) != 0 {
+		t.Errorf("ungrouped gaps = %v", gaps)
+	}
+
+	if gaps := DetectGaps(entries, 3*time.Minute); len(gaps) != 3 {
+		t.Errorf("got %d gaps of 3m, want 3: %v", len(gaps), gaps)
+	}
+}
+
+func ExampleDetectGaps() {
+	logs := `{"time":"2024-01-02T03:01:00Z","service":"worker","msg":"job 1 done"}
+{"time":"2024-01-02T03:02:00Z","service":"worker","msg":"job 2 done"}
+{"time":"2024-01-02T03:16:00Z","service":"worker","msg":"job 3 done"}`
+
+	entries, err := New().ParseString(logs)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, gap := range DetectGaps(entries, 5*time.Minute) {
+		fmt.Println(gap)
+	}
+	// Output: no logs from service=worker for 14m0s between 03:02 and 03:16
+}
@@ -0,0 +1,195 @@
+package logparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Garbage collector lines from the JVM (unified logging and the pre-JDK 9
+// -XX:+PrintGCDetails style) and from Go's GODEBUG=gctrace=1. Matched lines
+// keep their text as the message and get typed fields: gc (int),
+// gc_runtime ("jvm" or "go"), pause_ms, and heap sizes in megabytes.
+var (
+	gcHeapRe       = regexp.MustCompile(`(\d+)([KMG])->(\d+)([KMG])\((\d+)([KMG])\)`)
+	gcPauseMsRe    = regexp.MustCompile(`\s([\d.]+)ms$`)
+	gcProcsRe      = regexp.MustCompile(`, (\d+) P\b`)
+	jvmTimeLayouts = []string{"2006-01-02T15:04:05.000-0700", "2006-01-02T15:04:05.000Z0700", time.RFC3339Nano}
+)
+
+// WithGCPauseThreshold sets the level of recognized garbage collector lines
+// to WARN when their pause exceeds d; other GC lines are INFO. A zero
+// threshold (the default) never warns.
+func WithGCPauseThreshold(d time.Duration) Option {
+	return func(c *config) {
+		c.gcPauseWarn = d
+	}
+}
+
+// parseJVMUnifiedGC handles unified logging lines such as
+// [2024-01-02T15:04:05.123+0000][info][gc] GC(42) Pause Young (Normal) (G1 Evacuation Pause) 24M->4M(256M) 12.345ms
+func parseJVMUnifiedGC(entry *LogEntry, matches []string, cfg *config) error {
+	decorators, event := matches[1], matches[3]
+
+	// The first decorator is the wall-clock time when -Xlog includes it
+	if first, _, ok := strings.Cut(strings.TrimPrefix(decorators, "["), "]"); ok {
+		if t, ok := parseJVMTime(first, cfg); ok {
+			entry.Timestamp = t
+		}
+	}
+
+	setGCNumber(entry, matches[2], "jvm", cfg)
+	setJVMHeap(entry, event, cfg)
+
+	var pause float64
+
+	if strings.HasPrefix(event, "Pause") {
+		if m := gcPauseMsRe.FindStringSubmatch(event); m != nil {
+			pause, _ = strconv.ParseFloat(m[1], 64)
+			cfg.setField(entry, "pause_ms", pause)
+		}
+	}
+
+	if name, _, ok := strings.Cut(event, " ("); ok && !strings.ContainsAny(name, "0123456789") {
+		cfg.setField(entry, "gc_event", name)
+	}
+
+	setGCLevel(entry, pause, cfg)
+
+	return nil
+}
+
+// parseJVMLegacyGC handles -XX:+PrintGCDetails lines such as
+//
+//	2024-01-02T15:04:05.123+0000: 1.234: [GC (Allocation Failure)
+//	    [PSYoungGen: 65536K->10720K(76288K)] 65536K->10736K(251392K), 0.0123456 secs]
+//
+// shown here wrapped; the log writes each on one line
+func parseJVMLegacyGC(entry *LogEntry, matches []string, cfg *config) error {
+	if matches[1] != "" {
+		if t, ok := parseJVMTime(matches[1], cfg); ok {
+			entry.Timestamp = t
+		}
+	}
+
+	cfg.setField(entry, "gc_runtime", "jvm")
+	cfg.setField(entry, "gc_event", matches[2])
+	cfg.setField(entry, "gc_cause", matches[3])
+
+	// The last heap triple covers the whole heap; earlier ones are generations
+	setJVMHeap(entry, matches[4], cfg)
+
+	secs, err := strconv.ParseFloat(matches[5], 64)
+	if err == nil {
+		cfg.setField(entry, "pause_ms", secs*1000)
+	}
+
+	setGCLevel(entry, secs*1000, cfg)
+
+	return nil
+}
+
+// parseGoGCTrace handles GODEBUG=gctrace=1 lines such as
+// gc 42 @12.345s 1%: 0.021+1.2+0.030 ms clock, 0.17+0.35/1.1/2.5+0.24 ms cpu, 4->4->2 MB, 5 MB goal, 8 P
+//
+// The clock triple is sweep termination (stop-the-world), concurrent mark,
+// and mark termination (stop-the-world); pause_ms is the sum of the two
+// stop-the-world phases. The cpu group splits concurrent mark into assist,
+// background, and idle time.
+func parseGoGCTrace(entry *LogEntry, matches []string, cfg *config) error {
+	setGCNumber(entry, matches[1], "go", cfg)
+
+	floats := make([]float64, len(matches))
+
+	for i := 2; i <= 15; i++ {
+		floats[i], _ = strconv.ParseFloat(matches[i], 64)
+	}
+
+	for i, key := range [...]string{
+		2: "uptime_s", 3: "gc_cpu_percent",
+		4: "stw_sweep_term_ms", 5: "concurrent_mark_ms", 6: "stw_mark_term_ms",
+		7: "cpu_sweep_term_ms", 8: "cpu_assist_ms", 9: "cpu_background_ms", 10: "cpu_idle_ms", 11: "cpu_mark_term_ms",
+		12: "heap_before_mb", 13: "heap_after_mb", 14: "heap_live_mb", 15: "heap_goal_mb",
+	} {
+		if key != "" {
+			cfg.setField(entry, key, floats[i])
+		}
+	}
+
+	if m := gcProcsRe.FindStringSubmatch(matches[16]); m != nil {
+		if procs, err := strconv.Atoi(m[1]); err == nil {
+			cfg.setField(entry, "procs", procs)
+		}
+	}
+
+	if strings.Contains(matches[16], "(forced)") {
+		cfg.setField(entry, "forced", true)
+	}
+
+	pause := floats[4] + floats[6]
+	cfg.setField(entry, "pause_ms", pause)
+	setGCLevel(entry, pause, cfg)
+
+	return nil
+}
+
+// setGCNumber stores the collection number and runtime of a GC line
+func setGCNumber(entry *LogEntry, number, runtime string, cfg *config) {
+	if n, err := strconv.Atoi(number); err == nil {
+		cfg.setField(entry, "gc", n)
+	}
+
+	cfg.setField(entry, "gc_runtime", runtime)
+}
+
+// setJVMHeap stores the last before->after(total) heap sizes found in s
+func setJVMHeap(entry *LogEntry, s string, cfg *config) {
+	all := gcHeapRe.FindAllStringSubmatch(s, -1)
+	if all == nil {
+		return
+	}
+
+	m := all[len(all)-1]
+	for i, key := range [...]string{"heap_before_mb", "heap_after_mb", "heap_total_mb"} {
+		cfg.setField(entry, key, jvmSizeMB(m[1+2*i], m[2+2*i]))
+	}
+}
+
+// jvmSizeMB converts a JVM heap size with a K, M, or G suffix to megabytes
+func jvmSizeMB(digits, unit string) float64 {
+	n, _ := strconv.ParseFloat(digits, 64)
+
+	switch unit {
+	case "K":
+		return n / 1024
+	case "G":
+		return n * 1024
+	default:
+		return n
+	}
+}
+
+// setGCLevel marks a GC line INFO, or WARN when its pause exceeds the
+// configured threshold
+func setGCLevel(entry *LogEntry, pauseMs float64, cfg *config) {
+	entry.Level = LevelInfo
+
+	if threshold := cfg.gcPauseWarn; threshold > 0 && time.Duration(pauseMs*float64(time.Millisecond)) > threshold {
+		entry.Level = "WARN"
+	}
+}
+
+// parseJVMTime parses a JVM log timestamp with the first layout that fits
+func parseJVMTime(s string, cfg *config) (time.Time, bool) {
+	start := cfg.timer.start()
+	defer cfg.timer.stop(phaseExtractTS, start, 1)
+
+	for _, layout := range jvmTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
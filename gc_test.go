@@ -0,0 +1,90 @@
+package logparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGCLines(t *testing.T) {
+	data, err := os.ReadFile("testdata/gc.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := NewWithFormat(FormatText, WithGCPauseThreshold(100*time.Millisecond)).ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(entries) != len(lines) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(lines))
+	}
+
+	tests := []struct {
+		level  string
+		fields map[string]interface{}
+	}{
+		{"INFO", map[string]interface{}{
+			"gc": 41, "gc_runtime": "jvm", "gc_event": "Pause Young", "pause_ms": 12.345,
+			"heap_before_mb": 24.0, "heap_after_mb": 4.0, "heap_total_mb": 256.0,
+		}},
+		{"INFO", map[string]interface{}{"gc": 42, "gc_event": "Pause Young"}},
+		{"WARN", map[string]interface{}{"gc": 42, "pause_ms": 245.6, "heap_before_mb": 1024.0, "heap_after_mb": 512.0, "heap_total_mb": 2048.0}},
+		{"INFO", map[string]interface{}{"gc": 43}},
+		{"INFO", map[string]interface{}{
+			"gc_event": "GC", "gc_cause": "Allocation Failure", "pause_ms": 12.3456,
+			"heap_before_mb": 64.0, "heap_after_mb": 10736.0 / 1024, "heap_total_mb": 251392.0 / 1024,
+		}},
+		{"INFO", map[string]interface{}{"pause_ms": 42.4051, "heap_after_mb": 35124.0 / 1024}},
+		{"WARN", map[string]interface{}{"gc_event": "Full GC", "gc_cause": "Ergonomics", "pause_ms": 350.123}},
+		{"INFO", map[string]interface{}{
+			"gc": 1, "gc_runtime": "go", "uptime_s": 0.012, "gc_cpu_percent": 2.0,
+			"stw_sweep_term_ms": 0.021, "concurrent_mark_ms": 1.2, "stw_mark_term_ms": 0.030,
+			"cpu_sweep_term_ms": 0.17, "cpu_assist_ms": 0.35, "cpu_background_ms": 1.1, "cpu_idle_ms": 2.5, "cpu_mark_term_ms": 0.24,
+			"heap_before_mb": 4.0, "heap_after_mb": 4.0, "heap_live_mb": 2.0, "heap_goal_mb": 5.0, "procs": 8,
+		}},
+		{"WARN", map[string]interface{}{"gc": 2, "pause_ms": 150.5, "forced": true}},
+	}
+
+	for i, tt := range tests {
+		e := entries[i]
+
+		if e.Level != tt.level {
+			t.Errorf("entry %d: level %s, want %s", i, e.Level, tt.level)
+		}
+
+		if e.Message != lines[i] {
+			t.Errorf("entry %d: message %q, want the original line", i, e.Message)
+		}
+
+		for key, want := range tt.fields {
+			got := e.Fields[key]
+			if f, ok := want.(float64); ok {
+				if g, isFloat := got.(float64); !isFloat || g-f > 1e-9 || f-g > 1e-9 {
+					t.Errorf("entry %d: %s = %v, want %v", i, key, got, want)
+				}
+
+				continue
+			}
+
+			if got != want {
+				t.Errorf("entry %d: %s = %v (%T), want %v", i, key, got, got, want)
+			}
+		}
+	}
+
+	if _, ok := entries[3].Fields["pause_ms"]; ok {
+		t.Error("concurrent phase reported as a pause")
+	}
+
+	if want := time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC); !entries[0].Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", entries[0].Timestamp, want)
+	}
+
+	if want := time.Date(2024, 1, 2, 15, 4, 8, 123000000, time.UTC); !entries[4].Timestamp.Equal(want) {
+		t.Errorf("legacy Timestamp = %v, want %v", entries[4].Timestamp, want)
+	}
+}
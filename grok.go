@@ -0,0 +1,363 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxGrokDepth bounds how deeply grok patterns may reference each other,
+// which also catches patterns that reference themselves
+const maxGrokDepth = 32
+
+// grokPatterns is the built-in grok library: the common primitives of the
+// Logstash core patterns, adapted where Go's RE2 syntax lacks lookaround
+var grokPatterns = map[string]string{
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"INT":               `(?:[+-]?(?:[0-9]+))`,
+	"BASE10NUM":         `(?:[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+))`,
+	"NUMBER":            `(?:%{BASE10NUM})`,
+	"BASE16NUM":         `(?:0[xX])?[0-9A-Fa-f]+`,
+	"POSINT":            `\b(?:[1-9][0-9]*)\b`,
+	"NONNEGINT":         `\b(?:[0-9]+)\b`,
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|` + "`(?:[^`\\\\]|\\\\.)*`)",
+	"QS":                `%{QUOTEDSTRING}`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}(?:[0-9A-Fa-f]{1,4}|%{IPV4})?`,
+	"IP":                `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME":          `\b(?:[0-9A-Za-z][0-9A-Za-z-]{0,62})(?:\.(?:[0-9A-Za-z][0-9A-Za-z-]{0,62}))*\.?`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"UNIXPATH":          `(?:/[\w_%!$@:.,+~-]*)+`,
+	"WINPATH":           `(?:[A-Za-z]+:|\\)(?:\\[^\\?*]*)+`,
+	"PATH":              `(?:%{UNIXPATH}|%{WINPATH})`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"MONTH":             `\b(?:[Jj]an|[Ff]eb|[Mm]ar|[Aa]pr|[Mm]ay|[Jj]un|[Jj]ul|[Aa]ug|[Ss]ep|[Oo]ct|[Nn]ov|[Dd]ec)[a-z]*\b`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:(?:0[1-9])|(?:[12][0-9])|(?:3[01])|[1-9])`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+	"MINUTE":            `(?:[0-5][0-9])`,
+	"SECOND":            `(?:(?:[0-5]?[0-9]|60)(?:[.,][0-9]+)?)`,
+	"TIME":              `%{HOUR}:%{MINUTE}:%{SECOND}`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"PROG":              `[\x21-\x5a\x5c\x5e-\x7e]+`,
+	"SYSLOGPROG":        `%{PROG:program}(?:\[%{POSINT:pid}\])?`,
+	"LOGLEVEL": `(?:[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo?(?:rmation)?|INFO?(?:RMATION)?|` +
+		`[Ww]arn?(?:ing)?|WARN?(?:ING)?|[Ee]rr?(?:or)?|ERR?(?:OR)?|[Cc]rit?(?:ical)?|CRIT?(?:ICAL)?|[Ff]atal|FATAL|` +
+		`[Ss]evere|SEVERE|EMERG(?:ENCY)?|[Ee]merg(?:ency)?)`,
+	"HTTPDUSER": `(?:%{USERNAME}|-)`,
+	"COMMONAPACHELOG": `%{IPORHOST:clientip} %{HTTPDUSER:ident} %{HTTPDUSER:auth} \[%{HTTPDATE:timestamp}\] ` +
+		`"(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" ` +
+		`%{NUMBER:response:int} (?:%{NUMBER:bytes:int}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+}
+
+// grokTimeLayouts are the layouts tried for a grok capture named as a
+// timestamp. Fractional seconds are accepted after any of them.
+var grokTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"02/Jan/2006:15:04:05 -0700",
+	"Jan _2 15:04:05",
+	"Jan _2 15:04:05 2006",
+}
+
+// grokCapturePrefix starts the names of the regexp groups expand writes for
+// grok captures
+const grokCapturePrefix = "grok__"
+
+// grokRefRe matches a %{NAME}, %{NAME:field}, or %{NAME:field:type} reference
+var grokRefRe = regexp.MustCompile(`%\{(\w+)(?::([\w@.\[\]-]+))?(?::(int|float|string))?\}`)
+
+// Grok compiles grok expressions against the built-in pattern library and
+// any custom definitions
+type Grok struct {
+	defs map[string]string
+}
+
+// TextPattern is a compiled pattern for plain text lines, registered with
+// WithTextPatterns
+type TextPattern struct {
+	expr    string
+	pattern *textPattern
+}
+
+// grokCapture is a named capture of a compiled grok expression
+type grokCapture struct {
+	field string
+	kind  string // "int", "float", or "" for a string
+}
+
+// NewGrok returns a grok compiler. defs adds patterns to the built-in
+// library, or replaces built-in ones of the same name; their expressions
+// may reference each other and the built-in patterns.
+func NewGrok(defs map[string]string) *Grok {
+	g := &Grok{defs: make(map[string]string, len(grokPatterns)+len(defs))}
+
+	for name, expr := range grokPatterns {
+		g.defs[name] = expr
+	}
+
+	for name, expr := range defs {
+		g.defs[name] = expr
+	}
+
+	return g
+}
+
+// CompileGrok compiles a grok expression against the built-in library.
+// See Grok.Compile.
+func CompileGrok(pattern string) (*TextPattern, error) {
+	return NewGrok(nil).Compile(pattern)
+}
+
+// Compile compiles a grok expression such as
+//
+//	%{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} %{GREEDYDATA:msg}
+//
+// into a text pattern. %{NAME} matches a library pattern, %{NAME:field}
+// also captures it, and %{NAME:field:int} or :float converts the capture to
+// int64 or float64. Logstash's nested field syntax, [http][status], becomes
+// the dotted name http.status. Regexp named groups, (?P<field>...), capture
+// too. Captures named like a standard JSON key (ts, timestamp, @timestamp,
+// level, severity, msg, message, ...) set the entry's timestamp, level, and
+// message; the rest go to Fields, empty captures excepted. Like Logstash,
+// the expression may match anywhere in the line unless anchored with ^.
+// Referencing an unknown pattern is an error naming it.
+func (g *Grok) Compile(pattern string) (*TextPattern, error) {
+	var captures []grokCapture
+
+	expr, err := g.expand(pattern, &captures, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("grok: %w", err)
+	}
+
+	tp := &textPattern{regex: re}
+	if strings.HasPrefix(pattern, "^") {
+		tp.first = firstBytes(expr)
+	}
+
+	var (
+		tsIndex int
+		fields  = make(map[int]grokCapture)
+	)
+
+	for i, name := range re.SubexpNames() {
+		var capture grokCapture
+
+		switch {
+		case grokCaptureIndex(name, len(captures)) >= 0:
+			capture = captures[grokCaptureIndex(name, len(captures))]
+		case name != "":
+			capture = grokCapture{field: name}
+		default:
+			continue
+		}
+
+		rank, std := jsonStdKeys[strings.ToLower(capture.field)]
+
+		switch {
+		case std && rank.field == stdTimestamp && tsIndex == 0:
+			tsIndex = i
+		case std && rank.field == stdLevel && tp.lvlIndex == 0:
+			tp.lvlIndex = i
+		case std && rank.field == stdMessage && tp.msgIndex == 0:
+			tp.msgIndex = i
+		default:
+			fields[i] = capture
+		}
+	}
+
+	tp.post = func(entry *LogEntry, matches []string, cfg *config) error {
+		if tsIndex > 0 {
+			start := cfg.timer.start()
+			if t, ok := parseGrokTime(matches[tsIndex], cfg); ok {
+				entry.Timestamp = t
+			}
+			cfg.timer.stop(phaseExtractTS, start, 1)
+		}
+
+		for i, capture := range fields {
+			if matches[i] != "" {
+				cfg.setField(entry, capture.field, capture.value(matches[i]))
+			}
+		}
+
+		return nil
+	}
+
+	return &TextPattern{expr: pattern, pattern: tp}, nil
+}
+
+// expand replaces the pattern references in expr with their expressions,
+// turning captures into groups named by grokCapturePrefix and their index
+// in captures
+func (g *Grok) expand(expr string, captures *[]grokCapture, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok: patterns nested deeper than %d, possibly recursive", maxGrokDepth)
+	}
+
+	var (
+		b    strings.Builder
+		last int
+	)
+
+	for _, m := range grokRefRe.FindAllStringSubmatchIndex(expr, -1) {
+		b.WriteString(expr[last:m[0]])
+		last = m[1]
+
+		name := expr[m[2]:m[3]]
+
+		def, ok := g.defs[name]
+		if !ok {
+			return "", fmt.Errorf("grok: unknown pattern %q", name)
+		}
+
+		sub, err := g.expand(def, captures, depth+1)
+		if err != nil {
+			return "", err
+		}
+
+		if m[4] < 0 {
+			b.WriteString("(?:" + sub + ")")
+
+			continue
+		}
+
+		capture := grokCapture{field: grokFieldName(expr[m[4]:m[5]])}
+		if m[6] >= 0 && expr[m[6]:m[7]] != "string" {
+			capture.kind = expr[m[6]:m[7]]
+		}
+
+		fmt.Fprintf(&b, "(?P<%s%d>%s)", grokCapturePrefix, len(*captures), sub)
+		*captures = append(*captures, capture)
+	}
+
+	b.WriteString(expr[last:])
+
+	return b.String(), nil
+}
+
+// grokCaptureIndex returns the capture index encoded in a group name
+// written by expand, or -1 for any other name
+func grokCaptureIndex(name string, n int) int {
+	rest, ok := strings.CutPrefix(name, grokCapturePrefix)
+	if !ok {
+		return -1
+	}
+
+	i, err := strconv.Atoi(rest)
+	if err != nil || i >= n {
+		return -1
+	}
+
+	return i
+}
+
+// grokFieldName turns Logstash's [a][b] field reference into a.b
+func grokFieldName(name string) string {
+	if !strings.HasPrefix(name, "[") {
+		return name
+	}
+
+	return strings.ReplaceAll(strings.Trim(name, "[]"), "][", ".")
+}
+
+// value converts a captured string to the capture's type, keeping it as
+// written when it does not parse
+func (c grokCapture) value(s string) interface{} {
+	switch c.kind {
+	case "int":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+
+	return s
+}
+
+// parseGrokTime parses a captured timestamp with the registered timestamp
+// parsers, then the first grok layout that fits, reading times without a
+// zone in the configured location; under WithStrictTimestamps only the
+// strict layouts are tried
+func parseGrokTime(s string, cfg *config) (time.Time, bool) {
+	if cfg.strictTime {
+		t, err := cfg.parseStrictTime(s)
+
+		return t, err == nil
+	}
+
+	if t, err := cfg.customTime(s); !errors.Is(err, ErrTimestampDeclined) {
+		if err == nil && t.Year() == 0 {
+			t = cfg.inferYear(t)
+		}
+
+		return t, err == nil
+	}
+
+	for _, layout := range grokTimeLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case t.Year() == 0:
+			t = cfg.inferYear(t)
+		case !layoutHasZone(layout):
+			t = wallClock(t, cfg.timeLocation())
+		}
+
+		return t, true
+	}
+
+	t, err := parseTimestampIn(s, cfg.timeLocation())
+
+	return t, err == nil
+}
+
+// String returns the grok expression the pattern was compiled from
+func (p *TextPattern) String() string {
+	return p.expr
+}
+
+// WithTextPatterns registers patterns for plain text lines, tried in order
+// before the built-in ones. A line matching none of them falls through to
+// the built-in patterns.
+func WithTextPatterns(patterns ...*TextPattern) Option {
+	return func(c *config) {
+		for _, p := range patterns {
+			c.textPatterns = append(c.textPatterns, p.pattern)
+		}
+	}
+}
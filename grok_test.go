@@ -0,0 +1,165 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileGrok(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		line    string
+		time    time.Time
+		level   string
+		message string
+		fields  map[string]interface{}
+	}{
+		{
+			name:    "application",
+			pattern: `%{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} %{GREEDYDATA:msg}`,
+			line:    "2024-01-02 15:04:05,123 WARNING disk almost full",
+			time:    time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC),
+			level:   "WARN",
+			message: "disk almost full",
+			fields:  map[string]interface{}{},
+		},
+		{
+			name:    "apache combined",
+			pattern: `%{COMBINEDAPACHELOG}`,
+			line: `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 ` +
+				`"http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`,
+			time:  time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC),
+			level: LevelInfo,
+			message: `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 ` +
+				`"http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`,
+			fields: map[string]interface{}{
+				"clientip":    "127.0.0.1",
+				"ident":       "-",
+				"auth":        "frank",
+				"verb":        "GET",
+				"request":     "/apache_pb.gif",
+				"httpversion": "1.0",
+				"response":    int64(200),
+				"bytes":       int64(2326),
+				"referrer":    `"http://www.example.com/start.html"`,
+				"agent":       `"Mozilla/4.08 [en] (Win98; I ;Nav)"`,
+			},
+		},
+		{
+			name:    "syslog",
+			pattern: `^%{SYSLOGTIMESTAMP:timestamp} %{IPORHOST:host} %{SYSLOGPROG}: %{GREEDYDATA:message}`,
+			line:    "Mar  4 09:15:02 web-01 sshd[4242]: Accepted publickey for deploy from 10.0.0.5 port 51622 ssh2",
+			time:    time.Date(2024, 3, 4, 9, 15, 2, 0, time.UTC),
+			level:   LevelInfo,
+			message: "Accepted publickey for deploy from 10.0.0.5 port 51622 ssh2",
+			fields:  map[string]interface{}{"host": "web-01", "program": "sshd", "pid": "4242"},
+		},
+		{
+			name:    "nested fields and types",
+			pattern: `%{IP:[client][ip]} %{WORD:[http][method]} %{URIPATHPARAM:[url][path]} %{NUMBER:[http][status]:int} %{NUMBER:duration:float}`,
+			line:    "55.3.244.1 GET /index.html?q=1 200 0.043",
+			level:   LevelInfo,
+			message: "55.3.244.1 GET /index.html?q=1 200 0.043",
+			fields: map[string]interface{}{
+				"client.ip":   "55.3.244.1",
+				"http.method": "GET",
+				"url.path":    "/index.html?q=1",
+				"http.status": int64(200),
+				"duration":    0.043,
+			},
+		},
+		{
+			name:    "regexp groups",
+			pattern: `request (?P<request_id>%{UUID}) took (?<took>\d+)ms`,
+			line:    "INFO request 8601b555-6a83-4c12-8269-97c8e32cdb22 took 31ms",
+			level:   LevelInfo,
+			message: "INFO request 8601b555-6a83-4c12-8269-97c8e32cdb22 took 31ms",
+			fields:  map[string]interface{}{"request_id": "8601b555-6a83-4c12-8269-97c8e32cdb22", "took": "31"},
+		},
+	}
+
+	ref := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := CompileGrok(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := NewWithFormat(FormatText, WithTextPatterns(pattern), WithReferenceTime(ref)).ParseString(tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e := entries[0]
+			if !tt.time.IsZero() && !e.Timestamp.Equal(tt.time) {
+				t.Errorf("Timestamp = %v, want %v", e.Timestamp, tt.time)
+			}
+
+			if e.Level != tt.level || e.Message != tt.message {
+				t.Errorf("level, message = %s, %q; want %s, %q", e.Level, e.Message, tt.level, tt.message)
+			}
+
+			if len(e.Fields) != len(tt.fields) {
+				t.Errorf("Fields = %v, want %v", e.Fields, tt.fields)
+			}
+
+			for k, v := range tt.fields {
+				if e.Fields[k] != v {
+					t.Errorf("Fields[%q] = %#v, want %#v", k, e.Fields[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestGrokCustomDefinitions(t *testing.T) {
+	grok := NewGrok(map[string]string{
+		"ORDER_ID": `ORD-\d{6}`,
+		"ORDER":    `order %{ORDER_ID:order_id} for %{NUMBER:amount:float} %{WORD:currency}`,
+	})
+
+	pattern, err := grok.Compile(`^\[%{LOGLEVEL:severity}\] %{ORDER}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := "[ERROR] order ORD-000042 for 19.99 EUR\n2024-01-02 15:04:05 [INFO] not an order\n"
+
+	entries, err := New(WithTextPatterns(pattern)).ParseString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := entries[0]; e.Level != LevelError || e.Fields["order_id"] != "ORD-000042" || e.Fields["amount"] != 19.99 {
+		t.Errorf("entry 0 = %+v", e)
+	}
+
+	// Lines the pattern does not match fall through to the built-in patterns
+	if e := entries[1]; e.Message != "not an order" || e.Timestamp.Year() != 2024 {
+		t.Errorf("entry 1 = %+v", e)
+	}
+}
+
+func TestGrokErrors(t *testing.T) {
+	tests := []struct {
+		pattern string
+		defs    map[string]string
+		want    string
+	}{
+		{`%{IP:ip} %{NOPE:x}`, nil, `unknown pattern "NOPE"`},
+		{`%{OUTER}`, map[string]string{"OUTER": `a%{INNER}`, "INNER": `%{MISSING}`}, `unknown pattern "MISSING"`},
+		{`%{LOOP}`, map[string]string{"LOOP": `x%{LOOP}`}, "possibly recursive"},
+		{`%{WORD:w} (`, nil, "missing closing )"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewGrok(tt.defs).Compile(tt.pattern)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%q) error = %v, want %q", tt.pattern, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package logparser
+
+import (
+	"sort"
+	"strings"
+)
+
+// GroupValueKey holds a leaf value whose key is also a prefix of other keys
+// when fields are grouped. With `http=on http.method=GET`, Fields["http"]
+// becomes {"_value": "on", "method": "GET"}.
+const GroupValueKey = "_value"
+
+// WithGroupFields nests keys containing sep into sub-maps, so `http.method`
+// and `http.status` become Fields["http"] = {"method": ..., "status": ...}.
+// Keys with an empty segment are left as they are. A key that is both a
+// leaf and a prefix keeps its leaf value under GroupValueKey. Grouping runs
+// before transforms, which see the nested maps.
+func WithGroupFields(sep string) Option {
+	return func(c *config) {
+		c.groupSep = sep
+	}
+}
+
+// groupFields nests the dotted keys of entry when grouping is enabled
+func (c *config) groupFields(entry *LogEntry) {
+	if c.groupSep == "" || len(entry.Fields) == 0 {
+		return
+	}
+
+	entry.Fields = GroupFields(entry.Fields, c.groupSep)
+}
+
+// GroupFields returns a copy of fields with keys containing sep nested into
+// sub-maps, following the rules of WithGroupFields. Existing map values are
+// merged with the grouped keys rather than replaced. It is the inverse of
+// FlattenFields.
+func GroupFields(fields map[string]interface{}, sep string) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields))
+
+	// Sorted so that values colliding at the same path resolve the same way
+	// on every run
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		val := fields[key]
+
+		if sep == "" || !strings.Contains(key, sep) {
+			mergeGroupLeaf(out, key, val)
+
+			continue
+		}
+
+		path := strings.Split(key, sep)
+		if hasEmptySegment(path) {
+			mergeGroupLeaf(out, key, val)
+
+			continue
+		}
+
+		node := out
+		for _, part := range path[:len(path)-1] {
+			node = groupChild(node, part)
+		}
+
+		mergeGroupLeaf(node, path[len(path)-1], val)
+	}
+
+	return out
+}
+
+// FlattenFields returns a copy of fields with nested maps flattened into
+// keys joined by sep. A GroupValueKey entry takes the key of its map, so
+// grouping the result with GroupFields restores the nested form.
+func FlattenFields(fields map[string]interface{}, sep string) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields))
+	flattenInto(out, "", fields, sep)
+
+	return out
+}
+
+// flattenInto copies m into out with keys prefixed by prefix
+func flattenInto(out map[string]interface{}, prefix string, m map[string]interface{}, sep string) {
+	for key, val := range m {
+		full := key
+
+		switch {
+		case prefix == "":
+		case key == GroupValueKey:
+			full = prefix
+		default:
+			full = prefix + sep + key
+		}
+
+		if nested, ok := val.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenInto(out, full, nested, sep)
+
+			continue
+		}
+
+		out[full] = val
+	}
+}
+
+// groupChild returns the sub-map of node at key, creating it or moving a
+// leaf already stored there under GroupValueKey
+func groupChild(node map[string]interface{}, key string) map[string]interface{} {
+	switch existing := node[key].(type) {
+	case map[string]interface{}:
+		return existing
+	case nil:
+		if _, ok := node[key]; !ok {
+			child := make(map[string]interface{})
+			node[key] = child
+
+			return child
+		}
+	}
+
+	child := map[string]interface{}{GroupValueKey: node[key]}
+	node[key] = child
+
+	return child
+}
+
+// mergeGroupLeaf stores val at key, moving it under GroupValueKey when a
+// sub-map already occupies key and merging it when val is itself a map
+func mergeGroupLeaf(node map[string]interface{}, key string, val interface{}) {
+	existing, ok := node[key].(map[string]interface{})
+	if !ok {
+		if nested, isMap := val.(map[string]interface{}); isMap {
+			val = GroupFields(nested, "")
+		}
+
+		node[key] = val
+
+		return
+	}
+
+	nested, isMap := val.(map[string]interface{})
+	if !isMap {
+		existing[GroupValueKey] = val
+
+		return
+	}
+
+	for k, v := range nested {
+		mergeGroupLeaf(existing, k, v)
+	}
+}
+
+// hasEmptySegment reports whether a split key has an empty part, as in
+// `a..b` or `.a`
+func hasEmptySegment(path []string) bool {
+	for _, part := range path {
+		if part == "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,100 @@
+package logparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name:   "three levels",
+			fields: map[string]interface{}{"http.req.method": "GET", "http.req.path": "/x", "http.status": 200.0, "user": "ada"},
+			want: map[string]interface{}{
+				"http": map[string]interface{}{
+					"req":    map[string]interface{}{"method": "GET", "path": "/x"},
+					"status": 200.0,
+				},
+				"user": "ada",
+			},
+		},
+		{
+			name:   "leaf and prefix",
+			fields: map[string]interface{}{"http": "on", "http.method": "GET", "http.req.id": "1", "http.req": "r"},
+			want: map[string]interface{}{
+				"http": map[string]interface{}{
+					GroupValueKey: "on",
+					"method":      "GET",
+					"req":         map[string]interface{}{GroupValueKey: "r", "id": "1"},
+				},
+			},
+		},
+		{
+			name:   "merges existing object",
+			fields: map[string]interface{}{"db": map[string]interface{}{"name": "shop"}, "db.query_time": 1.5},
+			want:   map[string]interface{}{"db": map[string]interface{}{"name": "shop", "query_time": 1.5}},
+		},
+		{
+			name:   "empty segments kept flat",
+			fields: map[string]interface{}{"a..b": 1.0, ".c": 2.0, "d.": 3.0},
+			want:   map[string]interface{}{"a..b": 1.0, ".c": 2.0, "d.": 3.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GroupFields(tt.fields, ".")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GroupFields() = %v, want %v", got, tt.want)
+			}
+
+			// group -> flatten -> group is stable
+			if again := GroupFields(FlattenFields(got, "."), "."); !reflect.DeepEqual(again, got) {
+				t.Errorf("round trip = %v, want %v", again, got)
+			}
+		})
+	}
+}
+
+func TestGroupFieldsDoesNotMutateInput(t *testing.T) {
+	inner := map[string]interface{}{"name": "shop"}
+
+	GroupFields(map[string]interface{}{"db": inner, "db.table": "orders"}, ".")
+
+	if len(inner) != 1 {
+		t.Errorf("input map modified: %v", inner)
+	}
+}
+
+func TestFlattenFields(t *testing.T) {
+	got := FlattenFields(map[string]interface{}{
+		"http": map[string]interface{}{GroupValueKey: "on", "req": map[string]interface{}{"id": "1"}},
+		"tags": []interface{}{"a"},
+	}, "/")
+
+	want := map[string]interface{}{"http": "on", "http/req/id": "1", "tags": []interface{}{"a"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenFields() = %v, want %v", got, want)
+	}
+}
+
+func TestWithGroupFields(t *testing.T) {
+	parser := NewWithFormat(FormatLogfmt, WithGroupFields("."))
+
+	entries, err := parser.ParseString(`level=info msg=done http.method=GET http.status=200 db.query_time=3ms`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"http": map[string]interface{}{"method": "GET", "status": "200"},
+		"db":   map[string]interface{}{"query_time": "3ms"},
+	}
+	if !reflect.DeepEqual(entries[0].Fields, want) {
+		t.Errorf("Fields = %v, want %v", entries[0].Fields, want)
+	}
+}
@@ -0,0 +1,195 @@
+package logparser
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type tags written before each hashed value so that, for example, the
+// string "1" and the number 1 hash differently
+const (
+	hashNil    = 'z'
+	hashBool   = 'b'
+	hashInt    = 'i'
+	hashFloat  = 'f'
+	hashString = 's'
+	hashTime   = 't'
+	hashArray  = 'a'
+	hashObject = 'o'
+	hashOther  = 'v'
+)
+
+// Hash returns a 64-bit FNV-1a hash of the entry's timestamp, level,
+// message, service, logger, and fields, for recognizing the same entry received twice. The
+// encoding is fixed, so hashes are stable across runs, processes, and
+// architectures. Fields are hashed in key order; numbers are compared by
+// value, so 2, 2.0, and json.Number("2") hash alike. Keys starting with
+// InternalFieldPrefix and LogEntry.Source are not hashed, so an entry
+// parsed from two shipments at different line numbers keeps one identity.
+func (e *LogEntry) Hash() uint64 {
+	h := fnv.New64a()
+
+	if e.Timestamp.IsZero() {
+		writeHashTag(h, hashNil)
+	} else {
+		writeHashTag(h, hashTime)
+		writeHashInt(h, e.Timestamp.UnixNano())
+	}
+
+	writeHashString(h, e.Level)
+	writeHashString(h, e.Message)
+
+	// Written only when set, so entries without them hash as they did
+	// before LogEntry had them
+	if e.Service != "" || e.Logger != "" {
+		writeHashTag(h, hashOther)
+		writeHashString(h, e.Service)
+		writeHashString(h, e.Logger)
+	}
+
+	keys := make([]string, 0, len(e.Fields))
+
+	for k := range e.Fields {
+		if !strings.HasPrefix(k, InternalFieldPrefix) {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	writeHashTag(h, hashObject)
+	writeHashInt(h, int64(len(keys)))
+
+	for _, k := range keys {
+		writeHashString(h, k)
+		writeHashValue(h, e.Fields[k])
+	}
+
+	return h.Sum64()
+}
+
+// DedupeByHash returns the entries with duplicates removed, keeping the
+// first occurrence of each Hash in input order. The input is not modified.
+func DedupeByHash(entries []LogEntry) []LogEntry {
+	seen := make(map[uint64]struct{}, len(entries))
+	result := make([]LogEntry, 0, len(entries))
+
+	for i := range entries {
+		sum := entries[i].Hash()
+		if _, ok := seen[sum]; ok {
+			continue
+		}
+
+		seen[sum] = struct{}{}
+		result = append(result, entries[i])
+	}
+
+	return result
+}
+
+// writeHashValue writes a tagged encoding of a field value
+func writeHashValue(h hash.Hash64, val interface{}) {
+	switch v := val.(type) {
+	case nil:
+		writeHashTag(h, hashNil)
+	case bool:
+		writeHashTag(h, hashBool)
+
+		if v {
+			writeHashInt(h, 1)
+		} else {
+			writeHashInt(h, 0)
+		}
+	case string:
+		writeHashString(h, v)
+	case float64:
+		writeHashNumber(h, v)
+	case float32:
+		writeHashNumber(h, float64(v))
+	case int:
+		writeHashTag(h, hashInt)
+		writeHashInt(h, int64(v))
+	case int64:
+		writeHashTag(h, hashInt)
+		writeHashInt(h, v)
+	case time.Duration:
+		writeHashTag(h, hashInt)
+		writeHashInt(h, int64(v))
+	case json.Number:
+		if f, err := strconv.ParseFloat(string(v), 64); err == nil {
+			writeHashNumber(h, f)
+		} else {
+			writeHashString(h, string(v))
+		}
+	case time.Time:
+		writeHashTag(h, hashTime)
+		writeHashInt(h, v.UnixNano())
+	case []interface{}:
+		writeHashTag(h, hashArray)
+		writeHashInt(h, int64(len(v)))
+
+		for _, item := range v {
+			writeHashValue(h, item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+		writeHashTag(h, hashObject)
+		writeHashInt(h, int64(len(keys)))
+
+		for _, k := range keys {
+			writeHashString(h, k)
+			writeHashValue(h, v[k])
+		}
+	default:
+		writeHashTag(h, hashOther)
+		writeHashString(h, fmt.Sprintf("%v", v))
+	}
+}
+
+// writeHashNumber writes a number so that equal values hash alike: whole
+// numbers as integers, negative zero as zero, and every NaN the same
+func writeHashNumber(h hash.Hash64, f float64) {
+	switch {
+	case f == math.Trunc(f) && math.Abs(f) < 1<<63:
+		writeHashTag(h, hashInt)
+		writeHashInt(h, int64(f))
+	case math.IsNaN(f):
+		writeHashTag(h, hashFloat)
+		writeHashInt(h, int64(math.Float64bits(math.NaN())))
+	default:
+		writeHashTag(h, hashFloat)
+		writeHashInt(h, int64(math.Float64bits(f)))
+	}
+}
+
+// writeHashString writes a length-prefixed string
+func writeHashString(h hash.Hash64, s string) {
+	writeHashTag(h, hashString)
+	writeHashInt(h, int64(len(s)))
+	_, _ = h.Write([]byte(s))
+}
+
+// writeHashTag writes a single type tag byte
+func writeHashTag(h hash.Hash64, tag byte) {
+	_, _ = h.Write([]byte{tag})
+}
+
+// writeHashInt writes n as eight big-endian bytes
+func writeHashInt(h hash.Hash64, n int64) {
+	var buf [8]byte
+
+	binary.BigEndian.PutUint64(buf[:], uint64(n)) //nolint:gosec // the bit pattern is hashed, not interpreted
+	_, _ = h.Write(buf[:])
+}
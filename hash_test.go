@@ -0,0 +1,134 @@
+package logparser
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHashGolden(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 123456789, time.UTC)
+
+	// Fixed values: a change here breaks dedup against hashes stored by
+	// earlier versions, so only update them deliberately
+	tests := []struct {
+		name  string
+		entry LogEntry
+		want  uint64
+	}{
+		{"empty", LogEntry{}, 0x63dfbd993ba16d4c},
+		{"message", LogEntry{Timestamp: ts, Level: LevelInfo, Message: "started"}, 0x7bfdc28394929954},
+		{
+			name: "fields",
+			entry: LogEntry{Timestamp: ts, Level: LevelError, Message: "request failed", Fields: map[string]interface{}{
+				"status":  500.0,
+				"latency": 1.25,
+				"ok":      false,
+				"path":    "/api/v1/users",
+				"tags":    []interface{}{"a", 2.0, nil},
+				"http":    map[string]interface{}{"method": "GET"},
+			}},
+			want: 0x70764428f56efbc9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.Hash(); got != tt.want {
+				t.Errorf("Hash() = %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashEquivalence(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	base := LogEntry{Timestamp: ts, Level: LevelInfo, Message: "m", Fields: map[string]interface{}{"n": 2.0, "s": "x"}}
+
+	same := []LogEntry{
+		{Timestamp: ts.In(time.FixedZone("CET", 3600)), Level: LevelInfo, Message: "m", Fields: map[string]interface{}{"s": "x", "n": 2.0}},
+		{Timestamp: ts, Level: LevelInfo, Message: "m", Fields: map[string]interface{}{"n": json.Number("2"), "s": "x"}},
+		{Timestamp: ts, Level: LevelInfo, Message: "m", Fields: map[string]interface{}{"n": 2, "s": "x", "_ts_key": "time"}},
+		{Timestamp: ts, Level: LevelInfo, Message: "m", Fields: map[string]interface{}{"n": 2.0, "s": "x"}, Source: &Source{Name: "b.log", Line: 9}},
+	}
+
+	for i, e := range same {
+		if e.Hash() != base.Hash() {
+			t.Errorf("entry %d hashes differently from the base entry", i)
+		}
+	}
+
+	different := []LogEntry{
+		{Timestamp: ts, Level: LevelInfo, Message: "m", Fields: map[string]interface{}{"n": "2", "s": "x"}},
+		{Timestamp: ts, Level: LevelInfo, Message: "m", Fields: map[string]interface{}{"n": 2.5, "s": "x"}},
+		{Timestamp: ts, Level: LevelInfo, Message: "m", Fields: map[string]interface{}{"n": 2.0}},
+		{Timestamp: ts, Level: LevelInfo, Message: "m", Fields: map[string]interface{}{"ns": "x", "n": 2.0}},
+		{Timestamp: ts.Add(time.Nanosecond), Level: LevelInfo, Message: "m", Fields: base.Fields},
+		{Level: LevelInfo, Message: "m", Fields: base.Fields},
+		{Timestamp: ts, Level: "WARN", Message: "m", Fields: base.Fields},
+		{Timestamp: ts, Level: LevelInfo, Message: "mm", Fields: base.Fields},
+	}
+
+	for i, e := range different {
+		if e.Hash() == base.Hash() {
+			t.Errorf("entry %d hashes the same as the base entry", i)
+		}
+	}
+
+	nan := LogEntry{Fields: map[string]interface{}{"v": math.NaN()}}
+	if nan.Hash() != nan.Hash() {
+		t.Error("NaN fields do not hash consistently")
+	}
+
+	zero := LogEntry{Fields: map[string]interface{}{"v": 0.0}}
+	negZero := LogEntry{Fields: map[string]interface{}{"v": math.Copysign(0, -1)}}
+
+	if zero.Hash() != negZero.Hash() {
+		t.Error("0 and -0 hash differently")
+	}
+}
+
+func TestDedupeByHash(t *testing.T) {
+	first := `{"time":"2024-01-02T03:04:05Z","level":"info","msg":"a","n":1}
+{"time":"2024-01-02T03:04:06Z","level":"info","msg":"b"}
+{"time":"2024-01-02T03:04:07Z","level":"info","msg":"c"}`
+
+	// The second shipment overlaps the first and re-sends b and c
+	second := `{"time":"2024-01-02T03:04:06Z","level":"info","msg":"b"}
+{"time":"2024-01-02T03:04:07Z","level":"info","msg":"c"}
+{"time":"2024-01-02T03:04:08Z","level":"info","msg":"d"}`
+
+	parser := New(WithSourceName("shipment"))
+
+	a, err := parser.ParseString(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := parser.ParseString(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged := append(append([]LogEntry{}, a...), b...)
+	deduped := DedupeByHash(merged)
+
+	var msgs []string
+	for _, e := range deduped {
+		msgs = append(msgs, e.Message)
+	}
+
+	if got := strings.Join(msgs, ","); got != "a,b,c,d" {
+		t.Errorf("DedupeByHash() = %s, want a,b,c,d", got)
+	}
+
+	if deduped[1].Source.Line != 2 {
+		t.Errorf("kept b from line %d, want the first occurrence on line 2", deduped[1].Source.Line)
+	}
+
+	if len(merged) != 6 {
+		t.Errorf("input modified to %d entries", len(merged))
+	}
+}
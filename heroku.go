@@ -0,0 +1,77 @@
+package logparser
+
+import "time"
+
+// herokuRouterDurations lists the Heroku router fields holding millisecond
+// durations such as "30000ms"
+var herokuRouterDurations = []string{"connect", "service"}
+
+// isHerokuRouter reports whether logfmt pairs are a Heroku router line,
+// which carries its level in at= and names the dyno that served it
+func isHerokuRouter(pairs map[string]interface{}) bool {
+	at, ok := pairs["at"].(string)
+	if !ok {
+		return false
+	}
+
+	if _, ok := lookupLevel(at); !ok {
+		return false
+	}
+
+	_, ok = pairs["dyno"]
+
+	return ok
+}
+
+// extractHerokuRouter fills the entry from a Heroku router line: the level
+// from at=, the message from desc= (set for errors), and connect= and
+// service= as durations, or float64 milliseconds with WithDurationsAsMillis.
+// Router error codes such as H12 stay in Fields["code"].
+func extractHerokuRouter(pairs map[string]interface{}, entry *LogEntry, cfg *config) {
+	if entry.Level == "" {
+		entry.Level = ParseLevel(pairs["at"].(string))
+
+		cfg.consumeKey(pairs, "_level_key", "at")
+	}
+
+	if desc, ok := pairs["desc"].(string); ok && entry.Message == "" {
+		entry.Message = desc
+
+		cfg.consumeKey(pairs, "_msg_key", "desc")
+	}
+
+	for _, key := range herokuRouterDurations {
+		d, ok := parseDurationValue(pairs[key], time.Millisecond)
+		if !ok {
+			continue
+		}
+
+		if cfg.durationMillis {
+			pairs[key] = DurationMillis(d)
+		} else {
+			pairs[key] = d
+		}
+	}
+}
+
+// parseHerokuRouterLine extracts the logfmt pairs of a router line read
+// through the Heroku log drain, after the timestamp and source prefix
+func parseHerokuRouterLine(entry *LogEntry, matches []string, cfg *config) error {
+	if matches[2] != "heroku" || matches[3] != "router" {
+		return nil
+	}
+
+	pairs := decodeLogfmtFragment(entry.Message)
+	if pairs == nil || !isHerokuRouter(pairs) {
+		return nil
+	}
+
+	entry.Message = ""
+	extractHerokuRouter(pairs, entry, cfg)
+
+	for k, v := range pairs {
+		cfg.setField(entry, k, v)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package logparser
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHerokuRouterLogfmt(t *testing.T) {
+	data, err := os.ReadFile("testdata/heroku_router.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := New().ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+
+	if e := entries[0]; e.Level != LevelInfo || e.Message != "" || e.Fields["service"] != 18*time.Millisecond {
+		t.Errorf("entry 0 = %+v", e)
+	}
+
+	e := entries[1]
+	if e.Level != LevelError || e.Message != "Request timeout" || e.Fields["code"] != "H12" {
+		t.Errorf("entry 1 = %+v", e)
+	}
+
+	if e.Fields["connect"] != time.Duration(0) || e.Fields["service"] != 30*time.Second || e.Fields["status"] != "503" {
+		t.Errorf("entry 1 fields = %v", e.Fields)
+	}
+
+	if _, ok := e.Fields["at"]; ok {
+		t.Errorf("at kept in fields: %v", e.Fields)
+	}
+
+	// Empty durations of a crashed dyno are kept as written
+	if e := entries[2]; e.Message != "App crashed" || e.Fields["service"] != "" {
+		t.Errorf("entry 2 = %+v", e)
+	}
+}
+
+func TestHerokuRouterMillis(t *testing.T) {
+	entry, err := NewLineParser(FormatLogfmt, WithDurationsAsMillis(true)).
+		Parse(`at=info method=GET path="/" dyno=web.1 connect=2ms service=1.5s status=200`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Fields["connect"] != 2.0 || entry.Fields["service"] != 1500.0 {
+		t.Errorf("fields = %v, want millisecond floats", entry.Fields)
+	}
+
+	// at= is only a level on router lines
+	entry, err = NewLineParser(FormatLogfmt).Parse(`msg=moved at=error`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Level != LevelInfo || entry.Fields["at"] != "error" {
+		t.Errorf("entry = %+v, want at kept as a field", entry)
+	}
+}
+
+func TestHerokuDrain(t *testing.T) {
+	data, err := os.ReadFile("testdata/heroku.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := New().ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if len(entries) != 6 {
+		t.Fatalf("got %d entries, want 6", len(entries))
+	}
+
+	e := entries[0]
+	if e.Fields["source"] != "app" || e.Fields["dyno"] != "web.1" || e.Message != `Started GET "/api/orders" for 10.1.4.7 at 2024-03-04 09:15:02 +0000` {
+		t.Errorf("entry 0 = %+v", e)
+	}
+
+	if want := time.Date(2024, 3, 4, 9, 15, 2, 481532000, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	// Router lines carry the dyno that served the request
+	e = entries[2]
+	if e.Level != LevelError || e.Message != "Request timeout" || e.Fields["dyno"] != "web.2" || e.Fields["service"] != 30*time.Second {
+		t.Errorf("entry 2 = %+v", e)
+	}
+
+	if e := entries[4]; e.Fields["source"] != "heroku" || e.Message != "Error R14 (Memory quota exceeded)" {
+		t.Errorf("entry 4 = %+v", e)
+	}
+}
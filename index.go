@@ -0,0 +1,297 @@
+package logparser
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Index answers repeated field queries over a parsed slice of entries
+// without rescanning it. Keys are field paths as in CSV columns: "level",
+// "message", and "timestamp" select the entry's own fields and any other
+// key is looked up in Fields (dotted keys descend into nested objects).
+//
+// Values are normalized before indexing so that a key holding strings in
+// some entries and numbers in others still matches: 200, 200.0, and "200"
+// are the same value. An Index is read-only once built and safe for
+// concurrent use.
+type Index struct {
+	entries int
+	keys    map[string]*keyIndex
+}
+
+// IndexStats describes the index of one key
+type IndexStats struct {
+	Key     string `json:"key"`
+	Entries int    `json:"entries"` // Entries carrying the key
+	Values  int    `json:"values"`  // Distinct normalized values
+	Bytes   int    `json:"bytes"`   // Estimated memory held by the key's index
+}
+
+// keyIndex holds the postings of one key: entry indexes per exact value and
+// sorted numeric and time values for range queries
+type keyIndex struct {
+	entries int
+	exact   map[string][]int
+	numbers []indexedNumber
+	times   []indexedTime
+}
+
+type indexedNumber struct {
+	value float64
+	entry int
+}
+
+type indexedTime struct {
+	value time.Time
+	entry int
+}
+
+// BuildIndex indexes the given keys of entries. With no keys, "level" and
+// every top-level key in Fields are indexed; use Stats to see what each
+// costs and rebuild with the keys worth keeping.
+func BuildIndex(entries []LogEntry, keys ...string) *Index {
+	if len(keys) == 0 {
+		keys = indexAllKeys(entries)
+	}
+
+	ix := &Index{entries: len(entries), keys: make(map[string]*keyIndex, len(keys))}
+
+	for _, key := range keys {
+		if _, dup := ix.keys[key]; dup {
+			continue
+		}
+
+		ki := &keyIndex{exact: make(map[string][]int)}
+
+		for i := range entries {
+			val, ok := indexValue(&entries[i], key)
+			if ok {
+				ki.add(i, val)
+			}
+		}
+
+		sort.SliceStable(ki.numbers, func(a, b int) bool { return ki.numbers[a].value < ki.numbers[b].value })
+		sort.SliceStable(ki.times, func(a, b int) bool { return ki.times[a].value.Before(ki.times[b].value) })
+
+		ix.keys[key] = ki
+	}
+
+	return ix
+}
+
+// Lookup returns the indexes of the entries whose key equals value, in
+// entry order. It returns nil if the key was not indexed.
+func (ix *Index) Lookup(key string, value interface{}) []int {
+	ki, ok := ix.keys[key]
+	if !ok {
+		return nil
+	}
+
+	norm, ok := normalizeIndexValue(value)
+	if !ok {
+		return []int{}
+	}
+
+	return append([]int{}, ki.exact[norm]...)
+}
+
+// Range returns the indexes of the entries whose key lies between lo and hi
+// inclusive, in entry order. Bounds are either both time.Time, matching
+// time values and timestamp strings, or both numbers (or numeric strings),
+// matching numeric values. It returns nil if the key was not indexed.
+func (ix *Index) Range(key string, lo, hi interface{}) []int {
+	ki, ok := ix.keys[key]
+	if !ok {
+		return nil
+	}
+
+	var out []int
+
+	if from, isTime := lo.(time.Time); isTime {
+		to, ok := hi.(time.Time)
+		if !ok {
+			return []int{}
+		}
+
+		start := sort.Search(len(ki.times), func(i int) bool { return !ki.times[i].value.Before(from) })
+		for i := start; i < len(ki.times) && !ki.times[i].value.After(to); i++ {
+			out = append(out, ki.times[i].entry)
+		}
+	} else {
+		from, okLo := indexNumber(lo)
+		to, okHi := indexNumber(hi)
+
+		if !okLo || !okHi {
+			return []int{}
+		}
+
+		start := sort.Search(len(ki.numbers), func(i int) bool { return ki.numbers[i].value >= from })
+		for i := start; i < len(ki.numbers) && ki.numbers[i].value <= to; i++ {
+			out = append(out, ki.numbers[i].entry)
+		}
+	}
+
+	sort.Ints(out)
+
+	if out == nil {
+		return []int{}
+	}
+
+	return out
+}
+
+// Len returns the number of entries the index was built over
+func (ix *Index) Len() int {
+	return ix.entries
+}
+
+// Stats reports the size of each key's index, ordered by key
+func (ix *Index) Stats() []IndexStats {
+	stats := make([]IndexStats, 0, len(ix.keys))
+
+	for key, ki := range ix.keys {
+		stats = append(stats, IndexStats{
+			Key:     key,
+			Entries: ki.entries,
+			Values:  len(ki.exact),
+			Bytes:   ki.bytes(),
+		})
+	}
+
+	sort.Slice(stats, func(a, b int) bool { return stats[a].Key < stats[b].Key })
+
+	return stats
+}
+
+// add records that entry i holds val
+func (ki *keyIndex) add(i int, val interface{}) {
+	norm, ok := normalizeIndexValue(val)
+	if !ok {
+		return
+	}
+
+	ki.entries++
+	ki.exact[norm] = append(ki.exact[norm], i)
+
+	if t, ok := indexTime(val); ok {
+		ki.times = append(ki.times, indexedTime{value: t, entry: i})
+	} else if f, ok := indexNumber(val); ok {
+		ki.numbers = append(ki.numbers, indexedNumber{value: f, entry: i})
+	}
+}
+
+// bytes estimates the memory held by the key's index on a 64-bit platform:
+// map entries with their string keys and posting lists, plus the sorted
+// range slices
+func (ki *keyIndex) bytes() int {
+	const (
+		mapEntryOverhead = 16 // Bucket slot and tophash, amortised
+		stringHeader     = 16
+		sliceHeader      = 24
+		intSize          = 8
+		numberSize       = 16 // indexedNumber
+		timeSize         = 32 // indexedTime
+	)
+
+	n := 0
+
+	for value, postings := range ki.exact {
+		n += mapEntryOverhead + stringHeader + len(value) + sliceHeader + cap(postings)*intSize
+	}
+
+	return n + cap(ki.numbers)*numberSize + cap(ki.times)*timeSize
+}
+
+// indexAllKeys lists "level" and the top-level field keys of entries
+func indexAllKeys(entries []LogEntry) []string {
+	seen := map[string]bool{"level": true}
+	keys := []string{"level"}
+
+	for i := range entries {
+		for key := range entries[i].Fields {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys[1:])
+
+	return keys
+}
+
+// indexValue resolves key on an entry
+func indexValue(entry *LogEntry, key string) (interface{}, bool) {
+	switch key {
+	case "timestamp":
+		return entry.Timestamp, !entry.Timestamp.IsZero()
+	case "level":
+		return entry.Level, entry.Level != ""
+	case "message":
+		return entry.Message, true
+	default:
+		return entryField(entry, key)
+	}
+}
+
+// normalizeIndexValue returns the comparable form of a value: times and
+// RFC 3339 strings in UTC RFC 3339, numbers and numeric strings in shortest
+// decimal form, and other values as rendered by formatValue. Nil is not
+// indexed.
+func normalizeIndexValue(val interface{}) (string, bool) {
+	if val == nil {
+		return "", false
+	}
+
+	if t, ok := indexTime(val); ok {
+		return t.UTC().Format(time.RFC3339Nano), true
+	}
+
+	if f, ok := indexNumber(val); ok {
+		return strconv.FormatFloat(f, 'g', -1, 64), true
+	}
+
+	return formatValue(val), true
+}
+
+// indexNumber converts numbers and numeric strings to float64
+func indexNumber(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	default:
+		return 0, false
+	}
+}
+
+// indexTime converts time values and RFC 3339 strings to a time
+func indexTime(val interface{}) (time.Time, bool) {
+	switch v := val.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+
+		return t, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
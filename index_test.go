@@ -0,0 +1,171 @@
+package logparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIndexLookup(t *testing.T) {
+	entries := []LogEntry{
+		{Level: "INFO", Fields: map[string]interface{}{"user_id": 123.0, "http": map[string]interface{}{"status": 200.0}}},
+		{Level: "ERROR", Fields: map[string]interface{}{"user_id": "123", "http": map[string]interface{}{"status": "500"}}},
+		{Level: "INFO", Fields: map[string]interface{}{"user_id": json.Number("124")}},
+		{Level: "INFO", Fields: map[string]interface{}{"user_id": "ada", "flag": true}},
+		{Level: "INFO"},
+	}
+
+	ix := BuildIndex(entries, "user_id", "level", "http.status", "flag")
+
+	tests := []struct {
+		key   string
+		value interface{}
+		want  []int
+	}{
+		{"user_id", 123, []int{0, 1}},
+		{"user_id", "123.0", []int{0, 1}},
+		{"user_id", 124.0, []int{2}},
+		{"user_id", "ada", []int{3}},
+		{"user_id", "nobody", []int{}},
+		{"level", "INFO", []int{0, 2, 3, 4}},
+		{"http.status", 500, []int{1}},
+		{"flag", true, []int{3}},
+		{"service", "api", nil}, // Not indexed
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s=%v", tt.key, tt.value), func(t *testing.T) {
+			if got := ix.Lookup(tt.key, tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lookup(%q, %v) = %v, want %v", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexRange(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+
+	entries := []LogEntry{
+		{Timestamp: base.Add(3 * time.Minute), Fields: map[string]interface{}{"duration": 12.5}},
+		{Timestamp: base, Fields: map[string]interface{}{"duration": "3"}},
+		{Timestamp: base.Add(time.Minute), Fields: map[string]interface{}{"duration": 40}},
+		{Timestamp: base.Add(2 * time.Minute), Fields: map[string]interface{}{"duration": "slow", "seen": "2024-01-02T03:05:00+01:00"}},
+		{Fields: map[string]interface{}{"seen": "2024-01-02T02:30:00Z"}},
+	}
+
+	ix := BuildIndex(entries, "timestamp", "duration", "seen")
+
+	if got, want := ix.Range("duration", 3, 20), []int{0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("numeric Range = %v, want %v", got, want)
+	}
+
+	if got, want := ix.Range("timestamp", base.Add(time.Minute), base.Add(2*time.Minute)), []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("time Range = %v, want %v", got, want)
+	}
+
+	// Timestamp strings are compared as instants, whatever their offset
+	if got, want := ix.Range("seen", base.Add(-2*time.Hour), base), []int{3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("string time Range = %v, want %v", got, want)
+	}
+
+	if got := ix.Lookup("seen", base.Add(-55*time.Minute)); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("Lookup by time = %v, want [3]", got)
+	}
+
+	if got := ix.Range("duration", base, 5); len(got) != 0 {
+		t.Errorf("mixed bounds = %v, want none", got)
+	}
+}
+
+func TestIndexStats(t *testing.T) {
+	entries := []LogEntry{
+		{Level: "INFO", Fields: map[string]interface{}{"user_id": "1", "service": "api"}},
+		{Level: "WARN", Fields: map[string]interface{}{"user_id": "2", "service": "api"}},
+		{Level: "INFO", Fields: map[string]interface{}{"user_id": "3"}},
+	}
+
+	stats := BuildIndex(entries).Stats()
+
+	want := []IndexStats{
+		{Key: "level", Entries: 3, Values: 2},
+		{Key: "service", Entries: 2, Values: 1},
+		{Key: "user_id", Entries: 3, Values: 3},
+	}
+
+	if len(stats) != len(want) {
+		t.Fatalf("Stats() = %+v, want keys %+v", stats, want)
+	}
+
+	for i, w := range want {
+		got := stats[i]
+		if got.Key != w.Key || got.Entries != w.Entries || got.Values != w.Values || got.Bytes <= 0 {
+			t.Errorf("Stats()[%d] = %+v, want %+v with positive Bytes", i, got, w)
+		}
+	}
+}
+
+// indexBenchEntries is the size of the benchmark corpus
+const indexBenchEntries = 1000000
+
+// indexBenchCorpus builds entries spread over 10000 users
+func indexBenchCorpus() []LogEntry {
+	base := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := make([]LogEntry, indexBenchEntries)
+
+	for i := range entries {
+		entries[i] = LogEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Millisecond),
+			Level:     LevelInfo,
+			Fields:    map[string]interface{}{"user_id": float64(i % 10000)},
+		}
+	}
+
+	return entries
+}
+
+func BenchmarkIndexLookup(b *testing.B) {
+	ix := BuildIndex(indexBenchCorpus(), "user_id")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		if len(ix.Lookup("user_id", 123)) != indexBenchEntries/10000 {
+			b.Fatal("wrong result")
+		}
+	}
+}
+
+func BenchmarkIndexLinearScan(b *testing.B) {
+	entries := indexBenchCorpus()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		var found []int
+
+		for i := range entries {
+			if entries[i].Fields["user_id"] == 123.0 {
+				found = append(found, i)
+			}
+		}
+
+		if len(found) != indexBenchEntries/10000 {
+			b.Fatal("wrong result")
+		}
+	}
+}
+
+func BenchmarkBuildIndex(b *testing.B) {
+	entries := indexBenchCorpus()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		BuildIndex(entries, "user_id", "timestamp")
+	}
+}
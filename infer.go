@@ -0,0 +1,107 @@
+package logparser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// inferenceOrder lists levels from most to least severe; the first level
+// with a matching keyword wins
+func inferenceOrder() []string {
+	return []string{"FATAL", LevelError, "WARN", LevelInfo, "DEBUG"}
+}
+
+// defaultLevelKeywords returns the built-in keywords used for level inference
+func defaultLevelKeywords() map[string][]string {
+	return map[string][]string{
+		LevelError: {"panic:", "fatal", "error", "failed", "failure", "exception", "traceback"},
+		"WARN":     {"warning", "warn", "deprecated"},
+	}
+}
+
+// WithLevelInference assigns a level from the message content when a line
+// has no explicit level. Keywords such as "panic:", "error", and "failed"
+// yield ERROR; "warning" and "deprecated" yield WARN. Matching is case
+// insensitive and respects word boundaries.
+func WithLevelInference() Option {
+	return func(c *config) {
+		c.inferLevels = true
+	}
+}
+
+// WithLevelKeywords adds keywords that infer the given level, in addition
+// to the built-in ones. It enables level inference.
+func WithLevelKeywords(level string, keywords ...string) Option {
+	return func(c *config) {
+		c.inferLevels = true
+
+		if c.levelKeywords == nil {
+			c.levelKeywords = defaultLevelKeywords()
+		}
+
+		level = ParseLevel(level)
+		for _, kw := range keywords {
+			c.levelKeywords[level] = append(c.levelKeywords[level], strings.ToLower(kw))
+		}
+	}
+}
+
+// inferLevel returns the most severe level whose keywords appear in msg
+func (c *config) inferLevel(msg string) (string, bool) {
+	keywords := c.levelKeywords
+	if keywords == nil {
+		keywords = defaultLevelKeywords()
+	}
+
+	lower := strings.ToLower(msg)
+
+	for _, level := range inferenceOrder() {
+		for _, kw := range keywords[level] {
+			if containsWord(lower, kw) {
+				return level, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// containsWord reports whether word occurs in s without being part of a
+// longer word. Boundaries are only enforced next to letters and digits, so
+// a keyword like "panic:" matches "panic: boom".
+func containsWord(s, word string) bool {
+	if word == "" {
+		return false
+	}
+
+	first, _ := utf8.DecodeRuneInString(word)
+	last, _ := utf8.DecodeLastRuneInString(word)
+
+	for offset := 0; offset < len(s); {
+		idx := strings.Index(s[offset:], word)
+		if idx < 0 {
+			return false
+		}
+
+		start := offset + idx
+		end := start + len(word)
+
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+
+		if (start == 0 || !isWordRune(first) || !isWordRune(before)) &&
+			(end == len(s) || !isWordRune(last) || !isWordRune(after)) {
+			return true
+		}
+
+		offset = start + 1
+	}
+
+	return false
+}
+
+// isWordRune reports whether r is part of a word
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,100 @@
+package logparser
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLevelInferenceFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/inference.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := NewWithFormat(FormatText, WithLevelInference()).ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	want := map[string]string{
+		"panic: runtime error: index out of range [5] with length 3": "ERROR",
+		"goroutine 1 [running]:":             "INFO",
+		"Traceback (most recent call last):": "ERROR",
+		"Failed to bind port 8080":           "ERROR",
+		"Listening on :8080":                 "INFO",
+		"errorsome handler registered":       "INFO",
+		"main()":                             "INFO",
+		"DeprecationWarning: the imp module is deprecated in favour of importlib": "WARN",
+		"connect() failed (111: Connection refused) while connecting to upstream": "ERROR",
+		"an upstream response is buffered to a temporary file":                    "WARN",
+	}
+
+	found := 0
+
+	for _, e := range entries {
+		level, ok := want[e.Message]
+		if !ok {
+			continue
+		}
+
+		found++
+
+		if e.Level != level {
+			t.Errorf("%q: want level %s, got %s", e.Message, level, e.Level)
+		}
+	}
+
+	if found != len(want) {
+		t.Errorf("matched %d of %d fixture lines", found, len(want))
+	}
+}
+
+func TestLevelInferenceRespectsExplicitLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   string
+	}{
+		{name: "JSON explicit level", format: FormatJSON, input: `{"level":"info","msg":"retry failed, will try again"}`, want: "INFO"},
+		{name: "JSON without level", format: FormatJSON, input: `{"msg":"retry failed, will try again"}`, want: "ERROR"},
+		{name: "logfmt explicit level", format: FormatLogfmt, input: `level=debug msg="exception handler installed"`, want: "DEBUG"},
+		{name: "logfmt without level", format: FormatLogfmt, input: `msg="exception handler installed"`, want: "ERROR"},
+		{name: "text explicit level", format: FormatText, input: `[INFO] error budget at 50%`, want: "INFO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := NewWithFormat(tt.format, WithLevelInference()).ParseString(tt.input)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			if entries[0].Level != tt.want {
+				t.Errorf("want level %s, got %s", tt.want, entries[0].Level)
+			}
+		})
+	}
+}
+
+func TestLevelInferenceDisabledByDefault(t *testing.T) {
+	entries, _ := NewWithFormat(FormatText).ParseString("panic: boom")
+	if entries[0].Level != LevelInfo {
+		t.Errorf("want INFO without inference, got %s", entries[0].Level)
+	}
+}
+
+func TestLevelKeywords(t *testing.T) {
+	parser := NewWithFormat(FormatText, WithLevelKeywords("warn", "throttled"), WithLevelKeywords("fatal", "OOMKilled"))
+
+	entries, err := parser.ParseString("request throttled by upstream\ncontainer OOMKilled\nconnection failed")
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	for i, want := range []string{"WARN", "FATAL", "ERROR"} {
+		if entries[i].Level != want {
+			t.Errorf("entry %d: want %s, got %s", i, want, entries[i].Level)
+		}
+	}
+}
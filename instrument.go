@@ -0,0 +1,133 @@
+package logparser
+
+import (
+	"time"
+)
+
+// Parse phases reported by WithInstrumentation, in the order they are
+// reported
+const (
+	// PhaseScan is reading the input and splitting it into lines, or XML
+	// records for Windows Event XML
+	PhaseScan = "scan"
+	// PhaseDetect is format auto-detection on sample lines
+	PhaseDetect = "detect"
+	// PhaseParse is decoding lines and extracting fields, including the
+	// steps that follow such as enrichment and transforms, but not
+	// timestamp parsing
+	PhaseParse = "parse"
+	// PhaseExtractTS is parsing timestamps
+	PhaseExtractTS = "extract-ts"
+)
+
+// Phase indexes into phaseTimer
+const (
+	phaseScan = iota
+	phaseDetect
+	phaseParse
+	phaseExtractTS
+	phaseCount
+)
+
+// phaseNames are the phase names by index
+var phaseNames = [phaseCount]string{PhaseScan, PhaseDetect, PhaseParse, PhaseExtractTS}
+
+// Instrumentation receives the time a parse call spent in one phase and
+// the number of lines it handled there: lines read for PhaseScan, sample
+// lines for PhaseDetect, lines parsed for PhaseParse, and timestamp
+// parse attempts for PhaseExtractTS
+type Instrumentation func(phase string, d time.Duration, lines int)
+
+// TimingBreakdown is the time a parse call spent in each phase, returned
+// in Stats.Timings under WithTimings
+type TimingBreakdown struct {
+	Scan      time.Duration `json:"scan_ns"`
+	Detect    time.Duration `json:"detect_ns"`
+	Parse     time.Duration `json:"parse_ns"`
+	ExtractTS time.Duration `json:"extract_ts_ns"`
+}
+
+// Total returns the time spent in all phases
+func (b TimingBreakdown) Total() time.Duration {
+	return b.Scan + b.Detect + b.Parse + b.ExtractTS
+}
+
+// WithInstrumentation calls fn once per phase (PhaseScan, PhaseDetect,
+// PhaseParse, and PhaseExtractTS, in that order) when a parse call, or a
+// NewWriter, finishes reading its input, with the time the call spent in
+// the phase, to tell where a slow parse spends its time. The phases are
+// the same for every format; a phase a call did not reach reports zero.
+// Times are summed per call, so the overhead is a clock reading at each
+// phase boundary of each line. May be given more than once. LineParser
+// does not report timings.
+func WithInstrumentation(fn Instrumentation) Option {
+	return func(c *config) {
+		c.instruments = append(c.instruments, fn)
+	}
+}
+
+// WithTimings records the time spent in each phase in Stats.Timings, as
+// WithInstrumentation reports it
+func WithTimings(enable bool) Option {
+	return func(c *config) {
+		c.timings = enable
+	}
+}
+
+// phaseTimer sums the time a run spends in each phase. Its methods do
+// nothing on a nil timer, so uninstrumented runs only pay a nil check.
+type phaseTimer struct {
+	durations [phaseCount]time.Duration
+	counts    [phaseCount]int
+}
+
+// instrumented reports whether runs time their phases
+func (c *config) instrumented() bool {
+	return c.timings || len(c.instruments) > 0
+}
+
+// start returns the start time of a phase
+func (t *phaseTimer) start() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return time.Now()
+}
+
+// stop adds the time since start and n lines to a phase
+func (t *phaseTimer) stop(phase int, start time.Time, n int) {
+	if t == nil {
+		return
+	}
+
+	t.durations[phase] += time.Since(start)
+	t.counts[phase] += n
+}
+
+// report records the timings in stats and passes them to the configured
+// functions. Timestamp parsing happens while lines are parsed, so its
+// time is taken out of the parse phase.
+func (t *phaseTimer) report(c *config, stats *Stats) {
+	if t == nil {
+		return
+	}
+
+	durations := t.durations
+	durations[phaseParse] = max(durations[phaseParse]-durations[phaseExtractTS], 0)
+
+	if c.timings {
+		stats.Timings = &TimingBreakdown{
+			Scan:      durations[phaseScan],
+			Detect:    durations[phaseDetect],
+			Parse:     durations[phaseParse],
+			ExtractTS: durations[phaseExtractTS],
+		}
+	}
+
+	for _, fn := range c.instruments {
+		for phase, name := range phaseNames {
+			fn(name, durations[phase], t.counts[phase])
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package logparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowReader returns one line per Read, sleeping before each
+type slowReader struct {
+	lines []string
+	delay time.Duration
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if len(r.lines) == 0 {
+		return 0, io.EOF
+	}
+
+	time.Sleep(r.delay)
+
+	n := copy(p, r.lines[0])
+	r.lines[0] = r.lines[0][n:]
+
+	if r.lines[0] == "" {
+		r.lines = r.lines[1:]
+	}
+
+	return n, nil
+}
+
+func TestTimingsAttributeSlowReaderToScan(t *testing.T) {
+	const lines, delay = 20, 2 * time.Millisecond
+
+	src := &slowReader{delay: delay}
+	for i := range lines {
+		src.lines = append(src.lines, fmt.Sprintf(`{"time":"2024-01-02T03:04:%02dZ","level":"info","msg":"line %d"}`+"\n", i, i))
+	}
+
+	_, stats, err := ParseWithStats(New(WithTimings(true)), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tb := stats.Timings
+	if tb == nil {
+		t.Fatal("Stats.Timings = nil")
+	}
+
+	if tb.Scan < lines*delay {
+		t.Errorf("Scan = %v, want at least %v", tb.Scan, lines*delay)
+	}
+
+	if rest := tb.Detect + tb.Parse + tb.ExtractTS; rest >= tb.Scan/2 {
+		t.Errorf("detect+parse+extract-ts = %v, not small next to scan %v", rest, tb.Scan)
+	}
+
+	if tb.Total() != tb.Scan+tb.Detect+tb.Parse+tb.ExtractTS {
+		t.Errorf("Total() = %v", tb.Total())
+	}
+
+	if _, stats, _ := ParseWithStats(New(), strings.NewReader(`{"msg":"x"}`)); stats.Timings != nil {
+		t.Errorf("Timings = %+v without WithTimings", stats.Timings)
+	}
+}
+
+// phaseReport is one call of an Instrumentation
+type phaseReport struct {
+	phase string
+	lines int
+}
+
+func TestInstrumentationPhasesAcrossFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		input  string
+		detect int // Lines sampled for detection
+	}{
+		{"json", nil, "{\"time\":\"2024-01-02T03:04:05Z\",\"msg\":\"a\"}\n{\"msg\":\"b\"}\n\n", 2},
+		{"logfmt", []Option{WithFormat(FormatLogfmt)}, "time=2024-01-02T03:04:05Z msg=a\nmsg=b\n", 0},
+		{"text", nil, "2024-01-02 03:04:05,000 INFO [main] App - a\n2024-01-02 03:04:06,000 WARN [main] App - b\n", 2},
+		{"alb", []Option{WithFormat(FormatALB)}, strings.Repeat(`http 2018-07-02T22:23:00Z app/lb 1.2.3.4:1 - 0 0 0 301 - 0 0 "GET / HTTP/1.1"`+"\n", 2), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []phaseReport
+
+			opts := append(tt.opts, WithInstrumentation(func(phase string, d time.Duration, lines int) {
+				if d < 0 {
+					t.Errorf("%s: negative duration %v", phase, d)
+				}
+
+				got = append(got, phaseReport{phase, lines})
+			}))
+
+			entries, err := New(opts...).Parse(strings.NewReader(tt.input))
+			if err != nil || len(entries) != 2 {
+				t.Fatalf("ParseString() = %d entries, %v", len(entries), err)
+			}
+
+			// Blank lines are scanned but not parsed
+			scanned := strings.Count(tt.input, "\n")
+			want := []phaseReport{{PhaseScan, scanned}, {PhaseDetect, tt.detect}, {PhaseParse, 2}, {PhaseExtractTS, 0}}
+
+			if len(got) != len(want) {
+				t.Fatalf("reports = %v, want %v", got, want)
+			}
+
+			for i := range want[:3] {
+				if got[i] != want[i] {
+					t.Errorf("report %d = %v, want %v", i, got[i], want[i])
+				}
+			}
+
+			if got[3].phase != PhaseExtractTS || got[3].lines < 1 {
+				t.Errorf("extract-ts report = %v, want attempts", got[3])
+			}
+		})
+	}
+}
+
+func TestInstrumentationWriter(t *testing.T) {
+	calls := 0
+
+	p := NewWithFormat(FormatJSON, WithInstrumentation(func(string, time.Duration, int) { calls++ }))
+
+	w := NewWriter(p, func(LogEntry) {})
+	fmt.Fprintln(w, `{"msg":"a"}`)
+
+	if calls != 0 {
+		t.Errorf("reported %d phases before Close", calls)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 4 {
+		t.Errorf("reported %d phases, want 4", calls)
+	}
+}
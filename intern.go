@@ -0,0 +1,36 @@
+package logparser
+
+import "strings"
+
+// Interning limits: keys longer than maxInternLen are never cached, and the
+// cache stops growing at maxInternEntries so inputs with unique keys cannot
+// grow it without limit
+const (
+	maxInternLen     = 64
+	maxInternEntries = 1024
+)
+
+// interner returns canonical copies of short, recurring strings such as
+// field keys, so millions of entries share one instance of "service"
+// instead of each holding its own. It belongs to a single parse run or
+// LineParser and is not safe for concurrent use. A nil interner returns
+// strings unchanged.
+type interner map[string]string
+
+// intern returns the cached instance of s, caching a copy of s if there is
+// room. A string that is not cached is returned as is.
+func (in interner) intern(s string) string {
+	if c, ok := in[s]; ok {
+		return c
+	}
+
+	if in == nil || len(s) > maxInternLen || len(in) >= maxInternEntries {
+		return s
+	}
+
+	// Copy so the cache does not keep the line s was sliced from alive
+	c := strings.Clone(s)
+	in[c] = c
+
+	return c
+}
@@ -0,0 +1,93 @@
+// Package testgen generates deterministic log corpora for benchmarks.
+//
+// The same format, line count, and seed always produce byte-identical
+// output, so benchmark numbers can be reproduced across machines.
+package testgen
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Format names accepted by Generate
+const (
+	JSON   = "json"
+	Logfmt = "logfmt"
+	Text   = "text"
+	Mixed  = "mixed"
+)
+
+// DefaultSeed is the seed used by the package benchmarks
+const DefaultSeed = 20240102
+
+// Generation constants
+const (
+	avgLineBytes = 128
+	maxLatencyMS = 5000
+	userIDs      = 100000
+	mixedBlock   = 50 // Lines per format block in mixed corpora
+)
+
+// generator produces random field values from a seeded source
+type generator struct {
+	rng  *rand.Rand
+	base time.Time
+}
+
+// Generate returns n newline-terminated log lines in the given format.
+// Mixed corpora alternate blocks of text, JSON, and logfmt lines; since
+// the first block is text, auto-detection parses the whole corpus as text.
+func Generate(format string, n int, seed uint64) []byte {
+	g := &generator{
+		rng:  rand.New(rand.NewPCG(seed, seed)), //nolint:gosec // deterministic corpora, not security sensitive
+		base: time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC),
+	}
+
+	var b strings.Builder
+
+	b.Grow(n * avgLineBytes)
+
+	formats := []string{Text, JSON, Logfmt}
+
+	for i := range n {
+		f := format
+		if format == Mixed {
+			f = formats[(i/mixedBlock)%len(formats)]
+		}
+
+		g.line(&b, f, i)
+	}
+
+	return []byte(b.String())
+}
+
+// line writes one line in the given format
+func (g *generator) line(b *strings.Builder, format string, i int) {
+	ts := g.base.Add(time.Duration(i) * time.Millisecond)
+	level := g.pick("debug", "info", "info", "info", "warn", "error")
+	service := g.pick("api", "worker", "auth", "billing")
+	msg := g.pick("request processed", "cache miss", "user login", "query executed", "connection reset by peer")
+	latency := g.rng.IntN(maxLatencyMS)
+	user := g.rng.IntN(userIDs)
+
+	switch format {
+	case JSON:
+		fmt.Fprintf(b, `{"timestamp":%q,"level":%q,"message":%q,"service":%q,"latency_ms":%d,"user_id":"u%d","request_id":"%016x"}`,
+			ts.Format(time.RFC3339Nano), level, msg, service, latency, user, g.rng.Uint64())
+	case Logfmt:
+		fmt.Fprintf(b, `time=%s level=%s msg=%q service=%s latency=%dms user_id=u%d request_id=%016x`,
+			ts.Format(time.RFC3339Nano), level, msg, service, latency, user, g.rng.Uint64())
+	default:
+		fmt.Fprintf(b, `%s [%s] %s service=%s latency=%dms user=u%d`,
+			ts.Format("2006-01-02 15:04:05"), strings.ToUpper(level), msg, service, latency, user)
+	}
+
+	b.WriteByte('\n')
+}
+
+// pick returns one of the choices at random
+func (g *generator) pick(choices ...string) string {
+	return choices[g.rng.IntN(len(choices))]
+}
@@ -0,0 +1,25 @@
+package testgen
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenerateDeterministic(t *testing.T) {
+	for _, format := range []string{JSON, Logfmt, Text, Mixed} {
+		a := Generate(format, 500, DefaultSeed)
+		b := Generate(format, 500, DefaultSeed)
+
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s: output differs between runs with the same seed", format)
+		}
+
+		if got := bytes.Count(a, []byte("\n")); got != 500 {
+			t.Errorf("%s: want 500 lines, got %d", format, got)
+		}
+
+		if bytes.Equal(a, Generate(format, 500, DefaultSeed+1)) {
+			t.Errorf("%s: different seeds produced identical output", format)
+		}
+	}
+}
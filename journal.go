@@ -0,0 +1,119 @@
+package logparser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Journal export fields mapped onto LogEntry by journalctl -o json input
+const (
+	journalTimestampKey = "__REALTIME_TIMESTAMP"
+	journalCursorKey    = "__CURSOR"
+	journalPriorityKey  = "PRIORITY"
+	journalMessageKey   = "MESSAGE"
+)
+
+// journalLevels maps syslog priorities 0 (emerg) to 7 (debug) to levels
+var journalLevels = [...]string{
+	"FATAL", "FATAL", "FATAL", LevelError, "WARN", LevelInfo, LevelInfo, "DEBUG",
+}
+
+// WithStripJournalPrefix drops the leading underscores from journal field
+// names kept in Fields, so _SYSTEMD_UNIT is stored as SYSTEMD_UNIT and
+// __MONOTONIC_TIMESTAMP as MONOTONIC_TIMESTAMP. A stripped name that
+// collides with a field already on the entry keeps its underscores.
+func WithStripJournalPrefix(strip bool) Option {
+	return func(c *config) {
+		c.stripJournal = strip
+	}
+}
+
+// isJournalObject reports whether a decoded JSON object is a journalctl
+// export record
+func isJournalObject(raw map[string]interface{}) bool {
+	if _, ok := raw[journalTimestampKey]; ok {
+		return true
+	}
+
+	_, ok := raw[journalCursorKey]
+
+	return ok
+}
+
+// extractJournal maps the journal's timestamp, priority, and message fields
+// onto entry
+func extractJournal(raw map[string]interface{}, entry *LogEntry, cfg *config) {
+	if s, ok := raw[journalTimestampKey].(string); ok {
+		if micros, err := strconv.ParseInt(s, 10, 64); err == nil {
+			entry.Timestamp = time.UnixMicro(micros).UTC()
+
+			cfg.consumeKey(raw, "_ts_key", journalTimestampKey)
+		}
+	}
+
+	if s, ok := raw[journalPriorityKey].(string); ok {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < len(journalLevels) {
+			entry.Level = journalLevels[n]
+
+			cfg.consumeKey(raw, "_level_key", journalPriorityKey)
+		}
+	}
+
+	if msg, ok := journalMessage(raw[journalMessageKey]); ok {
+		entry.Message = msg
+
+		cfg.consumeKey(raw, "_msg_key", journalMessageKey)
+	}
+}
+
+// journalMessage returns MESSAGE as text. The journal exports payloads that
+// are not valid UTF-8 as arrays of byte values; invalid sequences in them
+// become replacement characters.
+func journalMessage(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		buf := make([]byte, len(v))
+
+		for i, b := range v {
+			n, ok := b.(float64)
+			if !ok || n < 0 || n > 255 || n != float64(int(n)) {
+				return "", false
+			}
+
+			buf[i] = byte(n)
+		}
+
+		if utf8.Valid(buf) {
+			return string(buf), true
+		}
+
+		return strings.ToValidUTF8(string(buf), string(utf8.RuneError)), true
+	default:
+		return "", false
+	}
+}
+
+// journalFieldName returns the name a journal field is stored under,
+// dropping leading underscores when stripping is enabled. Only names made
+// of upper-case letters, digits, and underscores are journal fields, so
+// library keys such as _ts_key are left alone.
+func (c *config) journalFieldName(raw map[string]interface{}, key string) string {
+	if !c.stripJournal || !strings.HasPrefix(key, "_") {
+		return key
+	}
+
+	name := strings.TrimLeft(key, "_")
+	if name == "" || strings.TrimLeft(name, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_") != "" {
+		return key
+	}
+
+	if _, taken := raw[name]; taken {
+		return key
+	}
+
+	return name
+}
@@ -0,0 +1,89 @@
+package logparser
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestJournalExport(t *testing.T) {
+	data, err := os.ReadFile("testdata/journal.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := New().ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	wantLevels := []string{"INFO", "ERROR", "WARN", "FATAL", "DEBUG"}
+	if len(entries) != len(wantLevels) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(wantLevels))
+	}
+
+	for i, want := range wantLevels {
+		if entries[i].Level != want {
+			t.Errorf("entry %d: level %s, want %s", i, entries[i].Level, want)
+		}
+	}
+
+	e := entries[0]
+	if want := time.Date(2024, 1, 2, 15, 4, 5, 123456000, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	if e.Message != "Started nginx.service - A high performance web server." {
+		t.Errorf("Message = %q", e.Message)
+	}
+
+	for _, key := range []string{"__REALTIME_TIMESTAMP", "PRIORITY", "MESSAGE"} {
+		if _, ok := e.Fields[key]; ok {
+			t.Errorf("extracted key %s left in Fields", key)
+		}
+	}
+
+	if got := e.Fields["_SYSTEMD_UNIT"]; got != "init.scope" {
+		t.Errorf("Fields[_SYSTEMD_UNIT] = %v, want init.scope", got)
+	}
+
+	if got := entries[4].Message; got != "bad � byte" {
+		t.Errorf("byte array MESSAGE = %q, want replacement character", got)
+	}
+}
+
+func TestWithStripJournalPrefix(t *testing.T) {
+	line := `{"__REALTIME_TIMESTAMP":"1704207845000000","PRIORITY":"6","MESSAGE":"m",` +
+		`"_HOSTNAME":"web-1","__MONOTONIC_TIMESTAMP":"1","_COMM":"nginx","COMM":"user"}`
+
+	entries, err := NewWithFormat(FormatJSON, WithStripJournalPrefix(true), WithPreserveOriginalKeys(true)).ParseString(line)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"HOSTNAME":            "web-1",
+		"MONOTONIC_TIMESTAMP": "1",
+		"COMM":                "user",
+		"_COMM":               "nginx", // Stripping would collide with COMM
+		"_ts_key":             "__REALTIME_TIMESTAMP",
+		"REALTIME_TIMESTAMP":  "1704207845000000",
+	}
+
+	for key, val := range want {
+		if got := entries[0].Fields[key]; got != val {
+			t.Errorf("Fields[%q] = %v, want %v", key, got, val)
+		}
+	}
+}
+
+func TestJournalPriorityOutOfRange(t *testing.T) {
+	entries, err := NewWithFormat(FormatJSON).ParseString(`{"__REALTIME_TIMESTAMP":"1704207845000000","PRIORITY":"9","MESSAGE":"m"}`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if e := entries[0]; e.Level != LevelInfo || e.Fields["PRIORITY"] != "9" {
+		t.Errorf("got level %s fields %v, want INFO with PRIORITY kept", e.Level, e.Fields)
+	}
+}
@@ -3,103 +3,379 @@ package logparser
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
-	"time"
+	"unicode/utf8"
 )
 
-// parseJSON parses JSON formatted logs
-func parseJSON(lines []string) ([]LogEntry, error) {
-	entries := make([]LogEntry, 0, len(lines))
+// maxJSONScanDepth is the nesting depth past which decodeJSONObject defers
+// to encoding/json
+const maxJSONScanDepth = 64
 
-	for _, line := range lines {
-		entry, err := parseJSONLine(line)
-		if err != nil {
-			return nil, err
+// parseJSONLine parses a single JSON log line. Object keys are interned
+// through names.
+func parseJSONLine(line string, cfg *config, names interner) (*LogEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, ErrEmptyLine
+	}
+
+	raw, ok := decodeJSONObject(line, names)
+	if !ok {
+		// encoding/json decides what the odd cases mean and words the errors
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+
+		// decode again keeping the digits of big integers
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.UseNumber()
+
+		raw = nil
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
 		}
 
-		entries = append(entries, *entry)
+		exactNumbers(raw)
 	}
 
-	return entries, nil
+	return jsonEntry(raw, cfg), nil
 }
 
-// parseJSONLine parses a single JSON log line
-func parseJSONLine(line string) (*LogEntry, error) {
-	line = strings.TrimSpace(line)
-	if line == "" {
-		return nil, ErrEmptyLine
+// decodeJSONObject decodes a line holding a single JSON object into the
+// same values encoding/json produces, interning keys and slicing unescaped
+// strings from the line instead of copying them. It reports false for
+// anything it does not handle itself, including all invalid input, so the
+// caller can fall back to encoding/json.
+func decodeJSONObject(line string, keys interner) (map[string]interface{}, bool) {
+	if line == "" || line[0] != '{' || !utf8.ValidString(line) {
+		return nil, false
 	}
 
-	// Parse JSON
-	var raw map[string]interface{}
-	if err := json.Unmarshal([]byte(line), &raw); err != nil {
-		return nil, fmt.Errorf("invalid JSON: %w", err)
+	d := jsonScanner{s: line, keys: keys}
+
+	obj, ok := d.object(0)
+	if !ok {
+		return nil, false
 	}
 
-	entry := &LogEntry{
-		Fields: make(map[string]interface{}),
+	d.skipSpace()
+
+	return obj, d.i == len(d.s)
+}
+
+// jsonScanner decodes JSON values from s starting at offset i
+type jsonScanner struct {
+	s    string
+	i    int
+	keys interner
+}
+
+// skipSpace advances past JSON whitespace
+func (d *jsonScanner) skipSpace() {
+	for d.i < len(d.s) {
+		switch d.s[d.i] {
+		case ' ', '\t', '\n', '\r':
+			d.i++
+		default:
+			return
+		}
 	}
+}
 
-	// Extract standard fields
-	extractJSONTimestamp(raw, entry)
-	extractJSONLevel(raw, entry)
-	extractJSONMessage(raw, entry)
+// value decodes the value at the current offset
+func (d *jsonScanner) value(depth int) (interface{}, bool) {
+	d.skipSpace()
 
-	// Remaining fields go to Fields map
-	for k, v := range raw {
-		entry.Fields[k] = v
+	if d.i == len(d.s) {
+		return nil, false
 	}
 
-	return entry, nil
+	switch c := d.s[d.i]; {
+	case c == '{':
+		return d.object(depth + 1)
+	case c == '[':
+		return d.array(depth + 1)
+	case c == '"':
+		return d.str()
+	case c == '-' || c >= '0' && c <= '9':
+		return d.number()
+	case strings.HasPrefix(d.s[d.i:], "true"):
+		d.i += len("true")
+
+		return true, true
+	case strings.HasPrefix(d.s[d.i:], "false"):
+		d.i += len("false")
+
+		return false, true
+	case strings.HasPrefix(d.s[d.i:], "null"):
+		d.i += len("null")
+
+		return nil, true
+	default:
+		return nil, false
+	}
 }
 
-// extractJSONTimestamp extracts timestamp from various field names
-func extractJSONTimestamp(raw map[string]interface{}, entry *LogEntry) {
-	for _, key := range []string{"timestamp", "time", "@timestamp", "ts"} {
-		if val, ok := raw[key]; ok {
-			if t, err := parseTimestamp(val); err == nil {
-				entry.Timestamp = t
+// object decodes the object starting at the current '{'
+func (d *jsonScanner) object(depth int) (map[string]interface{}, bool) {
+	if depth > maxJSONScanDepth {
+		return nil, false
+	}
 
-				delete(raw, key)
+	d.i++ // Skip '{'
+	obj := make(map[string]interface{})
 
-				return
-			}
+	d.skipSpace()
+
+	if d.i < len(d.s) && d.s[d.i] == '}' {
+		d.i++
+
+		return obj, true
+	}
+
+	for {
+		d.skipSpace()
+
+		if d.i == len(d.s) || d.s[d.i] != '"' {
+			return nil, false
+		}
+
+		key, ok := d.str()
+		if !ok {
+			return nil, false
 		}
+
+		d.skipSpace()
+
+		if d.i == len(d.s) || d.s[d.i] != ':' {
+			return nil, false
+		}
+
+		d.i++
+
+		val, ok := d.value(depth)
+		if !ok {
+			return nil, false
+		}
+
+		obj[d.keys.intern(key)] = val
+
+		d.skipSpace()
+
+		if d.i == len(d.s) {
+			return nil, false
+		}
+
+		switch d.s[d.i] {
+		case ',':
+			d.i++
+		case '}':
+			d.i++
+
+			return obj, true
+		default:
+			return nil, false
+		}
+	}
+}
+
+// array decodes the array starting at the current '['
+func (d *jsonScanner) array(depth int) ([]interface{}, bool) {
+	if depth > maxJSONScanDepth {
+		return nil, false
 	}
-	// Default to current time if no timestamp found
-	if entry.Timestamp.IsZero() {
-		entry.Timestamp = time.Now()
+
+	d.i++ // Skip '['
+	arr := []interface{}{}
+
+	d.skipSpace()
+
+	if d.i < len(d.s) && d.s[d.i] == ']' {
+		d.i++
+
+		return arr, true
+	}
+
+	for {
+		val, ok := d.value(depth)
+		if !ok {
+			return nil, false
+		}
+
+		arr = append(arr, val)
+
+		d.skipSpace()
+
+		if d.i == len(d.s) {
+			return nil, false
+		}
+
+		switch d.s[d.i] {
+		case ',':
+			d.i++
+		case ']':
+			d.i++
+
+			return arr, true
+		default:
+			return nil, false
+		}
 	}
 }
 
-// extractJSONLevel extracts log level from various field names
-func extractJSONLevel(raw map[string]interface{}, entry *LogEntry) {
-	for _, key := range []string{"level", "severity", "log.level"} {
-		if val, ok := raw[key]; ok {
-			if s, ok := val.(string); ok {
-				entry.Level = ParseLevel(s)
+// str decodes the string starting at the current '"'. Strings without
+// escapes are sliced from the input; escaped ones are left to
+// encoding/json.
+func (d *jsonScanner) str() (string, bool) {
+	start := d.i
+	escaped := false
 
-				delete(raw, key)
+	for d.i++; d.i < len(d.s); d.i++ {
+		switch c := d.s[d.i]; {
+		case c == '\\':
+			escaped = true
+			d.i++ // The escaped byte cannot close the string
+		case c == '"':
+			d.i++
 
-				return
+			if !escaped {
+				return d.s[start+1 : d.i-1], true
 			}
+
+			var out string
+			if json.Unmarshal([]byte(d.s[start:d.i]), &out) != nil {
+				return "", false
+			}
+
+			return out, true
+		case c < ' ':
+			return "", false
 		}
 	}
-	// Default level
-	entry.Level = LevelInfo
+
+	return "", false
 }
 
-// extractJSONMessage extracts message from various field names
-func extractJSONMessage(raw map[string]interface{}, entry *LogEntry) {
-	for _, key := range []string{"message", "msg", "log"} {
-		if val, ok := raw[key]; ok {
-			if s, ok := val.(string); ok {
-				entry.Message = s
+// maxExactFloatInt is the largest magnitude up to which every integer is a
+// float64 exactly
+const maxExactFloatInt = 1 << 53
 
-				delete(raw, key)
+// number decodes a number at the current offset, checking it against the
+// JSON grammar, which is stricter than strconv.ParseFloat. Numbers are
+// float64, as encoding/json decodes them, except integer literals beyond
+// the range float64 holds exactly, such as nanosecond epoch timestamps,
+// which are kept as json.Number so no digits are lost.
+func (d *jsonScanner) number() (interface{}, bool) {
+	start := d.i
+	digits := func() bool {
+		n := d.i
+		for d.i < len(d.s) && d.s[d.i] >= '0' && d.s[d.i] <= '9' {
+			d.i++
+		}
 
-				return
-			}
+		return d.i > n
+	}
+
+	if d.s[d.i] == '-' {
+		d.i++
+	}
+
+	if d.i < len(d.s) && d.s[d.i] == '0' {
+		d.i++
+	} else if !digits() {
+		return 0, false
+	}
+
+	integer := true
+
+	if d.i < len(d.s) && d.s[d.i] == '.' {
+		d.i++
+		integer = false
+
+		if !digits() {
+			return 0, false
 		}
 	}
+
+	if d.i < len(d.s) && (d.s[d.i] == 'e' || d.s[d.i] == 'E') {
+		d.i++
+		integer = false
+
+		if d.i < len(d.s) && (d.s[d.i] == '+' || d.s[d.i] == '-') {
+			d.i++
+		}
+
+		if !digits() {
+			return 0, false
+		}
+	}
+
+	f, err := strconv.ParseFloat(d.s[start:d.i], 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if integer && (f >= maxExactFloatInt || f <= -maxExactFloatInt) {
+		return json.Number(d.s[start:d.i]), true
+	}
+
+	return f, true
+}
+
+// exactNumbers turns the json.Number values of v, decoded with UseNumber,
+// into what jsonScanner.number returns for them
+func exactNumbers(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, e := range x {
+			x[k] = exactNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = exactNumbers(e)
+		}
+	case json.Number:
+		f, err := x.Float64()
+		if err != nil || strings.ContainsAny(string(x), ".eE") || (f < maxExactFloatInt && f > -maxExactFloatInt) {
+			return f
+		}
+	}
+
+	return v
+}
+
+// jsonEntry builds an entry from a decoded JSON object
+func jsonEntry(raw map[string]interface{}, cfg *config) *LogEntry {
+	entry := &LogEntry{
+		Fields: cfg.newFields(),
+	}
+
+	journal := isJournalObject(raw)
+
+	// Extract standard fields
+	switch {
+	case journal:
+		extractJournal(raw, entry, cfg)
+	case isMongoObject(raw):
+		extractMongo(raw, entry, cfg)
+	default:
+		keys := jsonStdKeys.scan(raw)
+		extractStdFields(raw, &keys, entry, cfg)
+	}
+
+	cfg.extractSemanticFields(raw, entry)
+
+	// Remaining fields go to Fields map
+	for k, v := range raw {
+		if journal {
+			k = cfg.journalFieldName(raw, k)
+		}
+
+		cfg.setField(entry, k, v)
+	}
+
+	cfg.finishEntry(entry)
+
+	return entry
 }
@@ -0,0 +1,150 @@
+package logparser
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONObjectMatchesEncodingJSON(t *testing.T) {
+	lines := []string{
+		`{}`,
+		`{"a":1,"b":-0.5e3,"c":"x","d":true,"e":false,"f":null}`,
+		`{ "nested" : { "list" : [1, "two", [], {}, null] } , "empty":"" }`,
+		`{"esc":"line\nbreak \"quoted\" é 😀 \/"}`,
+		`{"dup":1,"dup":{"x":2}}`,
+		`{"max":9007199254740991,"big":1.2345678901234567e19,"small":1e-300,"zero":-0}`,
+		`{"unicode":"héllo wörld","tab":	"ok"}`,
+	}
+
+	names := make(interner)
+
+	for _, line := range lines {
+		got, ok := decodeJSONObject(line, names)
+		if !ok {
+			t.Errorf("decodeJSONObject(%s) deferred to encoding/json", line)
+
+			continue
+		}
+
+		var want map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &want); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("decodeJSONObject(%s)\n got %#v\nwant %#v", line, got, want)
+		}
+	}
+
+	// Integers a float64 cannot hold exactly keep their digits
+	got, ok := decodeJSONObject(`{"big":12345678901234567890,"neg":-9007199254740993,"f":12345678901234567890.0}`, names)
+	if !ok || got["big"] != json.Number("12345678901234567890") || got["neg"] != json.Number("-9007199254740993") ||
+		got["f"] != 12345678901234567890.0 {
+		t.Errorf("big numbers = %#v", got)
+	}
+
+	// Anything invalid or unusual is left to encoding/json
+	for _, line := range []string{
+		`{"a":1`, `{"a":1}x`, `{"a":01}`, `{"a":1.}`, `{"a":.5}`, `{"a":+1}`, `{"a":1e999}`, `{"a":tru}`,
+		`{"a":"ctrl` + "\x01" + `"}`, `{"a":"bad` + "\xff" + `"}`, `{a:1}`, `{"a":1,}`, `[1]`, `null`,
+		`{"a":"\x"}`, strings.Repeat(`{"a":`, 100) + "1" + strings.Repeat("}", 100),
+	} {
+		if _, ok := decodeJSONObject(line, names); ok {
+			t.Errorf("decodeJSONObject(%q) accepted input it should defer", line)
+		}
+	}
+}
+
+func TestParseJSONLineErrors(t *testing.T) {
+	if _, err := parseJSONLine(`{"a":}`, &config{}, nil); err == nil || !strings.HasPrefix(err.Error(), "invalid JSON: ") {
+		t.Errorf("parseJSONLine() error = %v", err)
+	}
+
+	// A deeply nested object is still decoded, by encoding/json
+	deep := strings.Repeat(`{"a":`, 100) + `"x"` + strings.Repeat("}", 100)
+	if _, err := parseJSONLine(deep, &config{}, nil); err != nil {
+		t.Errorf("parseJSONLine(deep) error = %v", err)
+	}
+}
+
+func TestInterner(t *testing.T) {
+	names := make(interner)
+	line := `{"service":"api"}`
+
+	first := names.intern(line[2:9])
+	second := names.intern(strings.Clone("service"))
+
+	if first != "service" || second != "service" || len(names) != 1 {
+		t.Fatalf("intern() = %q, %q with %d entries", first, second, len(names))
+	}
+
+	if long := strings.Repeat("k", maxInternLen+1); names.intern(long) != long || len(names) != 1 {
+		t.Error("a key above the length threshold was cached")
+	}
+
+	for i := range 2 * maxInternEntries {
+		names.intern(strings.Repeat("x", i%maxInternLen) + string(rune('a'+i%26)) + string(rune('A'+i/26%26)))
+	}
+
+	if len(names) > maxInternEntries {
+		t.Errorf("cache grew to %d entries, bound %d", len(names), maxInternEntries)
+	}
+
+	var none interner
+	if none.intern("key") != "key" {
+		t.Error("nil interner changed its input")
+	}
+}
+
+func TestLookupLevelDoesNotAllocate(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts differ under the race detector")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if level, ok := lookupLevel("warning"); !ok || level != "WARN" {
+			t.Fatal(level)
+		}
+	})
+
+	if allocs != 0 {
+		t.Errorf("lookupLevel allocates %.0f times", allocs)
+	}
+
+	// Non-ASCII names still upper-case like strings.ToUpper
+	if level, ok := lookupLevel("ınfo"); !ok || level != LevelInfo {
+		t.Errorf("lookupLevel(ınfo) = %q, %v", level, ok)
+	}
+}
+
+func TestParseLevelAliases(t *testing.T) {
+	tests := map[string][]string{
+		"TRACE":    {"trace", "TRC"},
+		"DEBUG":    {"debug", "dbg", "d", "V", "verbose", "DEBUG1", "debug5"},
+		LevelInfo:  {"info", "inf", "I", "Information", "notice", "note", "LOG", "system"},
+		"WARN":     {"warn", "Warning", "wrn", "w"},
+		LevelError: {"error", "err", "E"},
+		"FATAL":    {"fatal", "ftl", "F", "crit", "critical", "alert", "emerg", "emergency", "panic"},
+	}
+
+	for want, names := range tests {
+		for _, name := range names {
+			if got := ParseLevel(name); got != want {
+				t.Errorf("ParseLevel(%q) = %q, want %q", name, got, want)
+			}
+		}
+	}
+
+	// Anything else is INFO, as before the aliases were added
+	for _, name := range []string{"", "X", "DEBUG6", "severe", "ok"} {
+		if _, ok := lookupLevel(name); ok {
+			t.Errorf("lookupLevel(%q) recognized", name)
+		}
+
+		if got := ParseLevel(name); got != LevelInfo {
+			t.Errorf("ParseLevel(%q) = %q, want INFO", name, got)
+		}
+	}
+}
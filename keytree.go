@@ -0,0 +1,112 @@
+package logparser
+
+import (
+	"sort"
+	"strings"
+)
+
+// keyTree is a set of dotted field keys organized by path segment.
+// A key mapped to nil is a leaf that selects the whole value.
+type keyTree map[string]keyTree
+
+// insert adds a dotted key. The full key is also stored as a leaf so that
+// flat keys containing dots (common in logfmt) match literally.
+func (t keyTree) insert(key string) {
+	t[key] = nil
+
+	parts := strings.Split(key, ".")
+	if len(parts) == 1 {
+		return
+	}
+
+	node := t
+
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			node[part] = nil
+
+			return
+		}
+
+		child, ok := node[part]
+		if ok && child == nil {
+			return // A shorter key already selects this whole subtree
+		}
+
+		if !ok {
+			child = make(keyTree)
+			node[part] = child
+		}
+
+		node = child
+	}
+}
+
+// allow returns a copy of m containing only the keys selected by t
+func (t keyTree) allow(m map[string]interface{}) map[string]interface{} {
+	var out map[string]interface{}
+
+	for key, node := range t {
+		val, ok := m[key]
+		if !ok {
+			continue
+		}
+
+		if node != nil {
+			nested, isMap := val.(map[string]interface{})
+			if !isMap {
+				continue
+			}
+
+			nested = node.allow(nested)
+			if len(nested) == 0 {
+				continue
+			}
+
+			val = nested
+		}
+
+		if out == nil {
+			out = make(map[string]interface{})
+		}
+
+		out[key] = val
+	}
+
+	return out
+}
+
+// deny returns a copy of m without the keys selected by t
+func (t keyTree) deny(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+
+	for key, val := range m {
+		node, ok := t[key]
+		if ok && node == nil {
+			continue
+		}
+
+		if nested, isMap := val.(map[string]interface{}); ok && isMap {
+			val = node.deny(nested)
+		}
+
+		out[key] = val
+	}
+
+	return out
+}
+
+// keys returns the dotted keys inserted into the tree, sorted
+func (t keyTree) keys() []string {
+	keys := make([]string, 0, len(t))
+
+	for key, child := range t {
+		if child == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
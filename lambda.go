@@ -0,0 +1,43 @@
+package logparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// lambdaMetricRe matches one "Label: value unit" metric of a Lambda REPORT line
+var lambdaMetricRe = regexp.MustCompile(`([A-Za-z][A-Za-z ]*): ([0-9.]+) (ms|MB)`)
+
+// lambdaMetricFields names the fields of the well-known REPORT metrics.
+// Other metrics are stored under their snake-cased label and unit.
+var lambdaMetricFields = map[string]string{
+	"Duration":         "duration_ms",
+	"Billed Duration":  "billed_duration_ms",
+	"Memory Size":      "memory_mb",
+	"Max Memory Used":  "max_memory_used_mb",
+	"Init Duration":    "init_duration_ms",
+	"Restore Duration": "restore_duration_ms",
+}
+
+// parseLambdaReport stores the metrics of a Lambda REPORT line as float64
+// fields (duration_ms, billed_duration_ms, memory_mb, max_memory_used_mb)
+func parseLambdaReport(entry *LogEntry, matches []string, cfg *config) error {
+	for _, m := range lambdaMetricRe.FindAllStringSubmatch(matches[3], -1) {
+		value, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+
+		label := strings.TrimSpace(m[1])
+
+		key, ok := lambdaMetricFields[label]
+		if !ok {
+			key = strings.ToLower(strings.ReplaceAll(label, " ", "_")) + "_" + strings.ToLower(m[3])
+		}
+
+		cfg.setField(entry, key, value)
+	}
+
+	return nil
+}
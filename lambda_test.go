@@ -0,0 +1,77 @@
+package logparser
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLambdaLogs(t *testing.T) {
+	data, err := os.ReadFile("testdata/lambda.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := New().ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	wantLevels := []string{"INFO", "INFO", "WARN", "ERROR", "INFO", "INFO", "INFO", "ERROR", "DEBUG", "INFO", "INFO"}
+	if len(entries) != len(wantLevels) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(wantLevels))
+	}
+
+	first, second := "8f5c2e1a-3b4d-4c6e-9f0a-1b2c3d4e5f60", "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"
+
+	for i, want := range wantLevels {
+		if entries[i].Level != want {
+			t.Errorf("entry %d: level %s, want %s", i, entries[i].Level, want)
+		}
+
+		wantID := first
+		if i > 5 {
+			wantID = second
+		}
+
+		if got := entries[i].Fields["request_id"]; got != wantID {
+			t.Errorf("entry %d: request_id %v, want %s", i, got, wantID)
+		}
+	}
+
+	app := entries[1]
+	if want := time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC); !app.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", app.Timestamp, want)
+	}
+
+	if app.Message != "Processing order 1234" {
+		t.Errorf("Message = %q", app.Message)
+	}
+
+	if got := entries[0].Fields["version"]; got != "$LATEST" {
+		t.Errorf("START version = %v, want $LATEST", got)
+	}
+
+	if got := entries[4].Fields["lambda_event"]; got != "END" {
+		t.Errorf("lambda_event = %v, want END", got)
+	}
+
+	report := entries[5]
+	wantMetrics := map[string]float64{
+		"duration_ms":        712.34,
+		"billed_duration_ms": 713,
+		"memory_mb":          128,
+		"max_memory_used_mb": 87,
+		"init_duration_ms":   152.08,
+	}
+
+	for key, want := range wantMetrics {
+		if got := report.Fields[key]; got != want {
+			t.Errorf("REPORT %s = %v, want %v", key, got, want)
+		}
+	}
+
+	if python := entries[7]; python.Message != "Unhandled exception in handler" {
+		t.Errorf("Python message = %q", python.Message)
+	}
+}
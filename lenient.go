@@ -0,0 +1,92 @@
+package logparser
+
+import "strings"
+
+// WithLenientJSON accepts JSON lines with the slips of hand-written and
+// templated JSON: "//" comments to the end of the line, "/* */" comments,
+// and trailing commas before "}" or "]". They are blanked out before
+// decoding, outside strings only, so a URL or message containing "//"
+// is kept as it is. Valid JSON is never changed. Auto-detection counts
+// such lines as JSON too. JSON is strict by default.
+func WithLenientJSON(enable bool) Option {
+	return func(c *config) {
+		c.lenientJSON = enable
+	}
+}
+
+// relaxJSON returns s with comments and trailing commas outside strings
+// replaced by spaces, keeping every other byte in place. An unterminated
+// block comment is left for the decoder to reject. s is returned as it is
+// when there is nothing to replace.
+func relaxJSON(s string) string {
+	if !strings.ContainsAny(s, "/,") {
+		return s
+	}
+
+	var out []byte // Copy of s, made on the first replacement
+
+	blank := func(from, to int) {
+		if out == nil {
+			out = []byte(s)
+		}
+
+		for i := from; i < to; i++ {
+			out[i] = ' '
+		}
+	}
+
+	comma := -1 // Offset of a comma with only space and comments after it
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			i = jsonStringEnd(s, i)
+			comma = -1
+		case c == '/' && i+1 < len(s) && s[i+1] == '/':
+			blank(i, len(s))
+			i = len(s)
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				i = len(s)
+
+				break
+			}
+
+			blank(i, i+2+end+2)
+			i += 2 + end + 1
+		case c == ',':
+			comma = i
+		case c == '}' || c == ']':
+			if comma >= 0 {
+				blank(comma, comma+1)
+			}
+
+			comma = -1
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		default:
+			comma = -1
+		}
+	}
+
+	if out == nil {
+		return s
+	}
+
+	return string(out)
+}
+
+// jsonStringEnd returns the offset of the quote closing the string that
+// opens at s[start], or the last offset of s if it is unterminated
+func jsonStringEnd(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return len(s) - 1
+}
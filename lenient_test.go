@@ -0,0 +1,87 @@
+package logparser
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestRelaxJSON(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"a":1,}`, `{"a":1 }`},
+		{`[1, 2, ]`, `[1, 2  ]`},
+		{`{"a":[1,],"b":{"c":2,},}`, `{"a":[1 ],"b":{"c":2 } }`},
+		{`{"a":1} // note`, `{"a":1}        `},
+		{`{/* x */"a":1}`, `{       "a":1}`},
+		{`{"a":1, /* gone */ }`, `{"a":1             }`},
+		{`{"url":"http://x//y"}`, `{"url":"http://x//y"}`},
+		{`{"a":"\"//",}`, `{"a":"\"//" }`},
+		{`{"a":"/* no */"}`, `{"a":"/* no */"}`},
+		{`{"a":1 /* open`, `{"a":1 /* open`},
+	}
+
+	for _, tt := range tests {
+		if got := relaxJSON(tt.in); got != tt.want {
+			t.Errorf("relaxJSON(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLenientJSONFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/templated.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantLevels := []string{"INFO", "WARN", "ERROR", "INFO", "DEBUG"}
+
+	for name, opts := range map[string][]Option{
+		"json": {WithFormat(FormatJSON), WithLenientJSON(true)},
+		"auto": {WithLenientJSON(true)},
+	} {
+		entries, err := New(opts...).ParseString(string(data))
+		if err != nil {
+			t.Fatalf("%s: error = %v", name, err)
+		}
+
+		if len(entries) != len(wantLevels) {
+			t.Fatalf("%s: got %d entries, want %d", name, len(entries), len(wantLevels))
+		}
+
+		for i, want := range wantLevels {
+			if entries[i].Level != want {
+				t.Errorf("%s: entry %d level = %q, want %q", name, i, entries[i].Level, want)
+			}
+		}
+
+		if url := entries[2].Fields["url"]; url != "https://example.com/a//b" {
+			t.Errorf("%s: url = %v", name, url)
+		}
+
+		if msg := entries[3].Message; msg != "text with // and /* inside */" {
+			t.Errorf("%s: message = %q", name, msg)
+		}
+
+		if msg := entries[4].Message; msg != `quote " // still text` {
+			t.Errorf("%s: message = %q", name, msg)
+		}
+	}
+}
+
+func TestLenientJSONStrictByDefault(t *testing.T) {
+	data, err := os.ReadFile("testdata/templated.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, stats, err := ParseWithStats(New(WithFormat(FormatJSON), WithSkipInvalid(true)), bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.LinesSkipped != 5 {
+		t.Errorf("strict parse skipped %d lines, want 5", stats.LinesSkipped)
+	}
+}
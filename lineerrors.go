@@ -0,0 +1,120 @@
+package logparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxLineErrorText caps the line text kept in a LineError
+const maxLineErrorText = 200
+
+// WithMaxErrors collects up to n line errors instead of aborting on the
+// first one. Parsing stops once n lines have failed (or continues, with
+// WithSkipInvalid) and the entries that did parse are returned together
+// with a *LineErrors. The default of 1 keeps the strict behavior of
+// returning the first error alone.
+func WithMaxErrors(n int) Option {
+	return func(c *config) {
+		c.maxErrors = n
+	}
+}
+
+// LineError describes a line that failed to parse or transform
+type LineError struct {
+	Source string // Source name, if any
+	Line   int    // 1-based line number
+	Offset int64  // Byte offset of the line start
+	Text   string // The line, truncated to 200 bytes
+	Err    error
+}
+
+func (e *LineError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("%s:%d: %v", e.Source, e.Line, e.Err)
+	}
+
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// LineErrors is returned when WithMaxErrors is set and lines failed
+type LineErrors struct {
+	Errors []*LineError
+}
+
+func (e *LineErrors) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, le := range e.Errors {
+		msgs = append(msgs, le.Error())
+	}
+
+	return fmt.Sprintf("%d lines failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the individual line errors for errors.Is and errors.As
+func (e *LineErrors) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, le := range e.Errors {
+		errs[i] = le
+	}
+
+	return errs
+}
+
+// collectsErrors reports whether line errors are accumulated
+func (c *config) collectsErrors() bool {
+	return c.maxErrors > 1
+}
+
+// lineFailed handles a line that failed to parse or transform, returning
+// the error that should abort the run, if any
+func (r *parseRun) lineFailed(line string, pos linePos, err error) error {
+	cfg := r.cfg
+
+	if !cfg.collectsErrors() {
+		if cfg.skipInvalid {
+			r.skip(pos, err)
+
+			return nil
+		}
+
+		return err
+	}
+
+	r.skip(pos, err)
+
+	if len(r.errs) < cfg.maxErrors {
+		if len(line) > maxLineErrorText {
+			line = line[:maxLineErrorText]
+		}
+
+		r.errs = append(r.errs, &LineError{Source: r.source, Line: pos.line, Offset: pos.offset, Text: line, Err: err})
+	}
+
+	if len(r.errs) >= cfg.maxErrors && !cfg.skipInvalid {
+		r.aborted = true
+	}
+
+	return nil
+}
+
+// lineErrors returns the collected line errors, or nil if there were none
+func (r *parseRun) lineErrors() error {
+	if len(r.errs) == 0 {
+		return nil
+	}
+
+	return &LineErrors{Errors: r.errs}
+}
+
+// skip counts a dropped line, keeping its reason when the run feeds a Report
+func (r *parseRun) skip(pos linePos, err error) {
+	r.stats.LinesSkipped++
+
+	if r.skips != nil && len(r.skips) < maxReportSkips {
+		r.skips = append(r.skips, SkippedLine{Line: pos.line, Offset: pos.offset, Reason: err.Error()})
+	}
+}
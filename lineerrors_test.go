@@ -0,0 +1,124 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// jsonWithBrokenLines returns n JSON lines, with the given 1-based lines broken
+func jsonWithBrokenLines(n int, broken ...int) string {
+	var b strings.Builder
+
+	for i := 1; i <= n; i++ {
+		if slices.Contains(broken, i) {
+			fmt.Fprintf(&b, "{\"msg\":\"line %d\"\n", i)
+
+			continue
+		}
+
+		fmt.Fprintf(&b, "{\"level\":\"info\",\"msg\":\"line %d\"}\n", i)
+	}
+
+	return b.String()
+}
+
+func TestMaxErrorsCollectsAllLineErrors(t *testing.T) {
+	input := jsonWithBrokenLines(1000, 17, 503, 998)
+
+	entries, stats, err := ParseWithStats(NewWithFormat(FormatJSON, WithMaxErrors(100)), strings.NewReader(input))
+
+	var lineErrs *LineErrors
+	if !errors.As(err, &lineErrs) {
+		t.Fatalf("ParseWithStats() error = %v, want *LineErrors", err)
+	}
+
+	lines := make([]int, 0, len(lineErrs.Errors))
+	for _, le := range lineErrs.Errors {
+		lines = append(lines, le.Line)
+	}
+
+	if !reflect.DeepEqual(lines, []int{17, 503, 998}) {
+		t.Errorf("failed lines = %v, want [17 503 998]", lines)
+	}
+
+	if len(entries) != 997 || stats.LinesSkipped != 3 {
+		t.Errorf("got %d entries, %d skipped", len(entries), stats.LinesSkipped)
+	}
+
+	if !strings.HasPrefix(lineErrs.Errors[0].Text, `{"msg":"line 17"`) {
+		t.Errorf("Text = %q", lineErrs.Errors[0].Text)
+	}
+
+	var lineErr *LineError
+	if !errors.As(err, &lineErr) || lineErr.Line != 17 {
+		t.Errorf("errors.As(*LineError) = %v", lineErr)
+	}
+}
+
+func TestMaxErrorsCap(t *testing.T) {
+	input := jsonWithBrokenLines(10, 2, 4, 6, 8)
+
+	entries, err := NewWithFormat(FormatJSON, WithMaxErrors(2)).ParseString(input)
+
+	var lineErrs *LineErrors
+	if !errors.As(err, &lineErrs) || len(lineErrs.Errors) != 2 {
+		t.Fatalf("ParseString() error = %v, want 2 line errors", err)
+	}
+
+	// Parsing stops at the second failure on line 4
+	if len(entries) != 2 {
+		t.Errorf("got %d entries, want 2", len(entries))
+	}
+
+	// With skip-invalid the run continues past the cap
+	entries, err = NewWithFormat(FormatJSON, WithMaxErrors(2), WithSkipInvalid(true)).ParseString(input)
+	if !errors.As(err, &lineErrs) || len(lineErrs.Errors) != 2 || len(entries) != 6 {
+		t.Errorf("got %d entries, error %v", len(entries), err)
+	}
+}
+
+func TestMaxErrorsDefaultIsStrict(t *testing.T) {
+	entries, err := NewWithFormat(FormatJSON).ParseString(jsonWithBrokenLines(5, 3))
+
+	var lineErrs *LineErrors
+	if err == nil || errors.As(err, &lineErrs) || entries != nil {
+		t.Errorf("want the first error alone, got %v (%d entries)", err, len(entries))
+	}
+}
+
+func TestMaxErrorsAcrossFormats(t *testing.T) {
+	reject := func(e *LogEntry) error {
+		if e.Message == "b" {
+			return errors.New("rejected")
+		}
+
+		return nil
+	}
+
+	tests := []struct {
+		format Format
+		input  string
+	}{
+		{FormatLogfmt, "level=info msg=a\nlevel=info msg=b\nlevel=info msg=c"},
+		{FormatText, "[INFO] a\n[INFO] b\n[INFO] c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			entries, err := NewWithFormat(tt.format, WithMaxErrors(10), WithTransform(reject)).ParseString(tt.input)
+
+			var lineErrs *LineErrors
+			if !errors.As(err, &lineErrs) || len(lineErrs.Errors) != 1 || lineErrs.Errors[0].Line != 2 {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			if len(entries) != 2 {
+				t.Errorf("got %d entries, want 2", len(entries))
+			}
+		})
+	}
+}
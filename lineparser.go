@@ -0,0 +1,127 @@
+package logparser
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// LineParser parses one line per call, for callers that split input
+// themselves. It keeps the line parsers it builds between calls, so
+// repeated use skips the per-call setup of Parser.ParseString.
+//
+// A LineParser is not safe for concurrent use; give each goroutine its own
+// with Clone.
+type LineParser struct {
+	format   Format
+	cfg      *config
+	parsers  map[Format]lineParseFunc
+	detector Detector
+	last     Format // Format of the last line parsed
+}
+
+// NewLineParser creates a line parser for format. With FormatAuto each
+// line is classified on its own as JSON, logfmt, or text. Options that act
+// on a whole input (sampling, limits, error collection, detection) have no
+// effect.
+func NewLineParser(format Format, opts ...Option) *LineParser {
+	cfg := newConfig(opts)
+
+	return &LineParser{
+		format:  format,
+		cfg:     &cfg,
+		parsers: make(map[Format]lineParseFunc, 1),
+	}
+}
+
+// ParseLine parses a single line in format with default options. Use a
+// LineParser to parse many lines.
+func ParseLine(line string, format Format) (LogEntry, error) {
+	return NewLineParser(format).Parse(line)
+}
+
+// ParseLineAuto parses a single line, detecting its format from the line
+// alone
+func ParseLineAuto(line string) (LogEntry, error) {
+	return NewLineParser(FormatAuto).Parse(line)
+}
+
+// Clone returns an independent line parser with the same format and
+// options
+func (lp *LineParser) Clone() *LineParser {
+	return &LineParser{
+		format:  lp.format,
+		cfg:     lp.cfg,
+		parsers: make(map[Format]lineParseFunc, len(lp.parsers)),
+	}
+}
+
+// Parse parses line into an entry. Blank lines return ErrEmptyLine. A line
+// that holds several entries, such as a Fluentd forward batch, returns the
+// first. Continuation lines are returned as entries of their own.
+func (lp *LineParser) Parse(line string) (LogEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return LogEntry{}, ErrEmptyLine
+	}
+
+	if !utf8.ValidString(line) {
+		line = strings.ToValidUTF8(line, string(utf8.RuneError))
+	}
+
+	entries, err := lp.parse(line)
+
+	switch {
+	case errors.Is(err, errContinuation):
+		lp.cfg.finishEntry(entries[0])
+	case err != nil:
+		return LogEntry{}, err
+	case len(entries) == 0:
+		return LogEntry{}, ErrEmptyLine
+	}
+
+	lp.cfg.keepRawLine(entries[0], line)
+
+	var stats Stats
+
+	if err := lp.cfg.postProcess(entries[0], &stats); err != nil {
+		return LogEntry{}, err
+	}
+
+	return *entries[0], nil
+}
+
+// parse runs the line parser for the line's format
+func (lp *LineParser) parse(line string) ([]*LogEntry, error) {
+	lp.last = lp.format
+	if lp.format != FormatAuto {
+		return lp.parser(lp.format)(line)
+	}
+
+	// Parsing JSON directly avoids decoding it twice to classify it first
+	if line[0] == '{' || line[0] == '[' {
+		if entries, err := lp.parser(FormatJSON)(line); err == nil {
+			lp.last = FormatJSON
+
+			return entries, nil
+		}
+	}
+
+	lp.last = FormatText
+	if lp.detector.isLogfmt(line) {
+		lp.last = FormatLogfmt
+	}
+
+	return lp.parser(lp.last)(line)
+}
+
+// parser returns the line parser for format, building it on first use
+func (lp *LineParser) parser(format Format) lineParseFunc {
+	parse, ok := lp.parsers[format]
+	if !ok {
+		parse = lineParserFor(format, lp.cfg)
+		lp.parsers[format] = parse
+	}
+
+	return parse
+}
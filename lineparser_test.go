@@ -0,0 +1,175 @@
+package logparser
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// singleLines are one line of each format, shared by the tests and benchmarks
+var singleLines = []struct {
+	format Format
+	line   string
+}{
+	{FormatJSON, `{"timestamp":"2024-01-02T15:04:05Z","level":"ERROR","message":"Database connection failed","service":"api"}`},
+	{FormatLogfmt, `time=2024-01-02T15:04:05Z level=error msg="Connection timeout" service=worker duration=1.23`},
+	{FormatText, `2024-01-02 15:04:05 [ERROR] Failed to connect to database`},
+}
+
+func TestParseLineMatchesParseString(t *testing.T) {
+	for _, tt := range singleLines {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			want, err := NewWithFormat(tt.format).ParseString(tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, parse := range map[string]func(string) (LogEntry, error){
+				"ParseLine":     func(s string) (LogEntry, error) { return ParseLine(s, tt.format) },
+				"ParseLineAuto": ParseLineAuto,
+			} {
+				got, err := parse(tt.line)
+				if err != nil {
+					t.Fatalf("%s() error = %v", name, err)
+				}
+
+				if !got.Timestamp.Equal(want[0].Timestamp) || got.Level != want[0].Level || got.Message != want[0].Message ||
+					len(got.Fields) != len(want[0].Fields) {
+					t.Errorf("%s() = %+v, want %+v", name, got, want[0])
+				}
+			}
+		})
+	}
+}
+
+func TestLineParser(t *testing.T) {
+	lp := NewLineParser(FormatAuto, WithFieldDenylist("service"), WithTransform(func(e *LogEntry) error {
+		if e.Message == "reject" {
+			return errors.New("rejected")
+		}
+
+		return nil
+	}))
+
+	// Each line is classified on its own
+	for _, tt := range singleLines {
+		entry, err := lp.Parse("  " + tt.line + "\n")
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", tt.format, err)
+		}
+
+		if entry.Level != LevelError {
+			t.Errorf("Parse(%s).Level = %q", tt.format, entry.Level)
+		}
+
+		if _, ok := entry.Fields["service"]; ok {
+			t.Errorf("Parse(%s) kept a denied field", tt.format)
+		}
+	}
+
+	// A line that looks like JSON but is not falls back to text
+	if entry, err := lp.Parse("{not json"); err != nil || entry.Message != "{not json" {
+		t.Errorf("Parse(malformed JSON) = %+v, %v", entry, err)
+	}
+
+	if _, err := lp.Parse("   "); !errors.Is(err, ErrEmptyLine) {
+		t.Errorf("Parse(blank) error = %v, want ErrEmptyLine", err)
+	}
+
+	if _, err := lp.Parse(`{"msg":"reject"}`); err == nil {
+		t.Error("Parse() ignored a transform error")
+	}
+
+	if _, err := NewLineParser(FormatJSON).Parse("{broken"); err == nil {
+		t.Error("explicit JSON parse of a broken line succeeded")
+	}
+
+	// Fluentd batches return their first entry
+	batch := `["app",[[1704207845,{"log":"first"}],[1704207846,{"log":"second"}]]]`
+	if entry, err := ParseLine(batch, FormatJSON); err != nil || entry.Message != "first" {
+		t.Errorf("ParseLine(batch) = %+v, %v", entry, err)
+	}
+
+	event := `<Event><System><EventID>4625</EventID><Level>2</Level></System></Event>`
+	if entry, err := ParseLine(event, FormatWindowsEventXML); err != nil || entry.Fields["event_id"] != 4625 {
+		t.Errorf("ParseLine(event) = %+v, %v", entry, err)
+	}
+}
+
+func TestLineParserClone(t *testing.T) {
+	lp := NewLineParser(FormatAuto)
+
+	var wg sync.WaitGroup
+
+	for range 8 {
+		clone := lp.Clone()
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 200 {
+				for _, tt := range singleLines {
+					if _, err := clone.Parse(tt.line); err != nil {
+						t.Error(err)
+
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func BenchmarkSingleLine(b *testing.B) {
+	for _, tt := range singleLines {
+		b.Run(tt.format.String()+"/ParseString", func(b *testing.B) {
+			parser := New()
+
+			b.ReportAllocs()
+
+			for range b.N {
+				if _, err := parser.ParseString(tt.line); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(tt.format.String()+"/LineParserAuto", func(b *testing.B) {
+			lp := NewLineParser(FormatAuto)
+
+			b.ReportAllocs()
+
+			for range b.N {
+				if _, err := lp.Parse(tt.line); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(tt.format.String()+"/ParseLineAuto", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for range b.N {
+				if _, err := ParseLineAuto(tt.line); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(tt.format.String()+"/LineParser", func(b *testing.B) {
+			lp := NewLineParser(tt.format)
+
+			b.ReportAllocs()
+
+			for range b.N {
+				if _, err := lp.Parse(tt.line); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,149 +1,260 @@
 package logparser
 
 import (
+	"slices"
+	"strconv"
 	"strings"
-	"time"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
-// parseLogfmt parses logfmt formatted logs
-func parseLogfmt(lines []string) ([]LogEntry, error) {
-	entries := make([]LogEntry, 0, len(lines))
-
-	for _, line := range lines {
-		entry, err := parseLogfmtLine(line)
-		if err != nil {
-			return nil, err
-		}
-
-		entries = append(entries, *entry)
-	}
-
-	return entries, nil
-}
-
-// parseLogfmtLine parses a single logfmt line
-func parseLogfmtLine(line string) (*LogEntry, error) {
+// parseLogfmtLine parses a single logfmt line. Keys are interned through
+// names.
+func parseLogfmtLine(line string, cfg *config, names interner) (*LogEntry, error) {
 	line = strings.TrimSpace(line)
 	if line == "" {
 		return nil, ErrEmptyLine
 	}
 
 	entry := &LogEntry{
-		Fields: make(map[string]interface{}),
-		Level:  "INFO", // Default level
+		Fields: cfg.newFields(),
 	}
 
 	// Parse key=value pairs
-	pairs := parseLogfmtPairs(line)
+	pairs := parseLogfmtPairs(line, names)
 
 	// Extract standard fields
-	extractLogfmtTimestamp(pairs, entry)
-	extractLogfmtLevel(pairs, entry)
-	extractLogfmtMessage(pairs, entry)
+	keys := logfmtStdKeys.scan(pairs)
+	extractStdFields(pairs, &keys, entry, cfg)
+
+	// Router lines have a service= duration, and name no service
+	if isHerokuRouter(pairs) {
+		extractHerokuRouter(pairs, entry, cfg)
+	} else {
+		cfg.extractSemanticFields(pairs, entry)
+	}
+
+	if cfg.keyOrder {
+		cfg.setField(entry, keyOrderField, logfmtKeyOrder(line))
+	}
 
 	// Remaining pairs go to Fields
 	for k, v := range pairs {
-		entry.Fields[k] = v
+		cfg.setField(entry, k, v)
 	}
 
-	// Default timestamp if not found
-	if entry.Timestamp.IsZero() {
-		entry.Timestamp = time.Now()
-	}
+	cfg.finishEntry(entry)
 
 	return entry, nil
 }
 
-// parseLogfmtPairs parses key=value pairs from a line
-func parseLogfmtPairs(line string) map[string]interface{} {
-	pairs := make(map[string]interface{})
+// keyOrderField holds the keys of a logfmt line in the order written
+const keyOrderField = "_key_order"
+
+// WithPreserveOrder records the keys of each logfmt line, in the order
+// they were written, in Fields["_key_order"] as a []string. Convert with
+// WithKeyOrder and Replay write fields in that order, so a parsed line can
+// be re-emitted as it was. A key repeated in the line is listed once, at
+// its first position. Other formats are unaffected.
+func WithPreserveOrder(preserve bool) Option {
+	return func(c *config) {
+		c.keyOrder = preserve
+	}
+}
 
-	var key string
+// logfmtKeyOrder returns the distinct keys of a logfmt line in order
+func logfmtKeyOrder(line string) []string {
+	var order []string
 
-	var value strings.Builder
+	scanLogfmt(line, func(key, _ string, _ bool) {
+		if !slices.Contains(order, key) {
+			order = append(order, key)
+		}
+	})
 
-	inQuotes := false
-	inKey := true
+	return order
+}
 
-	for i := range len(line) {
-		ch := line[i]
+// parseLogfmtPairs parses key=value pairs from a line, interning keys
+// through keys. Bare keys map to an empty string.
+func parseLogfmtPairs(line string, keys interner) map[string]interface{} {
+	pairs := make(map[string]interface{})
 
-		switch {
-		case ch == '=' && inKey && !inQuotes:
-			inKey = false
+	scanLogfmt(line, func(key, value string, _ bool) {
+		pairs[keys.intern(key)] = value
+	})
 
-		case ch == '"' && !inKey:
-			if i > 0 && line[i-1] != '\\' {
-				inQuotes = !inQuotes
-			} else {
-				value.WriteByte(ch)
-			}
+	return pairs
+}
 
-		case ch == ' ' && !inQuotes && !inKey:
-			// End of value
-			if key != "" {
-				pairs[key] = value.String()
+// scanLogfmt calls fn for every key in line, following the go-kit logfmt
+// grammar: keys run up to '=' or whitespace, unquoted values up to
+// whitespace, and quoted values up to the closing quote, after which the
+// next key may start immediately. Only the first '=' after a key starts a
+// value, so unquoted values may contain '=', as URLs and base64 padding
+// do. Keys may be quoted, as some encoders write keys with spaces. Escapes
+// in quoted keys and values are decoded; malformed input is kept as written
+// rather than rejected. go-logfmt rejects '=' in unquoted values and quoted
+// keys. bare reports a key with no '='.
+func scanLogfmt(line string, fn func(key, value string, bare bool)) {
+	i := 0
+
+	for i < len(line) {
+		if line[i] <= ' ' {
+			i++
+
+			continue
+		}
+
+		var key string
+
+		if line[i] == '"' {
+			key, i = scanLogfmtQuoted(line, i)
+		} else {
+			start := i
+			for i < len(line) && line[i] != '=' && line[i] > ' ' {
+				i++
 			}
 
-			key = ""
+			key = line[start:i]
+		}
 
-			value.Reset()
+		if i == len(line) || line[i] != '=' {
+			fn(key, "", true)
 
-			inKey = true
+			continue
+		}
 
-		case inKey:
-			key += string(ch)
+		i++ // Skip '='
 
-		default:
-			value.WriteByte(ch)
+		if i == len(line) || line[i] != '"' {
+			start := i
+			for i < len(line) && line[i] > ' ' {
+				i++
+			}
+
+			fn(key, line[start:i], false)
+
+			continue
 		}
+
+		var value string
+
+		value, i = scanLogfmtQuoted(line, i)
+		fn(key, value, false)
 	}
+}
 
-	// Handle last pair
-	if key != "" {
-		pairs[key] = value.String()
+// scanLogfmtQuoted decodes the quoted value starting at line[i] and returns
+// it with the offset just past the closing quote. An unterminated value
+// runs to the end of the line.
+func scanLogfmtQuoted(line string, i int) (string, int) {
+	start := i + 1
+	escaped := false
+
+	for i = start; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			escaped = true
+			i++ // The escaped byte cannot close the value
+		case '"':
+			return unquoteLogfmt(line[start:i], escaped), i + 1
+		}
 	}
 
-	return pairs
+	end := min(i, len(line))
+
+	return unquoteLogfmt(line[start:end], escaped), end
 }
 
-// extractLogfmtTimestamp extracts timestamp from logfmt pairs
-func extractLogfmtTimestamp(pairs map[string]interface{}, entry *LogEntry) {
-	for _, key := range []string{"timestamp", "time", "ts"} {
-		if val, ok := pairs[key]; ok {
-			if t, err := parseTimestamp(val); err == nil {
-				entry.Timestamp = t
+// unquoteLogfmt decodes the JSON-style escapes go-kit writes: \" \\ \/ \b
+// \f \n \r \t and \uXXXX, including surrogate pairs. Unknown escapes are
+// kept verbatim.
+func unquoteLogfmt(s string, escaped bool) string {
+	if !escaped {
+		return s
+	}
 
-				delete(pairs, key)
+	var b strings.Builder
 
-				return
-			}
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+
+			continue
 		}
-	}
-}
 
-// extractLogfmtLevel extracts log level from logfmt pairs
-func extractLogfmtLevel(pairs map[string]interface{}, entry *LogEntry) {
-	if val, ok := pairs["level"]; ok {
-		if s, ok := val.(string); ok {
-			entry.Level = ParseLevel(s)
+		i++
+
+		switch s[i] {
+		case '"', '\\', '/':
+			b.WriteByte(s[i])
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			r, n := decodeLogfmtRune(s[i+1:])
+			if n == 0 {
+				b.WriteString(`\u`)
+
+				continue
+			}
 
-			delete(pairs, "level")
+			b.WriteRune(r)
+			i += n
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
 		}
 	}
+
+	return b.String()
 }
 
-// extractLogfmtMessage extracts message from logfmt pairs
-func extractLogfmtMessage(pairs map[string]interface{}, entry *LogEntry) {
-	for _, key := range []string{"msg", "message"} {
-		if val, ok := pairs[key]; ok {
-			if s, ok := val.(string); ok {
-				entry.Message = s
+// decodeLogfmtRune decodes the hex digits of a \u escape, joining a
+// following low surrogate escape. It returns the bytes consumed, or 0 if s
+// does not start with four hex digits.
+func decodeLogfmtRune(s string) (rune, int) {
+	r := hexRune(s)
+	if r < 0 {
+		return 0, 0
+	}
 
-				delete(pairs, key)
+	if !utf16.IsSurrogate(r) {
+		return r, 4
+	}
 
-				return
+	if len(s) >= 10 && s[4] == '\\' && s[5] == 'u' {
+		if r2 := hexRune(s[6:]); r2 >= 0 {
+			if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+				return dec, 10
 			}
 		}
 	}
+
+	return utf8.RuneError, 4
+}
+
+// hexRune parses four hex digits, returning -1 if s is too short or invalid
+func hexRune(s string) rune {
+	if len(s) < 4 {
+		return -1
+	}
+
+	n, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return -1
+	}
+
+	return rune(n)
 }
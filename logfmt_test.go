@@ -0,0 +1,119 @@
+package logparser
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// logfmtConformance is testdata/golden/logfmt_conformance.json: how the
+// go-logfmt decoder reads testdata/logfmt_corpus.log, lines chosen to
+// exercise quoting, escapes and value boundaries, and the quoted forms of
+// testdata/logfmt_equals.log. It is recorded by testdata/logfmtgen, a
+// module of its own so the library does not depend on go-logfmt.
+type logfmtConformance struct {
+	Corpus []logfmtDecoded `json:"corpus"`
+	Equals []logfmtDecoded `json:"equals"`
+}
+
+// logfmtDecoded is one line and go-logfmt's pairs for it, keeping the last
+// value per key
+type logfmtDecoded struct {
+	Line   string            `json:"line"`
+	Quoted string            `json:"quoted"`
+	Pairs  map[string]string `json:"pairs"`
+}
+
+// want returns the pairs as parseLogfmtPairs returns them
+func (d logfmtDecoded) want() map[string]interface{} {
+	pairs := make(map[string]interface{}, len(d.Pairs))
+	for k, v := range d.Pairs {
+		pairs[k] = v
+	}
+
+	return pairs
+}
+
+func readLogfmtConformance(t *testing.T) logfmtConformance {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/golden/logfmt_conformance.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c logfmtConformance
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestLogfmtConformance(t *testing.T) {
+	for _, d := range readLogfmtConformance(t).Corpus {
+		t.Run(d.Line, func(t *testing.T) {
+			if got, want := parseLogfmtPairs(d.Line, nil), d.want(); !reflect.DeepEqual(got, want) {
+				t.Errorf("parseLogfmtPairs(%q)\n got %q\nwant %q", d.Line, got, want)
+			}
+		})
+	}
+}
+
+// TestLogfmtEqualsInValues checks lines with '=' in unquoted values, which
+// go-logfmt rejects, against go-logfmt's reading of the same line with those
+// values quoted
+func TestLogfmtEqualsInValues(t *testing.T) {
+	for _, d := range readLogfmtConformance(t).Equals {
+		t.Run(d.Line, func(t *testing.T) {
+			want := d.want()
+
+			if got := parseLogfmtPairs(d.Line, nil); !reflect.DeepEqual(got, want) {
+				t.Errorf("parseLogfmtPairs(%q)\n got %q\nwant %q", d.Line, got, want)
+			}
+
+			if got := parseLogfmtPairs(d.Quoted, nil); !reflect.DeepEqual(got, want) {
+				t.Errorf("parseLogfmtPairs(%q)\n got %q\nwant %q", d.Quoted, got, want)
+			}
+		})
+	}
+}
+
+func TestLogfmtLenient(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]interface{}
+	}{
+		{`msg="unterminated value`, map[string]interface{}{"msg": "unterminated value"}},
+		{`msg="bad \q escape"`, map[string]interface{}{"msg": `bad \q escape`}},
+		{`msg="short \u12"`, map[string]interface{}{"msg": `short \u12`}},
+		{`msg="lone \ud83d"`, map[string]interface{}{"msg": "lone \uFFFD"}},
+		{`k=a"b`, map[string]interface{}{"k": `a"b`}},
+		{`url=/?q=1 status=200`, map[string]interface{}{"url": "/?q=1", "status": "200"}},
+		{`"weird key"=1 b=2`, map[string]interface{}{"weird key": "1", "b": "2"}},
+		{`"say \"hi\""="x y" "bare key" k=v`, map[string]interface{}{`say "hi"`: "x y", "bare key": "", "k": "v"}},
+		{`"a"b=1`, map[string]interface{}{"a": "", "b": "1"}},
+		{`"unterminated key=1`, map[string]interface{}{"unterminated key=1": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := parseLogfmtPairs(tt.line, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLogfmtPairs(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeLogfmtFragmentRejectsProse(t *testing.T) {
+	for _, s := range []string{"set x=1 and retry", "a=b c", `{"k"=1}`} {
+		if got := decodeLogfmtFragment(s); got != nil {
+			t.Errorf("decodeLogfmtFragment(%q) = %v, want nil", s, got)
+		}
+	}
+
+	if got := decodeLogfmtFragment("a=1 b=2 extra"); len(got) != 2 {
+		t.Errorf("decodeLogfmtFragment kept %v, want a and b", got)
+	}
+}
@@ -0,0 +1,221 @@
+package logparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// WriteEntries writes entries to w as JSON or logfmt lines, one per entry.
+// The timestamp, level, and message are written under time, level, and
+// msg, then the service and logger when set, followed by the fields in
+// key order, as ReplayTo writes them.
+func WriteEntries(w io.Writer, entries []LogEntry, format Format) error {
+	if _, err := appendEntryLine(nil, &LogEntry{}, format); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	var buf []byte
+
+	for i := range entries {
+		var err error
+
+		buf, err = appendEntryLine(buf[:0], &entries[i], format)
+		if err != nil {
+			return err
+		}
+
+		if _, err := bw.Write(append(buf, '\n')); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// appendEntryLine appends entry as a single JSON or logfmt line, without
+// the trailing newline. The timestamp, level, and message come first under
+// the keys time, level, and msg, then service and logger when set and not
+// also fields, followed by the fields in key order, or in the order
+// recorded by WithPreserveOrder; fields with those names are written
+// after them as they are.
+func appendEntryLine(buf []byte, entry *LogEntry, format Format) ([]byte, error) {
+	order, _ := entry.Fields[keyOrderField].([]string)
+	keys := make([]string, 0, len(entry.Fields))
+
+	for _, k := range order {
+		if _, ok := entry.Fields[k]; ok && k != keyOrderField {
+			keys = append(keys, k)
+		}
+	}
+
+	ordered := len(keys)
+
+	for k := range entry.Fields {
+		if k != keyOrderField && (order == nil || !slices.Contains(keys[:ordered], k)) {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys[ordered:])
+
+	switch format {
+	case FormatJSON:
+		return appendEntryJSON(buf, entry, keys)
+	case FormatLogfmt:
+		return appendEntryLogfmt(buf, entry, keys), nil
+	default:
+		return buf, fmt.Errorf("cannot encode entries as %s", format)
+	}
+}
+
+// appendEntryJSON appends entry as a JSON object with ordered keys
+func appendEntryJSON(buf []byte, entry *LogEntry, keys []string) ([]byte, error) {
+	buf = append(buf, '{')
+
+	if !entry.Timestamp.IsZero() {
+		buf = append(buf, `"time":`...)
+		buf = append(buf, '"')
+		buf = entry.Timestamp.AppendFormat(buf, time.RFC3339Nano)
+		buf = append(buf, `",`...)
+	}
+
+	buf = append(buf, `"level":`...)
+	buf = appendJSONString(buf, entry.Level)
+	buf = append(buf, `,"msg":`...)
+	buf = appendJSONString(buf, entry.Message)
+
+	for _, sf := range semanticPairs(entry) {
+		buf = append(buf, ',')
+		buf = appendJSONString(buf, sf[0])
+		buf = append(buf, ':')
+		buf = appendJSONString(buf, sf[1])
+	}
+
+	for _, k := range keys {
+		val, err := json.Marshal(entry.Fields[k])
+		if err != nil {
+			return buf, fmt.Errorf("field %q: %w", k, err)
+		}
+
+		buf = append(buf, ',')
+		buf = appendJSONString(buf, k)
+		buf = append(buf, ':')
+		buf = append(buf, val...)
+	}
+
+	return append(buf, '}'), nil
+}
+
+// semanticPairs returns the service and logger keys and values to write
+// for entry, leaving out empty values and keys the fields also hold
+func semanticPairs(entry *LogEntry) [][2]string {
+	var pairs [][2]string
+
+	for _, sf := range [][2]string{{"service", entry.Service}, {"logger", entry.Logger}} {
+		if _, ok := entry.Fields[sf[0]]; sf[1] != "" && !ok {
+			pairs = append(pairs, sf)
+		}
+	}
+
+	return pairs
+}
+
+// appendJSONString appends s as a JSON string
+func appendJSONString(buf []byte, s string) []byte {
+	data, _ := json.Marshal(s) //nolint:errchkjson // strings always marshal
+
+	return append(buf, data...)
+}
+
+// appendEntryLogfmt appends entry as logfmt pairs
+func appendEntryLogfmt(buf []byte, entry *LogEntry, keys []string) []byte {
+	if !entry.Timestamp.IsZero() {
+		buf = append(buf, "time="...)
+		buf = entry.Timestamp.AppendFormat(buf, time.RFC3339Nano)
+		buf = append(buf, ' ')
+	}
+
+	buf = append(buf, "level="...)
+	buf = appendLogfmtValue(buf, entry.Level)
+	buf = append(buf, " msg="...)
+	buf = appendLogfmtValue(buf, entry.Message)
+
+	for _, sf := range semanticPairs(entry) {
+		buf = append(buf, ' ')
+		buf = append(buf, sf[0]...)
+		buf = append(buf, '=')
+		buf = appendLogfmtValue(buf, sf[1])
+	}
+
+	for _, k := range keys {
+		buf = append(buf, ' ')
+		buf = appendLogfmtKey(buf, k)
+		buf = append(buf, '=')
+		buf = appendLogfmtValue(buf, formatValue(entry.Fields[k]))
+	}
+
+	return buf
+}
+
+// appendLogfmtKey appends k with the characters logfmt does not allow in a
+// key replaced by underscores
+func appendLogfmtKey(buf []byte, k string) []byte {
+	if k == "" {
+		return append(buf, '_')
+	}
+
+	for _, r := range k {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			r = '_'
+		}
+
+		buf = utf8.AppendRune(buf, r)
+	}
+
+	return buf
+}
+
+// appendLogfmtValue appends s, quoting it when it is empty or contains
+// spaces, quotes, '=', or control characters. Quoted values use the escapes
+// understood by go-kit's logfmt decoder.
+func appendLogfmtValue(buf []byte, s string) []byte {
+	if s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f
+	}) {
+		return append(buf, s...)
+	}
+
+	const hex = "0123456789abcdef"
+
+	buf = append(buf, '"')
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c == '\n':
+			buf = append(buf, '\\', 'n')
+		case c == '\r':
+			buf = append(buf, '\\', 'r')
+		case c == '\t':
+			buf = append(buf, '\\', 't')
+		case c < ' ' || c == 0x7f:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+
+	return append(buf, '"')
+}
@@ -0,0 +1,142 @@
+package logparser
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// nestedPayloadLimit caps the size of a string considered for nested parsing
+const nestedPayloadLimit = 64 << 10
+
+// WithNestedParsing parses JSON objects and logfmt fragments embedded in the
+// message or in string field values, merging the results into Fields under
+// the field name as a prefix (msg.path, payload.status). Embedded values are
+// decoded recursively up to maxDepth levels; the original message and field
+// values are left in place.
+func WithNestedParsing(maxDepth int) Option {
+	return func(c *config) {
+		c.nestedDepth = maxDepth
+	}
+}
+
+// expandNested merges payloads embedded in the message and fields of entry
+func (c *config) expandNested(entry *LogEntry) {
+	if c.nestedDepth <= 0 {
+		return
+	}
+
+	// Snapshot the top-level strings so merged keys are not re-expanded
+	keys := make([]string, 0, len(entry.Fields))
+
+	for k, v := range entry.Fields {
+		if _, ok := v.(string); ok && !strings.HasPrefix(k, "_") {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	c.mergeNested(entry, "msg", entry.Message, c.nestedDepth)
+
+	for _, k := range keys {
+		s, _ := entry.Fields[k].(string)
+		c.mergeNested(entry, k, s, c.nestedDepth)
+	}
+}
+
+// mergeNested decodes s and stores its pairs under prefix, recursing into
+// string values while depth remains
+func (c *config) mergeNested(entry *LogEntry, prefix, s string, depth int) {
+	if depth <= 0 {
+		return
+	}
+
+	pairs := decodeNested(s)
+	if pairs == nil {
+		return
+	}
+
+	for k, v := range pairs {
+		key := prefix + "." + k
+		c.setField(entry, key, v)
+
+		if str, ok := v.(string); ok {
+			c.mergeNested(entry, key, str, depth-1)
+		}
+	}
+}
+
+// decodeNested returns the pairs of a JSON object or logfmt fragment held in
+// s, or nil if s is neither
+func decodeNested(s string) map[string]interface{} {
+	s = strings.TrimSpace(s)
+	if s == "" || len(s) > nestedPayloadLimit {
+		return nil
+	}
+
+	switch {
+	case s[0] == '"':
+		// Double-encoded JSON: unquote and try again; each step shrinks s
+		var inner string
+		if json.Unmarshal([]byte(s), &inner) != nil {
+			return nil
+		}
+
+		return decodeNested(inner)
+	case s[0] == '{':
+		var obj map[string]interface{}
+		if json.Unmarshal([]byte(s), &obj) == nil {
+			return obj
+		}
+
+		// Quotes escaped by an outer logfmt value
+		if strings.Contains(s, `\"`) {
+			return decodeNested(`"` + s + `"`)
+		}
+
+		return nil
+	case strings.Contains(s, "="):
+		return decodeLogfmtFragment(s)
+	default:
+		return nil
+	}
+}
+
+// decodeLogfmtFragment parses s as logfmt, rejecting prose that merely
+// contains '=' (see isLogfmtPairs)
+func decodeLogfmtFragment(s string) map[string]interface{} {
+	if !isLogfmtPairs(s) {
+		return nil
+	}
+
+	pairs := make(map[string]interface{})
+
+	scanLogfmt(s, func(key, value string, bare bool) {
+		if !bare {
+			pairs[key] = value
+		}
+	})
+
+	return pairs
+}
+
+// isLogfmtPairs reports whether s reads as logfmt rather than prose: keys
+// must not contain quotes or braces, and bare words may not outnumber
+// key=value pairs
+func isLogfmtPairs(s string) bool {
+	pairs, bare, valid := 0, 0, true
+
+	scanLogfmt(s, func(key, _ string, isBare bool) {
+		switch {
+		case isBare:
+			bare++
+		case strings.ContainsAny(key, "\"{}"):
+			valid = false
+		default:
+			pairs++
+		}
+	})
+
+	return valid && pairs > 0 && bare < pairs
+}
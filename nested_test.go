@@ -0,0 +1,103 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNestedParsing(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   map[string]interface{}
+	}{
+		{
+			name:   "JSON in logfmt",
+			format: FormatLogfmt,
+			input:  `level=info msg="{\"path\":\"/x\",\"status\":200}"`,
+			want:   map[string]interface{}{"msg.path": "/x", "msg.status": 200.0},
+		},
+		{
+			name:   "logfmt in JSON message",
+			format: FormatJSON,
+			input:  `{"level":"info","msg":"method=GET path=/y status=404"}`,
+			want:   map[string]interface{}{"msg.method": "GET", "msg.path": "/y", "msg.status": "404"},
+		},
+		{
+			name:   "double-encoded JSON",
+			format: FormatJSON,
+			input:  `{"msg":"\"{\\\"user\\\":\\\"ada\\\"}\""}`,
+			want:   map[string]interface{}{"msg.user": "ada"},
+		},
+		{
+			name:   "JSON in field value",
+			format: FormatJSON,
+			input:  `{"msg":"request","payload":"{\"id\":7,\"inner\":\"k=v\"}"}`,
+			want:   map[string]interface{}{"payload.id": 7.0, "payload.inner": "k=v", "payload.inner.k": "v"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := NewWithFormat(tt.format, WithNestedParsing(3)).ParseString(tt.input)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			for key, want := range tt.want {
+				if got := entries[0].Fields[key]; got != want {
+					t.Errorf("Fields[%q] = %v, want %v (fields %v)", key, got, want, entries[0].Fields)
+				}
+			}
+		})
+	}
+}
+
+func TestNestedParsingKeepsMessage(t *testing.T) {
+	entries, err := NewWithFormat(FormatJSON, WithNestedParsing(1)).ParseString(`{"msg":"a=1 b=2"}`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if entries[0].Message != "a=1 b=2" {
+		t.Errorf("Message = %q, want original", entries[0].Message)
+	}
+}
+
+func TestNestedParsingLimits(t *testing.T) {
+	// Depth 1 decodes the payload but not the logfmt inside it
+	entries, err := NewWithFormat(FormatJSON, WithNestedParsing(1)).
+		ParseString(`{"msg":"{\"inner\":\"k=v\"}"}`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if _, ok := entries[0].Fields["msg.inner.k"]; ok {
+		t.Errorf("depth limit exceeded: %v", entries[0].Fields)
+	}
+
+	// Oversized payloads are left alone
+	big := `{"msg":"k=` + strings.Repeat("x", nestedPayloadLimit) + `"}`
+
+	entries, err = NewWithFormat(FormatJSON, WithNestedParsing(3)).ParseString(big)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if _, ok := entries[0].Fields["msg.k"]; ok {
+		t.Error("payload over the size cap should not be parsed")
+	}
+
+	// Prose containing '=' is not a logfmt fragment
+	entries, err = NewWithFormat(FormatJSON, WithNestedParsing(3)).ParseString(`{"msg":"set retries = 3 for x"}`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	for key := range entries[0].Fields {
+		if strings.HasPrefix(key, "msg.") {
+			t.Errorf("prose was parsed as logfmt: %v", entries[0].Fields)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package logparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// nginxContextRe finds the start of the context pairs nginx appends to
+// error messages
+var nginxContextRe = regexp.MustCompile(`, (?:client|server|request|upstream|host|referrer|subrequest): `)
+
+// splitNginxContext moves the trailing "key: value" context pairs of an
+// nginx error message (client, server, request, upstream, host) into Fields
+func splitNginxContext(entry *LogEntry, _ []string, cfg *config) error {
+	loc := nginxContextRe.FindStringIndex(entry.Message)
+	if loc == nil {
+		return nil
+	}
+
+	tail := entry.Message[loc[0]+2:]
+	entry.Message = entry.Message[:loc[0]]
+
+	for tail != "" {
+		key, rest, ok := strings.Cut(tail, ": ")
+		if !ok {
+			break
+		}
+
+		var value string
+
+		value, tail = nginxContextValue(rest)
+		cfg.setField(entry, key, value)
+	}
+
+	return nil
+}
+
+// nginxContextValue reads one context value, quoted or bare, and returns it
+// with the remainder after the following ", " separator
+func nginxContextValue(s string) (value, rest string) {
+	if strings.HasPrefix(s, `"`) {
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				return s[1:i], strings.TrimPrefix(s[i+1:], ", ")
+			}
+		}
+
+		return s[1:], ""
+	}
+
+	value, rest, _ = strings.Cut(s, ", ")
+
+	return value, rest
+}
@@ -0,0 +1,70 @@
+package logparser
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNginxErrorLog(t *testing.T) {
+	data, err := os.ReadFile("testdata/nginx_error.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := New().ParseString(string(data))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	wantLevels := []string{"INFO", "INFO", "ERROR", "WARN", "FATAL", "FATAL", "FATAL"}
+	if len(entries) != len(wantLevels) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(wantLevels))
+	}
+
+	for i, want := range wantLevels {
+		if entries[i].Level != want {
+			t.Errorf("entry %d: level %s, want %s", i, entries[i].Level, want)
+		}
+	}
+
+	e := entries[2]
+	if want := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	if e.Message != "connect() failed (111: Connection refused) while connecting to upstream" {
+		t.Errorf("Message = %q", e.Message)
+	}
+
+	want := map[string]string{
+		"pid":        "1234",
+		"tid":        "5678",
+		"connection": "91",
+		"client":     "10.0.0.2",
+		"server":     "example.com",
+		"request":    "GET /x HTTP/1.1",
+		"upstream":   "http://127.0.0.1:8080/x",
+		"host":       "example.com",
+	}
+
+	for k, v := range want {
+		if e.Fields[k] != v {
+			t.Errorf("Fields[%q] = %v, want %q", k, e.Fields[k], v)
+		}
+	}
+
+	if got := entries[3].Fields["referrer"]; got != "https://example.com/form" {
+		t.Errorf("referrer = %v", got)
+	}
+
+	// Lines without a context tail or connection id
+	e = entries[6]
+	if e.Message != "bind() to 0.0.0.0:80 failed (98: Address already in use)" || e.Fields["pid"] != "1" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	if _, ok := e.Fields["connection"]; ok {
+		t.Errorf("unexpected connection field %v", e.Fields)
+	}
+}
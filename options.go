@@ -0,0 +1,435 @@
+package logparser
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Option configures a Parser
+type Option func(*config)
+
+// config holds the settings applied by a parser
+type config struct {
+	format Format
+
+	fieldAllow keyTree
+	fieldDeny  keyTree
+
+	sampleRate    float64
+	sampleSeed    *uint64
+	smartSampling *SmartSampling
+	dedup         *DedupFilter
+	lenientJSON   bool
+	serviceKeys   []string // nil means DefaultServiceKeys
+	loggerKeys    []string // nil means DefaultLoggerKeys
+	headLimit     int
+	tailLimit     int
+
+	preserveKeys   bool
+	keyOrder       bool
+	conflicts      ConflictPolicy
+	rawLine        bool
+	inferTemplates bool
+
+	inferLevels   bool
+	levelKeywords map[string][]string
+	escalations   []EscalationRule
+
+	keepANSI        bool
+	sanitizeControl bool
+	bracketFields   bool
+	contextDepth    int
+	textPatterns    []*textPattern
+
+	sourceName string
+
+	skipInvalid bool
+	transforms  []Transform
+	partial     PartialPolicy
+	fallbacks   []Format
+	profile     *Profile
+
+	keepBlank       bool
+	commentPrefixes []string
+
+	durationFields []string
+	durationUnits  map[string]time.Duration
+	durationMillis bool
+
+	nestedDepth int
+
+	payloadKeys []string
+	payloadMax  int
+
+	enrichments []enrichment
+
+	hashing fieldHashing
+
+	strictTime    bool
+	strictLayouts []string
+
+	wrapWidth int
+	wrapMax   int
+
+	instruments []Instrumentation
+	timings     bool
+	timer       *phaseTimer // Set in the per-run copy of instrumented runs
+
+	detectionSamples int
+	minConfidence    float64
+	blockDetection   bool
+
+	parseStacks   bool
+	replaceStacks bool
+
+	maxErrors int
+
+	groupSep string
+
+	stripJournal bool
+
+	gcPauseWarn time.Duration
+
+	maxFieldSize int
+	maxLineSize  int
+
+	referenceTime time.Time
+	location      *time.Location
+	normalizeUTC  bool
+	timeParsers   []TimestampParser
+}
+
+// newConfig builds a config from options
+func newConfig(opts []Option) config {
+	var cfg config
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// validate reports options that conflict or are out of range. New applies
+// such options as documented; NewE rejects them.
+func (c *config) validate() error {
+	switch {
+	case c.format < FormatAuto || c.format > FormatSquid:
+		return fmt.Errorf("%w: unknown format %d", ErrInvalidOptions, c.format)
+	case c.fieldAllow != nil && c.fieldDeny != nil:
+		return fmt.Errorf("%w: WithFieldAllowlist and WithFieldDenylist both set", ErrInvalidOptions)
+	case c.sampleRate < 0 || c.sampleRate > 1:
+		return fmt.Errorf("%w: sampling rate %v outside [0, 1]", ErrInvalidOptions, c.sampleRate)
+	case c.minConfidence < 0 || c.minConfidence > 1:
+		return fmt.Errorf("%w: detection confidence %v outside [0, 1]", ErrInvalidOptions, c.minConfidence)
+	case c.conflicts < ConflictIgnore || c.conflicts > ConflictResolve:
+		return fmt.Errorf("%w: unknown conflict policy %d", ErrInvalidOptions, c.conflicts)
+	case c.partial < PartialFail || c.partial > PartialKeep:
+		return fmt.Errorf("%w: unknown partial line policy %d", ErrInvalidOptions, c.partial)
+	case !validFallbacks(c.fallbacks):
+		return fmt.Errorf("%w: fallback formats must be line formats", ErrInvalidOptions)
+	case c.headLimit < 0 || c.tailLimit < 0 || c.maxErrors < 0 || c.maxFieldSize < 0:
+		return fmt.Errorf("%w: negative limit", ErrInvalidOptions)
+	case len(c.payloadKeys) > 0 && c.payloadMax <= 0:
+		return fmt.Errorf("%w: payload size cap %d not positive", ErrInvalidOptions, c.payloadMax)
+	case c.wrapWidth < 0 || c.wrapMax < 0:
+		return fmt.Errorf("%w: negative wrap width or length", ErrInvalidOptions)
+	case c.smartSampling != nil && c.sampleRate != 0:
+		return fmt.Errorf("%w: WithSmartSampling and WithSampling both set", ErrInvalidOptions)
+	}
+
+	if c.smartSampling != nil {
+		if err := c.smartSampling.validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := AmbiguityCheck(c.strictLayouts); err != nil {
+		return fmt.Errorf("%w: strict timestamps: %w", ErrInvalidOptions, err)
+	}
+
+	for _, en := range c.enrichments {
+		if en.err != nil {
+			return en.err
+		}
+	}
+
+	if err := c.hashing.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WithFormat parses input as format instead of detecting it
+func WithFormat(format Format) Option {
+	return func(c *config) {
+		c.format = format
+	}
+}
+
+// WithDetectionSampleSize sets how many lines are buffered before the format
+// is auto-detected (default 10). Larger windows see past startup banners.
+func WithDetectionSampleSize(n int) Option {
+	return func(c *config) {
+		c.detectionSamples = n
+	}
+}
+
+// WithMinDetectionConfidence makes auto-detection fail with an
+// *AmbiguousFormatError when fewer than the given fraction of sample lines
+// match the detected format, instead of guessing
+func WithMinDetectionConfidence(f float64) Option {
+	return func(c *config) {
+		c.minConfidence = f
+	}
+}
+
+// WithFieldAllowlist keeps only the listed keys in LogEntry.Fields.
+// Dotted keys select values inside nested objects. When both an allowlist
+// and a denylist are set, the allowlist wins.
+func WithFieldAllowlist(keys ...string) Option {
+	return func(c *config) {
+		if c.fieldAllow == nil {
+			c.fieldAllow = make(keyTree)
+		}
+
+		for _, key := range keys {
+			c.fieldAllow.insert(key)
+		}
+	}
+}
+
+// WithFieldDenylist drops the listed keys from LogEntry.Fields.
+// Dotted keys select values inside nested objects.
+func WithFieldDenylist(keys ...string) Option {
+	return func(c *config) {
+		if c.fieldDeny == nil {
+			c.fieldDeny = make(keyTree)
+		}
+
+		for _, key := range keys {
+			c.fieldDeny.insert(key)
+		}
+	}
+}
+
+// WithSampling keeps each line with probability rate (0 < rate < 1).
+// Dropped lines are not parsed. Use WithSampleSeed for reproducible samples.
+func WithSampling(rate float64) Option {
+	return func(c *config) {
+		c.sampleRate = rate
+	}
+}
+
+// sampleRand returns the random source of a sampler, seeded by
+// WithSampleSeed or else by the time
+func (c *config) sampleRand() *rand.Rand {
+	seed := uint64(time.Now().UnixNano()) //nolint:gosec // seed only needs to vary between runs
+	if c.sampleSeed != nil {
+		seed = *c.sampleSeed
+	}
+
+	return rand.New(rand.NewPCG(seed, seed)) //nolint:gosec // sampling does not need crypto randomness
+}
+
+// WithSampleSeed seeds the sampler so repeated runs keep the same lines
+func WithSampleSeed(seed uint64) Option {
+	return func(c *config) {
+		c.sampleSeed = &seed
+	}
+}
+
+// WithHeadLimit stops parsing once n entries have been produced.
+// The underlying reader is not read past that point.
+func WithHeadLimit(n int) Option {
+	return func(c *config) {
+		c.headLimit = n
+	}
+}
+
+// WithTailLimit keeps only the last n entries of the input
+func WithTailLimit(n int) Option {
+	return func(c *config) {
+		c.tailLimit = n
+	}
+}
+
+// WithPreserveOriginalKeys keeps the timestamp, level, message, service,
+// and logger keys in Fields after they are extracted into the LogEntry.
+// The key used for each is recorded in Fields["_ts_key"],
+// Fields["_level_key"], Fields["_msg_key"], Fields["_service_key"], and
+// Fields["_logger_key"]. By default extracted keys are removed from Fields.
+func WithPreserveOriginalKeys(preserve bool) Option {
+	return func(c *config) {
+		c.preserveKeys = preserve
+	}
+}
+
+// WithRawLine keeps the line each entry was parsed from in Fields["_raw"].
+// For an entry that spans several lines this is the first one.
+func WithRawLine(keep bool) Option {
+	return func(c *config) {
+		c.rawLine = keep
+	}
+}
+
+// keepRawLine records the line an entry was parsed from if WithRawLine is set
+func (c *config) keepRawLine(entry *LogEntry, line string) {
+	if c.rawLine {
+		c.setField(entry, "_raw", line)
+	}
+}
+
+// WithStripANSI controls whether the text parser removes ANSI escape
+// sequences (colors, OSC titles) before matching patterns. Stripping is on
+// by default; lines that had sequences removed are marked with
+// Fields["_ansi"] = true.
+func WithStripANSI(strip bool) Option {
+	return func(c *config) {
+		c.keepANSI = !strip
+	}
+}
+
+// WithSkipInvalid skips lines that fail to parse or transform instead of
+// aborting the whole parse. Skipped lines are counted in Stats.LinesSkipped.
+func WithSkipInvalid(skip bool) Option {
+	return func(c *config) {
+		c.skipInvalid = skip
+	}
+}
+
+// WithKeepBlankLines counts blank lines in Stats.LinesSeen, so it agrees
+// with the physical line count. Blank lines never produce entries, and
+// LogEntry.Source line numbers count them either way; by default they are
+// only reported in Stats.BlankLines.
+func WithKeepBlankLines(keep bool) Option {
+	return func(c *config) {
+		c.keepBlank = keep
+	}
+}
+
+// WithCommentPrefix skips lines that start with one of the prefixes after
+// leading whitespace, such as "#" for logrotate headers and W3C "#Fields:"
+// directives. Comment lines are counted in Stats.CommentLines, not
+// Stats.LinesSeen, and are not used for format detection.
+func WithCommentPrefix(prefixes ...string) Option {
+	return func(c *config) {
+		c.commentPrefixes = append(c.commentPrefixes, prefixes...)
+	}
+}
+
+// WithSourceName attributes every entry to the named source, populating
+// LogEntry.Source with the name, line number, and byte offset. ParseFile
+// uses the file path when no name is given, and ParseGlob always does.
+func WithSourceName(name string) Option {
+	return func(c *config) {
+		c.sourceName = name
+	}
+}
+
+// consumeKey removes an extracted key from raw, or records which key was
+// used under the provenance key when original keys are preserved
+func (c *config) consumeKey(raw map[string]interface{}, provenance, key string) {
+	if c.preserveKeys {
+		raw[provenance] = key
+
+		return
+	}
+
+	delete(raw, key)
+}
+
+// isComment reports whether a trimmed line starts with a comment prefix
+func (c *config) isComment(line string) bool {
+	for _, prefix := range c.commentPrefixes {
+		if prefix != "" && strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// samples reports whether probabilistic sampling is enabled
+func (c *config) samples() bool {
+	return c.sampleRate > 0 && c.sampleRate < 1
+}
+
+// filtersFields reports whether an allowlist or denylist is configured
+func (c *config) filtersFields() bool {
+	return len(c.fieldAllow) > 0 || len(c.fieldDeny) > 0
+}
+
+// newFields returns the initial Fields map for an entry. It is never nil,
+// even when the field filters keep nothing, so transforms and callers can
+// always write to it.
+func (c *config) newFields() map[string]interface{} {
+	return make(map[string]interface{})
+}
+
+// setField stores a field on the entry if the field filters keep it
+func (c *config) setField(entry *LogEntry, key string, val interface{}) {
+	switch {
+	case len(c.fieldAllow) > 0:
+		node, ok := c.fieldAllow[key]
+		if !ok {
+			return
+		}
+
+		if node != nil {
+			nested, isMap := val.(map[string]interface{})
+			if !isMap {
+				return
+			}
+
+			nested = node.allow(nested)
+			if len(nested) == 0 {
+				return
+			}
+
+			val = nested
+		}
+	case len(c.fieldDeny) > 0:
+		node, ok := c.fieldDeny[key]
+		if ok && node == nil {
+			return
+		}
+
+		if nested, isMap := val.(map[string]interface{}); ok && isMap {
+			val = node.deny(nested)
+		}
+	}
+
+	if entry.Fields == nil {
+		entry.Fields = make(map[string]interface{})
+	}
+
+	entry.Fields[key] = val
+}
+
+// finishEntry fills in the level and timestamp when the line had none,
+// leaving the timestamp zero under WithStrictTimestamps, and converts the
+// timestamp to UTC under WithNormalizeUTC
+func (c *config) finishEntry(entry *LogEntry) {
+	if entry.Level == "" {
+		entry.Level = LevelInfo
+
+		if c.inferLevels {
+			if level, ok := c.inferLevel(entry.Message); ok {
+				entry.Level = level
+			}
+		}
+	}
+
+	// Default to current time if no timestamp found
+	if entry.Timestamp.IsZero() && !c.strictTime {
+		entry.Timestamp = time.Now()
+	}
+
+	if c.normalizeUTC {
+		entry.Timestamp = entry.Timestamp.UTC()
+	}
+}
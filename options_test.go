@@ -0,0 +1,391 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFieldFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		opts   []Option
+		want   map[string]interface{}
+	}{
+		{
+			name:   "JSON allowlist",
+			format: FormatJSON,
+			input:  `{"level":"info","msg":"ok","env":"prod","region":"eu","user_id":"42"}`,
+			opts:   []Option{WithFieldAllowlist("env", "user_id")},
+			want:   map[string]interface{}{"env": "prod", "user_id": "42"},
+		},
+		{
+			name:   "JSON denylist",
+			format: FormatJSON,
+			input:  `{"level":"info","msg":"ok","env":"prod","region":"eu"}`,
+			opts:   []Option{WithFieldDenylist("region")},
+			want:   map[string]interface{}{"env": "prod"},
+		},
+		{
+			name:   "allowlist wins over denylist",
+			format: FormatJSON,
+			input:  `{"msg":"ok","env":"prod","region":"eu","zone":"a"}`,
+			opts:   []Option{WithFieldDenylist("env"), WithFieldAllowlist("env", "zone")},
+			want:   map[string]interface{}{"env": "prod", "zone": "a"},
+		},
+		{
+			name:   "dotted allowlist selects nested value",
+			format: FormatJSON,
+			input:  `{"msg":"ok","http":{"method":"GET","status":200,"headers":{"a":"b"}}}`,
+			opts:   []Option{WithFieldAllowlist("http.method", "http.status")},
+			want:   map[string]interface{}{"http": map[string]interface{}{"method": "GET", "status": float64(200)}},
+		},
+		{
+			name:   "dotted denylist prunes nested value",
+			format: FormatJSON,
+			input:  `{"msg":"ok","http":{"method":"GET","headers":{"a":"b"}}}`,
+			opts:   []Option{WithFieldDenylist("http.headers")},
+			want:   map[string]interface{}{"http": map[string]interface{}{"method": "GET"}},
+		},
+		{
+			name:   "logfmt allowlist matches literal dotted key",
+			format: FormatLogfmt,
+			input:  `level=info msg=ok http.method=GET http.path=/x service=api`,
+			opts:   []Option{WithFieldAllowlist("http.method")},
+			want:   map[string]interface{}{"http.method": "GET"},
+		},
+		{
+			name:   "text denylist",
+			format: FormatText,
+			input:  `2024-01-02T15:04:05.000000Z 12 [Warning] [MY-010055] [Server] boom`,
+			opts:   []Option{WithFieldDenylist("thread")},
+			want:   map[string]interface{}{"code": "MY-010055", "subsystem": "Server"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := NewWithFormat(tt.format, tt.opts...).ParseString(tt.input)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			got := fmt.Sprint(entries[0].Fields)
+			if want := fmt.Sprint(tt.want); got != want {
+				t.Errorf("want fields %s, got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestFieldFiltersKeepFieldsNonNil(t *testing.T) {
+	inputs := map[Format]string{
+		FormatJSON:   `{"level":"info","msg":"ok","service":"api"}`,
+		FormatLogfmt: `level=info msg=ok service=api`,
+		FormatText:   `[INFO] ok`,
+	}
+
+	for format, input := range inputs {
+		for _, opt := range []Option{WithFieldAllowlist("missing"), WithFieldDenylist("service")} {
+			// A transform writing to Fields must not find it nil
+			transform := WithTransform(func(e *LogEntry) error {
+				e.Fields["seen"] = true
+
+				return nil
+			})
+
+			entries, err := NewWithFormat(format, opt, transform).ParseString(input)
+			if err != nil {
+				t.Fatalf("%s: ParseString() error = %v", format, err)
+			}
+
+			if entries[0].Fields == nil || entries[0].Fields["seen"] != true {
+				t.Errorf("%s: want Fields holding the transform's key, got %v", format, entries[0].Fields)
+			}
+		}
+	}
+}
+
+// wideJSONLine builds a JSON log line carrying n extra fields
+func wideJSONLine(n int) string {
+	var b strings.Builder
+
+	b.WriteString(`{"timestamp":"2024-01-02T15:04:05Z","level":"info","message":"ok"`)
+
+	for i := range n {
+		fmt.Fprintf(&b, `,"field_%02d":"value %d"`, i, i)
+	}
+
+	b.WriteString("}")
+
+	return b.String()
+}
+
+func BenchmarkJSONParserWideFields(b *testing.B) {
+	input := strings.Repeat(wideJSONLine(40)+"\n", 100)
+
+	benchmarks := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "all fields"},
+		{name: "allowlist", opts: []Option{WithFieldAllowlist("field_01", "field_02", "field_03", "field_04", "field_05")}},
+		{name: "allowlist none kept", opts: []Option{WithFieldAllowlist("absent")}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			parser := NewWithFormat(FormatJSON, bm.opts...)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for range b.N {
+				_, _ = parser.ParseString(input)
+			}
+		})
+	}
+}
+
+// numberedLogfmt returns n logfmt lines with messages "line 0" .. "line n-1"
+func numberedLogfmt(n int) string {
+	var b strings.Builder
+
+	for i := range n {
+		fmt.Fprintf(&b, "level=info msg=\"line %d\"\n", i)
+	}
+
+	return b.String()
+}
+
+// failingReader fails the test if it is ever read
+type failingReader struct {
+	t *testing.T
+}
+
+func (f failingReader) Read([]byte) (int, error) {
+	f.t.Error("reader was read past the head limit")
+
+	return 0, io.EOF
+}
+
+func TestHeadLimitStopsReading(t *testing.T) {
+	input := io.MultiReader(strings.NewReader(numberedLogfmt(5)), failingReader{t: t})
+
+	entries, stats, err := ParseWithStats(NewWithFormat(FormatLogfmt, WithHeadLimit(3)), input)
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	if len(entries) != 3 || entries[2].Message != "line 2" {
+		t.Fatalf("want first 3 entries, got %+v", entries)
+	}
+
+	if stats.LinesSeen != 3 || stats.EntriesEmitted != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestHeadLimitWithAutoDetection(t *testing.T) {
+	entries, err := New(WithHeadLimit(2)).ParseString(numberedLogfmt(20))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if len(entries) != 2 || entries[1].Message != "line 1" {
+		t.Errorf("want first 2 entries, got %+v", entries)
+	}
+}
+
+func TestTailLimit(t *testing.T) {
+	entries, stats, err := ParseWithStats(NewWithFormat(FormatLogfmt, WithTailLimit(3)), strings.NewReader(numberedLogfmt(10)))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	want := []string{"line 7", "line 8", "line 9"}
+	if len(entries) != len(want) {
+		t.Fatalf("want %d entries, got %d", len(want), len(entries))
+	}
+
+	for i, msg := range want {
+		if entries[i].Message != msg {
+			t.Errorf("entry %d: want %q, got %q", i, msg, entries[i].Message)
+		}
+	}
+
+	if stats.LinesSeen != 10 || stats.EntriesEmitted != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	const lines = 10000
+
+	input := numberedLogfmt(lines)
+	parser := NewWithFormat(FormatLogfmt, WithSampling(0.1), WithSampleSeed(42))
+
+	first, stats, err := ParseWithStats(parser, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	if stats.LinesSeen != lines || stats.EntriesEmitted != len(first) {
+		t.Errorf("unexpected stats %+v for %d entries", stats, len(first))
+	}
+
+	if len(first) < 800 || len(first) > 1200 {
+		t.Errorf("want roughly 1000 sampled entries, got %d", len(first))
+	}
+
+	second, err := parser.ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if len(first) != len(second) {
+		t.Fatalf("seeded samples differ in size: %d vs %d", len(first), len(second))
+	}
+
+	for i := range first {
+		if first[i].Message != second[i].Message {
+			t.Fatalf("seeded samples differ at %d: %q vs %q", i, first[i].Message, second[i].Message)
+		}
+	}
+}
+
+func TestPreserveOriginalKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		input    string
+		preserve bool
+		want     map[string]interface{}
+	}{
+		{
+			name:   "JSON default removes extracted keys",
+			format: FormatJSON,
+			input:  `{"@timestamp":"2024-01-02T15:04:05Z","severity":"warn","msg":"disk low","host":"a"}`,
+			want:   map[string]interface{}{"host": "a"},
+		},
+		{
+			name:     "JSON preserve keeps originals and provenance",
+			format:   FormatJSON,
+			input:    `{"@timestamp":"2024-01-02T15:04:05Z","severity":"warn","msg":"disk low","host":"a"}`,
+			preserve: true,
+			want: map[string]interface{}{
+				"@timestamp": "2024-01-02T15:04:05Z", "severity": "warn", "msg": "disk low", "host": "a",
+				"_ts_key": "@timestamp", "_level_key": "severity", "_msg_key": "msg",
+			},
+		},
+		{
+			name:   "logfmt default removes extracted keys",
+			format: FormatLogfmt,
+			input:  `ts=2024-01-02T15:04:05Z level=warn message="disk low" host=a`,
+			want:   map[string]interface{}{"host": "a"},
+		},
+		{
+			name:     "logfmt preserve keeps originals and provenance",
+			format:   FormatLogfmt,
+			input:    `ts=2024-01-02T15:04:05Z level=warn message="disk low" host=a`,
+			preserve: true,
+			want: map[string]interface{}{
+				"ts": "2024-01-02T15:04:05Z", "level": "warn", "message": "disk low", "host": "a",
+				"_ts_key": "ts", "_level_key": "level", "_msg_key": "message",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := NewWithFormat(tt.format, WithPreserveOriginalKeys(tt.preserve)).ParseString(tt.input)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			e := entries[0]
+			if e.Level != "WARN" || e.Message != "disk low" || e.Timestamp.Year() != 2024 {
+				t.Errorf("standard fields not extracted: %+v", e)
+			}
+
+			if got, want := fmt.Sprint(e.Fields), fmt.Sprint(tt.want); got != want {
+				t.Errorf("want fields %s, got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestWithFormat(t *testing.T) {
+	line := `{"level":"error","msg":"not text"}` + "\n"
+
+	// WithFormat bypasses detection exactly like NewWithFormat
+	for name, parser := range map[string]Parser{
+		"WithFormat":    New(WithFormat(FormatText)),
+		"NewWithFormat": NewWithFormat(FormatText),
+	} {
+		entries, err := parser.ParseString(line)
+		if err != nil || len(entries) != 1 || entries[0].Message != strings.TrimSpace(line) {
+			t.Errorf("%s: got %+v, %v; want the line as a text message", name, entries, err)
+		}
+	}
+
+	// Options are carried into the format parser together with the format
+	parser, err := NewE(WithFormat(FormatJSON), WithSkipInvalid(true))
+	if err != nil {
+		t.Fatalf("NewE() error = %v", err)
+	}
+
+	entries, err := parser.ParseString(line + "not json\n" + line)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("got %d entries, %v; want 2 with the invalid line skipped", len(entries), err)
+	}
+}
+
+func TestNewEValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"allow and deny", []Option{WithFieldAllowlist("a"), WithFieldDenylist("b")}},
+		{"unknown format", []Option{WithFormat(Format(42))}},
+		{"sampling rate", []Option{WithSampling(1.5)}},
+		{"confidence", []Option{WithMinDetectionConfidence(-0.1)}},
+		{"negative limit", []Option{WithHeadLimit(-1)}},
+		{"fallback format", []Option{WithFallbackFormats(FormatText, FormatWindowsEventXML)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewE(tt.opts...); !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("NewE() error = %v, want ErrInvalidOptions", err)
+			}
+
+			// New keeps accepting them
+			if New(tt.opts...) == nil {
+				t.Error("New() returned nil")
+			}
+		})
+	}
+
+	if _, err := NewE(WithFieldAllowlist("a"), WithSampling(0.5), WithFormat(FormatLogfmt)); err != nil {
+		t.Errorf("NewE() error = %v for valid options", err)
+	}
+}
+
+func TestMust(t *testing.T) {
+	if Must(NewE(WithFormat(FormatJSON))) == nil {
+		t.Error("Must() returned nil for valid options")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Must() did not panic on an error")
+		}
+	}()
+
+	Must(NewE(WithSampling(-1)))
+}
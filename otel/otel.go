@@ -0,0 +1,202 @@
+// Package otel maps parsed log entries onto the OpenTelemetry log data model.
+//
+// Records use a small local struct rather than the collector's pdata types so
+// the parser does not pull in the OpenTelemetry dependency tree; the fields
+// correspond one-to-one with the OTLP LogRecord message.
+package otel
+
+import (
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	logparser "github.com/yildizm/go-logparser"
+)
+
+// traceparentRe matches a W3C traceparent header value
+var traceparentRe = regexp.MustCompile(`\b([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})\b`)
+
+// SeverityNumber is the OTel severity number (1-24)
+type SeverityNumber int32
+
+// Severity numbers for the first step of each OTel severity range
+const (
+	SeverityUnspecified SeverityNumber = 0
+	SeverityTrace       SeverityNumber = 1
+	SeverityDebug       SeverityNumber = 5
+	SeverityInfo        SeverityNumber = 9
+	SeverityWarn        SeverityNumber = 13
+	SeverityError       SeverityNumber = 17
+	SeverityFatal       SeverityNumber = 21
+)
+
+// Record is a log record in the OTel log data model
+type Record struct {
+	Timestamp         time.Time
+	ObservedTimestamp time.Time
+	SeverityNumber    SeverityNumber
+	SeverityText      string
+	Body              string
+	Attributes        map[string]interface{}
+	TraceID           string // 32 lowercase hex digits, empty if absent
+	SpanID            string // 16 lowercase hex digits, empty if absent
+	TraceFlags        uint8
+}
+
+// TraceContext is the trace identity carried by a W3C traceparent
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	Flags   uint8
+}
+
+// Trace context field names, checked in order
+var (
+	traceIDKeys = []string{"trace_id", "traceId", "trace.id"}
+	spanIDKeys  = []string{"span_id", "spanId", "span.id"}
+)
+
+// ToOTel converts an entry to an OTel log record. Valid trace_id, span_id,
+// and traceparent fields are moved out of Attributes into the dedicated
+// trace fields; invalid values stay in Attributes. A traceparent found in
+// the message is used when no trace fields are present.
+func ToOTel(e logparser.LogEntry) Record {
+	rec := Record{
+		Timestamp:         e.Timestamp,
+		ObservedTimestamp: time.Now(),
+		SeverityNumber:    Severity(e.Level),
+		SeverityText:      e.Level,
+		Body:              e.Message,
+		Attributes:        make(map[string]interface{}, len(e.Fields)),
+	}
+
+	for k, v := range e.Fields {
+		rec.Attributes[k] = v
+	}
+
+	if s, ok := rec.Attributes["traceparent"].(string); ok {
+		if tc, ok := ParseTraceparent(s); ok {
+			rec.setTrace(tc)
+			delete(rec.Attributes, "traceparent")
+		}
+	}
+
+	if rec.TraceID == "" {
+		rec.TraceID = takeHex(rec.Attributes, traceIDKeys, 32)
+	}
+
+	if rec.SpanID == "" {
+		rec.SpanID = takeHex(rec.Attributes, spanIDKeys, 16)
+	}
+
+	if rec.TraceID == "" && rec.SpanID == "" {
+		if tc, ok := ExtractTraceparent(e.Message); ok {
+			rec.setTrace(tc)
+		}
+	}
+
+	return rec
+}
+
+// setTrace copies a trace context onto the record
+func (r *Record) setTrace(tc TraceContext) {
+	r.TraceID = tc.TraceID
+	r.SpanID = tc.SpanID
+	r.TraceFlags = tc.Flags
+}
+
+// Severity maps a level string to its OTel severity number. Unrecognized
+// levels are treated as INFO, as ParseLevel does; an empty level is unspecified.
+func Severity(level string) SeverityNumber {
+	if level == "" {
+		return SeverityUnspecified
+	}
+
+	switch logparser.ParseLevel(level) {
+	case "TRACE":
+		return SeverityTrace
+	case "DEBUG":
+		return SeverityDebug
+	case logparser.LevelInfo:
+		return SeverityInfo
+	case "WARN":
+		return SeverityWarn
+	case logparser.LevelError:
+		return SeverityError
+	case "FATAL":
+		return SeverityFatal
+	default:
+		return SeverityUnspecified
+	}
+}
+
+// ParseTraceparent parses a W3C traceparent value such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+func ParseTraceparent(s string) (TraceContext, bool) {
+	m := traceparentRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || m[0] != strings.TrimSpace(s) {
+		return TraceContext{}, false
+	}
+
+	return traceContext(m)
+}
+
+// ExtractTraceparent finds the first valid traceparent within free text
+func ExtractTraceparent(text string) (TraceContext, bool) {
+	for _, m := range traceparentRe.FindAllStringSubmatch(text, -1) {
+		if tc, ok := traceContext(m); ok {
+			return tc, true
+		}
+	}
+
+	return TraceContext{}, false
+}
+
+// traceContext validates traceparent submatches
+func traceContext(m []string) (TraceContext, bool) {
+	if m[1] == "ff" || !validHex(m[2], 32) || !validHex(m[3], 16) {
+		return TraceContext{}, false
+	}
+
+	flags, err := hex.DecodeString(m[4])
+	if err != nil {
+		return TraceContext{}, false
+	}
+
+	return TraceContext{TraceID: m[2], SpanID: m[3], Flags: flags[0]}, true
+}
+
+// takeHex removes and returns the first field in keys holding a valid hex ID
+// of the given length, lower-cased
+func takeHex(attrs map[string]interface{}, keys []string, length int) string {
+	for _, key := range keys {
+		s, ok := attrs[key].(string)
+		if !ok {
+			continue
+		}
+
+		s = strings.ToLower(s)
+		if validHex(s, length) {
+			delete(attrs, key)
+
+			return s
+		}
+	}
+
+	return ""
+}
+
+// validHex reports whether s is length hex digits and not all zero, as the
+// W3C trace context spec requires
+func validHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+
+	if _, err := hex.DecodeString(s); err != nil {
+		return false
+	}
+
+	return strings.Trim(s, "0") != ""
+}
@@ -0,0 +1,105 @@
+package otel
+
+import (
+	"testing"
+	"time"
+
+	logparser "github.com/yildizm/go-logparser"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestToOTel(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	rec := ToOTel(logparser.LogEntry{
+		Timestamp: ts,
+		Level:     "WARN",
+		Message:   "slow request",
+		Fields: map[string]interface{}{
+			"trace_id": "4BF92F3577B34DA6A3CE929D0E0E4736",
+			"span_id":  testSpanID,
+			"path":     "/users",
+		},
+	})
+
+	if rec.Timestamp != ts || rec.ObservedTimestamp.IsZero() {
+		t.Errorf("timestamps = %v, %v", rec.Timestamp, rec.ObservedTimestamp)
+	}
+
+	if rec.SeverityNumber != SeverityWarn || rec.SeverityText != "WARN" || rec.Body != "slow request" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	if rec.TraceID != testTraceID || rec.SpanID != testSpanID {
+		t.Errorf("trace context = %q/%q", rec.TraceID, rec.SpanID)
+	}
+
+	if len(rec.Attributes) != 1 || rec.Attributes["path"] != "/users" {
+		t.Errorf("Attributes = %v", rec.Attributes)
+	}
+}
+
+func TestToOTelInvalidTraceIDsStayInAttributes(t *testing.T) {
+	fields := map[string]interface{}{
+		"trace_id": "abc123",                           // wrong length
+		"span_id":  "zz0000000000000z",                 // not hex
+		"traceId":  "00000000000000000000000000000000", // all zero
+	}
+
+	rec := ToOTel(logparser.LogEntry{Level: "INFO", Fields: fields})
+
+	if rec.TraceID != "" || rec.SpanID != "" {
+		t.Errorf("invalid IDs were accepted: %q/%q", rec.TraceID, rec.SpanID)
+	}
+
+	for k, v := range fields {
+		if rec.Attributes[k] != v {
+			t.Errorf("Attributes[%q] = %v, want %v", k, rec.Attributes[k], v)
+		}
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	header := "00-" + testTraceID + "-" + testSpanID + "-01"
+
+	rec := ToOTel(logparser.LogEntry{Fields: map[string]interface{}{"traceparent": header}})
+	if rec.TraceID != testTraceID || rec.SpanID != testSpanID || rec.TraceFlags != 1 {
+		t.Errorf("traceparent field not extracted: %+v", rec)
+	}
+
+	if _, ok := rec.Attributes["traceparent"]; ok {
+		t.Error("valid traceparent should be removed from Attributes")
+	}
+
+	rec = ToOTel(logparser.LogEntry{Message: "forwarding request traceparent=" + header + " upstream=b"})
+	if rec.TraceID != testTraceID || rec.SpanID != testSpanID {
+		t.Errorf("traceparent not extracted from message: %+v", rec)
+	}
+
+	if _, ok := ParseTraceparent("ff-" + testTraceID + "-" + testSpanID + "-01"); ok {
+		t.Error("version ff is invalid")
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	tests := map[string]SeverityNumber{
+		"trace": SeverityTrace,
+		"DEBUG": SeverityDebug,
+		"info":  SeverityInfo,
+		"warn":  SeverityWarn,
+		"err":   SeverityError,
+		"FATAL": SeverityFatal,
+		"other": SeverityInfo,
+		"":      SeverityUnspecified,
+	}
+
+	for level, want := range tests {
+		if got := Severity(level); got != want {
+			t.Errorf("Severity(%q) = %d, want %d", level, got, want)
+		}
+	}
+}
@@ -1,102 +1,976 @@
 package logparser
 
 import (
-	"bufio"
 	"errors"
+	"fmt"
 	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-// Parser is the main interface for log parsing
+// Parser is the main interface for log parsing. Parsers are immutable once
+// created and safe for concurrent use by multiple goroutines. Functions
+// passed with WithTransform must also be safe for concurrent use when a
+// parser is shared.
 type Parser interface {
 	Parse(r io.Reader) ([]LogEntry, error)
 	ParseString(s string) ([]LogEntry, error)
 }
 
-// parser implements the Parser interface
+// parser implements the Parser interface. Its fields are set by the
+// constructors and never modified; state for a single call lives in
+// parseRun, and anything cached across calls must be initialized with
+// sync.Once or atomics.
 type parser struct {
 	format   Format
-	detector *detector
+	cfg      config
+	detector *Detector
 }
 
-// New creates a parser with auto-detection
-func New() Parser {
+// Parsing constants
+const (
+	detectionSampleSize = 10   // Default lines buffered before auto-detecting the format
+	capacitySampleLines = 1000 // Lines used to estimate average line length
+)
+
+// New creates a parser. The format is auto-detected unless WithFormat is
+// given. Conflicting options are resolved as documented on each option; use
+// NewE to have them reported instead.
+func New(opts ...Option) Parser {
+	cfg := newConfig(opts)
+
 	return &parser{
-		format:   FormatAuto,
-		detector: newDetector(),
+		format:   cfg.format,
+		cfg:      cfg,
+		detector: &Detector{lenientJSON: cfg.lenientJSON},
 	}
 }
 
-// NewWithFormat creates a parser for specific format
-func NewWithFormat(format Format) Parser {
+// NewWithFormat creates a parser for specific format. It is equivalent to
+// New with WithFormat(format) before opts.
+func NewWithFormat(format Format, opts ...Option) Parser {
+	return New(append([]Option{WithFormat(format)}, opts...)...)
+}
+
+// NewE creates a parser like New, but returns an error wrapping
+// ErrInvalidOptions if the options conflict or are out of range
+func NewE(opts ...Option) (Parser, error) {
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
 	return &parser{
-		format:   format,
-		detector: newDetector(),
+		format:   cfg.format,
+		cfg:      cfg,
+		detector: &Detector{lenientJSON: cfg.lenientJSON},
+	}, nil
+}
+
+// Must returns p, panicking if err is not nil. It is intended for
+// package-level parsers built with NewE from fixed options.
+func Must(p Parser, err error) Parser {
+	if err != nil {
+		panic(err)
 	}
+
+	return p
 }
 
 // Parse parses logs from a reader
 func (p *parser) Parse(r io.Reader) ([]LogEntry, error) {
-	var lines []string
+	entries, _, err := p.parseReader(r, runInput{name: p.cfg.sourceName})
+
+	return entries, err
+}
+
+// ParseWithStats parses logs from a reader with p and reports statistics
+// about the run, such as how many lines were seen versus emitted. Parsers
+// not made by this package parse with Parse, and only EntriesEmitted is
+// counted for them.
+func ParseWithStats(p Parser, r io.Reader) ([]LogEntry, Stats, error) {
+	if pp, ok := p.(*parser); ok {
+		return pp.parseReader(r, runInput{name: pp.cfg.sourceName})
+	}
+
+	entries, err := p.Parse(r)
+
+	return entries, Stats{EntriesEmitted: len(entries)}, err
+}
+
+// ParseFile parses logs from the file at path with p, streaming it line by
+// line. The result slice is pre-sized from the file size so large files are
+// parsed without holding the raw lines in memory. The file's modification
+// time anchors year inference unless WithReferenceTime is set. Parsers not
+// made by this package parse the file with Parse, as ParseGlob does.
+func ParseFile(p Parser, path string) ([]LogEntry, error) {
+	pp, ok := p.(*parser)
+	if !ok {
+		return parseFileWith(p, path)
+	}
 
-	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, BufferSize), BufferSize) // 1MB buffer
+	name := pp.cfg.sourceName
+	if name == "" {
+		name = path
+	}
+
+	return pp.parseFile(path, name)
+}
+
+// parseFile parses the file at path, attributing its entries to name
+func (p *parser) parseFile(path, name string) ([]LogEntry, error) {
+	file, err := os.Open(path) //nolint:gosec // path is supplied by the caller
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, _, err := p.parseReader(file, runInput{name: name, size: info.Size(), modTime: info.ModTime()})
+
+	return entries, err
+}
+
+// ParseGlob parses every file matching pattern, as filepath.Glob matches
+// it, in lexical order, returning their entries one file after another.
+// Each entry's Source is filled in with the path of its file, even when
+// WithSourceName is set, so entries can be told apart by file. A file
+// that fails to parse stops the run, with its path in the error. No
+// match is not an error. Parsers not made by this package parse each file
+// with Parse, and their entries get a Source naming the file if they have
+// none.
+func ParseGlob(p Parser, pattern string) ([]LogEntry, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []LogEntry
+
+	for _, path := range paths {
+		var entries []LogEntry
+
+		if pp, ok := p.(*parser); ok {
+			entries, err = pp.parseFile(path, path)
+		} else {
+			entries, err = parseFileWith(p, path)
+		}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			lines = append(lines, line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
 		}
+
+		all = append(all, entries...)
 	}
 
-	if err := scanner.Err(); err != nil {
+	return all, nil
+}
+
+// parseFileWith parses a file with a Parser of another package
+func parseFileWith(p Parser, path string) ([]LogEntry, error) {
+	file, err := os.Open(path) //nolint:gosec // path is supplied by the caller
+	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
+
+	entries, err := p.Parse(file)
+
+	for i := range entries {
+		if entries[i].Source == nil {
+			entries[i].Source = &Source{Name: path}
+		}
+	}
 
-	return p.parseLines(lines)
+	return entries, err
 }
 
-// ParseString parses a single log string
+// ParseString parses logs held in a string, walking its lines in place so
+// no copy or line slice of the input is made. UTF-16 strings, as read from
+// a file without conversion, are transcoded as Parse does.
 func (p *parser) ParseString(s string) ([]LogEntry, error) {
-	lines := strings.Split(s, "\n")
+	if isUTF16([]byte(s[:min(len(s), sniffSize)])) {
+		return p.Parse(strings.NewReader(s))
+	}
+
+	var offset int64
+
+	if trimmed := strings.TrimPrefix(s, "\uFEFF"); len(trimmed) != len(s) {
+		offset = int64(len(s) - len(trimmed))
+		s = trimmed
+	}
+
+	run := p.newRun(runInput{name: p.cfg.sourceName})
+	if run.format == FormatWindowsEventXML {
+		entries, _, err := run.read(strings.NewReader(s), 0)
+
+		return entries, err
+	}
+
+	size := int64(len(s))
+	lineCount := 0
+
+	// Cut lines off the input in place rather than splitting it up front
+	cursor := run.cursor(func() (string, int64, bool) {
+		n, advance, ok := lineEnd(s, true, strings.IndexAny)
+		if !ok {
+			return "", 0, false
+		}
+
+		line := s[:n]
+		s = s[advance:]
 
-	var cleanLines []string
+		start := offset
+		offset += int64(advance)
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			cleanLines = append(cleanLines, line)
+		if lineCount++; lineCount == capacitySampleLines {
+			run.reserve(int(size * int64(lineCount) / offset))
 		}
+
+		return line, start, true
+	})
+
+	if err := run.feed(cursor); err != nil {
+		return nil, err
 	}
 
-	return p.parseLines(cleanLines)
+	entries, _, err := run.finish()
+
+	return entries, err
+}
+
+// parseReader streams lines from r into a parse run. A positive input size
+// is used to estimate the number of entries once enough lines have been
+// seen.
+func (p *parser) parseReader(r io.Reader, in runInput) ([]LogEntry, Stats, error) {
+	return p.newRun(in).read(r, in.size)
 }
 
-// parseLines parses an array of log lines
-func (p *parser) parseLines(lines []string) ([]LogEntry, error) {
-	if len(lines) == 0 {
-		return []LogEntry{}, nil
+// read streams lines, or records for XML formats, from r into the run and
+// finishes it
+func (r *parseRun) read(src io.Reader, size int64) ([]LogEntry, Stats, error) {
+	if r.format == FormatWindowsEventXML {
+		return r.readWinEvents(src)
 	}
 
-	format := p.format
+	start := r.cfg.timer.start()
+	src, skipped := decodeInput(src)
+	r.cfg.timer.stop(phaseScan, start, 0)
+
+	scanner := newLineScanner(src, skipped, r.cfg.lineLimit())
+	defer scanner.release()
+
+	defer func() { r.bytes = scanner.consumed }()
 
-	// Auto-detect format if needed
-	if format == FormatAuto {
-		format = p.detector.detectFormat(lines)
+	lineCount := 0
+
+	cursor := r.cursor(func() (string, int64, bool) {
+		if !scanner.Scan() {
+			return "", 0, false
+		}
+
+		lineCount++
+
+		if size > 0 && lineCount == capacitySampleLines {
+			r.reserve(int(size * int64(lineCount) / scanner.consumed))
+		}
+
+		return scanner.Text(), scanner.lineStart, true
+	})
+
+	if err := r.feed(cursor); err != nil {
+		return nil, r.stats, err
 	}
 
-	// Parse using the appropriate format parser
+	if err := scanner.Err(); err != nil {
+		return nil, r.stats, err
+	}
+
+	return r.finish()
+}
+
+// lineParseFunc parses a single trimmed, non-empty log line. Most lines
+// yield one entry; batched formats such as Fluentd forward arrays may yield
+// several. The returned slice is only valid until the next call.
+type lineParseFunc func(line string) ([]*LogEntry, error)
+
+// lineParserFor returns the line parser for a concrete format
+func lineParserFor(format Format, cfg *config) lineParseFunc {
 	switch format {
-	case FormatAuto:
-		// This should not happen as FormatAuto is handled above
-		return nil, errors.New("auto-detection failed")
 	case FormatJSON:
-		return parseJSON(lines)
+		keys := make(interner)
+		single := singleEntry(func(line string) (*LogEntry, error) {
+			return parseJSONLine(line, cfg, keys)
+		})
+
+		return func(line string) ([]*LogEntry, error) {
+			if cfg.lenientJSON {
+				line = strings.TrimSpace(relaxJSON(line))
+			}
+
+			if strings.HasPrefix(line, "[") {
+				return parseFluentLine(line, cfg)
+			}
+
+			return single(line)
+		}
 	case FormatLogfmt:
-		return parseLogfmt(lines)
-	case FormatText:
-		return parseText(lines)
+		keys := make(interner)
+
+		return singleEntry(func(line string) (*LogEntry, error) {
+			return parseLogfmtLine(line, cfg, keys)
+		})
+	case FormatWindowsEventXML:
+		return singleEntry(func(line string) (*LogEntry, error) {
+			return parseWinEventLine(line, cfg)
+		})
+	case FormatALB:
+		return singleEntry(func(line string) (*LogEntry, error) {
+			return parseALBLine(line, cfg)
+		})
+	case FormatHAProxy:
+		return singleEntry(func(line string) (*LogEntry, error) {
+			return parseHAProxyLine(line, cfg)
+		})
+	case FormatSquid:
+		return singleEntry(func(line string) (*LogEntry, error) {
+			return parseSquidLine(line, cfg)
+		})
+	case FormatAuto, FormatText:
+		return textLineParser(cfg, nil)
 	default:
-		return parseText(lines) // Default fallback
+		return lineParserFor(FormatText, cfg) // Default fallback
+	}
+}
+
+// textLineParser returns the text line parser, counting the lines each
+// pattern matches in hits unless it is nil
+func textLineParser(cfg *config, hits map[*textPattern]int) lineParseFunc {
+	patterns := builtinTextPatterns()
+	if len(cfg.textPatterns) > 0 {
+		patterns = append(slices.Clip(cfg.textPatterns), patterns...)
+	}
+
+	var (
+		prev  *textPattern
+		block contextBlock
+	)
+
+	return singleEntry(func(line string) (*LogEntry, error) {
+		if cfg.contextDepth > 0 {
+			if entry, ok := block.fold(line, cfg); ok {
+				return entry, errContinuation
+			}
+		}
+
+		entry, matched, err := parseTextLine(line, patterns, prev, cfg)
+		prev = matched
+		block.open = err == nil
+
+		if hits != nil && matched != nil {
+			hits[matched]++
+		}
+
+		return entry, err
+	})
+}
+
+// singleEntry adapts a one-entry line parser, reusing the result slice
+func singleEntry(parse func(line string) (*LogEntry, error)) lineParseFunc {
+	var buf [1]*LogEntry
+
+	return func(line string) ([]*LogEntry, error) {
+		entry, err := parse(line)
+		if entry == nil {
+			return nil, err
+		}
+
+		buf[0] = entry
+
+		return buf[:], err
+	}
+}
+
+// linePos locates a line within its input
+type linePos struct {
+	line   int
+	offset int64
+}
+
+// lineCursor walks the physical lines of an input, numbering them, and
+// yields the trimmed lines to parse. Blank and comment lines are counted in
+// the run's stats and passed over, so line numbers always match the input.
+type lineCursor struct {
+	next  func() (string, int64, bool) // Next raw line and the offset of its start
+	more  func() bool                  // Whether input may follow once next runs out; nil when not
+	join  *lineJoiner                  // Joins wrapped lines, under WithWrappedLines
+	cfg   *config
+	stats *Stats
+	line  int // Number of the last line read
+}
+
+// cursor returns a line cursor over the raw lines produced by next
+func (r *parseRun) cursor(next func() (string, int64, bool)) *lineCursor {
+	return &lineCursor{next: next, join: r.newLineJoiner(), cfg: r.cfg, stats: &r.stats}
+}
+
+// scan returns the next line to parse and its position, or false at the
+// end of the input
+func (c *lineCursor) scan() (string, linePos, bool) {
+	for {
+		raw, pos, ok := c.joined()
+		if !ok {
+			return "", linePos{}, false
+		}
+
+		line := strings.TrimSpace(raw)
+
+		switch {
+		case line == "":
+			c.stats.BlankLines++
+
+			if c.cfg.keepBlank {
+				c.stats.LinesSeen++
+			}
+		case c.cfg.isComment(line):
+			c.stats.CommentLines++
+		case c.cfg.contextDepth > 0:
+			// Context blocks are told apart by their indentation
+			return strings.TrimRightFunc(raw, unicode.IsSpace), pos, true
+		default:
+			return line, pos, true
+		}
+	}
+}
+
+// read returns the next raw line and its position, or false at the end of
+// the input
+func (c *lineCursor) read() (string, linePos, bool) {
+	start := c.cfg.timer.start()
+
+	raw, offset, ok := c.next()
+	if !ok {
+		c.cfg.timer.stop(phaseScan, start, 0)
+
+		return "", linePos{}, false
+	}
+
+	c.cfg.timer.stop(phaseScan, start, 1)
+	c.line++
+
+	return raw, linePos{line: c.line, offset: offset}, true
+}
+
+// joined returns the next raw line with any wrapped lines that follow it
+// joined on. The last line read is held until the next one shows whether
+// it continues, or until the input ends.
+func (c *lineCursor) joined() (string, linePos, bool) {
+	j := c.join
+	if j == nil {
+		return c.read()
+	}
+
+	for {
+		raw, pos, ok := c.read()
+		if !ok {
+			if !j.holding || c.more != nil && c.more() {
+				return "", linePos{}, false
+			}
+
+			line, pos := j.take()
+
+			return line, pos, true
+		}
+
+		raw = strings.TrimSuffix(raw, "\r")
+
+		switch {
+		case !j.holding:
+			j.hold(raw, pos)
+		case j.joins(raw):
+			j.add(raw)
+			c.stats.WrappedLines++
+		default:
+			line, heldPos := j.take()
+			j.hold(raw, pos)
+
+			return line, heldPos, true
+		}
+	}
+}
+
+// feed adds the lines of c to the run until the input or the run ends
+func (r *parseRun) feed(c *lineCursor) error {
+	for !r.done() {
+		line, pos, ok := c.scan()
+		if !ok {
+			return nil
+		}
+
+		if err := r.add(line, pos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runInput describes the input of a parse run
+type runInput struct {
+	name    string    // Source name attributed to entries, if any
+	size    int64     // Input size in bytes, if known
+	modTime time.Time // Modification time of a file, anchoring year inference
+}
+
+// pendingLine is a line buffered until the format is detected
+type pendingLine struct {
+	text string
+	pos  linePos
+}
+
+// parseRun holds the state of a single parse call
+type parseRun struct {
+	p        *parser
+	cfg      *config // Parser config, or a copy adjusted for this input
+	source   string  // Source name attributed to entries, if any
+	format   Format  // Format of parse; FormatAuto until first detected
+	parse    lineParseFunc
+	pending  []pendingLine
+	entries  []LogEntry
+	tailNext int // Next ring slot to overwrite when a tail limit is set
+	sampler  *rand.Rand
+	smart    *smartSampler // Set by WithSmartSampling
+	dropped  LogEntry      // Last entry dropped by WithDedupFilter, while dropping
+	dropping bool          // Whether the last entry emitted was dropped as a duplicate
+	stats    Stats
+	errs     []*LineError             // Errors collected under WithMaxErrors
+	aborted  bool                     // Set once the error cap stops the run
+	canFold  bool                     // Whether the last line was stored, so continuations can attach to it
+	started  bool                     // Whether a line has been parsed
+	held     *heldLine                // Possibly truncated line, kept until it is known not to be the last
+	primed   bool                     // Whether the format came from WithProfile and is not yet contradicted
+	hits     map[*textPattern]int     // Lines matched per text pattern, for the run's profile
+	columns  *EntryColumns            // Destination of stored entries for ParseColumns
+	bytes    int64                    // Input bytes consumed by read, including line terminators
+	skips    []SkippedLine            // Skipped lines kept for a Report; nil unless reporting
+	fallback map[Format]lineParseFunc // Parsers of the fallback formats, created on first use
+}
+
+// newRun starts a parse run, deferring format selection in auto mode
+func (p *parser) newRun(in runInput) *parseRun {
+	run := &parseRun{
+		p:       p,
+		cfg:     &p.cfg,
+		source:  in.name,
+		entries: []LogEntry{},
+	}
+
+	if p.cfg.referenceTime.IsZero() && !in.modTime.IsZero() {
+		cfg := p.cfg
+		cfg.referenceTime = in.modTime
+		run.cfg = &cfg
+	}
+
+	if p.cfg.instrumented() {
+		cfg := *run.cfg
+		cfg.timer = &phaseTimer{}
+		run.cfg = &cfg
+	}
+
+	if p.format != FormatAuto {
+		run.format = p.format
+		run.parse = run.lineParser(p.format)
+	} else if p.cfg.primes() {
+		run.format = p.cfg.profile.Format
+		run.parse = run.lineParser(run.format)
+		run.primed = true
+	}
+
+	if p.cfg.samples() {
+		run.sampler = p.cfg.sampleRand()
+	}
+
+	if p.cfg.smartSampling != nil {
+		run.smart = newSmartSampler(*p.cfg.smartSampling, p.cfg.sampleRand())
+	}
+
+	return run
+}
+
+// reserve grows the entry slice capacity to hold at least n entries
+func (r *parseRun) reserve(n int) {
+	if limit := r.cfg.headLimit; limit > 0 && n > limit {
+		n = limit
+	}
+
+	if limit := r.cfg.tailLimit; limit > 0 && n > limit {
+		n = limit
+	}
+
+	if n <= cap(r.entries) {
+		return
+	}
+
+	entries := make([]LogEntry, len(r.entries), n)
+	copy(entries, r.entries)
+	r.entries = entries
+}
+
+// add parses a line, buffering it first if the format is not yet known
+func (r *parseRun) add(line string, pos linePos) error {
+	r.stats.LinesSeen++
+
+	if !utf8.ValidString(line) {
+		line = strings.ToValidUTF8(line, string(utf8.RuneError))
+	}
+
+	if r.primed {
+		return r.addPrimed(line, pos)
+	}
+
+	if r.parse != nil && r.redetects() && !r.p.detector.fits(r.format, line) {
+		r.parse = nil
+	}
+
+	if r.parse == nil {
+		r.pending = append(r.pending, pendingLine{text: line, pos: pos})
+		if len(r.pending) < r.cfg.sampleSize() {
+			return nil
+		}
+
+		return r.detect()
+	}
+
+	return r.parseLine(line, pos)
+}
+
+// done reports whether the head limit or the error cap has been reached
+func (r *parseRun) done() bool {
+	return r.aborted || (r.cfg.headLimit > 0 && r.stats.EntriesEmitted >= r.cfg.headLimit)
+}
+
+// redetects reports whether the format is detected again when a line does
+// not fit it
+func (r *parseRun) redetects() bool {
+	return r.cfg.blockDetection && r.p.format == FormatAuto
+}
+
+// detect selects the format from the buffered lines and parses them. With
+// block detection, a repeat detection only samples the leading lines that
+// do not fit the previous format, and replay stops at the first later line
+// that does not fit the selected format; the remaining lines wait for
+// another detection.
+func (r *parseRun) detect() error {
+	samples := make([]string, 0, len(r.pending))
+
+	for i, pl := range r.pending {
+		if i > 0 && r.format != FormatAuto && r.p.detector.fits(r.format, pl.text) {
+			break
+		}
+
+		samples = append(samples, pl.text)
 	}
+
+	// A garbled first line is handled as partial, so it does not vote
+	if r.format == FormatAuto && !r.started && r.cfg.partial != PartialFail && len(samples) > 1 {
+		samples = samples[1:]
+	}
+
+	r.stats.Detections++
+
+	start := r.cfg.timer.start()
+	format, confidence := r.p.detector.DetectWithConfidence(samples)
+	r.cfg.timer.stop(phaseDetect, start, len(samples))
+
+	if threshold := r.cfg.minConfidence; confidence < threshold {
+		return &AmbiguousFormatError{
+			Confidence: confidence,
+			Threshold:  threshold,
+			Scores:     r.p.detector.Scores(samples),
+		}
+	}
+
+	if r.format != FormatAuto && r.format != format {
+		r.stats.FormatSwitches = append(r.stats.FormatSwitches, FormatSwitch{
+			Line: r.pending[0].pos.line,
+			From: r.format,
+			To:   format,
+		})
+	}
+
+	r.format = format
+	r.parse = r.lineParser(format)
+
+	pending := r.pending
+	r.pending = nil
+
+	for i, pl := range pending {
+		if r.done() {
+			break
+		}
+
+		// The first line is always parsed, so every detection makes progress
+		if i > 0 && r.redetects() && !r.p.detector.fits(format, pl.text) {
+			r.parse = nil
+			r.pending = pending[i:]
+
+			if len(r.pending) < r.cfg.sampleSize() {
+				return nil
+			}
+
+			return r.detect()
+		}
+
+		if err := r.parseLine(pl.text, pl.pos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sampledOut reports whether sampling drops the next line or record
+func (r *parseRun) sampledOut() bool {
+	if r.sampler != nil && r.sampler.Float64() >= r.cfg.sampleRate {
+		r.canFold = false
+
+		return true
+	}
+
+	return false
+}
+
+// addRecord stores an entry decoded from a record that is not a single
+// line, such as an XML element. Records count as lines in Stats.
+func (r *parseRun) addRecord(entry *LogEntry, pos linePos) error {
+	r.stats.LinesSeen++
+
+	if r.sampledOut() {
+		return nil
+	}
+
+	if err := r.emit(entry, pos); err != nil {
+		return r.lineFailed("", pos, err)
+	}
+
+	return nil
+}
+
+// parseLine parses a line with the selected format and stores its entries
+func (r *parseRun) parseLine(line string, pos linePos) error {
+	if r.sampledOut() {
+		return nil
+	}
+
+	defer r.cfg.timer.stop(phaseParse, r.cfg.timer.start(), 1)
+
+	first := !r.started
+	r.started = true
+
+	if err := r.releaseHeld(); err != nil {
+		return err
+	}
+
+	entries, err := r.parse(line)
+
+	if r.cfg.partial != PartialFail {
+		failed := err != nil && !errors.Is(err, errContinuation)
+
+		switch {
+		case failed && first:
+			return r.partialLine(line, pos, nil, true)
+		case failed || err == nil && r.format == FormatLogfmt && logfmtTruncated(line):
+			// Only the last line can be cut short; wait for the next one
+			r.held = &heldLine{line: line, pos: pos, entries: append([]*LogEntry(nil), entries...), err: err}
+			r.canFold = false
+
+			return nil
+		}
+	}
+
+	return r.storeParsed(line, pos, entries, err)
+}
+
+// storeParsed stores the entries parsed from a line, or handles the error
+// it failed with
+func (r *parseRun) storeParsed(line string, pos linePos, entries []*LogEntry, err error) error {
+	if err != nil && len(r.cfg.fallbacks) > 0 && !errors.Is(err, errContinuation) {
+		entries, err = r.parseFallback(line, entries, err)
+	}
+
+	switch {
+	case errors.Is(err, errContinuation):
+		if r.foldContinuation(entries[0], errors.Is(err, errMessageContinuation)) {
+			return nil
+		}
+
+		// Nothing to attach to; keep the line as an entry of its own
+		r.cfg.finishEntry(entries[0])
+	case err != nil:
+		r.canFold = false
+
+		return r.lineFailed(line, pos, err)
+	}
+
+	for _, entry := range entries {
+		if r.done() {
+			break
+		}
+
+		r.cfg.keepRawLine(entry, line)
+
+		if err := r.emit(entry, pos); err != nil {
+			if err := r.lineFailed(line, pos, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// emit applies the post-extraction steps and transforms to an entry and
+// stores it
+func (r *parseRun) emit(entry *LogEntry, pos linePos) error {
+	r.canFold = false
+
+	if r.source != "" {
+		entry.Source = &Source{Name: r.source, Line: pos.line, Offset: pos.offset}
+	}
+
+	if err := r.cfg.postProcess(entry, &r.stats); err != nil {
+		return err
+	}
+
+	// A duplicate stays the fold target, so its continuation lines go too
+	if r.dropping = r.cfg.dedup != nil && !r.cfg.dedup.addNew(entry.Hash()); r.dropping {
+		r.stats.DuplicatesDropped++
+		r.dropped = *entry
+	} else {
+		r.store(entry)
+	}
+
+	r.canFold = true
+
+	return nil
+}
+
+// postProcess applies the option-driven steps that follow field
+// extraction: payload decoding, truncation, nested and stack trace
+// expansion, duration normalization, enrichment, hashing, grouping, level
+// escalation, and transforms. Counts are added to stats.
+func (c *config) postProcess(entry *LogEntry, stats *Stats) error {
+	if _, ok := entry.Fields["_conflicts"]; ok && c.conflicts != ConflictIgnore {
+		stats.ConflictedEntries++
+	}
+
+	if err := c.checkStrictTime(entry); err != nil {
+		return err
+	}
+
+	c.sanitizeMessage(entry)
+	stats.PayloadsUndecoded += c.decodePayloads(entry)
+	stats.FieldsTruncated += c.truncateFields(entry)
+	c.expandNested(entry)
+	c.expandStacktrace(entry)
+	stats.DurationsUnparsed += c.normalizeDurations(entry)
+
+	hits, misses := c.enrich(entry)
+	stats.EnrichmentHits += hits
+	stats.EnrichmentMisses += misses
+
+	c.hashFields(entry)
+	c.groupFields(entry)
+	c.inferTemplate(entry)
+	c.escalate(entry)
+
+	return c.applyTransforms(entry)
+}
+
+// store passes an entry to the smart sampler, if any, or keeps it
+func (r *parseRun) store(entry *LogEntry) {
+	if r.smart != nil {
+		r.smart.stage(r, entry)
+
+		return
+	}
+
+	r.keep(entry)
+}
+
+// keep appends an entry, overwriting the oldest one when a tail limit is set
+func (r *parseRun) keep(entry *LogEntry) {
+	r.stats.EntriesEmitted++
+
+	// Only the last entry is kept whole, since continuation lines may still
+	// be folded into it
+	if r.columns != nil {
+		if len(r.entries) > 0 {
+			r.columns.Append(r.entries[0])
+			r.entries = r.entries[:0]
+		}
+
+		r.entries = append(r.entries, *entry)
+
+		return
+	}
+
+	limit := r.cfg.tailLimit
+	if limit <= 0 || len(r.entries) < limit {
+		r.entries = append(r.entries, *entry)
+
+		return
+	}
+
+	r.entries[r.tailNext] = *entry
+	r.tailNext = (r.tailNext + 1) % limit
+}
+
+// finish flushes any buffered lines and returns the parsed entries
+func (r *parseRun) finish() ([]LogEntry, Stats, error) {
+	// Block detection may leave lines that start another block
+	for r.parse == nil && len(r.pending) > 0 {
+		if err := r.detect(); err != nil {
+			return nil, r.stats, err
+		}
+	}
+
+	if r.primed {
+		if err := r.flushPending(); err != nil {
+			return nil, r.stats, err
+		}
+	}
+
+	if h := r.held; h != nil && !r.done() {
+		r.held = nil
+
+		if err := r.partialLine(h.line, h.pos, h.entries, false); err != nil {
+			return nil, r.stats, err
+		}
+	}
+
+	if r.smart != nil {
+		r.smart.flush(r)
+	}
+
+	if r.tailNext > 0 {
+		ordered := make([]LogEntry, 0, len(r.entries))
+		ordered = append(ordered, r.entries[r.tailNext:]...)
+		r.entries = append(ordered, r.entries[:r.tailNext]...)
+	}
+
+	r.stats.EntriesEmitted = len(r.entries)
+	r.cfg.timer.report(r.cfg, &r.stats)
+
+	return r.entries, r.stats, r.lineErrors()
 }
@@ -1,8 +1,15 @@
 package logparser
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestJSONParser(t *testing.T) {
@@ -23,8 +30,8 @@ func TestJSONParser(t *testing.T) {
 				if e.Message != "Database connection failed" {
 					t.Errorf("want message 'Database connection failed', got %s", e.Message)
 				}
-				if service, ok := e.Fields["service"].(string); !ok || service != "api" {
-					t.Errorf("want service 'api', got %v", e.Fields["service"])
+				if e.Service != "api" {
+					t.Errorf("want service 'api', got %q", e.Service)
 				}
 			},
 		},
@@ -121,6 +128,233 @@ func TestTextParser(t *testing.T) {
 	}
 }
 
+func TestLogcatFixture(t *testing.T) {
+	file, err := os.Open("testdata/logcat.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// Logcat lines have no year; anchor inference instead of using the clock
+	ref := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	entries, err := NewWithFormat(FormatText, WithReferenceTime(ref)).Parse(file)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	wantLevels := []string{"ERROR", "INFO", "DEBUG", "WARN", "DEBUG", "FATAL"}
+	if len(entries) != len(wantLevels) {
+		t.Fatalf("want %d entries, got %d", len(wantLevels), len(entries))
+	}
+
+	for i, want := range wantLevels {
+		if entries[i].Level != want {
+			t.Errorf("entry %d: want level %s, got %s", i, want, entries[i].Level)
+		}
+	}
+
+	e := entries[0]
+	if e.Message != "ANR in com.example" {
+		t.Errorf("want message 'ANR in com.example', got %q", e.Message)
+	}
+
+	if e.Fields["pid"] != "1234" || e.Fields["tid"] != "5678" || e.Fields["tag"] != "ActivityManager" {
+		t.Errorf("unexpected fields %v", e.Fields)
+	}
+
+	if e.Timestamp.Year() != ref.Year() || e.Timestamp.Month() != time.January || e.Timestamp.Day() != 2 {
+		t.Errorf("want Jan 2 of the reference year, got %v", e.Timestamp)
+	}
+
+	if e.Timestamp.Nanosecond() != 123*int(time.Millisecond) {
+		t.Errorf("want 123ms fraction, got %v", e.Timestamp)
+	}
+
+	if tag := entries[5].Fields["tag"]; tag != "libc" {
+		t.Errorf("want padded tag trimmed to 'libc', got %q", tag)
+	}
+}
+
+func TestKlogFixture(t *testing.T) {
+	file, err := os.Open("testdata/klog.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	ref := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	entries, err := New(WithReferenceTime(ref)).Parse(file)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	wantLevels := []string{"INFO", "INFO", "WARN", "ERROR", "ERROR", "INFO", "ERROR", "FATAL"}
+	if len(entries) != len(wantLevels) {
+		t.Fatalf("want %d entries, got %d (continuation lines should fold)", len(wantLevels), len(entries))
+	}
+
+	errorCount := 0
+
+	for i, want := range wantLevels {
+		if entries[i].Level != want {
+			t.Errorf("entry %d: want level %s, got %s", i, want, entries[i].Level)
+		}
+
+		if entries[i].Level == LevelError {
+			errorCount++
+		}
+	}
+
+	if errorCount != 3 {
+		t.Errorf("want 3 ERROR entries, got %d", errorCount)
+	}
+
+	e := entries[3]
+	if e.Message != `Failed to sync deployment "default/web"` {
+		t.Errorf("unexpected message %q", e.Message)
+	}
+
+	if e.Fields["pid"] != "12345" || e.Fields["caller"] != "controller.go:87" {
+		t.Errorf("unexpected fields %v", e.Fields)
+	}
+
+	if want := time.Date(2024, 1, 2, 15, 4, 7, 123456000, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("want %v, got %v", want, e.Timestamp)
+	}
+
+	multi := entries[4].Message
+	if !strings.HasSuffix(multi, "err=<\nOperation cannot be fulfilled on replicasets.apps \"web-7d9f8b6c5d\":\n"+
+		"the object has been modified; please apply your changes to the latest version and try again\n>") {
+		t.Errorf("continuation lines not folded into the message: %q", multi)
+	}
+
+	// Without a preceding klog line, an unrecognized line stands alone
+	lone, err := NewWithFormat(FormatText).ParseString("\tindented detail\nI0102 15:04:05.000001 1 main.go:1] ok")
+	if err != nil || len(lone) != 2 || lone[0].Message != "indented detail" {
+		t.Errorf("unexpected entries %+v, %v", lone, err)
+	}
+}
+
+func TestLog4jFixture(t *testing.T) {
+	file, err := os.Open("testdata/log4j.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	entries, err := NewWithFormat(FormatText).Parse(file)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	wantLevels := []string{"ERROR", "INFO", "WARN", "DEBUG", "FATAL"}
+	if len(entries) != len(wantLevels) {
+		t.Fatalf("want %d entries, got %d", len(wantLevels), len(entries))
+	}
+
+	for i, want := range wantLevels {
+		if entries[i].Level != want {
+			t.Errorf("entry %d: want level %s, got %s", i, want, entries[i].Level)
+		}
+	}
+
+	e := entries[0]
+	if e.Message != "Unable to open connection to db01:5432" {
+		t.Errorf("unexpected message %q", e.Message)
+	}
+
+	if e.Fields["thread"] != "main" || e.Logger != "com.example.Foo" {
+		t.Errorf("unexpected fields %v, logger %q", e.Fields, e.Logger)
+	}
+
+	want := time.Date(2024, time.January, 2, 15, 4, 5, 123*int(time.Millisecond), time.UTC)
+	if !e.Timestamp.Equal(want) {
+		t.Errorf("want timestamp %v, got %v", want, e.Timestamp)
+	}
+
+	if thread := entries[2].Fields["thread"]; thread != "http-nio-8080-exec-1" {
+		t.Errorf("want thread 'http-nio-8080-exec-1', got %v", thread)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	entries, err := ParseFile(New(), "testdata/errors.log")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	file, err := os.Open("testdata/errors.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	want, err := New().Parse(file)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(entries) != len(want) || len(entries) == 0 {
+		t.Fatalf("want %d entries, got %d", len(want), len(entries))
+	}
+
+	for i := range entries {
+		if entries[i].Message != want[i].Message || entries[i].Level != want[i].Level {
+			t.Errorf("entry %d differs: %+v vs %+v", i, entries[i], want[i])
+		}
+	}
+
+	if _, err := ParseFile(New(), "testdata/does-not-exist.log"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestParseFileCapacityEstimate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeLogfmtFixture(t, path, 5000)
+
+	entries, err := ParseFile(New(), path)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if len(entries) != 5000 {
+		t.Fatalf("want 5000 entries, got %d", len(entries))
+	}
+
+	if cap(entries) > 2*len(entries) {
+		t.Errorf("capacity %d overshoots %d entries", cap(entries), len(entries))
+	}
+}
+
+func TestParseFunctionsOtherParser(t *testing.T) {
+	const input = "level=info msg=a\nlevel=error msg=b\n"
+
+	p := otherParser{NewWithFormat(FormatLogfmt)}
+
+	entries, stats, err := ParseWithStats(p, strings.NewReader(input))
+	if err != nil || len(entries) != 2 || stats.EntriesEmitted != 2 {
+		t.Errorf("ParseWithStats() = %d entries, %+v, %v", len(entries), stats, err)
+	}
+
+	_, report, err := ParseWithReport(p, strings.NewReader(input))
+	if err != nil || report.Levels[LevelError] != 1 || report.Version != Version || report.Skipped == nil {
+		t.Errorf("ParseWithReport() = %+v, %v", report, err)
+	}
+
+	cols, err := ParseColumns(p, strings.NewReader(input))
+	if err != nil || cols.Len() != 2 || cols.Entry(1).Message != "b" {
+		t.Errorf("ParseColumns() = %+v, %v", cols, err)
+	}
+
+	entries, err = ParseFile(p, "testdata/errors.log")
+	if err != nil || len(entries) == 0 || entries[0].Source == nil || entries[0].Source.Name != "testdata/errors.log" {
+		t.Errorf("ParseFile() = %d entries, %v", len(entries), err)
+	}
+}
+
 func TestFormatDetection(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -206,3 +440,136 @@ func BenchmarkTextParser(b *testing.B) {
 		_, _ = parser.ParseString(input)
 	}
 }
+
+// largeFixtureSize is the size of the generated ParseFile benchmark fixture
+const largeFixtureSize = 500 << 20
+
+// BenchmarkParseFileLarge parses a generated 500MB logfmt file and reports
+// the peak heap in use relative to the file size. The fixture is written to
+// testdata/large.log on first use and is not checked in.
+func BenchmarkParseFileLarge(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping large file benchmark in short mode")
+	}
+
+	path := filepath.Join("testdata", "large.log")
+	if info, err := os.Stat(path); err != nil || info.Size() < largeFixtureSize {
+		writeLogfmtFixture(b, path, largeFixtureSize/100)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	parser := New()
+
+	b.SetBytes(info.Size())
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var peak uint64
+
+	for range b.N {
+		runtime.GC()
+
+		stop := sampleHeap(&peak)
+		entries, err := ParseFile(parser, path)
+
+		stop()
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		runtime.KeepAlive(entries)
+	}
+
+	b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+	b.ReportMetric(float64(peak)/float64(info.Size()), "peak/file")
+}
+
+// sampleHeap records the peak HeapInuse into peak until stop is called
+func sampleHeap(peak *uint64) (stop func()) {
+	var done atomic.Bool
+
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		var stats runtime.MemStats
+
+		for !done.Load() {
+			runtime.ReadMemStats(&stats)
+
+			if stats.HeapInuse > *peak {
+				*peak = stats.HeapInuse
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	return func() {
+		done.Store(true)
+		<-finished
+	}
+}
+
+// writeLogfmtFixture writes n logfmt lines of roughly 100 bytes each to path
+func writeLogfmtFixture(tb testing.TB, path string, n int) {
+	tb.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	levels := []string{"info", "warn", "error", "debug"}
+
+	for i := range n {
+		fmt.Fprintf(w, "time=2024-01-02T15:04:05Z level=%s msg=\"request %08d handled\" service=api duration=%dms\n",
+			levels[i%len(levels)], i, i%1000)
+	}
+
+	if err := w.Flush(); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func TestSyslogFields(t *testing.T) {
+	input := "Jan 12 06:25:43 web01 sshd[4123]: Accepted publickey for deploy from 10.0.0.5 port 52144 ssh2\n" +
+		"Jan  3 07:00:01 web01 cron[77]: ERROR job backup failed\n" +
+		"Mar 30 12:00:00 db02 kernel: Out of memory: Killed process 812\n"
+
+	entries, err := NewWithFormat(FormatText).ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	e := entries[0]
+	if e.Fields["hostname"] != "web01" || e.Fields["process"] != "sshd" || e.Fields["pid"] != "4123" {
+		t.Errorf("unexpected fields %v", e.Fields)
+	}
+
+	if e.Message != "Accepted publickey for deploy from 10.0.0.5 port 52144 ssh2" || e.Level != LevelInfo {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	if e.Timestamp.Month() != time.January || e.Timestamp.Day() != 12 || e.Timestamp.Hour() != 6 {
+		t.Errorf("unexpected timestamp %v", e.Timestamp)
+	}
+
+	e = entries[1]
+	if e.Level != LevelError || e.Message != "job backup failed" || e.Timestamp.Day() != 3 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	e = entries[2]
+	if _, ok := e.Fields["pid"]; ok || e.Fields["process"] != "kernel" || e.Message != "Out of memory: Killed process 812" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
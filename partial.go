@@ -0,0 +1,185 @@
+package logparser
+
+import (
+	"errors"
+	"strings"
+)
+
+// PartialPolicy selects how a cut-off line is handled
+type PartialPolicy int
+
+const (
+	// PartialFail treats a cut-off line like any other invalid line
+	PartialFail PartialPolicy = iota
+	// PartialDrop drops a cut-off line and counts it in Stats.PartialLines
+	PartialDrop
+	// PartialKeep emits whatever could be read from a cut-off line, with
+	// Fields["_partial"] set to true
+	PartialKeep
+)
+
+// errPartialLine is the skip reason recorded for a dropped cut-off line
+var errPartialLine = errors.New("partial line")
+
+// heldLine is a line that may have been cut off, kept until the next line
+// shows it is not the last
+type heldLine struct {
+	line    string
+	pos     linePos
+	entries []*LogEntry
+	err     error
+}
+
+// WithPartialLines sets how lines cut off at a file rotation boundary are
+// handled. A log copied while it is written often ends in a truncated
+// record, and one that starts mid-file begins with the tail of one. With a
+// policy other than PartialFail, a last line that fails to parse, or a
+// logfmt last line with an unterminated quote, and a first line that fails
+// to parse are dropped or kept as partial entries; the first line is also
+// left out of format detection. For JSON, a kept line holds the key/value
+// pairs read before the cut, or after it for a first line. Lines that
+// cannot be salvaged are dropped. Both are counted in Stats.PartialLines.
+// LineParser ignores this option.
+func WithPartialLines(policy PartialPolicy) Option {
+	return func(c *config) {
+		c.partial = policy
+	}
+}
+
+// releaseHeld stores a held line now that a later line followed it
+func (r *parseRun) releaseHeld() error {
+	h := r.held
+	if h == nil {
+		return nil
+	}
+
+	r.held = nil
+
+	return r.storeParsed(h.line, h.pos, h.entries, h.err)
+}
+
+// partialLine handles a cut-off line under the partial policy. entries
+// holds what the line parsed to, if anything; head is set for a first line,
+// which lost its beginning rather than its end.
+func (r *parseRun) partialLine(line string, pos linePos, entries []*LogEntry, head bool) error {
+	r.stats.PartialLines++
+
+	var entry *LogEntry
+
+	switch {
+	case r.cfg.partial != PartialKeep:
+	case len(entries) > 0:
+		entry = entries[0]
+	case r.format == FormatJSON:
+		if raw := salvageJSON(line, head); len(raw) > 0 {
+			entry = jsonEntry(raw, r.cfg)
+		}
+	}
+
+	if entry == nil {
+		r.skip(pos, errPartialLine)
+
+		return nil
+	}
+
+	r.cfg.setField(entry, "_partial", true)
+	r.cfg.keepRawLine(entry, line)
+
+	if err := r.emit(entry, pos); err != nil {
+		return r.lineFailed(line, pos, err)
+	}
+
+	return nil
+}
+
+// salvageJSON returns the complete top-level pairs of a JSON object that
+// was cut off: those before the cut, or for a head cut, those after the
+// first comma that starts a readable pair
+func salvageJSON(line string, head bool) map[string]interface{} {
+	line = strings.TrimSpace(line)
+
+	if !head || strings.HasPrefix(line, "{") {
+		if !strings.HasPrefix(line, "{") {
+			return nil
+		}
+
+		return salvageJSONPairs(line)
+	}
+
+	// The cut may fall right before a key or inside an earlier pair
+	for i := -1; i < len(line); i++ {
+		if i >= 0 && line[i] != ',' {
+			continue
+		}
+
+		if raw, ok := decodeJSONObject("{"+line[i+1:], nil); ok && len(raw) > 0 {
+			return raw
+		}
+	}
+
+	return nil
+}
+
+// salvageJSONPairs reads the pairs of the object at the start of s up to
+// the first one that is incomplete
+func salvageJSONPairs(s string) map[string]interface{} {
+	d := jsonScanner{s: s, i: 1}
+	obj := make(map[string]interface{})
+
+	for {
+		d.skipSpace()
+
+		if d.i == len(d.s) || d.s[d.i] != '"' {
+			return obj
+		}
+
+		key, ok := d.str()
+		if !ok {
+			return obj
+		}
+
+		d.skipSpace()
+
+		if d.i == len(d.s) || d.s[d.i] != ':' {
+			return obj
+		}
+
+		d.i++
+
+		val, ok := d.value(0)
+		if !ok {
+			return obj
+		}
+
+		d.skipSpace()
+
+		// A value running up to the cut may itself be cut short
+		if d.i == len(d.s) {
+			return obj
+		}
+
+		obj[key] = val
+
+		if d.s[d.i] != ',' {
+			return obj
+		}
+
+		d.i++
+	}
+}
+
+// logfmtTruncated reports whether a logfmt line ends inside a quoted value
+func logfmtTruncated(line string) bool {
+	quoted := false
+
+	for i := 0; i < len(line); i++ {
+		switch {
+		case quoted && line[i] == '\\':
+			i++
+		case line[i] == '"' && (quoted || i > 0 && line[i-1] == '='):
+			quoted = !quoted
+		}
+	}
+
+	return quoted
+}
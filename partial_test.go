@@ -0,0 +1,150 @@
+package logparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPartialLastLine(t *testing.T) {
+	full := `{"ts":"2024-01-02T15:04:05Z","level":"info","msg":"one"}
+{"ts":"2024-01-02T15:04:06Z","level":"warn","msg":"two"}
+{"ts":"2024-01-02T15:04:07Z","level":"error","msg":"three","user":{"id":7},"path":"/pay"}`
+
+	last := strings.LastIndex(full, "\n") + 1
+
+	// Cuts inside the first value, after a complete pair, inside a nested
+	// object, and just before the closing brace
+	cuts := []struct {
+		at   string
+		want map[string]interface{}
+	}{
+		{`{"ts":"2024-01-02T15:04`, nil},
+		{`{"ts":"2024-01-02T15:04:07Z","level":"error","m`, map[string]interface{}{"level": "error"}},
+		{`{"ts":"2024-01-02T15:04:07Z","level":"error","msg":"three","user":{"i`, map[string]interface{}{"msg": "three"}},
+		{`{"ts":"2024-01-02T15:04:07Z","level":"error","msg":"three","user":{"id":7},"path":"/pay"`,
+			map[string]interface{}{"msg": "three", "user": map[string]interface{}{"id": 7.0}}},
+	}
+
+	for _, cut := range cuts {
+		if !strings.HasPrefix(full[last:], cut.at) {
+			t.Fatalf("%s is not a prefix of the last record", cut.at)
+		}
+
+		input := full[:last+len(cut.at)]
+
+		if _, err := New(WithFormat(FormatJSON)).ParseString(input); err == nil {
+			t.Errorf("cut %q: default policy accepted the line", cut.at)
+		}
+
+		entries, stats, err := ParseWithStats(New(WithPartialLines(PartialDrop)), strings.NewReader(input))
+		if err != nil || len(entries) != 2 || stats.PartialLines != 1 || stats.LinesSkipped != 1 {
+			t.Errorf("cut %q: drop gave %d entries, %d partial, err %v", cut.at, len(entries), stats.PartialLines, err)
+		}
+
+		entries, stats, err = ParseWithStats(New(WithPartialLines(PartialKeep)), strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("cut %q: %v", cut.at, err)
+		}
+
+		if cut.want == nil {
+			if len(entries) != 2 || stats.PartialLines != 1 {
+				t.Errorf("cut %q: kept %d entries, want the unsalvageable line dropped", cut.at, len(entries))
+			}
+
+			continue
+		}
+
+		if len(entries) != 3 || stats.PartialLines != 1 {
+			t.Fatalf("cut %q: kept %d entries with %d partial, want 3 with 1", cut.at, len(entries), stats.PartialLines)
+		}
+
+		got := entries[2]
+		if got.Fields["_partial"] != true || got.Timestamp.Second() != 7 || got.Level != LevelError {
+			t.Errorf("cut %q: entry = %+v", cut.at, got)
+		}
+
+		if msg, ok := cut.want["msg"]; ok && got.Message != msg {
+			t.Errorf("cut %q: message = %q, want %q", cut.at, got.Message, msg)
+		}
+
+		if user, ok := cut.want["user"]; ok && !reflect.DeepEqual(got.Fields["user"], user) {
+			t.Errorf("cut %q: user = %v, want %v", cut.at, got.Fields["user"], user)
+		}
+	}
+}
+
+func TestPartialOnlyAtBoundaries(t *testing.T) {
+	input := "{\"msg\":\"a\"}\n{\"msg\":\"b\n{\"msg\":\"c\"}\n"
+
+	if _, err := New(WithPartialLines(PartialKeep)).ParseString(input); err == nil {
+		t.Error("a broken line in the middle was treated as partial")
+	}
+
+	entries, err := New(WithPartialLines(PartialKeep), WithSkipInvalid(true)).ParseString(input)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("got %d entries, err %v; want the middle line skipped", len(entries), err)
+	}
+}
+
+func TestPartialFirstLine(t *testing.T) {
+	input := `05Z","level":"info","msg":"rotated","attempt":3}
+{"level":"info","msg":"one"}
+{"level":"info","msg":"two"}`
+
+	entries, stats, err := ParseWithStats(New(WithPartialLines(PartialKeep)), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if len(entries) != 3 || stats.PartialLines != 1 {
+		t.Fatalf("%d entries, %d partial", len(entries), stats.PartialLines)
+	}
+
+	if e := entries[0]; e.Message != "rotated" || e.Fields["attempt"] != 3.0 || e.Fields["_partial"] != true {
+		t.Errorf("first entry = %+v", e)
+	}
+
+	entries, stats, err = ParseWithStats(New(WithPartialLines(PartialDrop)), strings.NewReader(input))
+	if err != nil || len(entries) != 2 || stats.PartialLines != 1 {
+		t.Errorf("drop gave %d entries, %d partial, err %v", len(entries), stats.PartialLines, err)
+	}
+}
+
+func TestPartialLogfmt(t *testing.T) {
+	input := "level=info msg=one\nlevel=error msg=\"connection re"
+
+	entries, stats, err := ParseWithStats(New(WithPartialLines(PartialKeep)), strings.NewReader(input))
+	if err != nil || len(entries) != 2 || stats.PartialLines != 1 {
+		t.Fatalf("got %d entries, %d partial, err %v", len(entries), stats.PartialLines, err)
+	}
+
+	if e := entries[1]; e.Level != LevelError || e.Fields["_partial"] != true {
+		t.Errorf("last entry = %+v", e)
+	}
+
+	// Unterminated quotes are only suspicious on the last line
+	entries, stats, _ = ParseWithStats(New(WithPartialLines(PartialDrop)), strings.NewReader("msg=\"a\nmsg=b\n"))
+	if len(entries) != 2 || stats.PartialLines != 0 {
+		t.Errorf("got %d entries, %d partial", len(entries), stats.PartialLines)
+	}
+}
+
+func TestLogfmtTruncated(t *testing.T) {
+	tests := map[string]bool{
+		`a=1 b="x y"`:        false,
+		`a=1 b="x y`:         true,
+		`a="esc \" still`:    true,
+		`a="esc \" done"`:    false,
+		`say "hi" a=1`:       false,
+		`a=1 b="`:            true,
+		`msg="tail \\" x=1`:  false,
+		`msg="tail \\\" x=1`: true,
+	}
+
+	for line, want := range tests {
+		if got := logfmtTruncated(line); got != want {
+			t.Errorf("logfmtTruncated(%s) = %v, want %v", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package logparser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// payloadPreviewBytes is the number of decoded bytes shown in the hex
+// preview of a payload that is not JSON
+const payloadPreviewBytes = 64
+
+// errPayloadTooLarge reports a payload that decodes to more than the cap
+var errPayloadTooLarge = errors.New("decoded payload exceeds the size cap")
+
+// WithDecodePayloads expands base64 payloads logged in the listed fields,
+// such as payload=H4sIAAAA... Each value is base64-decoded, in standard or
+// URL encoding with or without padding, and decompressed when it starts
+// with the gzip or zlib magic bytes. A result that is UTF-8 JSON is parsed
+// into Fields[key+"_decoded"]; anything else is stored there as a hex
+// preview of its first 64 bytes. maxDecoded caps the bytes a payload may
+// decode or decompress to, which guards against decompression bombs; it
+// must be positive, and NewE rejects a cap that is not. Values that are
+// not strings or base64, or that fail to decompress or exceed the cap, are
+// left as they are and counted in Stats.PayloadsUndecoded. The original
+// value is always kept.
+func WithDecodePayloads(maxDecoded int, keys ...string) Option {
+	return func(c *config) {
+		c.payloadKeys = append(c.payloadKeys, keys...)
+		c.payloadMax = maxDecoded
+	}
+}
+
+// decodePayloads expands the configured payload fields of entry and
+// returns how many could not be decoded
+func (c *config) decodePayloads(entry *LogEntry) int {
+	failed := 0
+
+	for _, key := range c.payloadKeys {
+		val, ok := entry.Fields[key]
+		if !ok {
+			continue
+		}
+
+		s, ok := val.(string)
+		if !ok {
+			failed++
+
+			continue
+		}
+
+		decoded, err := decodePayload(s, c.payloadMax)
+		if err != nil {
+			failed++
+
+			continue
+		}
+
+		c.setField(entry, key+"_decoded", decoded)
+	}
+
+	return failed
+}
+
+// decodePayload decodes a base64 payload of at most limit bytes once
+// decoded and decompressed, returning its parsed JSON or a hex preview
+func decodePayload(s string, limit int) (interface{}, error) {
+	data, err := decodeBase64(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > limit {
+		return nil, errPayloadTooLarge
+	}
+
+	data, err = decompressPayload(data, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && utf8.Valid(trimmed) {
+		var v interface{}
+		if err := json.Unmarshal(trimmed, &v); err == nil {
+			return v, nil
+		}
+	}
+
+	return hex.EncodeToString(data[:min(len(data), payloadPreviewBytes)]), nil
+}
+
+// decodeBase64 decodes s in whichever base64 alphabet and padding it uses
+func decodeBase64(s string) ([]byte, error) {
+	if s == "" {
+		return nil, base64.CorruptInputError(0)
+	}
+
+	var err error
+
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		var data []byte
+		if data, err = enc.DecodeString(s); err == nil {
+			return data, nil
+		}
+	}
+
+	return nil, err
+}
+
+// decompressPayload inflates gzip or zlib data, reading at most limit
+// bytes; other data is returned as it is
+func decompressPayload(data []byte, limit int) ([]byte, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+
+	switch {
+	case len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b:
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	case len(data) >= 2 && data[0] == 0x78 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0:
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	default:
+		return data, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// One byte past the cap tells a payload at the cap from a larger one
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(out) > limit {
+		return nil, errPayloadTooLarge
+	}
+
+	return out, nil
+}
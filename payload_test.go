@@ -0,0 +1,115 @@
+package logparser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// gzipBase64 gzips data and base64-encodes the result
+func gzipBase64(t *testing.T, data []byte) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestWithDecodePayloads(t *testing.T) {
+	var zbuf bytes.Buffer
+
+	zw := zlib.NewWriter(&zbuf)
+	zw.Write([]byte{0xde, 0xad, 0xbe, 0xef}) //nolint:errcheck // bytes.Buffer writes cannot fail
+	zw.Close()
+
+	input := strings.Join([]string{
+		"level=debug msg=request payload=" + gzipBase64(t, []byte(`{"user":{"id":42},"items":["a","b"]}`)),
+		"level=debug msg=blob body=" + base64.RawURLEncoding.EncodeToString(zbuf.Bytes()),
+		"level=debug msg=broken payload=not*base64",
+		"level=debug msg=untouched other=" + gzipBase64(t, []byte(`{}`)),
+	}, "\n")
+
+	p := NewWithFormat(FormatLogfmt, WithDecodePayloads(1<<10, "payload", "body"))
+	entries, stats, err := ParseWithStats(p, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	decoded, ok := entries[0].Fields["payload_decoded"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("payload_decoded = %#v, want an object", entries[0].Fields["payload_decoded"])
+	}
+
+	if user, _ := decoded["user"].(map[string]interface{}); user["id"] != 42.0 {
+		t.Errorf("payload_decoded = %v", decoded)
+	}
+
+	if !strings.HasPrefix(entries[0].Fields["payload"].(string), "H4sI") {
+		t.Error("original payload not kept")
+	}
+
+	if got := entries[1].Fields["body_decoded"]; got != "deadbeef" {
+		t.Errorf("body_decoded = %#v, want a hex preview", got)
+	}
+
+	if _, ok := entries[2].Fields["payload_decoded"]; ok || entries[2].Fields["payload"] != "not*base64" {
+		t.Errorf("broken payload fields = %v", entries[2].Fields)
+	}
+
+	if _, ok := entries[3].Fields["other_decoded"]; ok {
+		t.Error("unlisted field decoded")
+	}
+
+	if stats.PayloadsUndecoded != 1 {
+		t.Errorf("PayloadsUndecoded = %d, want 1", stats.PayloadsUndecoded)
+	}
+}
+
+func TestDecodePayloadSizeCap(t *testing.T) {
+	// 16MB of zeros compresses to a few kilobytes
+	bomb := gzipBase64(t, make([]byte, 16<<20))
+	if len(bomb) > 64<<10 {
+		t.Fatalf("bomb is %d bytes encoded", len(bomb))
+	}
+
+	if _, err := decodePayload(bomb, 1<<20); !errors.Is(err, errPayloadTooLarge) {
+		t.Errorf("decodePayload() error = %v, want the cap enforced", err)
+	}
+
+	exact := gzipBase64(t, bytes.Repeat([]byte("x"), 100))
+	if got, err := decodePayload(exact, 100); err != nil || got != strings.Repeat("78", payloadPreviewBytes) {
+		t.Errorf("decodePayload() at the cap = %v, %v", got, err)
+	}
+
+	if _, err := decodePayload(exact, 99); !errors.Is(err, errPayloadTooLarge) {
+		t.Errorf("decodePayload() one byte over the cap error = %v", err)
+	}
+
+	// Uncompressed payloads are capped too
+	if _, err := decodePayload(base64.StdEncoding.EncodeToString(make([]byte, 200)), 100); !errors.Is(err, errPayloadTooLarge) {
+		t.Errorf("decodePayload() of a large raw payload error = %v", err)
+	}
+
+	p := NewWithFormat(FormatLogfmt, WithDecodePayloads(1<<20, "payload"))
+	entries, stats, err := ParseWithStats(p, strings.NewReader("msg=bomb payload="+bomb))
+	if err != nil || stats.PayloadsUndecoded != 1 || entries[0].Fields["payload"] != bomb {
+		t.Errorf("bomb entry = %v, stats %+v, %v", entries[0].Fields["payload_decoded"], stats, err)
+	}
+
+	if _, err := NewE(WithDecodePayloads(0, "payload")); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("NewE() error = %v, want a zero cap rejected", err)
+	}
+}
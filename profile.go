@@ -0,0 +1,154 @@
+package logparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Profile constants
+const (
+	profileVersion = 1 // Version written to new profiles
+	profileMisses  = 3 // Consecutive lines not fitting a profile's format that trigger detection
+)
+
+// Profile is the format state a parse run learned, saved so that a parser
+// started later can skip detection with WithProfile. Reports carry the
+// profile of their run. It marshals to JSON.
+type Profile struct {
+	Version     int      `json:"version"`
+	Format      Format   `json:"format"`                 // Format the run ended with
+	TimeLayouts []string `json:"time_layouts,omitempty"` // Timestamp layouts of the text patterns matched, most used first
+	Lines       int      `json:"lines"`                  // Lines the profile was learned from
+}
+
+// UnmarshalJSON decodes a profile, rejecting versions and formats this
+// package does not know
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	type plain Profile
+
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	if decoded.Version < 1 || decoded.Version > profileVersion {
+		return fmt.Errorf("unsupported profile version %d", decoded.Version)
+	}
+
+	*p = Profile(decoded)
+
+	return nil
+}
+
+// WithProfile primes an auto-detecting parser with a saved profile: lines
+// are parsed in the profile's format from the first one, without waiting
+// for detection samples. When profileMisses (3) consecutive lines do not
+// fit that format, the format is detected from those lines and the profile
+// is no longer used; lines that fit after fewer misses are parsed in the
+// profile's format. A profile without a concrete format, or with
+// WithFormat or WithBlockDetection, has no effect.
+func WithProfile(p Profile) Option {
+	return func(c *config) {
+		c.profile = &p
+	}
+}
+
+// primes reports whether the run starts with the profile's format
+func (c *config) primes() bool {
+	if c.profile == nil || c.format != FormatAuto || c.blockDetection {
+		return false
+	}
+
+	switch c.profile.Format {
+	case FormatJSON, FormatLogfmt, FormatText:
+		return true
+	default:
+		return false
+	}
+}
+
+// addPrimed parses a line with the profile's format, holding lines that do
+// not fit it until enough accumulate to detect the format again
+func (r *parseRun) addPrimed(line string, pos linePos) error {
+	if !r.p.detector.fits(r.format, line) {
+		r.pending = append(r.pending, pendingLine{text: line, pos: pos})
+		if len(r.pending) < profileMisses {
+			return nil
+		}
+
+		r.primed = false
+
+		return r.detect()
+	}
+
+	if err := r.flushPending(); err != nil {
+		return err
+	}
+
+	return r.parseLine(line, pos)
+}
+
+// flushPending parses held lines with the current format
+func (r *parseRun) flushPending() error {
+	pending := r.pending
+	r.pending = nil
+
+	for _, pl := range pending {
+		if r.done() {
+			break
+		}
+
+		if err := r.parseLine(pl.text, pl.pos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lineParser returns the line parser for format, counting the text
+// patterns it matches for the run's profile
+func (r *parseRun) lineParser(format Format) lineParseFunc {
+	if format != FormatText {
+		return lineParserFor(format, r.cfg)
+	}
+
+	if r.hits == nil {
+		r.hits = make(map[*textPattern]int)
+	}
+
+	return textLineParser(r.cfg, r.hits)
+}
+
+// profile returns the state the run learned
+func (r *parseRun) profile() Profile {
+	patterns := make([]*textPattern, 0, len(r.hits))
+	for pattern := range r.hits {
+		patterns = append(patterns, pattern)
+	}
+
+	sort.Slice(patterns, func(i, j int) bool {
+		if r.hits[patterns[i]] != r.hits[patterns[j]] {
+			return r.hits[patterns[i]] > r.hits[patterns[j]]
+		}
+
+		return patterns[i].regex.String() < patterns[j].regex.String()
+	})
+
+	var layouts []string
+
+	for _, pattern := range patterns {
+		if pattern.tsFormat != "" && !slices.Contains(layouts, pattern.tsFormat) {
+			layouts = append(layouts, pattern.tsFormat)
+		}
+	}
+
+	return Profile{
+		Version:     profileVersion,
+		Format:      r.format,
+		TimeLayouts: layouts,
+		Lines:       r.stats.LinesSeen,
+	}
+}
@@ -0,0 +1,132 @@
+package logparser
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// learnProfile parses input with a report and round-trips its profile
+// through JSON
+func learnProfile(t *testing.T, input string) Profile {
+	t.Helper()
+
+	_, report, err := ParseWithReport(New(), strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(report.Profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", data, err)
+	}
+
+	return profile
+}
+
+func TestProfilePrimesFormat(t *testing.T) {
+	profile := learnProfile(t, "level=info msg=a\nlevel=warn msg=b\n")
+	if profile.Format != FormatLogfmt || profile.Lines != 2 || profile.Version != profileVersion {
+		t.Fatalf("profile = %+v", profile)
+	}
+
+	parser := New(WithProfile(profile))
+
+	// Entries arrive from the first lines instead of after detection samples
+	var got []string
+
+	w := NewWriter(parser, func(e LogEntry) { got = append(got, e.Message) })
+	if _, err := w.Write([]byte("level=info msg=one\nlevel=info msg=two\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 || got[0] != "one" {
+		t.Errorf("delivered %q before Close, want [one]", got)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, stats, err := ParseWithStats(parser, strings.NewReader("msg=\"from line one\" n=1\n"))
+	if err != nil || len(entries) != 1 || entries[0].Message != "from line one" || stats.Detections != 0 {
+		t.Errorf("got %+v, %d detections, err %v", entries, stats.Detections, err)
+	}
+}
+
+func TestProfileRecovers(t *testing.T) {
+	parser := New(WithProfile(Profile{Version: profileVersion, Format: FormatLogfmt}))
+
+	input := "level=info msg=before\n" +
+		`{"level":"error","msg":"json 1"}` + "\n" +
+		`{"level":"info","msg":"json 2"}` + "\n" +
+		`{"level":"info","msg":"json 3"}` + "\n" +
+		`{"level":"info","msg":"json 4"}` + "\n"
+
+	entries, stats, err := ParseWithStats(parser, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	var messages []string
+	for _, e := range entries {
+		messages = append(messages, e.Message)
+	}
+
+	if want := []string{"before", "json 1", "json 2", "json 3", "json 4"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("messages = %q, want %q", messages, want)
+	}
+
+	if entries[1].Level != LevelError || stats.Detections != 1 {
+		t.Errorf("level %s, %d detections", entries[1].Level, stats.Detections)
+	}
+
+	want := []FormatSwitch{{Line: 2, From: FormatLogfmt, To: FormatJSON}}
+	if !reflect.DeepEqual(stats.FormatSwitches, want) {
+		t.Errorf("switches = %v, want %v", stats.FormatSwitches, want)
+	}
+
+	// Fewer misses than the threshold keep the profile
+	_, stats, _ = ParseWithStats(parser, strings.NewReader("msg=a\nplain text line\nmsg=b\n"))
+	if stats.Detections != 0 || stats.EntriesEmitted != 3 {
+		t.Errorf("after one miss: %d detections, %d entries", stats.Detections, stats.EntriesEmitted)
+	}
+}
+
+func TestProfileTimeLayouts(t *testing.T) {
+	data, err := os.ReadFile("testdata/nginx_error.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := learnProfile(t, string(data))
+	if profile.Format != FormatText || !reflect.DeepEqual(profile.TimeLayouts, []string{"2006/01/02 15:04:05"}) {
+		t.Errorf("profile = %+v", profile)
+	}
+}
+
+func TestProfileUnmarshal(t *testing.T) {
+	for _, data := range []string{
+		`{"version":0,"format":"json"}`,
+		`{"version":99,"format":"json"}`,
+		`{"version":1,"format":"yaml"}`,
+	} {
+		var p Profile
+		if err := json.Unmarshal([]byte(data), &p); err == nil {
+			t.Errorf("Unmarshal(%s) accepted %+v", data, p)
+		}
+	}
+
+	// Profiles that cannot prime a parser are ignored
+	entries, err := New(WithProfile(Profile{Format: FormatAuto})).ParseString(`{"msg":"x"}`)
+	if err != nil || entries[0].Message != "x" {
+		t.Errorf("got %+v, %v", entries, err)
+	}
+}
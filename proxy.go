@@ -0,0 +1,474 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// haproxyDateLayout is the layout of the HAProxy accept date; the
+// milliseconds are parsed as a fractional second
+const haproxyDateLayout = "02/Jan/2006:15:04:05"
+
+// haproxyHTTPTimers and haproxyTCPTimers name the timers of the HTTP log
+// (TR/Tw/Tc/Tr/Ta) and the TCP log (Tw/Tc/Tt)
+var (
+	haproxyHTTPTimers = []string{"request_ms", "queue_ms", "connect_ms", "response_ms", "total_ms"}
+	haproxyTCPTimers  = []string{"queue_ms", "connect_ms", "total_ms"}
+)
+
+// haproxyConnections and haproxyQueues name the connection counts and
+// the queue sizes
+var (
+	haproxyConnections = []string{"actconn", "feconn", "beconn", "srv_conn", "retries"}
+	haproxyQueues      = []string{"srv_queue", "backend_queue"}
+)
+
+// haproxyCauses decodes the first character of the termination state,
+// the event that ended the session
+var haproxyCauses = map[byte]string{
+	'-': "normal",
+	'C': "client abort",
+	'S': "server abort",
+	'P': "proxy abort",
+	'L': "local response",
+	'R': "resource exhausted",
+	'I': "internal error",
+	'D': "server down",
+	'U': "backup server killed",
+	'K': "admin killed",
+	'c': "client timeout",
+	's': "server timeout",
+}
+
+// haproxyStates decodes the second character of the termination state,
+// the session state when it ended
+var haproxyStates = map[byte]string{
+	'-': "completed",
+	'R': "waiting for request",
+	'Q': "queued",
+	'C': "connecting",
+	'H': "waiting for response headers",
+	'D': "data transfer",
+	'L': "last data",
+	'T': "tarpit",
+}
+
+// haproxyCookieStatus and haproxyCookieAction decode the third and fourth
+// characters of an HTTP termination state, the persistence cookie
+var (
+	haproxyCookieStatus = map[byte]string{
+		'N': "none",
+		'I': "invalid",
+		'D': "server down",
+		'V': "valid",
+		'E': "expired",
+		'O': "old",
+		'U': "unused",
+	}
+	haproxyCookieAction = map[byte]string{
+		'N': "none",
+		'I': "inserted",
+		'U': "updated",
+		'P': "provided",
+		'R': "rewritten",
+		'D': "deleted",
+	}
+)
+
+// parseHAProxyLine parses one HAProxy log line in the default HTTP or TCP
+// log format, with or without the syslog header naming the process. The
+// variant is told by the number of timers: five for HTTP, three for TCP.
+// The request line of an HTTP log becomes the message and is also split
+// into method, url, and protocol; a TCP log's message is the route, such
+// as "fe -> be/srv". Timers are float64 milliseconds in fields named
+// after what they measure, left out when -1; counts and sizes are
+// float64. The termination state is kept in termination_state and decoded
+// into termination_cause and session_state, and for HTTP cookie_status
+// and cookie_action. The level is the more severe of the status code's
+// and the termination cause's: ERROR for a 5xx status or a server-side,
+// resource, or internal failure, WARN for a 4xx status or a client, proxy,
+// or admin abort, and INFO otherwise.
+func parseHAProxyLine(line string, cfg *config) (*LogEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, ErrEmptyLine
+	}
+
+	open := haproxyDateIndex(line)
+	if open < 0 {
+		return nil, errors.New("HAProxy entry has no accept date")
+	}
+
+	end := strings.IndexByte(line[open:], ']')
+	if end < 0 {
+		return nil, errors.New("HAProxy entry has no accept date")
+	}
+
+	start := cfg.timer.start()
+	ts, err := time.Parse(haproxyDateLayout, line[open+1:open+end])
+	cfg.timer.stop(phaseExtractTS, start, 1)
+
+	if err != nil {
+		return nil, fmt.Errorf("HAProxy entry accept date: %w", err)
+	}
+
+	values := splitHAProxyFields(line[open+end+1:])
+	if len(values) < 3 {
+		return nil, fmt.Errorf("HAProxy entry has %d fields after the date, want at least 3", len(values))
+	}
+
+	timers := strings.Split(values[2], "/")
+
+	var names []string
+
+	switch len(timers) {
+	case len(haproxyHTTPTimers):
+		names = haproxyHTTPTimers
+	case len(haproxyTCPTimers):
+		names = haproxyTCPTimers
+	default:
+		return nil, fmt.Errorf("HAProxy entry has %d timers, want 5 or 3", len(timers))
+	}
+
+	http := len(names) == len(haproxyHTTPTimers)
+
+	// HTTP: status bytes req_cookie res_cookie state conns queues
+	// TCP: bytes state conns queues
+	minFields := 7
+	if http {
+		minFields = 10
+	}
+
+	if len(values) < minFields {
+		return nil, fmt.Errorf("HAProxy entry has %d fields after the date, want at least %d", len(values), minFields)
+	}
+
+	entry := &LogEntry{
+		Timestamp: wallClock(ts, cfg.timeLocation()),
+		Fields:    cfg.newFields(),
+	}
+
+	setHAProxyPrefix(entry, strings.TrimSpace(line[:open]), cfg)
+
+	frontend := values[0]
+	if f, ok := strings.CutSuffix(frontend, "~"); ok {
+		frontend = f
+		cfg.setField(entry, "ssl", true)
+	}
+
+	cfg.setField(entry, "frontend", frontend)
+
+	backend, server, _ := strings.Cut(values[1], "/")
+	cfg.setField(entry, "backend", backend)
+	cfg.setField(entry, "server", server)
+
+	for i, timer := range timers {
+		setHAProxyNumber(entry, names[i], timer, cfg)
+	}
+
+	rest := values[3:]
+	status := ""
+
+	if http {
+		status = rest[0]
+		setHAProxyNumber(entry, "status", status, cfg)
+
+		rest = rest[1:]
+	}
+
+	setHAProxyNumber(entry, "bytes_read", rest[0], cfg)
+	rest = rest[1:]
+
+	if http {
+		for i, name := range []string{"request_cookie", "response_cookie"} {
+			if rest[i] != "-" {
+				cfg.setField(entry, name, rest[i])
+			}
+		}
+
+		rest = rest[2:]
+	}
+
+	state := rest[0]
+	setHAProxyState(entry, state, http, cfg)
+
+	for i, count := range [][]string{haproxyConnections, haproxyQueues} {
+		for j, value := range strings.Split(rest[1+i], "/") {
+			if j >= len(count) {
+				break
+			}
+
+			if count[j] == "retries" && strings.HasPrefix(value, "+") {
+				cfg.setField(entry, "redispatched", true)
+			}
+
+			setHAProxyNumber(entry, count[j], value, cfg)
+		}
+	}
+
+	rest = rest[3:]
+	headers := []string{"request_headers", "response_headers"}
+
+	for len(rest) > 0 && strings.HasPrefix(rest[0], "{") {
+		if len(headers) > 0 {
+			cfg.setField(entry, headers[0], strings.TrimSuffix(strings.TrimPrefix(rest[0], "{"), "}"))
+			headers = headers[1:]
+		}
+
+		rest = rest[1:]
+	}
+
+	if http && len(rest) > 0 {
+		entry.Message = rest[0]
+
+		if method, tail, ok := strings.Cut(rest[0], " "); ok {
+			url, protocol, _ := strings.Cut(tail, " ")
+			cfg.setField(entry, "method", method)
+			cfg.setField(entry, "url", url)
+
+			if protocol != "" {
+				cfg.setField(entry, "protocol", protocol)
+			}
+		}
+	} else if !http {
+		entry.Message = frontend + " -> " + values[1]
+	}
+
+	entry.Level = haproxyLevel(status, state)
+
+	cfg.finishEntry(entry)
+
+	return entry, nil
+}
+
+// haproxyDateIndex returns the index of the '[' opening the accept date,
+// such as [06/Feb/2009:12:14:14.655], or -1
+func haproxyDateIndex(line string) int {
+	for i := 0; i < len(line); {
+		j := strings.IndexByte(line[i:], '[')
+		if j < 0 {
+			return -1
+		}
+
+		i += j
+		if d := line[i:]; len(d) > 13 && d[3] == '/' && d[7] == '/' && d[12] == ':' {
+			return i
+		}
+
+		i++
+	}
+
+	return -1
+}
+
+// setHAProxyPrefix stores what precedes the accept date: the client
+// address, split into client_ip and client_port, and from a syslog header
+// ending in "haproxy[pid]:" the process, pid, and the host before it
+func setHAProxyPrefix(entry *LogEntry, prefix string, cfg *config) {
+	tokens := strings.Fields(prefix)
+	if len(tokens) == 0 {
+		return
+	}
+
+	setALBAddress(entry, "client", tokens[len(tokens)-1], cfg)
+
+	if len(tokens) < 2 {
+		return
+	}
+
+	tag := strings.TrimSuffix(tokens[len(tokens)-2], ":")
+
+	process, pid, ok := strings.Cut(tag, "[")
+	if !ok || !strings.HasSuffix(pid, "]") {
+		return
+	}
+
+	cfg.setField(entry, "process", process)
+
+	if n, err := strconv.ParseFloat(strings.TrimSuffix(pid, "]"), 64); err == nil {
+		cfg.setField(entry, "pid", n)
+	}
+
+	if len(tokens) >= 3 && !strings.Contains(tokens[len(tokens)-3], ":") {
+		cfg.setField(entry, "host", tokens[len(tokens)-3])
+	}
+}
+
+// setHAProxyNumber stores a timer, count, or size as float64, leaving out
+// the -1 HAProxy writes for an unavailable value; a leading '+', which
+// marks a value that was truncated or a redispatch, is dropped
+func setHAProxyNumber(entry *LogEntry, name, value string, cfg *config) {
+	if f, err := strconv.ParseFloat(strings.TrimPrefix(value, "+"), 64); err == nil && f != -1 {
+		cfg.setField(entry, name, f)
+	}
+}
+
+// setHAProxyState stores the termination state and its decoded flags
+func setHAProxyState(entry *LogEntry, state string, http bool, cfg *config) {
+	cfg.setField(entry, "termination_state", state)
+
+	decode := []struct {
+		name  string
+		codes map[byte]string
+	}{
+		{"termination_cause", haproxyCauses},
+		{"session_state", haproxyStates},
+		{"cookie_status", haproxyCookieStatus},
+		{"cookie_action", haproxyCookieAction},
+	}
+
+	if !http {
+		decode = decode[:2]
+	}
+
+	for i, d := range decode {
+		if i >= len(state) {
+			break
+		}
+
+		if meaning, ok := d.codes[state[i]]; ok {
+			cfg.setField(entry, d.name, meaning)
+		}
+	}
+}
+
+// haproxyLevel returns the more severe of the levels given by the status
+// code and the termination state
+func haproxyLevel(status, state string) string {
+	level := statusLevel(status)
+
+	cause := LevelInfo
+	if state != "" {
+		switch state[0] {
+		case 'S', 's', 'R', 'I', 'D':
+			cause = LevelError
+		case 'C', 'c', 'P', 'K', 'U':
+			cause = ParseLevel("warn")
+		}
+	}
+
+	if levelRank(cause) > levelRank(level) {
+		return cause
+	}
+
+	return level
+}
+
+// splitHAProxyFields splits the part of an HAProxy entry after the accept
+// date at spaces, keeping {captured headers}, with their braces, and the
+// double-quoted request line, without its quotes, whole
+func splitHAProxyFields(line string) []string {
+	values := make([]string, 0, 12)
+
+	for i := 0; i < len(line); {
+		switch line[i] {
+		case ' ':
+			i++
+
+			continue
+		case '"':
+			var value string
+
+			value, i = scanLogfmtQuoted(line, i)
+			values = append(values, value)
+
+			continue
+		}
+
+		stop := byte(' ')
+		if line[i] == '{' {
+			stop = '}'
+		}
+
+		end := strings.IndexByte(line[i+1:], stop) + 1
+		switch {
+		case end == 0:
+			end = len(line) - i
+		case stop == '}':
+			end++ // Keep the closing brace
+		}
+
+		values = append(values, line[i:i+end])
+		i += end
+	}
+
+	return values
+}
+
+// squidMinFields is the number of fields up to and including the URL,
+// the least a Squid native access log entry must have
+const squidMinFields = 7
+
+// parseSquidLine parses one entry of Squid's native access.log format:
+// time elapsed client action/code size method URL user hierarchy/peer
+// type. The message is the method and URL. Fields are duration_ms, the
+// client_ip, the cache action (such as TCP_MISS) and the status, bytes,
+// method, url, and when not "-" the user, hierarchy, peer, and
+// content_type; numbers are float64. The level is ERROR for a 5xx status
+// and WARN for 4xx, or for a DENIED or ABORTED action, and INFO otherwise.
+func parseSquidLine(line string, cfg *config) (*LogEntry, error) {
+	values := strings.Fields(line)
+	if len(values) == 0 {
+		return nil, ErrEmptyLine
+	}
+
+	if len(values) < squidMinFields {
+		return nil, fmt.Errorf("squid entry has %d fields, want at least %d", len(values), squidMinFields)
+	}
+
+	start := cfg.timer.start()
+	ts, ok := parseEpochString(values[0])
+	cfg.timer.stop(phaseExtractTS, start, 1)
+
+	if !ok {
+		return nil, fmt.Errorf("squid entry time %q is not a Unix timestamp", values[0])
+	}
+
+	entry := &LogEntry{
+		Timestamp: ts,
+		Message:   values[5] + " " + values[6],
+		Fields:    cfg.newFields(),
+	}
+
+	setHAProxyNumber(entry, "duration_ms", values[1], cfg)
+	cfg.setField(entry, "client_ip", values[2])
+
+	action, status, _ := strings.Cut(values[3], "/")
+	cfg.setField(entry, "action", action)
+	setHAProxyNumber(entry, "status", status, cfg)
+	setHAProxyNumber(entry, "bytes", values[4], cfg)
+	cfg.setField(entry, "method", values[5])
+	cfg.setField(entry, "url", values[6])
+
+	optional := []string{"user", "hierarchy", "content_type"}
+	for i, value := range values[squidMinFields:min(len(values), squidMinFields+len(optional))] {
+		if value == "-" {
+			continue
+		}
+
+		if optional[i] == "hierarchy" {
+			hierarchy, peer, _ := strings.Cut(value, "/")
+			cfg.setField(entry, "hierarchy", hierarchy)
+
+			if peer != "" && peer != "-" {
+				cfg.setField(entry, "peer", peer)
+			}
+
+			continue
+		}
+
+		cfg.setField(entry, optional[i], value)
+	}
+
+	entry.Level = statusLevel(status)
+	if entry.Level == LevelInfo && (strings.Contains(action, "DENIED") || strings.Contains(action, "ABORTED")) {
+		entry.Level = ParseLevel("warn")
+	}
+
+	cfg.finishEntry(entry)
+
+	return entry, nil
+}
@@ -0,0 +1,175 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+// checkFields compares the listed fields of e with want
+func checkFields(t *testing.T, e LogEntry, want map[string]interface{}) {
+	t.Helper()
+
+	for k, v := range want {
+		if e.Fields[k] != v {
+			t.Errorf("Fields[%q] = %#v, want %#v", k, e.Fields[k], v)
+		}
+	}
+}
+
+func TestHAProxyLog(t *testing.T) {
+	entries := parseFixture(t, "haproxy.log", WithFormat(FormatHAProxy))
+
+	checkLevels(t, entries, []string{"INFO", "INFO", "ERROR", "WARN", "WARN", "INFO"})
+
+	e := entries[0]
+	if want := time.Date(2009, 2, 6, 12, 14, 14, 655000000, time.UTC); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	if e.Message != "GET /index.html HTTP/1.1" {
+		t.Errorf("Message = %q", e.Message)
+	}
+
+	checkFields(t, e, map[string]interface{}{
+		"host":              "localhost",
+		"process":           "haproxy",
+		"pid":               14389.0,
+		"client_ip":         "10.0.1.2",
+		"client_port":       33317.0,
+		"frontend":          "http-in",
+		"backend":           "static",
+		"server":            "srv1",
+		"request_ms":        10.0,
+		"queue_ms":          0.0,
+		"connect_ms":        30.0,
+		"response_ms":       69.0,
+		"total_ms":          109.0,
+		"status":            200.0,
+		"bytes_read":        2750.0,
+		"termination_state": "----",
+		"termination_cause": "normal",
+		"session_state":     "completed",
+		"actconn":           1.0,
+		"retries":           0.0,
+		"backend_queue":     0.0,
+		"request_headers":   "1wt.eu",
+		"response_headers":  "",
+		"method":            "GET",
+		"url":               "/index.html",
+		"protocol":          "HTTP/1.1",
+	})
+
+	if _, ok := e.Fields["request_cookie"]; ok {
+		t.Error("request_cookie set for -")
+	}
+
+	// A server abort while waiting for the connection, after redispatches
+	e = entries[2]
+	checkFields(t, e, map[string]interface{}{
+		"ssl":               true,
+		"termination_cause": "server abort",
+		"session_state":     "connecting",
+		"cookie_status":     "server down",
+		"cookie_action":     "none",
+		"retries":           3.0,
+		"redispatched":      true,
+	})
+
+	for _, k := range []string{"connect_ms", "response_ms"} {
+		if v, ok := e.Fields[k]; ok {
+			t.Errorf("Fields[%q] = %v, want none for -1", k, v)
+		}
+	}
+
+	if e := entries[3]; e.Message != "<BADREQ>" || e.Fields["server"] != "<NOSRV>" {
+		t.Errorf("bad request entry = %q %v", e.Message, e.Fields)
+	}
+
+	// The TCP log variant
+	e = entries[4]
+	if e.Message != "pg-in -> pg/db-primary" {
+		t.Errorf("Message = %q", e.Message)
+	}
+
+	checkFields(t, e, map[string]interface{}{
+		"queue_ms":          0.0,
+		"connect_ms":        1.0,
+		"total_ms":          5421.0,
+		"bytes_read":        18204.0,
+		"termination_cause": "client timeout",
+		"session_state":     "data transfer",
+		"srv_conn":          2.0,
+	})
+
+	for _, k := range []string{"status", "request_ms", "cookie_status", "method"} {
+		if v, ok := e.Fields[k]; ok {
+			t.Errorf("TCP Fields[%q] = %v, want none", k, v)
+		}
+	}
+}
+
+func TestHAProxyMalformed(t *testing.T) {
+	for _, line := range []string{
+		"10.0.1.2:33317 http-in static/srv1 10/0/30/69/109 200 2750",
+		"10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0 200 2750 - - ---- 1/1/1/1/0 0/0",
+		"10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750",
+		"10.0.1.2:33317 [06/Foo/2009:12:14:14.655] pg-in pg/db 0/1/2 10 -- 1/1/1/1/0 0/0",
+	} {
+		if _, err := ParseLine(line, FormatHAProxy); err == nil {
+			t.Errorf("ParseLine(%q) succeeded, want error", line)
+		}
+	}
+
+	// Without a syslog header, as written to stdout or a log file
+	entry, err := ParseLine("10.0.1.2:33317 [06/Feb/2009:12:14:14.655] pg-in pg/db 0/1/2 10 -- 1/1/1/1/0 0/0", FormatHAProxy)
+	if err != nil || entry.Fields["client_ip"] != "10.0.1.2" || entry.Fields["process"] != nil {
+		t.Errorf("ParseLine() = %+v, %v", entry, err)
+	}
+}
+
+func TestSquidLog(t *testing.T) {
+	entries := parseFixture(t, "squid.log", WithFormat(FormatSquid))
+
+	checkLevels(t, entries, []string{"INFO", "INFO", "WARN", "WARN", "ERROR", "INFO"})
+
+	e := entries[1]
+	if want := time.UnixMilli(1286536309144); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	if e.Message != "CONNECT mail.google.com:443" {
+		t.Errorf("Message = %q", e.Message)
+	}
+
+	checkFields(t, e, map[string]interface{}{
+		"duration_ms": 1021.0,
+		"client_ip":   "192.168.0.224",
+		"action":      "TCP_TUNNEL",
+		"status":      200.0,
+		"bytes":       5630.0,
+		"method":      "CONNECT",
+		"url":         "mail.google.com:443",
+		"user":        "alice",
+		"hierarchy":   "HIER_DIRECT",
+		"peer":        "209.85.148.19",
+	})
+
+	e = entries[2]
+	for _, k := range []string{"user", "peer"} {
+		if v, ok := e.Fields[k]; ok {
+			t.Errorf("Fields[%q] = %v, want none for -", k, v)
+		}
+	}
+
+	if e.Fields["content_type"] != "text/html" {
+		t.Errorf("content_type = %v", e.Fields["content_type"])
+	}
+
+	if _, err := ParseLine("1286536308.779 180 192.168.0.224 TCP_MISS/200 411", FormatSquid); err == nil {
+		t.Error("ParseLine() accepted a truncated entry")
+	}
+
+	if _, err := ParseLine("yesterday 180 192.168.0.224 TCP_MISS/200 411 GET http://x/", FormatSquid); err == nil {
+		t.Error("ParseLine() accepted a non-epoch time")
+	}
+}
@@ -0,0 +1,130 @@
+package logparser
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ReplayOption configures Replay and ReplayTo
+type ReplayOption func(*replayConfig)
+
+// replayConfig holds replay settings
+type replayConfig struct {
+	maxGap time.Duration
+	after  func(time.Duration) <-chan time.Time
+}
+
+// WithMaxReplayGap caps each delay between entries at d, after scaling by
+// the replay speed, so long silences in a capture do not stall a replay
+func WithMaxReplayGap(d time.Duration) ReplayOption {
+	return func(c *replayConfig) {
+		c.maxGap = d
+	}
+}
+
+// WithReplayClock replaces time.After as the source of replay delays, for
+// example with a fake clock in tests
+func WithReplayClock(after func(time.Duration) <-chan time.Time) ReplayOption {
+	return func(c *replayConfig) {
+		c.after = after
+	}
+}
+
+// errReplaySpeed is returned for a speed that is not positive
+var errReplaySpeed = errors.New("replay speed must be positive")
+
+// Replay calls fn for each entry in order, sleeping between entries for the
+// difference of their timestamps divided by speed (2.0 replays twice as
+// fast). The first entry, entries with a zero timestamp, and entries older
+// than one already replayed are passed on immediately. Replay stops with
+// the context's error when ctx is done, or with the error returned by fn.
+func Replay(ctx context.Context, entries []LogEntry, speed float64, fn func(LogEntry) error, opts ...ReplayOption) error {
+	if speed <= 0 {
+		return errReplaySpeed
+	}
+
+	cfg := replayConfig{after: time.After}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var last time.Time // Latest timestamp replayed so far
+
+	for i := range entries {
+		ts := entries[i].Timestamp
+
+		if !ts.IsZero() {
+			if !last.IsZero() && ts.After(last) {
+				if err := cfg.wait(ctx, time.Duration(float64(ts.Sub(last))/speed)); err != nil {
+					return err
+				}
+			}
+
+			if ts.After(last) {
+				last = ts
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := fn(entries[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReplayTo replays entries like Replay, writing each one to w as a JSON or
+// logfmt line as it is due. The timestamp, level, and message are written
+// under time, level, and msg, followed by the fields in key order.
+func ReplayTo(ctx context.Context, w io.Writer, entries []LogEntry, speed float64, format Format, opts ...ReplayOption) error {
+	if _, err := appendEntryLine(nil, &LogEntry{}, format); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	var buf []byte
+
+	return Replay(ctx, entries, speed, func(e LogEntry) error {
+		var err error
+
+		buf, err = appendEntryLine(buf[:0], &e, format)
+		if err != nil {
+			return err
+		}
+
+		buf = append(buf, '\n')
+
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+
+		// Flush per entry so the reader sees the original pacing
+		return bw.Flush()
+	}, opts...)
+}
+
+// wait blocks for d, capped at the maximum gap, or until ctx is done
+func (c *replayConfig) wait(ctx context.Context, d time.Duration) error {
+	if c.maxGap > 0 && d > c.maxGap {
+		d = c.maxGap
+	}
+
+	if d <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.after(d):
+		return nil
+	}
+}
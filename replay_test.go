@@ -0,0 +1,147 @@
+package logparser
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock records requested delays and fires immediately
+type fakeClock struct {
+	waits []time.Duration
+}
+
+func (c *fakeClock) after(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+
+	ch := make(chan time.Time, 1)
+	ch <- time.Time{}
+
+	return ch
+}
+
+func TestReplay(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	entries := []LogEntry{
+		{Timestamp: base, Message: "a"},
+		{Timestamp: base.Add(2 * time.Second), Message: "b"},
+		{Message: "no timestamp"},
+		{Timestamp: base.Add(time.Second), Message: "out of order"},
+		{Timestamp: base.Add(3 * time.Second), Message: "c"},
+		{Timestamp: base.Add(time.Hour), Message: "after a long silence"},
+	}
+
+	clock := &fakeClock{}
+
+	var got []string
+
+	err := Replay(context.Background(), entries, 2, func(e LogEntry) error {
+		got = append(got, e.Message)
+
+		return nil
+	}, WithReplayClock(clock.after), WithMaxReplayGap(10*time.Second))
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+
+	if len(got) != len(entries) {
+		t.Errorf("replayed %v", got)
+	}
+
+	want := []time.Duration{time.Second, 500 * time.Millisecond, 10 * time.Second}
+	if !reflect.DeepEqual(clock.waits, want) {
+		t.Errorf("waits = %v, want %v", clock.waits, want)
+	}
+}
+
+func TestReplayStops(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	entries := []LogEntry{{Timestamp: base}, {Timestamp: base.Add(time.Minute)}, {Timestamp: base.Add(2 * time.Minute)}}
+
+	errStop := errors.New("stop")
+	calls := 0
+
+	err := Replay(context.Background(), entries, 1, func(LogEntry) error {
+		calls++
+
+		return errStop
+	}, WithReplayClock((&fakeClock{}).after))
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("callback error: got %v after %d calls", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+
+	// A clock that never fires: only cancellation can end the wait
+	never := func(time.Duration) <-chan time.Time { return nil }
+
+	err = Replay(ctx, entries, 1, func(LogEntry) error {
+		calls++
+		cancel()
+
+		return nil
+	}, WithReplayClock(never))
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("cancellation: got %v after %d calls", err, calls)
+	}
+
+	if err := Replay(context.Background(), entries, 0, func(LogEntry) error { return nil }); err == nil {
+		t.Error("expected an error for zero speed")
+	}
+}
+
+func TestReplayTo(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	entries := []LogEntry{
+		{Timestamp: base, Level: "INFO", Message: "started", Fields: map[string]interface{}{"port": 8080.0}},
+		{Timestamp: base.Add(time.Second), Level: "ERROR", Message: `bad "input"`, Fields: map[string]interface{}{"user": "ada lovelace"}},
+	}
+
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatJSON, `{"time":"2024-01-02T03:00:00Z","level":"INFO","msg":"started","port":8080}` + "\n" +
+			`{"time":"2024-01-02T03:00:01Z","level":"ERROR","msg":"bad \"input\"","user":"ada lovelace"}` + "\n"},
+		{FormatLogfmt, `time=2024-01-02T03:00:00Z level=INFO msg=started port=8080` + "\n" +
+			`time=2024-01-02T03:00:01Z level=ERROR msg="bad \"input\"" user="ada lovelace"` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			var b strings.Builder
+
+			if err := ReplayTo(context.Background(), &b, entries, 1, tt.format, WithReplayClock((&fakeClock{}).after)); err != nil {
+				t.Fatalf("ReplayTo() error = %v", err)
+			}
+
+			if b.String() != tt.want {
+				t.Errorf("ReplayTo() wrote\n%s\nwant\n%s", b.String(), tt.want)
+			}
+
+			// WriteEntries writes the same lines without pacing
+			var direct strings.Builder
+			if err := WriteEntries(&direct, entries, tt.format); err != nil || direct.String() != tt.want {
+				t.Errorf("WriteEntries() = %v, wrote\n%s", err, direct.String())
+			}
+
+			// The output parses back to the same entries
+			parsed, err := NewWithFormat(tt.format).ParseString(b.String())
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			if parsed[1].Message != entries[1].Message || parsed[1].Fields["user"] != "ada lovelace" {
+				t.Errorf("round trip = %+v", parsed[1])
+			}
+		})
+	}
+
+	if err := ReplayTo(context.Background(), &strings.Builder{}, entries, 1, FormatText); err == nil {
+		t.Error("expected an error for text output")
+	}
+}
@@ -0,0 +1,364 @@
+package logparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxReportSkips caps the skipped lines listed in a Report; Stats.LinesSkipped
+// still counts every one
+const maxReportSkips = 1000
+
+// errReportFormat is returned by WriteReport for formats other than JSON and text
+var errReportFormat = errors.New("logparser: reports are written as json or text")
+
+// Report is an auditable record of a single parse run, returned by
+// ParseWithReport. It marshals to JSON with a stable schema; see
+// testdata/golden/report.json.
+type Report struct {
+	Version  string         // Library version that produced the report
+	Source   string         // Source name, if any
+	Started  time.Time      // When the run began
+	Duration time.Duration  // Wall time of the run
+	Bytes    int64          // Input bytes read, including line terminators
+	Formats  []Format       // Formats parsed, in order of first use
+	Levels   map[string]int // Entries per level; entries without a level count under ""
+	Stats    Stats
+	Skipped  []SkippedLine // Skipped lines with reasons, up to the first 1000
+	Options  ReportOptions
+	Profile  Profile // Format state learned by the run, for WithProfile
+	Error    string  // Error that ended the run, if any
+}
+
+// SkippedLine is a line dropped during a run and the reason it was dropped
+type SkippedLine struct {
+	Line   int    `json:"line"`   // 1-based line number
+	Offset int64  `json:"offset"` // Byte offset of the line start
+	Reason string `json:"reason"`
+}
+
+// ReportOptions records the parser settings that affect which lines and
+// fields a run keeps, so the run can be reproduced
+type ReportOptions struct {
+	Format          Format         `json:"format"`
+	SkipInvalid     bool           `json:"skip_invalid"`
+	KeepBlankLines  bool           `json:"keep_blank_lines"`
+	CommentPrefixes []string       `json:"comment_prefixes"`
+	WrapWidth       int            `json:"wrap_width"`
+	MaxErrors       int            `json:"max_errors"`
+	FieldAllowlist  []string       `json:"field_allowlist"`
+	FieldDenylist   []string       `json:"field_denylist"`
+	SampleRate      float64        `json:"sample_rate"`
+	SampleSeed      *uint64        `json:"sample_seed"`
+	SmartSampling   *SmartSampling `json:"smart_sampling"`
+	DedupFilter     bool           `json:"dedup_filter"` // Whether WithDedupFilter is set
+	LenientJSON     bool           `json:"lenient_json"`
+	HeadLimit       int            `json:"head_limit"`
+	TailLimit       int            `json:"tail_limit"`
+	BlockDetection  bool           `json:"block_detection"`
+	FallbackFormats []Format       `json:"fallback_formats"`
+	Transforms      int            `json:"transforms"` // Number of WithTransform functions
+}
+
+// ParseWithReport parses logs from a reader with p and returns a Report of
+// the run alongside the entries. The report is filled in even when parsing
+// fails, with the failure recorded in Report.Error. Parsers not made by this
+// package parse with Parse, and their reports hold only the version, timing,
+// levels, and Stats.EntriesEmitted.
+func ParseWithReport(p Parser, r io.Reader) ([]LogEntry, Report, error) {
+	started := time.Now()
+
+	pp, ok := p.(*parser)
+	if !ok {
+		entries, err := p.Parse(r)
+
+		report := Report{
+			Version:  Version,
+			Started:  started,
+			Duration: time.Since(started),
+			Formats:  []Format{},
+			Levels:   make(map[string]int),
+			Stats:    Stats{EntriesEmitted: len(entries)},
+			Skipped:  []SkippedLine{},
+		}
+
+		report.finish(entries, err)
+
+		return entries, report, err
+	}
+
+	run := pp.newRun(runInput{name: pp.cfg.sourceName})
+	run.skips = []SkippedLine{}
+
+	entries, stats, err := run.read(r, 0)
+
+	report := Report{
+		Version:  Version,
+		Source:   run.source,
+		Started:  started,
+		Duration: time.Since(started),
+		Bytes:    run.bytes,
+		Formats:  run.formats(),
+		Levels:   make(map[string]int),
+		Stats:    stats,
+		Skipped:  run.skips,
+		Options:  pp.reportOptions(),
+		Profile:  run.profile(),
+	}
+
+	report.finish(entries, err)
+
+	return entries, report, err
+}
+
+// finish counts the entries per level and records the error ending the run
+func (r *Report) finish(entries []LogEntry, err error) {
+
+	for i := range entries {
+		r.Levels[entries[i].Level]++
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// formats lists the formats a run parsed with, in order of first use
+func (r *parseRun) formats() []Format {
+	formats := []Format{}
+
+	add := func(f Format) {
+		for _, seen := range formats {
+			if seen == f {
+				return
+			}
+		}
+
+		formats = append(formats, f)
+	}
+
+	if switches := r.stats.FormatSwitches; len(switches) > 0 {
+		add(switches[0].From)
+
+		for _, s := range switches {
+			add(s.To)
+		}
+	} else if r.format != FormatAuto {
+		add(r.format)
+	}
+
+	return formats
+}
+
+// reportOptions records the parser's settings for a Report
+func (p *parser) reportOptions() ReportOptions {
+	cfg := &p.cfg
+
+	return ReportOptions{
+		Format:          p.format,
+		SkipInvalid:     cfg.skipInvalid,
+		KeepBlankLines:  cfg.keepBlank,
+		CommentPrefixes: cfg.commentPrefixes,
+		WrapWidth:       cfg.wrapWidth,
+		MaxErrors:       cfg.maxErrors,
+		FieldAllowlist:  cfg.fieldAllow.keys(),
+		FieldDenylist:   cfg.fieldDeny.keys(),
+		SampleRate:      cfg.sampleRate,
+		SampleSeed:      cfg.sampleSeed,
+		SmartSampling:   cfg.smartSampling,
+		DedupFilter:     cfg.dedup != nil,
+		LenientJSON:     cfg.lenientJSON,
+		HeadLimit:       cfg.headLimit,
+		TailLimit:       cfg.tailLimit,
+		BlockDetection:  cfg.blockDetection,
+		FallbackFormats: cfg.fallbacks,
+		Transforms:      len(cfg.transforms),
+	}
+}
+
+// BytesPerSecond returns the input throughput of the run
+func (r Report) BytesPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+
+	return float64(r.Bytes) / r.Duration.Seconds()
+}
+
+// LinesPerSecond returns the line throughput of the run
+func (r Report) LinesPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+
+	return float64(r.Stats.LinesSeen) / r.Duration.Seconds()
+}
+
+// reportJSON is the JSON schema of a Report. Fields may be added but are
+// never renamed or removed.
+type reportJSON struct {
+	Version        string         `json:"version"`
+	Source         string         `json:"source"`
+	Started        time.Time      `json:"started"`
+	DurationMs     float64        `json:"duration_ms"`
+	Bytes          int64          `json:"bytes"`
+	BytesPerSecond float64        `json:"bytes_per_second"`
+	LinesPerSecond float64        `json:"lines_per_second"`
+	Formats        []Format       `json:"formats"`
+	Levels         map[string]int `json:"levels"`
+	Stats          Stats          `json:"stats"`
+	Skipped        []SkippedLine  `json:"skipped"`
+	Options        ReportOptions  `json:"options"`
+	Profile        Profile        `json:"profile"`
+	Error          string         `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the report with snake_case keys, the duration in
+// milliseconds, and the derived throughput figures
+func (r Report) MarshalJSON() ([]byte, error) {
+	out := reportJSON{
+		Version:        r.Version,
+		Source:         r.Source,
+		Started:        r.Started,
+		DurationMs:     float64(r.Duration) / float64(time.Millisecond),
+		Bytes:          r.Bytes,
+		BytesPerSecond: r.BytesPerSecond(),
+		LinesPerSecond: r.LinesPerSecond(),
+		Formats:        r.Formats,
+		Levels:         r.Levels,
+		Stats:          r.Stats,
+		Skipped:        r.Skipped,
+		Options:        r.Options,
+		Profile:        r.Profile,
+		Error:          r.Error,
+	}
+
+	// Empty collections encode as [] and {} rather than null
+	if out.Formats == nil {
+		out.Formats = []Format{}
+	}
+
+	if out.Levels == nil {
+		out.Levels = map[string]int{}
+	}
+
+	if out.Skipped == nil {
+		out.Skipped = []SkippedLine{}
+	}
+
+	if out.Options.CommentPrefixes == nil {
+		out.Options.CommentPrefixes = []string{}
+	}
+
+	if out.Options.FieldAllowlist == nil {
+		out.Options.FieldAllowlist = []string{}
+	}
+
+	if out.Options.FieldDenylist == nil {
+		out.Options.FieldDenylist = []string{}
+	}
+
+	if out.Options.FallbackFormats == nil {
+		out.Options.FallbackFormats = []Format{}
+	}
+
+	return json.Marshal(out)
+}
+
+// WriteReport writes the report to w as indented JSON (FormatJSON) or as a
+// short plaintext summary (FormatText)
+func (r Report) WriteReport(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(append(data, '\n'))
+
+		return err
+	case FormatText:
+		_, err := io.WriteString(w, r.String())
+
+		return err
+	default:
+		return fmt.Errorf("%w, not %s", errReportFormat, format)
+	}
+}
+
+// String renders the report as a plaintext summary
+func (r Report) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "logparser %s", r.Version)
+
+	if r.Source != "" {
+		fmt.Fprintf(&b, " parsing %s", r.Source)
+	}
+
+	b.WriteString("\n")
+
+	names := make([]string, len(r.Formats))
+	for i, f := range r.Formats {
+		names[i] = f.String()
+	}
+
+	fmt.Fprintf(&b, "Formats:  %s\n", strings.Join(names, ", "))
+	fmt.Fprintf(&b, "Lines:    %d seen, %d entries, %d skipped\n",
+		r.Stats.LinesSeen, r.Stats.EntriesEmitted, r.Stats.LinesSkipped)
+
+	levels := make([]string, 0, len(r.Levels))
+	for level, n := range r.Levels {
+		if level == "" {
+			level = "none"
+		}
+
+		levels = append(levels, fmt.Sprintf("%s=%d", level, n))
+	}
+
+	sort.Slice(levels, func(i, j int) bool {
+		return reportLevelLess(levels[i], levels[j])
+	})
+
+	fmt.Fprintf(&b, "Levels:   %s\n", strings.Join(levels, " "))
+	fmt.Fprintf(&b, "Duration: %s (%d bytes, %.0f bytes/s, %.0f lines/s)\n",
+		r.Duration, r.Bytes, r.BytesPerSecond(), r.LinesPerSecond())
+
+	if r.Error != "" {
+		fmt.Fprintf(&b, "Error:    %s\n", r.Error)
+	}
+
+	if len(r.Skipped) > 0 {
+		b.WriteString("\nSkipped lines:\n")
+
+		for _, s := range r.Skipped {
+			fmt.Fprintf(&b, "%8d  %s\n", s.Line, s.Reason)
+		}
+	}
+
+	return b.String()
+}
+
+// reportLevelLess orders "LEVEL=n" pairs by severity, then unknown levels
+// by name
+func reportLevelLess(a, b string) bool {
+	la, _, _ := strings.Cut(a, "=")
+	lb, _, _ := strings.Cut(b, "=")
+	ra, rb := levelRank(la), levelRank(lb)
+
+	if ra != rb && ra >= 0 && rb >= 0 {
+		return ra < rb
+	}
+
+	if (ra >= 0) != (rb >= 0) {
+		return ra >= 0
+	}
+
+	return la < lb
+}
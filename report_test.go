@@ -0,0 +1,130 @@
+package logparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWithReport(t *testing.T) {
+	data, err := os.ReadFile("testdata/report.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewWithFormat(FormatJSON, WithSkipInvalid(true), WithFieldDenylist("password"), WithSourceName("report.log"))
+
+	entries, report, err := ParseWithReport(parser, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseWithReport() error = %v", err)
+	}
+
+	if len(entries) != 6 {
+		t.Fatalf("got %d entries, want 6", len(entries))
+	}
+
+	if report.Version != Version {
+		t.Errorf("Version = %q, want %q", report.Version, Version)
+	}
+
+	if report.Bytes != int64(len(data)) {
+		t.Errorf("Bytes = %d, want %d", report.Bytes, len(data))
+	}
+
+	if report.Stats.LinesSeen != 8 || report.Stats.LinesSkipped != 2 || report.Stats.EntriesEmitted != 6 {
+		t.Errorf("Stats = %+v, want 8 seen, 2 skipped, 6 emitted", report.Stats)
+	}
+
+	wantLevels := map[string]int{LevelInfo: 3, "DEBUG": 1, "WARN": 1, LevelError: 1}
+	if len(report.Levels) != len(wantLevels) {
+		t.Errorf("Levels = %v, want %v", report.Levels, wantLevels)
+	}
+
+	for level, n := range wantLevels {
+		if report.Levels[level] != n {
+			t.Errorf("Levels[%s] = %d, want %d", level, report.Levels[level], n)
+		}
+	}
+
+	if len(report.Skipped) != 2 || report.Skipped[0].Line != 3 || report.Skipped[1].Line != 5 {
+		t.Errorf("Skipped = %+v, want lines 3 and 5", report.Skipped)
+	}
+
+	// Fix the run-dependent values so the schema can be compared byte for byte
+	report.Started = time.Date(2024, 3, 1, 10, 5, 0, 0, time.UTC)
+	report.Duration = 2 * time.Millisecond
+	report.Version = "test"
+
+	var buf bytes.Buffer
+	if err := report.WriteReport(&buf, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "report.json", buf.Bytes())
+
+	buf.Reset()
+
+	if err := report.WriteReport(&buf, FormatText); err != nil {
+		t.Fatal(err)
+	}
+
+	text := buf.String()
+	for _, want := range []string{
+		"logparser test parsing report.log",
+		"Lines:    8 seen, 6 entries, 2 skipped",
+		"Levels:   DEBUG=1 INFO=3 WARN=1 ERROR=1",
+		"       3  ",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text report missing %q:\n%s", want, text)
+		}
+	}
+
+	if err := report.WriteReport(&buf, FormatLogfmt); err == nil {
+		t.Error("WriteReport(logfmt) succeeded, want error")
+	}
+}
+
+func TestParseWithReportFailure(t *testing.T) {
+	input := "{\"msg\":\"ok\"}\nlevel=info msg=switched\n{\"msg\":\"ok\"}\n{broken\n"
+
+	entries, report, err := ParseWithReport(New(WithBlockDetection()), strings.NewReader(input))
+	if err == nil {
+		t.Fatal("ParseWithReport() succeeded, want error")
+	}
+
+	if entries != nil {
+		t.Errorf("got %d entries, want none on failure", len(entries))
+	}
+
+	if report.Error != err.Error() {
+		t.Errorf("Error = %q, want %q", report.Error, err.Error())
+	}
+
+	if got := report.Formats; len(got) != 2 || got[0] != FormatJSON || got[1] != FormatLogfmt {
+		t.Errorf("Formats = %v, want [json logfmt]", got)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(mustMarshal(t, report), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded["error"] != err.Error() {
+		t.Errorf("JSON error = %v", decoded["error"])
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
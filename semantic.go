@@ -0,0 +1,78 @@
+package logparser
+
+// Keys LogEntry.Service and LogEntry.Logger are read from unless
+// WithServiceKeys or WithLoggerKeys say otherwise, in precedence order.
+// Dotted keys match a key with the dots in it or the nested value, as
+// service.name does in {"service":{"name":"api"}}.
+var (
+	DefaultServiceKeys = []string{"service", "service.name", "app", "application", "component"}
+	DefaultLoggerKeys  = []string{"logger", "logger_name", "log.logger", "category"}
+)
+
+// WithServiceKeys sets the keys LogEntry.Service is read from, in
+// precedence order, instead of DefaultServiceKeys. With no keys, Service
+// is left empty and the keys stay in Fields.
+func WithServiceKeys(keys ...string) Option {
+	return func(c *config) {
+		c.serviceKeys = append([]string{}, keys...)
+	}
+}
+
+// WithLoggerKeys sets the keys LogEntry.Logger is read from, in precedence
+// order, instead of DefaultLoggerKeys. With no keys, Logger is left empty
+// and the keys stay in Fields.
+func WithLoggerKeys(keys ...string) Option {
+	return func(c *config) {
+		c.loggerKeys = append([]string{}, keys...)
+	}
+}
+
+// extractSemanticFields sets the service and logger from the first of
+// their keys holding a non-empty string. Like the level and message keys,
+// the keys used are removed from raw, or recorded in "_service_key" and
+// "_logger_key" under WithPreserveOriginalKeys.
+func (c *config) extractSemanticFields(raw map[string]interface{}, entry *LogEntry) {
+	if len(raw) == 0 {
+		return
+	}
+
+	serviceKeys, loggerKeys := c.serviceKeys, c.loggerKeys
+	if serviceKeys == nil {
+		serviceKeys = DefaultServiceKeys
+	}
+
+	if loggerKeys == nil {
+		loggerKeys = DefaultLoggerKeys
+	}
+
+	if s, ok := c.extractSemantic(raw, serviceKeys, "_service_key"); ok {
+		entry.Service = s
+	}
+
+	if s, ok := c.extractSemantic(raw, loggerKeys, "_logger_key"); ok {
+		entry.Logger = s
+	}
+}
+
+// extractSemantic returns the value of the first key holding a non-empty
+// string and consumes the key
+func (c *config) extractSemantic(raw map[string]interface{}, keys []string, provenance string) (string, bool) {
+	for _, key := range keys {
+		val, _ := lookupField(raw, key)
+
+		s, ok := val.(string)
+		if !ok || s == "" {
+			continue
+		}
+
+		if c.preserveKeys {
+			raw[provenance] = key
+		} else {
+			deleteField(raw, key)
+		}
+
+		return s, true
+	}
+
+	return "", false
+}
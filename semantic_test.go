@@ -0,0 +1,188 @@
+package logparser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSemanticFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		input   string
+		opts    []Option
+		service string
+		logger  string
+		fields  map[string]interface{}
+	}{
+		{
+			name:    "json service and logger",
+			format:  FormatJSON,
+			input:   `{"msg":"ok","service":"api","logger":"http","region":"eu"}`,
+			service: "api",
+			logger:  "http",
+			fields:  map[string]interface{}{"region": "eu"},
+		},
+		{
+			name:    "json nested service.name and log.logger",
+			format:  FormatJSON,
+			input:   `{"msg":"ok","service":{"name":"api","version":"1.2"},"log":{"logger":"db"}}`,
+			service: "api",
+			logger:  "db",
+			fields:  map[string]interface{}{"service": map[string]interface{}{"version": "1.2"}},
+		},
+		{
+			name:    "json precedence skips empty and non-string values",
+			format:  FormatJSON,
+			input:   `{"msg":"ok","service":"","app":7,"application":"billing","component":"cron"}`,
+			service: "billing",
+			fields:  map[string]interface{}{"service": "", "app": float64(7), "component": "cron"},
+		},
+		{
+			name:    "json preserved keys",
+			format:  FormatJSON,
+			input:   `{"msg":"ok","app":"api","logger_name":"main"}`,
+			opts:    []Option{WithPreserveOriginalKeys(true)},
+			service: "api",
+			logger:  "main",
+			fields: map[string]interface{}{
+				"app": "api", "logger_name": "main", "msg": "ok",
+				"_service_key": "app", "_logger_key": "logger_name", "_msg_key": "msg",
+			},
+		},
+		{
+			name:    "json custom keys",
+			format:  FormatJSON,
+			input:   `{"msg":"ok","service":"api","svc":"billing","SourceContext":"Orders"}`,
+			opts:    []Option{WithServiceKeys("svc"), WithLoggerKeys("SourceContext")},
+			service: "billing",
+			logger:  "Orders",
+			fields:  map[string]interface{}{"service": "api"},
+		},
+		{
+			name:   "json extraction disabled",
+			format: FormatJSON,
+			input:  `{"msg":"ok","service":"api","logger":"http"}`,
+			opts:   []Option{WithServiceKeys(), WithLoggerKeys()},
+			fields: map[string]interface{}{"service": "api", "logger": "http"},
+		},
+		{
+			name:    "logfmt component and category",
+			format:  FormatLogfmt,
+			input:   `level=info msg=ok component=scheduler category=jobs attempt=2`,
+			service: "scheduler",
+			logger:  "jobs",
+			fields:  map[string]interface{}{"attempt": "2"},
+		},
+		{
+			name:    "logfmt logger_name",
+			format:  FormatLogfmt,
+			input:   `level=info msg=ok app=api logger_name=auth`,
+			service: "api",
+			logger:  "auth",
+			fields:  map[string]interface{}{},
+		},
+		{
+			name:   "logfmt heroku router duration",
+			format: FormatLogfmt,
+			input: `at=info method=GET path="/" host=example.herokuapp.com request_id=abc fwd="1.2.3.4" ` +
+				`dyno=web.1 connect=1ms service=23ms status=200 bytes=512 protocol=https`,
+		},
+		{
+			name:   "text log4j",
+			format: FormatText,
+			input:  `2024-01-02 15:04:05,123 ERROR [main] com.example.Foo - boom`,
+			logger: "com.example.Foo",
+			fields: map[string]interface{}{"thread": "main"},
+		},
+		{
+			name:   "text python logging",
+			format: FormatText,
+			input:  `2024-01-02 15:04:05,123 - app.db - WARNING - slow query`,
+			logger: "app.db",
+			fields: map[string]interface{}{},
+		},
+		{
+			name:   "text elasticsearch",
+			format: FormatText,
+			input:  `[2024-01-02T15:04:05,123][WARN ][o.e.c.r.a.DiskThresholdMonitor] [node-1] high disk watermark`,
+			logger: "o.e.c.r.a.DiskThresholdMonitor",
+			fields: map[string]interface{}{"node": "node-1"},
+		},
+		{
+			name:    "text structlog pairs",
+			format:  FormatText,
+			input:   `2024-01-02 15:04:05 [info     ] job done                       component=worker job_id=881`,
+			service: "worker",
+			fields:  map[string]interface{}{"job_id": "881"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := New(append([]Option{WithFormat(tt.format)}, tt.opts...)...).ParseString(tt.input)
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("got %d entries, error = %v", len(entries), err)
+			}
+
+			e := entries[0]
+			if e.Service != tt.service || e.Logger != tt.logger {
+				t.Errorf("service, logger = %q, %q, want %q, %q", e.Service, e.Logger, tt.service, tt.logger)
+			}
+
+			if tt.fields == nil {
+				return
+			}
+
+			delete(e.Fields, keyOrderField)
+
+			if len(e.Fields) != len(tt.fields) {
+				t.Fatalf("fields = %v, want %v", e.Fields, tt.fields)
+			}
+
+			for k, want := range tt.fields {
+				if diff := DiffEntries(LogEntry{Fields: map[string]interface{}{k: e.Fields[k]}},
+					LogEntry{Fields: map[string]interface{}{k: want}}); diff != "" {
+					t.Errorf("%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestSemanticFieldsMarshal(t *testing.T) {
+	data, err := json.Marshal(LogEntry{Message: "ok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "service") || strings.Contains(string(data), "logger") {
+		t.Errorf("empty service and logger marshalled: %s", data)
+	}
+
+	data, err = json.Marshal(LogEntry{Message: "ok", Service: "api", Logger: "http"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), `"service":"api"`) || !strings.Contains(string(data), `"logger":"http"`) {
+		t.Errorf("service and logger missing: %s", data)
+	}
+
+	var b strings.Builder
+
+	entries := []LogEntry{{Level: LevelInfo, Message: "ok", Service: "api", Logger: "http"}}
+	if err := WriteEntries(&b, entries, FormatLogfmt); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := b.String(), "level=INFO msg=ok service=api logger=http\n"; got != want {
+		t.Errorf("logfmt = %q, want %q", got, want)
+	}
+
+	back, err := NewWithFormat(FormatLogfmt).ParseString(b.String())
+	if err != nil || len(back) != 1 || !EntryEqual(entries[0], back[0], WithIgnoreTimestamp(true)) {
+		t.Errorf("round trip = %+v, error = %v", back, err)
+	}
+}
@@ -0,0 +1,160 @@
+package logparser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Summary returns the message shortened to at most maxRunes runes for
+// display, leaving Message itself untouched. Messages that fit are returned
+// with their whitespace collapsed. Longer ones are cut and marked with "…":
+//
+//   - below WARN, the first sentence is kept when it fits
+//   - a trailing parenthetical or bracketed token such as "(code=ECONNRESET)"
+//     is kept after the cut when it fits alongside some of the message
+//   - cuts fall on a word boundary where possible and never inside a quoted
+//     string, so a quoted path is shown whole or not at all
+//
+// The result depends only on the entry's level and message.
+func (e *LogEntry) Summary(maxRunes int) string {
+	if maxRunes <= 0 {
+		return ""
+	}
+
+	msg := []rune(strings.Join(strings.Fields(e.Message), " "))
+	if len(msg) <= maxRunes {
+		return string(msg)
+	}
+
+	head, tail := splitTrailingToken(msg)
+
+	// The token needs the ellipsis, a space, and at least one rune of message
+	if len(tail) == 0 || len(tail)+3 > maxRunes {
+		head, tail = msg, nil
+	}
+
+	budget := maxRunes - 1 // Room for "…"
+	if len(tail) > 0 {
+		budget -= len(tail) + 1
+	}
+
+	cut := string(shortenRunes(head, budget, levelRank(e.Level) < levelRank("WARN"))) + "…"
+	if len(tail) > 0 {
+		cut += " " + string(tail)
+	}
+
+	return cut
+}
+
+// splitTrailingToken splits a final "(...)" or "[...]" token off msg,
+// returning msg unchanged and a nil token when there is none
+func splitTrailingToken(msg []rune) (head, token []rune) {
+	if len(msg) < 2 {
+		return msg, nil
+	}
+
+	var open rune
+
+	switch msg[len(msg)-1] {
+	case ')':
+		open = '('
+	case ']':
+		open = '['
+	default:
+		return msg, nil
+	}
+
+	for i := len(msg) - 2; i > 0; i-- {
+		switch msg[i] {
+		case open:
+			if msg[i-1] != ' ' {
+				return msg, nil
+			}
+
+			return msg[:i-1], msg[i:]
+		case ')', ']', '(', '[':
+			return msg, nil
+		}
+	}
+
+	return msg, nil
+}
+
+// shortenRunes cuts msg to at most n runes, preferring the end of the first
+// sentence when sentence is set, then the last word boundary, and moving
+// the cut before any quoted string it would split
+func shortenRunes(msg []rune, n int, sentence bool) []rune {
+	if n <= 0 {
+		return nil
+	}
+
+	if len(msg) <= n {
+		return msg
+	}
+
+	if sentence {
+		if end := firstSentenceEnd(msg); end > 0 && end <= n {
+			return msg[:end]
+		}
+	}
+
+	cut := n
+
+	// Back off to a word boundary unless that would drop over half the budget
+	if msg[cut] != ' ' {
+		for i := cut - 1; i > n/2; i-- {
+			if msg[i] == ' ' {
+				cut = i
+
+				break
+			}
+		}
+	}
+
+	if start := openQuoteAt(msg, cut); start >= 0 {
+		cut = start
+	}
+
+	return []rune(strings.TrimRightFunc(string(msg[:cut]), unicode.IsSpace))
+}
+
+// firstSentenceEnd returns the length of the first sentence of msg
+// including its punctuation, or 0 if msg is a single sentence
+func firstSentenceEnd(msg []rune) int {
+	for i := 0; i < len(msg)-1; i++ {
+		if (msg[i] == '.' || msg[i] == '!' || msg[i] == '?') && msg[i+1] == ' ' {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// openQuoteAt returns the index of the opening quote of a quoted string
+// that spans position cut, or -1. Quotes open at the start of msg or after
+// a space, '=', ':', or '(' so that apostrophes are not mistaken for them.
+func openQuoteAt(msg []rune, cut int) int {
+	for i := 0; i < cut; i++ {
+		q := msg[i]
+		if q != '"' && q != '\'' && q != '`' {
+			continue
+		}
+
+		if i > 0 && !strings.ContainsRune(" =:(", msg[i-1]) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(msg) && msg[end] != q {
+			end++
+		}
+
+		if end >= cut {
+			return i
+		}
+
+		i = end
+	}
+
+	return -1
+}
@@ -0,0 +1,159 @@
+package logparser
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		name  string
+		level string
+		msg   string
+		max   int
+		want  string
+	}{
+		{
+			name: "fits",
+			msg:  "connection\n  reset",
+			max:  20,
+			want: "connection reset",
+		},
+		{
+			name:  "first sentence below WARN",
+			level: LevelInfo,
+			msg:   "Cache warmed. Loaded 1200 keys from the primary store in 3.2s",
+			max:   30,
+			want:  "Cache warmed.…",
+		},
+		{
+			name:  "no sentence cut at ERROR",
+			level: LevelError,
+			msg:   "Dial failed. Retrying against the secondary region now",
+			max:   30,
+			want:  "Dial failed. Retrying against…",
+		},
+		{
+			name:  "trailing code survives",
+			level: LevelError,
+			msg:   "read tcp 10.0.0.1:5432: connection reset by peer while streaming rows (code=ECONNRESET)",
+			max:   40,
+			want:  "read tcp 10.0.0.1:543… (code=ECONNRESET)",
+		},
+		{
+			name:  "trailing bracketed id survives",
+			level: "WARN",
+			msg:   "request exceeded the configured deadline and was cancelled [req-7f3a]",
+			max:   30,
+			want:  "request exceeded… [req-7f3a]",
+		},
+		{
+			name:  "token too long to keep",
+			level: LevelError,
+			msg:   "failed (a very long parenthetical that cannot fit)",
+			max:   20,
+			want:  "failed (a very long…",
+		},
+		{
+			name:  "quoted path kept whole or dropped",
+			level: LevelError,
+			msg:   `open "/var/lib/service/data/segments/000123.log": permission denied`,
+			max:   30,
+			want:  "open…",
+		},
+		{
+			name:  "apostrophe is not a quote",
+			level: LevelError,
+			msg:   "can't reach the upstream server after five attempts",
+			max:   25,
+			want:  "can't reach the upstream…",
+		},
+		{
+			name: "single rune",
+			msg:  "hello world",
+			max:  1,
+			want: "…",
+		},
+		{
+			name: "non-positive",
+			msg:  "hello",
+			max:  0,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := LogEntry{Level: tt.level, Message: tt.msg}
+			if got := e.Summary(tt.max); got != tt.want {
+				t.Errorf("Summary(%d) = %q, want %q", tt.max, got, tt.want)
+			}
+
+			if e.Message != tt.msg {
+				t.Errorf("Summary modified Message to %q", e.Message)
+			}
+		})
+	}
+}
+
+func TestSummaryProperties(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	words := []string{"connect", "failed", "ünïcødé", "日本語", `"/tmp/a b/c"`, "it's", "retry.", "x", "=", "(", "]"}
+	levels := []string{"", "DEBUG", LevelInfo, "WARN", LevelError}
+
+	for range 5000 {
+		parts := make([]string, 1+rng.IntN(12))
+		for i := range parts {
+			parts[i] = words[rng.IntN(len(words))]
+		}
+
+		token := ""
+		if rng.IntN(2) == 0 {
+			token = []string{"(code=ECONNRESET)", "[req-42]", "(日本)"}[rng.IntN(3)]
+			parts = append(parts, token)
+		}
+
+		e := LogEntry{Level: levels[rng.IntN(len(levels))], Message: strings.Join(parts, " ")}
+		maxRunes := 1 + rng.IntN(40)
+
+		got := e.Summary(maxRunes)
+		if n := utf8.RuneCountInString(got); n > maxRunes {
+			t.Fatalf("Summary(%d) of %q = %q has %d runes", maxRunes, e.Message, got, n)
+		}
+
+		if !utf8.ValidString(got) {
+			t.Fatalf("Summary(%d) of %q is not valid UTF-8", maxRunes, e.Message)
+		}
+
+		if again := e.Summary(maxRunes); again != got {
+			t.Fatalf("Summary(%d) of %q is not deterministic: %q then %q", maxRunes, e.Message, got, again)
+		}
+
+		// A trailing token that fits beside some message must survive
+		if token != "" && utf8.RuneCountInString(token)+3 <= maxRunes && !strings.HasSuffix(got, token) {
+			t.Fatalf("Summary(%d) of %q = %q dropped %q", maxRunes, e.Message, got, token)
+		}
+	}
+}
+
+func TestWriteTextSummarize(t *testing.T) {
+	entries := []LogEntry{{
+		Level:   LevelError,
+		Message: "upstream request failed after retries (code=ETIMEDOUT)",
+	}}
+
+	var buf bytes.Buffer
+
+	f := Formatter{TimeLayout: "15:04", MaxMessageWidth: 30, Summarize: true, Fields: []string{}}
+	if err := WriteText(&buf, entries, f); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "       ERROR  upstream… (code=ETIMEDOUT)\n"
+	if buf.String() != want {
+		t.Errorf("WriteText() = %q, want %q", buf.String(), want)
+	}
+}
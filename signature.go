@@ -0,0 +1,133 @@
+package logparser
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrorGroup summarises error entries sharing a signature
+type ErrorGroup struct {
+	Signature string    `json:"signature"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Example   string    `json:"example"` // Representative raw message
+}
+
+// Patterns used to strip variable parts from error messages
+var (
+	sigExceptionRe = regexp.MustCompile(`\b(?:[a-z_][\w$]*\.)*([A-Z][\w$]*(?:Exception|Error))\b`)
+	sigErrnoRe     = regexp.MustCompile(`\bE(?:ACCES|ADDR|AGAIN|BUSY|CONN|EXIST|HOST|INVAL|IO|MFILE|` +
+		`NET|NOENT|NOSPC|NOTFOUND|PERM|PIPE|TIMEDOUT)[A-Z]*\b`)
+)
+
+// ExtractErrorSignature returns a stable signature for an error message.
+// Variable parts such as numbers, IDs, paths, and durations are replaced
+// with placeholders. For wrapped errors ("a: b: c") the innermost cause
+// is the primary component, unless an outer segment names an exception
+// class or errno, which is more specific.
+func ExtractErrorSignature(entry LogEntry) string {
+	msg := entry.Message
+	if msg == "" {
+		for _, key := range []string{"error", "err"} {
+			if s, ok := entry.Fields[key].(string); ok {
+				msg = s
+
+				break
+			}
+		}
+	}
+
+	segments := splitErrorChain(msg)
+	if len(segments) == 0 {
+		return ""
+	}
+
+	primary := len(segments) - 1
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		if sigExceptionRe.MatchString(segments[i]) || sigErrnoRe.MatchString(segments[i]) {
+			primary = i
+
+			break
+		}
+	}
+
+	sig := normalizeErrorSegment(segments[primary])
+	if primary != 0 {
+		sig += " | " + normalizeErrorSegment(segments[0])
+	}
+
+	return sig
+}
+
+// SummarizeErrors groups ERROR and FATAL entries by signature. Groups are
+// ordered by count, most frequent first.
+func SummarizeErrors(entries []LogEntry) []ErrorGroup {
+	groups := make(map[string]*ErrorGroup)
+
+	for i := range entries {
+		e := &entries[i]
+		if e.Level != LevelError && e.Level != "FATAL" {
+			continue
+		}
+
+		sig := ExtractErrorSignature(*e)
+
+		group, ok := groups[sig]
+		if !ok {
+			group = &ErrorGroup{Signature: sig, FirstSeen: e.Timestamp, LastSeen: e.Timestamp, Example: e.Message}
+			groups[sig] = group
+		}
+
+		group.Count++
+
+		if e.Timestamp.Before(group.FirstSeen) {
+			group.FirstSeen = e.Timestamp
+		}
+
+		if e.Timestamp.After(group.LastSeen) {
+			group.LastSeen = e.Timestamp
+		}
+	}
+
+	result := make([]ErrorGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+
+		return result[i].Signature < result[j].Signature
+	})
+
+	return result
+}
+
+// splitErrorChain splits a wrapped error message on ": " separators
+func splitErrorChain(msg string) []string {
+	parts := strings.Split(msg, ": ")
+	segments := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			segments = append(segments, part)
+		}
+	}
+
+	return segments
+}
+
+// normalizeErrorSegment replaces variable tokens with placeholders
+func normalizeErrorSegment(s string) string {
+	if m := sigExceptionRe.FindStringSubmatch(s); m != nil {
+		s = strings.Replace(s, m[0], m[1], 1) // Drop the package qualifier
+	}
+
+	return MessageTemplate(s)
+}
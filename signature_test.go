@@ -0,0 +1,95 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtractErrorSignature(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{
+			name: "numbers and durations",
+			msg:  "request 8812 timed out after 1.5s",
+			want: "request <n> timed out after <duration>",
+		},
+		{
+			name: "wrapped Go error uses innermost cause",
+			msg:  "fetch user 42: rpc call to 10.0.0.7:9000 failed: context deadline exceeded",
+			want: "context deadline exceeded | fetch user <n>",
+		},
+		{
+			name: "Java exception class",
+			msg:  "java.lang.NullPointerException: null",
+			want: "NullPointerException",
+		},
+		{
+			name: "errno preferred over trailing detail",
+			msg:  "write /var/lib/app/data.db: ENOSPC: no space left on device",
+			want: "ENOSPC | write <path>",
+		},
+		{
+			name: "identifiers",
+			msg:  "order 3f2504e0-4f89-11d3-9a0c-0305e82c3301 not found in shard a94f3c2d",
+			want: "order <uuid> not found in shard <hex>",
+		},
+		{
+			name: "quoted values",
+			msg:  `duplicate key value violates unique constraint "users_email_key"`,
+			want: "duplicate key value violates unique constraint <str>",
+		},
+		{
+			name: "plain words kept",
+			msg:  "database connection refused and/or reset",
+			want: "database connection refused and/or reset",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractErrorSignature(LogEntry{Message: tt.msg}); got != tt.want {
+				t.Errorf("ExtractErrorSignature(%q) = %q, want %q", tt.msg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractErrorSignatureFromField(t *testing.T) {
+	entry := LogEntry{Fields: map[string]interface{}{"error": "dial tcp 10.1.2.3:5432: connect: ECONNREFUSED"}}
+
+	if got, want := ExtractErrorSignature(entry), "ECONNREFUSED | dial tcp <ip>"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestSummarizeErrors(t *testing.T) {
+	base := time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC)
+	entries := []LogEntry{
+		{Timestamp: base.Add(2 * time.Minute), Level: LevelError, Message: "query users: context deadline exceeded"},
+		{Timestamp: base, Level: LevelError, Message: "query orders: context deadline exceeded"},
+		{Timestamp: base.Add(time.Minute), Level: LevelInfo, Message: "request 1 ok"},
+		{Timestamp: base.Add(3 * time.Minute), Level: "FATAL", Message: "out of memory allocating 4096 bytes"},
+		{Timestamp: base.Add(4 * time.Minute), Level: LevelError, Message: "query users: context deadline exceeded"},
+	}
+
+	groups := SummarizeErrors(entries)
+	if len(groups) != 3 {
+		t.Fatalf("want 3 groups, got %d: %+v", len(groups), groups)
+	}
+
+	top := groups[0]
+	if top.Signature != "context deadline exceeded | query users" || top.Count != 2 {
+		t.Errorf("unexpected top group %+v", top)
+	}
+
+	if !top.FirstSeen.Equal(base.Add(2*time.Minute)) || !top.LastSeen.Equal(base.Add(4*time.Minute)) {
+		t.Errorf("unexpected first/last seen %v / %v", top.FirstSeen, top.LastSeen)
+	}
+
+	if top.Example != "query users: context deadline exceeded" {
+		t.Errorf("unexpected example %q", top.Example)
+	}
+}
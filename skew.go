@@ -0,0 +1,226 @@
+package logparser
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DefaultMaxSkew is the offset a source's clock may have before
+// SkewOptions reports it, unless MaxSkew says otherwise
+const DefaultMaxSkew = time.Second
+
+// ClockSkew is the clock offset detected for one source
+type ClockSkew struct {
+	Source  string        `json:"source"`
+	Offset  time.Duration `json:"offset"`  // How far the source's clock runs ahead of the reference
+	Samples int           `json:"samples"` // Entries the offset was measured on
+}
+
+// String describes the skew, for example "db: +5m0s over 120 entries"
+func (s ClockSkew) String() string {
+	sign := "+"
+	if s.Offset < 0 {
+		sign = ""
+	}
+
+	return fmt.Sprintf("%s: %s%s over %d entries", s.Source, sign, s.Offset, s.Samples)
+}
+
+// SkewOptions configures clock skew detection
+type SkewOptions struct {
+	// SourceKey is the field naming the source of an entry, such as
+	// "host" or "service"; when empty, LogEntry.Source.Name is used
+	SourceKey string
+	// Reference is the source whose clock is taken as correct (default
+	// the source with the most entries)
+	Reference string
+	// MaxSkew is the largest offset taken as ordinary jitter (default
+	// DefaultMaxSkew)
+	MaxSkew time.Duration
+	// Adjust subtracts the offset from the timestamps of skewed sources
+	// instead of only recording it
+	Adjust bool
+}
+
+// RepairMonotonicity detects sources whose clock is off by more than
+// maxSkew, grouping entries by LogEntry.Source, and records the offset on
+// their entries without changing timestamps; see SkewOptions.Repair
+func RepairMonotonicity(entries []LogEntry, maxSkew time.Duration) []ClockSkew {
+	return SkewOptions{MaxSkew: maxSkew}.Repair(entries)
+}
+
+// Repair detects sources whose clock is off by more than MaxSkew in
+// entries that are in the order they were written, such as a log that
+// several services append to, where a skewed source shows up as
+// timestamps out of step with the lines around it. Each entry of a source
+// is compared with the midpoint of the nearest reference entries before
+// and after it in the slice, and the median difference is the source's
+// offset. Entries of a skewed source get the offset as a time.Duration in
+// Fields["_clock_skew"], or with Adjust have it subtracted from their
+// timestamp and recorded in Fields["_clock_offset"], so adding it back
+// restores the original. Entries without a timestamp or source are
+// ignored. Skewed sources are returned in order of first appearance.
+func (o SkewOptions) Repair(entries []LogEntry) []ClockSkew {
+	if o.MaxSkew <= 0 {
+		o.MaxSkew = DefaultMaxSkew
+	}
+
+	sources := make([]string, len(entries))
+	counts := make(map[string]int)
+
+	var order []string
+
+	for i := range entries {
+		if entries[i].Timestamp.IsZero() {
+			continue
+		}
+
+		source := o.source(&entries[i])
+		if source == "" {
+			continue
+		}
+
+		if counts[source] == 0 {
+			order = append(order, source)
+		}
+
+		sources[i] = source
+		counts[source]++
+	}
+
+	ref := o.Reference
+	if ref == "" {
+		for _, source := range order {
+			if counts[source] > counts[ref] {
+				ref = source
+			}
+		}
+	}
+
+	if counts[ref] == 0 {
+		return []ClockSkew{}
+	}
+
+	diffs := referenceDiffs(entries, sources, ref)
+	skews := []ClockSkew{}
+
+	for _, source := range order {
+		d := diffs[source]
+		if source == ref || len(d) == 0 {
+			continue
+		}
+
+		slices.Sort(d)
+
+		offset := d[len(d)/2]
+		if len(d)%2 == 0 {
+			offset = d[len(d)/2-1] + (d[len(d)/2]-d[len(d)/2-1])/2
+		}
+
+		if offset.Abs() <= o.MaxSkew {
+			continue
+		}
+
+		skews = append(skews, ClockSkew{Source: source, Offset: offset, Samples: len(d)})
+	}
+
+	o.apply(entries, sources, skews)
+
+	return skews
+}
+
+// source returns the source name of an entry, or "" if it has none
+func (o SkewOptions) source(e *LogEntry) string {
+	if o.SourceKey == "" {
+		if e.Source == nil {
+			return ""
+		}
+
+		return e.Source.Name
+	}
+
+	if val, ok := entryField(e, o.SourceKey); ok {
+		return formatValue(val)
+	}
+
+	return ""
+}
+
+// referenceDiffs returns, per source, the differences between its entries'
+// timestamps and the midpoint of the nearest ref entries around them
+func referenceDiffs(entries []LogEntry, sources []string, ref string) map[string][]time.Duration {
+	// Nearest ref entry at or after each position
+	next := make([]int, len(entries)+1)
+	next[len(entries)] = -1
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		next[i] = next[i+1]
+		if sources[i] == ref {
+			next[i] = i
+		}
+	}
+
+	diffs := make(map[string][]time.Duration)
+	prev := -1
+
+	for i := range entries {
+		switch sources[i] {
+		case "":
+			continue
+		case ref:
+			prev = i
+
+			continue
+		}
+
+		ts := entries[i].Timestamp
+
+		var diff time.Duration
+
+		switch after := next[i+1]; {
+		case prev >= 0 && after >= 0:
+			before, later := entries[prev].Timestamp, entries[after].Timestamp
+			diff = ts.Sub(before) - later.Sub(before)/2
+		case prev >= 0:
+			diff = ts.Sub(entries[prev].Timestamp)
+		default:
+			diff = ts.Sub(entries[after].Timestamp)
+		}
+
+		diffs[sources[i]] = append(diffs[sources[i]], diff)
+	}
+
+	return diffs
+}
+
+// apply records or applies the offsets of skewed sources to their entries
+func (o SkewOptions) apply(entries []LogEntry, sources []string, skews []ClockSkew) {
+	if len(skews) == 0 {
+		return
+	}
+
+	offsets := make(map[string]time.Duration, len(skews))
+	for _, s := range skews {
+		offsets[s.Source] = s.Offset
+	}
+
+	for i := range entries {
+		offset, ok := offsets[sources[i]]
+		if !ok {
+			continue
+		}
+
+		e := &entries[i]
+		if e.Fields == nil {
+			e.Fields = make(map[string]interface{}, 1)
+		}
+
+		if o.Adjust {
+			e.Timestamp = e.Timestamp.Add(-offset)
+			e.Fields["_clock_offset"] = offset
+		} else {
+			e.Fields["_clock_skew"] = offset
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package logparser
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+// skewedEntries interleaves the entries of two services in the order they
+// were written: the api, with two thirds of the entries, and the db,
+// logging with its clock skew ahead
+func skewedEntries(n int, skew time.Duration) []LogEntry {
+	rng := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // deterministic test data
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	entries := make([]LogEntry, n)
+
+	for i := range entries {
+		now = now.Add(time.Duration(rng.IntN(2000)) * time.Millisecond)
+
+		service, ts := "api", now
+		if rng.IntN(3) == 0 {
+			service, ts = "db", now.Add(skew)
+		}
+
+		entries[i] = LogEntry{
+			Timestamp: ts,
+			Level:     LevelInfo,
+			Message:   service + " event",
+			Fields:    map[string]interface{}{"service": service},
+			Source:    &Source{Name: service + ".log", Line: i + 1},
+		}
+	}
+
+	return entries
+}
+
+func TestRepairMonotonicity(t *testing.T) {
+	entries := skewedEntries(400, 5*time.Minute)
+
+	skews := RepairMonotonicity(entries, 10*time.Second)
+	if len(skews) != 1 || skews[0].Source != "db.log" {
+		t.Fatalf("skews = %v, want db.log", skews)
+	}
+
+	if off := skews[0].Offset - 5*time.Minute; off.Abs() > 2*time.Second {
+		t.Errorf("offset = %v, want 5m within 2s", skews[0].Offset)
+	}
+
+	if want := "db.log: +" + skews[0].Offset.String() + " over "; !strings.HasPrefix(skews[0].String(), want) {
+		t.Errorf("String() = %q", skews[0].String())
+	}
+
+	for _, e := range entries {
+		_, annotated := e.Fields["_clock_skew"]
+		if annotated != (e.Source.Name == "db.log") {
+			t.Fatalf("entry from %s: annotated = %v", e.Source.Name, annotated)
+		}
+
+		if _, ok := e.Fields["_clock_offset"]; ok {
+			t.Fatal("timestamps adjusted without Adjust")
+		}
+	}
+}
+
+func TestSkewOptionsAdjust(t *testing.T) {
+	entries := skewedEntries(400, -5*time.Minute)
+	original := make([]time.Time, len(entries))
+
+	for i := range entries {
+		original[i] = entries[i].Timestamp
+	}
+
+	skews := SkewOptions{SourceKey: "service", Reference: "api", Adjust: true}.Repair(entries)
+	if len(skews) != 1 || skews[0].Source != "db" {
+		t.Fatalf("skews = %v, want db", skews)
+	}
+
+	if off := skews[0].Offset + 5*time.Minute; off.Abs() > 2*time.Second {
+		t.Errorf("offset = %v, want -5m within 2s", skews[0].Offset)
+	}
+
+	// Adjusted timestamps are back in writing order, give or take the
+	// estimation error, and the recorded offset restores the original
+	for i, e := range entries {
+		if i > 0 && e.Timestamp.Before(entries[i-1].Timestamp.Add(-2*time.Second)) {
+			t.Errorf("entry %d at %v still before entry %d at %v", i, e.Timestamp, i-1, entries[i-1].Timestamp)
+		}
+
+		if offset, ok := e.Fields["_clock_offset"].(time.Duration); ok {
+			if restored := e.Timestamp.Add(offset); !restored.Equal(original[i]) {
+				t.Errorf("entry %d: restored %v, want %v", i, restored, original[i])
+			}
+		} else if !e.Timestamp.Equal(original[i]) {
+			t.Errorf("entry %d of the reference moved", i)
+		}
+	}
+}
+
+func TestSkewOptionsWithinTolerance(t *testing.T) {
+	entries := skewedEntries(100, 0)
+
+	if skews := RepairMonotonicity(entries, 0); len(skews) != 0 {
+		t.Errorf("skews = %v, want none for synchronized clocks", skews)
+	}
+
+	if skews := (SkewOptions{Reference: "missing"}).Repair(entries); len(skews) != 0 {
+		t.Errorf("skews = %v, want none without the reference", skews)
+	}
+
+	if skews := RepairMonotonicity(nil, time.Second); skews == nil || len(skews) != 0 {
+		t.Errorf("skews = %v, want empty", skews)
+	}
+}
+
+func TestWithNormalizeUTC(t *testing.T) {
+	input := `{"time":"2024-03-01T19:00:00+09:00","msg":"tokyo"}` + "\n" + `{"time":"2024-03-01T10:00:01Z","msg":"utc"}`
+
+	entries, err := NewWithFormat(FormatJSON, WithNormalizeUTC(true)).ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	if e := entries[0]; !e.Timestamp.Equal(want) || e.Timestamp.Location() != time.UTC {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, want)
+	}
+
+	entries, err = NewWithFormat(FormatJSON).ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if _, offset := entries[0].Timestamp.Zone(); offset != 9*3600 {
+		t.Errorf("zone offset = %d, want +09:00 kept by default", offset)
+	}
+}
@@ -0,0 +1,243 @@
+package logparser
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// Levels outside slog's built-in four. TRACE sits one step (4) below
+// slog.LevelDebug and FATAL one step above slog.LevelError, following the
+// spacing slog uses between its own levels.
+const (
+	SlogLevelTrace = slog.Level(-8)
+	SlogLevelFatal = slog.Level(12)
+)
+
+// SlogLevel maps a level string to slog's numeric scale. Unknown levels map
+// to slog.LevelInfo.
+func SlogLevel(level string) slog.Level {
+	switch ParseLevel(level) {
+	case "TRACE":
+		return SlogLevelTrace
+	case "DEBUG":
+		return slog.LevelDebug
+	case "WARN":
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	case "FATAL":
+		return SlogLevelFatal
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// LevelFromSlog maps a slog level to a level string. Levels between the
+// named ones round down, so slog.LevelWarn+2 becomes WARN.
+func LevelFromSlog(l slog.Level) string {
+	switch {
+	case l < slog.LevelDebug:
+		return "TRACE"
+	case l < slog.LevelInfo:
+		return "DEBUG"
+	case l < slog.LevelWarn:
+		return LevelInfo
+	case l < slog.LevelError:
+		return "WARN"
+	case l < SlogLevelFatal:
+		return LevelError
+	default:
+		return "FATAL"
+	}
+}
+
+// ToSlogRecord converts an entry to a slog.Record. Fields become attributes
+// in key order, with nested maps converted to groups.
+func ToSlogRecord(e LogEntry) slog.Record {
+	r := slog.NewRecord(e.Timestamp, SlogLevel(e.Level), e.Message, 0)
+	r.AddAttrs(fieldAttrs(e.Fields)...)
+
+	return r
+}
+
+// FromSlogRecord converts a slog.Record to an entry. Groups become nested maps.
+func FromSlogRecord(r slog.Record) LogEntry {
+	entry := LogEntry{
+		Timestamp: r.Time,
+		Level:     LevelFromSlog(r.Level),
+		Message:   r.Message,
+		Fields:    make(map[string]interface{}),
+	}
+
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(entry.Fields, a)
+
+		return true
+	})
+
+	return entry
+}
+
+// fieldAttrs converts a field map to attributes sorted by key
+func fieldAttrs(fields map[string]interface{}) []slog.Attr {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+
+	for _, k := range keys {
+		if nested, ok := fields[k].(map[string]interface{}); ok {
+			attrs = append(attrs, slog.Attr{Key: k, Value: slog.GroupValue(fieldAttrs(nested)...)})
+
+			continue
+		}
+
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+
+	return attrs
+}
+
+// addAttr stores a resolved attribute in fields, following slog's rules for
+// empty keys and groups
+func addAttr(fields map[string]interface{}, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		fields[a.Key] = a.Value.Any()
+
+		return
+	}
+
+	group := a.Value.Group()
+	if len(group) == 0 {
+		return
+	}
+
+	target := fields
+	if a.Key != "" {
+		nested, ok := fields[a.Key].(map[string]interface{})
+		if !ok {
+			nested = make(map[string]interface{})
+			fields[a.Key] = nested
+		}
+
+		target = nested
+	}
+
+	for _, ga := range group {
+		addAttr(target, ga)
+	}
+}
+
+// EntryHandler is a slog.Handler that stores records as entries in memory,
+// for asserting on an application's logging in tests. It is safe for
+// concurrent use; handlers derived with WithAttrs and WithGroup share the
+// same entries.
+type EntryHandler struct {
+	store  *entryStore
+	level  slog.Leveler
+	attrs  []slog.Attr // Preset attributes, already wrapped in their groups
+	groups []string    // Open groups for record attributes
+}
+
+// entryStore holds the entries shared by an EntryHandler and its derivatives
+type entryStore struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// NewEntryHandler creates a handler that records entries at or above level.
+// A nil level records everything from SlogLevelTrace up.
+func NewEntryHandler(level slog.Leveler) *EntryHandler {
+	if level == nil {
+		level = SlogLevelTrace
+	}
+
+	return &EntryHandler{store: &entryStore{}, level: level}
+}
+
+// Entries returns a copy of the recorded entries
+func (h *EntryHandler) Entries() []LogEntry {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+
+	entries := make([]LogEntry, len(h.store.entries))
+	copy(entries, h.store.entries)
+
+	return entries
+}
+
+// Enabled implements slog.Handler
+func (h *EntryHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler
+func (h *EntryHandler) Handle(_ context.Context, r slog.Record) error {
+	var attrs []slog.Attr
+
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+
+		return true
+	})
+
+	rec := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	rec.AddAttrs(h.attrs...)
+	rec.AddAttrs(wrapGroups(h.groups, attrs)...)
+
+	entry := FromSlogRecord(rec)
+
+	h.store.mu.Lock()
+	h.store.entries = append(h.store.entries, entry)
+	h.store.mu.Unlock()
+
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (h *EntryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	clone := *h
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), wrapGroups(h.groups, attrs)...)
+
+	return &clone
+}
+
+// WithGroup implements slog.Handler
+func (h *EntryHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.groups = append(append([]string(nil), h.groups...), name)
+
+	return &clone
+}
+
+// wrapGroups nests attrs inside the given groups, outermost first
+func wrapGroups(groups []string, attrs []slog.Attr) []slog.Attr {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	for i := len(groups) - 1; i >= 0; i-- {
+		attrs = []slog.Attr{{Key: groups[i], Value: slog.GroupValue(attrs...)}}
+	}
+
+	return attrs
+}
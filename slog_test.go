@@ -0,0 +1,88 @@
+package logparser
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSlogLevelRoundTrip(t *testing.T) {
+	for _, level := range []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"} {
+		if got := LevelFromSlog(SlogLevel(level)); got != level {
+			t.Errorf("LevelFromSlog(SlogLevel(%q)) = %q", level, got)
+		}
+	}
+
+	if got := LevelFromSlog(slog.LevelWarn + 2); got != "WARN" {
+		t.Errorf("intermediate level = %q, want WARN", got)
+	}
+
+	if got := SlogLevel("verbose"); got != slog.LevelDebug {
+		t.Errorf("SlogLevel(verbose) = %v, want DEBUG", got)
+	}
+}
+
+func TestSlogRecordRoundTrip(t *testing.T) {
+	entry := LogEntry{
+		Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Level:     "WARN",
+		Message:   "slow query",
+		Fields: map[string]interface{}{
+			"duration": 1.5,
+			"db":       map[string]interface{}{"name": "users", "shard": "3"},
+		},
+	}
+
+	rec := ToSlogRecord(entry)
+	if rec.Level != slog.LevelWarn || rec.Message != "slow query" || rec.NumAttrs() != 2 {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+
+	var keys []string
+
+	rec.Attrs(func(a slog.Attr) bool {
+		keys = append(keys, a.Key)
+
+		return true
+	})
+
+	if !reflect.DeepEqual(keys, []string{"db", "duration"}) {
+		t.Errorf("attrs not in key order: %v", keys)
+	}
+
+	if got := FromSlogRecord(rec); !reflect.DeepEqual(got, entry) {
+		t.Errorf("FromSlogRecord() = %+v, want %+v", got, entry)
+	}
+}
+
+func TestEntryHandler(t *testing.T) {
+	h := NewEntryHandler(slog.LevelInfo)
+	logger := slog.New(h).With("service", "api").WithGroup("req")
+
+	logger.Debug("dropped")
+	logger.Info("handled", "path", "/users", "status", 200)
+	logger.With("id", "r1").Error("failed", slog.Group("err", "code", 7))
+
+	entries := h.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	first := entries[0]
+	if first.Level != "INFO" || first.Fields["service"] != "api" {
+		t.Errorf("unexpected entry %+v", first)
+	}
+
+	req, _ := first.Fields["req"].(map[string]interface{})
+	if req["path"] != "/users" || req["status"] != int64(200) {
+		t.Errorf("req group = %v", req)
+	}
+
+	req, _ = entries[1].Fields["req"].(map[string]interface{})
+	errGroup, _ := req["err"].(map[string]interface{})
+
+	if entries[1].Level != "ERROR" || req["id"] != "r1" || errGroup["code"] != int64(7) {
+		t.Errorf("unexpected entry %+v", entries[1])
+	}
+}
@@ -0,0 +1,251 @@
+package logparser
+
+import (
+	"container/list"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+)
+
+// DefaultSampledTemplates is the number of message templates
+// WithSmartSampling tracks unless SmartSampling.MaxTemplates is set
+const DefaultSampledTemplates = 10000
+
+// SmartSampling configures WithSmartSampling
+type SmartSampling struct {
+	MinLevel     string  `json:"min_level"`     // Least severe level kept in full; empty means ERROR
+	First        int     `json:"first"`         // Entries kept from the start of each message template
+	Last         int     `json:"last"`          // Entries kept from the end of each message template
+	Rate         float64 `json:"rate"`          // Probability of keeping any other entry, in [0, 1]
+	MaxTemplates int     `json:"max_templates"` // Templates tracked at once; zero means DefaultSampledTemplates
+}
+
+// SamplingStats counts the decisions of WithSmartSampling. Every entry
+// parsed falls in exactly one category.
+type SamplingStats struct {
+	KeptLevel        int `json:"kept_level"`        // At or above SmartSampling.MinLevel
+	KeptFirst        int `json:"kept_first"`        // Among the first of their template
+	KeptLast         int `json:"kept_last"`         // Among the last of their template
+	KeptSampled      int `json:"kept_sampled"`      // Kept at SmartSampling.Rate
+	Dropped          int `json:"dropped"`           // Sampled out
+	EvictedTemplates int `json:"evicted_templates"` // Templates forgotten to stay under MaxTemplates
+}
+
+// WithSmartSampling thins high-volume logs without losing what matters.
+// Entries at or above cfg.MinLevel are all kept. Of the rest, the first
+// cfg.First and last cfg.Last entries of each message template (see
+// MessageTemplate) are kept, and the others with probability cfg.Rate.
+// Parsing takes one pass: up to cfg.Last entries per template are held
+// until a later entry of the template displaces them or the input ends,
+// and at most cfg.MaxTemplates templates are tracked, least recently seen
+// first to go. A forgotten template's held entries are kept, and a later
+// entry of it counts as the first again. Entries are returned in input
+// order, except with WithTailLimit or through NewWriter and ParseColumns,
+// where held entries follow those parsed after them. Stats.Sampling
+// reports the decisions. WithSampleSeed makes them reproducible; the
+// option cannot be combined with WithSampling.
+func WithSmartSampling(cfg SmartSampling) Option {
+	return func(c *config) {
+		c.smartSampling = &cfg
+	}
+}
+
+// validate reports settings out of range
+func (s *SmartSampling) validate() error {
+	switch {
+	case s.MinLevel != "" && levelRank(s.MinLevel) < 0:
+		return fmt.Errorf("%w: unknown sampling level %q", ErrInvalidOptions, s.MinLevel)
+	case s.Rate < 0 || s.Rate > 1:
+		return fmt.Errorf("%w: smart sampling rate %v outside [0, 1]", ErrInvalidOptions, s.Rate)
+	case s.First < 0 || s.Last < 0 || s.MaxTemplates < 0:
+		return fmt.Errorf("%w: negative smart sampling limit", ErrInvalidOptions)
+	}
+
+	return nil
+}
+
+// smartSampler decides which entries of a run WithSmartSampling keeps
+type smartSampler struct {
+	cfg       SmartSampling
+	minRank   int
+	rng       *rand.Rand
+	templates map[string]*list.Element // Values are *sampledTemplate
+	recent    *list.List               // Templates, most recently seen first
+	staged    LogEntry                 // Newest entry, which continuation lines may still be folded into
+	hasStaged bool
+	seq       int   // Input position of the next entry admitted
+	ordered   bool  // Whether seqs track the run's entries, so input order can be restored
+	seqs      []int // Input positions of the run's entries, when ordered
+	stats     SamplingStats
+}
+
+// sampledTemplate is a template's count and its held last entries
+type sampledTemplate struct {
+	key  string
+	seen int
+	held []sampledEntry // Oldest first
+}
+
+// sampledEntry is an entry and its input position
+type sampledEntry struct {
+	entry LogEntry
+	seq   int
+}
+
+// newSmartSampler returns a sampler for cfg drawing from rng
+func newSmartSampler(cfg SmartSampling, rng *rand.Rand) *smartSampler {
+	if cfg.MinLevel == "" {
+		cfg.MinLevel = LevelError
+	}
+
+	if cfg.MaxTemplates == 0 {
+		cfg.MaxTemplates = DefaultSampledTemplates
+	}
+
+	return &smartSampler{
+		cfg:       cfg,
+		minRank:   levelRank(cfg.MinLevel),
+		rng:       rng,
+		templates: make(map[string]*list.Element),
+		recent:    list.New(),
+		ordered:   true,
+	}
+}
+
+// stage holds entry until the next one is stored, and decides on the
+// entry held before it
+func (s *smartSampler) stage(r *parseRun, entry *LogEntry) {
+	if s.hasStaged {
+		s.admit(r, s.staged)
+	}
+
+	s.staged, s.hasStaged = *entry, true
+}
+
+// admit decides on an entry no continuation line can change any more
+func (s *smartSampler) admit(r *parseRun, entry LogEntry) {
+	seq := s.seq
+	s.seq++
+
+	if levelRank(entry.Level) >= s.minRank {
+		s.stats.KeptLevel++
+		s.keep(r, entry, seq)
+
+		return
+	}
+
+	t := s.template(r, MessageTemplate(entry.Message))
+
+	if t.seen++; t.seen <= s.cfg.First {
+		s.stats.KeptFirst++
+		s.keep(r, entry, seq)
+
+		return
+	}
+
+	if s.cfg.Last == 0 {
+		s.sample(r, entry, seq)
+
+		return
+	}
+
+	if len(t.held) < s.cfg.Last {
+		t.held = append(t.held, sampledEntry{entry, seq})
+
+		return
+	}
+
+	oldest := t.held[0]
+	copy(t.held, t.held[1:])
+	t.held[len(t.held)-1] = sampledEntry{entry, seq}
+	s.sample(r, oldest.entry, oldest.seq)
+}
+
+// template returns the tracked template for key, forgetting the least
+// recently seen one if a new template exceeds the cap
+func (s *smartSampler) template(r *parseRun, key string) *sampledTemplate {
+	if el, ok := s.templates[key]; ok {
+		s.recent.MoveToFront(el)
+
+		return el.Value.(*sampledTemplate)
+	}
+
+	if s.recent.Len() >= s.cfg.MaxTemplates {
+		oldest := s.recent.Back()
+		t := s.recent.Remove(oldest).(*sampledTemplate)
+		delete(s.templates, t.key)
+		s.release(r, t)
+		s.stats.EvictedTemplates++
+	}
+
+	t := &sampledTemplate{key: key}
+	s.templates[key] = s.recent.PushFront(t)
+
+	return t
+}
+
+// sample keeps an entry with probability Rate
+func (s *smartSampler) sample(r *parseRun, entry LogEntry, seq int) {
+	if s.rng.Float64() >= s.cfg.Rate {
+		s.stats.Dropped++
+
+		return
+	}
+
+	s.stats.KeptSampled++
+	s.keep(r, entry, seq)
+}
+
+// release keeps the entries a template holds as its last
+func (s *smartSampler) release(r *parseRun, t *sampledTemplate) {
+	for _, h := range t.held {
+		s.stats.KeptLast++
+		s.keep(r, h.entry, h.seq)
+	}
+
+	t.held = nil
+}
+
+// keep stores an entry in the run, noting its input position when order
+// can be restored
+func (s *smartSampler) keep(r *parseRun, entry LogEntry, seq int) {
+	s.ordered = s.ordered && r.columns == nil && r.cfg.tailLimit <= 0
+	if s.ordered {
+		s.seqs = append(s.seqs, seq)
+	}
+
+	r.keep(&entry)
+}
+
+// flush decides on the staged entry and keeps every held one, then puts
+// the run's entries back in input order when possible
+func (s *smartSampler) flush(r *parseRun) {
+	if s.hasStaged {
+		s.hasStaged = false
+		s.admit(r, s.staged)
+	}
+
+	for el := s.recent.Back(); el != nil; el = el.Prev() {
+		s.release(r, el.Value.(*sampledTemplate))
+	}
+
+	if s.ordered && len(s.seqs) == len(r.entries) {
+		sort.Stable(bySeq{r.entries, s.seqs})
+	}
+
+	stats := s.stats
+	r.stats.Sampling = &stats
+}
+
+// bySeq sorts entries by their input positions
+type bySeq struct {
+	entries []LogEntry
+	seqs    []int
+}
+
+func (b bySeq) Len() int           { return len(b.entries) }
+func (b bySeq) Less(i, j int) bool { return b.seqs[i] < b.seqs[j] }
+func (b bySeq) Swap(i, j int) {
+	b.entries[i], b.entries[j] = b.entries[j], b.entries[i]
+	b.seqs[i], b.seqs[j] = b.seqs[j], b.seqs[i]
+}
@@ -0,0 +1,213 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// chattyLog returns logfmt lines: 1000 cache hits, a few hundred retry
+// warnings, and an ERROR every 97 lines
+func chattyLog() (lines string, errs int) {
+	var b strings.Builder
+
+	for i := range 1500 {
+		switch {
+		case i%97 == 0:
+			fmt.Fprintf(&b, "level=error msg=\"payment %d declined\" n=%d\n", i, i)
+			errs++
+		case i%3 == 0:
+			fmt.Fprintf(&b, "level=warn msg=\"retrying request %d\" n=%d\n", i, i)
+		default:
+			fmt.Fprintf(&b, "level=info msg=\"cache hit for key %d\" n=%d\n", i, i)
+		}
+	}
+
+	return b.String(), errs
+}
+
+// fieldInt returns the integer in an entry's field key
+func fieldInt(t *testing.T, e LogEntry, key string) int {
+	t.Helper()
+
+	n, err := strconv.Atoi(fmt.Sprint(e.Fields[key]))
+	if err != nil {
+		t.Fatalf("field %s of %+v: %v", key, e, err)
+	}
+
+	return n
+}
+
+func TestSmartSamplingKeepsErrors(t *testing.T) {
+	input, errs := chattyLog()
+
+	p, err := NewE(WithFormat(FormatLogfmt), WithSmartSampling(SmartSampling{First: 5, Last: 5, Rate: 0.01}), WithSampleSeed(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, stats, err := ParseWithStats(p, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := 0
+	last := -1
+
+	for _, e := range entries {
+		if e.Level == LevelError {
+			got++
+		}
+
+		n := fieldInt(t, e, "n")
+		if n <= last {
+			t.Fatalf("entry %d after %d: not in input order", n, last)
+		}
+
+		last = n
+	}
+
+	if got != errs {
+		t.Errorf("kept %d errors, want all %d", got, errs)
+	}
+
+	s := stats.Sampling
+	if s == nil {
+		t.Fatal("no sampling stats")
+	}
+
+	if s.KeptLevel != errs || s.KeptFirst != 10 || s.KeptLast != 10 {
+		t.Errorf("stats = %+v", *s)
+	}
+
+	kept := s.KeptLevel + s.KeptFirst + s.KeptLast + s.KeptSampled
+	if kept != len(entries) || kept+s.Dropped != 1500 || stats.EntriesEmitted != kept {
+		t.Errorf("stats = %+v for %d entries", *s, len(entries))
+	}
+}
+
+func TestSmartSamplingFirstAndLast(t *testing.T) {
+	var b strings.Builder
+
+	// 21 entries of one template, interleaved with 3 of another
+	for i := range 24 {
+		if i%8 == 7 {
+			fmt.Fprintf(&b, "level=debug msg=\"gc pause %dms\" n=%d\n", i, i)
+		} else {
+			fmt.Fprintf(&b, "level=info msg=\"worker %d idle\" n=%d\n", i, i)
+		}
+	}
+
+	p := New(WithFormat(FormatLogfmt), WithSmartSampling(SmartSampling{First: 3, Last: 3}))
+
+	entries, stats, err := ParseWithStats(p, strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var idle, gc []int
+
+	for _, e := range entries {
+		if strings.HasPrefix(e.Message, "worker") {
+			idle = append(idle, fieldInt(t, e, "n"))
+		} else {
+			gc = append(gc, fieldInt(t, e, "n"))
+		}
+	}
+
+	if want := []int{0, 1, 2, 20, 21, 22}; fmt.Sprint(idle) != fmt.Sprint(want) {
+		t.Errorf("worker entries = %v, want %v", idle, want)
+	}
+
+	// A template seen three times is kept whole
+	if want := []int{7, 15, 23}; fmt.Sprint(gc) != fmt.Sprint(want) {
+		t.Errorf("gc entries = %v, want %v", gc, want)
+	}
+
+	if s := *stats.Sampling; s.KeptFirst != 6 || s.KeptLast != 3 || s.Dropped != 15 || s.KeptSampled != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestSmartSamplingTemplateCap(t *testing.T) {
+	var b strings.Builder
+
+	// Each template appears twice, far apart, with only one tracked at a time
+	for _, word := range []string{"alpha", "beta", "alpha", "beta"} {
+		for i := range 4 {
+			fmt.Fprintf(&b, "level=info msg=\"%s step %d\"\n", word, i)
+		}
+	}
+
+	p := New(WithFormat(FormatLogfmt), WithSmartSampling(SmartSampling{First: 1, Last: 1, MaxTemplates: 1}))
+
+	entries, stats, err := ParseWithStats(p, strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each run of four keeps its first and last
+	if len(entries) != 8 {
+		t.Errorf("got %d entries, want 8", len(entries))
+	}
+
+	if s := *stats.Sampling; s.EvictedTemplates != 3 || s.KeptFirst != 4 || s.KeptLast != 4 || s.Dropped != 8 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestSmartSamplingContinuation(t *testing.T) {
+	file, err := os.Open("testdata/klog.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	entries, err := New(WithSmartSampling(SmartSampling{})).Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ERROR and FATAL entries, with their continuation lines
+	if len(entries) != 4 || !strings.HasSuffix(entries[1].Message, "try again\n>") {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestSmartSamplingWriter(t *testing.T) {
+	input, errs := chattyLog()
+
+	var got []LogEntry
+
+	w := NewWriter(New(WithFormat(FormatLogfmt), WithSmartSampling(SmartSampling{First: 2, Last: 2})), func(e LogEntry) {
+		got = append(got, e)
+	})
+
+	if _, err := w.Write([]byte(input)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := errs + 8; len(got) != want {
+		t.Errorf("got %d entries, want %d", len(got), want)
+	}
+}
+
+func TestSmartSamplingOptions(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"with sampling": {WithSampling(0.5), WithSmartSampling(SmartSampling{})},
+		"level":         {WithSmartSampling(SmartSampling{MinLevel: "LOUD"})},
+		"rate":          {WithSmartSampling(SmartSampling{Rate: 2})},
+		"negative":      {WithSmartSampling(SmartSampling{Last: -1})},
+	} {
+		if _, err := NewE(opts...); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%s: error = %v, want ErrInvalidOptions", name, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package logparser
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSourceMergeNamedReaders(t *testing.T) {
+	sources := map[string]string{
+		"api-7f9c": `{"timestamp":"2024-01-02T15:04:01Z","level":"info","message":"api started"}` + "\n" +
+			`{"timestamp":"2024-01-02T15:04:04Z","level":"error","message":"api failed"}`,
+		"worker-1": "time=2024-01-02T15:04:02Z level=info msg=\"worker started\"\n\n" +
+			"time=2024-01-02T15:04:05Z level=warn msg=\"worker slow\"",
+		"db-0": "2024-01-02 15:04:03 [INFO] db started",
+	}
+
+	var merged []LogEntry
+
+	for name, input := range sources {
+		entries, err := New(WithSourceName(name)).Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: Parse() error = %v", name, err)
+		}
+
+		merged = append(merged, entries...)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+
+	want := []struct {
+		msg    string
+		source string
+		line   int
+	}{
+		{"api started", "api-7f9c", 1},
+		{"worker started", "worker-1", 1},
+		{"db started", "db-0", 1},
+		{"api failed", "api-7f9c", 2},
+		{"worker slow", "worker-1", 3},
+	}
+
+	if len(merged) != len(want) {
+		t.Fatalf("want %d entries, got %d", len(want), len(merged))
+	}
+
+	for i, w := range want {
+		e := merged[i]
+		if e.Message != w.msg || e.Source == nil || e.Source.Name != w.source || e.Source.Line != w.line {
+			t.Errorf("entry %d: want %q from %s:%d, got %q from %+v", i, w.msg, w.source, w.line, e.Message, e.Source)
+		}
+	}
+}
+
+func TestSourceOffsets(t *testing.T) {
+	input := "level=info msg=one\r\n\r\nlevel=info msg=two\nlevel=info msg=three"
+
+	readerEntries, err := NewWithFormat(FormatLogfmt, WithSourceName("in")).Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stringEntries, err := NewWithFormat(FormatLogfmt, WithSourceName("in")).ParseString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantLines := []int{1, 3, 4}
+	wantOffsets := []int64{0, 22, 41}
+
+	for _, entries := range [][]LogEntry{readerEntries, stringEntries} {
+		for i, e := range entries {
+			if e.Source.Line != wantLines[i] || e.Source.Offset != wantOffsets[i] {
+				t.Errorf("entry %d: want line %d offset %d, got %+v", i, wantLines[i], wantOffsets[i], e.Source)
+			}
+
+			if !strings.HasPrefix(input[e.Source.Offset:], "level=info msg="+e.Message) {
+				t.Errorf("entry %d: offset %d does not point at its line", i, e.Source.Offset)
+			}
+		}
+	}
+}
+
+func TestSourceFromParseFile(t *testing.T) {
+	entries, err := ParseFile(New(), "testdata/log4j.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := entries[1].Source; s == nil || s.Name != "testdata/log4j.log" || s.Line != 2 {
+		t.Errorf("unexpected source %+v", s)
+	}
+
+	entries, err = ParseFile(New(WithSourceName("java-app")), "testdata/log4j.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := entries[0].Source; s == nil || s.Name != "java-app" {
+		t.Errorf("want caller-supplied name, got %+v", s)
+	}
+}
+
+// otherParser stands for a Parser implemented outside the package
+type otherParser struct{ Parser }
+
+func TestSourceFromParseGlob(t *testing.T) {
+	for name, p := range map[string]Parser{"parser": New(WithSourceName("ignored")), "other": otherParser{New()}} {
+		entries, err := ParseGlob(p, "testdata/log[4c]*.log")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		files := map[string]int{}
+		for _, e := range entries {
+			if e.Source != nil {
+				files[e.Source.Name]++
+			}
+		}
+
+		if len(entries) == 0 || entries[0].Source == nil || entries[0].Source.Name != "testdata/log4j.log" ||
+			len(files) != 2 || files["testdata/logcat.log"] == 0 {
+			t.Errorf("%s: entries from %v, first %+v", name, files, entries[0])
+		}
+	}
+
+	entries, err := ParseGlob(New(), "testdata/{log4j,mysql}.log")
+	if err != nil || len(entries) != 0 {
+		t.Errorf("braces are not glob syntax: %d entries, error = %v", len(entries), err)
+	}
+
+	if _, err := ParseGlob(New(), "testdata/["); !errors.Is(err, filepath.ErrBadPattern) {
+		t.Errorf("error = %v, want filepath.ErrBadPattern", err)
+	}
+}
+
+func TestSourceUnsetByDefault(t *testing.T) {
+	entries, _ := New().ParseString("[INFO] hello")
+	if entries[0].Source != nil {
+		t.Errorf("want nil Source without a name, got %+v", entries[0].Source)
+	}
+}
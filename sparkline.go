@@ -0,0 +1,258 @@
+package logparser
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RenderOption configures RenderSparkline and RenderLevelBar
+type RenderOption func(*renderConfig)
+
+type renderConfig struct {
+	ascii   bool
+	buckets *Buckets
+}
+
+// WithASCII draws with ASCII characters only, for terminals that cannot
+// show block characters
+func WithASCII(ascii bool) RenderOption {
+	return func(c *renderConfig) {
+		c.ascii = ascii
+	}
+}
+
+// WithBuckets aligns RenderSparkline buckets as b describes, such as on
+// a local wall clock or by calendar day, instead of fixed buckets aligned
+// in UTC; a zero b.Size and b.Unit take the bucket argument
+func WithBuckets(b Buckets) RenderOption {
+	return func(c *renderConfig) {
+		c.buckets = &b
+	}
+}
+
+// Sparkline glyphs from lowest to highest; empty buckets are blank
+var (
+	sparkBlocks = []rune{'▁', '▂', '▃', '▅', '▇'}
+	sparkASCII  = []rune{'.', ':', '-', '=', '#'}
+)
+
+// levelBarOther labels levels outside levelOrder in RenderLevelBar
+const levelBarOther = "OTHER"
+
+// Level bar glyphs, indexed like levelOrder with OTHER last
+var (
+	levelBarBlocks = []rune{'·', '░', '▒', '▓', '█', '▀', '?'}
+	levelBarASCII  = []rune{'.', '-', '=', '+', '#', '!', '?'}
+)
+
+// maxSparklineWidth caps the characters RenderSparkline draws for fixed
+// buckets
+const maxSparklineWidth = 4096
+
+// RenderSparkline draws the number of entries at level in each bucket
+// (default one minute, aligned in UTC; see WithBuckets) as a row of block
+// characters, one per bucket from the earliest to the latest timestamp of
+// entries, so quiet buckets show as blanks. Fixed buckets that would draw
+// more than 4096 characters are widened to the smallest multiple of their
+// size that fits. An empty level counts every entry. Heights are relative
+// to the fullest bucket. Entries without a timestamp are ignored; with
+// none left the result is "".
+func RenderSparkline(entries []LogEntry, bucket time.Duration, level string, opts ...RenderOption) string {
+	cfg := newRenderConfig(opts)
+
+	buckets := Buckets{Size: bucket}
+	if cfg.buckets != nil {
+		buckets = *cfg.buckets
+		if buckets.Size <= 0 && buckets.Unit == BucketFixed {
+			buckets.Size = bucket
+		}
+	}
+
+	if buckets.Unit == BucketFixed {
+		buckets.Size = sparklineBucketSize(entries, buckets.withDefaults().Size)
+	}
+
+	if l, ok := lookupLevel(level); ok {
+		level = l
+	}
+
+	histogram := Histogram(entries, buckets)
+	if len(histogram) == 0 {
+		return ""
+	}
+
+	counts := make([]int, len(histogram))
+	peak := 0
+
+	for i, h := range histogram {
+		counts[i] = h.Count
+		if level != "" {
+			counts[i] = h.Levels[level]
+		}
+
+		peak = max(peak, counts[i])
+	}
+
+	glyphs := sparkBlocks
+	if cfg.ascii {
+		glyphs = sparkASCII
+	}
+
+	var b strings.Builder
+
+	for _, n := range counts {
+		if n == 0 {
+			b.WriteByte(' ')
+
+			continue
+		}
+
+		// Any count maps to a glyph; only the peak reaches the top one
+		b.WriteRune(glyphs[(n*len(glyphs)-1)/peak])
+	}
+
+	return b.String()
+}
+
+// sparklineBucketSize returns size, or the smallest multiple of it that
+// cuts the timestamps of entries into at most maxSparklineWidth buckets
+func sparklineBucketSize(entries []LogEntry, size time.Duration) time.Duration {
+	var first, last time.Time
+
+	for i := range entries {
+		ts := entries[i].Timestamp
+		if ts.IsZero() {
+			continue
+		}
+
+		if first.IsZero() || ts.Before(first) {
+			first = ts
+		}
+
+		if ts.After(last) {
+			last = ts
+		}
+	}
+
+	// In float64 seconds, as spans past 292 years overflow a Duration.
+	// Alignment and offset changes can add two buckets to those the span
+	// holds.
+	span := float64(last.Unix()-first.Unix()) + float64(last.Nanosecond()-first.Nanosecond())/1e9
+	if n := span / size.Seconds(); n > maxSparklineWidth-2 {
+		size = time.Duration(float64(size) * (math.Floor(n/(maxSparklineWidth-2)) + 1))
+	}
+
+	return size
+}
+
+// RenderLevelBar draws the share of each level among entries as a bar
+// width characters wide, one glyph per level, followed by a legend such
+// as "▒ INFO 80 (80%)". Levels appear in severity order; levels other
+// than the standard ones are counted together as OTHER. Every level
+// present gets at least one character when width allows. The result is
+// "" for no entries or a width below one.
+func RenderLevelBar(entries []LogEntry, width int, opts ...RenderOption) string {
+	cfg := newRenderConfig(opts)
+
+	if len(entries) == 0 || width < 1 {
+		return ""
+	}
+
+	counts := make([]int, len(levelOrder)+1)
+
+	for i := range entries {
+		rank := levelRank(entries[i].Level)
+		if rank < 0 {
+			rank = len(levelOrder)
+		}
+
+		counts[rank]++
+	}
+
+	glyphs := levelBarBlocks
+	if cfg.ascii {
+		glyphs = levelBarASCII
+	}
+
+	var bar, legend strings.Builder
+
+	for i, w := range levelBarWidths(counts, len(entries), width) {
+		bar.WriteString(strings.Repeat(string(glyphs[i]), w))
+
+		if counts[i] == 0 {
+			continue
+		}
+
+		name := levelBarOther
+		if i < len(levelOrder) {
+			name = levelOrder[i]
+		}
+
+		fmt.Fprintf(&legend, "  %c %s %d (%d%%)", glyphs[i], name, counts[i], (counts[i]*100+len(entries)/2)/len(entries))
+	}
+
+	return bar.String() + legend.String()
+}
+
+// levelBarWidths splits width among counts in proportion, by largest
+// remainder, then gives each nonzero count at least one character taken
+// from the widest segment
+func levelBarWidths(counts []int, total, width int) []int {
+	widths := make([]int, len(counts))
+	order := make([]int, 0, len(counts))
+	used := 0
+
+	for i, n := range counts {
+		widths[i] = n * width / total
+		used += widths[i]
+
+		if n > 0 {
+			order = append(order, i)
+		}
+	}
+
+	// Largest remainders first, ties in severity order
+	sort.SliceStable(order, func(a, b int) bool {
+		return counts[order[a]]*width%total > counts[order[b]]*width%total
+	})
+
+	for i := 0; used < width; i++ {
+		widths[order[i%len(order)]]++
+		used++
+	}
+
+	if len(order) > width {
+		return widths
+	}
+
+	for _, i := range order {
+		if widths[i] > 0 {
+			continue
+		}
+
+		widest := 0
+		for j := range widths {
+			if widths[j] > widths[widest] {
+				widest = j
+			}
+		}
+
+		widths[widest]--
+		widths[i]++
+	}
+
+	return widths
+}
+
+// newRenderConfig applies render options
+func newRenderConfig(opts []RenderOption) renderConfig {
+	var cfg renderConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
@@ -0,0 +1,111 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenderSparkline(t *testing.T) {
+	start := time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC)
+	at := func(minutes, seconds int, level string) LogEntry {
+		return LogEntry{Timestamp: start.Add(time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second), Level: level}
+	}
+
+	var entries []LogEntry
+
+	// 1, 0, 3, 5, and 10 errors in five minutes, with INFO lines in between
+	for minute, n := range []int{1, 0, 3, 5, 10} {
+		for i := 0; i < n; i++ {
+			entries = append(entries, at(minute, i, LevelError))
+		}
+
+		entries = append(entries, at(minute, 59, LevelInfo))
+	}
+
+	entries = append(entries, LogEntry{Level: LevelError}) // No timestamp
+
+	tests := []struct {
+		name   string
+		bucket time.Duration
+		level  string
+		opts   []RenderOption
+		want   string
+	}{
+		{"errors", time.Minute, "error", nil, "▁ ▂▃▇"},
+		{"ascii", time.Minute, "ERROR", []RenderOption{WithASCII(true)}, ". :-#"},
+		{"all levels", time.Minute, "", nil, "▁▁▂▃▇"},
+		{"default bucket", 0, "error", nil, "▁ ▂▃▇"},
+		{"single bucket", time.Hour, "error", nil, "▇"},
+		{"no matches", time.Minute, "fatal", nil, "     "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderSparkline(entries, tt.bucket, tt.level, tt.opts...); got != tt.want {
+				t.Errorf("RenderSparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := RenderSparkline(nil, time.Minute, "error"); got != "" {
+		t.Errorf("RenderSparkline(nil) = %q, want empty", got)
+	}
+
+	if got := RenderSparkline(entries[:1], time.Minute, "error"); got != "▇" {
+		t.Errorf("RenderSparkline(one entry) = %q, want a full block", got)
+	}
+
+	// Nanosecond buckets over 100 hours are widened to fit
+	wide := []LogEntry{{Timestamp: start}, {Timestamp: start.Add(100 * time.Hour)}}
+	if got := []rune(RenderSparkline(wide, time.Nanosecond, "")); len(got) > maxSparklineWidth || got[0] != '▇' || got[len(got)-1] != '▇' {
+		t.Errorf("RenderSparkline(1ns over 100h) = %d characters", len(got))
+	}
+
+	wide = []LogEntry{{Timestamp: time.Date(1, 1, 1, 0, 0, 1, 0, time.UTC)}, {Timestamp: time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)}}
+	if got := []rune(RenderSparkline(wide, time.Nanosecond, "")); len(got) > maxSparklineWidth {
+		t.Errorf("RenderSparkline(1ns over 10,000 years) = %d characters", len(got))
+	}
+}
+
+func TestRenderLevelBar(t *testing.T) {
+	var entries []LogEntry
+
+	for level, n := range map[string]int{LevelInfo: 80, "WARN": 15, LevelError: 4, "FATAL": 1} {
+		for i := 0; i < n; i++ {
+			entries = append(entries, LogEntry{Level: level})
+		}
+	}
+
+	tests := []struct {
+		name    string
+		entries []LogEntry
+		width   int
+		opts    []RenderOption
+		want    string
+	}{
+		{
+			"blocks", entries, 20, nil,
+			"▒▒▒▒▒▒▒▒▒▒▒▒▒▒▒▓▓▓█▀  ▒ INFO 80 (80%)  ▓ WARN 15 (15%)  █ ERROR 4 (4%)  ▀ FATAL 1 (1%)",
+		},
+		{
+			"ascii", entries, 10, []RenderOption{WithASCII(true)},
+			"======++#!  = INFO 80 (80%)  + WARN 15 (15%)  # ERROR 4 (4%)  ! FATAL 1 (1%)",
+		},
+		{"narrow", entries, 2, []RenderOption{WithASCII(true)}, "==  = INFO 80 (80%)  + WARN 15 (15%)  # ERROR 4 (4%)  ! FATAL 1 (1%)"},
+		{
+			"other", []LogEntry{{Level: "DEBUG"}, {Level: "NOTICE"}}, 4, []RenderOption{WithASCII(true)},
+			"--??  - DEBUG 1 (50%)  ? OTHER 1 (50%)",
+		},
+		{"single level", []LogEntry{{Level: LevelError}}, 3, nil, "███  █ ERROR 1 (100%)"},
+		{"empty", nil, 20, nil, ""},
+		{"zero width", entries, 0, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderLevelBar(tt.entries, tt.width, tt.opts...); got != tt.want {
+				t.Errorf("RenderLevelBar() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
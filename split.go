@@ -0,0 +1,314 @@
+package logparser
+
+import (
+	"bufio"
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultPartition is the partition of entries without a split key
+const DefaultPartition = "_default"
+
+// DefaultMaxOpenFiles is the number of files SplitToFiles keeps open
+const DefaultMaxOpenFiles = 64
+
+// SplitOption configures NewSplitter and SplitToFiles
+type SplitOption func(*splitConfig)
+
+// splitConfig holds splitting settings
+type splitConfig struct {
+	defaultKey string
+	maxOpen    int
+}
+
+// WithDefaultPartition sets the partition of entries whose key is empty
+// (default DefaultPartition). Applies to NewSplitter.
+func WithDefaultPartition(key string) SplitOption {
+	return func(c *splitConfig) {
+		c.defaultKey = key
+	}
+}
+
+// WithMaxOpenFiles caps the files SplitToFiles keeps open (default
+// DefaultMaxOpenFiles); values below 1 mean 1
+func WithMaxOpenFiles(n int) SplitOption {
+	return func(c *splitConfig) {
+		c.maxOpen = max(n, 1)
+	}
+}
+
+// newSplitConfig applies opts to the defaults
+func newSplitConfig(opts []SplitOption) splitConfig {
+	cfg := splitConfig{defaultKey: DefaultPartition, maxOpen: DefaultMaxOpenFiles}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// Splitter partitions entries by a key, such as a tenant ID, passing each
+// one to a sink with its partition as it arrives, so a stream is split in
+// a single pass. Its Handle method is a NewWriter callback:
+//
+//	files := logparser.SplitToFiles("out", "tenant-*.ndjson")
+//	split := logparser.NewSplitter(logparser.SplitByField("tenant_id"), files.Write)
+//	w := logparser.NewWriter(parser, split.Handle)
+//
+// A Splitter is safe for concurrent use.
+type Splitter struct {
+	mu         sync.Mutex
+	keyFn      func(LogEntry) string
+	sink       func(key string, e LogEntry) error
+	defaultKey string
+	counts     map[string]int
+	err        error
+}
+
+// NewSplitter returns a splitter passing each entry to sink with the key
+// keyFn returns for it, or the default partition (see
+// WithDefaultPartition) when that is empty
+func NewSplitter(keyFn func(LogEntry) string, sink func(key string, e LogEntry) error, opts ...SplitOption) *Splitter {
+	cfg := newSplitConfig(opts)
+
+	return &Splitter{keyFn: keyFn, sink: sink, defaultKey: cfg.defaultKey, counts: make(map[string]int)}
+}
+
+// SplitByField returns a key function reading the named field, formatted
+// as a string; dotted names select values inside nested objects. Entries
+// without the field get an empty key.
+func SplitByField(key string) func(LogEntry) string {
+	return func(e LogEntry) string {
+		val, ok := entryField(&e, key)
+		if !ok {
+			return ""
+		}
+
+		return formatValue(val)
+	}
+}
+
+// Add passes an entry to the sink and returns the sink's error. Entries
+// are passed on after an error too.
+func (s *Splitter) Add(e LogEntry) error {
+	key := s.keyFn(e)
+	if key == "" {
+		key = s.defaultKey
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.sink(key, e); err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+
+		return err
+	}
+
+	s.counts[key]++
+
+	return nil
+}
+
+// Handle is Add for callbacks that cannot return an error, such as that of
+// NewWriter. Once the sink has failed, entries are dropped; Err returns
+// the error.
+func (s *Splitter) Handle(e LogEntry) {
+	s.mu.Lock()
+	failed := s.err != nil
+	s.mu.Unlock()
+
+	if !failed {
+		_ = s.Add(e) // Kept for Err
+	}
+}
+
+// Err returns the first error of the sink
+func (s *Splitter) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Counts returns the number of entries the sink accepted per partition
+func (s *Splitter) Counts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int, len(s.counts))
+	for k, n := range s.counts {
+		counts[k] = n
+	}
+
+	return counts
+}
+
+// FileSink writes each partition to its own NDJSON file; see SplitToFiles
+type FileSink struct {
+	mu      sync.Mutex
+	dir     string
+	pattern string
+	maxOpen int
+	open    map[string]*list.Element // Open files by key, in lru
+	lru     *list.List               // Open files, most recently used first
+	created map[string]bool          // Keys whose file this sink created
+	buf     []byte
+}
+
+// partitionFile is an open partition file
+type partitionFile struct {
+	key  string
+	file *os.File
+	w    *bufio.Writer
+}
+
+// SplitToFiles returns a sink for NewSplitter that writes each partition
+// as JSON lines, in the format of WriteEntries, to a file in dir named by
+// pattern with its last "*" replaced by the key, or the key appended when
+// there is none. Bytes of the key other than ASCII letters, digits, "-",
+// "_", and ".", and a leading ".", are written as %XX, so every key maps
+// to its own file inside dir. A file is created or truncated when its
+// partition is first written. At most WithMaxOpenFiles files are open at
+// a time: the least recently written one is flushed and closed to make
+// room, and reopened in append mode when its partition is written again.
+// Close flushes and closes the remaining files.
+func SplitToFiles(dir, pattern string, opts ...SplitOption) *FileSink {
+	cfg := newSplitConfig(opts)
+
+	return &FileSink{
+		dir:     dir,
+		pattern: pattern,
+		maxOpen: cfg.maxOpen,
+		open:    make(map[string]*list.Element),
+		lru:     list.New(),
+		created: make(map[string]bool),
+	}
+}
+
+// Write appends an entry to the file of its partition
+func (s *FileSink) Write(key string, e LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pf, err := s.file(key)
+	if err != nil {
+		return err
+	}
+
+	s.buf, err = appendEntryLine(s.buf[:0], &e, FormatJSON)
+	if err != nil {
+		return err
+	}
+
+	_, err = pf.w.Write(append(s.buf, '\n'))
+
+	return err
+}
+
+// Path returns the file a partition is written to
+func (s *FileSink) Path(key string) string {
+	name := escapePartitionKey(key)
+
+	if i := strings.LastIndexByte(s.pattern, '*'); i >= 0 {
+		name = s.pattern[:i] + name + s.pattern[i+1:]
+	} else {
+		name = s.pattern + name
+	}
+
+	return filepath.Join(s.dir, name)
+}
+
+// Close flushes and closes the open files
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+
+	for s.lru.Len() > 0 {
+		errs = append(errs, s.closeFile(s.lru.Back()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// file returns the open file of a partition, opening it and closing the
+// least recently used one if needed
+func (s *FileSink) file(key string) (*partitionFile, error) {
+	if el, ok := s.open[key]; ok {
+		s.lru.MoveToFront(el)
+
+		return el.Value.(*partitionFile), nil
+	}
+
+	for s.lru.Len() >= s.maxOpen {
+		if err := s.closeFile(s.lru.Back()); err != nil {
+			return nil, err
+		}
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if !s.created[key] {
+		if err := os.MkdirAll(s.dir, 0o755); err != nil {
+			return nil, err
+		}
+
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(s.Path(key), flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("partition %q: %w", key, err)
+	}
+
+	s.created[key] = true
+
+	pf := &partitionFile{key: key, file: f, w: bufio.NewWriter(f)}
+	s.open[key] = s.lru.PushFront(pf)
+
+	return pf, nil
+}
+
+// closeFile flushes and closes an open file
+func (s *FileSink) closeFile(el *list.Element) error {
+	pf := s.lru.Remove(el).(*partitionFile)
+	delete(s.open, pf.key)
+
+	err := pf.w.Flush()
+	if closeErr := pf.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("partition %q: %w", pf.key, err)
+	}
+
+	return nil
+}
+
+// escapePartitionKey makes a key safe as a file name, escaping all but
+// ASCII letters, digits, "-", "_", and "." not at the start
+func escapePartitionKey(key string) string {
+	var b strings.Builder
+
+	for i := range len(key) {
+		c := key[i]
+
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_', c == '.' && i > 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
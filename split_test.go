@@ -0,0 +1,172 @@
+package logparser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitterWithWriter(t *testing.T) {
+	var got []string
+
+	split := NewSplitter(SplitByField("tenant_id"), func(key string, e LogEntry) error {
+		got = append(got, key+":"+e.Message)
+
+		return nil
+	}, WithDefaultPartition("shared"))
+
+	w := NewWriter(NewWithFormat(FormatJSON), split.Handle)
+	fmt.Fprintln(w, `{"msg":"a","tenant_id":"acme"}`)
+	fmt.Fprintln(w, `{"msg":"b","tenant_id":42}`)
+	fmt.Fprintln(w, `{"msg":"c"}`)
+	fmt.Fprintln(w, `{"msg":"d","tenant_id":"acme"}`)
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "acme:a 42:b shared:c acme:d"; strings.Join(got, " ") != want {
+		t.Errorf("sink saw %v, want %s", got, want)
+	}
+
+	if counts := split.Counts(); counts["acme"] != 2 || counts["42"] != 1 || counts["shared"] != 1 {
+		t.Errorf("Counts() = %v", counts)
+	}
+}
+
+func TestSplitterSinkError(t *testing.T) {
+	errFull := errors.New("disk full")
+	calls := 0
+
+	split := NewSplitter(SplitByField("t"), func(string, LogEntry) error {
+		calls++
+
+		return errFull
+	})
+
+	split.Handle(LogEntry{})
+	split.Handle(LogEntry{})
+
+	if !errors.Is(split.Err(), errFull) || calls != 1 {
+		t.Errorf("Err() = %v after %d calls, want disk full after 1", split.Err(), calls)
+	}
+
+	if err := split.Add(LogEntry{}); !errors.Is(err, errFull) || len(split.Counts()) != 0 {
+		t.Errorf("Add() = %v, counts %v", err, split.Counts())
+	}
+}
+
+// openFDs returns the number of open file descriptors, or -1 where
+// /proc/self/fd is not available
+func openFDs() int {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+
+	return len(fds)
+}
+
+func TestSplitToFilesBoundsOpenFiles(t *testing.T) {
+	const keys, rounds, limit = 500, 3, 20
+
+	dir := filepath.Join(t.TempDir(), "tenants")
+	files := SplitToFiles(dir, "tenant-*.ndjson", WithMaxOpenFiles(limit))
+	split := NewSplitter(SplitByField("tenant_id"), files.Write)
+
+	baseFDs := openFDs()
+
+	// Keys are interleaved so every file is closed and reopened
+	for round := range rounds {
+		for k := range keys {
+			e := LogEntry{Message: fmt.Sprintf("round %d", round), Level: LevelInfo, Fields: map[string]interface{}{"tenant_id": fmt.Sprintf("t%03d", k)}}
+			if err := split.Add(e); err != nil {
+				t.Fatal(err)
+			}
+
+			if n := files.lru.Len(); n > limit {
+				t.Fatalf("%d files open, limit %d", n, limit)
+			}
+
+			if baseFDs >= 0 && openFDs()-baseFDs > limit {
+				t.Fatalf("%d descriptors opened, limit %d", openFDs()-baseFDs, limit)
+			}
+		}
+	}
+
+	if err := files.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := os.ReadDir(dir)
+	if err != nil || len(names) != keys {
+		t.Fatalf("%d files in dir, %v; want %d", len(names), err, keys)
+	}
+
+	// Every file holds its tenant's entries in order, appended across reopens
+	for _, k := range []int{0, 137, 499} {
+		key := fmt.Sprintf("t%03d", k)
+
+		f, err := os.Open(files.Path(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := NewWithFormat(FormatJSON).Parse(bufio.NewReader(f))
+		f.Close()
+
+		if err != nil || len(entries) != rounds {
+			t.Fatalf("%s: %d entries, %v", key, len(entries), err)
+		}
+
+		for round, e := range entries {
+			if e.Message != fmt.Sprintf("round %d", round) || e.Fields["tenant_id"] != key {
+				t.Errorf("%s entry %d = %+v", key, round, e)
+			}
+		}
+	}
+}
+
+func TestSplitToFilesTruncatesAndEscapes(t *testing.T) {
+	dir := t.TempDir()
+
+	stale := filepath.Join(dir, "acme.jsonl")
+	if err := os.WriteFile(stale, []byte("from an earlier run\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := SplitToFiles(dir, ".jsonl")
+
+	for _, key := range []string{"acme", "../etc/passwd", ".hidden", "a b%"} {
+		if err := files.Write(key, LogEntry{Message: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := files.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without "*" the key is appended to the pattern
+	if data, _ := os.ReadFile(filepath.Join(dir, ".jsonlacme")); !strings.Contains(string(data), `"msg":"acme"`) {
+		t.Errorf("acme file = %q", data)
+	}
+
+	for key, want := range map[string]string{
+		"../etc/passwd": ".jsonl%2E.%2Fetc%2Fpasswd",
+		".hidden":       ".jsonl%2Ehidden",
+		"a b%":          ".jsonla%20b%25",
+	} {
+		if got := filepath.Base(files.Path(key)); got != want {
+			t.Errorf("Path(%q) = %s, want %s", key, got, want)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, want)); err != nil {
+			t.Errorf("%q: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package logparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Stacktrace patterns
+var (
+	goFileRe     = regexp.MustCompile(`^\s+(\S+\.go):(\d+)`)
+	goHeaderRe   = regexp.MustCompile(`^(goroutine \d+ \[.*\]:|panic: |fatal error: |\[signal |exit status )`)
+	javaFrameRe  = regexp.MustCompile(`^\s*at\s+([\w$.<>/]+)\(([^:)]*)(?::(\d+))?\)`)
+	javaHeaderRe = regexp.MustCompile(`^\s*(Caused by: |Suppressed: |\.\.\. \d+ (more|common frames omitted))`)
+	pyFrameRe    = regexp.MustCompile(`^\s*File "([^"]+)", line (\d+)(?:, in (.+))?`)
+)
+
+// Frame is a single call in a parsed stack trace
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// stackLanguage identifies the runtime that produced a stack trace
+type stackLanguage int
+
+const (
+	stackUnknown stackLanguage = iota
+	stackGo
+	stackJava
+	stackPython
+)
+
+// ParseStacktrace parses a Go, Java, or Python stack trace into frames,
+// detecting the language from its frame syntax. Partial or garbled traces
+// return the frames that did parse together with a *ParseError naming the
+// first line that could not be understood.
+func ParseStacktrace(s string) ([]Frame, error) {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+
+	switch detectStackLanguage(lines) {
+	case stackGo:
+		return parseGoStack(lines)
+	case stackJava:
+		return parseJavaStack(lines)
+	case stackPython:
+		return parsePythonStack(lines)
+	default:
+		return nil, &ParseError{Type: "stacktrace", Value: s, Err: "unknown stacktrace format"}
+	}
+}
+
+// detectStackLanguage returns the language of the first recognizable frame
+func detectStackLanguage(lines []string) stackLanguage {
+	for _, line := range lines {
+		switch {
+		case javaFrameRe.MatchString(line):
+			return stackJava
+		case pyFrameRe.MatchString(line):
+			return stackPython
+		case goFileRe.MatchString(line):
+			return stackGo
+		}
+	}
+
+	return stackUnknown
+}
+
+// stackError reports the first unrecognized line of a stack trace
+func stackError(line string) error {
+	return &ParseError{Type: "stacktrace", Value: line, Err: "unrecognized stack frame: " + strings.TrimSpace(line)}
+}
+
+// parseGoStack parses function/file line pairs as printed by panics and
+// runtime/debug.Stack
+func parseGoStack(lines []string) ([]Frame, error) {
+	var (
+		frames   []Frame
+		firstErr error
+		fn       string
+	)
+
+	fail := func(line string) {
+		if firstErr == nil {
+			firstErr = stackError(line)
+		}
+	}
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" || goHeaderRe.MatchString(line) {
+			continue
+		}
+
+		if m := goFileRe.FindStringSubmatch(line); m != nil {
+			if fn == "" {
+				fail(line)
+
+				continue
+			}
+
+			n, _ := strconv.Atoi(m[2])
+			frames = append(frames, Frame{Function: fn, File: m[1], Line: n})
+			fn = ""
+
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			fail(line)
+
+			continue
+		}
+
+		if fn != "" {
+			fail(fn)
+		}
+
+		fn = goFunctionName(line)
+	}
+
+	if fn != "" {
+		fail(fn)
+	}
+
+	return frames, firstErr
+}
+
+// goFunctionName strips call arguments and goroutine annotations from a Go
+// stack function line
+func goFunctionName(line string) string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "created by ")
+
+	if i := strings.Index(line, " in goroutine "); i >= 0 {
+		line = line[:i]
+	}
+
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			line = line[:i]
+		}
+	}
+
+	return line
+}
+
+// parseJavaStack parses "at pkg.Class.method(File.java:10)" frames
+func parseJavaStack(lines []string) ([]Frame, error) {
+	var (
+		frames   []Frame
+		firstErr error
+		seen     bool
+	)
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" || javaHeaderRe.MatchString(line) {
+			continue
+		}
+
+		if m := javaFrameRe.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[3])
+			frames = append(frames, Frame{Function: m[1], File: m[2], Line: n})
+			seen = true
+
+			continue
+		}
+
+		// The exception message precedes the first frame
+		if seen && firstErr == nil {
+			firstErr = stackError(line)
+		}
+	}
+
+	return frames, firstErr
+}
+
+// parsePythonStack parses 'File "x.py", line 3, in f' frames, skipping the
+// source line printed beneath each one
+func parsePythonStack(lines []string) ([]Frame, error) {
+	var (
+		frames   []Frame
+		firstErr error
+		codeLine bool
+	)
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if m := pyFrameRe.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[2])
+			frames = append(frames, Frame{Function: m[3], File: m[1], Line: n})
+			codeLine = true
+
+			continue
+		}
+
+		indented := line[0] == ' ' || line[0] == '\t'
+
+		switch {
+		case indented && codeLine:
+			codeLine = false
+		case indented:
+			if firstErr == nil {
+				firstErr = stackError(line)
+			}
+		default:
+			// Headers and the final exception line
+			codeLine = false
+		}
+	}
+
+	return frames, firstErr
+}
+
+// WithStacktraceParsing parses "stacktrace" and "stack" fields into []Frame.
+// With replace the field value is swapped for the frames; otherwise they are
+// added under "_stack_frames" and the original text is kept.
+func WithStacktraceParsing(replace bool) Option {
+	return func(c *config) {
+		c.parseStacks = true
+		c.replaceStacks = replace
+	}
+}
+
+// expandStacktrace parses the stack trace field of entry, if any
+func (c *config) expandStacktrace(entry *LogEntry) {
+	if !c.parseStacks {
+		return
+	}
+
+	for _, key := range []string{"stacktrace", "stack"} {
+		s, ok := entry.Fields[key].(string)
+		if !ok {
+			continue
+		}
+
+		frames, _ := ParseStacktrace(s)
+		if len(frames) == 0 {
+			continue
+		}
+
+		if c.replaceStacks {
+			entry.Fields[key] = frames
+		} else {
+			entry.Fields["_stack_frames"] = frames
+		}
+
+		return
+	}
+}
@@ -0,0 +1,122 @@
+package logparser
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseStacktraceFixtures(t *testing.T) {
+	tests := []struct {
+		file string
+		want []Frame
+	}{
+		{
+			file: "testdata/stack_go.txt",
+			want: []Frame{
+				{Function: "main.(*Server).handle", File: "/app/server.go", Line: 42},
+				{Function: "main.main", File: "/app/main.go", Line: 12},
+				{Function: "net/http.(*Server).Serve", File: "/usr/local/go/src/net/http/server.go", Line: 3285},
+			},
+		},
+		{
+			file: "testdata/stack_java.txt",
+			want: []Frame{
+				{Function: "com.example.db.Pool.acquire", File: "Pool.java", Line: 88},
+				{Function: "com.example.api.UserHandler.get", File: "UserHandler.java", Line: 31},
+				{Function: "java.base/jdk.internal.reflect.NativeMethodAccessorImpl.invoke0", File: "Native Method"},
+				{Function: "java.base/sun.nio.ch.NioSocketImpl.implWrite", File: "NioSocketImpl.java", Line: 420},
+			},
+		},
+		{
+			file: "testdata/stack_python.txt",
+			want: []Frame{
+				{Function: "<module>", File: "/srv/app/main.py", Line: 27},
+				{Function: "run", File: "/srv/app/main.py", Line: 19},
+				{Function: "process", File: "/srv/app/handler.py", Line: 8},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			frames, err := ParseStacktrace(string(data))
+			if err != nil {
+				t.Fatalf("ParseStacktrace() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(frames, tt.want) {
+				t.Errorf("ParseStacktrace() = %+v, want %+v", frames, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStacktracePartial(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		frames int
+	}{
+		{"go missing file line", "main.a()\n\t/x/a.go:1\nmain.b()\n", 1},
+		{"java garbage after frames", "E: boom\n\tat a.B.c(B.java:1)\n\t#$%!\n\tat a.B.d(B.java:2)", 2},
+		{"python stray indented line", "Traceback:\n  File \"a.py\", line 1, in f\n    f()\n    ???\n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, err := ParseStacktrace(tt.input)
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseStacktrace() error = %v, want *ParseError", err)
+			}
+
+			if len(frames) != tt.frames {
+				t.Errorf("got %d frames, want %d: %+v", len(frames), tt.frames, frames)
+			}
+		})
+	}
+
+	if _, err := ParseStacktrace("not a stack trace"); err == nil {
+		t.Error("expected an error for unrecognized input")
+	}
+}
+
+func TestStacktraceParsingOption(t *testing.T) {
+	data, err := os.ReadFile("testdata/stack_go.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := json.Marshal(map[string]string{"level": "error", "msg": "panic", "stacktrace": string(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := NewWithFormat(FormatJSON, WithStacktraceParsing(false)).ParseString(string(line))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	frames, ok := entries[0].Fields["_stack_frames"].([]Frame)
+	if !ok || len(frames) != 3 || entries[0].Fields["stacktrace"] != string(data) {
+		t.Errorf("expected frames alongside the original text, got %v", entries[0].Fields)
+	}
+
+	entries, err = NewWithFormat(FormatJSON, WithStacktraceParsing(true)).ParseString(string(line))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if frames, ok := entries[0].Fields["stacktrace"].([]Frame); !ok || frames[1].Function != "main.main" {
+		t.Errorf("expected stacktrace replaced by frames, got %v", entries[0].Fields["stacktrace"])
+	}
+}
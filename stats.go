@@ -0,0 +1,38 @@
+package logparser
+
+import "fmt"
+
+// Stats describes a single parse call
+type Stats struct {
+	LinesSeen         int              `json:"lines_seen"`                // Lines read, less comments and blank lines (see WithKeepBlankLines)
+	BlankLines        int              `json:"blank_lines"`               // Blank lines passed over
+	CommentLines      int              `json:"comment_lines"`             // Lines skipped by WithCommentPrefix
+	WrappedLines      int              `json:"wrapped_lines"`             // Lines joined to the line before by WithWrappedLines
+	EntriesEmitted    int              `json:"entries_emitted"`           // Entries returned to the caller
+	LinesSkipped      int              `json:"lines_skipped"`             // Lines dropped after a parse or transform error
+	DuplicatesDropped int              `json:"duplicates_dropped"`        // Entries dropped by WithDedupFilter
+	PartialLines      int              `json:"partial_lines"`             // Cut-off first or last lines handled by WithPartialLines
+	FallbackLines     int              `json:"fallback_lines"`            // Lines parsed by a WithFallbackFormats format
+	DurationsUnparsed int              `json:"durations_unparsed"`        // Duration field values left unconverted
+	PayloadsUndecoded int              `json:"payloads_undecoded"`        // Payload field values WithDecodePayloads could not expand
+	EnrichmentHits    int              `json:"enrichment_hits"`           // Lookups that found a WithEnrichment or WithCIDREnrichment value
+	EnrichmentMisses  int              `json:"enrichment_misses"`         // Lookups of present fields that found none
+	FieldsTruncated   int              `json:"fields_truncated"`          // Values shortened by WithMaxFieldSize
+	ConflictedEntries int              `json:"conflicted_entries"`        // Entries given Fields["_conflicts"] by WithConflicts
+	Detections        int              `json:"detections"`                // Format auto-detection passes
+	FormatSwitches    []FormatSwitch   `json:"format_switches,omitempty"` // Format changes found with WithBlockDetection
+	Timings           *TimingBreakdown `json:"timings,omitempty"`         // Time per phase, under WithTimings
+	Sampling          *SamplingStats   `json:"sampling,omitempty"`        // Decisions of WithSmartSampling
+}
+
+// FormatSwitch records a change of detected format mid-input
+type FormatSwitch struct {
+	Line int    `json:"line"` // First line parsed with the new format
+	From Format `json:"from"`
+	To   Format `json:"to"`
+}
+
+// String describes the switch, for example "line 42: json→logfmt"
+func (s FormatSwitch) String() string {
+	return fmt.Sprintf("line %d: %s→%s", s.Line, s.From, s.To)
+}
@@ -0,0 +1,220 @@
+package logparser
+
+import (
+	"strings"
+	"time"
+)
+
+// stdField identifies a standard LogEntry field extracted from a key
+type stdField int
+
+const (
+	stdTimestamp stdField = iota
+	stdLevel
+	stdMessage
+	stdFieldCount
+)
+
+// maxStdKeys bounds the candidate keys per standard field
+const maxStdKeys = 8
+
+// stdKeyTable maps lower-cased key names to the standard field they hold
+// and their precedence within it
+type stdKeyTable map[string]stdKeyRank
+
+type stdKeyRank struct {
+	field stdField
+	rank  int
+}
+
+// stdKeySet holds, per standard field, the keys present in an object in
+// precedence order; unused slots are empty
+type stdKeySet [stdFieldCount][maxStdKeys]string
+
+// Standard key names, in precedence order, for each structured format.
+// Matching is case-insensitive, so Level, LogLevel, and RenderedMessage
+// (Serilog, NLog) resolve like their lower-case forms.
+var (
+	jsonStdKeys = newStdKeyTable(
+		[]string{"timestamp", "time", "@timestamp", "ts", "@t"},
+		[]string{"level", "severity", "log.level", "loglevel", "@l"},
+		[]string{"message", "msg", "log", "renderedmessage", "@m", "@mt"},
+	)
+	logfmtStdKeys = newStdKeyTable(
+		[]string{"timestamp", "time", "ts"},
+		[]string{"level", "loglevel"},
+		[]string{"msg", "message"},
+	)
+)
+
+// newStdKeyTable builds a table from the key lists of each standard field
+func newStdKeyTable(timestamp, level, message []string) stdKeyTable {
+	table := make(stdKeyTable)
+
+	for field, keys := range [stdFieldCount][]string{timestamp, level, message} {
+		for rank, key := range keys {
+			table[key] = stdKeyRank{field: stdField(field), rank: rank}
+		}
+	}
+
+	return table
+}
+
+// scan finds the standard keys of m in a single pass. When a key appears in
+// several cases, the exact lower-case spelling wins.
+func (t stdKeyTable) scan(m map[string]interface{}) stdKeySet {
+	var set stdKeySet
+
+	for key := range m {
+		lower := strings.ToLower(key)
+
+		slot, ok := t[lower]
+		if !ok {
+			continue
+		}
+
+		current := &set[slot.field][slot.rank]
+		if *current == "" || key == lower {
+			*current = key
+		}
+	}
+
+	return set
+}
+
+// extractStdFields sets the timestamp, level, and message from the
+// candidate keys found by scan, recording disagreeing candidates in
+// Fields["_conflicts"] when a ConflictPolicy asks for it
+func extractStdFields(raw map[string]interface{}, keys *stdKeySet, entry *LogEntry, cfg *config) {
+	var conflicts []interface{}
+
+	extractTimestamp(raw, keys[stdTimestamp][:], entry, cfg, &conflicts)
+	extractLevel(raw, keys[stdLevel][:], entry, cfg, &conflicts)
+	extractMessage(raw, keys[stdMessage][:], entry, cfg, &conflicts)
+
+	if len(conflicts) > 0 {
+		cfg.setField(entry, "_conflicts", conflicts)
+	}
+}
+
+// extractTimestamp sets the timestamp from the first candidate key that
+// parses, or the earliest under ConflictResolve
+func extractTimestamp(raw map[string]interface{}, keys []string, entry *LogEntry, cfg *config, conflicts *[]interface{}) {
+	var (
+		found [maxStdKeys]time.Time
+		win   = -1
+	)
+
+	for i, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		start := cfg.timer.start()
+		t, err := cfg.parseTime(raw[key])
+		cfg.timer.stop(phaseExtractTS, start, 1)
+
+		if err != nil {
+			continue
+		}
+
+		if t.Year() == 0 {
+			t = cfg.inferYear(t)
+		}
+
+		found[i] = t
+
+		if win < 0 || cfg.conflicts == ConflictResolve && t.Before(found[win]) {
+			win = i
+		}
+
+		if cfg.conflicts == ConflictIgnore {
+			break
+		}
+	}
+
+	if win < 0 {
+		return
+	}
+
+	entry.Timestamp = found[win]
+
+	for i, key := range keys {
+		if !found[i].IsZero() && !found[i].Equal(found[win]) {
+			*conflicts = append(*conflicts, stdConflict("timestamp", key, raw[key]))
+		}
+	}
+
+	cfg.consumeKey(raw, "_ts_key", keys[win])
+}
+
+// extractLevel sets the level from the first candidate key holding a
+// string, or the most severe under ConflictResolve
+func extractLevel(raw map[string]interface{}, keys []string, entry *LogEntry, cfg *config, conflicts *[]interface{}) {
+	var (
+		found [maxStdKeys]string
+		win   = -1
+	)
+
+	for i, key := range keys {
+		s, ok := raw[key].(string)
+		if !ok || key == "" {
+			continue
+		}
+
+		found[i] = ParseLevel(s)
+
+		if win < 0 || cfg.conflicts == ConflictResolve && levelRank(found[i]) > levelRank(found[win]) {
+			win = i
+		}
+
+		if cfg.conflicts == ConflictIgnore {
+			break
+		}
+	}
+
+	if win < 0 {
+		return
+	}
+
+	entry.Level = found[win]
+
+	for i, key := range keys {
+		if found[i] != "" && found[i] != found[win] {
+			*conflicts = append(*conflicts, stdConflict("level", key, raw[key]))
+		}
+	}
+
+	cfg.consumeKey(raw, "_level_key", keys[win])
+}
+
+// extractMessage sets the message from the first candidate key holding a string
+func extractMessage(raw map[string]interface{}, keys []string, entry *LogEntry, cfg *config, conflicts *[]interface{}) {
+	win := -1
+
+	for i, key := range keys {
+		s, ok := raw[key].(string)
+		if !ok || key == "" {
+			continue
+		}
+
+		if win < 0 {
+			win = i
+			entry.Message = s
+
+			if cfg.conflicts == ConflictIgnore {
+				break
+			}
+
+			continue
+		}
+
+		if s != entry.Message {
+			*conflicts = append(*conflicts, stdConflict("message", key, s))
+		}
+	}
+
+	if win >= 0 {
+		cfg.consumeKey(raw, "_msg_key", keys[win])
+	}
+}
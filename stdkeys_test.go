@@ -0,0 +1,85 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCaseInsensitiveStandardKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		input   string
+		level   string
+		message string
+		ts      time.Time
+	}{
+		{
+			name:    "Serilog compact",
+			format:  FormatJSON,
+			input:   `{"@t":"2024-01-02T15:04:05Z","@l":"Warning","@m":"Disk low on C:","@mt":"Disk low on {Drive}","Drive":"C:"}`,
+			level:   "WARN",
+			message: "Disk low on C:",
+			ts:      time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "Serilog JSON formatter",
+			format:  FormatJSON,
+			input:   `{"Timestamp":"2024-01-02T15:04:05Z","Level":"Error","RenderedMessage":"Payment failed","Properties":{"OrderId":7}}`,
+			level:   "ERROR",
+			message: "Payment failed",
+			ts:      time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    ".NET console JSON",
+			format:  FormatJSON,
+			input:   `{"TimeStamp":"2024-01-02T15:04:05Z","LogLevel":"Information","Message":"Request started","Category":"Api"}`,
+			level:   "INFO",
+			message: "Request started",
+			ts:      time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "NLog JSON",
+			format:  FormatJSON,
+			input:   `{"time":"2024-01-02T15:04:05Z","Level":"Debug","Message":"Cache warmed","logger":"App.Cache"}`,
+			level:   "DEBUG",
+			message: "Cache warmed",
+			ts:      time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "logfmt capitalised",
+			format:  FormatLogfmt,
+			input:   `Time=2024-01-02T15:04:05Z Level=error Msg="Connection timeout"`,
+			level:   "ERROR",
+			message: "Connection timeout",
+			ts:      time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := NewWithFormat(tt.format).ParseString(tt.input)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			e := entries[0]
+			if e.Level != tt.level || e.Message != tt.message || !e.Timestamp.Equal(tt.ts) {
+				t.Errorf("got %s %q %v, want %s %q %v", e.Level, e.Message, e.Timestamp, tt.level, tt.message, tt.ts)
+			}
+		})
+	}
+}
+
+func TestStandardKeyPrecedence(t *testing.T) {
+	// The exact lower-case key wins over other spellings, and earlier names
+	// in the list win over later ones
+	entries, err := NewWithFormat(FormatJSON).ParseString(`{"Message":"upper","message":"lower","msg":"short"}`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if entries[0].Message != "lower" || entries[0].Fields["Message"] != "upper" || entries[0].Fields["msg"] != "short" {
+		t.Errorf("unexpected entry %+v", entries[0])
+	}
+}
@@ -0,0 +1,391 @@
+package logparser
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultErrorHalfLife is the error rate half-life StreamStats uses unless
+// StreamStatsOptions.ErrorHalfLife says otherwise
+const DefaultErrorHalfLife = time.Minute
+
+// DefaultQuantiles are the quantiles StreamStats estimates unless
+// StreamStatsOptions.Quantiles says otherwise
+var DefaultQuantiles = []float64{0.5, 0.9, 0.99}
+
+// StreamStatsOptions configures StreamStats
+type StreamStatsOptions struct {
+	// Fields are the numeric fields, such as "latency_ms" or "http.duration",
+	// whose distribution is tracked. Numbers and numeric strings are used
+	// as they are; durations, as time.Duration values or strings such as
+	// "250ms", in milliseconds.
+	Fields []string
+	// Quantiles to estimate for each field, between 0 and 1 (default
+	// DefaultQuantiles)
+	Quantiles []float64
+	// ErrorHalfLife is the time over which an error's weight in
+	// StatsSnapshot.ErrorRate halves (default DefaultErrorHalfLife)
+	ErrorHalfLife time.Duration
+	// TopK are the fields, such as "service" or "http.status", whose most
+	// frequent values are counted, in TopKSize×10 counters each (see TopK)
+	TopK []string
+	// TopKSize is the number of values reported per TopK field (default
+	// DefaultTopK)
+	TopKSize int
+}
+
+// StreamStats keeps running statistics over a stream of entries in a fixed
+// amount of memory: counts per level, an exponentially weighted error
+// rate, the quantiles of selected numeric fields, and the most frequent
+// values of selected fields. Quantiles are estimated with the P²
+// algorithm, five values per quantile, so they are approximate: typically
+// within a few percent for smooth distributions, but poor for a quantile
+// that falls in a gap between clusters of values.
+// Observe and Snapshot may be called concurrently.
+type StreamStats struct {
+	mu sync.RWMutex
+
+	quantiles []float64
+	tau       float64 // Error rate time constant in seconds
+
+	count     int64
+	levels    map[string]int64
+	errors    int64
+	errorRate float64 // Errors per second as of latest
+	latest    time.Time
+	fields    map[string]*fieldStream
+	topKSize  int
+	topK      map[string]*topKSketch
+}
+
+// StatsSnapshot is the state of a StreamStats at one moment
+type StatsSnapshot struct {
+	Count     int64                   `json:"count"`
+	Levels    map[string]int64        `json:"levels"`     // Entries per level; entries without one are not listed
+	Errors    int64                   `json:"errors"`     // ERROR and FATAL entries
+	ErrorRate float64                 `json:"error_rate"` // Errors per second, exponentially weighted, as of Latest
+	Latest    time.Time               `json:"latest"`     // Latest entry timestamp seen
+	Fields    map[string]FieldStats   `json:"fields,omitempty"`
+	TopValues map[string][]ValueCount `json:"top_values,omitempty"` // Most frequent values of the TopK fields
+}
+
+// FieldStats summarizes the values of a numeric field
+type FieldStats struct {
+	Count     int64      `json:"count"`
+	Min       float64    `json:"min"`
+	Max       float64    `json:"max"`
+	Mean      float64    `json:"mean"`
+	Quantiles []Quantile `json:"quantiles"`
+}
+
+// Quantile is an estimated quantile of a field
+type Quantile struct {
+	Q     float64 `json:"q"`
+	Value float64 `json:"value"`
+}
+
+// Quantile returns the estimate for quantile q, if it is tracked
+func (f FieldStats) Quantile(q float64) (float64, bool) {
+	for _, est := range f.Quantiles {
+		if est.Q == q {
+			return est.Value, true
+		}
+	}
+
+	return 0, false
+}
+
+// fieldStream accumulates one field
+type fieldStream struct {
+	count     int64
+	min, max  float64
+	sum       float64
+	estimates []p2Quantile
+}
+
+// NewStreamStats returns empty statistics
+func NewStreamStats(opts StreamStatsOptions) *StreamStats {
+	quantiles := opts.Quantiles
+	if len(quantiles) == 0 {
+		quantiles = DefaultQuantiles
+	}
+
+	halfLife := opts.ErrorHalfLife
+	if halfLife <= 0 {
+		halfLife = DefaultErrorHalfLife
+	}
+
+	topKSize := opts.TopKSize
+	if topKSize <= 0 {
+		topKSize = DefaultTopK
+	}
+
+	s := &StreamStats{
+		quantiles: slices.Clone(quantiles),
+		tau:       halfLife.Seconds() / math.Ln2,
+		levels:    make(map[string]int64),
+		fields:    make(map[string]*fieldStream, len(opts.Fields)),
+		topKSize:  topKSize,
+		topK:      make(map[string]*topKSketch, len(opts.TopK)),
+	}
+
+	for _, key := range opts.TopK {
+		s.topK[key] = newTopKSketch(topKSize)
+	}
+
+	for _, key := range opts.Fields {
+		f := &fieldStream{estimates: make([]p2Quantile, len(quantiles))}
+		for i, q := range quantiles {
+			f.estimates[i] = newP2Quantile(q)
+		}
+
+		s.fields[key] = f
+	}
+
+	return s
+}
+
+// Observe adds an entry. The error rate decays by entry timestamp, so a
+// replayed log gives the rate it had at the time; entries older than the
+// latest count as at the latest, and entries without a timestamp as at
+// the time they are observed.
+func (s *StreamStats) Observe(entry LogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+
+	if entry.Level != "" {
+		s.levels[entry.Level]++
+	}
+
+	ts := entry.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	if ts.After(s.latest) {
+		if !s.latest.IsZero() {
+			s.errorRate *= math.Exp(-ts.Sub(s.latest).Seconds() / s.tau)
+		}
+
+		s.latest = ts
+	}
+
+	if entry.Level == LevelError || entry.Level == "FATAL" {
+		s.errors++
+		s.errorRate += 1 / s.tau
+	}
+
+	for key, f := range s.fields {
+		val, ok := lookupField(entry.Fields, key)
+		if !ok {
+			continue
+		}
+
+		if x, ok := streamValue(val); ok {
+			f.add(x)
+		}
+	}
+
+	for key, sketch := range s.topK {
+		if val, ok := entryField(&entry, key); ok {
+			sketch.add(formatValue(val))
+		}
+	}
+}
+
+// Snapshot returns a copy of the current statistics
+func (s *StreamStats) Snapshot() StatsSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap := StatsSnapshot{
+		Count:     s.count,
+		Levels:    make(map[string]int64, len(s.levels)),
+		Errors:    s.errors,
+		ErrorRate: s.errorRate,
+		Latest:    s.latest,
+	}
+
+	for level, n := range s.levels {
+		snap.Levels[level] = n
+	}
+
+	if len(s.fields) > 0 {
+		snap.Fields = make(map[string]FieldStats, len(s.fields))
+	}
+
+	for key, f := range s.fields {
+		fs := FieldStats{Count: f.count, Quantiles: make([]Quantile, len(f.estimates))}
+
+		if f.count > 0 {
+			fs.Min, fs.Max, fs.Mean = f.min, f.max, f.sum/float64(f.count)
+		}
+
+		for i := range f.estimates {
+			fs.Quantiles[i] = Quantile{Q: f.estimates[i].p, Value: f.estimates[i].value()}
+		}
+
+		snap.Fields[key] = fs
+	}
+
+	if len(s.topK) > 0 {
+		snap.TopValues = make(map[string][]ValueCount, len(s.topK))
+	}
+
+	for key, sketch := range s.topK {
+		snap.TopValues[key] = sketch.top(s.topKSize)
+	}
+
+	return snap
+}
+
+// add records a field value
+func (f *fieldStream) add(x float64) {
+	if f.count == 0 || x < f.min {
+		f.min = x
+	}
+
+	if f.count == 0 || x > f.max {
+		f.max = x
+	}
+
+	f.count++
+	f.sum += x
+
+	for i := range f.estimates {
+		f.estimates[i].add(x)
+	}
+}
+
+// streamValue converts a field value to a number, durations to
+// milliseconds
+func streamValue(val interface{}) (float64, bool) {
+	if d, ok := val.(time.Duration); ok {
+		return DurationMillis(d), true
+	}
+
+	if x, ok := numericValue(val); ok {
+		return x, !math.IsNaN(x) && !math.IsInf(x, 0)
+	}
+
+	if s, ok := val.(string); ok {
+		if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+			return DurationMillis(d), true
+		}
+	}
+
+	return 0, false
+}
+
+// p2Quantile estimates a quantile with the P² algorithm of Jain and
+// Chlamtac, which keeps five markers whose heights approximate the
+// minimum, the quantile, the maximum, and the points halfway between
+type p2Quantile struct {
+	p      float64
+	n      int
+	height [5]float64
+	pos    [5]float64 // Actual marker positions, from 1
+	want   [5]float64 // Desired marker positions
+	step   [5]float64 // Desired position increments
+}
+
+func newP2Quantile(p float64) p2Quantile {
+	return p2Quantile{
+		p:    p,
+		want: [5]float64{1, 1 + 2*p, 1 + 4*p, 3 + 2*p, 5},
+		step: [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+// add records an observation
+func (e *p2Quantile) add(x float64) {
+	if e.n < 5 {
+		e.height[e.n] = x
+		e.n++
+
+		if e.n == 5 {
+			slices.Sort(e.height[:])
+			e.pos = [5]float64{1, 2, 3, 4, 5}
+		}
+
+		return
+	}
+
+	e.n++
+
+	// Find the cell holding x, stretching the extremes if needed
+	var k int
+
+	switch {
+	case x < e.height[0]:
+		e.height[0] = x
+	case x >= e.height[4]:
+		e.height[4] = x
+		k = 3
+	default:
+		for x >= e.height[k+1] {
+			k++
+		}
+	}
+
+	for i := k + 1; i < 5; i++ {
+		e.pos[i]++
+	}
+
+	for i := range e.want {
+		e.want[i] += e.step[i]
+	}
+
+	// Move the middle markers toward their desired positions
+	for i := 1; i < 4; i++ {
+		d := e.want[i] - e.pos[i]
+
+		if d >= 1 && e.pos[i+1]-e.pos[i] > 1 || d <= -1 && e.pos[i-1]-e.pos[i] < -1 {
+			s := math.Copysign(1, d)
+
+			h := e.parabolic(i, s)
+			if h <= e.height[i-1] || h >= e.height[i+1] {
+				h = e.linear(i, s)
+			}
+
+			e.height[i] = h
+			e.pos[i] += s
+		}
+	}
+}
+
+// parabolic is the piecewise parabolic prediction for moving marker i by s
+func (e *p2Quantile) parabolic(i int, s float64) float64 {
+	return e.height[i] + s/(e.pos[i+1]-e.pos[i-1])*
+		((e.pos[i]-e.pos[i-1]+s)*(e.height[i+1]-e.height[i])/(e.pos[i+1]-e.pos[i])+
+			(e.pos[i+1]-e.pos[i]-s)*(e.height[i]-e.height[i-1])/(e.pos[i]-e.pos[i-1]))
+}
+
+// linear is the linear prediction for moving marker i by s
+func (e *p2Quantile) linear(i int, s float64) float64 {
+	j := i + int(s)
+
+	return e.height[i] + s*(e.height[j]-e.height[i])/(e.pos[j]-e.pos[i])
+}
+
+// value returns the estimate, exact while there are five observations or
+// fewer
+func (e *p2Quantile) value() float64 {
+	if e.n == 0 {
+		return 0
+	}
+
+	if e.n < 5 {
+		sorted := slices.Clone(e.height[:e.n])
+		slices.Sort(sorted)
+
+		return sorted[max(0, int(math.Ceil(e.p*float64(e.n)))-1)]
+	}
+
+	return e.height[2]
+}
@@ -0,0 +1,196 @@
+package logparser
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// exactQuantile returns the nearest-rank quantile q of sorted values
+func exactQuantile(sorted []float64, q float64) float64 {
+	return sorted[max(0, int(math.Ceil(q*float64(len(sorted))))-1)]
+}
+
+func TestStreamStatsQuantileAccuracy(t *testing.T) {
+	const n = 100000
+
+	rng := rand.New(rand.NewSource(42))
+
+	tests := []struct {
+		name string
+		gen  func(i int) float64
+		tol  float64 // Allowed error relative to the exact quantile
+	}{
+		{"uniform", func(int) float64 { return rng.Float64() * 1000 }, 0.02},
+		{"normal", func(int) float64 { return 200 + 30*rng.NormFloat64() }, 0.02},
+		{"exponential", func(int) float64 { return 50 * rng.ExpFloat64() }, 0.03},
+		{"lognormal", func(int) float64 { return math.Exp(3 + rng.NormFloat64()) }, 0.05},
+		// A latency spike halfway through, 2% of the values, moves p99
+		{"burst", func(i int) float64 {
+			if i >= n/2 && i < n/2+n/50 {
+				return 2000 + 100*rng.Float64()
+			}
+
+			return 100 + 10*rng.NormFloat64()
+		}, 0.05},
+		{"ascending", func(i int) float64 { return float64(i) }, 0.02},
+	}
+
+	quantiles := []float64{0.5, 0.9, 0.95, 0.99}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStreamStats(StreamStatsOptions{Fields: []string{"latency"}, Quantiles: quantiles})
+			values := make([]float64, n)
+
+			for i := range values {
+				values[i] = tt.gen(i)
+				s.Observe(LogEntry{Level: LevelInfo, Fields: map[string]interface{}{"latency": values[i]}})
+			}
+
+			slices.Sort(values)
+
+			fs := s.Snapshot().Fields["latency"]
+			if fs.Count != n || fs.Min != values[0] || fs.Max != values[n-1] {
+				t.Errorf("count %d, min %v, max %v", fs.Count, fs.Min, fs.Max)
+			}
+
+			for _, q := range quantiles {
+				got, ok := fs.Quantile(q)
+				want := exactQuantile(values, q)
+
+				if !ok || math.Abs(got-want) > tt.tol*math.Abs(want) {
+					t.Errorf("p%v = %.2f, exact %.2f, outside %.0f%%", q*100, got, want, tt.tol*100)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamStatsFewValues(t *testing.T) {
+	s := NewStreamStats(StreamStatsOptions{Fields: []string{"ms"}, Quantiles: []float64{0.5, 1}})
+
+	for _, v := range []interface{}{"30", 10.0, json.Number("20")} {
+		s.Observe(LogEntry{Fields: map[string]interface{}{"ms": v}})
+	}
+
+	fs := s.Snapshot().Fields["ms"]
+
+	if p50, _ := fs.Quantile(0.5); p50 != 20 {
+		t.Errorf("p50 = %v, want 20", p50)
+	}
+
+	if p100, _ := fs.Quantile(1); p100 != 30 || fs.Mean != 20 {
+		t.Errorf("p100 = %v, mean = %v", p100, fs.Mean)
+	}
+
+	if _, ok := fs.Quantile(0.99); ok {
+		t.Error("untracked quantile reported")
+	}
+}
+
+func TestStreamStatsFields(t *testing.T) {
+	s := NewStreamStats(StreamStatsOptions{Fields: []string{"http.duration", "missing"}})
+
+	for _, v := range []interface{}{250 * time.Millisecond, "1.5s", 100.0, "fast", nil} {
+		s.Observe(LogEntry{Level: "DEBUG", Fields: map[string]interface{}{
+			"http": map[string]interface{}{"duration": v},
+		}})
+	}
+
+	snap := s.Snapshot()
+
+	fs := snap.Fields["http.duration"]
+	if fs.Count != 3 || fs.Min != 100 || fs.Max != 1500 {
+		t.Errorf("http.duration = %+v, want 3 values from 100 to 1500", fs)
+	}
+
+	if snap.Fields["missing"].Count != 0 || len(snap.Fields["missing"].Quantiles) != len(DefaultQuantiles) {
+		t.Errorf("missing = %+v", snap.Fields["missing"])
+	}
+
+	if snap.Count != 5 || snap.Levels["DEBUG"] != 5 {
+		t.Errorf("count %d, levels %v", snap.Count, snap.Levels)
+	}
+}
+
+func TestStreamStatsErrorRate(t *testing.T) {
+	s := NewStreamStats(StreamStatsOptions{ErrorHalfLife: time.Minute})
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// One error per second for ten half-lives settles at one per second
+	for i := range 600 {
+		s.Observe(LogEntry{Timestamp: start.Add(time.Duration(i) * time.Second), Level: LevelError})
+		s.Observe(LogEntry{Timestamp: start.Add(time.Duration(i)*time.Second + time.Second/2), Level: LevelInfo})
+	}
+
+	snap := s.Snapshot()
+	if math.Abs(snap.ErrorRate-1) > 0.02 {
+		t.Errorf("steady rate = %v, want about 1/s", snap.ErrorRate)
+	}
+
+	if snap.Errors != 600 || snap.Levels[LevelInfo] != 600 || snap.Count != 1200 {
+		t.Errorf("errors %d, levels %v", snap.Errors, snap.Levels)
+	}
+
+	// A minute of INFO halves it
+	before := snap.ErrorRate
+	s.Observe(LogEntry{Timestamp: snap.Latest.Add(time.Minute), Level: LevelInfo})
+
+	if got := s.Snapshot().ErrorRate; math.Abs(got-before/2) > 1e-9 {
+		t.Errorf("rate after one half-life = %v, want %v", got, before/2)
+	}
+
+	// An out of order entry counts at the latest time
+	latest := s.Snapshot().Latest
+	s.Observe(LogEntry{Timestamp: start, Level: "FATAL"})
+
+	if snap := s.Snapshot(); !snap.Latest.Equal(latest) || snap.Errors != 601 {
+		t.Errorf("latest %v, errors %d", snap.Latest, snap.Errors)
+	}
+}
+
+func TestStreamStatsConcurrentReaders(t *testing.T) {
+	s := NewStreamStats(StreamStatsOptions{Fields: []string{"ms"}})
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+
+	for range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				snap := s.Snapshot()
+				if snap.Fields["ms"].Count > snap.Count {
+					t.Error("snapshot not consistent")
+
+					return
+				}
+			}
+		}()
+	}
+
+	for i := range 10000 {
+		s.Observe(LogEntry{Level: LevelInfo, Fields: map[string]interface{}{"ms": float64(i)}})
+	}
+
+	close(done)
+	wg.Wait()
+
+	if got := s.Snapshot().Count; got != 10000 {
+		t.Errorf("count = %d", got)
+	}
+}
@@ -0,0 +1,139 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStrictTimestamp is the line error for an entry without a timestamp
+// in one of the WithStrictTimestamps layouts
+var ErrStrictTimestamp = errors.New("no timestamp in a strict layout")
+
+// ErrAmbiguousLayouts is returned by AmbiguityCheck for layouts that read
+// the same text as different times
+var ErrAmbiguousLayouts = errors.New("ambiguous timestamp layouts")
+
+// ambiguityProbes are the times AmbiguityCheck writes with each layout and
+// reads back with the others. Day, month, and two-digit year differ and
+// are all 12 or less, so swapped fields still parse.
+var ambiguityProbes = []time.Time{
+	time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC),
+	time.Date(2003, 4, 5, 6, 7, 8, 900000000, time.UTC),
+	time.Date(2011, 12, 10, 9, 8, 7, 0, time.UTC),
+	time.Date(2009, 11, 7, 23, 45, 1, 0, time.UTC),
+}
+
+// WithStrictTimestamps accepts only timestamps written in one of layouts
+// (default time.RFC3339, which also reads fractional seconds), for
+// pipelines that must not guess at a date such as 01/02/2024. Values of
+// timestamp keys in JSON and logfmt lines, and timestamps captured by text
+// and grok patterns, are parsed with these layouts alone; the built-in
+// layouts, epoch numbers, and WithTimestampParser parsers are not tried.
+// Layouts without a zone read the configured location, and layouts
+// without a year have it inferred. An entry left without a timestamp
+// fails its line with ErrStrictTimestamp, collected like any other line
+// error (see WithMaxErrors and WithSkipInvalid), instead of getting the
+// current time. Formats with a fixed, documented timestamp layout, such
+// as FormatALB and FormatHAProxy, keep it. NewE rejects layouts that
+// AmbiguityCheck flags.
+func WithStrictTimestamps(layouts ...string) Option {
+	return func(c *config) {
+		c.strictTime = true
+		c.strictLayouts = append(c.strictLayouts, layouts...)
+	}
+}
+
+// AmbiguityCheck reports layouts that read the same text as different
+// times, such as "01/02/2006" and "02/01/2006", which both read
+// 01/02/2024, as January 2 and as February 1. Each layout writes a set of
+// probe times whose day, month, and year are all small enough to be
+// mistaken for one another, and every other layout reads them back. The
+// error wraps ErrAmbiguousLayouts and names the first pair found; nil
+// means no probe was read two ways.
+func AmbiguityCheck(layouts []string) error {
+	for i, a := range layouts {
+		for _, b := range layouts[i+1:] {
+			if a == b {
+				continue
+			}
+
+			if err := ambiguousPair(a, b); err != nil {
+				return err
+			}
+
+			if err := ambiguousPair(b, a); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ambiguousPair reports text written with layout a that layout b reads
+// as a different time than a does
+func ambiguousPair(a, b string) error {
+	for _, probe := range ambiguityProbes {
+		text := probe.Format(a)
+
+		ta, err := time.Parse(a, text)
+		if err != nil {
+			continue
+		}
+
+		tb, err := time.Parse(b, text)
+		if err != nil || tb.Equal(ta) {
+			continue
+		}
+
+		return fmt.Errorf("%w: %q and %q read %q as %s and %s", ErrAmbiguousLayouts, a, b, text,
+			ta.Format(time.DateTime), tb.Format(time.DateTime))
+	}
+
+	return nil
+}
+
+// strictTimeLayouts returns the configured strict layouts, or the default
+func (c *config) strictTimeLayouts() []string {
+	if len(c.strictLayouts) == 0 {
+		return []string{time.RFC3339}
+	}
+
+	return c.strictLayouts
+}
+
+// parseStrictTime parses a timestamp string with the strict layouts alone
+func (c *config) parseStrictTime(val interface{}) (time.Time, error) {
+	s, ok := val.(string)
+	if !ok {
+		return time.Time{}, &ParseError{Type: "timestamp", Value: val, Err: "strict timestamps must be strings"}
+	}
+
+	for _, layout := range c.strictTimeLayouts() {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case t.Year() == 0:
+			t = c.inferYear(t)
+		case !layoutHasZone(layout):
+			t = wallClock(t, c.timeLocation())
+		}
+
+		return t, nil
+	}
+
+	return time.Time{}, &ParseError{Type: "timestamp", Value: s, Err: "not in a strict timestamp layout"}
+}
+
+// checkStrictTime fails an entry left without a timestamp in strict mode
+func (c *config) checkStrictTime(entry *LogEntry) error {
+	if c.strictTime && entry.Timestamp.IsZero() {
+		return ErrStrictTimestamp
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package logparser
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// strictLineErrors parses the ambiguous dates fixture and returns the
+// entries and the numbers of the lines that failed
+func strictLineErrors(t *testing.T, opts ...Option) ([]LogEntry, []int) {
+	t.Helper()
+
+	f, err := os.Open("testdata/ambiguous_dates.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	entries, err := NewWithFormat(FormatJSON, append(opts, WithMaxErrors(10), WithSkipInvalid(true))...).Parse(f)
+
+	var lines []int
+
+	var lineErrs *LineErrors
+	if errors.As(err, &lineErrs) {
+		for _, le := range lineErrs.Errors {
+			if !errors.Is(le.Err, ErrStrictTimestamp) {
+				t.Errorf("line %d: error = %v, want ErrStrictTimestamp", le.Line, le.Err)
+			}
+
+			lines = append(lines, le.Line)
+		}
+	} else if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	return entries, lines
+}
+
+func TestStrictTimestampsDefault(t *testing.T) {
+	entries, failed := strictLineErrors(t, WithStrictTimestamps())
+
+	// Only the RFC 3339 line parses; the others are rejected instead of
+	// being read as US dates or stamped with the current time
+	if !reflect.DeepEqual(failed, []int{1, 2, 3, 5}) {
+		t.Errorf("failed lines = %v, want [1 2 3 5]", failed)
+	}
+
+	if len(entries) != 1 || !entries[0].Timestamp.Equal(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	// Without strict mode the same lines all parse
+	if _, failed := strictLineErrors(t); failed != nil {
+		t.Errorf("lenient failed lines = %v", failed)
+	}
+}
+
+func TestStrictTimestampsExplicitLayout(t *testing.T) {
+	entries, failed := strictLineErrors(t, WithStrictTimestamps("01/02/2006 15:04:05"), WithLocation(time.UTC))
+
+	// The EU-only date, the RFC 3339 line, and the line without a
+	// timestamp fail under a US layout
+	if !reflect.DeepEqual(failed, []int{3, 4, 5}) {
+		t.Errorf("failed lines = %v, want [3 4 5]", failed)
+	}
+
+	want := []time.Time{
+		time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 13, 10, 0, 0, 0, time.UTC),
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for i, w := range want {
+		if !entries[i].Timestamp.Equal(w) {
+			t.Errorf("entry %d: Timestamp = %v, want %v", i, entries[i].Timestamp, w)
+		}
+	}
+}
+
+func TestStrictTimestampsText(t *testing.T) {
+	p := New(WithStrictTimestamps("2006-01-02 15:04:05,000"), WithLocation(time.UTC))
+
+	entries, err := p.ParseString("2024-03-01 12:00:00,250 ERROR [main] com.example.App - boom")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ParseString() = %v, %v", entries, err)
+	}
+
+	if want := time.Date(2024, 3, 1, 12, 0, 0, 250_000_000, time.UTC); !entries[0].Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", entries[0].Timestamp, want)
+	}
+
+	// A syslog line matches its pattern, but not the strict layout
+	if _, err := p.ParseString("Mar  1 12:00:00 host app[1]: started"); !errors.Is(err, ErrStrictTimestamp) {
+		t.Errorf("syslog ParseString() error = %v, want ErrStrictTimestamp", err)
+	}
+}
+
+func TestAmbiguityCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		layouts   []string
+		ambiguous bool
+	}{
+		{"US and EU dates", []string{"01/02/2006", "02/01/2006"}, true},
+		{"US and EU with times", []string{time.RFC3339, "01/02/2006 15:04", "02/01/2006 15:04"}, true},
+		{"two-digit years", []string{"06-01-02", "02-01-06"}, true},
+		{"distinct shapes", []string{time.RFC3339, "2006-01-02 15:04:05", "Jan _2 15:04:05"}, false},
+		{"duplicates", []string{"01/02/2006", "01/02/2006"}, false},
+		{"one layout", []string{"02/01/2006"}, false},
+		{"none", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AmbiguityCheck(tt.layouts)
+			if errors.Is(err, ErrAmbiguousLayouts) != tt.ambiguous {
+				t.Errorf("AmbiguityCheck() = %v, want ambiguous %v", err, tt.ambiguous)
+			}
+		})
+	}
+
+	if _, err := NewE(WithStrictTimestamps("01/02/2006", "02/01/2006")); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("NewE() error = %v, want ErrInvalidOptions", err)
+	}
+}
@@ -0,0 +1,45 @@
+package logparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// structlogPairsRe splits a structlog console event from the key=value
+// pairs printed after it
+var structlogPairsRe = regexp.MustCompile(`^(.*?)\s+((?:[A-Za-z_][\w.-]*=\S+\s*)+)$`)
+
+// parseStructlogLine reads the timestamp of a structlog ConsoleRenderer
+// line, which may be ISO 8601 or "2006-01-02 15:04:05", and moves the
+// key=value pairs after the event into fields
+func parseStructlogLine(entry *LogEntry, matches []string, cfg *config) error {
+	start := cfg.timer.start()
+
+	if cfg.strictTime {
+		if t, err := cfg.parseStrictTime(matches[1]); err == nil {
+			entry.Timestamp = t
+		}
+	} else if t, err := parseTimestampIn(matches[1], cfg.timeLocation()); err == nil {
+		entry.Timestamp = t
+	}
+
+	cfg.timer.stop(phaseExtractTS, start, 1)
+
+	m := structlogPairsRe.FindStringSubmatch(entry.Message)
+	if m == nil {
+		return nil
+	}
+
+	pairs := decodeLogfmtFragment(m[2])
+	if pairs == nil {
+		return nil
+	}
+
+	entry.Message = strings.TrimSpace(m[1])
+
+	for k, v := range pairs {
+		cfg.setField(entry, k, v)
+	}
+
+	return nil
+}
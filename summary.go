@@ -0,0 +1,198 @@
+package logparser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SummaryOptions configures Summarize
+type SummaryOptions struct {
+	TopN          int      // Entries in each ranking (default 10)
+	ServiceFields []string // Fields naming the service, tried in order (default service, app, component)
+	Buckets       Buckets  // Buckets the busiest minute is taken from (default one minute in UTC)
+}
+
+// Summary is a triage overview of a set of entries. It marshals to JSON.
+type Summary struct {
+	Total              int            `json:"total"`
+	Start              time.Time      `json:"start"`
+	End                time.Time      `json:"end"`
+	Levels             map[string]int `json:"levels"`
+	TopMessages        []SummaryCount `json:"top_messages"`       // Most frequent message templates
+	TopErrorServices   []SummaryCount `json:"top_error_services"` // Services with the most ERROR/FATAL entries
+	BusiestMinute      time.Time      `json:"busiest_minute"`
+	BusiestMinuteCount int            `json:"busiest_minute_count"`
+}
+
+// SummaryCount is a ranked key in a Summary
+type SummaryCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// levelOrder is the display order of the standard levels
+var levelOrder = []string{"TRACE", "DEBUG", LevelInfo, "WARN", LevelError, "FATAL"}
+
+// Summarize builds a triage summary of entries: time span, counts per level,
+// the most frequent message templates, the services with the most errors,
+// and the busiest minute.
+func Summarize(entries []LogEntry, opts SummaryOptions) Summary {
+	if opts.TopN <= 0 {
+		opts.TopN = 10
+	}
+
+	if len(opts.ServiceFields) == 0 {
+		opts.ServiceFields = []string{"service", "app", "component"}
+	}
+
+	s := Summary{
+		Total:            len(entries),
+		Levels:           make(map[string]int),
+		TopMessages:      []SummaryCount{},
+		TopErrorServices: []SummaryCount{},
+	}
+
+	templates := make(map[string]int)
+	errorServices := make(map[string]int)
+	minutes := make(map[time.Time]int)
+
+	for i := range entries {
+		e := &entries[i]
+
+		s.Levels[e.Level]++
+		templates[MessageTemplate(e.Message)]++
+
+		if !e.Timestamp.IsZero() {
+			if s.Start.IsZero() || e.Timestamp.Before(s.Start) {
+				s.Start = e.Timestamp
+			}
+
+			if e.Timestamp.After(s.End) {
+				s.End = e.Timestamp
+			}
+
+			minutes[opts.Buckets.Floor(e.Timestamp)]++
+		}
+
+		if e.Level == LevelError || e.Level == "FATAL" {
+			if service := serviceOf(e, opts.ServiceFields); service != "" {
+				errorServices[service]++
+			}
+		}
+	}
+
+	s.TopMessages = topCounts(templates, opts.TopN)
+	s.TopErrorServices = topCounts(errorServices, opts.TopN)
+
+	for minute, n := range minutes {
+		if n > s.BusiestMinuteCount || (n == s.BusiestMinuteCount && minute.Before(s.BusiestMinute)) {
+			s.BusiestMinute, s.BusiestMinuteCount = minute, n
+		}
+	}
+
+	return s
+}
+
+// Span returns the time between the first and last entry
+func (s Summary) Span() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// String renders the summary as a plaintext report
+func (s Summary) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Entries:        %d\n", s.Total)
+
+	if !s.Start.IsZero() {
+		fmt.Fprintf(&b, "Time span:      %s - %s (%s)\n",
+			s.Start.Format(time.RFC3339), s.End.Format(time.RFC3339), s.Span())
+		fmt.Fprintf(&b, "Busiest minute: %s (%d entries)\n", s.BusiestMinute.Format(time.RFC3339), s.BusiestMinuteCount)
+	}
+
+	levels := make([]string, 0, len(s.Levels))
+	for _, level := range levelOrder {
+		if n := s.Levels[level]; n > 0 {
+			levels = append(levels, fmt.Sprintf("%s=%d", level, n))
+		}
+	}
+
+	var other []string
+
+	for level, n := range s.Levels {
+		if levelRank(level) < 0 {
+			other = append(other, fmt.Sprintf("%s=%d", level, n))
+		}
+	}
+
+	sort.Strings(other)
+
+	fmt.Fprintf(&b, "Levels:         %s\n", strings.Join(append(levels, other...), " "))
+
+	writeCounts(&b, "Top messages", s.TopMessages)
+	writeCounts(&b, "Top services by errors", s.TopErrorServices)
+
+	return b.String()
+}
+
+// levelRank returns the position of a standard level in severity order,
+// or -1 for unknown levels
+func levelRank(level string) int {
+	for i, l := range levelOrder {
+		if l == level {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// writeCounts appends a ranked section to a report
+func writeCounts(b *strings.Builder, title string, counts []SummaryCount) {
+	if len(counts) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n%s:\n", title)
+
+	for _, c := range counts {
+		fmt.Fprintf(b, "%8d  %s\n", c.Count, c.Key)
+	}
+}
+
+// serviceOf returns the first non-empty service field of an entry
+func serviceOf(e *LogEntry, keys []string) string {
+	for _, key := range keys {
+		if val, ok := entryField(e, key); ok {
+			if s := formatValue(val); s != "" {
+				return s
+			}
+		}
+	}
+
+	return ""
+}
+
+// topCounts returns the n largest counts, ties broken by key
+func topCounts(counts map[string]int, n int) []SummaryCount {
+	result := make([]SummaryCount, 0, len(counts))
+	for k, c := range counts {
+		result = append(result, SummaryCount{Key: k, Count: c})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+
+		return result[i].Key < result[j].Key
+	})
+
+	if len(result) > n {
+		result = result[:n]
+	}
+
+	return result
+}
@@ -0,0 +1,77 @@
+package logparser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	base := time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return base.Add(time.Duration(sec) * time.Second) }
+
+	entries := []LogEntry{
+		{Timestamp: at(0), Level: "INFO", Message: "request 1 handled"},
+		{Timestamp: at(10), Level: "INFO", Message: "request 2 handled"},
+		{Timestamp: at(70), Level: "ERROR", Message: "db timeout", Fields: map[string]interface{}{"service": "api"}},
+		{Timestamp: at(80), Level: "ERROR", Message: "db timeout", Fields: map[string]interface{}{"app": "worker"}},
+		{Timestamp: at(90), Level: "FATAL", Message: "crash", Fields: map[string]interface{}{"service": "api"}},
+		{Timestamp: at(200), Level: "WARN", Message: "request 3 handled", Fields: map[string]interface{}{"component": "api"}},
+	}
+
+	s := Summarize(entries, SummaryOptions{TopN: 2})
+
+	if s.Total != 6 || s.Start != at(0) || s.End != at(200) || s.Span() != 200*time.Second {
+		t.Errorf("unexpected totals %+v", s)
+	}
+
+	if s.Levels["INFO"] != 2 || s.Levels["ERROR"] != 2 || s.Levels["FATAL"] != 1 {
+		t.Errorf("Levels = %v", s.Levels)
+	}
+
+	if len(s.TopMessages) != 2 || s.TopMessages[0] != (SummaryCount{Key: "request <n> handled", Count: 3}) {
+		t.Errorf("TopMessages = %v", s.TopMessages)
+	}
+
+	wantServices := []SummaryCount{{Key: "api", Count: 2}, {Key: "worker", Count: 1}}
+	if len(s.TopErrorServices) != 2 || s.TopErrorServices[0] != wantServices[0] || s.TopErrorServices[1] != wantServices[1] {
+		t.Errorf("TopErrorServices = %v", s.TopErrorServices)
+	}
+
+	if s.BusiestMinute != base.Add(time.Minute) || s.BusiestMinuteCount != 3 {
+		t.Errorf("busiest minute = %v (%d)", s.BusiestMinute, s.BusiestMinuteCount)
+	}
+
+	report := s.String()
+	for _, want := range []string{"Entries:        6", "Levels:         INFO=2 WARN=1 ERROR=2 FATAL=1", "request <n> handled"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), `"top_error_services":[{"key":"api","count":2}`) {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
+
+func TestSummarizeServiceFields(t *testing.T) {
+	entries := []LogEntry{
+		{Level: "ERROR", Fields: map[string]interface{}{"service": "api", "k8s": map[string]interface{}{"pod": "api-7f"}}},
+	}
+
+	s := Summarize(entries, SummaryOptions{ServiceFields: []string{"k8s.pod"}})
+	if len(s.TopErrorServices) != 1 || s.TopErrorServices[0].Key != "api-7f" {
+		t.Errorf("TopErrorServices = %v", s.TopErrorServices)
+	}
+
+	empty := Summarize(nil, SummaryOptions{})
+	if empty.Total != 0 || !strings.HasPrefix(empty.String(), "Entries:        0") {
+		t.Errorf("unexpected empty summary %q", empty.String())
+	}
+}
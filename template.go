@@ -0,0 +1,209 @@
+package logparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Patterns used to replace variable tokens in messages
+var (
+	tmplQuotedRe   = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	tmplUUIDRe     = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	tmplHexRe      = regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]{6,}\b`)
+	tmplIPRe       = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`)
+	tmplPathRe     = regexp.MustCompile(`(^|[\s(=])(?:[A-Za-z]:\\|\.{0,2}/)[^\s:,;()]+`)
+	tmplDurationRe = regexp.MustCompile(`\b\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h)\b`)
+	tmplNumberRe   = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	tmplSpaceRe    = regexp.MustCompile(`\s+`)
+	tmplHashRe     = regexp.MustCompile(`^[0-9a-fA-F]{6,}$`)
+)
+
+// MessageTemplate returns the message with variable tokens replaced by placeholders:
+// quoted strings (<str>), UUIDs (<uuid>), IP addresses (<ip>), file paths
+// (<path>), durations (<duration>), hex identifiers (<hex>), and numbers
+// (<n>). Messages that differ only in those tokens share a template.
+func MessageTemplate(s string) string {
+	s = tmplQuotedRe.ReplaceAllString(s, "<str>")
+	s = tmplUUIDRe.ReplaceAllString(s, "<uuid>")
+	s = tmplIPRe.ReplaceAllString(s, "<ip>")
+	s = tmplPathRe.ReplaceAllString(s, "$1<path>")
+	s = tmplDurationRe.ReplaceAllString(s, "<duration>")
+	s = tmplHexRe.ReplaceAllStringFunc(s, func(tok string) string {
+		// Only treat mixed digit/letter runs as hashes, not plain words
+		if strings.HasPrefix(tok, "0x") || strings.HasPrefix(tok, "0X") ||
+			(strings.ContainsAny(tok, "0123456789") && strings.ContainsAny(tok, "abcdefABCDEF")) {
+			return "<hex>"
+		}
+
+		return tok
+	})
+	s = tmplNumberRe.ReplaceAllString(s, "<n>")
+
+	return strings.TrimSpace(tmplSpaceRe.ReplaceAllString(s, " "))
+}
+
+// WithTemplateInference stores the printf-style template of each message in
+// Fields["_template"] and its arguments in Fields["_args"], as returned by
+// InferTemplate. Messages without arguments get no fields.
+func WithTemplateInference(enable bool) Option {
+	return func(c *config) {
+		c.inferTemplates = enable
+	}
+}
+
+// inferTemplate attaches the message's template when inference is enabled
+func (c *config) inferTemplate(entry *LogEntry) {
+	if !c.inferTemplates {
+		return
+	}
+
+	tmpl, args := InferTemplate(entry.Message)
+	if len(args) == 0 {
+		return
+	}
+
+	c.setField(entry, "_template", tmpl)
+	c.setField(entry, "_args", args)
+}
+
+// InferTemplate reconstructs the fmt format string a message was likely
+// written with, such as "user %d not found in region %s" for "user 42 not
+// found in region eu-west-1", and returns it with the arguments. Whole
+// whitespace-separated tokens are classified: integers become %d (int64),
+// durations and floats %v (time.Duration, float64), 0x-prefixed hex %#x
+// (uint64), double-quoted strings %q, and IPs, UUIDs, hex hashes, and
+// identifiers that mix digits with separators, such as req-42, %s. Plain
+// words, including ones like utf8, stay in the template. A value is only
+// typed when formatting it gives back the same text, so fmt.Sprintf(template,
+// args...) always reproduces the message.
+func InferTemplate(message string) (template string, args []interface{}) {
+	var b strings.Builder
+
+	b.Grow(len(message))
+
+	for i := 0; i < len(message); {
+		if isTemplateSpace(message[i]) {
+			b.WriteByte(message[i])
+			i++
+
+			continue
+		}
+
+		end := templateTokenEnd(message, i)
+		args = appendTemplateToken(&b, message[i:end], args)
+		i = end
+	}
+
+	return b.String(), args
+}
+
+// isTemplateSpace reports whether c separates template tokens
+func isTemplateSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// templateTokenEnd returns the end of the token starting at i: a quoted
+// string up to its closing quote, or a run of non-space bytes that ends
+// after an '=' so a quoted value after a key is a token of its own
+func templateTokenEnd(s string, i int) int {
+	if q := s[i]; q == '"' || q == '\'' {
+		for j := i + 1; j < len(s); j++ {
+			switch {
+			case s[j] == '\\' && q == '"':
+				j++
+			case s[j] == q:
+				return j + 1
+			}
+		}
+	}
+
+	for j := i; j < len(s); j++ {
+		switch {
+		case isTemplateSpace(s[j]):
+			return j
+		case s[j] == '=':
+			return j + 1
+		}
+	}
+
+	return len(s)
+}
+
+// appendTemplateToken writes a token's template to b, with surrounding
+// punctuation kept literal, and appends its argument, if any
+func appendTemplateToken(b *strings.Builder, tok string, args []interface{}) []interface{} {
+	core := strings.TrimLeft(tok, "([{<")
+	lead := tok[:len(tok)-len(core)]
+
+	if core == "" || core[0] != '"' && core[0] != '\'' {
+		core = strings.TrimRight(core, ",.;:!?)]}>=%")
+	}
+
+	trail := tok[len(lead)+len(core):]
+
+	verb, arg := templateArg(core)
+	if verb == "" {
+		writeTemplateLiteral(b, tok)
+
+		return args
+	}
+
+	writeTemplateLiteral(b, lead)
+	b.WriteString(verb)
+	writeTemplateLiteral(b, trail)
+
+	return append(args, arg)
+}
+
+// writeTemplateLiteral writes s to b with '%' escaped
+func writeTemplateLiteral(b *strings.Builder, s string) {
+	b.WriteString(strings.ReplaceAll(s, "%", "%%"))
+}
+
+// templateArg classifies a token, returning its verb and argument, or an
+// empty verb for a literal word
+func templateArg(tok string) (string, interface{}) {
+	if len(tok) >= 2 && tok[0] == tok[len(tok)-1] && (tok[0] == '"' || tok[0] == '\'') {
+		if s, err := strconv.Unquote(tok); err == nil && tok[0] == '"' && strconv.Quote(s) == tok {
+			return "%q", s
+		}
+
+		return "%s", tok
+	}
+
+	if !strings.ContainsAny(tok, "0123456789") {
+		return "", nil
+	}
+
+	if n, err := strconv.ParseInt(tok, 10, 64); err == nil && strconv.FormatInt(n, 10) == tok {
+		return "%d", n
+	}
+
+	if d, err := time.ParseDuration(tok); err == nil && d.String() == tok {
+		return "%v", d
+	}
+
+	if f, err := strconv.ParseFloat(tok, 64); err == nil {
+		if fmt.Sprint(f) == tok {
+			return "%v", f
+		}
+
+		return "%s", tok // 007, 1e3
+	}
+
+	if strings.HasPrefix(tok, "0x") {
+		if n, err := strconv.ParseUint(tok[2:], 16, 64); err == nil && fmt.Sprintf("%#x", n) == tok {
+			return "%#x", n
+		}
+	}
+
+	// Values such as 5MB, eu-west-1, and 3f9a2c7e, but not words like utf8
+	if tok[0] >= '0' && tok[0] <= '9' || strings.ContainsAny(tok, "-_./:@") || tmplHashRe.MatchString(tok) {
+		return "%s", tok
+	}
+
+	return "", nil
+}
@@ -0,0 +1,78 @@
+package logparser
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInferTemplate(t *testing.T) {
+	tests := []struct {
+		message  string
+		template string
+		args     []interface{}
+	}{
+		{"user 42 not found in region eu-west-1", "user %d not found in region %s", []interface{}{int64(42), "eu-west-1"}},
+		{"request took 1.5s (limit 250ms)", "request took %v (limit %v)", []interface{}{1500 * time.Millisecond, 250 * time.Millisecond}},
+		{"cpu at 93.5%, load -2", "cpu at %v%%, load %d", []interface{}{93.5, int64(-2)}},
+		{`open "/tmp/a b.txt": permission denied`, "open %q: permission denied", []interface{}{"/tmp/a b.txt"}},
+		{`key=value msg="retry later" code=0x1f`, `key=value msg=%q code=%#x`, []interface{}{"retry later", uint64(31)}},
+		{
+			"conn from 10.0.0.7:5432 id 123e4567-e89b-12d3-a456-426614174000 hash 3f9a2c7e",
+			"conn from %s id %s hash %s",
+			[]interface{}{"10.0.0.7:5432", "123e4567-e89b-12d3-a456-426614174000", "3f9a2c7e"},
+		},
+		{"padded 007 and 1e3 and 5MB", "padded %s and %s and %s", []interface{}{"007", "1e3", "5MB"}},
+		{"utf8 decoder for IPv4 on x509", "utf8 decoder for IPv4 on x509", nil},
+		{"took 1m", "took %s", []interface{}{"1m"}},
+		{"", "", nil},
+	}
+
+	for _, tt := range tests {
+		template, args := InferTemplate(tt.message)
+		if template != tt.template || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("InferTemplate(%q)\n got %q %#v\nwant %q %#v", tt.message, template, args, tt.template, tt.args)
+		}
+	}
+}
+
+func TestWithTemplateInference(t *testing.T) {
+	entries, err := New(WithTemplateInference(true)).ParseString("level=info msg=\"user 7 logged in\"\nlevel=info msg=ready\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := entries[0].Fields["_template"]; got != "user %d logged in" {
+		t.Errorf("_template = %v", got)
+	}
+
+	if got := entries[0].Fields["_args"]; !reflect.DeepEqual(got, []interface{}{int64(7)}) {
+		t.Errorf("_args = %#v", got)
+	}
+
+	if _, ok := entries[1].Fields["_template"]; ok {
+		t.Error("a message without arguments got a template")
+	}
+}
+
+func FuzzInferTemplate(f *testing.F) {
+	for _, seed := range []string{
+		"user 42 not found in region eu-west-1",
+		`msg="quoted \"inner\" text" after='single' 0xdeadbeef 0X1F`,
+		"100% done in 1h2m3.5s, 3.0 vs 3 vs -0 vs +1",
+		"%d %s %% literal 5%",
+		"tabs\tand\nnewlines  (nested [42]) {x=\"y}",
+		"\"unterminated 12",
+		"bad utf8 \xff 12 \"\xfe\"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, message string) {
+		template, args := InferTemplate(message)
+		if got := fmt.Sprintf(template, args...); got != message {
+			t.Fatalf("Sprintf(%q, %#v) = %q, want %q", template, args, got, message)
+		}
+	})
+}
@@ -0,0 +1,5 @@
+module github.com/yildizm/go-logparser/testdata/logfmtgen
+
+go 1.22
+
+require github.com/go-logfmt/logfmt v0.6.1
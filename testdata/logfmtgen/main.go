@@ -0,0 +1,120 @@
+// Command logfmtgen records how go-logfmt decodes the logfmt conformance
+// corpus, so the parser's tests can check against it without depending on
+// go-logfmt. Run it from this directory after changing either corpus:
+//
+//	GOWORK=off go run . > ../golden/logfmt_conformance.json
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-logfmt/logfmt"
+)
+
+// conformance is the golden file's layout
+type conformance struct {
+	// Corpus holds lines go-logfmt accepts, from logfmt_corpus.log
+	Corpus []decoded `json:"corpus"`
+	// Equals holds lines with '=' in unquoted values, which go-logfmt
+	// rejects, from logfmt_equals.log. Pairs are go-logfmt's reading of
+	// the same line with those values quoted.
+	Equals []decoded `json:"equals"`
+}
+
+// decoded is one line and the pairs go-logfmt reads from it, keeping the
+// last value per key
+type decoded struct {
+	Line   string            `json:"line"`
+	Quoted string            `json:"quoted,omitempty"`
+	Pairs  map[string]string `json:"pairs"`
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "logfmtgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var out conformance
+
+	corpus, err := readLines("../logfmt_corpus.log")
+	if err != nil {
+		return err
+	}
+
+	for _, line := range corpus {
+		pairs, err := decode(line)
+		if err != nil {
+			return fmt.Errorf("go-logfmt rejects corpus line %q: %w", line, err)
+		}
+
+		out.Corpus = append(out.Corpus, decoded{Line: line, Pairs: pairs})
+	}
+
+	equals, err := readLines("../logfmt_equals.log")
+	if err != nil {
+		return err
+	}
+
+	for _, line := range equals {
+		raw, quoted, ok := strings.Cut(line, "\t")
+		if !ok {
+			return fmt.Errorf("corpus line %q has no tab", line)
+		}
+
+		if _, err := decode(raw); err == nil {
+			return fmt.Errorf("go-logfmt accepts %q; move it to logfmt_corpus.log", raw)
+		}
+
+		pairs, err := decode(quoted)
+		if err != nil {
+			return fmt.Errorf("go-logfmt rejects quoted line %q: %w", quoted, err)
+		}
+
+		out.Equals = append(out.Equals, decoded{Line: raw, Quoted: quoted, Pairs: pairs})
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(out)
+}
+
+// readLines returns the non-empty lines of a corpus file, without trimming
+// spaces
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, nil
+}
+
+// decode reads line with go-logfmt
+func decode(line string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	dec := logfmt.NewDecoder(strings.NewReader(line))
+
+	for dec.ScanRecord() {
+		for dec.ScanKeyval() {
+			pairs[string(dec.Key())] = string(dec.Value())
+		}
+	}
+
+	return pairs, dec.Err()
+}
@@ -2,8 +2,12 @@ package logparser
 
 import (
 	"regexp"
+	"regexp/syntax"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // textPattern represents a text log pattern
@@ -13,55 +17,82 @@ type textPattern struct {
 	tsIndex  int
 	lvlIndex int
 	msgIndex int
+	fields   map[string]int // Field name to capture group index
+	noYear   bool           // Timestamp layout lacks a year
+	post     textPostFunc   // Format-specific extraction after the generic fields
+	first    *[256]bool     // Bytes a match can start with; nil if any
+	folds    bool           // Following lines matching no pattern continue the message
 }
 
-// parseText parses plain text logs with common patterns
-func parseText(lines []string) ([]LogEntry, error) {
-	patterns := initTextPatterns()
-	entries := make([]LogEntry, 0, len(lines))
+// textPostFunc extracts format-specific details from a matched line. It may
+// return errContinuation to fold the entry into the previous one.
+type textPostFunc func(entry *LogEntry, matches []string, cfg *config) error
 
-	for _, line := range lines {
-		entry, err := parseTextLine(line, patterns)
-		if err != nil {
-			return nil, err
-		}
-
-		entries = append(entries, *entry)
+// parseTextLine parses a single text log line. It also returns the pattern
+// that matched; when prev folds, a line matching no pattern is returned with
+// errMessageContinuation and prev is kept.
+func parseTextLine(line string, patterns []*textPattern, prev *textPattern, cfg *config) (*LogEntry, *textPattern, error) {
+	stripped := false
+	if !cfg.keepANSI {
+		line, stripped = stripANSI(line)
 	}
 
-	return entries, nil
-}
-
-// parseTextLine parses a single text log line
-func parseTextLine(line string, patterns []*textPattern) (*LogEntry, error) {
 	line = strings.TrimSpace(line)
 	if line == "" {
-		return nil, ErrEmptyLine
+		return nil, nil, ErrEmptyLine
 	}
 
 	entry := &LogEntry{
-		Message: line,   // Default to full line
-		Level:   "INFO", // Default level
-		Fields:  make(map[string]interface{}),
+		Message: line, // Default to full line
+		Fields:  cfg.newFields(),
+	}
+
+	if stripped {
+		cfg.setField(entry, "_ansi", true)
 	}
 
+	var matched *textPattern
+
 	// Try each pattern
 	for _, pattern := range patterns {
+		if pattern.first != nil && !pattern.first[line[0]] {
+			continue
+		}
+
 		matches := pattern.regex.FindStringSubmatch(line)
 		if matches == nil {
 			continue
 		}
 
+		matched = pattern
+
 		// Extract timestamp
-		if pattern.tsIndex > 0 && pattern.tsIndex < len(matches) && pattern.tsFormat != "" {
-			if t, err := time.Parse(pattern.tsFormat, matches[pattern.tsIndex]); err == nil {
+		start := cfg.timer.start()
+
+		if pattern.tsIndex > 0 && pattern.tsIndex < len(matches) && cfg.strictTime {
+			if t, err := cfg.parseStrictTime(matches[pattern.tsIndex]); err == nil {
+				entry.Timestamp = t
+			}
+		} else if pattern.tsIndex > 0 && pattern.tsIndex < len(matches) && pattern.tsFormat != "" {
+			if t, err := parseLayoutIn(pattern.tsFormat, matches[pattern.tsIndex], cfg.timeLocation()); err == nil {
+				switch {
+				case pattern.noYear:
+					t = cfg.inferYear(t)
+				case !layoutHasZone(pattern.tsFormat):
+					t = wallClock(t, cfg.timeLocation())
+				}
+
 				entry.Timestamp = t
 			}
 		}
 
-		// Extract level
+		cfg.timer.stop(phaseExtractTS, start, 1)
+
+		// Extract level, ignoring words that are not a known level
 		if pattern.lvlIndex > 0 && pattern.lvlIndex < len(matches) {
-			entry.Level = ParseLevel(matches[pattern.lvlIndex])
+			if level, ok := lookupLevel(matches[pattern.lvlIndex]); ok {
+				entry.Level = level
+			}
 		}
 
 		// Extract message
@@ -69,17 +100,41 @@ func parseTextLine(line string, patterns []*textPattern) (*LogEntry, error) {
 			entry.Message = matches[pattern.msgIndex]
 		}
 
+		// Extract additional fields
+		for name, idx := range pattern.fields {
+			if idx > 0 && idx < len(matches) && matches[idx] != "" {
+				cfg.setField(entry, name, matches[idx])
+			}
+		}
+
+		if pattern.post != nil {
+			if err := pattern.post(entry, matches, cfg); err != nil {
+				return entry, pattern, err
+			}
+		}
+
 		break // Use first matching pattern
 	}
 
-	// If no timestamp found, use current time
-	if entry.Timestamp.IsZero() {
-		entry.Timestamp = time.Now()
+	if matched == nil && prev != nil && prev.folds {
+		return entry, prev, errMessageContinuation
+	}
+
+	if cfg.bracketFields {
+		cfg.extractBracketFields(entry)
 	}
 
-	return entry, nil
+	// Fields only hold what the allowlist and denylist let through
+	cfg.extractSemanticFields(entry.Fields, entry)
+	cfg.finishEntry(entry)
+
+	return entry, matched, nil
 }
 
+// builtinTextPatterns returns the built-in patterns, compiled once and
+// shared read-only between parse calls
+var builtinTextPatterns = sync.OnceValue(initTextPatterns)
+
 // initTextPatterns initializes common log patterns
 func initTextPatterns() []*textPattern {
 	patterns := []struct {
@@ -88,14 +143,180 @@ func initTextPatterns() []*textPattern {
 		tsIndex  int
 		lvlIndex int
 		msgIndex int
+		fields   map[string]int
+		noYear   bool
+		post     textPostFunc
+		folds    bool
 	}{
-		// Syslog format: Jan 02 15:04:05 hostname process[pid]: message
+		// Syslog format: Jan 02 15:04:05 hostname process[pid]: [LEVEL] message
 		{
-			pattern:  `^(\w{3}\s+\d{1,2}\s+\d{2}:\d{2}:\d{2})\s+\S+\s+\S+:\s+\[?(\w+)\]?\s+(.*)$`,
-			tsFormat: "Jan 02 15:04:05",
+			pattern: `^(\w{3}\s+\d{1,2}\s+\d{2}:\d{2}:\d{2})\s+(\S+)\s+([^\s\[:]+)(?:\[(\d+)\])?:\s+` +
+				`(?:\[?((?i:trace|debug|info|warning|warn|error|err|fatal))\]?:?\s+)?(.*)$`,
+			tsFormat: "Jan _2 15:04:05",
+			tsIndex:  1,
+			lvlIndex: 5,
+			msgIndex: 6,
+			fields:   map[string]int{"hostname": 2, "process": 3, "pid": 4},
+			noYear:   true,
+		},
+		// Android logcat (threadtime): 01-02 15:04:05.123  1234  5678 E Tag: message
+		{
+			pattern:  `^(\d{2}-\d{2}\s+\d{2}:\d{2}:\d{2}\.\d{3})\s+(\d+)\s+(\d+)\s+([VDIWEF])\s+(.*?)\s*:\s(.*)$`,
+			tsFormat: "01-02 15:04:05.000",
+			tsIndex:  1,
+			lvlIndex: 4,
+			msgIndex: 6,
+			fields:   map[string]int{"pid": 2, "tid": 3, "tag": 5},
+			noYear:   true,
+		},
+		// Kubernetes klog: E0102 15:04:05.123456   12345 controller.go:87] message
+		{
+			pattern:  `^([IWEF])(\d{4} \d{2}:\d{2}:\d{2}\.\d{6})\s+(\d+) ([^\s:\]]+:\d+)\] (.*)$`,
+			tsFormat: "0102 15:04:05.000000",
+			tsIndex:  2,
+			lvlIndex: 1,
+			msgIndex: 5,
+			fields:   map[string]int{"pid": 3, "caller": 4},
+			noYear:   true,
+			folds:    true,
+		},
+		// Log4j PatternLayout: 2006-01-02 15:04:05,000 LEVEL [thread] logger - message
+		{
+			pattern:  `^(\d{4}-\d{2}-\d{2}\s+\d{2}:\d{2}:\d{2},\d{3})\s+(\w+)\s+\[([^\]]*)\]\s+(\S+)\s+-\s(.*)$`,
+			tsFormat: "2006-01-02 15:04:05,000",
 			tsIndex:  1,
 			lvlIndex: LevelIndex,
-			msgIndex: MessageIndex,
+			msgIndex: 5,
+			fields:   map[string]int{"thread": 3, "logger": 4},
+		},
+		// Python logging basic format: 2006-01-02 15:04:05,000 - logger - LEVEL - message
+		{
+			pattern:  `^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2},\d{3}) - (\S+) - ([A-Z]+) - (.*)$`,
+			tsFormat: "2006-01-02 15:04:05,000",
+			tsIndex:  1,
+			lvlIndex: 3,
+			msgIndex: 4,
+			fields:   map[string]int{"logger": 2},
+		},
+		// nginx error log: 2006/01/02 15:04:05 [level] pid#tid: *cid message, client: ..., server: ...
+		{
+			pattern:  `^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[(\w+)\] (\d+)#(\d+): (?:\*(\d+) )?(.*)$`,
+			tsFormat: "2006/01/02 15:04:05",
+			tsIndex:  1,
+			lvlIndex: LevelIndex,
+			msgIndex: 6,
+			fields:   map[string]int{"pid": 3, "tid": 4, "connection": 5},
+			post:     splitNginxContext,
+		},
+		// PostgreSQL: 2006-01-02 15:04:05.000 UTC [pid] user@db SQLSTATE LEVEL:  message
+		{
+			pattern: `^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)? (?:[A-Z]{2,5}|[+-]\d{2}(?:\d{2})?)) \[(\d+)\](?:-\d+)?` +
+				`(?: (\w*)@(\w*))?(?: ([0-9A-Z]{5}))? ([A-Z]+\d?):\s+(.*)$`,
+			tsFormat: "2006-01-02 15:04:05 MST",
+			tsIndex:  1,
+			lvlIndex: 6,
+			msgIndex: 7,
+			fields:   map[string]int{"pid": 2, "user": 3, "database": 4, "sqlstate": 5},
+			post:     foldPostgresContinuation,
+		},
+		// MySQL 8: 2006-01-02T15:04:05.000000Z thread [Level] [MY-000000] [Subsystem] message
+		{
+			pattern:  `^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?Z) (\d+) \[(\w+)\] \[(MY-\d+)\] \[(\w+)\] (.*)$`,
+			tsFormat: time.RFC3339Nano,
+			tsIndex:  1,
+			lvlIndex: 3,
+			msgIndex: 6,
+			fields:   map[string]int{"thread": 2, "code": 4, "subsystem": 5},
+		},
+		// Elasticsearch: [2006-01-02T15:04:05,000][LEVEL][logger] [node] message
+		{
+			pattern:  `^\[(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2},\d{3})\]\[(\w+)\s*\]\[([^\]]*?)\s*\] \[([^\]]*)\] (.*)$`,
+			tsFormat: "2006-01-02T15:04:05,000",
+			tsIndex:  1,
+			lvlIndex: 2,
+			msgIndex: 5,
+			fields:   map[string]int{"logger": 3, "node": 4},
+			folds:    true,
+		},
+		// Redis: pid:role 02 Jan 2006 15:04:05.000 glyph message
+		{
+			pattern:  `^(\d+):([MSCX]) (\d{2} \w{3} \d{4} \d{2}:\d{2}:\d{2}\.\d{3}) ([.*#-]) (.*)$`,
+			tsFormat: "02 Jan 2006 15:04:05.000",
+			tsIndex:  3,
+			msgIndex: 5,
+			fields:   map[string]int{"pid": 1},
+			post:     parseRedisLine,
+		},
+		// Ruby Logger (Rails): I, [2006-01-02T15:04:05.000000 #1234]  INFO -- progname: message
+		{
+			pattern:  `^[DIWEFAU], \[(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{6}) #(\d+)\]\s+(\w+) -- ([^:]*): (.*)$`,
+			tsFormat: "2006-01-02T15:04:05.000000",
+			tsIndex:  1,
+			lvlIndex: 3,
+			msgIndex: 5,
+			fields:   map[string]int{"pid": 2, "progname": 4},
+		},
+		// Heroku log drain: 2006-01-02T15:04:05.000000+00:00 source[dyno]: message
+		{
+			pattern:  `^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})) (\w+)\[([\w.-]+)\]: (.*)$`,
+			tsFormat: time.RFC3339Nano,
+			tsIndex:  1,
+			msgIndex: 4,
+			fields:   map[string]int{"source": 2, "dyno": 3},
+			post:     parseHerokuRouterLine,
+		},
+		// AWS Lambda application output (Node.js, Java): 2006-01-02T15:04:05.000Z\trequest-id\tLEVEL\tmessage
+		{
+			pattern:  `^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}Z)\t(\S+)\t(\w+)\t(.*)$`,
+			tsFormat: time.RFC3339Nano,
+			tsIndex:  1,
+			lvlIndex: 3,
+			msgIndex: 4,
+			fields:   map[string]int{"request_id": 2},
+		},
+		// AWS Lambda application output (Python): [LEVEL]\t2006-01-02T15:04:05.000Z\trequest-id\tmessage
+		{
+			pattern:  `^\[(\w+)\]\t(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}Z)\t(\S+)\t(.*)$`,
+			tsFormat: time.RFC3339Nano,
+			tsIndex:  2,
+			lvlIndex: 1,
+			msgIndex: 4,
+			fields:   map[string]int{"request_id": 3},
+		},
+		// AWS Lambda platform lines: START/END RequestId: id [Version: $LATEST]
+		{
+			pattern: `^(START|END) RequestId: (\S+)(?: Version: (\S+))?$`,
+			fields:  map[string]int{"lambda_event": 1, "request_id": 2, "version": 3},
+		},
+		// AWS Lambda REPORT RequestId: id\tDuration: 12.3 ms\tBilled Duration: 13 ms ...
+		{
+			pattern: `^(REPORT) RequestId: (\S+)\s+(.*)$`,
+			fields:  map[string]int{"lambda_event": 1, "request_id": 2},
+			post:    parseLambdaReport,
+		},
+		// JVM unified GC logging: [2006-01-02T15:04:05.000+0000][info][gc] GC(42) Pause Young ... 12.345ms
+		{
+			pattern: `^((?:\[[^\]]*\])*?)\[gc[\w,]*\s*\]\s*GC\((\d+)\)\s+(.*)$`,
+			post:    parseJVMUnifiedGC,
+		},
+		// JVM -XX:+PrintGCDetails: 2006-01-02T15:04:05.000+0000: 1.234: [GC (Allocation Failure) ... 0.0123 secs]
+		{
+			pattern: `^(?:(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}[+-]\d{4}): )?(?:[\d.]+: )?` +
+				`\[(Full GC|GC) \(([^)]*)\)\s*(.*), ([\d.]+) secs\]`,
+			post: parseJVMLegacyGC,
+		},
+		// Go gctrace: gc 42 @12.345s 1%: 0.021+1.2+0.030 ms clock, 0.17+0.35/1.1/2.5+0.24 ms cpu, 4->4->2 MB, 5 MB goal, 8 P
+		{
+			pattern: `^gc (\d+) @([\d.]+)s (\d+)%: ([\d.]+)\+([\d.]+)\+([\d.]+) ms clock, ` +
+				`([\d.]+)\+([\d.]+)/([\d.]+)/([\d.]+)\+([\d.]+) ms cpu, (\d+)->(\d+)->(\d+) MB, (\d+) MB goal(.*)$`,
+			post: parseGoGCTrace,
+		},
+		// structlog ConsoleRenderer: 2006-01-02 15:04:05 [info     ] event   key=value
+		{
+			pattern:  `^(\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}(?:\.\d+)?Z?) \[(\w+) +\] (.*)$`,
+			lvlIndex: 2,
+			msgIndex: 3,
+			post:     parseStructlogLine,
 		},
 		// Common format: 2006-01-02 15:04:05 [LEVEL] message
 		{
@@ -137,8 +358,109 @@ func initTextPatterns() []*textPattern {
 			tsIndex:  pt.tsIndex,
 			lvlIndex: pt.lvlIndex,
 			msgIndex: pt.msgIndex,
+			fields:   pt.fields,
+			noYear:   pt.noYear,
+			post:     pt.post,
+			first:    firstBytes(pt.pattern),
+			folds:    pt.folds,
 		})
 	}
 
 	return textPatterns
 }
+
+// firstBytes returns the set of bytes that a match of an anchored pattern
+// can start with, so lines that cannot match are rejected without running
+// the regexp. It returns nil when any byte could start a match.
+func firstBytes(pattern string) *[256]bool {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+
+	var set [256]bool
+
+	if firstBytesOf(re.Simplify(), &set) {
+		return nil // The pattern can match without consuming a byte
+	}
+
+	return &set
+}
+
+// firstBytesOf adds the bytes re can start with to set and reports whether
+// re can match the empty string. Non-ASCII runes add every UTF-8 lead byte.
+func firstBytesOf(re *syntax.Regexp, set *[256]bool) bool {
+	addRange := func(lo, hi rune) {
+		for r := lo; r <= hi && r < utf8.RuneSelf; r++ {
+			set[r] = true
+		}
+
+		if hi >= utf8.RuneSelf {
+			for b := utf8.RuneSelf; b < len(set); b++ {
+				set[b] = true
+			}
+		}
+	}
+
+	switch re.Op {
+	case syntax.OpLiteral:
+		if len(re.Rune) == 0 {
+			return true
+		}
+
+		r := re.Rune[0]
+		addRange(r, r)
+
+		if re.Flags&syntax.FoldCase != 0 {
+			for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+				addRange(f, f)
+			}
+		}
+
+		return false
+	case syntax.OpCharClass:
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			addRange(re.Rune[i], re.Rune[i+1])
+		}
+
+		return false
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		addRange(0, unicode.MaxRune)
+
+		return false
+	case syntax.OpCapture, syntax.OpPlus:
+		return firstBytesOf(re.Sub[0], set)
+	case syntax.OpStar, syntax.OpQuest:
+		firstBytesOf(re.Sub[0], set)
+
+		return true
+	case syntax.OpRepeat:
+		return firstBytesOf(re.Sub[0], set) || re.Min == 0
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !firstBytesOf(sub, set) {
+				return false
+			}
+		}
+
+		return true
+	case syntax.OpAlternate:
+		nullable := false
+
+		for _, sub := range re.Sub {
+			if firstBytesOf(sub, set) {
+				nullable = true
+			}
+		}
+
+		return nullable
+	case syntax.OpBeginText, syntax.OpBeginLine, syntax.OpEmptyMatch:
+		return true
+	default:
+		// End anchors and word boundaries may let a match end or need
+		// context before it starts; give up on filtering
+		addRange(0, unicode.MaxRune)
+
+		return true
+	}
+}
@@ -0,0 +1,181 @@
+package logparser
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ANSI color codes used for levels
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiGray   = "\x1b[90m"
+)
+
+// Formatter controls how WriteText renders entries
+type Formatter struct {
+	TimeLayout      string   // Timestamp layout (default "2006-01-02 15:04:05")
+	Columns         []string // Fields shown as aligned columns before the message
+	Fields          []string // Fields inlined after the message; nil inlines all but the columns, empty inlines none
+	Color           bool     // Color the level with ANSI escape codes
+	MaxMessageWidth int      // Truncate longer messages with "…"; 0 disables truncation
+	Summarize       bool     // Shorten messages to MaxMessageWidth with LogEntry.Summary rather than a plain cut
+}
+
+// WriteText writes entries as aligned, human-readable lines:
+//
+//	2024-01-02 15:04:05  ERROR  api  Database connection failed  (retry=2)
+//
+// Column widths are computed across all entries. Dotted field names select
+// values inside nested objects.
+func WriteText(w io.Writer, entries []LogEntry, f Formatter) error {
+	if f.TimeLayout == "" {
+		f.TimeLayout = "2006-01-02 15:04:05"
+	}
+
+	widths := make([]int, len(f.Columns))
+
+	for i := range entries {
+		for j, column := range f.Columns {
+			widths[j] = max(widths[j], utf8.RuneCountInString(textFieldValue(&entries[i], column)))
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+
+	var line strings.Builder
+
+	for i := range entries {
+		line.Reset()
+		f.formatEntry(&line, &entries[i], widths)
+
+		if _, err := bw.WriteString(line.String()); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// formatEntry renders one entry, including the trailing newline
+func (f *Formatter) formatEntry(b *strings.Builder, e *LogEntry, widths []int) {
+	if e.Timestamp.IsZero() {
+		b.WriteString(strings.Repeat(" ", utf8.RuneCountInString(time.Unix(0, 0).UTC().Format(f.TimeLayout))))
+	} else {
+		b.WriteString(e.Timestamp.Format(f.TimeLayout))
+	}
+
+	b.WriteString("  ")
+
+	level := e.Level
+	if f.Color {
+		if color := levelColor(level); color != "" {
+			level = color + level + ansiReset
+		}
+	}
+
+	b.WriteString(level)
+	b.WriteString(strings.Repeat(" ", max(0, 5-len(e.Level))))
+
+	for j, column := range f.Columns {
+		val := textFieldValue(e, column)
+
+		b.WriteString("  ")
+		b.WriteString(val)
+		b.WriteString(strings.Repeat(" ", widths[j]-utf8.RuneCountInString(val)))
+	}
+
+	b.WriteString("  ")
+	if f.Summarize && f.MaxMessageWidth > 0 {
+		b.WriteString(e.Summary(f.MaxMessageWidth))
+	} else {
+		b.WriteString(f.truncate(strings.ReplaceAll(e.Message, "\n", `\n`)))
+	}
+
+	if inline := f.inlineFields(e); inline != "" {
+		b.WriteString("  (")
+		b.WriteString(inline)
+		b.WriteString(")")
+	}
+
+	b.WriteString("\n")
+}
+
+// truncate shortens msg to MaxMessageWidth runes, marking the cut with "…"
+func (f *Formatter) truncate(msg string) string {
+	if f.MaxMessageWidth <= 0 || utf8.RuneCountInString(msg) <= f.MaxMessageWidth {
+		return msg
+	}
+
+	runes := []rune(msg)
+
+	return string(runes[:max(0, f.MaxMessageWidth-1)]) + "…"
+}
+
+// inlineFields renders the inlined fields as space-separated key=value pairs
+func (f *Formatter) inlineFields(e *LogEntry) string {
+	keys := f.Fields
+	if keys == nil {
+		keys = make([]string, 0, len(e.Fields))
+
+		for k := range e.Fields {
+			if !strings.HasPrefix(k, "_") && !slices.Contains(f.Columns, k) {
+				keys = append(keys, k)
+			}
+		}
+
+		sort.Strings(keys)
+	}
+
+	pairs := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		val, ok := entryField(e, k)
+		if !ok {
+			continue
+		}
+
+		s := formatValue(val)
+		if s == "" || strings.ContainsAny(s, " =\"\n") {
+			s = strconv.Quote(s)
+		}
+
+		pairs = append(pairs, k+"="+s)
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// textFieldValue returns a column value for WriteText
+func textFieldValue(e *LogEntry, key string) string {
+	val, ok := entryField(e, key)
+	if !ok {
+		return ""
+	}
+
+	return formatValue(val)
+}
+
+// levelColor returns the ANSI color for a level, or "" for none
+func levelColor(level string) string {
+	switch level {
+	case LevelError, "FATAL":
+		return ansiRed
+	case "WARN":
+		return ansiYellow
+	case LevelInfo:
+		return ansiGreen
+	case "DEBUG", "TRACE":
+		return ansiGray
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,63 @@
+package logparser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteText(t *testing.T) {
+	entries, err := ParseFile(New(), "testdata/export.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := WriteText(&buf, entries, Formatter{Columns: []string{"service"}}); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	checkGolden(t, "export.txt", buf.Bytes())
+}
+
+func TestWriteTextOptions(t *testing.T) {
+	entries := []LogEntry{{
+		Timestamp: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Level:     "ERROR",
+		Message:   "Database connection failed after several attempts",
+		Fields:    map[string]interface{}{"service": "api", "retry": 2.0, "host": "db 1"},
+	}}
+
+	var buf bytes.Buffer
+
+	f := Formatter{
+		TimeLayout:      "15:04:05",
+		Columns:         []string{"service"},
+		Fields:          []string{"retry", "host"},
+		MaxMessageWidth: 26,
+	}
+
+	if err := WriteText(&buf, entries, f); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	want := "15:04:05  ERROR  api  Database connection faile…  (retry=2 host=\"db 1\")\n"
+	if buf.String() != want {
+		t.Errorf("WriteText() = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+
+	f.Color = true
+	f.Fields = []string{}
+
+	if err := WriteText(&buf, entries, f); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "  \x1b[31mERROR\x1b[0m  api  ") || strings.Contains(buf.String(), "(") {
+		t.Errorf("unexpected colored output %q", buf.String())
+	}
+}
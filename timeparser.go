@@ -0,0 +1,70 @@
+package logparser
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTimestampDeclined is returned by a TimestampParser for a value it does
+// not handle, passing it on to the next parser and finally to the
+// built-in formats
+var ErrTimestampDeclined = errors.New("timestamp declined")
+
+// TimestampParser parses timestamp values in formats the parser does not
+// know, such as ordinal dates or spreadsheet serial numbers. Values are
+// strings, numbers (float64, json.Number, int64), or whatever a transform
+// stored. See the timeparsers package for implementations.
+type TimestampParser interface {
+	// Parse returns the time value stands for. It returns an error
+	// wrapping ErrTimestampDeclined to leave value to the parsers after
+	// it, and any other error to reject value as a timestamp.
+	Parse(value interface{}) (time.Time, error)
+}
+
+// TimestampParserFunc adapts a function to the TimestampParser interface
+type TimestampParserFunc func(value interface{}) (time.Time, error)
+
+// Parse calls f(value)
+func (f TimestampParserFunc) Parse(value interface{}) (time.Time, error) {
+	return f(value)
+}
+
+// WithTimestampParser consults p for the values of timestamp keys in JSON
+// and logfmt lines and for timestamp captures of grok patterns, before the
+// built-in formats. It may be given several times; parsers are tried in
+// order until one accepts or rejects the value, and the built-in formats
+// parse what all of them decline. Text lines matched by a built-in pattern
+// keep that pattern's layout. A returned time in year 0 has its year
+// inferred, as for syslog timestamps.
+func WithTimestampParser(p TimestampParser) Option {
+	return func(c *config) {
+		c.timeParsers = append(c.timeParsers, p)
+	}
+}
+
+// parseTime parses a timestamp value with the registered parsers, then
+// the built-in formats, or with the strict layouts alone
+func (c *config) parseTime(val interface{}) (time.Time, error) {
+	if c.strictTime {
+		return c.parseStrictTime(val)
+	}
+
+	if t, err := c.customTime(val); !errors.Is(err, ErrTimestampDeclined) {
+		return t, err
+	}
+
+	return parseTimestampIn(val, c.timeLocation())
+}
+
+// customTime parses a timestamp value with the registered parsers,
+// returning ErrTimestampDeclined when none of them handles it
+func (c *config) customTime(val interface{}) (time.Time, error) {
+	for _, p := range c.timeParsers {
+		t, err := p.Parse(val)
+		if !errors.Is(err, ErrTimestampDeclined) {
+			return t, err
+		}
+	}
+
+	return time.Time{}, ErrTimestampDeclined
+}
@@ -0,0 +1,116 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stardate parses "SD 1234.5" values as that many hours after 2300-01-01
+var stardate = TimestampParserFunc(func(value interface{}) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "SD ") {
+		return time.Time{}, ErrTimestampDeclined
+	}
+
+	var hours float64
+	if _, err := fmt.Sscanf(s, "SD %g", &hours); err != nil {
+		return time.Time{}, fmt.Errorf("bad stardate %q: %w", s, err)
+	}
+
+	return time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(hours * float64(time.Hour))), nil
+})
+
+func TestWithTimestampParser(t *testing.T) {
+	fixed := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	override := TimestampParserFunc(func(interface{}) (time.Time, error) { return fixed, nil })
+	declined := 0
+	counting := TimestampParserFunc(func(interface{}) (time.Time, error) {
+		declined++
+
+		return time.Time{}, fmt.Errorf("not mine: %w", ErrTimestampDeclined)
+	})
+
+	tests := []struct {
+		name    string
+		parsers []TimestampParser
+		line    string
+		want    time.Time
+	}{
+		{"custom format", []TimestampParser{stardate}, `{"time":"SD 36","msg":"m"}`, time.Date(2300, 1, 2, 12, 0, 0, 0, time.UTC)},
+		{"custom takes precedence", []TimestampParser{override}, `{"time":"2024-05-01T10:00:00Z","msg":"m"}`, fixed},
+		{"fallback on decline", []TimestampParser{stardate}, `{"time":"2024-05-01T10:00:00Z","msg":"m"}`,
+			time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{"wrapped decline", []TimestampParser{counting}, "time=1714557600 msg=m", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{"first accepting parser wins", []TimestampParser{counting, stardate, override}, `{"ts":"SD 0","msg":"m"}`,
+			time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := make([]Option, 0, len(tt.parsers))
+			for _, p := range tt.parsers {
+				opts = append(opts, WithTimestampParser(p))
+			}
+
+			entries, err := New(opts...).ParseString(tt.line)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			if got := entries[0].Timestamp; !got.Equal(tt.want) {
+				t.Errorf("Timestamp = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if declined != 2 {
+		t.Errorf("declining parser called %d times, want 2", declined)
+	}
+}
+
+func TestWithTimestampParserRejects(t *testing.T) {
+	// A parser error other than ErrTimestampDeclined skips the built-ins, so
+	// the key is not taken as the timestamp
+	before := time.Now()
+
+	entries, err := New(WithTimestampParser(stardate)).ParseString(`{"time":"SD warp","msg":"m"}`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if got := entries[0].Timestamp; got.Before(before) {
+		t.Errorf("Timestamp = %v, want parse time", got)
+	}
+
+	if _, err := stardate.Parse("SD warp"); err == nil || errors.Is(err, ErrTimestampDeclined) {
+		t.Errorf("Parse() error = %v, want rejection", err)
+	}
+}
+
+func TestWithTimestampParserGrok(t *testing.T) {
+	pattern, err := CompileGrok(`\[%{DATA:timestamp}\] %{GREEDYDATA:message}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewWithFormat(FormatText, WithTextPatterns(pattern), WithTimestampParser(stardate))
+
+	entries, err := p.ParseString("[SD 1.5] engaged\n[2024-05-01 10:00:00] docked")
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	want := []time.Time{
+		time.Date(2300, 1, 1, 1, 30, 0, 0, time.UTC),
+		time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	for i, w := range want {
+		if got := entries[i].Timestamp; !got.Equal(w) {
+			t.Errorf("entries[%d].Timestamp = %v, want %v", i, got, w)
+		}
+	}
+}
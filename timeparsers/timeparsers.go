@@ -0,0 +1,134 @@
+// Package timeparsers holds logparser.TimestampParser implementations for
+// timestamp formats too rare to parse by default.
+//
+// Register them with logparser.WithTimestampParser. Each declines values it
+// does not recognize, so the built-in formats still parse standard
+// timestamps:
+//
+//	p := logparser.New(logparser.WithTimestampParser(timeparsers.ExcelSerial{}))
+package timeparsers
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	logparser "github.com/yildizm/go-logparser"
+)
+
+// ordinalLayouts are the ISO 8601 ordinal date forms OrdinalDate accepts
+var ordinalLayouts = []string{
+	"2006-002T15:04:05Z07:00",
+	"2006-002T15:04:05",
+	"2006-002 15:04:05",
+	"2006-002",
+}
+
+// OrdinalDate parses ISO 8601 ordinal dates, a year and a day of the year
+// such as "2024-123" or "2024-123T10:15:30Z", as written by mainframe and
+// scientific software. Fractional seconds are accepted.
+type OrdinalDate struct {
+	// Location is used for times without a zone offset; nil means UTC
+	Location *time.Location
+}
+
+// Parse parses an ordinal date string, declining other values
+func (p OrdinalDate) Parse(value interface{}) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok || len(s) < len("2006-002") || s[4] != '-' {
+		return time.Time{}, logparser.ErrTimestampDeclined
+	}
+
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	for _, layout := range ordinalLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, logparser.ErrTimestampDeclined
+}
+
+// Limits of Excel serial dates: 1900-01-01 and 9999-12-31
+const (
+	excelMinSerial = 1
+	excelMaxSerial = 2958465
+)
+
+// Excel epochs. Serial dates count from 1899-12-30 rather than 1900-01-01
+// because Excel treats 1900 as a leap year; starting a day early puts every
+// serial from March 1900 on the right date.
+var (
+	excelEpoch1900 = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	excelEpoch1904 = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// ExcelSerial parses spreadsheet serial dates, the days since the Excel
+// epoch with the time of day as a fraction, such as 45413.5 for noon on
+// 2024-05-01. Numbers and numeric strings between 1 and 2958465 are
+// accepted; others, including Unix epochs, are declined. Times are rounded
+// to the millisecond.
+type ExcelSerial struct {
+	// Location is the zone of the wall-clock time the serial stands for;
+	// nil means UTC
+	Location *time.Location
+	// Date1904 counts from 1904-01-01, as workbooks created on old Macs do
+	Date1904 bool
+}
+
+// Parse parses a serial date, declining values that are not one
+func (p ExcelSerial) Parse(value interface{}) (time.Time, error) {
+	serial, ok := excelNumber(value)
+	if !ok || math.IsNaN(serial) || serial < excelMinSerial || serial > excelMaxSerial {
+		return time.Time{}, logparser.ErrTimestampDeclined
+	}
+
+	epoch := excelEpoch1900
+	if p.Date1904 {
+		epoch = excelEpoch1904
+	}
+
+	days := math.Floor(serial)
+	ms := math.Round((serial - days) * float64(24*time.Hour/time.Millisecond))
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(ms) * time.Millisecond)
+
+	if p.Location == nil {
+		return t, nil
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), p.Location), nil
+}
+
+// excelNumber returns a numeric value as a float64
+func excelNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// Interface checks
+var (
+	_ logparser.TimestampParser = OrdinalDate{}
+	_ logparser.TimestampParser = ExcelSerial{}
+)
@@ -0,0 +1,127 @@
+package timeparsers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	logparser "github.com/yildizm/go-logparser"
+)
+
+func TestOrdinalDate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+
+	tests := []struct {
+		name  string
+		p     OrdinalDate
+		value interface{}
+		want  time.Time
+	}{
+		{"date", OrdinalDate{}, "2024-123", time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{"leap day", OrdinalDate{}, "2024-060", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"time with zone", OrdinalDate{}, "2023-365T23:59:59+02:00", time.Date(2023, 12, 31, 21, 59, 59, 0, time.UTC)},
+		{"fractional seconds", OrdinalDate{}, "2024-001T00:00:01.25Z", time.Date(2024, 1, 1, 0, 0, 1, 25e7, time.UTC)},
+		{"space separated", OrdinalDate{}, "2024-032 08:00:00", time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC)},
+		{"location", OrdinalDate{Location: berlin}, "2024-183T12:00:00", time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.p.Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrdinalDateDeclines(t *testing.T) {
+	for _, v := range []interface{}{"2024-05-01", "2023-366", "2024-000", "24-123", "", 2024123.0, nil} {
+		if _, err := (OrdinalDate{}).Parse(v); !errors.Is(err, logparser.ErrTimestampDeclined) {
+			t.Errorf("Parse(%#v) error = %v, want ErrTimestampDeclined", v, err)
+		}
+	}
+}
+
+func TestExcelSerial(t *testing.T) {
+	tests := []struct {
+		name  string
+		p     ExcelSerial
+		value interface{}
+		want  time.Time
+	}{
+		{"date", ExcelSerial{}, 45413.0, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"noon", ExcelSerial{}, 45413.5, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"rounded to milliseconds", ExcelSerial{}, 45413.000011574, time.Date(2024, 5, 1, 0, 0, 1, 0, time.UTC)},
+		{"first day", ExcelSerial{}, 1.0, time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"after phantom leap day", ExcelSerial{}, 61.0, time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"1904 system", ExcelSerial{Date1904: true}, 43951.0, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"string", ExcelSerial{}, "45413.25", time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)},
+		{"json number", ExcelSerial{}, json.Number("45413"), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"int64", ExcelSerial{}, int64(45413), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"location", ExcelSerial{Location: time.FixedZone("EST", -5*3600)}, 45413.5, time.Date(2024, 5, 1, 17, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.p.Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcelSerialDeclines(t *testing.T) {
+	for _, v := range []interface{}{1714557600.0, 0.5, -3.0, "today", "NaN", "2024-05-01", true, nil} {
+		if _, err := (ExcelSerial{}).Parse(v); !errors.Is(err, logparser.ErrTimestampDeclined) {
+			t.Errorf("Parse(%#v) error = %v, want ErrTimestampDeclined", v, err)
+		}
+	}
+}
+
+func TestParsersWithLogparser(t *testing.T) {
+	p := logparser.New(
+		logparser.WithTimestampParser(OrdinalDate{}),
+		logparser.WithTimestampParser(ExcelSerial{}),
+	)
+
+	input := `{"time":"2024-123T06:00:00Z","msg":"ordinal"}
+{"time":45413.75,"msg":"serial"}
+{"time":"2024-05-01T10:00:00Z","msg":"rfc3339"}
+{"time":1714557600,"msg":"epoch"}`
+
+	entries, err := p.ParseString(input)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	want := []time.Time{
+		time.Date(2024, 5, 2, 6, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for i, w := range want {
+		if got := entries[i].Timestamp; !got.Equal(w) {
+			t.Errorf("%s: Timestamp = %v, want %v", entries[i].Message, got, w)
+		}
+	}
+}
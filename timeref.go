@@ -0,0 +1,152 @@
+package logparser
+
+import (
+	"strings"
+	"time"
+)
+
+// yearInferenceGrace is how far past the reference time a timestamp without
+// a year may fall before the previous year is chosen, absorbing clock skew
+// between the logging host and the reader
+const yearInferenceGrace = time.Hour
+
+// WithReferenceTime anchors year inference for timestamps that lack a year,
+// such as syslog's "Jan  2 15:04:05". Each is given the year that puts it
+// closest to, but not after, the reference time, so a December line read in
+// January lands in the previous year. ParseFile uses the file's
+// modification time when no reference is set; other inputs use the current
+// time.
+func WithReferenceTime(t time.Time) Option {
+	return func(c *config) {
+		c.referenceTime = t
+	}
+}
+
+// WithLocation interprets timestamps without a zone offset (syslog,
+// "2006-01-02 15:04:05", and similar) as wall-clock time in loc rather than
+// UTC. Times in the hour skipped by a daylight saving change are moved
+// forward by the change, and times in a repeated hour resolve to one of
+// its two instants, so neither fails to parse. Zone abbreviations, as in
+// PostgreSQL's "2006-01-02 15:04:05 CET", take their offset from loc.
+func WithLocation(loc *time.Location) Option {
+	return func(c *config) {
+		c.location = loc
+	}
+}
+
+// WithNormalizeUTC converts every parsed timestamp to UTC, so entries from
+// sources logging in different zones print alike. Only the presentation
+// changes: timestamps with an offset already compare by instant.
+func WithNormalizeUTC(normalize bool) Option {
+	return func(c *config) {
+		c.normalizeUTC = normalize
+	}
+}
+
+// timeLocation returns the location for timestamps without a zone
+func (c *config) timeLocation() *time.Location {
+	if c.location != nil {
+		return c.location
+	}
+
+	return time.UTC
+}
+
+// inferYear gives t, parsed from a layout without a year, the year that
+// places it closest to but not after the reference time. Only the date and
+// clock fields of t are used; they are read as wall-clock time in the
+// configured location.
+func (c *config) inferYear(t time.Time) time.Time {
+	ref := c.referenceTime
+	if ref.IsZero() {
+		ref = time.Now()
+	}
+
+	loc := c.timeLocation()
+	ref = ref.In(loc)
+	limit := ref.Add(yearInferenceGrace)
+
+	var candidate time.Time
+
+	// Feb 29 only exists in leap years, so look back until the date is real
+	for year := ref.Year(); year > ref.Year()-8; year-- {
+		naive := time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+		if naive.Day() != t.Day() {
+			continue
+		}
+
+		candidate = wallClock(naive, loc)
+		if !candidate.After(limit) {
+			break
+		}
+	}
+
+	return candidate
+}
+
+// wallClock returns the instant at which clocks in loc show the date and
+// time fields of t. In an hour skipped by a daylight saving change the
+// fields are read with the offset in effect before the change, moving them
+// forward (02:30 becomes 03:30); in a repeated hour time.Date picks one of
+// the two instants.
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	if loc == time.UTC {
+		return t
+	}
+
+	local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	if local.Hour() == t.Hour() && local.Minute() == t.Minute() {
+		return local
+	}
+
+	// The fields fell in a gap: try the offsets on both sides of it
+	_, first := local.Zone()
+	early := t.Add(-time.Duration(first) * time.Second)
+	_, second := early.In(loc).Zone()
+	late := t.Add(-time.Duration(second) * time.Second)
+
+	if late.After(early) {
+		early = late
+	}
+
+	return early.In(loc)
+}
+
+// layoutHasZone reports whether a time layout includes a zone offset or
+// abbreviation
+func layoutHasZone(layout string) bool {
+	return strings.Contains(layout, "Z07") || strings.Contains(layout, "-07") || strings.Contains(layout, "MST")
+}
+
+// parseLayoutIn is time.Parse, except for layouts ending in the "MST" zone
+// element, whose abbreviations Go reads with a zero offset unless loc
+// defines them. Abbreviations loc defines (CET and CEST for Europe/Berlin) take their
+// offset from loc, UTC and GMT are UTC, and other abbreviations are read as
+// wall-clock time in loc rather than as UTC. Numeric zones such as "+03" and
+// "+0530", which PostgreSQL prints for zones without an abbreviation, are
+// offsets.
+func parseLayoutIn(layout, value string, loc *time.Location) (time.Time, error) {
+	if !strings.HasSuffix(layout, "MST") {
+		return time.Parse(layout, value)
+	}
+
+	if i := strings.LastIndexByte(value, ' '); i >= 0 && i+1 < len(value) && (value[i+1] == '+' || value[i+1] == '-') {
+		zone := value[i+1:]
+		if len(zone) == len("+07") {
+			zone += "00"
+		}
+
+		return time.Parse(strings.Replace(layout, "MST", "-0700", 1), value[:i+1]+zone)
+	}
+
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return t, err
+	}
+
+	if name, _ := t.Zone(); t.Location() != loc && t.Location() != time.UTC && name != "GMT" {
+		t = wallClock(time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC), loc)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,133 @@
+package logparser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestYearInference(t *testing.T) {
+	utc := func(y int, m time.Month, d, h, min int) time.Time { return time.Date(y, m, d, h, min, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name string
+		ref  time.Time
+		line string
+		want time.Time
+	}{
+		{"same year", utc(2025, 1, 15, 0, 0), "Jan 14 10:00:00 host app: m", utc(2025, 1, 14, 10, 0)},
+		{"december read in january", utc(2025, 1, 1, 0, 10), "Dec 31 23:59:00 host app: m", utc(2024, 12, 31, 23, 59)},
+		{"december read mid-year", utc(2025, 6, 1, 0, 0), "Dec 25 08:00:00 host app: m", utc(2024, 12, 25, 8, 0)},
+		{"new year line read at new year", utc(2025, 1, 1, 0, 10), "Jan  1 00:05:00 host app: m", utc(2025, 1, 1, 0, 5)},
+		{"slightly ahead of reference", utc(2025, 3, 10, 12, 0), "Mar 10 12:30:00 host app: m", utc(2025, 3, 10, 12, 30)},
+		{"well ahead of reference", utc(2025, 3, 10, 12, 0), "Mar 10 14:00:00 host app: m", utc(2024, 3, 10, 14, 0)},
+		{"leap day after a leap year", utc(2025, 3, 1, 0, 0), "Feb 29 10:00:00 host app: m", utc(2024, 2, 29, 10, 0)},
+		{"logcat", utc(2025, 1, 1, 0, 10), "12-31 23:59:59.500  1  2 I Tag: m", time.Date(2024, 12, 31, 23, 59, 59, 5e8, time.UTC)},
+		{"JSON without year", utc(2025, 1, 1, 0, 10), `{"time":"Dec 31 23:59:00","msg":"m"}`, utc(2024, 12, 31, 23, 59)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := New(WithReferenceTime(tt.ref)).ParseString(tt.line)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			if got := entries[0].Timestamp; !got.Equal(tt.want) {
+				t.Errorf("Timestamp = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYearInferenceFromFileModTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog")
+	if err := os.WriteFile(path, []byte("Dec 31 23:00:00 host app: rotated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	mtime := time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ParseFile(New(), path)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if got := entries[0].Timestamp.Year(); got != 2024 {
+		t.Errorf("year = %d, want 2024 from the file modification time", got)
+	}
+
+	// An explicit reference time wins over the modification time
+	entries, err = ParseFile(New(WithReferenceTime(time.Date(2031, 6, 1, 0, 0, 0, 0, time.UTC))), path)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if got := entries[0].Timestamp.Year(); got != 2030 {
+		t.Errorf("year = %d, want 2030 from the reference time", got)
+	}
+}
+
+func TestLocationDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want []time.Time // Acceptable instants
+	}{
+		{
+			name: "standard time",
+			line: "2025-01-15 09:00:00 [INFO] m",
+			want: []time.Time{time.Date(2025, 1, 15, 14, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "daylight time",
+			line: "2025-07-15 09:00:00 [INFO] m",
+			want: []time.Time{time.Date(2025, 7, 15, 13, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "skipped hour moves forward",
+			line: "2025-03-09 02:30:00 [INFO] m",
+			want: []time.Time{time.Date(2025, 3, 9, 7, 30, 0, 0, time.UTC)},
+		},
+		{
+			name: "repeated hour",
+			line: "2025-11-02 01:30:00 [INFO] m",
+			want: []time.Time{time.Date(2025, 11, 2, 5, 30, 0, 0, time.UTC), time.Date(2025, 11, 2, 6, 30, 0, 0, time.UTC)},
+		},
+		{
+			name: "syslog across new year in local time",
+			line: "Dec 31 23:45:00 host app: m",
+			want: []time.Time{time.Date(2026, 1, 1, 4, 45, 0, 0, time.UTC)},
+		},
+	}
+
+	ref := time.Date(2026, 1, 1, 5, 0, 0, 0, time.UTC) // Midnight in New York
+	parser := NewWithFormat(FormatText, WithLocation(ny), WithReferenceTime(ref))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parser.ParseString(tt.line)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+
+			got := entries[0].Timestamp
+			for _, want := range tt.want {
+				if got.Equal(want) {
+					return
+				}
+			}
+
+			t.Errorf("Timestamp = %v (%v UTC), want one of %v", got, got.UTC(), tt.want)
+		})
+	}
+}
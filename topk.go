@@ -0,0 +1,141 @@
+package logparser
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// DefaultTopK is the number of values StreamStats reports per field unless
+// StreamStatsOptions.TopKSize says otherwise
+const DefaultTopK = 10
+
+// topKCountersPerValue is the number of counters a top-k sketch keeps per
+// value reported. More counters give tighter error bounds; the bound on
+// each count is the stream length divided by the number of counters.
+const topKCountersPerValue = 10
+
+// ValueCount is one of the most frequent values of a field. Count may
+// overstate the true count by up to Error and never understates it, so
+// the value occurred between Count-Error and Count times. Error is zero
+// for values counted exactly since their first occurrence.
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+	Error int64  `json:"error"`
+}
+
+// TopK returns up to k of the most frequent values of the field at key,
+// most frequent first, ties by value. key is a field path as lookupField
+// takes it, with "service" and "logger" falling back to LogEntry.Service
+// and LogEntry.Logger. Values are compared as strings, formatted as the
+// logfmt writer formats them, so 2, 2.0, and "2" count as one value.
+// Entries without the field are skipped.
+//
+// Values are counted with the Space-Saving algorithm of Metwally et al.
+// in 10×k counters, whatever the number of distinct values. Over n
+// entries with the field, Error is at most n/(10×k), and every value
+// occurring more than n/(10×k) times is among the counted. With the skewed
+// distributions logs usually have, the result is the exact top k.
+func TopK(entries []LogEntry, key string, k int) []ValueCount {
+	s := newTopKSketch(k)
+	for i := range entries {
+		if val, ok := entryField(&entries[i], key); ok {
+			s.add(formatValue(val))
+		}
+	}
+
+	return s.top(k)
+}
+
+// topKSketch is a Space-Saving sketch: a fixed number of counters, the
+// smallest of which is taken over by a value not already counted
+type topKSketch struct {
+	size     int
+	counters topKHeap
+	index    map[string]*topKCounter
+}
+
+// topKCounter counts one value
+type topKCounter struct {
+	value string
+	count int64
+	error int64 // Count the value inherited when it took the counter over
+	pos   int   // Position in the heap
+}
+
+// newTopKSketch returns a sketch sized to report k values, at least one
+func newTopKSketch(k int) *topKSketch {
+	size := max(1, k) * topKCountersPerValue
+
+	return &topKSketch{size: size, index: make(map[string]*topKCounter, size)}
+}
+
+// add counts one occurrence of a value
+func (s *topKSketch) add(value string) {
+	if c, ok := s.index[value]; ok {
+		c.count++
+		heap.Fix(&s.counters, c.pos)
+
+		return
+	}
+
+	if len(s.counters) < s.size {
+		c := &topKCounter{value: value, count: 1}
+		heap.Push(&s.counters, c)
+		s.index[value] = c
+
+		return
+	}
+
+	// Replace the least counted value, inheriting its count as the error
+	c := s.counters[0]
+	delete(s.index, c.value)
+
+	c.value, c.error = value, c.count
+	c.count++
+	s.index[value] = c
+	heap.Fix(&s.counters, 0)
+}
+
+// top returns up to k counted values, most counted first
+func (s *topKSketch) top(k int) []ValueCount {
+	result := make([]ValueCount, len(s.counters))
+	for i, c := range s.counters {
+		result[i] = ValueCount{Value: c.value, Count: c.count, Error: c.error}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+
+		return result[i].Value < result[j].Value
+	})
+
+	return result[:min(max(k, 0), len(result))]
+}
+
+// topKHeap orders counters by count, least first
+type topKHeap []*topKCounter
+
+func (h topKHeap) Len() int           { return len(h) }
+func (h topKHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h topKHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos, h[j].pos = i, j
+}
+
+func (h *topKHeap) Push(x interface{}) {
+	c := x.(*topKCounter)
+	c.pos = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *topKHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return c
+}
@@ -0,0 +1,157 @@
+package logparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"testing"
+)
+
+// zipfEntries returns n entries whose user field follows a Zipf
+// distribution with exponent s over distinct values, and the exact counts
+func zipfEntries(n int, s float64, distinct uint64) ([]LogEntry, map[string]int64) {
+	zipf := rand.NewZipf(rand.New(rand.NewPCG(7, 11)), s, 1, distinct-1)
+	entries := make([]LogEntry, n)
+	exact := make(map[string]int64)
+
+	for i := range entries {
+		user := fmt.Sprintf("user-%d", zipf.Uint64())
+		entries[i] = LogEntry{Fields: map[string]interface{}{"user": user}}
+		exact[user]++
+	}
+
+	return entries, exact
+}
+
+// exactTop returns the k values with the highest counts, ties by value
+func exactTop(counts map[string]int64, k int) []string {
+	values := make([]string, 0, len(counts))
+	for v := range counts {
+		values = append(values, v)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+
+		return values[i] < values[j]
+	})
+
+	return values[:min(k, len(values))]
+}
+
+func TestTopKZipf(t *testing.T) {
+	const (
+		n = 200000
+		k = 10
+	)
+
+	for _, s := range []float64{1.1, 1.5, 2} {
+		entries, exact := zipfEntries(n, s, 1_000_000)
+
+		got := TopK(entries, "user", k)
+		want := exactTop(exact, k)
+
+		if len(got) != k {
+			t.Fatalf("s=%v: got %d values, want %d", s, len(got), k)
+		}
+
+		for i, vc := range got {
+			if vc.Value != want[i] {
+				t.Errorf("s=%v: value %d = %q, want %q", s, i, vc.Value, want[i])
+			}
+
+			if occurred := exact[vc.Value]; vc.Count-vc.Error > occurred || vc.Count < occurred {
+				t.Errorf("s=%v: %q counted %d±%d, occurred %d times", s, vc.Value, vc.Count, vc.Error, occurred)
+			}
+
+			if vc.Error > n/(topKCountersPerValue*k) {
+				t.Errorf("s=%v: %q error %d above bound", s, vc.Value, vc.Error)
+			}
+		}
+	}
+}
+
+func TestTopKBoundedMemory(t *testing.T) {
+	const k = 5
+
+	sketch := newTopKSketch(k)
+	for i := range 100000 {
+		sketch.add(fmt.Sprintf("trace-%d", i))
+	}
+
+	if len(sketch.counters) != k*topKCountersPerValue || len(sketch.index) != k*topKCountersPerValue {
+		t.Errorf("%d counters, %d indexed, want %d", len(sketch.counters), len(sketch.index), k*topKCountersPerValue)
+	}
+
+	// With every value distinct, no count is exact
+	for _, vc := range sketch.top(k) {
+		if vc.Count-vc.Error > 1 {
+			t.Errorf("%q counted %d±%d, occurred once", vc.Value, vc.Count, vc.Error)
+		}
+	}
+}
+
+func TestTopKMixedTypes(t *testing.T) {
+	var entries []LogEntry
+
+	for _, v := range []interface{}{2.0, "2", json.Number("2"), 2, true, "true", "x", nil} {
+		entries = append(entries, LogEntry{Fields: map[string]interface{}{"code": v}})
+	}
+
+	entries = append(entries, LogEntry{Message: "no code"})
+
+	got := TopK(entries, "code", 3)
+	want := []ValueCount{{Value: "2", Count: 4}, {Value: "true", Count: 2}, {Value: "", Count: 1}}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("TopK = %v, want %v", got, want)
+	}
+
+	if got := TopK(entries, "code", 0); len(got) != 0 {
+		t.Errorf("k=0 gave %v", got)
+	}
+
+	if got := TopK(entries, "code", 100); len(got) != 4 {
+		t.Errorf("k=100 gave %d values, want all 4", len(got))
+	}
+}
+
+func TestTopKServiceFallback(t *testing.T) {
+	entries, err := NewWithFormat(FormatLogfmt).ParseString("msg=a service=api\nmsg=b service=api\nmsg=c app=worker\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := TopK(entries, "service", 2)
+	if len(got) != 2 || got[0] != (ValueCount{Value: "api", Count: 2}) || got[1].Value != "worker" {
+		t.Errorf("TopK = %v", got)
+	}
+}
+
+func TestStreamStatsTopValues(t *testing.T) {
+	s := NewStreamStats(StreamStatsOptions{TopK: []string{"http.status", "missing"}, TopKSize: 2})
+
+	for _, status := range []float64{200, 200, 200, 500, 500, 404} {
+		s.Observe(LogEntry{Fields: map[string]interface{}{
+			"http": map[string]interface{}{"status": status},
+		}})
+	}
+
+	snap := s.Snapshot()
+
+	want := []ValueCount{{Value: "200", Count: 3}, {Value: "500", Count: 2}}
+	if got := snap.TopValues["http.status"]; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("http.status = %v, want %v", got, want)
+	}
+
+	if got, ok := snap.TopValues["missing"]; !ok || len(got) != 0 {
+		t.Errorf("missing = %v, %v", got, ok)
+	}
+
+	if _, err := json.Marshal(snap); err != nil {
+		t.Fatal(err)
+	}
+}
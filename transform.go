@@ -0,0 +1,97 @@
+package logparser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Transform rewrites an entry after extraction. Returning an error skips
+// the entry when WithSkipInvalid is set and aborts the parse otherwise.
+type Transform func(entry *LogEntry) error
+
+// WithTransform registers a transform that runs on every entry after the
+// standard fields are extracted. Transforms run in registration order.
+func WithTransform(fn Transform) Option {
+	return func(c *config) {
+		c.transforms = append(c.transforms, fn)
+	}
+}
+
+// RenameFields returns a transform that renames field keys according to
+// renames (old key to new key). The renames apply at once, to the values
+// the keys held before any of them, so {"a": "b", "b": "a"} swaps two
+// fields and {"a": "b", "b": "c"} moves each one along. A renamed key
+// replaces any existing value; when several keys are renamed to one key,
+// the value of the key sorting first wins.
+func RenameFields(renames map[string]string) Transform {
+	froms := make([]string, 0, len(renames))
+	for from := range renames {
+		froms = append(froms, from)
+	}
+
+	sort.Strings(froms)
+
+	return func(entry *LogEntry) error {
+		moved := make(map[string]interface{}, len(froms))
+
+		for _, from := range froms {
+			if val, ok := entry.Fields[from]; ok {
+				moved[from] = val
+				delete(entry.Fields, from)
+			}
+		}
+
+		// In reverse, so the first of several keys renamed to one is set last
+		for i := len(froms) - 1; i >= 0; i-- {
+			if val, ok := moved[froms[i]]; ok {
+				entry.Fields[renames[froms[i]]] = val
+			}
+		}
+
+		return nil
+	}
+}
+
+// LowercaseKeys returns a transform that lower-cases all field keys. When
+// keys differ only in case, a key already in lower case keeps its value;
+// otherwise the value of the key sorting first wins, so of "Foo" and
+// "FOO", "FOO" is kept as "foo".
+func LowercaseKeys() Transform {
+	return func(entry *LogEntry) error {
+		var upper []string
+
+		for key := range entry.Fields {
+			if strings.ToLower(key) != key {
+				upper = append(upper, key)
+			}
+		}
+
+		sort.Strings(upper)
+
+		moved := make([]interface{}, len(upper))
+		for i, key := range upper {
+			moved[i] = entry.Fields[key]
+			delete(entry.Fields, key)
+		}
+
+		for i, key := range upper {
+			if _, taken := entry.Fields[strings.ToLower(key)]; !taken {
+				entry.Fields[strings.ToLower(key)] = moved[i]
+			}
+		}
+
+		return nil
+	}
+}
+
+// applyTransforms runs the configured transforms in order
+func (c *config) applyTransforms(entry *LogEntry) error {
+	for _, fn := range c.transforms {
+		if err := fn(entry); err != nil {
+			return fmt.Errorf("transform failed: %w", err)
+		}
+	}
+
+	return nil
+}
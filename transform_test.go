@@ -0,0 +1,157 @@
+package logparser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransformsComposeInOrder(t *testing.T) {
+	toMillis := func(e *LogEntry) error {
+		s, ok := e.Fields["duration"].(string)
+		if !ok {
+			return nil
+		}
+
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		e.Fields["duration"] = float64(d) / float64(time.Millisecond)
+
+		return nil
+	}
+
+	parser := NewWithFormat(FormatLogfmt,
+		WithTransform(LowercaseKeys()),
+		WithTransform(RenameFields(map[string]string{"svc": "service"})),
+		WithTransform(toMillis),
+	)
+
+	entries, err := parser.ParseString(`level=info msg=done SVC=api Duration=1.5s`)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	fields := entries[0].Fields
+	if fields["service"] != "api" || fields["duration"] != 1500.0 {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	if _, ok := fields["SVC"]; ok {
+		t.Errorf("original key should be renamed away: %v", fields)
+	}
+}
+
+func TestRenameAndLowercaseDeterministic(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform Transform
+		fields    map[string]interface{}
+		want      string
+	}{
+		{
+			name:      "swap",
+			transform: RenameFields(map[string]string{"a": "b", "b": "a"}),
+			fields:    map[string]interface{}{"a": 1, "b": 2},
+			want:      "map[a:2 b:1]",
+		},
+		{
+			name:      "chain",
+			transform: RenameFields(map[string]string{"a": "b", "b": "c"}),
+			fields:    map[string]interface{}{"a": 1, "b": 2},
+			want:      "map[b:1 c:2]",
+		},
+		{
+			name:      "chain onto existing key",
+			transform: RenameFields(map[string]string{"a": "b", "b": "c"}),
+			fields:    map[string]interface{}{"a": 1, "b": 2, "c": 3},
+			want:      "map[b:1 c:2]",
+		},
+		{
+			name:      "two keys renamed to one",
+			transform: RenameFields(map[string]string{"svc": "service", "app": "service"}),
+			fields:    map[string]interface{}{"svc": "a", "app": "b"},
+			want:      "map[service:b]",
+		},
+		{
+			name:      "lower-case key wins",
+			transform: LowercaseKeys(),
+			fields:    map[string]interface{}{"Foo": 1, "foo": 2, "FOO": 3},
+			want:      "map[foo:2]",
+		},
+		{
+			name:      "first sorted key wins",
+			transform: LowercaseKeys(),
+			fields:    map[string]interface{}{"Foo": 1, "FOO": 3, "fOo": 4, "Bar": 5},
+			want:      "map[bar:5 foo:3]",
+		},
+	}
+
+	for _, tt := range tests {
+		// Map iteration order varies, so repeat to catch order dependence
+		for range 50 {
+			fields := make(map[string]interface{}, len(tt.fields))
+			for k, v := range tt.fields {
+				fields[k] = v
+			}
+
+			entry := &LogEntry{Fields: fields}
+			if err := tt.transform(entry); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+
+			if got := fmt.Sprint(entry.Fields); got != tt.want {
+				t.Fatalf("%s: fields = %s, want %s", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	errReject := errors.New("rejected")
+	reject := func(e *LogEntry) error {
+		if strings.Contains(e.Message, "secret") {
+			return errReject
+		}
+
+		return nil
+	}
+
+	input := "level=info msg=ok\nlevel=info msg=\"secret stuff\"\nlevel=info msg=fine"
+
+	_, err := NewWithFormat(FormatLogfmt, WithTransform(reject)).ParseString(input)
+	if !errors.Is(err, errReject) {
+		t.Fatalf("want transform error to abort, got %v", err)
+	}
+
+	p := NewWithFormat(FormatLogfmt, WithTransform(reject), WithSkipInvalid(true))
+	entries, stats, err := ParseWithStats(p, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	if len(entries) != 2 || entries[1].Message != "fine" || stats.LinesSkipped != 1 {
+		t.Errorf("want rejected entry skipped, got %d entries and %+v", len(entries), stats)
+	}
+}
+
+func TestSkipInvalidLines(t *testing.T) {
+	input := `{"level":"info","msg":"one"}` + "\n" + `{"level":"info","msg":` + "\n" + `{"level":"info","msg":"two"}`
+
+	if _, err := NewWithFormat(FormatJSON).ParseString(input); err == nil {
+		t.Fatal("want error for truncated JSON line by default")
+	}
+
+	entries, stats, err := ParseWithStats(NewWithFormat(FormatJSON, WithSkipInvalid(true)), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	if len(entries) != 2 || stats.LinesSkipped != 1 || stats.LinesSeen != 3 {
+		t.Errorf("want 2 entries and 1 skipped line, got %d entries and %+v", len(entries), stats)
+	}
+}
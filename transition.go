@@ -0,0 +1,250 @@
+package logparser
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Flapping defaults of TransitionOptions
+const (
+	DefaultFlapCount      = 3
+	defaultFlapWindowSpan = 10 // Flap window in multiples of the window
+)
+
+// Transition is a change in the dominant level of a group of entries, such
+// as a service starting to fail or recovering
+type Transition struct {
+	Key      string     `json:"key,omitempty"`   // Field used for grouping, if any
+	Group    string     `json:"group,omitempty"` // Value of the grouping field
+	From     string     `json:"from"`
+	To       string     `json:"to"`
+	At       time.Time  `json:"at"`                 // Timestamp of the first entry at which To dominated
+	Flapping bool       `json:"flapping,omitempty"` // Part of a burst of transitions
+	Entries  []LogEntry `json:"entries"`            // Entries at level To in the window ending at At
+}
+
+// String describes the transition, for example
+// "service=api INFO→ERROR at 14:02 (flapping)"
+func (t Transition) String() string {
+	s := fmt.Sprintf("%s→%s at %s", t.From, t.To, t.At.Format("15:04"))
+	if t.Group != "" {
+		s = fmt.Sprintf("%s=%s %s", t.Key, t.Group, s)
+	}
+
+	if t.Flapping {
+		s += " (flapping)"
+	}
+
+	return s
+}
+
+// TransitionOptions configures transition detection
+type TransitionOptions struct {
+	// Window is the span over which levels are counted (default one
+	// minute). A new level must also dominate for a whole window before it
+	// counts as a transition.
+	Window time.Duration
+	// Transitions are flapping when more than FlapCount (default
+	// DefaultFlapCount) of them fall within FlapWindow (default ten
+	// windows) of each other
+	FlapCount  int
+	FlapWindow time.Duration
+}
+
+// DetectTransitions finds changes in the dominant level of each group of
+// entries, using TransitionOptions{Window: window}
+func DetectTransitions(entries []LogEntry, groupKey string, window time.Duration) []Transition {
+	return TransitionOptions{Window: window}.Detect(entries, groupKey)
+}
+
+// Detect finds changes in the dominant level of each group of entries.
+// Entries are grouped by the value of the groupKey field, or all form one
+// group if groupKey is empty. At each entry of a group, the levels of its
+// entries in the window ending at that entry (later than its timestamp
+// minus Window, up to and including it) are counted, and the level with
+// strictly the most entries dominates; a tie has no dominant level. A
+// level that comes to dominate becomes the group's level once it has
+// dominated at every entry for at least Window, so blips shorter than the
+// window produce no transition, and neither does a change still pending
+// at the end of the input. The first dominant level of a group is its
+// starting level. Entries need not be sorted; those without a timestamp
+// or level are ignored. Transitions are ordered by time.
+func (o TransitionOptions) Detect(entries []LogEntry, groupKey string) []Transition {
+	if o.Window <= 0 {
+		o.Window = time.Minute
+	}
+
+	if o.FlapCount <= 0 {
+		o.FlapCount = DefaultFlapCount
+	}
+
+	if o.FlapWindow <= 0 {
+		o.FlapWindow = defaultFlapWindowSpan * o.Window
+	}
+
+	groups := make(map[string][]int)
+
+	var order []string
+
+	for i := range entries {
+		e := &entries[i]
+		if e.Timestamp.IsZero() || e.Level == "" {
+			continue
+		}
+
+		var group string
+
+		if groupKey != "" {
+			if val, ok := entryField(e, groupKey); ok {
+				group = formatValue(val)
+			}
+		}
+
+		if _, seen := groups[group]; !seen {
+			order = append(order, group)
+		}
+
+		groups[group] = append(groups[group], i)
+	}
+
+	transitions := []Transition{}
+
+	for _, group := range order {
+		idx := groups[group]
+
+		sort.SliceStable(idx, func(a, b int) bool {
+			return entries[idx[a]].Timestamp.Before(entries[idx[b]].Timestamp)
+		})
+
+		found := o.groupTransitions(entries, idx)
+		for i := range found {
+			if groupKey != "" && group != "" {
+				found[i].Key, found[i].Group = groupKey, group
+			}
+		}
+
+		transitions = append(transitions, found...)
+	}
+
+	sort.SliceStable(transitions, func(a, b int) bool {
+		return transitions[a].At.Before(transitions[b].At)
+	})
+
+	return transitions
+}
+
+// groupTransitions finds the transitions of one group, whose entries are
+// at idx in time order
+func (o TransitionOptions) groupTransitions(entries []LogEntry, idx []int) []Transition {
+	var (
+		found   []Transition
+		counts  = make(map[string]int)
+		current string // Level of the group
+		pending string // Level dominating instead of current, if any
+		since   int    // Position in idx of the first entry pending dominated
+		first   int    // Position in idx of the oldest entry in the window
+	)
+
+	for n, i := range idx {
+		now := entries[i].Timestamp
+		counts[entries[i].Level]++
+
+		for !entries[idx[first]].Timestamp.After(now.Add(-o.Window)) {
+			if level := entries[idx[first]].Level; counts[level] > 1 {
+				counts[level]--
+			} else {
+				delete(counts, level)
+			}
+
+			first++
+		}
+
+		dominant := dominantLevel(counts)
+
+		switch {
+		case current == "":
+			current = dominant
+		case dominant == current || dominant == "":
+			pending = ""
+		case dominant != pending:
+			pending, since = dominant, n
+		}
+
+		if pending == "" || now.Sub(entries[idx[since]].Timestamp) < o.Window {
+			continue
+		}
+
+		found = append(found, Transition{
+			From:    current,
+			To:      pending,
+			At:      entries[idx[since]].Timestamp,
+			Entries: windowEntries(entries, idx[:since+1], pending, o.Window),
+		})
+
+		current, pending = pending, ""
+	}
+
+	// A transition flaps when the flap window ending at it holds too many
+	for n := range found {
+		count := 0
+
+		for m := n; m >= 0 && found[n].At.Sub(found[m].At) < o.FlapWindow; m-- {
+			count++
+		}
+
+		if count > o.FlapCount {
+			for m := n - count + 1; m <= n; m++ {
+				found[m].Flapping = true
+			}
+		}
+	}
+
+	return found
+}
+
+// dominantLevel returns the level with strictly the most entries, or ""
+// on a tie
+func dominantLevel(counts map[string]int) string {
+	var (
+		best string
+		top  int
+		tied bool
+	)
+
+	for level, n := range counts {
+		switch {
+		case n > top:
+			best, top, tied = level, n, false
+		case n == top:
+			tied = true
+		}
+	}
+
+	if tied {
+		return ""
+	}
+
+	return best
+}
+
+// windowEntries returns the entries at level in the window ending at the
+// last of idx
+func windowEntries(entries []LogEntry, idx []int, level string, window time.Duration) []LogEntry {
+	end := entries[idx[len(idx)-1]].Timestamp
+
+	var result []LogEntry
+
+	for n := len(idx) - 1; n >= 0 && entries[idx[n]].Timestamp.After(end.Add(-window)); n-- {
+		if e := entries[idx[n]]; e.Level == level {
+			result = append(result, e)
+		}
+	}
+
+	// Collected newest first
+	for a, b := 0, len(result)-1; a < b; a, b = a+1, b-1 {
+		result[a], result[b] = result[b], result[a]
+	}
+
+	return result
+}
@@ -0,0 +1,169 @@
+package logparser
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// levelRun appends an entry every step from start until end, at level
+func levelRun(entries []LogEntry, service, level string, start, end time.Time, step time.Duration) []LogEntry {
+	for t := start; t.Before(end); t = t.Add(step) {
+		entries = append(entries, LogEntry{
+			Timestamp: t,
+			Level:     level,
+			Message:   "tick",
+			Fields:    map[string]interface{}{"service": service},
+		})
+	}
+
+	return entries
+}
+
+// at returns 14:mm:ss on a fixed day
+func at(m, s int) time.Time {
+	return time.Date(2024, 5, 1, 14, m, s, 0, time.UTC)
+}
+
+func TestDetectTransitions(t *testing.T) {
+	step := 10 * time.Second
+
+	var entries []LogEntry
+	entries = levelRun(entries, "api", LevelInfo, at(0, 0), at(2, 0), step)
+	entries = levelRun(entries, "api", LevelError, at(2, 0), at(19, 0), step)
+	entries = levelRun(entries, "api", LevelInfo, at(19, 0), at(30, 0), step)
+
+	got := DetectTransitions(entries, "service", time.Minute)
+
+	want := []string{"service=api INFO→ERROR at 14:02", "service=api ERROR→INFO at 14:19"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("transition %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// ERROR outnumbers INFO from 14:02:30, with four errors in the window
+	if !got[0].At.Equal(at(2, 30)) || len(got[0].Entries) != 4 || got[0].Entries[0].Timestamp != at(2, 0) {
+		t.Errorf("first transition at %v with %d entries", got[0].At, len(got[0].Entries))
+	}
+
+	for _, e := range got[0].Entries {
+		if e.Level != LevelError {
+			t.Errorf("triggering entry at level %s", e.Level)
+		}
+	}
+
+	// Shuffled input gives the same transitions
+	shuffled := append([]LogEntry(nil), entries...)
+	rand.New(rand.NewSource(1)).Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	again := DetectTransitions(shuffled, "service", time.Minute)
+	if len(again) != 2 || !again[0].At.Equal(got[0].At) || !again[1].At.Equal(got[1].At) {
+		t.Errorf("shuffled input gave %v", again)
+	}
+}
+
+func TestDetectTransitionsIgnoresBlips(t *testing.T) {
+	step := 10 * time.Second
+
+	var entries []LogEntry
+	entries = levelRun(entries, "api", LevelInfo, at(0, 0), at(10, 0), step)
+	// Errors dominate from 14:10:30 until a tie at 14:11:10, under a window
+	entries = levelRun(entries, "api", LevelError, at(10, 0), at(10, 50), step)
+	entries = levelRun(entries, "api", LevelInfo, at(10, 50), at(20, 0), step)
+	// A single warning in a quiet stretch dominates only until INFO returns
+	entries = levelRun(entries, "api", "WARN", at(22, 0), at(22, 1), step)
+	entries = levelRun(entries, "api", LevelInfo, at(22, 30), at(25, 0), step)
+
+	if got := DetectTransitions(entries, "service", time.Minute); len(got) != 0 {
+		t.Errorf("blips gave transitions %v", got)
+	}
+
+	// A pending change at the end of the input is not confirmed
+	tail := levelRun(nil, "api", LevelInfo, at(0, 0), at(5, 0), step)
+	tail = levelRun(tail, "api", LevelError, at(5, 0), at(5, 50), step)
+
+	if got := DetectTransitions(tail, "service", time.Minute); len(got) != 0 {
+		t.Errorf("unconfirmed change gave %v", got)
+	}
+}
+
+func TestDetectTransitionsWindowBoundary(t *testing.T) {
+	// The window ending at 14:01 starts just after 14:00, leaving out the
+	// INFO entry, so ERROR dominates from 14:01 rather than 14:02
+	entries := []LogEntry{
+		{Timestamp: at(0, 0), Level: LevelInfo},
+		{Timestamp: at(1, 0), Level: LevelError},
+		{Timestamp: at(2, 0), Level: LevelError},
+		{Timestamp: at(3, 0), Level: LevelError},
+	}
+
+	got := DetectTransitions(entries, "", time.Minute)
+	if len(got) != 1 || !got[0].At.Equal(at(1, 0)) || got[0].Group != "" {
+		t.Fatalf("got %v, want INFO→ERROR at 14:01:00", got)
+	}
+
+	if got[0].String() != "INFO→ERROR at 14:01" {
+		t.Errorf("String() = %q", got[0].String())
+	}
+
+	// Confirmation needs a full window: at 14:01:59 ERROR has dominated
+	// for just under a minute
+	entries[2].Timestamp = at(1, 59)
+	if got := DetectTransitions(entries[:3], "", time.Minute); len(got) != 0 {
+		t.Errorf("got %v before a full window", got)
+	}
+}
+
+func TestDetectTransitionsFlapping(t *testing.T) {
+	step := 10 * time.Second
+
+	var entries []LogEntry
+
+	levels := []string{LevelInfo, LevelError, LevelInfo, LevelError, LevelInfo}
+	for i, level := range levels {
+		entries = levelRun(entries, "api", level, at(2*i, 0), at(2*i+2, 0), step)
+	}
+
+	entries = levelRun(entries, "db", LevelInfo, at(0, 0), at(4, 0), step)
+	entries = levelRun(entries, "db", "WARN", at(4, 0), at(10, 0), step)
+
+	opts := TransitionOptions{Window: 30 * time.Second, FlapCount: 3, FlapWindow: 10 * time.Minute}
+	got := opts.Detect(entries, "service")
+
+	var api, db int
+
+	for _, tr := range got {
+		switch tr.Group {
+		case "api":
+			api++
+
+			if !tr.Flapping {
+				t.Errorf("%v not flapping", tr)
+			}
+		case "db":
+			db++
+
+			if tr.Flapping || tr.String() != "service=db INFO→WARN at 14:04" {
+				t.Errorf("db transition = %v", tr)
+			}
+		}
+	}
+
+	if api != 4 || db != 1 {
+		t.Errorf("got %d api and %d db transitions, want 4 and 1", api, db)
+	}
+
+	// Spread over more than the flap window, the same changes are not
+	// flapping
+	opts.FlapWindow = 5 * time.Minute
+	for _, tr := range opts.Detect(entries, "service") {
+		if tr.Flapping {
+			t.Errorf("%v flapping with a short flap window", tr)
+		}
+	}
+}
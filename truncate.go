@@ -0,0 +1,86 @@
+package logparser
+
+import (
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// truncatedFieldsKey lists the keys whose values WithMaxFieldSize cut short
+const truncatedFieldsKey = "_truncated_fields"
+
+// WithMaxFieldSize truncates the message and string field values longer
+// than n bytes, including strings inside nested objects, appending a
+// marker such as "…[truncated 2096342 bytes]". Cuts never split a UTF-8
+// sequence. The affected keys are listed in Fields["_truncated_fields"]
+// ("message" for the message, dotted paths for nested values) and counted
+// in Stats.FieldsTruncated. Off by default.
+func WithMaxFieldSize(n int) Option {
+	return func(c *config) {
+		c.maxFieldSize = n
+	}
+}
+
+// truncateFields shortens oversized values of entry in place and returns
+// how many were truncated
+func (c *config) truncateFields(entry *LogEntry) int {
+	if c.maxFieldSize <= 0 {
+		return 0
+	}
+
+	var keys []string
+
+	if s, ok := truncateValue(entry.Message, c.maxFieldSize); ok {
+		entry.Message = s
+		keys = append(keys, "message")
+	}
+
+	keys = truncateMap(entry.Fields, "", c.maxFieldSize, keys)
+	if len(keys) == 0 {
+		return 0
+	}
+
+	sort.Strings(keys)
+	c.setField(entry, truncatedFieldsKey, keys)
+
+	return len(keys)
+}
+
+// truncateMap truncates the strings of m and its nested objects, appending
+// the dotted path of each truncated value to keys
+func truncateMap(m map[string]interface{}, prefix string, limit int, keys []string) []string {
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+
+		switch val := v.(type) {
+		case string:
+			if s, ok := truncateValue(val, limit); ok {
+				m[k] = s
+				keys = append(keys, path)
+			}
+		case map[string]interface{}:
+			keys = truncateMap(val, path, limit, keys)
+		}
+	}
+
+	return keys
+}
+
+// truncateValue cuts s to at most limit bytes at a rune boundary and
+// appends a marker with the number of bytes removed. It reports false if s
+// fits.
+func truncateValue(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+
+	return s[:cut] + "…[truncated " + strconv.Itoa(len(s)-cut) + " bytes]", true
+}
@@ -0,0 +1,64 @@
+package logparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWithMaxFieldSize(t *testing.T) {
+	blob := strings.Repeat("QUJD", 1000)
+	line := `{"level":"info","msg":"upload ` + strings.Repeat("x", 30) + `","payload":"` + blob +
+		`","meta":{"note":"` + strings.Repeat("é", 20) + `"},"user":"ada","size":4000}`
+
+	entries, stats, err := ParseWithStats(NewWithFormat(FormatJSON, WithMaxFieldSize(16)), strings.NewReader(line))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	e := entries[0]
+
+	if want := "upload xxxxxxxxx…[truncated 21 bytes]"; e.Message != want {
+		t.Errorf("Message = %q, want %q", e.Message, want)
+	}
+
+	if want := "QUJDQUJDQUJDQUJD…[truncated 3984 bytes]"; e.Fields["payload"] != want {
+		t.Errorf("payload = %q, want %q", e.Fields["payload"], want)
+	}
+
+	// 16 bytes would end inside a two-byte rune, so only 8 runes are kept
+	note, _ := e.Fields["meta"].(map[string]interface{})["note"].(string)
+	if !utf8.ValidString(note) || !strings.HasPrefix(note, strings.Repeat("é", 8)+"…[truncated 24 bytes]") {
+		t.Errorf("meta.note = %q", note)
+	}
+
+	if e.Fields["user"] != "ada" || e.Fields["size"] != 4000.0 {
+		t.Errorf("short values changed: %v", e.Fields)
+	}
+
+	if want := []string{"message", "meta.note", "payload"}; !reflect.DeepEqual(e.Fields["_truncated_fields"], want) {
+		t.Errorf("_truncated_fields = %v, want %v", e.Fields["_truncated_fields"], want)
+	}
+
+	if stats.FieldsTruncated != 3 {
+		t.Errorf("FieldsTruncated = %d, want 3", stats.FieldsTruncated)
+	}
+}
+
+func TestMaxFieldSizeOffByDefault(t *testing.T) {
+	long := strings.Repeat("y", 100000)
+
+	entries, err := NewWithFormat(FormatLogfmt).ParseString("level=info msg=" + long)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	if entries[0].Message != long {
+		t.Errorf("message truncated without WithMaxFieldSize")
+	}
+
+	if _, ok := entries[0].Fields["_truncated_fields"]; ok {
+		t.Error("_truncated_fields set without WithMaxFieldSize")
+	}
+}
@@ -1,9 +1,13 @@
 package logparser
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // LogEntry represents a parsed log entry
@@ -11,9 +15,26 @@ type LogEntry struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Level     string                 `json:"level"`
 	Message   string                 `json:"message"`
+	Service   string                 `json:"service,omitempty"` // Service or application, from DefaultServiceKeys
+	Logger    string                 `json:"logger,omitempty"`  // Logger name or category, from DefaultLoggerKeys
 	Fields    map[string]interface{} `json:"fields,omitempty"`
+	Source    *Source                `json:"source,omitempty"`
 }
 
+// Source records where an entry was read from
+type Source struct {
+	Name   string `json:"name"`   // File path or caller-supplied source name
+	Line   int    `json:"line"`   // 1-based physical line number
+	Offset int64  `json:"offset"` // Byte offset of the line start (in UTF-8 for transcoded input)
+}
+
+// InternalFieldPrefix starts the Fields keys the library adds itself, such
+// as "_ansi", "_ts_key", and "_truncated_fields", as opposed to keys read
+// from the log line. Hash ignores keys with this prefix. Journal fields keep
+// their leading underscore unless WithStripJournalPrefix is set, so they are
+// ignored too.
+const InternalFieldPrefix = "_"
+
 // Format represents log format types
 type Format int
 
@@ -22,11 +43,16 @@ const (
 	FormatJSON
 	FormatLogfmt
 	FormatText
+	FormatWindowsEventXML // Windows Event Log XML exports; never auto-detected
+	FormatALB             // AWS Application Load Balancer access logs; never auto-detected
+	FormatHAProxy         // HAProxy HTTP and TCP logs; never auto-detected
+	FormatSquid           // Squid native access logs; never auto-detected
 )
 
 // Static errors
 var (
-	ErrEmptyLine = errors.New("empty line")
+	ErrEmptyLine      = errors.New("empty line")
+	ErrInvalidOptions = errors.New("invalid options")
 )
 
 // Log level constants
@@ -37,7 +63,7 @@ const (
 
 // Buffer and pattern constants
 const (
-	BufferSize      = 1024 * 1024 // 1MB buffer
+	BufferSize      = 1024 * 1024 // Default maximum line size (1MB)
 	LevelIndex      = 2
 	MessageIndex    = 3
 	MessageIndexAlt = 2 // Alternative message index for some patterns
@@ -52,6 +78,14 @@ func (f Format) String() string {
 		return "logfmt"
 	case FormatText:
 		return "text"
+	case FormatWindowsEventXML:
+		return "windows_event_xml"
+	case FormatALB:
+		return "alb"
+	case FormatHAProxy:
+		return "haproxy"
+	case FormatSquid:
+		return "squid"
 	case FormatAuto:
 		return "auto"
 	default:
@@ -59,26 +93,94 @@ func (f Format) String() string {
 	}
 }
 
+// MarshalText encodes the format by name, so it appears as "json" rather
+// than a number in JSON output
+func (f Format) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText decodes a format name written by MarshalText
+func (f *Format) UnmarshalText(text []byte) error {
+	formats := []Format{FormatAuto, FormatJSON, FormatLogfmt, FormatText, FormatWindowsEventXML, FormatALB, FormatHAProxy, FormatSquid}
+	for _, format := range formats {
+		if format.String() == string(text) {
+			*f = format
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown format %q", text)
+}
+
 // ParseLevel parses string to standard level
 func ParseLevel(s string) string {
-	switch strings.ToUpper(s) {
-	case "DEBUG", "DBG":
-		return "DEBUG"
-	case LevelInfo, "INF":
-		return "INFO"
-	case "WARN", "WARNING", "WRN":
-		return "WARN"
-	case "ERROR", "ERR":
-		return "ERROR"
-	case "FATAL", "FTL":
-		return "FATAL"
+	if level, ok := lookupLevel(s); ok {
+		return level
+	}
+
+	return LevelInfo
+}
+
+// lookupLevel maps a level name or alias to a standard level, reporting
+// whether the name was recognized. The result is always one of the level
+// constants, never a new string.
+func lookupLevel(s string) (string, bool) {
+	// Upper-case ASCII names on the stack, since strings.ToUpper allocates
+	// for every lower-case level in the input
+	var buf [32]byte
+	if len(s) > len(buf) {
+		return "", false
+	}
+
+	for i := range len(s) {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			return lookupUpperLevel(strings.ToUpper(s))
+		}
+
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+
+		buf[i] = c
+	}
+
+	return lookupUpperLevel(string(buf[:len(s)]))
+}
+
+// lookupUpperLevel is lookupLevel for an upper-case name
+func lookupUpperLevel(s string) (string, bool) {
+	switch s {
+	case "TRACE", "TRC":
+		return "TRACE", true
+	case "DEBUG", "DBG", "D", "V", "VERBOSE", "DEBUG1", "DEBUG2", "DEBUG3", "DEBUG4", "DEBUG5":
+		return "DEBUG", true
+	case LevelInfo, "INF", "I", "INFORMATION", "NOTICE", "NOTE", "LOG", "SYSTEM":
+		return "INFO", true
+	case "WARN", "WARNING", "WRN", "W":
+		return "WARN", true
+	case "ERROR", "ERR", "E":
+		return "ERROR", true
+	case "FATAL", "FTL", "F", "CRIT", "CRITICAL", "ALERT", "EMERG", "EMERGENCY", "PANIC":
+		return "FATAL", true
 	default:
-		return "INFO"
+		return "", false
 	}
 }
 
-// parseTimestamp attempts to parse various timestamp formats
+// parseTimestamp attempts to parse various timestamp formats. Numbers and
+// numeric strings are unix epoch values in seconds, milliseconds,
+// microseconds, or nanoseconds, chosen by magnitude; fractional parts are
+// kept. Strings without a zone offset are read as UTC.
 func parseTimestamp(val interface{}) (time.Time, error) {
+	return parseTimestampIn(val, time.UTC)
+}
+
+// parseTimestampIn is parseTimestamp reading strings without a zone offset
+// as wall-clock time in loc. Strings without a year are returned in year 0
+// with their fields unchanged, for the caller to infer the year.
+func parseTimestampIn(val interface{}, loc *time.Location) (time.Time, error) {
 	switch v := val.(type) {
 	case string:
 		// Try common formats
@@ -87,21 +189,42 @@ func parseTimestamp(val interface{}) (time.Time, error) {
 			time.RFC3339Nano,
 			"2006-01-02T15:04:05.000Z",
 			"2006-01-02 15:04:05",
-			"Jan 02 15:04:05",
+			"Jan _2 15:04:05",
 		}
 		for _, format := range formats {
 			if t, err := time.Parse(format, v); err == nil {
+				if t.Year() != 0 && !layoutHasZone(format) {
+					t = wallClock(t, loc)
+				}
+
 				return t, nil
 			}
 		}
 
+		if t, ok := parseEpochString(v); ok {
+			return t, nil
+		}
+
 		return time.Time{}, &ParseError{Type: "timestamp", Value: v, Err: "unknown time format"}
 	case float64:
-		// Unix timestamp
-		return time.Unix(int64(v), 0), nil
-	default:
-		return time.Time{}, &ParseError{Type: "timestamp", Value: val, Err: "unsupported timestamp type"}
+		if t, ok := parseEpochFloat(v); ok {
+			return t, nil
+		}
+	case json.Number:
+		if t, ok := parseEpochNumber(v); ok {
+			return t, nil
+		}
+	case int:
+		if t, ok := parseEpochString(strconv.Itoa(v)); ok {
+			return t, nil
+		}
+	case int64:
+		if t, ok := parseEpochString(strconv.FormatInt(v, 10)); ok {
+			return t, nil
+		}
 	}
+
+	return time.Time{}, &ParseError{Type: "timestamp", Value: val, Err: "unsupported timestamp type"}
 }
 
 // ParseError represents a parsing error
@@ -0,0 +1,4 @@
+package logparser
+
+// Version is the library version, recorded in parse reports
+const Version = "1.1.0-dev"
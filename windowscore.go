@@ -0,0 +1,175 @@
+package logparser
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// topWindowTemplates is the number of templates listed per WindowScore
+const topWindowTemplates = 3
+
+// severityWeights weigh entries by level in WindowScore; other levels
+// weigh nothing
+var severityWeights = map[string]float64{LevelError: 1, "FATAL": 10}
+
+// WindowScore rates how suspicious a time window of logs is, to tell where
+// to look first during an incident. Score is the sum of the three
+// components, which are reported separately so they can be re-weighted.
+type WindowScore struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Entries int       `json:"entries"`
+	Score   float64   `json:"score"`
+	// Severity is log2(1 + w), where w counts ERROR entries once and
+	// FATAL entries ten times, so a flood of errors does not drown out
+	// everything else
+	Severity float64 `json:"severity"`
+	// Novelty sums, over the message templates not seen in an earlier
+	// window, 1 plus the weight of the template's most severe level: 11
+	// for a new FATAL template, 2 for a new ERROR one, and 1 for others
+	Novelty float64 `json:"novelty"`
+	// RateChange is log2 of the ratio of entries in this window to the
+	// preceding one, both plus one; only increases add to Score
+	RateChange float64          `json:"rate_change"`
+	Templates  []WindowTemplate `json:"templates"` // Top contributing templates, most first
+}
+
+// WindowTemplate is a message template's share of a WindowScore
+type WindowTemplate struct {
+	Template string `json:"template"` // As returned by MessageTemplate
+	Level    string `json:"level"`    // Most severe level of its entries in the window
+	Count    int    `json:"count"`
+	New      bool   `json:"new"` // Whether the window is the first it appears in
+}
+
+// ScoreWindows splits the entries into windows of the given length,
+// aligned to multiples of it since the zero time, and scores each one
+// that has entries by severity, novel message templates, and the change
+// in log rate from the preceding window (see WindowScore). The first
+// window is the baseline: its templates are not new and its rate has not
+// changed. Each score lists the three templates contributing most, by
+// severity weight plus novelty and then by count. Entries need not be
+// sorted; zero timestamps are ignored. Scores are ordered by score, most
+// suspicious first, then by start time.
+func ScoreWindows(entries []LogEntry, window time.Duration) []WindowScore {
+	idx := make([]int, 0, len(entries))
+
+	for i := range entries {
+		if !entries[i].Timestamp.IsZero() {
+			idx = append(idx, i)
+		}
+	}
+
+	scores := []WindowScore{}
+	if len(idx) == 0 || window <= 0 {
+		return scores
+	}
+
+	sort.SliceStable(idx, func(a, b int) bool {
+		return entries[idx[a]].Timestamp.Before(entries[idx[b]].Timestamp)
+	})
+
+	seen := make(map[string]bool)
+	prevStart := time.Time{}
+	prevCount := 0
+
+	for n := 0; n < len(idx); {
+		start := entries[idx[n]].Timestamp.Truncate(window)
+		end := start.Add(window)
+
+		first := n
+		for n < len(idx) && entries[idx[n]].Timestamp.Before(end) {
+			n++
+		}
+
+		// A window after an empty one follows no entries
+		if !prevStart.IsZero() && !prevStart.Add(window).Equal(start) {
+			prevCount = 0
+		}
+
+		ws := scoreWindow(entries, idx[first:n], seen, !prevStart.IsZero(), prevCount)
+		ws.Start, ws.End = start, end
+		scores = append(scores, ws)
+
+		prevStart, prevCount = start, n-first
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+
+	return scores
+}
+
+// scoreWindow scores the entries at idx, adding their templates to seen.
+// Templates are only new, and the rate only changes, after the first
+// window.
+func scoreWindow(entries []LogEntry, idx []int, seen map[string]bool, baselined bool, prevCount int) WindowScore {
+	type templateStats struct {
+		WindowTemplate
+		weight float64
+	}
+
+	templates := make(map[string]*templateStats)
+	weight := 0.0
+
+	for _, i := range idx {
+		e := &entries[i]
+		weight += severityWeights[e.Level]
+
+		tmpl := MessageTemplate(e.Message)
+
+		ts, ok := templates[tmpl]
+		if !ok {
+			ts = &templateStats{WindowTemplate: WindowTemplate{Template: tmpl, Level: e.Level, New: baselined && !seen[tmpl]}}
+			templates[tmpl] = ts
+		}
+
+		ts.Count++
+
+		if levelRank(e.Level) > levelRank(ts.Level) {
+			ts.Level = e.Level
+		}
+	}
+
+	ws := WindowScore{Entries: len(idx), Severity: math.Log2(1 + weight)}
+
+	list := make([]*templateStats, 0, len(templates))
+
+	for tmpl, ts := range templates {
+		seen[tmpl] = true
+		ts.weight = severityWeights[ts.Level] * float64(ts.Count)
+
+		if ts.New {
+			novelty := 1 + severityWeights[ts.Level]
+			ws.Novelty += novelty
+			ts.weight += novelty
+		}
+
+		list = append(list, ts)
+	}
+
+	if baselined {
+		ws.RateChange = math.Log2(float64(len(idx)+1) / float64(prevCount+1))
+	}
+
+	ws.Score = ws.Severity + ws.Novelty + max(ws.RateChange, 0)
+
+	sort.Slice(list, func(i, j int) bool {
+		switch {
+		case list[i].weight != list[j].weight:
+			return list[i].weight > list[j].weight
+		case list[i].Count != list[j].Count:
+			return list[i].Count > list[j].Count
+		default:
+			return list[i].Template < list[j].Template
+		}
+	})
+
+	for _, ts := range list[:min(len(list), topWindowTemplates)] {
+		ws.Templates = append(ws.Templates, ts.WindowTemplate)
+	}
+
+	return ws
+}
@@ -0,0 +1,109 @@
+package logparser
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// incidentLog returns four five-minute windows of steady traffic: known
+// INFO and WARN templates in every window, with a new FATAL template in
+// the third
+func incidentLog() []LogEntry {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	var entries []LogEntry
+
+	for w := range 4 {
+		start := base.Add(time.Duration(w) * 5 * time.Minute)
+
+		for i := range 20 {
+			at := start.Add(time.Duration(i) * 10 * time.Second)
+			entries = append(entries,
+				LogEntry{Timestamp: at, Level: LevelInfo, Message: fmt.Sprintf("GET /api/orders/%d 200", i)},
+				LogEntry{Timestamp: at.Add(time.Second), Level: "WARN", Message: fmt.Sprintf("disk usage at %d%%", 80+i%5)},
+			)
+		}
+
+		if w == 2 {
+			entries = append(entries, LogEntry{
+				Timestamp: start.Add(2 * time.Minute),
+				Level:     "FATAL",
+				Message:   "panic: assignment to entry in nil map in scheduler",
+			})
+		}
+	}
+
+	// Newest first, to show order does not matter
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries
+}
+
+func TestScoreWindowsNewFatalFirst(t *testing.T) {
+	scores := ScoreWindows(incidentLog(), 5*time.Minute)
+	if len(scores) != 4 {
+		t.Fatalf("got %d windows, want 4", len(scores))
+	}
+
+	top := scores[0]
+	if want := time.Date(2024, 3, 1, 10, 10, 0, 0, time.UTC); !top.Start.Equal(want) {
+		t.Fatalf("top window starts %v, want %v: %+v", top.Start, want, scores)
+	}
+
+	if top.Novelty != 11 || top.Severity != math.Log2(11) {
+		t.Errorf("novelty = %v, severity = %v, want 11 and log2(11)", top.Novelty, top.Severity)
+	}
+
+	if len(top.Templates) == 0 || !top.Templates[0].New || top.Templates[0].Level != "FATAL" {
+		t.Errorf("top templates = %+v", top.Templates)
+	}
+
+	// The windows of known WARNs score only their slight rate changes
+	for _, ws := range scores[1:] {
+		if ws.Severity != 0 || ws.Novelty != 0 || ws.Score >= 1 {
+			t.Errorf("window %v: %+v", ws.Start, ws)
+		}
+
+		if ws.Score >= top.Score {
+			t.Errorf("window %v scores %v, not below %v", ws.Start, ws.Score, top.Score)
+		}
+	}
+}
+
+func TestScoreWindowsRateChange(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	var entries []LogEntry
+
+	// One entry in the first minute, then a burst of 63 in the third
+	// after a quiet second minute
+	entries = append(entries, LogEntry{Timestamp: base, Message: "tick"})
+
+	for i := range 63 {
+		entries = append(entries, LogEntry{Timestamp: base.Add(2*time.Minute + time.Duration(i)*500*time.Millisecond), Message: "tick"})
+	}
+
+	scores := ScoreWindows(entries, time.Minute)
+	if len(scores) != 2 {
+		t.Fatalf("got %d windows, want 2", len(scores))
+	}
+
+	// The burst follows an empty window, so its rate rose from nothing
+	if burst := scores[0]; burst.Entries != 63 || burst.RateChange != 6 || burst.Score != 6 {
+		t.Errorf("burst = %+v", burst)
+	}
+
+	if baseline := scores[1]; baseline.RateChange != 0 || baseline.Score != 0 {
+		t.Errorf("baseline = %+v", baseline)
+	}
+}
+
+func TestScoreWindowsEmpty(t *testing.T) {
+	if scores := ScoreWindows([]LogEntry{{Message: "no time"}}, time.Minute); scores == nil || len(scores) != 0 {
+		t.Errorf("scores = %v, want empty", scores)
+	}
+}
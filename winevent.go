@@ -0,0 +1,225 @@
+package logparser
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// winEventLevels maps Windows event levels 0 (LogAlways) to 5 (Verbose)
+var winEventLevels = [...]string{LevelInfo, "FATAL", LevelError, "WARN", LevelInfo, "DEBUG"}
+
+// Security audit keyword bits carried in System/Keywords
+const (
+	winAuditSuccess = 0x0020000000000000
+	winAuditFailure = 0x0010000000000000
+)
+
+// winEvent is the subset of a Windows Event XML record that is extracted
+type winEvent struct {
+	System struct {
+		Provider struct {
+			Name string `xml:"Name,attr"`
+		} `xml:"Provider"`
+		EventID     string `xml:"EventID"`
+		Level       string `xml:"Level"`
+		Task        string `xml:"Task"`
+		Keywords    string `xml:"Keywords"`
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+		EventRecordID string `xml:"EventRecordID"`
+		Execution     struct {
+			ProcessID string `xml:"ProcessID,attr"`
+			ThreadID  string `xml:"ThreadID,attr"`
+		} `xml:"Execution"`
+		Channel  string `xml:"Channel"`
+		Computer string `xml:"Computer"`
+		Security struct {
+			UserID string `xml:"UserID,attr"`
+		} `xml:"Security"`
+	} `xml:"System"`
+	EventData struct {
+		Data []winEventData `xml:"Data"`
+	} `xml:"EventData"`
+	RenderingInfo struct {
+		Message string `xml:"Message"`
+	} `xml:"RenderingInfo"`
+}
+
+// winEventData is a single EventData value, named or positional
+type winEventData struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// readWinEvents streams Event elements from src into the run and finishes
+// it. Events may be concatenated or wrapped in an <Events> element; other
+// elements are ignored. A malformed document ends the run, since the
+// decoder cannot resynchronize after an XML syntax error.
+func (r *parseRun) readWinEvents(src io.Reader) ([]LogEntry, Stats, error) {
+	src, skipped := decodeInput(src)
+
+	d := xml.NewDecoder(src)
+
+	// decodeInput has already transcoded UTF-16 exports to UTF-8
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "utf-16", "utf-16le", "utf-16be", "unicode":
+			return input, nil
+		default:
+			return nil, fmt.Errorf("logparser: unsupported XML encoding %q", charset)
+		}
+	}
+
+	defer func() { r.bytes = skipped + d.InputOffset() }()
+
+	for !r.done() {
+		line, _ := d.InputPos()
+		pos := linePos{line: line, offset: skipped + d.InputOffset()}
+
+		start := r.cfg.timer.start()
+
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, r.stats, r.recordError(pos, err)
+		}
+
+		elem, ok := tok.(xml.StartElement)
+		if !ok || elem.Name.Local != "Event" {
+			r.cfg.timer.stop(phaseScan, start, 0)
+
+			continue
+		}
+
+		var ev winEvent
+		if err := d.DecodeElement(&ev, &elem); err != nil {
+			return nil, r.stats, r.recordError(pos, err)
+		}
+
+		r.cfg.timer.stop(phaseScan, start, 1)
+		start = r.cfg.timer.start()
+
+		err = r.addRecord(winEventEntry(&ev, r.cfg), pos)
+		r.cfg.timer.stop(phaseParse, start, 1)
+
+		if err != nil {
+			return nil, r.stats, err
+		}
+	}
+
+	return r.finish()
+}
+
+// parseWinEventLine parses a single <Event> element held on one line
+func parseWinEventLine(line string, cfg *config) (*LogEntry, error) {
+	var ev winEvent
+	if err := xml.Unmarshal([]byte(line), &ev); err != nil {
+		return nil, fmt.Errorf("invalid Windows event XML: %w", err)
+	}
+
+	return winEventEntry(&ev, cfg), nil
+}
+
+// recordError describes a Windows event XML syntax error at pos
+func (r *parseRun) recordError(pos linePos, err error) error {
+	err = fmt.Errorf("invalid Windows event XML: %w", err)
+
+	return &LineError{Source: r.source, Line: pos.line, Offset: pos.offset, Err: err}
+}
+
+// winEventEntry maps a decoded event onto a LogEntry
+func winEventEntry(ev *winEvent, cfg *config) *LogEntry {
+	sys := &ev.System
+	entry := &LogEntry{Fields: cfg.newFields()}
+
+	start := cfg.timer.start()
+	t, err := time.Parse(time.RFC3339Nano, sys.TimeCreated.SystemTime)
+	cfg.timer.stop(phaseExtractTS, start, 1)
+
+	if err == nil {
+		entry.Timestamp = t
+	} else if sys.TimeCreated.SystemTime != "" {
+		cfg.setField(entry, "time_created", sys.TimeCreated.SystemTime)
+	}
+
+	if n, err := strconv.Atoi(sys.Level); err == nil && n >= 0 && n < len(winEventLevels) {
+		entry.Level = winEventLevels[n]
+	}
+
+	setWinEventInt(entry, cfg, "event_id", sys.EventID)
+	setWinEventInt(entry, cfg, "record_id", sys.EventRecordID)
+	setWinEventInt(entry, cfg, "process_id", sys.Execution.ProcessID)
+	setWinEventInt(entry, cfg, "thread_id", sys.Execution.ThreadID)
+	setWinEventInt(entry, cfg, "task", sys.Task)
+
+	for key, val := range map[string]string{
+		"provider": sys.Provider.Name,
+		"channel":  sys.Channel,
+		"computer": sys.Computer,
+		"user_id":  sys.Security.UserID,
+		"keywords": sys.Keywords,
+	} {
+		if val != "" {
+			cfg.setField(entry, key, val)
+		}
+	}
+
+	if keywords, err := strconv.ParseUint(strings.TrimPrefix(sys.Keywords, "0x"), 16, 64); err == nil {
+		switch {
+		case keywords&winAuditFailure != 0:
+			cfg.setField(entry, "audit", "failure")
+		case keywords&winAuditSuccess != 0:
+			cfg.setField(entry, "audit", "success")
+		}
+	}
+
+	var positional []interface{}
+
+	for _, data := range ev.EventData.Data {
+		if data.Name == "" {
+			positional = append(positional, data.Value)
+
+			continue
+		}
+
+		cfg.setField(entry, data.Name, data.Value)
+	}
+
+	if len(positional) > 0 {
+		cfg.setField(entry, "data", positional)
+	}
+
+	entry.Message = strings.TrimSpace(ev.RenderingInfo.Message)
+	if entry.Message == "" {
+		entry.Message = fmt.Sprintf("%s event %s", sys.Provider.Name, sys.EventID)
+	}
+
+	cfg.finishEntry(entry)
+
+	return entry
+}
+
+// setWinEventInt stores a numeric System value as an int, or as the raw
+// text if it is not a number
+func setWinEventInt(entry *LogEntry, cfg *config, key, val string) {
+	if val == "" {
+		return
+	}
+
+	if n, err := strconv.Atoi(val); err == nil {
+		cfg.setField(entry, key, n)
+
+		return
+	}
+
+	cfg.setField(entry, key, val)
+}
@@ -0,0 +1,136 @@
+package logparser
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+func TestParseWindowsEventXML(t *testing.T) {
+	entries, err := ParseFile(NewWithFormat(FormatWindowsEventXML), "testdata/winevent.xml")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	logon := entries[0]
+
+	if want := time.Date(2024, 3, 1, 10, 15, 30, 123456700, time.UTC); !logon.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", logon.Timestamp, want)
+	}
+
+	if logon.Level != LevelInfo || logon.Message != "Microsoft-Windows-Security-Auditing event 4625" {
+		t.Errorf("Level, Message = %q, %q", logon.Level, logon.Message)
+	}
+
+	for key, want := range map[string]interface{}{
+		"event_id":       4625,
+		"record_id":      118842,
+		"process_id":     732,
+		"thread_id":      5608,
+		"task":           12544,
+		"provider":       "Microsoft-Windows-Security-Auditing",
+		"channel":        "Security",
+		"computer":       "ws01.example.test",
+		"audit":          "failure",
+		"TargetUserName": "bob",
+		"Status":         "0xc000006d",
+		"LogonType":      "3",
+		"IpAddress":      "192.0.2.10",
+	} {
+		if got := logon.Fields[key]; got != want {
+			t.Errorf("Fields[%s] = %#v, want %#v", key, got, want)
+		}
+	}
+
+	if _, ok := logon.Fields["user_id"]; ok {
+		t.Error("empty Security element produced a user_id field")
+	}
+
+	scm := entries[1]
+
+	if scm.Level != LevelError || scm.Fields["event_id"] != 7000 || scm.Fields["user_id"] != "S-1-5-18" {
+		t.Errorf("second entry = %+v", scm)
+	}
+
+	if !strings.HasPrefix(scm.Message, "The Example Updater service failed to start") {
+		t.Errorf("Message = %q, want the rendered message", scm.Message)
+	}
+
+	if data, ok := scm.Fields["data"].([]interface{}); !ok || len(data) != 2 || data[0] != "Example Updater" {
+		t.Errorf("positional data = %#v", scm.Fields["data"])
+	}
+
+	if src := scm.Source; src == nil || src.Line != 31 {
+		t.Errorf("Source = %+v, want line 31", src)
+	}
+}
+
+func TestParseWindowsEventXMLLevels(t *testing.T) {
+	var b strings.Builder
+
+	// Concatenated events without a wrapper, all on one line
+	for level := range 6 {
+		b.WriteString("<Event><System><Level>")
+		b.WriteString(string(rune('0' + level)))
+		b.WriteString("</Level></System></Event>")
+	}
+
+	entries, err := NewWithFormat(FormatWindowsEventXML).ParseString(b.String())
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+
+	want := []string{LevelInfo, "FATAL", LevelError, "WARN", LevelInfo, "DEBUG"}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for i, e := range entries {
+		if e.Level != want[i] {
+			t.Errorf("Level %d = %q, want %q", i, e.Level, want[i])
+		}
+	}
+}
+
+func TestParseWindowsEventXMLMalformed(t *testing.T) {
+	input := "<Events>\n<Event><System><Level>2</Level></System></Event>\n<Event><System><Level>2</Level></Event>\n</Events>\n"
+
+	p := NewWithFormat(FormatWindowsEventXML, WithSourceName("bad.xml"))
+	_, stats, err := ParseWithStats(p, strings.NewReader(input))
+
+	var le *LineError
+	if !errors.As(err, &le) || le.Line != 3 || le.Source != "bad.xml" {
+		t.Fatalf("error = %v, want a *LineError on line 3", err)
+	}
+
+	if stats.LinesSeen != 1 {
+		t.Errorf("LinesSeen = %d, want 1 record before the error", stats.LinesSeen)
+	}
+}
+
+func TestParseWindowsEventXMLUTF16(t *testing.T) {
+	text := "\uFEFF<?xml version=\"1.0\" encoding=\"UTF-16\"?>\n<Events><Event><System><Level>3</Level>" +
+		"<Computer>höst</Computer></System></Event></Events>\n"
+	units := utf16.Encode([]rune(text))
+
+	data := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		data = append(data, byte(u), byte(u>>8))
+	}
+
+	entries, err := NewWithFormat(FormatWindowsEventXML).Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Level != "WARN" || entries[0].Fields["computer"] != "höst" {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
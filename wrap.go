@@ -0,0 +1,137 @@
+package logparser
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxWrappedLength caps the length of a line joined from wrapped
+// pieces when WithWrappedLines is given no cap
+const DefaultMaxWrappedLength = 64 * 1024
+
+// recordStartRe matches lines opening with a timestamp or level, as text
+// records do: an ISO or slashed date, a time of day, a syslog month and
+// day, a klog header, or a level word, optionally after "[" or a syslog
+// priority
+var recordStartRe = regexp.MustCompile(`^(?:<\d{1,3}>|\[)?(?:\d{4}[-/]\d{2}[-/]\d{2}|\d{2}[-/]\d{2}[ T]\d{2}:|\d{1,2}:\d{2}:\d{2}|` +
+	`(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) +\d{1,2} |[IWEF]\d{4} |` +
+	`(?i:trace|debug|info|warn|warning|error|err|fatal|critical|crit|panic)\b)`)
+
+// logfmtStartRe matches lines opening with a logfmt key
+var logfmtStartRe = regexp.MustCompile(`^[A-Za-z_][\w.-]*=`)
+
+// WithWrappedLines joins lines that a terminal or log viewer hard-wrapped
+// at width columns back into the line that was logged. A line is appended
+// to the one before it, without a separator, when the one before is at or
+// near the wrap width (within a tenth of it, for viewers that wrap at
+// spaces) and the line does not start a record: for JSON, any line after
+// a complete object, or one that is an object; for logfmt, one opening
+// with a key; for text, one opening with a timestamp or level, or
+// matching a pattern with a timestamp. Before the format is detected, the line the pieces are
+// joined onto decides which rule applies. A short line is never joined to
+// the line after it, so free text is only joined when it fills the width.
+// Joining stops once the line would exceed maxLength bytes (default
+// DefaultMaxWrappedLength when 0). Joined lines are counted in
+// Stats.WrappedLines, and entries take the number of the first piece.
+func WithWrappedLines(width, maxLength int) Option {
+	return func(c *config) {
+		c.wrapWidth = width
+		c.wrapMax = maxLength
+	}
+}
+
+// lineJoiner reassembles wrapped lines for a cursor. It holds the last
+// line read until the next one shows whether it continues.
+type lineJoiner struct {
+	width   int
+	slack   int
+	max     int
+	starts  func(held, line string) bool // Whether line starts a new record after held
+	held    strings.Builder
+	pos     linePos
+	last    int // Runes in the last piece held
+	holding bool
+}
+
+// newLineJoiner returns a joiner for the configured width, or nil when
+// wrapped lines are not joined
+func (r *parseRun) newLineJoiner() *lineJoiner {
+	if r.cfg.wrapWidth <= 0 {
+		return nil
+	}
+
+	maxLength := r.cfg.wrapMax
+	if maxLength == 0 {
+		maxLength = DefaultMaxWrappedLength
+	}
+
+	return &lineJoiner{width: r.cfg.wrapWidth, slack: r.cfg.wrapWidth / 10, max: maxLength, starts: r.startsRecord}
+}
+
+// joins reports whether line continues the held line
+func (j *lineJoiner) joins(line string) bool {
+	return j.last >= j.width-j.slack && j.last <= j.width &&
+		strings.TrimSpace(line) != "" &&
+		j.held.Len()+len(line) <= j.max &&
+		!j.starts(j.held.String(), line)
+}
+
+// hold starts holding a line
+func (j *lineJoiner) hold(line string, pos linePos) {
+	j.held.Reset()
+	j.held.WriteString(line)
+	j.pos = pos
+	j.last = utf8.RuneCountInString(line)
+	j.holding = true
+}
+
+// add appends a wrapped piece to the held line
+func (j *lineJoiner) add(line string) {
+	j.held.WriteString(line)
+	j.last = utf8.RuneCountInString(line)
+}
+
+// take returns the held line and stops holding it
+func (j *lineJoiner) take() (string, linePos) {
+	j.holding = false
+
+	return j.held.String(), j.pos
+}
+
+// startsRecord reports whether line starts a new record rather than
+// continuing held, by the rules of the run's format or, before detection,
+// the format held looks like
+func (r *parseRun) startsRecord(held, line string) bool {
+	format := r.format
+	if format == FormatAuto {
+		switch {
+		case strings.HasPrefix(held, "{"):
+			format = FormatJSON
+		case logfmtStartRe.MatchString(held) && !recordStartRe.MatchString(held):
+			format = FormatLogfmt
+		default:
+			format = FormatText
+		}
+	}
+
+	switch format {
+	case FormatJSON:
+		return json.Valid([]byte(held)) || strings.HasPrefix(line, "{") && json.Valid([]byte(line))
+	case FormatLogfmt:
+		return logfmtStartRe.MatchString(line)
+	}
+
+	if recordStartRe.MatchString(line) {
+		return true
+	}
+
+	for _, tp := range r.cfg.textPatterns {
+		if tp.tsIndex > 0 && tp.regex.MatchString(line) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,164 @@
+package logparser
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// wrappedMessages are the messages of testdata/wrapped.json, which holds
+// JSON lines hard-wrapped at 80 columns
+var wrappedMessages = []string{
+	"service started",
+	"slow request to upstream payments gateway, retrying with backoff",
+	"request failed",
+	"cache stats",
+	"stopped",
+}
+
+// wrappedLines are the first physical lines of the wrapped entries
+var wrappedLines = []int{1, 3, 6, 9, 11}
+
+func TestWrappedJSONReassembles(t *testing.T) {
+	data, err := os.ReadFile("testdata/wrapped.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		parser Parser
+	}{
+		{"auto", New(WithWrappedLines(80, 0), WithSourceName("wrapped.json"))},
+		{"json", NewWithFormat(FormatJSON, WithWrappedLines(80, 0), WithSourceName("wrapped.json"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, stats, err := ParseWithStats(tt.parser, strings.NewReader(string(data)))
+			if err != nil {
+				t.Fatalf("ParseWithStats() error = %v", err)
+			}
+
+			var messages []string
+
+			var lines []int
+
+			for _, e := range entries {
+				messages = append(messages, e.Message)
+				lines = append(lines, e.Source.Line)
+			}
+
+			if !reflect.DeepEqual(messages, wrappedMessages) {
+				t.Errorf("messages = %q, want %q", messages, wrappedMessages)
+			}
+
+			if !reflect.DeepEqual(lines, wrappedLines) {
+				t.Errorf("lines = %v, want %v", lines, wrappedLines)
+			}
+
+			if stats.WrappedLines != 7 || stats.LinesSeen != 5 {
+				t.Errorf("WrappedLines = %d, LinesSeen = %d, want 7 and 5", stats.WrappedLines, stats.LinesSeen)
+			}
+
+			// The piece opening with a date is part of the value it wraps
+			if since := entries[4].Fields["since"]; since != "2024-03-01T09:00:00Z" {
+				t.Errorf("since = %v", since)
+			}
+
+			user, _ := entries[2].Fields["user"].(map[string]interface{})
+			if user["name"] != "Ayşe Yılmaz" {
+				t.Errorf("user = %v", entries[2].Fields["user"])
+			}
+		})
+	}
+
+	// Without the option the pieces fail to parse
+	if _, err := NewWithFormat(FormatJSON).ParseString(string(data)); err == nil {
+		t.Error("unwrapped parse succeeded")
+	}
+}
+
+func TestWrappedLinesWriter(t *testing.T) {
+	data, err := os.ReadFile("testdata/wrapped.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+
+	w := NewWriter(New(WithWrappedLines(80, 0)), func(e LogEntry) {
+		messages = append(messages, e.Message)
+	})
+
+	// Write a byte at a time, so pieces arrive in separate writes
+	for _, b := range data {
+		if _, err := w.Write([]byte{b}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(messages, wrappedMessages) {
+		t.Errorf("messages = %q, want %q", messages, wrappedMessages)
+	}
+}
+
+func TestWrappedLinesText(t *testing.T) {
+	long := "2024-03-01 10:00:00 ERROR upstream payments gateway refused the connection after"
+	input := strings.Join([]string{
+		"2024-03-01 10:00:00 INFO short line",
+		"which is not wrapped",
+		long[:80],
+		" three retries",
+		long[:80],
+		"2024-03-01 10:00:01 WARN next record",
+		long[:80],
+		"ERROR level at the start of a record",
+	}, "\n")
+
+	entries, stats, err := ParseWithStats(NewWithFormat(FormatText, WithWrappedLines(80, 0)), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	// Only the piece after a full-width line without a timestamp or level
+	// is joined
+	if stats.WrappedLines != 1 {
+		t.Errorf("WrappedLines = %d, want 1", stats.WrappedLines)
+	}
+
+	if len(entries) != 7 {
+		t.Fatalf("got %d entries, want 7", len(entries))
+	}
+
+	if !strings.HasSuffix(entries[2].Message, "connection after three retries") {
+		t.Errorf("joined message = %q", entries[2].Message)
+	}
+}
+
+func TestWrappedLinesMaxLength(t *testing.T) {
+	piece := strings.Repeat("x", 40)
+	input := strings.Repeat(piece+"\n", 6)
+
+	_, stats, err := ParseWithStats(NewWithFormat(FormatText, WithWrappedLines(40, 100)), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWithStats() error = %v", err)
+	}
+
+	// Two pieces fit in 100 bytes, so six make three lines
+	if stats.WrappedLines != 3 || stats.LinesSeen != 3 {
+		t.Errorf("WrappedLines = %d, LinesSeen = %d, want 3 and 3", stats.WrappedLines, stats.LinesSeen)
+	}
+}
+
+func TestWrappedLinesInvalid(t *testing.T) {
+	if _, err := NewE(WithWrappedLines(-1, 0)); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("NewE() error = %v, want ErrInvalidOptions", err)
+	}
+}
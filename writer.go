@@ -0,0 +1,243 @@
+package logparser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// entryWriter is the io.WriteCloser returned by NewWriter
+type entryWriter struct {
+	mu     sync.Mutex
+	fn     func(LogEntry)
+	run    *parseRun   // Incremental run, for parsers from this package
+	cursor *lineCursor // Cursor over the complete lines in buf
+	other  Parser      // Any other parser, given one line at a time
+	buf    []byte      // Input not yet handed to the run
+	start  int         // Start of the next line in buf
+	offset int64       // Input offset of buf[start]
+	first  bool        // Whether the next line is the first, which may start with a BOM
+	eof    bool        // Set by Close, so the final unterminated line is read
+	closed bool
+	err    error // First error, returned by every later call
+}
+
+// NewWriter returns a writer that parses the log lines written to it with
+// p and calls fn with each entry, synchronously, so a slow fn slows down
+// the writer. It fits os/exec pipelines:
+//
+//	cmd.Stdout = logparser.NewWriter(parser, handleEntry)
+//
+// Partial lines are buffered across Write calls, and Close parses the final
+// unterminated line. An entry is passed to fn once the following line
+// shows that no continuation line will be folded into it, and in auto mode
+// once the format has been detected; Close delivers the rest. Lines longer
+// than the line size limit (WithMaxLineSize) fail with bufio.ErrTooLong,
+// as in Parse. Once the head
+// limit is reached, further input is discarded. WithTailLimit is ignored.
+//
+// A parse error is returned by the Write that completed the failing line,
+// or by Close when errors are collected with WithMaxErrors, and every call
+// after it returns the same error. Input must be UTF-8. Windows Event XML
+// is parsed when the writer is closed. Write and Close may be called from
+// different goroutines.
+func NewWriter(p Parser, fn func(LogEntry)) io.WriteCloser {
+	pp, ok := p.(*parser)
+	if !ok {
+		return &entryWriter{fn: fn, first: true, other: p}
+	}
+
+	return pp.newWriter(runInput{name: pp.cfg.sourceName}, fn)
+}
+
+// newWriter returns the writer of NewWriter for an input described by in
+func (p *parser) newWriter(in runInput, fn func(LogEntry)) *entryWriter {
+	w := &entryWriter{fn: fn, first: true}
+
+	w.run = p.newRun(in)
+	if w.run.cfg.tailLimit > 0 {
+		cfg := *w.run.cfg
+		cfg.tailLimit = 0
+		w.run.cfg = &cfg
+	}
+
+	// Delivered entries cannot be reordered
+	if w.run.smart != nil {
+		w.run.smart.ordered = false
+	}
+
+	w.cursor = w.run.cursor(w.next)
+	w.cursor.more = func() bool { return !w.eof }
+
+	return w
+}
+
+// Write parses the complete lines in b, buffering a trailing partial line
+func (w *entryWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch {
+	case w.err != nil:
+		return 0, w.err
+	case w.closed:
+		return 0, os.ErrClosed
+	case w.run != nil && w.run.done():
+		return len(b), nil
+	}
+
+	w.buf = append(w.buf, b...)
+
+	if w.run != nil && w.run.format == FormatWindowsEventXML {
+		return len(b), nil
+	}
+
+	if err := w.flush(); err != nil {
+		w.err = err
+
+		return len(b), err
+	}
+
+	// Keep only the partial line, reusing the buffer
+	w.buf = append(w.buf[:0], w.buf[w.start:]...)
+	w.start = 0
+
+	limit := BufferSize
+	if w.run != nil {
+		limit = w.run.cfg.lineLimit()
+	}
+
+	if len(w.buf) > limit {
+		w.err = bufio.ErrTooLong
+
+		return len(b), w.err
+	}
+
+	return len(b), nil
+}
+
+// Close parses the final line, if it is unterminated, and delivers the
+// remaining entries
+func (w *entryWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return w.err
+	}
+
+	w.closed = true
+	w.eof = true
+
+	if w.err != nil {
+		return w.err
+	}
+
+	if w.run != nil && w.run.format == FormatWindowsEventXML {
+		entries, _, err := w.run.read(bytes.NewReader(w.buf), int64(len(w.buf)))
+		w.deliver(entries)
+		w.err = err
+
+		return err
+	}
+
+	if err := w.flush(); err != nil {
+		w.err = err
+
+		return err
+	}
+
+	if w.run == nil {
+		return nil
+	}
+
+	entries, _, err := w.run.finish()
+	w.deliver(entries)
+	w.err = err
+
+	return err
+}
+
+// flush parses the complete lines in the buffer. All but the last entry
+// stored by the run are delivered, since a continuation line may still be
+// folded into that one.
+func (w *entryWriter) flush() error {
+	if w.run == nil {
+		return w.flushOther()
+	}
+
+	if err := w.run.feed(w.cursor); err != nil {
+		return err
+	}
+
+	// Under smart sampling continuation lines fold into the sampler's
+	// staged entry instead
+	held := 1
+	if w.run.smart != nil {
+		held = 0
+	}
+
+	if n := len(w.run.entries) - held; n > 0 {
+		w.deliver(w.run.entries[:n])
+		w.run.entries = append(w.run.entries[:0], w.run.entries[n:]...)
+	}
+
+	return nil
+}
+
+// flushOther parses the complete lines in the buffer with a parser from
+// outside this package, one line at a time
+func (w *entryWriter) flushOther() error {
+	for {
+		line, _, ok := w.next()
+		if !ok {
+			return nil
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		entries, err := w.other.ParseString(line)
+		if err != nil {
+			return err
+		}
+
+		w.deliver(entries)
+	}
+}
+
+// next returns the next complete line in the buffer and its offset, or
+// after Close the final unterminated one
+func (w *entryWriter) next() (string, int64, bool) {
+	n, advance, ok := lineEnd(w.buf[w.start:], w.eof, bytes.IndexAny)
+	if !ok {
+		return "", 0, false
+	}
+
+	line := string(w.buf[w.start : w.start+n])
+	offset := w.offset
+	w.start += advance
+	w.offset += int64(advance)
+
+	if w.first {
+		w.first = false
+
+		if trimmed := strings.TrimPrefix(line, "\uFEFF"); len(trimmed) != len(line) {
+			offset += int64(len(line) - len(trimmed))
+			line = trimmed
+		}
+	}
+
+	return line, offset, true
+}
+
+// deliver passes entries to the callback
+func (w *entryWriter) deliver(entries []LogEntry) {
+	for _, entry := range entries {
+		w.fn(entry)
+	}
+}
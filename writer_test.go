@@ -0,0 +1,170 @@
+package logparser
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chunks splits s into pieces of size bytes
+func chunks(s string, size int) []string {
+	var pieces []string
+
+	for i := 0; i < len(s); i += size {
+		pieces = append(pieces, s[i:min(i+size, len(s))])
+	}
+
+	return pieces
+}
+
+// writeChunks writes the chunks to a new writer and returns the entries
+// delivered after Close
+func writeChunks(t *testing.T, p Parser, chunks []string) []LogEntry {
+	t.Helper()
+
+	var got []LogEntry
+
+	w := NewWriter(p, func(e LogEntry) { got = append(got, e) })
+
+	for _, chunk := range chunks {
+		if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write() = %d, %v", n, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	return got
+}
+
+func TestWriterMatchesParse(t *testing.T) {
+	for _, name := range []string{"klog.log", "sample.log", "mysql.log", "performance.log"} {
+		data, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		input := string(data)
+		parser := New(WithReferenceTime(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+
+		want, err := parser.ParseString(input)
+		if err != nil {
+			t.Fatalf("%s: ParseString() error = %v", name, err)
+		}
+
+		// Single bytes, an odd size, whole lines ending exactly on newlines,
+		// and all at once
+		for i, pieces := range [][]string{
+			chunks(input, 1), chunks(input, 7), strings.SplitAfter(input, "\n"), {input},
+		} {
+			got := writeChunks(t, parser, pieces)
+			if len(got) != len(want) {
+				t.Fatalf("%s, chunking %d: got %d entries, want %d", name, i, len(got), len(want))
+			}
+
+			for j := range want {
+				if !convertEqual(got[j], want[j], true) {
+					t.Errorf("%s, chunking %d: entry %d differs:\n%s", name, i, j, DiffEntries(got[j], want[j]))
+				}
+			}
+		}
+	}
+}
+
+func TestWriterDelivery(t *testing.T) {
+	var got []string
+
+	w := NewWriter(NewWithFormat(FormatLogfmt), func(e LogEntry) { got = append(got, e.Message) })
+
+	// Each entry is delivered once the next line is complete
+	steps := []struct {
+		write string
+		want  []string
+	}{
+		{"msg=a\nmsg=b", nil},
+		{"\n", []string{"a"}},
+		{"msg=c\n\n", []string{"a", "b"}},
+		{"msg=d", []string{"a", "b"}},
+	}
+
+	for _, step := range steps {
+		if _, err := w.Write([]byte(step.write)); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("after %q delivered %q, want %q", step.write, got, step.want)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after Close delivered %q, want %q", got, want)
+	}
+
+	if _, err := w.Write([]byte("msg=e\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write after Close error = %v", err)
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	w := NewWriter(NewWithFormat(FormatJSON), func(LogEntry) {})
+
+	if _, err := w.Write([]byte("{\"msg\":\"ok\"}\nnot json")); err != nil {
+		t.Fatalf("Write() error = %v before the bad line was complete", err)
+	}
+
+	if _, err := w.Write([]byte("\n")); err == nil {
+		t.Fatal("Write() accepted an invalid line")
+	}
+
+	if _, err := w.Write([]byte("{}\n")); err == nil || w.Close() == nil {
+		t.Error("the error did not stick")
+	}
+
+	w = NewWriter(New(), func(LogEntry) {})
+	if _, err := w.Write([]byte(strings.Repeat("x", BufferSize+1))); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("long line error = %v, want bufio.ErrTooLong", err)
+	}
+
+	var n int
+
+	w = NewWriter(New(WithHeadLimit(2)), func(LogEntry) { n++ })
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write([]byte("level=info msg=x\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil || n != 2 {
+		t.Errorf("head limit delivered %d entries, err %v", n, err)
+	}
+}
+
+// lineParser is a Parser from outside the package
+type lineParser struct{ Parser }
+
+func TestWriterOtherParser(t *testing.T) {
+	var got []string
+
+	w := NewWriter(lineParser{New()}, func(e LogEntry) { got = append(got, e.Message) })
+
+	for _, s := range []string{"msg=a\nms", "g=b\n\n", "msg=c"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil || !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("delivered %q, err %v", got, err)
+	}
+}